@@ -0,0 +1,252 @@
+// 供内部服务使用的只读包元数据接口，与HTTP接口共用PackageService，配置grpc.enabled后在单独端口提供
+//
+// 修改后重新生成代码（在仓库根目录执行）：
+//   protoc --go_out=. --go_opt=module=webservice --go-grpc_out=. --go-grpc_opt=module=webservice api/proto/package/v1/package.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: api/proto/package/v1/package.proto
+
+package packagev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PackageService_GetPackage_FullMethodName        = "/webservice.package.v1.PackageService/GetPackage"
+	PackageService_GetVersion_FullMethodName        = "/webservice.package.v1.PackageService/GetVersion"
+	PackageService_ResolveConstraint_FullMethodName = "/webservice.package.v1.PackageService/ResolveConstraint"
+	PackageService_BatchGetPackages_FullMethodName  = "/webservice.package.v1.PackageService/BatchGetPackages"
+)
+
+// PackageServiceClient is the client API for PackageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PackageService 只读的包元数据查询，调用方在metadata的authorization中携带 "Bearer <api_token>"
+type PackageServiceClient interface {
+	// GetPackage 获取包信息，不包含版本列表
+	GetPackage(ctx context.Context, in *GetPackageRequest, opts ...grpc.CallOption) (*Package, error)
+	// GetVersion 获取指定版本
+	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*Version, error)
+	// ResolveConstraint 返回满足版本约束（如 ^1.2.0）的最高版本
+	ResolveConstraint(ctx context.Context, in *ResolveConstraintRequest, opts ...grpc.CallOption) (*Version, error)
+	// BatchGetPackages 批量获取包信息，不存在或无权访问的包名在not_found中返回
+	BatchGetPackages(ctx context.Context, in *BatchGetPackagesRequest, opts ...grpc.CallOption) (*BatchGetPackagesResponse, error)
+}
+
+type packageServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPackageServiceClient(cc grpc.ClientConnInterface) PackageServiceClient {
+	return &packageServiceClient{cc}
+}
+
+func (c *packageServiceClient) GetPackage(ctx context.Context, in *GetPackageRequest, opts ...grpc.CallOption) (*Package, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Package)
+	err := c.cc.Invoke(ctx, PackageService_GetPackage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packageServiceClient) GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*Version, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Version)
+	err := c.cc.Invoke(ctx, PackageService_GetVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packageServiceClient) ResolveConstraint(ctx context.Context, in *ResolveConstraintRequest, opts ...grpc.CallOption) (*Version, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Version)
+	err := c.cc.Invoke(ctx, PackageService_ResolveConstraint_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *packageServiceClient) BatchGetPackages(ctx context.Context, in *BatchGetPackagesRequest, opts ...grpc.CallOption) (*BatchGetPackagesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetPackagesResponse)
+	err := c.cc.Invoke(ctx, PackageService_BatchGetPackages_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PackageServiceServer is the server API for PackageService service.
+// All implementations must embed UnimplementedPackageServiceServer
+// for forward compatibility.
+//
+// PackageService 只读的包元数据查询，调用方在metadata的authorization中携带 "Bearer <api_token>"
+type PackageServiceServer interface {
+	// GetPackage 获取包信息，不包含版本列表
+	GetPackage(context.Context, *GetPackageRequest) (*Package, error)
+	// GetVersion 获取指定版本
+	GetVersion(context.Context, *GetVersionRequest) (*Version, error)
+	// ResolveConstraint 返回满足版本约束（如 ^1.2.0）的最高版本
+	ResolveConstraint(context.Context, *ResolveConstraintRequest) (*Version, error)
+	// BatchGetPackages 批量获取包信息，不存在或无权访问的包名在not_found中返回
+	BatchGetPackages(context.Context, *BatchGetPackagesRequest) (*BatchGetPackagesResponse, error)
+	mustEmbedUnimplementedPackageServiceServer()
+}
+
+// UnimplementedPackageServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPackageServiceServer struct{}
+
+func (UnimplementedPackageServiceServer) GetPackage(context.Context, *GetPackageRequest) (*Package, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPackage not implemented")
+}
+func (UnimplementedPackageServiceServer) GetVersion(context.Context, *GetVersionRequest) (*Version, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (UnimplementedPackageServiceServer) ResolveConstraint(context.Context, *ResolveConstraintRequest) (*Version, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveConstraint not implemented")
+}
+func (UnimplementedPackageServiceServer) BatchGetPackages(context.Context, *BatchGetPackagesRequest) (*BatchGetPackagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetPackages not implemented")
+}
+func (UnimplementedPackageServiceServer) mustEmbedUnimplementedPackageServiceServer() {}
+func (UnimplementedPackageServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafePackageServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PackageServiceServer will
+// result in compilation errors.
+type UnsafePackageServiceServer interface {
+	mustEmbedUnimplementedPackageServiceServer()
+}
+
+func RegisterPackageServiceServer(s grpc.ServiceRegistrar, srv PackageServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPackageServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PackageService_ServiceDesc, srv)
+}
+
+func _PackageService_GetPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackageServiceServer).GetPackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackageService_GetPackage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackageServiceServer).GetPackage(ctx, req.(*GetPackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PackageService_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackageServiceServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackageService_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackageServiceServer).GetVersion(ctx, req.(*GetVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PackageService_ResolveConstraint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveConstraintRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackageServiceServer).ResolveConstraint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackageService_ResolveConstraint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackageServiceServer).ResolveConstraint(ctx, req.(*ResolveConstraintRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PackageService_BatchGetPackages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetPackagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PackageServiceServer).BatchGetPackages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PackageService_BatchGetPackages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PackageServiceServer).BatchGetPackages(ctx, req.(*BatchGetPackagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PackageService_ServiceDesc is the grpc.ServiceDesc for PackageService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PackageService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "webservice.package.v1.PackageService",
+	HandlerType: (*PackageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPackage",
+			Handler:    _PackageService_GetPackage_Handler,
+		},
+		{
+			MethodName: "GetVersion",
+			Handler:    _PackageService_GetVersion_Handler,
+		},
+		{
+			MethodName: "ResolveConstraint",
+			Handler:    _PackageService_ResolveConstraint_Handler,
+		},
+		{
+			MethodName: "BatchGetPackages",
+			Handler:    _PackageService_BatchGetPackages_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/proto/package/v1/package.proto",
+}
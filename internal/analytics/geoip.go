@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GeoResolver 将IP地址解析为国家代码
+type GeoResolver interface {
+	// Country 返回ISO 3166-1 alpha-2国家代码，无法解析时返回空字符串
+	Country(ip net.IP) string
+}
+
+// ipRange 一段连续IP地址对应的国家
+type ipRange struct {
+	start   net.IP // 统一为16字节形式
+	end     net.IP
+	country string
+}
+
+// CSVGeoDatabase 基于CSV地址段文件的GeoIP数据库
+// 每行格式为 start_ip,end_ip,country_code（兼容DB-IP等免费国家库的CSV导出），支持IPv4与IPv6
+type CSVGeoDatabase struct {
+	ranges []ipRange
+}
+
+// LoadCSVGeoDatabase 从文件加载GeoIP数据库
+func LoadCSVGeoDatabase(path string) (*CSVGeoDatabase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	defer f.Close()
+	return ParseCSVGeoDatabase(f)
+}
+
+// ParseCSVGeoDatabase 解析CSV格式的GeoIP数据，空行和#开头的行会被忽略
+func ParseCSVGeoDatabase(r io.Reader) (*CSVGeoDatabase, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	db := &CSVGeoDatabase{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid GeoIP database: %w", err)
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("invalid GeoIP database: line %d has %d fields, expected 3", line, len(record))
+		}
+
+		start := net.ParseIP(strings.TrimSpace(record[0]))
+		end := net.ParseIP(strings.TrimSpace(record[1]))
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("invalid GeoIP database: line %d has an invalid IP address", line)
+		}
+		db.ranges = append(db.ranges, ipRange{
+			start:   start.To16(),
+			end:     end.To16(),
+			country: strings.ToUpper(strings.TrimSpace(record[2])),
+		})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].start, db.ranges[j].start) < 0
+	})
+	return db, nil
+}
+
+// Country 二分查找IP所在的地址段
+func (db *CSVGeoDatabase) Country(ip net.IP) string {
+	if db == nil || ip == nil {
+		return ""
+	}
+	ip = ip.To16()
+
+	// 找到最后一个起始地址不大于ip的地址段
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return bytes.Compare(db.ranges[i].start, ip) > 0
+	}) - 1
+	if i < 0 || bytes.Compare(ip, db.ranges[i].end) > 0 {
+		return ""
+	}
+	return db.ranges[i].country
+}
+
+// Len 返回地址段数量
+func (db *CSVGeoDatabase) Len() int {
+	return len(db.ranges)
+}
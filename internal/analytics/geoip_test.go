@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+const testGeoCSV = `# start,end,country
+10.0.0.0,10.0.0.255,us
+192.0.2.0,192.0.2.127,DE
+
+2001:db8::,2001:db8::ffff,JP
+1.0.0.0,1.0.0.255,AU
+`
+
+func TestCSVGeoDatabaseCountry(t *testing.T) {
+	db, err := ParseCSVGeoDatabase(strings.NewReader(testGeoCSV))
+	if err != nil {
+		t.Fatalf("ParseCSVGeoDatabase: %v", err)
+	}
+	if db.Len() != 4 {
+		t.Fatalf("Len = %d, want 4", db.Len())
+	}
+
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.0.0.0", "US"}, // 国家代码统一为大写
+		{"10.0.0.255", "US"},
+		{"10.0.1.0", ""},
+		{"1.0.0.7", "AU"}, // 文件中未排序的地址段
+		{"192.0.2.127", "DE"},
+		{"192.0.2.128", ""}, // 地址段之间的空隙
+		{"0.0.0.1", ""},     // 第一个地址段之前
+		{"2001:db8::1", "JP"},
+		{"2001:db8::1:0", ""},
+		{"::ffff:10.0.0.5", "US"}, // IPv4映射地址
+	}
+	for _, tt := range tests {
+		if got := db.Country(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("Country(%s) = %q, want %q", tt.ip, got, tt.want)
+		}
+	}
+	if got := db.Country(nil); got != "" {
+		t.Errorf("Country(nil) = %q, want empty", got)
+	}
+	var missing *CSVGeoDatabase
+	if got := missing.Country(net.ParseIP("10.0.0.1")); got != "" {
+		t.Errorf("nil database Country = %q, want empty", got)
+	}
+}
+
+func TestParseCSVGeoDatabaseRejectsInvalidLines(t *testing.T) {
+	for _, input := range []string{
+		"10.0.0.0,10.0.0.255\n",
+		"10.0.0.0,not-an-ip,US\n",
+	} {
+		if _, err := ParseCSVGeoDatabase(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseCSVGeoDatabase(%q) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package analytics
+
+import (
+	"strings"
+)
+
+// 客户端类别
+const (
+	ToolBrowser        = "browser"
+	ToolCLI            = "cli"
+	ToolPackageManager = "package-manager"
+	ToolLibrary        = "library"
+	ToolBot            = "bot"
+	ToolOther          = "other"
+)
+
+// ClientInfo 从User-Agent中解析出的客户端信息
+type ClientInfo struct {
+	Client        string `json:"client"`         // 客户端名称，如 curl、Chrome、pip
+	ClientVersion string `json:"client_version"` // 客户端版本
+	OS            string `json:"os"`             // 操作系统
+	Tool          string `json:"tool"`           // 客户端类别
+}
+
+// clientRule 按产品名识别客户端的规则，顺序即优先级
+type clientRule struct {
+	product string // User-Agent中的产品标识（不区分大小写）
+	name    string
+	tool    string
+}
+
+// clientRules 命令行工具、包管理器和HTTP库优先于浏览器识别，浏览器按特征从具体到通用排列
+// （Edge/Opera的UA同时包含Chrome和Safari，Chrome的UA同时包含Safari）
+var clientRules = []clientRule{
+	{"curl", "curl", ToolCLI},
+	{"wget", "Wget", ToolCLI},
+	{"httpie", "HTTPie", ToolCLI},
+	{"pip", "pip", ToolPackageManager},
+	{"npm", "npm", ToolPackageManager},
+	{"yarn", "yarn", ToolPackageManager},
+	{"bundler", "Bundler", ToolPackageManager},
+	{"cargo", "cargo", ToolPackageManager},
+	{"maven", "Maven", ToolPackageManager},
+	{"gradle", "Gradle", ToolPackageManager},
+	{"go-http-client", "Go-http-client", ToolLibrary},
+	{"python-requests", "python-requests", ToolLibrary},
+	{"python-urllib", "Python-urllib", ToolLibrary},
+	{"okhttp", "okhttp", ToolLibrary},
+	{"axios", "axios", ToolLibrary},
+	{"node-fetch", "node-fetch", ToolLibrary},
+	{"java", "Java", ToolLibrary},
+	{"googlebot", "Googlebot", ToolBot},
+	{"bingbot", "bingbot", ToolBot},
+	{"edg", "Edge", ToolBrowser},
+	{"opr", "Opera", ToolBrowser},
+	{"firefox", "Firefox", ToolBrowser},
+	{"chrome", "Chrome", ToolBrowser},
+	{"safari", "Safari", ToolBrowser},
+}
+
+// osRules 操作系统识别规则，iOS/Android需先于macOS/Linux匹配
+var osRules = []struct {
+	marker string
+	name   string
+}{
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"android", "Android"},
+	{"windows", "Windows"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"darwin", "macOS"},
+	{"cros", "ChromeOS"},
+	{"freebsd", "FreeBSD"},
+	{"linux", "Linux"},
+}
+
+// ParseUserAgent 解析User-Agent
+// 无法识别的客户端返回类别other并保留首个产品名，空字符串返回零值
+func ParseUserAgent(ua string) ClientInfo {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return ClientInfo{}
+	}
+
+	products := parseProducts(ua)
+	info := ClientInfo{Tool: ToolOther}
+
+	matched := false
+	for _, rule := range clientRules {
+		if version, ok := products[rule.product]; ok {
+			info.Client = rule.name
+			info.ClientVersion = version
+			info.Tool = rule.tool
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		lower := strings.ToLower(ua)
+		if strings.Contains(lower, "bot") || strings.Contains(lower, "spider") || strings.Contains(lower, "crawler") {
+			info.Tool = ToolBot
+		}
+		name, version, _ := strings.Cut(firstProduct(ua), "/")
+		info.Client = name
+		info.ClientVersion = version
+	}
+
+	// Safari的版本号位于Version/产品中
+	if info.Client == "Safari" {
+		if version, ok := products["version"]; ok {
+			info.ClientVersion = version
+		}
+	}
+
+	info.OS = parseOS(ua)
+	return info
+}
+
+// parseProducts 提取UA中所有 name/version 形式的产品标识，键为小写产品名
+func parseProducts(ua string) map[string]string {
+	products := make(map[string]string)
+	for _, token := range strings.FieldsFunc(ua, func(r rune) bool {
+		return r == ' ' || r == '(' || r == ')' || r == ';' || r == ','
+	}) {
+		name, version, found := strings.Cut(token, "/")
+		if !found || name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if _, exists := products[key]; !exists {
+			products[key] = version
+		}
+	}
+	return products
+}
+
+// firstProduct 返回UA中的第一个产品标识
+func firstProduct(ua string) string {
+	if i := strings.IndexAny(ua, " ("); i >= 0 {
+		return ua[:i]
+	}
+	return ua
+}
+
+// parseOS 按注释或平台标识识别操作系统
+func parseOS(ua string) string {
+	lower := strings.ToLower(ua)
+	for _, rule := range osRules {
+		if strings.Contains(lower, rule.marker) {
+			return rule.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package analytics
+
+import "testing"
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want ClientInfo
+	}{
+		{"empty", "  ", ClientInfo{}},
+		{"curl", "curl/8.4.0", ClientInfo{Client: "curl", ClientVersion: "8.4.0", Tool: ToolCLI}},
+		{
+			"pip before its embedded python-requests",
+			`pip/23.3.1 {"implementation":{"name":"CPython"}} python-requests/2.31.0 (Linux x86_64)`,
+			ClientInfo{Client: "pip", ClientVersion: "23.3.1", OS: "Linux", Tool: ToolPackageManager},
+		},
+		{"go library", "Go-http-client/1.1", ClientInfo{Client: "Go-http-client", ClientVersion: "1.1", Tool: ToolLibrary}},
+		{
+			"chrome on windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			ClientInfo{Client: "Chrome", ClientVersion: "120.0.0.0", OS: "Windows", Tool: ToolBrowser},
+		},
+		{
+			"edge wins over chrome and safari",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			ClientInfo{Client: "Edge", ClientVersion: "120.0.2210.91", OS: "Windows", Tool: ToolBrowser},
+		},
+		{
+			"safari version comes from Version/",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			ClientInfo{Client: "Safari", ClientVersion: "17.1", OS: "iOS", Tool: ToolBrowser},
+		},
+		{
+			"firefox on macos",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.1; rv:120.0) Gecko/20100101 Firefox/120.0",
+			ClientInfo{Client: "Firefox", ClientVersion: "120.0", OS: "macOS", Tool: ToolBrowser},
+		},
+		{
+			"android before linux",
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			ClientInfo{Client: "Chrome", ClientVersion: "120.0.0.0", OS: "Android", Tool: ToolBrowser},
+		},
+		{
+			"known bot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			ClientInfo{Client: "Googlebot", ClientVersion: "2.1", Tool: ToolBot},
+		},
+		{"unknown crawler", "ExampleCrawler/0.3", ClientInfo{Client: "ExampleCrawler", ClientVersion: "0.3", Tool: ToolBot}},
+		{"unknown client keeps first product", "MyTool/1.2 (FreeBSD)", ClientInfo{Client: "MyTool", ClientVersion: "1.2", OS: "FreeBSD", Tool: ToolOther}},
+		{"product without version", "internal-mirror", ClientInfo{Client: "internal-mirror", Tool: ToolOther}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseUserAgent(tt.ua); got != tt.want {
+				t.Errorf("ParseUserAgent(%q) = %+v, want %+v", tt.ua, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package authz
+
+import (
+	"errors"
+	"strings"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/models"
+)
+
+// 用户管理相关操作
+const (
+	ActionUserList   = "user.list"
+	ActionUserRead   = "user.read"
+	ActionUserUpdate = "user.update"
+	ActionUserDelete = "user.delete"
+	ActionUserRole   = "user.role" // 修改用户角色，资源同时检查目标用户的当前角色和新角色
+)
+
+// ActionAdminSystem 查看系统配置、运行信息和功能开关
+const ActionAdminSystem = "admin.system"
+
+// 其他管理接口的操作，路由通过RequirePermission声明所需的操作
+const (
+	ActionAdminDiagnostics = "admin.diagnostics" // 运行时诊断、pprof、依赖可用性历史和弃用路由统计
+	ActionStorageRead      = "storage.read"      // 存储分层分布和上传限速配置
+	ActionLicenseRead      = "license.read"      // 无法规范化的许可证值
+	ActionCategoryManage   = "category.manage"   // 创建、修改、删除包分类
+	ActionTrustManage      = "trust.manage"      // 设置用户和包的信任等级
+	ActionPackageModerate  = "package.moderate"  // 修正版本对象键、重建包数据等对他人包的管理操作
+	ActionBillingRead      = "billing.read"      // 查看包的下载流量
+	ActionBillingManage    = "billing.manage"    // 设置包的每月流量上限
+	ActionDataExport       = "data.export"       // BI数据导出（包、版本、下载记录）
+	ActionUserExport       = "user.export"       // 用户列表CSV导出
+	ActionPackageExport    = "package.export"    // 包元数据迁移导出（含私有包、对象键和文件哈希）
+)
+
+// ActionFeatureManage 创建、修改、删除功能开关，默认仅super角色
+const ActionFeatureManage = "feature.manage"
+
+// ActionVersionUnlock 解除版本锁定，默认仅super角色；锁定版本使用ActionPackageModerate
+const ActionVersionUnlock = "package.version_unlock"
+
+// ActionVersionRelease 解除版本的病毒隔离，默认admin和super可用，可通过authz.rules单独收回；重新扫描使用ActionPackageModerate
+const ActionVersionRelease = "package.version_release"
+
+// ErrForbidden 角色没有执行操作的权限
+var ErrForbidden = errors.New("permission denied")
+
+// 规则效果
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Rule 授权规则，Role/Action/Resource为*表示任意，Action以.*结尾表示前缀匹配
+type Rule struct {
+	Role     string
+	Action   string
+	Resource string
+	Allow    bool
+}
+
+// DefaultRules 内置默认策略（角色的权限表）：
+// super允许所有操作；admin可以查看所有用户，但只能管理普通用户和客服，不能管理其他管理员或超级管理员，
+// 除信任等级、用户导出、包迁移导出和系统信息外可以使用其他管理接口；support只能查看用户、下载流量和许可证统计
+var DefaultRules = []Rule{
+	{Role: models.RoleSuper, Action: "*", Resource: "*", Allow: true},
+
+	{Role: models.RoleAdmin, Action: ActionUserList, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionUserRead, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionUserExport, Resource: "*", Allow: false}, // 下面的user.*不包括用户导出
+	{Role: models.RoleAdmin, Action: "user.*", Resource: models.RoleUser, Allow: true},
+	{Role: models.RoleAdmin, Action: "user.*", Resource: models.RoleSupport, Allow: true},
+	{Role: models.RoleAdmin, Action: ActionAdminDiagnostics, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionStorageRead, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionLicenseRead, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionCategoryManage, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionPackageModerate, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionVersionRelease, Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: "billing.*", Resource: "*", Allow: true},
+	{Role: models.RoleAdmin, Action: ActionDataExport, Resource: "*", Allow: true},
+
+	{Role: models.RoleSupport, Action: ActionUserList, Resource: "*", Allow: true},
+	{Role: models.RoleSupport, Action: ActionUserRead, Resource: "*", Allow: true},
+	{Role: models.RoleSupport, Action: ActionBillingRead, Resource: "*", Allow: true},
+	{Role: models.RoleSupport, Action: ActionLicenseRead, Resource: "*", Allow: true},
+}
+
+// Policy 角色授权策略，按顺序匹配规则，第一条匹配的规则决定结果，没有匹配的规则时拒绝
+type Policy struct {
+	rules []Rule
+}
+
+// NewPolicy 创建授权策略，配置中的规则排在默认规则之前，效果不合法的规则会被忽略
+func NewPolicy(cfg config.AuthzConfig) *Policy {
+	rules := make([]Rule, 0, len(cfg.Rules)+len(DefaultRules))
+	for _, r := range cfg.Rules {
+		effect := strings.ToLower(strings.TrimSpace(r.Effect))
+		if effect != EffectAllow && effect != EffectDeny {
+			logger.Warnf("Ignoring authz rule for role %q action %q: invalid effect %q", r.Role, r.Action, r.Effect)
+			continue
+		}
+		rules = append(rules, Rule{
+			Role:     r.Role,
+			Action:   r.Action,
+			Resource: r.Resource,
+			Allow:    effect == EffectAllow,
+		})
+	}
+	rules = append(rules, DefaultRules...)
+	return &Policy{rules: rules}
+}
+
+// Allowed 判断角色能否对资源执行操作
+func (p *Policy) Allowed(role, action, resource string) bool {
+	for _, r := range p.rules {
+		if matchValue(r.Role, role) && matchAction(r.Action, action) && matchValue(r.Resource, resource) {
+			return r.Allow
+		}
+	}
+	return false
+}
+
+// Authorize 与Allowed相同，不允许时返回ErrForbidden，供服务层在加载资源后直接返回错误
+func (p *Policy) Authorize(role, action, resource string) error {
+	if !p.Allowed(role, action, resource) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Permits 判断角色是否可能执行该操作（至少对一类资源允许），用于在加载具体资源前做路由级检查
+// 按资源逐条判断，前面的deny规则只屏蔽其覆盖的资源
+func (p *Policy) Permits(role, action string) bool {
+	for _, r := range p.rules {
+		if !r.Allow || !matchValue(r.Role, role) || !matchAction(r.Action, action) {
+			continue
+		}
+		if r.Resource == "" || r.Resource == "*" {
+			if p.Allowed(role, action, "*") {
+				return true
+			}
+			continue
+		}
+		if p.Allowed(role, action, r.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue 规则值为空或*时匹配任意值
+func matchValue(pattern, value string) bool {
+	return pattern == "" || pattern == "*" || pattern == value
+}
+
+// matchAction 匹配操作，支持*和以.*结尾的前缀匹配
+func matchAction(pattern, action string) bool {
+	if pattern == "*" || pattern == action {
+		return true
+	}
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(action, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package authz
+
+import (
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestDefaultPolicyUserManagement(t *testing.T) {
+	p := NewPolicy(config.AuthzConfig{})
+
+	tests := []struct {
+		role     string
+		action   string
+		resource string
+		want     bool
+	}{
+		{models.RoleSuper, ActionUserDelete, models.RoleSuper, true},
+		{models.RoleSuper, ActionUserRole, models.RoleAdmin, true},
+		{models.RoleAdmin, ActionUserDelete, models.RoleUser, true},
+		{models.RoleAdmin, ActionUserDelete, models.RoleSuper, false},
+		{models.RoleAdmin, ActionUserDelete, models.RoleAdmin, false},
+		{models.RoleAdmin, ActionUserRole, models.RoleSuper, false},
+		{models.RoleAdmin, ActionUserRead, models.RoleSuper, true},
+		{models.RoleAdmin, ActionUserExport, "*", false},
+		{models.RoleSupport, ActionUserDelete, models.RoleUser, false},
+		{models.RoleUser, ActionUserList, "*", false},
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(tt.role, tt.action, tt.resource); got != tt.want {
+			t.Errorf("Allowed(%s, %s, %s) = %v, want %v", tt.role, tt.action, tt.resource, got, tt.want)
+		}
+	}
+}
+
+func TestConfiguredRulesOverrideDefaults(t *testing.T) {
+	p := NewPolicy(config.AuthzConfig{Rules: []config.PolicyRuleConfig{
+		{Role: models.RoleAdmin, Action: ActionUserDelete, Resource: "*", Effect: "deny"},
+		{Role: models.RoleSupport, Action: ActionUserUpdate, Resource: models.RoleUser, Effect: "allow"},
+		{Role: models.RoleSupport, Action: ActionUserDelete, Resource: "*", Effect: "maybe"},
+	}})
+
+	if p.Allowed(models.RoleAdmin, ActionUserDelete, models.RoleUser) {
+		t.Error("configured deny rule did not take precedence over the default allow")
+	}
+	if !p.Allowed(models.RoleAdmin, ActionUserUpdate, models.RoleUser) {
+		t.Error("deny rule for user.delete affected user.update")
+	}
+	if !p.Allowed(models.RoleSupport, ActionUserUpdate, models.RoleUser) {
+		t.Error("configured allow rule not applied")
+	}
+	if p.Allowed(models.RoleSupport, ActionUserDelete, models.RoleUser) {
+		t.Error("rule with an invalid effect was applied")
+	}
+	if p.Permits(models.RoleAdmin, ActionUserDelete) {
+		t.Error("Permits allows an action denied for every resource")
+	}
+	if !p.Permits(models.RoleAdmin, ActionUserUpdate) {
+		t.Error("Permits rejects an action allowed for some resources")
+	}
+}
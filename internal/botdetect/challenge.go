@@ -0,0 +1,131 @@
+package botdetect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 工作量证明错误
+var (
+	ErrChallengeDisabled = errors.New("proof-of-work challenge is not enabled")
+	ErrInvalidProof      = errors.New("invalid proof-of-work")
+	ErrChallengeExpired  = errors.New("challenge has expired")
+	ErrProofReused       = errors.New("proof has already been used")
+)
+
+// Challenge 工作量证明题目：找到nonce使SHA256(challenge + ":" + nonce)至少有Difficulty个前导零比特
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// challenger 签发和校验与客户端IP绑定的工作量证明题目，题目以HMAC签名，无需存储；已使用的答案在过期前拒绝重复使用
+type challenger struct {
+	secret     []byte
+	difficulty int
+	ttl        time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time // 已使用的答案 -> 题目过期时间
+}
+
+// newChallenger 创建工作量证明签发器
+func newChallenger(secret string, difficulty int, ttl time.Duration) *challenger {
+	return &challenger{secret: []byte(secret), difficulty: difficulty, ttl: ttl, used: make(map[string]time.Time)}
+}
+
+// sign 计算题目签名
+func (ch *challenger) sign(ip, expires string) string {
+	mac := hmac.New(sha256.New, ch.secret)
+	mac.Write([]byte(ip + "|" + expires))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// issue 为IP签发题目，格式为 <过期时间戳>.<签名>
+func (ch *challenger) issue(ip string, now time.Time) Challenge {
+	expiresAt := now.Add(ch.ttl).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return Challenge{
+		Challenge:  expires + "." + ch.sign(ip, expires),
+		Difficulty: ch.difficulty,
+		ExpiresAt:  expiresAt.UTC(),
+	}
+}
+
+// verify 校验答案（<challenge>:<nonce>），通过后该答案在题目过期前不能再次使用
+func (ch *challenger) verify(ip, proof string, now time.Time) error {
+	i := strings.LastIndex(proof, ":")
+	if i <= 0 || i == len(proof)-1 {
+		return ErrInvalidProof
+	}
+	challenge := proof[:i]
+	expires, signature, ok := strings.Cut(challenge, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(ch.sign(ip, expires))) {
+		return ErrInvalidProof
+	}
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidProof
+	}
+	expiresAt := time.Unix(expiresUnix, 0)
+	if !now.Before(expiresAt) {
+		return ErrChallengeExpired
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(proof))) < ch.difficulty {
+		return ErrInvalidProof
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	for p, exp := range ch.used {
+		if !now.Before(exp) {
+			delete(ch.used, p)
+		}
+	}
+	if _, seen := ch.used[proof]; seen {
+		return ErrProofReused
+	}
+	ch.used[proof] = expiresAt
+	return nil
+}
+
+// leadingZeroBits 哈希值的前导零比特数
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// ChallengeEnabled 是否启用工作量证明
+func (d *Detector) ChallengeEnabled() bool {
+	return d.Enabled() && d.challenge != nil
+}
+
+// IssueChallenge 为IP签发工作量证明题目
+func (d *Detector) IssueChallenge(ip string) (Challenge, error) {
+	if !d.ChallengeEnabled() {
+		return Challenge{}, ErrChallengeDisabled
+	}
+	return d.challenge.issue(ip, time.Now()), nil
+}
+
+// VerifyProof 校验IP提交的工作量证明答案
+func (d *Detector) VerifyProof(ip, proof string) error {
+	if !d.ChallengeEnabled() {
+		return ErrChallengeDisabled
+	}
+	return d.challenge.verify(ip, proof, time.Now())
+}
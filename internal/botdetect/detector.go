@@ -0,0 +1,235 @@
+package botdetect
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+)
+
+// 标记原因
+const (
+	ReasonBotUserAgent = "bot_user_agent" // User-Agent匹配配置的爬虫模式
+	ReasonRateLimit    = "rate_limit"     // 匿名搜索超过限制
+)
+
+// flaggedTotal 按原因统计的标记次数，同一IP在标记有效期内重复命中同一原因只计一次
+var flaggedTotal = metrics.NewCounterVec("search_protection_flagged_total",
+	"IPs flagged as automated search traffic by reason", "reason")
+
+// window 单个IP或用户在当前窗口内的搜索次数
+type window struct {
+	start time.Time
+	count int
+}
+
+// flagRecord 被标记IP的记录
+type flagRecord struct {
+	reasons   map[string]time.Time // 原因 -> 最近一次命中时间
+	firstSeen time.Time
+	lastSeen  time.Time
+	userAgent string // 最近一次命中时的User-Agent
+}
+
+// Decision 一次搜索请求的限流结果
+type Decision struct {
+	Allowed    bool          // 未超过限制
+	Count      int           // 当前窗口内的请求数（含本次）
+	Limit      int           // 适用的限制
+	RetryAfter time.Duration // 超过限制时距离窗口重置的时间
+}
+
+// IPStatus 单个IP的标记和搜索频率，供管理接口查询
+type IPStatus struct {
+	IP             string               `json:"ip"`
+	Flagged        bool                 `json:"flagged"`
+	Reasons        map[string]time.Time `json:"reasons,omitempty"` // 原因 -> 最近一次命中时间
+	FirstFlaggedAt *time.Time           `json:"first_flagged_at,omitempty"`
+	LastFlaggedAt  *time.Time           `json:"last_flagged_at,omitempty"`
+	ExpiresAt      *time.Time           `json:"expires_at,omitempty"` // 标记失效时间
+	UserAgent      string               `json:"user_agent,omitempty"`
+	WindowCount    int                  `json:"window_count"` // 当前窗口内的匿名搜索次数
+	WindowLimit    int                  `json:"window_limit"`
+}
+
+// Detector 识别自动化搜索流量：按User-Agent匹配爬虫，按IP（登录后按用户）统计搜索频率，并记录被标记的IP
+// 被标记IP和爬虫User-Agent的下载不计入下载数，也就不影响热门包排名
+type Detector struct {
+	enabled            bool
+	patterns           []*regexp.Regexp
+	window             time.Duration
+	anonymousLimit     int
+	authenticatedLimit int
+	flagTTL            time.Duration
+	botCacheTTL        time.Duration
+	challenge          *challenger // 未启用时为nil
+
+	mu      sync.Mutex
+	windows map[string]*window // "ip:<ip>"或"user:<id>" -> 计数窗口
+	flags   map[string]*flagRecord
+}
+
+// New 根据配置创建检测器，无效的User-Agent模式只记录警告并跳过；secret为空时不启用工作量证明
+func New(cfg config.SearchProtectionConfig, secret string) *Detector {
+	d := &Detector{
+		enabled:            cfg.Enabled,
+		window:             cfg.Window,
+		anonymousLimit:     cfg.AnonymousLimit,
+		authenticatedLimit: cfg.AuthenticatedLimit,
+		flagTTL:            cfg.FlagTTL,
+		botCacheTTL:        cfg.BotCacheTTL,
+		windows:            make(map[string]*window),
+		flags:              make(map[string]*flagRecord),
+	}
+	for _, pattern := range cfg.BotUserAgents {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			logger.Warnf("Invalid bot user-agent pattern %q, ignored: %v", pattern, err)
+			continue
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	if cfg.Challenge.Enabled {
+		if cfg.Challenge.Secret != "" {
+			secret = cfg.Challenge.Secret
+		}
+		if secret == "" {
+			logger.Warnf("Search challenge enabled without a secret, proof-of-work disabled")
+		} else {
+			d.challenge = newChallenger(secret, cfg.Challenge.Difficulty, cfg.Challenge.TTL)
+		}
+	}
+	return d
+}
+
+// Enabled 是否启用搜索防护
+func (d *Detector) Enabled() bool {
+	return d != nil && d.enabled
+}
+
+// BotCacheTTL 爬虫请求的搜索结果缓存时间
+func (d *Detector) BotCacheTTL() time.Duration {
+	return d.botCacheTTL
+}
+
+// IsBot User-Agent是否匹配配置的爬虫模式
+func (d *Detector) IsBot(userAgent string) bool {
+	if !d.Enabled() {
+		return false
+	}
+	for _, re := range d.patterns {
+		if re.MatchString(userAgent) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check 计入一次搜索请求并判断是否超过限制，userID为nil时按IP使用匿名限制
+func (d *Detector) Check(ip string, userID *uint) Decision {
+	key, limit := "ip:"+ip, d.anonymousLimit
+	if userID != nil {
+		key, limit = fmt.Sprintf("user:%d", *userID), d.authenticatedLimit
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pruneLocked(now)
+
+	w, ok := d.windows[key]
+	if !ok {
+		w = &window{start: now}
+		d.windows[key] = w
+	}
+	w.count++
+
+	decision := Decision{Allowed: limit <= 0 || w.count <= limit, Count: w.count, Limit: limit}
+	if !decision.Allowed {
+		decision.RetryAfter = d.window - now.Sub(w.start)
+	}
+	return decision
+}
+
+// Flag 标记IP，标记在最近一次命中后flag_ttl内有效
+func (d *Detector) Flag(ip, reason, userAgent string) {
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	record, ok := d.flags[ip]
+	if !ok || now.Sub(record.lastSeen) >= d.flagTTL {
+		record = &flagRecord{reasons: make(map[string]time.Time), firstSeen: now}
+		d.flags[ip] = record
+	}
+	if last, seen := record.reasons[reason]; !seen || now.Sub(last) >= d.flagTTL {
+		flaggedTotal.Inc(reason)
+	}
+	record.reasons[reason] = now
+	record.lastSeen = now
+	record.userAgent = userAgent
+}
+
+// Flagged IP当前是否处于标记状态
+func (d *Detector) Flagged(ip string) bool {
+	if !d.Enabled() {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	record, ok := d.flags[ip]
+	return ok && time.Since(record.lastSeen) < d.flagTTL
+}
+
+// Excluded 请求是否应排除在下载数等统计之外：User-Agent匹配爬虫或IP处于标记状态
+func (d *Detector) Excluded(ip, userAgent string) bool {
+	return d.IsBot(userAgent) || d.Flagged(ip)
+}
+
+// Lookup 查询IP的标记状态和当前窗口内的匿名搜索次数
+func (d *Detector) Lookup(ip string) IPStatus {
+	status := IPStatus{IP: ip, WindowLimit: d.anonymousLimit}
+
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if w, ok := d.windows["ip:"+ip]; ok && now.Sub(w.start) < d.window {
+		status.WindowCount = w.count
+	}
+	record, ok := d.flags[ip]
+	if !ok || now.Sub(record.lastSeen) >= d.flagTTL {
+		return status
+	}
+
+	firstSeen, lastSeen, expiresAt := record.firstSeen, record.lastSeen, record.lastSeen.Add(d.flagTTL)
+	status.Flagged = true
+	status.FirstFlaggedAt, status.LastFlaggedAt, status.ExpiresAt = &firstSeen, &lastSeen, &expiresAt
+	status.UserAgent = record.userAgent
+	status.Reasons = make(map[string]time.Time, len(record.reasons))
+	for reason, at := range record.reasons {
+		if now.Sub(at) < d.flagTTL {
+			status.Reasons[reason] = at
+		}
+	}
+	return status
+}
+
+// pruneLocked 清理已过期的计数窗口和标记，避免map无限增长，调用方需持有锁
+func (d *Detector) pruneLocked(now time.Time) {
+	for k, w := range d.windows {
+		if now.Sub(w.start) >= d.window {
+			delete(d.windows, k)
+		}
+	}
+	for ip, record := range d.flags {
+		if now.Sub(record.lastSeen) >= d.flagTTL {
+			delete(d.flags, ip)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"webservice/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultDialTimeout 未配置dial_timeout时建立连接的超时时间
+const defaultDialTimeout = 5 * time.Second
+
+// NewRedisClient 创建Redis客户端并检查连通性，未配置地址时返回nil
+func NewRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	if cfg.Addr == "" {
+		return nil, nil
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = defaultDialTimeout
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:        cfg.Addr,
+		Password:    cfg.Password,
+		DB:          cfg.DB,
+		DialTimeout: cfg.DialTimeout,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", cfg.Addr, err)
+	}
+	return client, nil
+}
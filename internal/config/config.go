@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -8,12 +10,33 @@ import (
 
 // Config 应用配置结构体
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Log      LogConfig      `mapstructure:"log"`
-	Jaeger   JaegerConfig   `mapstructure:"jaeger"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	MinIO    MinIOConfig    `mapstructure:"minio"`
+	Server      ServerConfig       `mapstructure:"server"`
+	Database    DatabaseConfig     `mapstructure:"database"`
+	Log         LogConfig          `mapstructure:"log"`
+	Jaeger      JaegerConfig       `mapstructure:"jaeger"`
+	JWT         JWTConfig          `mapstructure:"jwt"`
+	MinIO       MinIOConfig        `mapstructure:"minio"`
+	RequestID   RequestIDConfig    `mapstructure:"request_id"`
+	Bootstrap   BootstrapConfig    `mapstructure:"bootstrap"`
+	Deprecation DeprecationConfig  `mapstructure:"deprecation"`
+	Retention   RetentionConfig    `mapstructure:"retention"`
+	Packages    PackagesConfig     `mapstructure:"packages"`
+	Debug       DebugConfig        `mapstructure:"debug"`
+	Password    PasswordConfig     `mapstructure:"password"`
+	Analytics   AnalyticsConfig    `mapstructure:"analytics"`
+	Outbound    OutboundHTTPConfig `mapstructure:"outbound"`
+	Authz       AuthzConfig        `mapstructure:"authz"`
+	Outbox      OutboxConfig       `mapstructure:"outbox"`
+	Integrity   IntegrityConfig    `mapstructure:"integrity"`
+	Export      ExportConfig       `mapstructure:"export"`
+	OAuth       OAuthConfig        `mapstructure:"oauth"`
+	Notify      NotifyConfig       `mapstructure:"notify"`
+	Health      HealthConfig       `mapstructure:"health"`
+
+	SearchProtection SearchProtectionConfig `mapstructure:"search_protection"`
+	IPFilter         IPFilterConfig         `mapstructure:"ip_filter"`
+	GRPC             GRPCConfig             `mapstructure:"grpc"`
+	Redis            RedisConfig            `mapstructure:"redis"`
 }
 
 // ServerConfig 服务器配置
@@ -22,22 +45,36 @@ type ServerConfig struct {
 	Mode         string        `mapstructure:"mode"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	StrictAccept bool          `mapstructure:"strict_accept"` // Accept请求头中的类型都不支持时返回406，否则回退为JSON
+	// StrictStartup 为true时MinIO、链路追踪初始化失败或启动自检未通过都会终止启动
+	StrictStartup bool `mapstructure:"strict_startup"`
+	// StartupBanner 启动日志中构建信息的格式：text（默认，单行文本）或structured（版本、提交等作为独立日志字段，便于日志系统检索）
+	StartupBanner string `mapstructure:"startup_banner"`
+	// TrustedProxies 信任的代理地址或CIDR，只有来自这些地址的请求才按X-Forwarded-For/X-Real-IP解析客户端IP，默认仅本机
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Driver          string        `mapstructure:"driver"`
+	Driver          string        `mapstructure:"driver"` // mysql（默认）或postgres
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
 	Username        string        `mapstructure:"username"`
 	Password        string        `mapstructure:"password"`
 	Database        string        `mapstructure:"database"`
-	Charset         string        `mapstructure:"charset"`
+	Charset         string        `mapstructure:"charset"` // 仅MySQL
+	SSLMode         string        `mapstructure:"sslmode"` // 仅PostgreSQL，默认disable
 	ParseTime       bool          `mapstructure:"parse_time"`
-	Loc             string        `mapstructure:"loc"`
+	Loc             string        `mapstructure:"loc"` // 已固定为UTC，其他取值会被忽略
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	// LogLevel SQL日志级别：silent、error、warn（默认，只记录慢查询和错误）、info（记录所有SQL）
+	LogLevel string `mapstructure:"log_level"`
+	// SlowThreshold 慢查询阈值，默认200ms
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"`
+	// RowLevelSecurity 私有包只对所有者、协作者和管理员可见：PostgreSQL为packages表开启行级安全，其他数据库在应用层为packages表的语句追加条件
+	RowLevelSecurity bool `mapstructure:"row_level_security"`
 }
 
 // LogConfig 日志配置
@@ -50,10 +87,20 @@ type LogConfig struct {
 	MaxBackups int    `mapstructure:"max_backups"`
 	MaxAge     int    `mapstructure:"max_age"`
 	Compress   bool   `mapstructure:"compress"`
+	// Routes 按路由设置请求日志级别，匹配gin的路由模式（c.FullPath()），以*结尾表示前缀匹配
+	Routes []RouteLogConfig `mapstructure:"routes"`
+}
+
+// RouteLogConfig 单个路由的请求日志配置
+type RouteLogConfig struct {
+	Path  string `mapstructure:"path"`
+	Level string `mapstructure:"level"` // skip, debug, info, warn, error
 }
 
 // JaegerConfig Jaeger链路追踪配置
 type JaegerConfig struct {
+	// Enabled 为false时不初始化tracer，追踪中间件也不再为每个请求创建span（默认true）
+	Enabled      bool    `mapstructure:"enabled"`
 	ServiceName  string  `mapstructure:"service_name"`
 	AgentHost    string  `mapstructure:"agent_host"`
 	AgentPort    int     `mapstructure:"agent_port"`
@@ -66,6 +113,72 @@ type JWTConfig struct {
 	Secret     string        `mapstructure:"secret"`
 	ExpireTime time.Duration `mapstructure:"expire_time"`
 	Issuer     string        `mapstructure:"issuer"`
+	// RefreshWindow 距过期不超过该时长的token才能刷新，默认30m
+	RefreshWindow time.Duration `mapstructure:"refresh_window"`
+	// MaxRefreshCount 同一登录最多连续刷新的次数，达到后需重新登录，默认10
+	MaxRefreshCount int `mapstructure:"max_refresh_count"`
+}
+
+// OAuthConfig 外部身份（OAuth/SSO）登录配置
+type OAuthConfig struct {
+	// EmailConflict 外部账号首次登录时邮箱已属于本地账号的处理方式：
+	// reject（默认）返回409，用户需用密码登录后在 /auth/identities 主动关联；link 在提供方确认邮箱已验证时直接关联到该账号
+	EmailConflict string `mapstructure:"email_conflict"`
+}
+
+// NotifyConfig 用户通知配置（如关注的包发布新版本）
+type NotifyConfig struct {
+	// Channel 通知渠道：log（默认，写入日志）或webhook（POST JSON到WebhookURL，由接收方转发为邮件等）
+	Channel    string `mapstructure:"channel"`
+	WebhookURL string `mapstructure:"webhook_url"`
+	// BatchSize 分批读取关注者的数量，默认500
+	BatchSize int `mapstructure:"batch_size"`
+	// Concurrency 同时发送的通知数，默认8
+	Concurrency int `mapstructure:"concurrency"`
+}
+
+// SearchProtectionConfig 包搜索防护：更严格的匿名限流、爬虫User-Agent识别和可选的工作量证明
+type SearchProtectionConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	Window             time.Duration `mapstructure:"window"`              // 计数窗口，默认1m
+	AnonymousLimit     int           `mapstructure:"anonymous_limit"`     // 每个IP在窗口内的匿名搜索次数，默认30
+	AuthenticatedLimit int           `mapstructure:"authenticated_limit"` // 每个登录用户在窗口内的搜索次数，默认300，0表示不限
+	// BotUserAgents 视为爬虫的User-Agent正则（不区分大小写），匹配的请求使用长时间缓存的搜索结果，下载不计数
+	BotUserAgents []string      `mapstructure:"bot_user_agents"`
+	BotCacheTTL   time.Duration `mapstructure:"bot_cache_ttl"` // 爬虫请求的搜索结果缓存时间，默认1h
+	FlagTTL       time.Duration `mapstructure:"flag_ttl"`      // IP最近一次被标记后，多长时间内其下载不计数，默认24h
+
+	Challenge SearchChallengeConfig `mapstructure:"challenge"`
+}
+
+// SearchChallengeConfig 匿名搜索超过限制后的工作量证明，启用后附带有效答案的请求不受匿名限制
+type SearchChallengeConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Difficulty int           `mapstructure:"difficulty"` // 答案哈希的前导零比特数，默认18
+	TTL        time.Duration `mapstructure:"ttl"`        // 题目有效期，默认5m
+	Secret     string        `mapstructure:"secret"`     // 题目签名密钥，为空时使用jwt.secret
+}
+
+// IPFilterConfig 按客户端IP限制访问，适用于只对内网开放的私有仓库
+// 命中deny的地址拒绝，命中allow的地址允许，都未命中时按default_action处理
+type IPFilterConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	DefaultAction string   `mapstructure:"default_action"` // allow（默认，仅拒绝deny中的地址）或deny（仅允许allow中的地址）
+	Allow         []string `mapstructure:"allow"`          // 允许的CIDR或单个IP
+	Deny          []string `mapstructure:"deny"`           // 拒绝的CIDR或单个IP，优先于allow
+	ExemptHealth  bool     `mapstructure:"exempt_health"`  // 为true时/health和/ping不受限制，便于负载均衡探活
+}
+
+// HealthConfig 依赖可用性历史配置，后台任务定期检查数据库和存储并记录结果
+type HealthConfig struct {
+	// SampleInterval 检查间隔，默认30s，0或负数表示不记录
+	SampleInterval time.Duration `mapstructure:"sample_interval"`
+	// HistorySize 内存中保留的最近检查结果数，默认2880（30s间隔时为24小时）
+	HistorySize int `mapstructure:"history_size"`
+	// Persist 同时写入health_checks表，重启后仍可查询，多实例时共享
+	Persist bool `mapstructure:"persist"`
+	// Retention health_checks表中记录的保留时长，默认168h
+	Retention time.Duration `mapstructure:"retention"`
 }
 
 // MinIOConfig MinIO配置
@@ -76,6 +189,339 @@ type MinIOConfig struct {
 	UseSSL     bool   `mapstructure:"use_ssl"`
 	BucketName string `mapstructure:"bucket_name"`
 	Region     string `mapstructure:"region"`
+
+	CompressArtifacts bool `mapstructure:"compress_artifacts"` // 存储前gzip压缩可压缩的制品，下载时透明解压
+	// UploadBandwidthLimitBytesPerSec 单次上传写入MinIO的带宽上限（字节/秒），每次上传独立限速，0表示不限速
+	UploadBandwidthLimitBytesPerSec int64 `mapstructure:"upload_bandwidth_limit_bytes_per_sec"`
+	// ObjectNaming 新上传对象的命名方案：legacy（默认）, flat, hash-sharded；已有对象按数据库中记录的对象键读取
+	ObjectNaming string `mapstructure:"object_naming"`
+
+	// Replicas 只读镜像，下载时与主节点并发请求，取最先响应的结果；bucket与主节点相同
+	Replicas []MinIOReplicaConfig `mapstructure:"replicas"`
+	// 启动时检查bucket失败（如MinIO尚未就绪）的重试，间隔从1s开始指数增长，最长30s
+	StartupRetriesEnabled bool          `mapstructure:"startup_retries_enabled"` // 是否重试，默认true
+	StartupRetries        int           `mapstructure:"startup_retries"`         // 最多重试次数，默认10
+	MaxStartupWait        time.Duration `mapstructure:"max_startup_wait"`        // 重试的最长总等待时间，默认2m，0表示只受重试次数限制
+
+	// ColdTier 冷存储：长期未下载的旧版本由存储分层任务移入单独的bucket
+	ColdTier ColdTierConfig `mapstructure:"cold_tier"`
+
+	// Mirrors 按地域分发下载的镜像，获取下载URL时按客户端所在国家选择同地域的镜像签发；bucket与主节点相同
+	Mirrors      []MirrorConfig     `mapstructure:"mirrors"`
+	MirrorHealth MirrorHealthConfig `mapstructure:"mirror_health"`
+
+	// DownloadCoalescing 同一包版本的并发下载合并为一次存储读取（包括镜像竞速），适用于新版本发布后大量客户端同时下载
+	DownloadCoalescing DownloadCoalescingConfig `mapstructure:"download_coalescing"`
+}
+
+// DownloadCoalescingConfig 并发下载合并配置
+type DownloadCoalescingConfig struct {
+	Enabled bool `mapstructure:"enabled"` // 是否开启，默认false
+	// MaxObjectBytes 只合并不超过该大小的文件，读取期间内容保存在内存中，默认64MB
+	MaxObjectBytes int64 `mapstructure:"max_object_bytes"`
+}
+
+// ColdTierConfig 冷存储配置，Bucket为空时不启用
+type ColdTierConfig struct {
+	Bucket       string        `mapstructure:"bucket"`        // 冷存储bucket，与主bucket位于同一MinIO
+	After        time.Duration `mapstructure:"after"`         // 发布且未被下载超过该时间的版本移入冷存储，默认2160h（90天）
+	KeepRecent   int           `mapstructure:"keep_recent"`   // 每个包最新的N个版本始终保留在主bucket，默认5
+	DownloadMode string        `mapstructure:"download_mode"` // transparent（默认，直接从冷存储读取）或restore（先异步移回主bucket，期间返回202）
+	// RestoreRetryAfter restore模式下返回202时的Retry-After，默认30s
+	RestoreRetryAfter time.Duration `mapstructure:"restore_retry_after"`
+}
+
+// MinIOReplicaConfig MinIO镜像节点配置
+type MinIOReplicaConfig struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+	Region    string `mapstructure:"region"`
+}
+
+// MirrorConfig 下载镜像配置
+type MirrorConfig struct {
+	Name         string `mapstructure:"name"`
+	Endpoint     string `mapstructure:"endpoint"`
+	AccessKey    string `mapstructure:"access_key"`
+	SecretKey    string `mapstructure:"secret_key"`
+	UseSSL       bool   `mapstructure:"use_ssl"`
+	Region       string `mapstructure:"region"`        // 服务的地域：asia, europe, north-america, south-america, oceania, africa
+	Priority     int    `mapstructure:"priority"`      // 同地域有多个镜像时数值小的优先
+	BucketRegion string `mapstructure:"bucket_region"` // 镜像bucket所在的S3区域，用于签名
+}
+
+// MirrorHealthConfig 下载镜像的熔断配置
+type MirrorHealthConfig struct {
+	CheckInterval    time.Duration `mapstructure:"check_interval"`    // 后台检查镜像可用性的间隔，默认30s
+	FailureThreshold int           `mapstructure:"failure_threshold"` // 连续失败多少次后熔断，默认3
+	OpenDuration     time.Duration `mapstructure:"open_duration"`     // 熔断后多久允许重新尝试，默认1m
+}
+
+// RequestIDConfig 请求ID配置
+type RequestIDConfig struct {
+	Format    string `mapstructure:"format"`     // uuid, ksuid
+	MaxLength int    `mapstructure:"max_length"` // 客户端传入请求ID的最大长度，超出则重新生成
+}
+
+// BootstrapConfig 首次启动引导配置
+// 未配置管理员账号时，启动时会生成一次性安装令牌，通过 POST /api/v1/public/setup 创建首个管理员
+type BootstrapConfig struct {
+	AdminUsername string        `mapstructure:"admin_username"`  // 初始管理员用户名（可选，建议通过环境变量注入）
+	AdminEmail    string        `mapstructure:"admin_email"`     // 初始管理员邮箱
+	AdminPassword string        `mapstructure:"admin_password"`  // 初始管理员密码，不提供默认值
+	SetupTokenTTL time.Duration `mapstructure:"setup_token_ttl"` // 安装令牌有效期
+	SeedTestUser  bool          `mapstructure:"seed_test_user"`  // 是否创建测试用户（仅限开发环境）
+
+	// 运行迁移和写入种子数据前等待数据库咨询锁的最长时间，默认1分钟；多个实例同时启动时依次执行，超时后不加锁继续
+	SeedLockTimeout time.Duration `mapstructure:"seed_lock_timeout"`
+}
+
+// PasswordConfig 密码哈希配置
+// 新密码使用Algorithm指定的算法；登录时旧算法或旧参数生成的哈希会自动重新计算
+type PasswordConfig struct {
+	Algorithm  string       `mapstructure:"algorithm"`   // argon2id（默认）, bcrypt
+	BcryptCost int          `mapstructure:"bcrypt_cost"` // bcrypt计算成本，默认10
+	Argon2     Argon2Config `mapstructure:"argon2"`
+}
+
+// Argon2Config Argon2id参数，未配置时使用默认值
+type Argon2Config struct {
+	Time       uint32 `mapstructure:"time"`        // 迭代次数，默认3
+	MemoryKB   uint32 `mapstructure:"memory_kb"`   // 内存开销(KB)，默认65536
+	Threads    uint8  `mapstructure:"threads"`     // 并行度，默认2
+	KeyLength  uint32 `mapstructure:"key_length"`  // 哈希长度，默认32
+	SaltLength uint32 `mapstructure:"salt_length"` // 盐长度，默认16
+}
+
+// OutboundHTTPConfig 出站HTTP请求配置，所有访问外部服务的功能共用，未配置的超时和连接数使用默认值
+type OutboundHTTPConfig struct {
+	ProxyURL              string        `mapstructure:"proxy_url"`               // 出口代理地址，为空时直连（不读取HTTP_PROXY环境变量）
+	DialTimeout           time.Duration `mapstructure:"dial_timeout"`            // 建立TCP连接超时，默认5s
+	TLSHandshakeTimeout   time.Duration `mapstructure:"tls_handshake_timeout"`   // TLS握手超时，默认5s
+	ResponseHeaderTimeout time.Duration `mapstructure:"response_header_timeout"` // 等待响应头超时，默认10s
+	Timeout               time.Duration `mapstructure:"timeout"`                 // 请求总超时（含读取响应体），默认30s，长传输可在调用处覆盖
+	IdleConnTimeout       time.Duration `mapstructure:"idle_conn_timeout"`       // 空闲连接保留时间，默认90s
+	MaxIdleConns          int           `mapstructure:"max_idle_conns"`          // 最大空闲连接数，默认100
+	MaxIdleConnsPerHost   int           `mapstructure:"max_idle_conns_per_host"` // 每个主机最大空闲连接数，默认10
+	CABundle              string        `mapstructure:"ca_bundle"`               // 额外信任的CA证书（PEM），追加到系统根证书
+	BlockPrivateNetworks  bool          `mapstructure:"block_private_networks"`  // 拒绝连接到回环/内网/链路本地地址（连接时按解析结果检查）
+}
+
+// AnalyticsConfig 下载分析配置
+type AnalyticsConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`        // 是否异步解析下载记录的User-Agent与IP归属地
+	GeoIPDatabase string `mapstructure:"geoip_database"` // GeoIP地址段CSV文件路径（start_ip,end_ip,country），为空时不解析国家
+}
+
+// AuthzConfig 管理接口授权策略配置
+type AuthzConfig struct {
+	// Rules 自定义规则，按顺序匹配且优先于内置默认策略
+	Rules []PolicyRuleConfig `mapstructure:"rules"`
+}
+
+// PolicyRuleConfig 授权规则：角色对某类资源执行某个操作时允许或拒绝
+type PolicyRuleConfig struct {
+	Role     string `mapstructure:"role"`     // 角色，*表示任意角色
+	Action   string `mapstructure:"action"`   // 操作，如user.delete；以.*结尾表示前缀匹配，*表示任意操作
+	Resource string `mapstructure:"resource"` // 资源，用户管理操作中为目标用户的角色，*或留空表示任意资源
+	Effect   string `mapstructure:"effect"`   // allow或deny
+}
+
+// GRPCConfig 供内部服务使用的只读gRPC接口配置，与HTTP服务使用不同端口
+type GRPCConfig struct {
+	Enabled bool `mapstructure:"enabled"` // 是否启动gRPC服务，默认false
+	Port    int  `mapstructure:"port"`    // 监听端口，默认9090
+	// MaxBatchSize BatchGetPackages每次最多查询的包数，默认100
+	MaxBatchSize int `mapstructure:"max_batch_size"`
+}
+
+// RedisConfig Redis配置，保存后台任务预先计算好、请求只从缓存读取的数据（如包排行榜）
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr"`     // host:port，为空表示不使用Redis，依赖Redis的接口返回503
+	Password string `mapstructure:"password"` // 密码，为空表示不认证
+	DB       int    `mapstructure:"db"`       // 数据库编号，默认0
+	// DialTimeout 建立连接的超时时间，默认5s
+	DialTimeout time.Duration `mapstructure:"dial_timeout"`
+}
+
+// OutboxConfig 发件箱分发配置，未配置时使用默认值
+type OutboxConfig struct {
+	DispatchInterval time.Duration `mapstructure:"dispatch_interval"` // 分发间隔，默认2s
+	BatchSize        int           `mapstructure:"batch_size"`        // 每次读取的事件数，默认100
+	Retention        time.Duration `mapstructure:"retention"`         // 所有消费者都已处理的事件保留时长，默认168h
+}
+
+// IntegrityConfig 存储完整性校验任务配置，未配置时使用默认值
+type IntegrityConfig struct {
+	Paused               bool          `mapstructure:"paused"`                  // 暂停校验（任务照常调度但不读取对象）
+	Interval             time.Duration `mapstructure:"interval"`                // 执行间隔，默认1h
+	BatchSize            int           `mapstructure:"batch_size"`              // 每次最多校验的版本数，默认20
+	MaxBytesPerRun       int64         `mapstructure:"max_bytes_per_run"`       // 每次最多读取的字节数，0表示只受batch_size限制
+	BandwidthBytesPerSec int64         `mapstructure:"bandwidth_bytes_per_sec"` // 读取对象的带宽上限（字节/秒），0表示不限速
+}
+
+// ExportConfig 管理员数据导出配置，未配置时使用默认值
+type ExportConfig struct {
+	MaxConcurrent int `mapstructure:"max_concurrent"` // 同时执行的导出数，超出时返回429，默认1
+	BatchSize     int `mapstructure:"batch_size"`     // 每批从数据库读取的行数，内存占用与之成正比，默认1000
+	FlushRows     int `mapstructure:"flush_rows"`     // 每写出多少行刷新一次响应，默认1000
+}
+
+// DebugConfig 调试配置
+type DebugConfig struct {
+	Pprof bool `mapstructure:"pprof"` // 是否在/debug/pprof挂载pprof接口（需要管理员权限）
+}
+
+// PackagesConfig 包管理配置
+type PackagesConfig struct {
+	// AliasMode 访问重命名前的旧包名时的处理方式：redirect返回301/308重定向到新路径，transparent直接按新包名处理
+	AliasMode string `mapstructure:"alias_mode"`
+	// InstallDedupWindow 同一用户/IP在窗口内重复完整下载同一版本只计一次安装，默认1h
+	InstallDedupWindow time.Duration `mapstructure:"install_dedup_window"`
+	// ReservedNames 保留的包名（不区分大小写），不能用于创建或重命名包
+	ReservedNames []string `mapstructure:"reserved_names"`
+	// DeletedNameHold 包删除后名称的保留期，期内不能被重新使用，默认720h
+	DeletedNameHold time.Duration `mapstructure:"deleted_name_hold"`
+	// DownloadURLMode 下载链接模式：presign（默认）返回MinIO预签名URL，app-signed返回由应用校验并转发的签名链接
+	DownloadURLMode string `mapstructure:"download_url_mode"`
+	// DownloadURLSecret app-signed模式的HMAC签名密钥，为空时使用JWT密钥
+	DownloadURLSecret string `mapstructure:"download_url_secret"`
+	// DownloadURLTTL 下载链接有效期，默认1h
+	DownloadURLTTL time.Duration `mapstructure:"download_url_ttl"`
+	// PublicBaseURL 生成app-signed下载链接使用的外部访问地址（如 https://pkg.example.com），为空时按请求的Host生成
+	PublicBaseURL string `mapstructure:"public_base_url"`
+	// CustomLicenses 除SPDX标识符外额外接受的许可证标识符（如公司内部许可证）
+	CustomLicenses []string `mapstructure:"custom_licenses"`
+	// AllowedLicenses 许可证允许列表，非空时创建或修改包只能使用其中的许可证（如禁止GPL）
+	AllowedLicenses []string `mapstructure:"allowed_licenses"`
+	// UploadLock 同一包版本并发上传的互斥方式：memory（默认，单实例）、database（数据库咨询锁，多实例共享）、none
+	UploadLock string `mapstructure:"upload_lock"`
+	// UploadLockTimeout 等待同一版本的其他上传完成的最长时间，超时返回409，默认5m
+	UploadLockTimeout time.Duration `mapstructure:"upload_lock_timeout"`
+	// MaxConcurrentDeletes 同时执行的删除包/版本事务数上限，超出时排队或返回503，0表示不限（默认）
+	MaxConcurrentDeletes int `mapstructure:"max_concurrent_deletes"`
+	// DeleteQueueTimeout 删除名额已满时的最长排队时间，超时或请求取消时返回503，0表示立即返回503
+	DeleteQueueTimeout time.Duration `mapstructure:"delete_queue_timeout"`
+	// UserUploadBandwidthLimitBytesPerSec 每个用户所有并发上传合计的带宽上限（字节/秒），0表示不限速
+	UserUploadBandwidthLimitBytesPerSec int64 `mapstructure:"user_upload_bandwidth_limit_bytes_per_sec"`
+	// StorageQuotaBytes 每个用户所有包的版本文件合计大小上限（字节），超过时拒绝上传（413），0表示不限
+	StorageQuotaBytes int64 `mapstructure:"storage_quota_bytes"`
+	// QuotaSoftLimitPercent 用量达到配额的该百分比后，上传响应带有X-Quota-Warning等响应头，默认80
+	QuotaSoftLimitPercent int `mapstructure:"quota_soft_limit_percent"`
+	// RequireIfVersion 修改包和版本元数据时必须携带if_version（乐观锁），未携带返回428；默认false，未携带时最后写入生效
+	RequireIfVersion bool `mapstructure:"require_if_version"`
+	// MaxLongDescriptionLength 包详细介绍（long_description）的最大字符数，默认20000
+	MaxLongDescriptionLength int `mapstructure:"max_long_description_length"`
+	// RequireHTTPSLinks 包的主页、仓库地址等链接只允许https；默认false，允许http和https，其他协议（如javascript:）和包含用户名密码的链接始终拒绝
+	RequireHTTPSLinks bool `mapstructure:"require_https_links"`
+	// EnforceChangelogFormat 上传和修改版本时检查更新日志的Markdown格式（至少一个标题、一个列表项，不含原始HTML），不符合时返回422；默认false
+	EnforceChangelogFormat bool `mapstructure:"enforce_changelog_format"`
+	// MaxChangelogBytes 版本更新日志的最大字节数，默认65536
+	MaxChangelogBytes int `mapstructure:"max_changelog_bytes"`
+	// ChangelogOverflow 更新日志超过最大字节数时的处理方式：reject（默认，返回400）或truncate（截断并追加说明）
+	ChangelogOverflow string `mapstructure:"changelog_overflow"`
+	// DependencyCheck 上传版本时的依赖解析检查：off（默认）、warn（问题记录在版本的dependency_warnings中）、enforce（有冲突时拒绝上传）
+	DependencyCheck string `mapstructure:"dependency_check"`
+	// DependencyCheckMaxDepth 依赖解析的最大深度，默认10
+	DependencyCheckMaxDepth int `mapstructure:"dependency_check_max_depth"`
+	// DependencyCheckTimeout 依赖解析的最长时间，超过时停止解析并记录incomplete警告，默认3s
+	DependencyCheckTimeout time.Duration `mapstructure:"dependency_check_timeout"`
+	// LockAfterMinutes 版本发布后自动锁定（不能删除）的分钟数，默认60，0表示不自动锁定
+	LockAfterMinutes int `mapstructure:"lock_after_minutes"`
+	// StatsCacheTTL 包统计（/packages/stats）的缓存时间，过期后先返回旧结果并在后台重新计算；默认30s，0表示每次请求都重新计算
+	StatsCacheTTL time.Duration `mapstructure:"stats_cache_ttl"`
+	// DownloadRateLimit 每个包的下载限流（所有用户合计），防止热门包占满带宽
+	DownloadRateLimit DownloadRateLimitConfig `mapstructure:"download_rate_limit"`
+	// PresignedUpload 预签名上传：客户端直接上传到存储，完成后回调upload-complete创建版本
+	PresignedUpload PresignedUploadConfig `mapstructure:"presigned_upload"`
+	// Icon 包图标上传限制
+	Icon PackageIconConfig `mapstructure:"icon"`
+	// Scan 上传版本的病毒扫描
+	Scan ScanConfig `mapstructure:"scan"`
+}
+
+// ScanConfig 病毒扫描配置，engine为none时不扫描，版本发布后直接可下载
+type ScanConfig struct {
+	Engine      string        `mapstructure:"engine"`       // 扫描引擎：none（默认）、clamav
+	Mode        string        `mapstructure:"mode"`         // async（默认）上传后后台扫描；sync上传请求等待扫描完成，最多等待sync_timeout
+	SyncTimeout time.Duration `mapstructure:"sync_timeout"` // sync模式的最长等待时间，超时后扫描转入后台继续，默认30s
+	ClamAV      ClamAVConfig  `mapstructure:"clamav"`
+}
+
+// ClamAVConfig clamd连接配置，通过TCP的INSTREAM命令发送文件内容
+type ClamAVConfig struct {
+	Address string        `mapstructure:"address"` // clamd地址，默认127.0.0.1:3310
+	Timeout time.Duration `mapstructure:"timeout"` // 单个文件的扫描超时，默认2m
+}
+
+// PackageIconConfig 包图标配置，支持png、webp和svg
+type PackageIconConfig struct {
+	MaxBytes     int64 `mapstructure:"max_bytes"`     // 图标文件的最大字节数，默认256KiB
+	MaxDimension int   `mapstructure:"max_dimension"` // 图标宽高的最大像素数（svg按width/height属性检查），默认1024
+}
+
+// PresignedUploadConfig 预签名上传配置
+type PresignedUploadConfig struct {
+	URLTTL           time.Duration `mapstructure:"url_ttl"`             // 上传URL和上传会话的有效期，默认1h
+	SyncHashMaxBytes int64         `mapstructure:"sync_hash_max_bytes"` // 不超过该大小的文件在回调请求中同步处理，更大的文件后台处理并返回202，默认64MiB
+}
+
+// DownloadRateLimitConfig 包下载限流配置，requests和bytes都为0时不限流
+type DownloadRateLimitConfig struct {
+	Interval  time.Duration                    `mapstructure:"interval"`  // 固定窗口长度，默认1m
+	Requests  int                              `mapstructure:"requests"`  // 每个包在窗口内的最大下载次数，0表示不限
+	Bytes     int64                            `mapstructure:"bytes"`     // 每个包在窗口内的最大下载字节数，0表示不限
+	Overrides map[string]DownloadRateLimitRule `mapstructure:"overrides"` // 按包名（不区分大小写）覆盖全局限制
+}
+
+// DownloadRateLimitRule 单个包的下载限制，0表示不限
+type DownloadRateLimitRule struct {
+	Requests int   `mapstructure:"requests"`
+	Bytes    int64 `mapstructure:"bytes"`
+}
+
+// RetentionConfig 版本保留配置
+type RetentionConfig struct {
+	PrereleaseMaxAge time.Duration `mapstructure:"prerelease_max_age"` // 预发布版本保留时长，0表示不自动过期
+}
+
+// DeprecationConfig API弃用检查配置
+type DeprecationConfig struct {
+	MapFile string `mapstructure:"map_file"` // 弃用映射文件路径
+}
+
+// DeprecationRule 弃用映射规则（v2中将移除的接口或请求头）
+type DeprecationRule struct {
+	PathPattern     string `mapstructure:"path_pattern"`     // 路径模式，支持path.Match通配符，以/**结尾表示前缀匹配
+	Method          string `mapstructure:"method"`           // 可选，仅匹配指定HTTP方法
+	Header          string `mapstructure:"header"`           // 可选，请求携带该请求头时才视为使用了弃用功能
+	DeprecatedSince string `mapstructure:"deprecated_since"` // 弃用日期（YYYY-MM-DD）
+	RemovalDate     string `mapstructure:"removal_date"`     // 计划移除日期（YYYY-MM-DD）
+	Alternative     string `mapstructure:"alternative"`      // 替代接口路径
+}
+
+// LoadDeprecationMap 加载弃用映射文件，文件不存在时返回空规则
+func LoadDeprecationMap(path string) ([]DeprecationRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var rules []DeprecationRule
+	if err := v.UnmarshalKey("deprecations", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
 }
 
 // Load 加载配置文件
@@ -85,10 +531,39 @@ func Load() (*Config, error) {
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./config")
 
-	// 设置环境变量前缀
+	// 设置环境变量前缀，嵌套键的"."映射为"_"（如 WEBSERVICE_JWT_SECRET）
 	viper.SetEnvPrefix("WEBSERVICE")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
+	viper.SetDefault("server.trusted_proxies", []string{"127.0.0.1", "::1"})
+	viper.SetDefault("jaeger.enabled", true)
+	viper.SetDefault("jwt.refresh_window", 30*time.Minute)
+	viper.SetDefault("jwt.max_refresh_count", 10)
+	viper.SetDefault("integrity.interval", time.Hour)
+	viper.SetDefault("minio.startup_retries_enabled", true)
+	viper.SetDefault("minio.startup_retries", 10)
+	viper.SetDefault("minio.max_startup_wait", 2*time.Minute)
+	viper.SetDefault("minio.mirror_health.check_interval", 30*time.Second)
+	viper.SetDefault("minio.mirror_health.failure_threshold", 3)
+	viper.SetDefault("minio.mirror_health.open_duration", time.Minute)
+	viper.SetDefault("health.sample_interval", 30*time.Second)
+	viper.SetDefault("packages.stats_cache_ttl", 30*time.Second)
+	viper.SetDefault("packages.lock_after_minutes", 60)
+	viper.SetDefault("search_protection.window", time.Minute)
+	viper.SetDefault("search_protection.anonymous_limit", 30)
+	viper.SetDefault("search_protection.authenticated_limit", 300)
+	viper.SetDefault("search_protection.bot_cache_ttl", time.Hour)
+	viper.SetDefault("search_protection.flag_ttl", 24*time.Hour)
+	viper.SetDefault("search_protection.challenge.difficulty", 18)
+	viper.SetDefault("search_protection.challenge.ttl", 5*time.Minute)
+	viper.SetDefault("ip_filter.default_action", "allow")
+	viper.SetDefault("ip_filter.exempt_health", true)
+	viper.SetDefault("minio.download_coalescing.max_object_bytes", 64<<20)
+	viper.SetDefault("grpc.port", 9090)
+	viper.SetDefault("grpc.max_batch_size", 100)
+	viper.SetDefault("redis.dial_timeout", 5*time.Second)
+
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
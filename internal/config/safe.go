@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// maskedValue 脱敏后显示的值
+const maskedValue = "******"
+
+// secretKeys 需要脱敏的配置项（mapstructure键名），只对字符串值生效
+var secretKeys = map[string]bool{
+	"password":            true,
+	"secret":              true,
+	"access_key":          true,
+	"secret_key":          true,
+	"admin_password":      true,
+	"download_url_secret": true,
+}
+
+// urlKeys 可能在userinfo中携带凭据的URL配置项
+var urlKeys = map[string]bool{
+	"proxy_url": true,
+}
+
+// Sanitized 返回脱敏后的配置，键名与配置文件一致
+// 密码、密钥等非空值替换为******，URL中的用户名密码会被去除，未设置的值保持为空以便区分
+func (c *Config) Sanitized() map[string]interface{} {
+	return sanitizeStruct(reflect.ValueOf(*c))
+}
+
+// SafeString 返回脱敏后配置的JSON文本，可安全写入日志或返回给管理接口
+func (c *Config) SafeString() string {
+	data, err := json.MarshalIndent(c.Sanitized(), "", "  ")
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// sanitizeStruct 按mapstructure标签将结构体转换为map并脱敏
+func sanitizeStruct(v reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{}, v.NumField())
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		result[key] = sanitizeValue(key, v.Field(i))
+	}
+	return result
+}
+
+// sanitizeValue 转换单个配置值，时长输出为可读字符串
+func sanitizeValue(key string, v reflect.Value) interface{} {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return sanitizeStruct(v)
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items[i] = sanitizeValue(key, v.Index(i))
+		}
+		return items
+	case reflect.String:
+		s := v.String()
+		if s == "" {
+			return s
+		}
+		if secretKeys[key] {
+			return maskedValue
+		}
+		if urlKeys[key] {
+			return redactURL(s)
+		}
+		return s
+	default:
+		return v.Interface()
+	}
+}
+
+// redactURL 去除URL中的用户名密码，无法解析时整体脱敏
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return maskedValue
+	}
+	if u.User != nil {
+		u.User = url.User(maskedValue)
+	}
+	return u.String()
+}
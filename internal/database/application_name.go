@@ -0,0 +1,48 @@
+package database
+
+import (
+	"webservice/internal/requestid"
+
+	"gorm.io/gorm"
+)
+
+// maxApplicationNameLength PostgreSQL application_name的最大长度（NAMEDATALEN-1）
+const maxApplicationNameLength = 63
+
+// RegisterApplicationNameCallbacks 注册gorm回调（仅PostgreSQL），在事务中执行写操作前把当前请求ID设置为application_name，
+// 使请求ID出现在pg_stat_activity中；使用事务级设置（等同SET LOCAL），事务结束后自动恢复
+func RegisterApplicationNameCallbacks(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	callbacks := db.Callback()
+	if err := callbacks.Create().After("gorm:begin_transaction").Register("requestid:application_name_create", setApplicationName); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:begin_transaction").Register("requestid:application_name_update", setApplicationName); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:begin_transaction").Register("requestid:application_name_delete", setApplicationName)
+}
+
+// setApplicationName 在当前事务中设置application_name，不在事务中或没有请求ID时跳过
+// SET LOCAL不支持参数绑定，使用等价的set_config(..., true)以避免拼接SQL
+func setApplicationName(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); !inTx {
+		return
+	}
+	requestID := requestid.FromContext(db.Statement.Context)
+	if requestID == "" {
+		return
+	}
+	if len(requestID) > maxApplicationNameLength {
+		requestID = requestID[:maxApplicationNameLength]
+	}
+
+	// 设置失败不影响业务SQL，只是pg_stat_activity中看不到请求ID
+	_, _ = db.Statement.ConnPool.ExecContext(db.Statement.Context, "SELECT set_config('application_name', $1, true)", requestID)
+}
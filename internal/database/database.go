@@ -1,37 +1,42 @@
 package database
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"webservice/internal/config"
+	"webservice/internal/logger"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 // Init 初始化数据库连接
 func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
-	// 构建DSN
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=%s",
-		cfg.Username,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-		cfg.Database,
-		cfg.Charset,
-		cfg.ParseTime,
-		cfg.Loc,
-	)
+	// 时间统一按UTC读写，统计窗口和接口返回的时间不随部署时区变化
+	if cfg.Loc != "" && !strings.EqualFold(cfg.Loc, "UTC") {
+		logger.Warnf("database.loc %q is ignored, timestamps are always stored and read as UTC", cfg.Loc)
+	}
 
 	// 配置GORM
 	gormConfig := &gorm.Config{
-		Logger:                                   logger.Default.LogMode(logger.Silent), // 完全禁用SQL日志以提高性能
-		DisableForeignKeyConstraintWhenMigrating: true,                                  // 禁用外键约束检查加快迁移
+		Logger:                                   newGormLogger(cfg.LogLevel, cfg.SlowThreshold), // SQL日志带有request_id，默认只记录慢查询和错误
+		DisableForeignKeyConstraintWhenMigrating: true,                                           // 禁用外键约束检查加快迁移
+		NowFunc:                                  func() time.Time { return time.Now().UTC() },   // CreatedAt/UpdatedAt等自动时间戳使用UTC
 	}
 
 	// 连接数据库
-	db, err := gorm.Open(mysql.Open(dsn), gormConfig)
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -52,9 +57,58 @@ func Init(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	// 注册链路追踪回调，SQL作为服务层span的子span记录
+	if err := RegisterTracingCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register tracing callbacks: %w", err)
+	}
+
+	// PostgreSQL事务中设置application_name为请求ID
+	if err := RegisterApplicationNameCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register application_name callbacks: %w", err)
+	}
+
+	// 行级安全：PostgreSQL按请求的访问范围设置会话变量，其他数据库在应用层限制packages表的可见行
+	if cfg.RowLevelSecurity {
+		if err := RegisterRowSecurityCallbacks(db); err != nil {
+			return nil, fmt.Errorf("failed to register row level security callbacks: %w", err)
+		}
+	}
+
 	return db, nil
 }
 
+// openDialector 按database.driver选择驱动：mysql（默认）或postgres，会话时区固定为UTC
+func openDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "mysql":
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=UTC",
+			cfg.Username,
+			cfg.Password,
+			cfg.Host,
+			cfg.Port,
+			cfg.Database,
+			cfg.Charset,
+			cfg.ParseTime,
+		)
+		return mysql.Open(dsn), nil
+	case "postgres", "postgresql":
+		sslMode := cfg.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.Username, cfg.Password),
+			Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+			Path:     "/" + cfg.Database,
+			RawQuery: url.Values{"sslmode": {sslMode}, "TimeZone": {"UTC"}}.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q (supported: mysql, postgres)", cfg.Driver)
+	}
+}
+
 // AutoMigrate 自动迁移数据库表结构
 func AutoMigrate(db *gorm.DB, models ...interface{}) error {
 	return db.AutoMigrate(models...)
@@ -68,3 +122,15 @@ func Close(db *gorm.DB) error {
 	}
 	return sqlDB.Close()
 }
+
+// Probe 执行一次简单查询，验证数据库连接可用
+func Probe(ctx context.Context, db *gorm.DB) error {
+	var result int
+	if err := db.WithContext(ctx).Raw("SELECT 1").Scan(&result).Error; err != nil {
+		return fmt.Errorf("failed to query database: %w", err)
+	}
+	if result != 1 {
+		return fmt.Errorf("unexpected probe result: %d", result)
+	}
+	return nil
+}
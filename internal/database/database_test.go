@@ -0,0 +1,52 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+)
+
+func TestOpenDialectorSelectsDriver(t *testing.T) {
+	cfg := config.DatabaseConfig{Host: "db", Port: 5432, Username: "app", Password: "p@ss word", Database: "webservice", Charset: "utf8mb4", ParseTime: true}
+
+	for _, driver := range []string{"", "mysql", "MySQL"} {
+		cfg.Driver = driver
+		dialector, err := openDialector(cfg)
+		if err != nil {
+			t.Fatalf("driver %q: %v", driver, err)
+		}
+		if dialector.Name() != "mysql" {
+			t.Errorf("driver %q opened %s, want mysql", driver, dialector.Name())
+		}
+		if dsn := dialector.(*mysql.Dialector).DSN; !strings.Contains(dsn, "loc=UTC") {
+			t.Errorf("mysql DSN %q does not pin the session time zone to UTC", dsn)
+		}
+	}
+
+	// 使用PostgreSQL时application_name和行级安全回调才会生效
+	for _, driver := range []string{"postgres", "postgresql"} {
+		cfg.Driver = driver
+		dialector, err := openDialector(cfg)
+		if err != nil {
+			t.Fatalf("driver %q: %v", driver, err)
+		}
+		if dialector.Name() != "postgres" {
+			t.Errorf("driver %q opened %s, want postgres", driver, dialector.Name())
+		}
+		dsn := dialector.(*postgres.Dialector).DSN
+		for _, want := range []string{"postgres://app:p%40ss%20word@db:5432/webservice", "sslmode=disable", "TimeZone=UTC"} {
+			if !strings.Contains(dsn, want) {
+				t.Errorf("postgres DSN %q does not contain %q", dsn, want)
+			}
+		}
+	}
+
+	cfg.Driver = "sqlserver"
+	if _, err := openDialector(cfg); err == nil {
+		t.Error("unsupported driver was accepted")
+	}
+}
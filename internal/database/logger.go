@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"webservice/internal/logger"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// defaultSlowThreshold 未配置时的慢查询阈值
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// gormLogger 将GORM日志写入应用日志，每条SQL日志带有发起请求的request_id，便于将慢查询与HTTP请求关联
+type gormLogger struct {
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// newGormLogger 创建GORM日志，level为silent、error、warn（默认，只记录慢查询和错误）或info（记录所有SQL）
+func newGormLogger(level string, slowThreshold time.Duration) *gormLogger {
+	if slowThreshold <= 0 {
+		slowThreshold = defaultSlowThreshold
+	}
+	l := &gormLogger{level: gormlogger.Warn, slowThreshold: slowThreshold}
+	switch level {
+	case "silent":
+		l.level = gormlogger.Silent
+	case "error":
+		l.level = gormlogger.Error
+	case "info":
+		l.level = gormlogger.Info
+	}
+	return l
+}
+
+// LogMode 返回指定级别的日志实例
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+// Info 记录信息日志
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		logger.FromContext(ctx).Infof(msg, args...)
+	}
+}
+
+// Warn 记录警告日志
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		logger.FromContext(ctx).Warnf(msg, args...)
+	}
+}
+
+// Error 记录错误日志
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		logger.FromContext(ctx).Errorf(msg, args...)
+	}
+}
+
+// Trace 记录SQL执行结果：出错记录为error，超过慢查询阈值记录为warn，info级别记录所有SQL
+// 记录不存在（gorm.ErrRecordNotFound）属于正常的业务结果，不记录为错误
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := elapsed > l.slowThreshold
+	switch {
+	case failed && l.level >= gormlogger.Error:
+	case slow && l.level >= gormlogger.Warn:
+	case l.level >= gormlogger.Info:
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	entry := logger.FromContext(ctx).WithFields(logrus.Fields{
+		"sql":        sanitizeStatement(sql),
+		"rows":       rows,
+		"elapsed_ms": float64(elapsed.Microseconds()) / 1000,
+	})
+	switch {
+	case failed:
+		entry.WithError(err).Error("SQL error")
+	case slow:
+		entry.Warnf("Slow SQL (>%s)", l.slowThreshold)
+	default:
+		entry.Info("SQL")
+	}
+}
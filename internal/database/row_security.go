@@ -0,0 +1,209 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rowScopeConnKey 在gorm语句实例中保存为设置行级安全变量而独占的连接的键
+const rowScopeConnKey = "row_security:conn"
+
+// rowScopeKey 在context.Context中存储行级安全访问范围的键类型
+type rowScopeKey struct{}
+
+// RowScope 请求对packages表的行级安全访问范围
+type RowScope struct {
+	UserID uint // 当前用户，0表示匿名
+	Admin  bool // 管理员不受行级安全限制
+}
+
+// WithRowScope 将访问范围写入context
+// 没有访问范围的context（后台任务、迁移）不受行级安全限制，HTTP请求在进入路由时就写入匿名范围
+func WithRowScope(ctx context.Context, scope RowScope) context.Context {
+	return context.WithValue(ctx, rowScopeKey{}, scope)
+}
+
+// rowScopeFromContext 从context中读取访问范围
+func rowScopeFromContext(ctx context.Context) (RowScope, bool) {
+	if ctx == nil {
+		return RowScope{}, false
+	}
+	scope, ok := ctx.Value(rowScopeKey{}).(RowScope)
+	return scope, ok
+}
+
+// RegisterRowSecurityCallbacks 注册gorm回调，按context中的访问范围限制packages表的可见行
+// PostgreSQL：每条SQL执行前设置app.user_id和app.bypass_rls，供packages表的行级安全策略使用（见migration.ConfigureRowLevelSecurity）；
+// 事务中使用事务级设置，不在事务中时为该语句独占一个连接并设置会话级变量，语句结束后归还，每条语句都会重新设置，不会沿用上一次的值
+// 其他数据库没有行级安全，改为在应用层为packages表的查询、更新和删除追加相同的可见性条件（见RegisterRowVisibilityCallbacks）
+func RegisterRowSecurityCallbacks(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return RegisterRowVisibilityCallbacks(db)
+	}
+
+	callbacks := db.Callback()
+	// 写操作默认在事务中执行，在开启事务后设置
+	if err := callbacks.Create().After("gorm:begin_transaction").Register("row_security:before_create", setRowScope); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("*").Register("row_security:after_create", releaseRowScopeConn); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:begin_transaction").Register("row_security:before_update", setRowScope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("*").Register("row_security:after_update", releaseRowScopeConn); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:begin_transaction").Register("row_security:before_delete", setRowScope); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("*").Register("row_security:after_delete", releaseRowScopeConn); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("*").Register("row_security:before_query", setRowScope); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("*").Register("row_security:after_query", releaseRowScopeConn); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("*").Register("row_security:before_raw", setRowScope); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().After("*").Register("row_security:after_raw", releaseRowScopeConn); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("*").Register("row_security:before_row", setRowScope); err != nil {
+		return err
+	}
+	return callbacks.Row().After("*").Register("row_security:after_row", releaseRowScopeConn)
+}
+
+// rowScopeSettings 返回访问范围对应的app.user_id和app.bypass_rls
+func rowScopeSettings(ctx context.Context) (userID, bypass string) {
+	scope, ok := rowScopeFromContext(ctx)
+	if !ok || scope.Admin {
+		return "", "on"
+	}
+	if scope.UserID == 0 {
+		return "", "off"
+	}
+	return strconv.FormatUint(uint64(scope.UserID), 10), "off"
+}
+
+// setRowScope 在执行SQL前设置行级安全变量，设置失败时语句不执行
+func setRowScope(db *gorm.DB) {
+	if db.Error != nil || db.DryRun {
+		return
+	}
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	userID, bypass := rowScopeSettings(ctx)
+
+	// 事务中使用事务级设置（等同SET LOCAL），事务结束后自动恢复
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		if _, err := db.Statement.ConnPool.ExecContext(ctx, "SELECT set_config('app.user_id', $1, true), set_config('app.bypass_rls', $2, true)", userID, bypass); err != nil {
+			_ = db.AddError(fmt.Errorf("failed to set row level security scope: %w", err))
+		}
+		return
+	}
+
+	const query = "SELECT set_config('app.user_id', $1, false), set_config('app.bypass_rls', $2, false)"
+	switch pool := db.Statement.ConnPool.(type) {
+	case *sql.Conn:
+		// 预加载等嵌套语句沿用外层语句独占的连接
+		if _, err := pool.ExecContext(ctx, query, userID, bypass); err != nil {
+			_ = db.AddError(fmt.Errorf("failed to set row level security scope: %w", err))
+		}
+	case *sql.DB:
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			_ = db.AddError(fmt.Errorf("failed to get connection for row level security: %w", err))
+			return
+		}
+		if _, err := conn.ExecContext(ctx, query, userID, bypass); err != nil {
+			conn.Close()
+			_ = db.AddError(fmt.Errorf("failed to set row level security scope: %w", err))
+			return
+		}
+		db.Statement.ConnPool = conn
+		db.InstanceSet(rowScopeConnKey, conn)
+	default:
+		_ = db.AddError(errors.New("row level security requires a *sql.DB connection pool"))
+	}
+}
+
+// releaseRowScopeConn 归还setRowScope独占的连接
+// Row/Rows返回时结果还未读取，连接在结果关闭后才能归还，因此在后台等待
+func releaseRowScopeConn(db *gorm.DB) {
+	value, ok := db.InstanceGet(rowScopeConnKey)
+	if !ok {
+		return
+	}
+	conn, ok := value.(*sql.Conn)
+	if !ok {
+		return
+	}
+	db.Statement.ConnPool = db.ConnPool
+	go conn.Close()
+}
+
+// rowSecurityTable 受行级安全限制的表
+const rowSecurityTable = "packages"
+
+// RegisterRowVisibilityCallbacks 注册gorm回调，在应用层实现与pkg_access策略相同的可见性：
+// 有访问范围且不是管理员时，packages表的查询（包括预加载和计数）、更新和删除只作用于公开包和当前用户拥有或作为协作者的包
+// 原生SQL和从其他表JOIN packages的查询不受限制，只能依靠服务层的权限检查
+func RegisterRowVisibilityCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("row_security:visible_query", restrictPackageRows); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("row_security:visible_update", restrictPackageRows); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("row_security:visible_delete", restrictPackageRows)
+}
+
+// restrictPackageRows 为packages表的语句追加可见性条件：公开包、所有者为当前用户或当前用户是协作者，匿名请求只能看到公开包
+func restrictPackageRows(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Table != rowSecurityTable {
+		return
+	}
+	ctx := db.Statement.Context
+	if ctx == nil {
+		return
+	}
+	scope, ok := rowScopeFromContext(ctx)
+	if !ok || scope.Admin {
+		return
+	}
+
+	var condition clause.Expression = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "is_private"}, Value: false}
+	if scope.UserID != 0 {
+		owned := clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "owner_id"}, Value: scope.UserID}
+		collaborator := clause.Expr{
+			SQL:  "? IN (SELECT package_id FROM package_collaborators WHERE user_id = ?)",
+			Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: "id"}, scope.UserID},
+		}
+		condition = clause.Or(condition, owned, collaborator)
+	}
+
+	// 语句原有的条件作为一组与可见性条件AND，避免其中的OR条件绕过限制
+	where := clause.Where{Exprs: []clause.Expression{condition}}
+	existing := db.Statement.Clauses["WHERE"]
+	if current, ok := existing.Expression.(clause.Where); ok && len(current.Exprs) > 0 {
+		where.Exprs = []clause.Expression{clause.And(current.Exprs...), condition}
+	}
+	existing.Name = "WHERE"
+	existing.Expression = where
+	db.Statement.Clauses["WHERE"] = existing
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// newRowSecurityDB 创建开启应用层行级安全的测试数据库，owner拥有公开包public和私有包secret
+func newRowSecurityDB(t *testing.T) (*gorm.DB, *models.User, *models.User) {
+	t.Helper()
+	db := testutil.NewDB(t, &models.User{}, &models.Package{}, &models.PackageCollaborator{})
+	if err := RegisterRowSecurityCallbacks(db); err != nil {
+		t.Fatalf("RegisterRowSecurityCallbacks: %v", err)
+	}
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "x", Role: models.RoleUser}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "x", Role: models.RoleUser}
+	for _, user := range []*models.User{owner, other} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	for _, pkg := range []*models.Package{
+		{Name: "public", OwnerID: owner.ID},
+		{Name: "secret", OwnerID: owner.ID, IsPrivate: true},
+	} {
+		if err := db.Create(pkg).Error; err != nil {
+			t.Fatalf("failed to create package: %v", err)
+		}
+	}
+	return db, owner, other
+}
+
+func TestRowVisibilityByScope(t *testing.T) {
+	db, owner, other := newRowSecurityDB(t)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		visible []string
+	}{
+		{"background job", context.Background(), []string{"public", "secret"}},
+		{"anonymous", WithRowScope(context.Background(), RowScope{}), []string{"public"}},
+		{"other user", WithRowScope(context.Background(), RowScope{UserID: other.ID}), []string{"public"}},
+		{"owner", WithRowScope(context.Background(), RowScope{UserID: owner.ID}), []string{"public", "secret"}},
+		{"admin", WithRowScope(context.Background(), RowScope{UserID: other.ID, Admin: true}), []string{"public", "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			if err := db.WithContext(tt.ctx).Model(&models.Package{}).Order("name").Pluck("name", &names).Error; err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if len(names) != len(tt.visible) {
+				t.Fatalf("visible packages = %v, want %v", names, tt.visible)
+			}
+			for i := range names {
+				if names[i] != tt.visible[i] {
+					t.Fatalf("visible packages = %v, want %v", names, tt.visible)
+				}
+			}
+
+			var count int64
+			if err := db.WithContext(tt.ctx).Model(&models.Package{}).Count(&count).Error; err != nil {
+				t.Fatalf("count failed: %v", err)
+			}
+			if count != int64(len(tt.visible)) {
+				t.Errorf("count = %d, want %d", count, len(tt.visible))
+			}
+		})
+	}
+}
+
+func TestRowVisibilityIncludesCollaborators(t *testing.T) {
+	db, _, other := newRowSecurityDB(t)
+	var secret models.Package
+	if err := db.Where("name = ?", "secret").First(&secret).Error; err != nil {
+		t.Fatalf("failed to find package: %v", err)
+	}
+	ctx := WithRowScope(context.Background(), RowScope{UserID: other.ID})
+
+	if err := db.WithContext(ctx).Where("name = ?", "secret").First(&models.Package{}).Error; err == nil {
+		t.Fatal("private package visible before the user became a collaborator")
+	}
+	collaborator := &models.PackageCollaborator{PackageID: secret.ID, UserID: other.ID, Role: models.CollaboratorRoleReader}
+	if err := db.Create(collaborator).Error; err != nil {
+		t.Fatalf("failed to add collaborator: %v", err)
+	}
+	if err := db.WithContext(ctx).Where("name = ?", "secret").First(&models.Package{}).Error; err != nil {
+		t.Errorf("collaborator cannot see private package: %v", err)
+	}
+}
+
+func TestRowVisibilityCombinesWithOrConditions(t *testing.T) {
+	db, _, other := newRowSecurityDB(t)
+	ctx := WithRowScope(context.Background(), RowScope{UserID: other.ID})
+
+	// 查询自身的OR条件不能绕过可见性条件
+	var pkgs []models.Package
+	if err := db.WithContext(ctx).Where("name = ?", "secret").Or("name = ?", "public").Find(&pkgs).Error; err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "public" {
+		t.Errorf("got %d packages, want only public", len(pkgs))
+	}
+}
+
+func TestRowVisibilityRestrictsWrites(t *testing.T) {
+	db, owner, other := newRowSecurityDB(t)
+	otherCtx := WithRowScope(context.Background(), RowScope{UserID: other.ID})
+
+	result := db.WithContext(otherCtx).Model(&models.Package{}).Where("name = ?", "secret").Update("description", "leaked")
+	if result.Error != nil {
+		t.Fatalf("update failed: %v", result.Error)
+	}
+	if result.RowsAffected != 0 {
+		t.Errorf("updated %d invisible rows, want 0", result.RowsAffected)
+	}
+	result = db.WithContext(otherCtx).Where("name = ?", "secret").Delete(&models.Package{})
+	if result.Error != nil {
+		t.Fatalf("delete failed: %v", result.Error)
+	}
+	if result.RowsAffected != 0 {
+		t.Errorf("deleted %d invisible rows, want 0", result.RowsAffected)
+	}
+
+	ownerCtx := WithRowScope(context.Background(), RowScope{UserID: owner.ID})
+	result = db.WithContext(ownerCtx).Model(&models.Package{}).Where("name = ?", "secret").Update("description", "updated")
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Errorf("owner update affected %d rows (err %v), want 1", result.RowsAffected, result.Error)
+	}
+}
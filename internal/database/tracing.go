@@ -0,0 +1,107 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"gorm.io/gorm"
+)
+
+// tracingSpanKey 在gorm语句实例中保存当前SQL span的键
+const tracingSpanKey = "tracing:span"
+
+// maxStatementLength db.statement标签的最大长度
+const maxStatementLength = 1024
+
+// RegisterTracingCallbacks 注册gorm回调，为携带span的context（db.WithContext）中的每条SQL创建子span
+// 语句中的参数保持为占位符，标签中不会出现实际值
+func RegisterTracingCallbacks(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("*").Register("tracing:before_create", startSQLSpan("create")); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("*").Register("tracing:after_create", finishSQLSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("*").Register("tracing:before_query", startSQLSpan("query")); err != nil {
+		return err
+	}
+	if err := callbacks.Query().After("*").Register("tracing:after_query", finishSQLSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("*").Register("tracing:before_update", startSQLSpan("update")); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("*").Register("tracing:after_update", finishSQLSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("*").Register("tracing:before_delete", startSQLSpan("delete")); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("*").Register("tracing:after_delete", finishSQLSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("*").Register("tracing:before_row", startSQLSpan("row")); err != nil {
+		return err
+	}
+	if err := callbacks.Row().After("*").Register("tracing:after_row", finishSQLSpan); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("*").Register("tracing:before_raw", startSQLSpan("raw")); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("*").Register("tracing:after_raw", finishSQLSpan)
+}
+
+// startSQLSpan 返回在SQL执行前创建子span的回调，context中没有span时不记录
+func startSQLSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			return
+		}
+		parent := opentracing.SpanFromContext(ctx)
+		if parent == nil {
+			return
+		}
+
+		span := opentracing.StartSpan("gorm:"+operation, opentracing.ChildOf(parent.Context()))
+		ext.DBType.Set(span, db.Dialector.Name())
+		ext.SpanKindRPCClient.Set(span)
+		if db.Statement.Table != "" {
+			span.SetTag("db.table", db.Statement.Table)
+		}
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+// finishSQLSpan 在SQL执行后记录语句、影响行数和错误并结束span
+func finishSQLSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(opentracing.Span)
+	if !ok {
+		return
+	}
+	defer span.Finish()
+
+	// 语句在执行阶段才生成，因此在结束时设置
+	ext.DBStatement.Set(span, sanitizeStatement(db.Statement.SQL.String()))
+	span.SetTag("db.rows_affected", db.Statement.RowsAffected)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		ext.Error.Set(span, true)
+		span.SetTag("error.message", db.Error.Error())
+	}
+}
+
+// sanitizeStatement 截断过长的SQL，参数以占位符形式保留
+func sanitizeStatement(statement string) string {
+	if len(statement) > maxStatementLength {
+		return statement[:maxStatementLength] + "..."
+	}
+	return statement
+}
@@ -0,0 +1,162 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"webservice/internal/logger"
+)
+
+// EventType 事件类型
+type EventType string
+
+const (
+	// PackageCreated 包已创建
+	PackageCreated EventType = "package.created"
+	// VersionUploaded 版本已上传
+	VersionUploaded EventType = "version.uploaded"
+	// PackageDeleted 包已删除
+	PackageDeleted EventType = "package.deleted"
+	// VersionYanked 版本已撤回（删除）
+	VersionYanked EventType = "version.yanked"
+	// PackageRenamed 包已重命名，Payload中old_name为原包名
+	PackageRenamed EventType = "package.renamed"
+	// PackageArchived 包已归档
+	PackageArchived EventType = "package.archived"
+	// PackageUnarchived 包已取消归档
+	PackageUnarchived EventType = "package.unarchived"
+	// PackageReindexed 管理员重建了包的派生状态，消费者应按数据库中的当前状态重新处理该包
+	PackageReindexed EventType = "package.reindexed"
+	// DownloadRecorded 下载记录已写入，Payload中download_id为下载记录ID
+	DownloadRecorded EventType = "download.recorded"
+	// VersionQuarantined 版本扫描发现病毒已被隔离，Payload中signature为病毒特征名
+	VersionQuarantined EventType = "version.quarantined"
+)
+
+// DefaultWorkers 默认订阅者执行协程数
+const DefaultWorkers = 8
+
+// defaultQueueSize 待执行事件队列长度
+const defaultQueueSize = 1024
+
+// Event 事件
+type Event struct {
+	ID          uint                   `json:"id,omitempty"` // 发件箱中的事件ID，经发件箱投递的事件才有，消费者据此去重
+	Type        EventType              `json:"type"`
+	PackageID   uint                   `json:"package_id,omitempty"`
+	PackageName string                 `json:"package_name"`
+	Version     string                 `json:"version,omitempty"`
+	UserID      uint                   `json:"user_id,omitempty"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	OccurredAt  time.Time              `json:"occurred_at"`
+}
+
+// EventHandler 事件处理函数
+type EventHandler func(event Event)
+
+// Publisher 事件发布接口，PackageService等依赖该接口以便替换为NullEventBus
+type Publisher interface {
+	Publish(event Event)
+}
+
+// job 待执行的订阅任务
+type job struct {
+	handler EventHandler
+	event   Event
+}
+
+// EventBus 进程内事件总线
+// 订阅者在有界协程池中异步执行，不阻塞发布方
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[EventType][]EventHandler
+	jobs     chan job
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+// NewEventBus 创建事件总线，workers为订阅者执行协程数
+func NewEventBus(workers int) *EventBus {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	bus := &EventBus{
+		handlers: make(map[EventType][]EventHandler),
+		jobs:     make(chan job, defaultQueueSize),
+	}
+
+	for i := 0; i < workers; i++ {
+		bus.wg.Add(1)
+		go bus.worker()
+	}
+
+	return bus
+}
+
+// Subscribe 订阅事件
+func (b *EventBus) Subscribe(eventType EventType, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish 发布事件，队列已满时丢弃并记录告警
+func (b *EventBus) Publish(event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, handler := range b.handlers[event.Type] {
+		select {
+		case b.jobs <- job{handler: handler, event: event}:
+		default:
+			logger.Warnf("Event queue full, dropping %s event for package %s", event.Type, event.PackageName)
+		}
+	}
+}
+
+// Close 停止接收事件并等待已入队的订阅任务执行完毕
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.jobs)
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// worker 订阅任务执行协程
+func (b *EventBus) worker() {
+	defer b.wg.Done()
+	for j := range b.jobs {
+		b.run(j)
+	}
+}
+
+// run 执行单个订阅任务，订阅者panic不影响其他任务
+func (b *EventBus) run(j job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Event handler for %s panicked: %v", j.event.Type, r)
+		}
+	}()
+	j.handler(j.event)
+}
+
+// NullEventBus 丢弃所有事件的空实现，用于单元测试
+type NullEventBus struct{}
+
+// Publish 丢弃事件
+func (NullEventBus) Publish(event Event) {}
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventBusDeliversToSubscribers(t *testing.T) {
+	bus := NewEventBus(2)
+
+	var mu sync.Mutex
+	got := map[string][]EventType{}
+	record := func(name string) EventHandler {
+		return func(e Event) {
+			mu.Lock()
+			got[name] = append(got[name], e.Type)
+			mu.Unlock()
+		}
+	}
+	bus.Subscribe(PackageCreated, record("indexer"))
+	bus.Subscribe(PackageCreated, record("notifier"))
+	bus.Subscribe(VersionUploaded, record("notifier"))
+
+	bus.Publish(Event{Type: PackageCreated, PackageName: "demo"})
+	bus.Publish(Event{Type: VersionUploaded, PackageName: "demo", Version: "1.0.0"})
+	bus.Publish(Event{Type: PackageDeleted, PackageName: "demo"}) // 无订阅者
+	bus.Close()
+
+	if len(got["indexer"]) != 1 || got["indexer"][0] != PackageCreated {
+		t.Errorf("indexer received %v, want [%s]", got["indexer"], PackageCreated)
+	}
+	if len(got["notifier"]) != 2 {
+		t.Errorf("notifier received %v, want 2 events", got["notifier"])
+	}
+}
+
+func TestEventBusSetsOccurredAt(t *testing.T) {
+	bus := NewEventBus(1)
+	received := make(chan Event, 1)
+	bus.Subscribe(PackageCreated, func(e Event) { received <- e })
+
+	before := time.Now()
+	bus.Publish(Event{Type: PackageCreated})
+	bus.Close()
+
+	e := <-received
+	if e.OccurredAt.Before(before) {
+		t.Errorf("OccurredAt = %v, want it set at publish time", e.OccurredAt)
+	}
+}
+
+func TestEventBusRecoversFromPanickingHandler(t *testing.T) {
+	bus := NewEventBus(1)
+	var calls atomic.Int32
+	bus.Subscribe(PackageCreated, func(Event) { panic("boom") })
+	bus.Subscribe(PackageCreated, func(Event) { calls.Add(1) })
+
+	bus.Publish(Event{Type: PackageCreated})
+	bus.Publish(Event{Type: PackageCreated})
+	bus.Close()
+
+	if calls.Load() != 2 {
+		t.Errorf("healthy handler ran %d times, want 2", calls.Load())
+	}
+}
+
+func TestEventBusPublishDoesNotBlock(t *testing.T) {
+	bus := NewEventBus(1)
+	release := make(chan struct{})
+	bus.Subscribe(PackageCreated, func(Event) { <-release })
+
+	// 订阅者阻塞且队列写满后，Publish丢弃事件而不是阻塞调用方
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < defaultQueueSize+10; i++ {
+			bus.Publish(Event{Type: PackageCreated})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a full queue")
+	}
+	close(release)
+	bus.Close()
+}
+
+func TestEventBusIgnoresPublishAfterClose(t *testing.T) {
+	bus := NewEventBus(1)
+	var calls atomic.Int32
+	bus.Subscribe(PackageCreated, func(Event) { calls.Add(1) })
+	bus.Close()
+	bus.Close() // 重复关闭不应panic
+
+	bus.Publish(Event{Type: PackageCreated})
+	if calls.Load() != 0 {
+		t.Errorf("handler ran %d times after Close, want 0", calls.Load())
+	}
+}
+
+func TestNullEventBusImplementsPublisher(t *testing.T) {
+	var p Publisher = NullEventBus{}
+	p.Publish(Event{Type: PackageCreated})
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"webservice/internal/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SearchIndexer 搜索索引订阅者
+type SearchIndexer struct{}
+
+// OnPackageCreated 新包创建后更新搜索索引
+func (SearchIndexer) OnPackageCreated(event Event) {
+	logger.WithFields(logrus.Fields{
+		"event":   event.Type,
+		"package": event.PackageName,
+	}).Debug("Indexing new package")
+}
+
+// OnPackageReindexed 管理员重建包状态后重新索引该包
+func (SearchIndexer) OnPackageReindexed(event Event) {
+	logger.WithFields(logrus.Fields{
+		"event":   event.Type,
+		"package": event.PackageName,
+	}).Debug("Reindexing package")
+}
+
+// NotificationSender 通知发送订阅者
+type NotificationSender struct{}
+
+// OnVersionUploaded 新版本上传后发送通知
+func (NotificationSender) OnVersionUploaded(event Event) {
+	logger.WithFields(logrus.Fields{
+		"event":   event.Type,
+		"package": event.PackageName,
+		"version": event.Version,
+	}).Info("New package version published")
+}
+
+// ActivityRecorder 操作记录订阅者
+type ActivityRecorder struct{}
+
+// OnPackageDeleted 记录包删除操作
+func (ActivityRecorder) OnPackageDeleted(event Event) {
+	logger.WithFields(logrus.Fields{
+		"event":   event.Type,
+		"package": event.PackageName,
+		"user_id": event.UserID,
+	}).Info("Package deleted")
+}
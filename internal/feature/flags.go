@@ -0,0 +1,113 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RefreshInterval 从数据库重新加载功能开关的间隔
+const RefreshInterval = time.Minute
+
+// 代码中使用的功能开关名称，管理员以相同名称创建开关后对命中的用户启用
+const (
+	// GraphQL POST /api/v1/graphql查询接口
+	GraphQL = "graphql"
+	// APIV2 /api/v2路由组
+	APIV2 = "v2_api"
+	// TOTP 设置和启用两步验证
+	TOTP = "totp"
+)
+
+// Flags 内存中的功能开关，启动时从数据库加载，之后由后台任务定期刷新，修改后立即重新加载
+// nil的Flags视为没有任何开关，所有功能均未启用
+type Flags struct {
+	db *gorm.DB
+
+	mu    sync.RWMutex
+	flags map[string]models.FeatureFlag
+}
+
+// New 创建功能开关集合，需调用Load加载
+func New(db *gorm.DB) *Flags {
+	return &Flags{db: db, flags: map[string]models.FeatureFlag{}}
+}
+
+// Load 从数据库重新加载全部功能开关，失败时保留已加载的开关
+func (f *Flags) Load(ctx context.Context) error {
+	var flags []models.FeatureFlag
+	if err := f.db.WithContext(ctx).Find(&flags).Error; err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	loaded := make(map[string]models.FeatureFlag, len(flags))
+	for _, flag := range flags {
+		loaded[flag.Name] = flag
+	}
+	f.mu.Lock()
+	f.flags = loaded
+	f.mu.Unlock()
+	return nil
+}
+
+// IsEnabled 功能开关是否对用户启用，不存在的开关视为未启用；userID为0表示匿名用户，只匹配全局启用
+func (f *Flags) IsEnabled(flagName string, userID uint, role string) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.RLock()
+	flag, ok := f.flags[flagName]
+	f.mu.RUnlock()
+	return ok && enabledFor(&flag, userID, role)
+}
+
+// EnabledFor 返回对用户启用的全部功能开关名称（按名称排序）
+func (f *Flags) EnabledFor(userID uint, role string) []string {
+	if f == nil {
+		return nil
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var names []string
+	for name, flag := range f.flags {
+		if enabledFor(&flag, userID, role) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// enabledFor 依次检查全局启用、角色、用户ID和灰度比例
+func enabledFor(flag *models.FeatureFlag, userID uint, role string) bool {
+	if flag.EnabledGlobally {
+		return true
+	}
+	if userID == 0 {
+		return false
+	}
+	if role != "" && slices.Contains(flag.EnabledForRoles, role) {
+		return true
+	}
+	if slices.Contains(flag.EnabledForUserIDs, userID) {
+		return true
+	}
+	return flag.RolloutPercentage > 0 && rolloutBucket(flag.Name, userID) < flag.RolloutPercentage
+}
+
+// rolloutBucket 用户在开关灰度中的分桶（0-99），同一用户对同一开关的结果固定，提高比例时已启用的用户保持启用
+func rolloutBucket(flagName string, userID uint) int {
+	h := fnv.New32a()
+	h.Write([]byte(flagName + strconv.FormatUint(uint64(userID), 10)))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,73 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"webservice/internal/database"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Caller 通过认证的gRPC调用方
+type Caller struct {
+	UserID   uint
+	Username string
+	Role     string
+	TokenID  uint // 所用API令牌的ID
+}
+
+// callerKey context中保存调用方的键
+type callerKey struct{}
+
+// CallerFromContext 从context中获取认证拦截器写入的调用方
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok
+}
+
+// AuthInterceptor 认证拦截器：metadata的authorization中携带 "Bearer <api_token>"，
+// 令牌在API令牌存储中校验，必须未吊销、未过期且所属用户为正常状态
+func AuthInterceptor(tokens *service.APITokenService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		plain := tokenFromMetadata(ctx)
+		if plain == "" {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization token")
+		}
+
+		token, user, err := tokens.Authenticate(ctx, plain)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAPIToken) {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			return nil, status.Error(codes.Unavailable, "failed to verify api token")
+		}
+
+		caller := &Caller{UserID: user.ID, Username: user.Username, Role: user.Role, TokenID: token.ID}
+		ctx = context.WithValue(ctx, callerKey{}, caller)
+		ctx = database.WithRowScope(ctx, database.RowScope{
+			UserID: user.ID,
+			Admin:  user.Role == models.RoleAdmin || user.Role == models.RoleSuper,
+		})
+		return handler(ctx, req)
+	}
+}
+
+// tokenFromMetadata 从metadata的authorization中读取令牌，与HTTP的Authorization请求头格式相同
+func tokenFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(values[0], "Bearer ")
+}
@@ -0,0 +1,67 @@
+package grpcserver
+
+import (
+	"encoding/json"
+
+	packagev1 "webservice/api/proto/package/v1"
+	"webservice/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toPackage 转换包信息，最新版本取最近发布的版本，下载数和安装数为所有版本的合计
+func toPackage(pkg *models.Package) *packagev1.Package {
+	out := &packagev1.Package{
+		Id:          uint64(pkg.ID),
+		Name:        pkg.Name,
+		Description: pkg.Description,
+		Author:      pkg.Author,
+		Homepage:    pkg.Homepage,
+		Repository:  pkg.Repository,
+		License:     pkg.License,
+		IsArchived:  pkg.IsArchived,
+		TrustLevel:  pkg.TrustLevel,
+		CreatedAt:   timestamppb.New(pkg.CreatedAt),
+		UpdatedAt:   timestamppb.New(pkg.UpdatedAt),
+	}
+	// 关键词以JSON数组存储，无法解析时不返回
+	if pkg.Keywords != "" {
+		_ = json.Unmarshal([]byte(pkg.Keywords), &out.Keywords)
+	}
+
+	var latest *models.PackageVersion
+	for i := range pkg.Versions {
+		v := &pkg.Versions[i]
+		out.DownloadCount += v.DownloadCount
+		out.InstallCount += v.InstallCount
+		if latest == nil || v.CreatedAt.After(latest.CreatedAt) {
+			latest = v
+		}
+	}
+	if latest != nil {
+		out.LatestVersion = latest.Version
+	}
+	return out
+}
+
+// toVersion 转换版本信息，依赖关系以JSON对象存储，无法解析时不返回
+func toVersion(v *models.PackageVersion, packageName string) *packagev1.Version {
+	out := &packagev1.Version{
+		Id:                 uint64(v.ID),
+		Package:            packageName,
+		Version:            v.Version,
+		Description:        v.Description,
+		FileSize:           v.FileSize,
+		FileHash:           v.FileHash,
+		IsPrerelease:       v.IsPrerelease,
+		Deprecated:         v.Deprecated,
+		DeprecationMessage: v.DeprecationMessage,
+		DownloadCount:      v.DownloadCount,
+		InstallCount:       v.InstallCount,
+		CreatedAt:          timestamppb.New(v.CreatedAt),
+	}
+	if v.Dependencies != "" {
+		_ = json.Unmarshal([]byte(v.Dependencies), &out.Dependencies)
+	}
+	return out
+}
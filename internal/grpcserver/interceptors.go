@@ -0,0 +1,103 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/metrics"
+	"webservice/internal/tracer"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcRequests = metrics.NewCounterVec(
+		"grpc_server_requests_total",
+		"Total number of gRPC requests by method and status code.",
+		"method", "code",
+	)
+	grpcDuration = metrics.NewHistogramVec(
+		"grpc_server_request_duration_seconds",
+		"Time to handle gRPC requests, by method.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+		"method",
+	)
+)
+
+// MetricsInterceptor 记录按方法和状态码的请求数及按方法的耗时
+func MetricsInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		grpcDuration.Observe(time.Since(start).Seconds(), info.FullMethod)
+		grpcRequests.Inc(info.FullMethod, status.Code(err).String())
+		return resp, err
+	}
+}
+
+// TracingInterceptor 链路追踪拦截器，与HTTP的TracingMiddleware相同：从metadata中提取上游span，
+// 为每个请求创建span并放入context，服务层和数据库操作据此创建子span；追踪未启用时直接放行
+func TracingInterceptor(cfg config.JaegerConfig) grpc.UnaryServerInterceptor {
+	if !cfg.Enabled || !tracer.Enabled() {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctx, req)
+		}
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		tr := opentracing.GlobalTracer()
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		spanCtx, _ := tr.Extract(opentracing.TextMap, metadataCarrier(md))
+
+		var span opentracing.Span
+		if spanCtx != nil {
+			span = tr.StartSpan(info.FullMethod, opentracing.ChildOf(spanCtx))
+		} else {
+			span = tr.StartSpan(info.FullMethod)
+		}
+		defer span.Finish()
+
+		ext.Component.Set(span, "grpc")
+		ext.SpanKindRPCServer.Set(span)
+
+		resp, err := handler(opentracing.ContextWithSpan(ctx, span), req)
+		code := status.Code(err)
+		span.SetTag("grpc.code", code.String())
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.LogFields(
+				log.String("event", "error"),
+				log.String("message", err.Error()),
+			)
+		}
+		return resp, err
+	}
+}
+
+// metadataCarrier 以opentracing的TextMap格式读取gRPC metadata
+type metadataCarrier metadata.MD
+
+// ForeachKey 遍历metadata中的键值
+func (c metadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	for key, values := range c {
+		for _, value := range values {
+			if err := handler(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set 写入metadata，metadata的键为小写
+func (c metadataCarrier) Set(key, val string) {
+	metadata.MD(c).Append(strings.ToLower(key), val)
+}
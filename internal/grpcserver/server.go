@@ -0,0 +1,143 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	packagev1 "webservice/api/proto/package/v1"
+	"webservice/internal/config"
+	"webservice/internal/minio"
+	"webservice/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// defaultMaxBatchSize BatchGetPackages未配置上限时每次最多查询的包数
+const defaultMaxBatchSize = 100
+
+// New 创建只读包元数据gRPC服务，认证、链路追踪和指标拦截器与HTTP中间件的行为一致
+func New(cfg *config.Config, db *gorm.DB, minioClient *minio.Client) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		TracingInterceptor(cfg.Jaeger),
+		MetricsInterceptor(),
+		AuthInterceptor(service.NewAPITokenService(db)),
+	))
+	packagev1.RegisterPackageServiceServer(srv, NewPackageServer(service.NewPackageService(db, minioClient, nil, cfg.Packages), cfg.GRPC))
+	return srv
+}
+
+// PackageServer 实现packagev1.PackageServiceServer，与HTTP接口共用PackageService
+type PackageServer struct {
+	packagev1.UnimplementedPackageServiceServer
+	packages     *service.PackageService
+	maxBatchSize int
+}
+
+// NewPackageServer 创建包元数据服务实现
+func NewPackageServer(packages *service.PackageService, cfg config.GRPCConfig) *PackageServer {
+	maxBatchSize := cfg.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultMaxBatchSize
+	}
+	return &PackageServer{packages: packages, maxBatchSize: maxBatchSize}
+}
+
+// GetPackage 获取包信息，不包含版本列表
+func (s *PackageServer) GetPackage(ctx context.Context, req *packagev1.GetPackageRequest) (*packagev1.Package, error) {
+	caller, err := authorizedCaller(ctx, req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := s.packages.GetPackage(ctx, req.GetName(), &caller.UserID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return toPackage(pkg), nil
+}
+
+// GetVersion 获取指定版本
+func (s *PackageServer) GetVersion(ctx context.Context, req *packagev1.GetVersionRequest) (*packagev1.Version, error) {
+	caller, err := authorizedCaller(ctx, req.GetPackage())
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.packages.GetPackageVersion(ctx, req.GetPackage(), req.GetVersion(), &caller.UserID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return toVersion(version, req.GetPackage()), nil
+}
+
+// ResolveConstraint 返回满足版本约束的最高版本，规则与HTTP的版本匹配接口相同
+func (s *PackageServer) ResolveConstraint(ctx context.Context, req *packagev1.ResolveConstraintRequest) (*packagev1.Version, error) {
+	caller, err := authorizedCaller(ctx, req.GetPackage())
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.packages.MatchVersion(ctx, req.GetPackage(), req.GetConstraint(), req.GetIncludePrerelease(), &caller.UserID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return toVersion(version, req.GetPackage()), nil
+}
+
+// BatchGetPackages 批量获取包信息，不存在或无权读取的包名在not_found中返回
+func (s *PackageServer) BatchGetPackages(ctx context.Context, req *packagev1.BatchGetPackagesRequest) (*packagev1.BatchGetPackagesResponse, error) {
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization token")
+	}
+	if len(req.GetNames()) > s.maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d packages per request", s.maxBatchSize)
+	}
+
+	packages, err := s.packages.GetPackagesByName(ctx, req.GetNames(), &caller.UserID)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	resp := &packagev1.BatchGetPackagesResponse{}
+	found := make(map[string]bool, len(packages))
+	for _, pkg := range packages {
+		found[pkg.Name] = true
+		resp.Packages = append(resp.Packages, toPackage(pkg))
+	}
+	for _, name := range req.GetNames() {
+		if !found[name] {
+			resp.NotFound = append(resp.NotFound, name)
+		}
+	}
+	return resp, nil
+}
+
+// authorizedCaller 返回已认证的调用方，包名为空时返回InvalidArgument
+func authorizedCaller(ctx context.Context, packageName string) (*Caller, error) {
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization token")
+	}
+	if packageName == "" {
+		return nil, status.Error(codes.InvalidArgument, "package name is required")
+	}
+	return caller, nil
+}
+
+// statusFromError 将服务层错误转换为gRPC状态，判断方式与HTTP处理器一致
+func statusFromError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidConstraint):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrNoMatchingVersion):
+		return status.Error(codes.NotFound, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		return status.Error(codes.NotFound, err.Error())
+	case strings.Contains(err.Error(), "access denied"):
+		return status.Error(codes.PermissionDenied, "access denied")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
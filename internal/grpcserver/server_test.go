@@ -0,0 +1,322 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	packagev1 "webservice/api/proto/package/v1"
+	"webservice/internal/config"
+	"webservice/internal/migration"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/gorm"
+)
+
+// testServer 通过bufconn连接的gRPC服务
+type testServer struct {
+	db     *gorm.DB
+	client packagev1.PackageServiceClient
+}
+
+// newTestServer 启动与main.go相同配置的gRPC服务，测试结束后关闭
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	db := testutil.NewDB(t, migration.Models()...)
+
+	cfg := &config.Config{GRPC: config.GRPCConfig{MaxBatchSize: 3}}
+	srv := New(cfg, db, nil)
+	listener := bufconn.Listen(1 << 20)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testServer{db: db, client: packagev1.NewPackageServiceClient(conn)}
+}
+
+// createUser 创建用户并为其创建API令牌，返回明文令牌
+func (ts *testServer) createUser(t *testing.T, username, role string) (*models.User, string) {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", Password: "x", Role: role, Status: models.UserStatusActive}
+	if err := ts.db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	plain, _ := ts.createToken(t, user, 0)
+	return user, plain
+}
+
+// createToken 为用户创建API令牌，expiresInDays为0表示不过期
+func (ts *testServer) createToken(t *testing.T, user *models.User, expiresInDays int) (string, *models.APIToken) {
+	t.Helper()
+	plain, token, err := service.NewAPITokenService(ts.db).CreateToken(context.Background(), user.ID, &models.CreateAPITokenRequest{Name: "test", ExpiresInDays: expiresInDays})
+	if err != nil {
+		t.Fatalf("failed to create api token: %v", err)
+	}
+	return plain, token
+}
+
+// createPackage 创建包及其版本
+func (ts *testServer) createPackage(t *testing.T, name string, owner *models.User, private bool, versions ...string) {
+	t.Helper()
+	pkg := &models.Package{Name: name, OwnerID: owner.ID, Keywords: `["grpc","test"]`}
+	if err := ts.db.Create(pkg).Error; err != nil {
+		t.Fatalf("failed to create package: %v", err)
+	}
+	if private {
+		if err := ts.db.Model(pkg).Update("is_private", true).Error; err != nil {
+			t.Fatalf("failed to mark package private: %v", err)
+		}
+	}
+	for i, v := range versions {
+		version := &models.PackageVersion{
+			PackageID:     pkg.ID,
+			Version:       v,
+			Dependencies:  `{"left-pad":"^1.0.0"}`,
+			FileSize:      100,
+			DownloadCount: 10,
+			UploaderID:    owner.ID,
+			CreatedAt:     time.Now().Add(time.Duration(i) * time.Minute),
+		}
+		if err := ts.db.Create(version).Error; err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+	}
+}
+
+// withToken 在metadata中携带token
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// assertCode 断言gRPC调用返回指定的状态码
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("code = %v (%v), want %v", got, err, want)
+	}
+}
+
+func TestGetPackage(t *testing.T) {
+	ts := newTestServer(t)
+	owner, token := ts.createUser(t, "alice", models.RoleUser)
+	ts.createPackage(t, "demo", owner, false, "1.0.0", "1.1.0")
+
+	pkg, err := ts.client.GetPackage(withToken(token), &packagev1.GetPackageRequest{Name: "demo"})
+	if err != nil {
+		t.Fatalf("GetPackage: %v", err)
+	}
+	if pkg.GetName() != "demo" || pkg.GetLatestVersion() != "1.1.0" || pkg.GetDownloadCount() != 20 {
+		t.Errorf("got name=%q latest=%q downloads=%d, want demo, 1.1.0, 20", pkg.GetName(), pkg.GetLatestVersion(), pkg.GetDownloadCount())
+	}
+	if len(pkg.GetKeywords()) != 2 {
+		t.Errorf("keywords = %v, want [grpc test]", pkg.GetKeywords())
+	}
+
+	_, err = ts.client.GetPackage(withToken(token), &packagev1.GetPackageRequest{Name: "missing"})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestGetVersionAndResolveConstraint(t *testing.T) {
+	ts := newTestServer(t)
+	owner, token := ts.createUser(t, "alice", models.RoleUser)
+	ts.createPackage(t, "demo", owner, false, "1.0.0", "1.2.0", "2.0.0")
+	ctx := withToken(token)
+
+	version, err := ts.client.GetVersion(ctx, &packagev1.GetVersionRequest{Package: "demo", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if version.GetVersion() != "1.0.0" || version.GetDependencies()["left-pad"] != "^1.0.0" {
+		t.Errorf("got version=%q deps=%v", version.GetVersion(), version.GetDependencies())
+	}
+	_, err = ts.client.GetVersion(ctx, &packagev1.GetVersionRequest{Package: "demo", Version: "9.9.9"})
+	assertCode(t, err, codes.NotFound)
+
+	resolved, err := ts.client.ResolveConstraint(ctx, &packagev1.ResolveConstraintRequest{Package: "demo", Constraint: "^1.0.0"})
+	if err != nil {
+		t.Fatalf("ResolveConstraint: %v", err)
+	}
+	if resolved.GetVersion() != "1.2.0" {
+		t.Errorf("^1.0.0 resolved to %q, want 1.2.0", resolved.GetVersion())
+	}
+	_, err = ts.client.ResolveConstraint(ctx, &packagev1.ResolveConstraintRequest{Package: "demo", Constraint: "^3.0.0"})
+	assertCode(t, err, codes.NotFound)
+	_, err = ts.client.ResolveConstraint(ctx, &packagev1.ResolveConstraintRequest{Package: "demo", Constraint: ">>1"})
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+func TestBatchGetPackages(t *testing.T) {
+	ts := newTestServer(t)
+	owner, token := ts.createUser(t, "alice", models.RoleUser)
+	other, _ := ts.createUser(t, "bob", models.RoleUser)
+	ts.createPackage(t, "a", owner, false, "1.0.0")
+	ts.createPackage(t, "b", owner, true, "1.0.0")
+	ts.createPackage(t, "secret", other, true, "1.0.0")
+
+	resp, err := ts.client.BatchGetPackages(withToken(token), &packagev1.BatchGetPackagesRequest{Names: []string{"a", "b", "secret"}})
+	if err != nil {
+		t.Fatalf("BatchGetPackages: %v", err)
+	}
+	if len(resp.GetPackages()) != 2 {
+		t.Errorf("got %d packages, want a and b (own private package)", len(resp.GetPackages()))
+	}
+	if len(resp.GetNotFound()) != 1 || resp.GetNotFound()[0] != "secret" {
+		t.Errorf("not_found = %v, want [secret] (another user's private package)", resp.GetNotFound())
+	}
+
+	_, err = ts.client.BatchGetPackages(withToken(token), &packagev1.BatchGetPackagesRequest{Names: []string{"a", "b", "c", "d"}})
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+func TestAuthRequiresActiveAPIToken(t *testing.T) {
+	ts := newTestServer(t)
+	owner, token := ts.createUser(t, "alice", models.RoleUser)
+	ts.createPackage(t, "demo", owner, false, "1.0.0")
+	req := &packagev1.GetPackageRequest{Name: "demo"}
+
+	if _, err := ts.client.GetPackage(withToken(token), req); err != nil {
+		t.Fatalf("GetPackage with a valid token: %v", err)
+	}
+
+	// 缺少令牌
+	_, err := ts.client.GetPackage(context.Background(), req)
+	assertCode(t, err, codes.Unauthenticated)
+	_, err = ts.client.GetPackage(withToken(""), req)
+	assertCode(t, err, codes.Unauthenticated)
+
+	// 不在令牌存储中的令牌
+	_, err = ts.client.GetPackage(withToken("not-a-token"), req)
+	assertCode(t, err, codes.Unauthenticated)
+
+	// 已过期的令牌
+	expired, expiredToken := ts.createToken(t, owner, 1)
+	if err := ts.db.Model(expiredToken).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("failed to expire token: %v", err)
+	}
+	_, err = ts.client.GetPackage(withToken(expired), req)
+	assertCode(t, err, codes.Unauthenticated)
+
+	// 吊销后令牌失效，其他令牌不受影响
+	revoked, revokedToken := ts.createToken(t, owner, 0)
+	if err := service.NewAPITokenService(ts.db).RevokeToken(context.Background(), owner.ID, revokedToken.ID); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	_, err = ts.client.GetPackage(withToken(revoked), req)
+	assertCode(t, err, codes.Unauthenticated)
+	if _, err := ts.client.GetPackage(withToken(token), req); err != nil {
+		t.Errorf("GetPackage with another active token: %v", err)
+	}
+
+	// 用户被暂停后其令牌全部失效
+	if err := ts.db.Model(owner).Update("status", models.UserStatusSuspended).Error; err != nil {
+		t.Fatalf("failed to suspend user: %v", err)
+	}
+	_, err = ts.client.GetPackage(withToken(token), req)
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestPrivatePackages(t *testing.T) {
+	ts := newTestServer(t)
+	owner, ownerToken := ts.createUser(t, "alice", models.RoleUser)
+	_, otherToken := ts.createUser(t, "bob", models.RoleUser)
+	ts.createPackage(t, "secret", owner, true, "1.0.0")
+
+	// 其他用户无权读取私有包及其版本
+	_, err := ts.client.GetPackage(withToken(otherToken), &packagev1.GetPackageRequest{Name: "secret"})
+	assertCode(t, err, codes.PermissionDenied)
+	_, err = ts.client.GetVersion(withToken(otherToken), &packagev1.GetVersionRequest{Package: "secret", Version: "1.0.0"})
+	assertCode(t, err, codes.PermissionDenied)
+	_, err = ts.client.ResolveConstraint(withToken(otherToken), &packagev1.ResolveConstraintRequest{Package: "secret", Constraint: "^1.0.0"})
+	if code := status.Code(err); code != codes.PermissionDenied && code != codes.NotFound {
+		t.Errorf("ResolveConstraint on another user's private package = %v, want PermissionDenied or NotFound", err)
+	}
+
+	// 与HTTP接口相同，只有所有者和协作者可以读取
+	pkg, err := ts.client.GetPackage(withToken(ownerToken), &packagev1.GetPackageRequest{Name: "secret"})
+	if err != nil {
+		t.Fatalf("owner GetPackage: %v", err)
+	}
+	if pkg.GetName() != "secret" {
+		t.Errorf("owner got package %q, want secret", pkg.GetName())
+	}
+	if _, err := ts.client.GetVersion(withToken(ownerToken), &packagev1.GetVersionRequest{Package: "secret", Version: "1.0.0"}); err != nil {
+		t.Errorf("owner GetVersion: %v", err)
+	}
+}
+
+func TestBatchGetPackagesLimit(t *testing.T) {
+	ts := newTestServer(t)
+	owner, token := ts.createUser(t, "alice", models.RoleUser)
+	for _, name := range []string{"a", "b", "c"} {
+		ts.createPackage(t, name, owner, false, "1.0.0")
+	}
+
+	// 恰好达到上限的请求可以执行
+	resp, err := ts.client.BatchGetPackages(withToken(token), &packagev1.BatchGetPackagesRequest{Names: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatalf("BatchGetPackages at the limit: %v", err)
+	}
+	if len(resp.GetPackages()) != 3 || len(resp.GetNotFound()) != 0 {
+		t.Errorf("got %d packages and not_found %v, want 3 and none", len(resp.GetPackages()), resp.GetNotFound())
+	}
+
+	_, err = ts.client.BatchGetPackages(withToken(token), &packagev1.BatchGetPackagesRequest{Names: []string{"a", "b", "c", "d"}})
+	assertCode(t, err, codes.InvalidArgument)
+
+	// 超过上限的请求在认证之后才检查，未认证时仍返回Unauthenticated
+	_, err = ts.client.BatchGetPackages(context.Background(), &packagev1.BatchGetPackagesRequest{Names: []string{"a", "b", "c", "d"}})
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestGracefulStopRejectsNewRequests(t *testing.T) {
+	ts := newTestServer(t)
+	owner, token := ts.createUser(t, "alice", models.RoleUser)
+	ts.createPackage(t, "demo", owner, false, "1.0.0")
+
+	cfg := &config.Config{}
+	srv := New(cfg, ts.db, nil)
+	listener := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	defer conn.Close()
+	client := packagev1.NewPackageServiceClient(conn)
+	if _, err := client.GetPackage(withToken(token), &packagev1.GetPackageRequest{Name: "demo"}); err != nil {
+		t.Fatalf("GetPackage: %v", err)
+	}
+
+	srv.GracefulStop()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve returned %v after graceful stop, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after graceful stop")
+	}
+	_, err = client.GetPackage(withToken(token), &packagev1.GetPackageRequest{Name: "demo"})
+	assertCode(t, err, codes.Unavailable)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/service"
+)
+
+func TestDownloadByRenamedPackageName(t *testing.T) {
+	tests := []struct {
+		aliasMode  string
+		wantStatus int
+	}{
+		{aliasMode: "redirect", wantStatus: http.StatusMovedPermanently},
+		{aliasMode: "transparent", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.aliasMode, func(t *testing.T) {
+			env := newPackageTestEnv(t, func(cfg *config.PackagesConfig) { cfg.AliasMode = tt.aliasMode })
+			owner := env.createUser("alice")
+			pkg := env.createPackage("old-name", owner)
+			content := env.uploadVersion(pkg, "1.0.0", owner.ID)
+			if _, err := env.service.RenamePackage(context.Background(), "old-name", "new-name", owner.ID); err != nil {
+				t.Fatalf("RenamePackage: %v", err)
+			}
+			env.r.GET("/api/v1/packages/:package/:version/download", env.handler.DownloadPackageVersion)
+
+			w := env.do(httptest.NewRequest(http.MethodGet, "/api/v1/packages/old-name/1.0.0/download", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("X-Package-Renamed-To"); got != "new-name" {
+				t.Errorf("X-Package-Renamed-To = %q, want new-name", got)
+			}
+			if w.Header().Get("Deprecation") != "true" {
+				t.Error("missing Deprecation header")
+			}
+
+			switch tt.wantStatus {
+			case http.StatusMovedPermanently:
+				if got := w.Header().Get("Location"); got != "/api/v1/packages/new-name/1.0.0/download" {
+					t.Errorf("Location = %q", got)
+				}
+			case http.StatusOK:
+				if w.Body.String() != content {
+					t.Errorf("body = %q, want %q", w.Body.String(), content)
+				}
+			}
+		})
+	}
+}
+
+func TestAliasedNameCannotBeReused(t *testing.T) {
+	env := newPackageTestEnv(t, nil)
+	owner := env.createUser("alice")
+	other := env.createUser("bob")
+	env.createPackage("old-name", owner)
+	ctx := context.Background()
+	if _, err := env.service.RenamePackage(ctx, "old-name", "new-name", owner.ID); err != nil {
+		t.Fatalf("RenamePackage: %v", err)
+	}
+
+	if _, err := env.service.CreatePackage(ctx, &models.CreatePackageRequest{Name: "old-name"}, other.ID); !errors.Is(err, service.ErrPackageNameAliased) {
+		t.Errorf("CreatePackage with aliased name = %v, want ErrPackageNameAliased", err)
+	}
+
+	// 所有者可以改回旧名称，之后该名称不再是别名
+	if _, err := env.service.RenamePackage(ctx, "new-name", "old-name", owner.ID); err != nil {
+		t.Fatalf("rename back: %v", err)
+	}
+	if target, err := env.service.ResolvePackageAlias(ctx, "old-name"); err != nil || target != "" {
+		t.Errorf("ResolvePackageAlias(old-name) = %q, %v, want no alias", target, err)
+	}
+}
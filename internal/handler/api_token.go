@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIToken 为当前用户创建API令牌，明文令牌只在本次响应中返回
+func (h *Handler) CreateAPIToken(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	plain, token, err := h.apiTokenService.CreateToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to create api token")
+		return
+	}
+
+	middleware.SuccessResponse(c, models.CreateAPITokenResponse{Token: plain, APIToken: token})
+}
+
+// GetAPITokens 获取当前用户未吊销的API令牌列表
+func (h *Handler) GetAPITokens(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	tokens, err := h.apiTokenService.ListTokens(c.Request.Context(), userID)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get api tokens")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"api_tokens": tokens})
+}
+
+// RevokeAPIToken 吊销当前用户的指定API令牌
+func (h *Handler) RevokeAPIToken(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid api token ID")
+		return
+	}
+
+	if err := h.apiTokenService.RevokeToken(c.Request.Context(), userID, uint(tokenID)); err != nil {
+		if errors.Is(err, service.ErrAPITokenNotFound) {
+			middleware.NotFoundResponse(c, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to revoke api token")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "API token revoked successfully"})
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ArchivePackage 归档包（仅包所有者），归档后不能上传新版本或修改包信息
+func (h *Handler) ArchivePackage(c *gin.Context) {
+	h.setPackageArchived(c, true)
+}
+
+// UnarchivePackage 取消包归档（仅包所有者）
+func (h *Handler) UnarchivePackage(c *gin.Context) {
+	h.setPackageArchived(c, false)
+}
+
+// setPackageArchived 切换包的归档状态，状态变化时记录审计日志
+func (h *Handler) setPackageArchived(c *gin.Context, archived bool) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	packageName := c.Param("package")
+	if packageName == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name is required")
+		return
+	}
+
+	pkg, changed, err := h.packageService.SetPackageArchived(c.Request.Context(), packageName, archived, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to update package archive state")
+		return
+	}
+
+	if changed {
+		action := "packages.unarchive"
+		if archived {
+			action = "packages.archive"
+		}
+		if err := h.auditService.Record(c.Request.Context(), userID, action, "packages/"+pkg.Name, nil, c.ClientIP()); err != nil {
+			logger.Warnf("Failed to audit package archive change: %v", err)
+		}
+	}
+
+	middleware.SuccessResponse(c, pkg)
+}
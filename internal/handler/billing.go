@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPackageBandwidth 获取包在时间范围内的下载流量（管理员）
+// from/to为RFC3339时间或日期，默认为本月初至今
+func (h *Handler) GetPackageBandwidth(c *gin.Context) {
+	from, to, ok := parseBandwidthRange(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.billingService.GetPackageBandwidthUsage(c.Request.Context(), c.Param("package"), from, to)
+	if err != nil {
+		respondBandwidthError(c, err, "Failed to get bandwidth usage")
+		return
+	}
+
+	middleware.SuccessResponse(c, usage)
+}
+
+// GetOwnedPackageBandwidth 包所有者查看包的下载流量
+func (h *Handler) GetOwnedPackageBandwidth(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	from, to, ok := parseBandwidthRange(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.billingService.GetOwnedPackageBandwidthUsage(c.Request.Context(), c.Param("package"), userID, from, to)
+	if err != nil {
+		respondBandwidthError(c, err, "Failed to get bandwidth usage")
+		return
+	}
+
+	middleware.SuccessResponse(c, usage)
+}
+
+// SetPackageBandwidthLimit 设置包每月下载流量上限（管理员），monthly_bandwidth_limit_bytes为null时取消限制
+func (h *Handler) SetPackageBandwidthLimit(c *gin.Context) {
+	var req models.SetBandwidthLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	pkg, err := h.billingService.SetBandwidthLimit(c.Request.Context(), c.Param("package"), req.MonthlyBandwidthLimitBytes)
+	if err != nil {
+		respondBandwidthError(c, err, "Failed to set bandwidth limit")
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(c)
+	if err := h.auditService.Record(c.Request.Context(), actorID, "packages.bandwidth_limit", "packages/"+pkg.Name, req, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit package bandwidth limit change: %v", err)
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"package":                       pkg.Name,
+		"monthly_bandwidth_limit_bytes": pkg.MonthlyBandwidthLimitBytes,
+	})
+}
+
+// parseBandwidthRange 解析from/to参数，解析失败时写入400响应并返回false
+func parseBandwidthRange(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+
+	if value := c.Query("from"); value != "" {
+		t, err := parseExportTime(value)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "Invalid from parameter, expected RFC3339 or YYYY-MM-DD")
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+	if value := c.Query("to"); value != "" {
+		t, err := parseExportTime(value)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "Invalid to parameter, expected RFC3339 or YYYY-MM-DD")
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		middleware.ValidationErrorResponse(c, "from must be before to")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// respondBandwidthError 流量统计接口的错误响应，message为未识别错误时的提示
+func respondBandwidthError(c *gin.Context, err error, message string) {
+	if strings.Contains(err.Error(), "not found") {
+		middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		return
+	}
+	if strings.Contains(err.Error(), "permission denied") {
+		middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+		return
+	}
+	middleware.InternalServerErrorResponse(c, message)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"webservice/internal/botdetect"
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestBotDownloadsExcludedFromCounts(t *testing.T) {
+	env := newPackageTestEnv(t, nil)
+	env.handler.botDetector = botdetect.New(config.SearchProtectionConfig{
+		Enabled:       true,
+		BotUserAgents: []string{"bot", `^python-requests/`},
+		FlagTTL:       time.Hour,
+	}, "test-secret")
+	owner := env.createUser("alice")
+	env.uploadVersion(env.createPackage("app", owner), "1.0.0", owner.ID)
+	env.r.GET("/packages/:package/:version/download", env.handler.DownloadPackageVersion)
+
+	download := func(ip, userAgent string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/packages/app/1.0.0/download", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("User-Agent", userAgent)
+		if w := env.do(req); w.Code != http.StatusOK {
+			t.Fatalf("download as %q: status = %d, body %s", userAgent, w.Code, w.Body.String())
+		}
+	}
+
+	// 爬虫仍能下载，但只记录为疑似爬虫流量
+	download("192.0.2.1", "Mozilla/5.0 (compatible; Googlebot/2.1)")
+	download("192.0.2.2", "python-requests/2.31.0")
+	download("192.0.2.3", "curl/8.4.0")
+	download("192.0.2.4", "Mozilla/5.0 (X11; Linux x86_64)")
+	// 被搜索防护标记的IP即使使用普通User-Agent也不计数
+	env.handler.botDetector.Flag("192.0.2.5", "search_rate", "curl/8.4.0")
+	download("192.0.2.5", "curl/8.4.0")
+
+	var version models.PackageVersion
+	var flagged, total int64
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if err := env.db.Where("version = ?", "1.0.0").First(&version).Error; err != nil {
+			t.Fatal(err)
+		}
+		env.db.Model(&models.PackageDownload{}).Count(&total)
+		env.db.Model(&models.PackageDownload{}).Where("flagged = ?", true).Count(&flagged)
+		if (total == 5 && version.DownloadCount == 2 && version.InstallCount == 2) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 等待可能迟到的异步计数
+	time.Sleep(50 * time.Millisecond)
+	if err := env.db.First(&version, version.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 5 || flagged != 3 {
+		t.Errorf("download records = %d (%d flagged), want 5 (3 flagged)", total, flagged)
+	}
+	if version.DownloadCount != 2 {
+		t.Errorf("download_count = %d, want 2", version.DownloadCount)
+	}
+	if version.InstallCount != 2 {
+		t.Errorf("install_count = %d, want 2", version.InstallCount)
+	}
+
+	var bots []models.PackageDownload
+	env.db.Where("flagged = ?", true).Order("ip_address").Find(&bots)
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.5"} {
+		if i >= len(bots) || bots[i].IPAddress != ip {
+			t.Errorf("flagged downloads = %+v, want from 192.0.2.1, 192.0.2.2 and 192.0.2.5", bots)
+			break
+		}
+	}
+}
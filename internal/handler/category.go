@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListCategories 获取包分类表及各分类下的公开包数
+func (h *Handler) ListCategories(c *gin.Context) {
+	categories, err := h.categoryService.ListCategories(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get categories")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"categories": categories})
+}
+
+// CreateCategory 创建包分类（管理员）
+func (h *Handler) CreateCategory(c *gin.Context) {
+	var req models.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	category, err := h.categoryService.CreateCategory(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCategorySlug) {
+			middleware.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrCategoryExists) {
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to create category")
+		return
+	}
+
+	middleware.SuccessResponse(c, category)
+}
+
+// UpdateCategory 修改包分类的名称和描述（管理员）
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid category ID")
+		return
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	category, err := h.categoryService.UpdateCategory(c.Request.Context(), uint(id), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			middleware.NotFoundResponse(c, "Category not found")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to update category")
+		return
+	}
+
+	middleware.SuccessResponse(c, category)
+}
+
+// DeleteCategory 删除包分类（管理员）
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid category ID")
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			middleware.NotFoundResponse(c, "Category not found")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to delete category")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "Category deleted successfully"})
+}
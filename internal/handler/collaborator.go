@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListCollaborators 列出包的协作者及其角色，私有包只有能读取它的用户可以查看
+func (h *Handler) ListCollaborators(c *gin.Context) {
+	packageName := c.Param("package")
+	collaborators, err := h.packageService.ListCollaborators(c.Request.Context(), packageName, h.packageCaller(c, packageName))
+	if err != nil {
+		respondCollaboratorError(c, err, "Failed to list collaborators")
+		return
+	}
+
+	middleware.SuccessResponse(c, collaborators)
+}
+
+// SetCollaborator 添加协作者或修改其角色（maintainer或reader），仅包所有者和管理员可以操作
+func (h *Handler) SetCollaborator(c *gin.Context) {
+	var req models.CollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	packageName := c.Param("package")
+	collaborator, err := h.packageService.SetCollaborator(c.Request.Context(), packageName, c.Param("username"), req.Role, h.packageCaller(c, packageName))
+	if err != nil {
+		respondCollaboratorError(c, err, "Failed to save collaborator")
+		return
+	}
+
+	middleware.SuccessResponse(c, collaborator)
+}
+
+// RemoveCollaborator 移除协作者，用户不是协作者时不报错；仅包所有者和管理员可以操作
+func (h *Handler) RemoveCollaborator(c *gin.Context) {
+	packageName := c.Param("package")
+	if err := h.packageService.RemoveCollaborator(c.Request.Context(), packageName, c.Param("username"), h.packageCaller(c, packageName)); err != nil {
+		respondCollaboratorError(c, err, "Failed to remove collaborator")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "Collaborator removed"})
+}
+
+// respondCollaboratorError 协作者接口的错误响应，message为未识别错误时的提示
+func respondCollaboratorError(c *gin.Context, err error, message string) {
+	switch {
+	case respondPackageArchived(c, err):
+	case errors.Is(err, service.ErrCollaboratorIsOwner):
+		middleware.ValidationErrorResponse(c, err.Error())
+	case strings.Contains(err.Error(), "user not found"):
+		middleware.ErrorResponse(c, http.StatusNotFound, "User not found")
+	case strings.Contains(err.Error(), "not found"):
+		middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+	case strings.Contains(err.Error(), "access denied"), strings.Contains(err.Error(), "permission denied"):
+		middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+	default:
+		middleware.InternalServerErrorResponse(c, message)
+	}
+}
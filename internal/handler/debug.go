@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"webservice/internal/middleware"
+	"webservice/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storageHealthTimeout 诊断接口检查存储健康状态的超时时间
+const storageHealthTimeout = 3 * time.Second
+
+// GetDebugInfo 获取构建信息和运行时诊断数据（管理员）
+func (h *Handler) GetDebugInfo(c *gin.Context) {
+	info := gin.H{
+		"build":      version.Get(),
+		"uptime":     version.Uptime().Round(time.Second).String(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"goroutines": runtime.NumGoroutine(),
+		"storage":    h.storageHealth(c.Request.Context()),
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	info["memory"] = gin.H{
+		"alloc_bytes":      mem.Alloc,
+		"heap_inuse_bytes": mem.HeapInuse,
+		"sys_bytes":        mem.Sys,
+		"num_gc":           mem.NumGC,
+	}
+
+	if sqlDB, err := h.db.DB(); err == nil {
+		stats := sqlDB.Stats()
+		info["database"] = gin.H{
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration":        stats.WaitDuration.String(),
+			"max_idle_closed":      stats.MaxIdleClosed,
+			"max_lifetime_closed":  stats.MaxLifetimeClosed,
+		}
+	}
+
+	middleware.SuccessResponse(c, info)
+}
+
+// storageHealth 检查存储后端是否可访问
+func (h *Handler) storageHealth(ctx context.Context) gin.H {
+	if h.minioClient == nil {
+		return gin.H{"status": "unavailable", "error": "storage not configured"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageHealthTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := h.minioClient.Ping(ctx); err != nil {
+		return gin.H{"status": "unhealthy", "error": err.Error()}
+	}
+	return gin.H{"status": "healthy", "latency": time.Since(start).String()}
+}
+
+// RegisterPprof 在指定路由组下挂载pprof接口
+func RegisterPprof(group *gin.RouterGroup) {
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	group.GET("/:name", func(c *gin.Context) {
+		pprof.Handler(c.Param("name")).ServeHTTP(c.Writer, c.Request)
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+)
+
+func TestDownloadRateLimitPerPackage(t *testing.T) {
+	env := newPackageTestEnv(t, func(cfg *config.PackagesConfig) {
+		cfg.DownloadRateLimit = config.DownloadRateLimitConfig{
+			Interval:  time.Minute,
+			Requests:  5,
+			Overrides: map[string]config.DownloadRateLimitRule{"Hot": {Requests: 2}},
+		}
+	})
+	owner := env.createUser("alice")
+	env.uploadVersion(env.createPackage("hot", owner), "1.0.0", owner.ID)
+	env.uploadVersion(env.createPackage("cold", owner), "1.0.0", owner.ID)
+	env.r.GET("/packages/:package/:version/download", env.handler.DownloadPackageVersion)
+
+	download := func(name string) *httptest.ResponseRecorder {
+		return env.do(httptest.NewRequest(http.MethodGet, "/packages/"+name+"/1.0.0/download", nil))
+	}
+
+	for i := 1; i <= 2; i++ {
+		if w := download("hot"); w.Code != http.StatusOK {
+			t.Fatalf("hot download %d: status = %d, body %s", i, w.Code, w.Body.String())
+		}
+	}
+	w := download("hot")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("hot download over the limit: status = %d, want 429", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 || retryAfter > 60 {
+		t.Errorf("Retry-After = %q, want seconds within the 1m window", w.Header().Get("Retry-After"))
+	}
+
+	// 其他包使用各自的窗口和全局限制，不受影响
+	for i := 1; i <= 5; i++ {
+		if w := download("cold"); w.Code != http.StatusOK {
+			t.Fatalf("cold download %d: status = %d, body %s", i, w.Code, w.Body.String())
+		}
+	}
+	if w := download("cold"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("cold download over the global limit: status = %d, want 429", w.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/service"
+)
+
+// signDownloadToken 用测试密钥直接签发令牌，用于构造已过期的链接
+func signDownloadToken(t *testing.T, token service.DownloadToken) string {
+	t.Helper()
+	payload, err := json.Marshal(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAppSignedDownloadURL(t *testing.T) {
+	env := newPackageTestEnv(t, func(cfg *config.PackagesConfig) {
+		cfg.DownloadURLMode = service.DownloadURLModeAppSigned
+	})
+	owner := env.createUser("alice")
+	pkg := env.createPackage("app", owner)
+	content := env.uploadVersion(pkg, "1.0.0", owner.ID)
+	env.r.GET("/api/v1/packages/:package/:version/download-url", env.handler.GetDownloadURL)
+	env.r.GET("/download/:token", env.handler.DownloadWithToken)
+
+	w := env.do(httptest.NewRequest(http.MethodGet, "/api/v1/packages/app/1.0.0/download-url", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("download-url: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			DownloadURL string `json:"download_url"`
+			ExpiresIn   int    `json:"expires_in"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	link, err := url.Parse(resp.Data.DownloadURL)
+	if err != nil || !strings.HasPrefix(link.Path, "/download/") {
+		t.Fatalf("download_url = %q, want an app route", resp.Data.DownloadURL)
+	}
+	if resp.Data.ExpiresIn != int(time.Hour.Seconds()) {
+		t.Errorf("expires_in = %d, want the default ttl", resp.Data.ExpiresIn)
+	}
+
+	t.Run("valid token streams the file", func(t *testing.T) {
+		w := env.do(httptest.NewRequest(http.MethodGet, link.Path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		if w.Body.String() != content {
+			t.Errorf("body = %q, want %q", w.Body.String(), content)
+		}
+	})
+
+	t.Run("tampered token rejected", func(t *testing.T) {
+		token := strings.TrimPrefix(link.Path, "/download/")
+		payload, sig, _ := strings.Cut(token, ".")
+		forged := base64.RawURLEncoding.EncodeToString([]byte(`{"p":"app","v":"1.0.0","e":4102444800}`))
+		for _, tampered := range []string{forged + "." + sig, payload + "." + sig[:len(sig)-2] + "AA", payload} {
+			if w := env.do(httptest.NewRequest(http.MethodGet, "/download/"+tampered, nil)); w.Code != http.StatusForbidden {
+				t.Errorf("token %q: status = %d, want 403", tampered, w.Code)
+			}
+		}
+	})
+
+	t.Run("expired token rejected", func(t *testing.T) {
+		expired := signDownloadToken(t, service.DownloadToken{Package: "app", Version: "1.0.0", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		if w := env.do(httptest.NewRequest(http.MethodGet, "/download/"+expired, nil)); w.Code != http.StatusGone {
+			t.Errorf("status = %d, want 410", w.Code)
+		}
+	})
+}
@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportMaxLimit 单次导出允许的最大行数
+const exportMaxLimit = 1000000
+
+// defaultExportFlushRows 未配置时每导出多少行刷新一次响应
+const defaultExportFlushRows = 1000
+
+// ExportPackages 导出包数据（管理员）
+func (h *Handler) ExportPackages(c *gin.Context) {
+	h.export(c, "packages")
+}
+
+// ExportVersions 导出版本数据（管理员）
+func (h *Handler) ExportVersions(c *gin.Context) {
+	h.export(c, "versions")
+}
+
+// ExportDownloads 导出下载记录（管理员），支持since参数
+func (h *Handler) ExportDownloads(c *gin.Context) {
+	h.export(c, "downloads")
+}
+
+// exportTrailer 导出结束记录，用于校验数据完整性
+type exportTrailer struct {
+	Trailer    bool   `json:"_trailer"`
+	Dataset    string `json:"dataset"`
+	Rows       int64  `json:"rows"`
+	SHA256     string `json:"sha256"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Complete   bool   `json:"complete"`
+	Error      string `json:"error,omitempty"`
+}
+
+// exportEncoder 导出格式编码器，逐行写出并计算数据行的校验和
+type exportEncoder interface {
+	writeHeader(columns []string) error
+	writeRow(columns []string, row []interface{}) error
+	writeTrailer(trailer exportTrailer) error
+}
+
+// export 以NDJSON或CSV流式导出数据集
+func (h *Handler) export(c *gin.Context, dataset string) {
+	format := c.DefaultQuery("format", "ndjson")
+	if format != "ndjson" && format != "csv" {
+		middleware.ValidationErrorResponse(c, "format must be ndjson or csv")
+		return
+	}
+
+	// 邮箱、IP等敏感列只导出给管理员
+	role, _ := middleware.GetRoleFromContext(c)
+	opts := service.ExportOptions{
+		Cursor:     c.Query("cursor"),
+		IncludePII: c.Query("include_pii") == "true" && (role == models.RoleAdmin || role == models.RoleSuper),
+	}
+	if _, err := service.DecodeExportCursor(opts.Cursor); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > exportMaxLimit {
+			middleware.ValidationErrorResponse(c, fmt.Sprintf("limit must be between 1 and %d", exportMaxLimit))
+			return
+		}
+		opts.Limit = limit
+	}
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		since, err := parseExportTime(sinceStr)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "since must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		opts.Since = since
+	}
+
+	columns, err := h.exportService.Columns(dataset, opts.IncludePII)
+	if err != nil {
+		middleware.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	checksum := sha256.New()
+	var encoder exportEncoder
+	if format == "csv" {
+		encoder = &csvExportEncoder{w: c.Writer, checksum: checksum}
+	} else {
+		encoder = &ndjsonExportEncoder{w: c.Writer, checksum: checksum}
+	}
+
+	// 首行数据写出前才发送响应头，导出无法开始时仍可返回JSON错误
+	started := false
+	start := func() error {
+		started = true
+		contentType := "application/x-ndjson"
+		if format == "csv" {
+			contentType = "text/csv; charset=utf-8"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s.%s", dataset, time.Now().Format("20060102150405"), format))
+		c.Status(http.StatusOK)
+		return encoder.writeHeader(columns)
+	}
+
+	flushRows := int64(h.cfg.Export.FlushRows)
+	if flushRows <= 0 {
+		flushRows = defaultExportFlushRows
+	}
+
+	var rows int64
+	summary, err := h.exportService.Export(c.Request.Context(), dataset, opts, func(row []interface{}) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := encoder.writeRow(columns, row); err != nil {
+			return err
+		}
+		rows++
+		// 首行立即刷新，客户端无需等待整个批次即可开始接收
+		if rows == 1 || rows%flushRows == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+
+	if err != nil && !started {
+		if errors.Is(err, service.ErrExportInProgress) {
+			middleware.ErrorResponse(c, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to export data")
+		return
+	}
+	if !started {
+		if err := start(); err != nil {
+			return
+		}
+	}
+
+	trailer := exportTrailer{
+		Trailer: true,
+		Dataset: dataset,
+		Rows:    rows,
+		SHA256:  hex.EncodeToString(checksum.Sum(nil)),
+	}
+	if err != nil {
+		trailer.Error = err.Error()
+		logger.Errorf("Export of %s failed after %d rows: %v", dataset, rows, err)
+	} else {
+		trailer.NextCursor = summary.NextCursor
+		trailer.Complete = summary.NextCursor == ""
+	}
+	encoder.writeTrailer(trailer)
+	c.Writer.Flush()
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	logger.Infof("Export of %s by user %d finished: %d rows (include_pii=%t)", dataset, userID, rows, opts.IncludePII)
+}
+
+// parseExportTime 解析since参数：带时区偏移的RFC3339时间转换为UTC，只有日期时按UTC零点
+func parseExportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// ndjsonExportEncoder NDJSON格式编码器，每行一个JSON对象
+type ndjsonExportEncoder struct {
+	w        io.Writer
+	checksum hash.Hash
+}
+
+func (e *ndjsonExportEncoder) writeHeader(columns []string) error {
+	return nil
+}
+
+func (e *ndjsonExportEncoder) writeRow(columns []string, row []interface{}) error {
+	record := make(map[string]interface{}, len(columns))
+	for i, name := range columns {
+		record[name] = row[i]
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	e.checksum.Write(line)
+	_, err = e.w.Write(line)
+	return err
+}
+
+func (e *ndjsonExportEncoder) writeTrailer(trailer exportTrailer) error {
+	line, err := json.Marshal(trailer)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(line, '\n'))
+	return err
+}
+
+// csvExportEncoder CSV格式编码器，首行为列名，末行为#trailer记录
+type csvExportEncoder struct {
+	w        io.Writer
+	checksum hash.Hash
+	csv      *csv.Writer
+}
+
+func (e *csvExportEncoder) writeHeader(columns []string) error {
+	// 数据行同时写入响应和校验和
+	e.csv = csv.NewWriter(io.MultiWriter(e.w, e.checksum))
+	header := csv.NewWriter(e.w)
+	if err := header.Write(columns); err != nil {
+		return err
+	}
+	header.Flush()
+	return header.Error()
+}
+
+func (e *csvExportEncoder) writeRow(columns []string, row []interface{}) error {
+	record := make([]string, len(row))
+	for i, v := range row {
+		record[i] = formatCSVValue(v)
+	}
+	if err := e.csv.Write(record); err != nil {
+		return err
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func (e *csvExportEncoder) writeTrailer(trailer exportTrailer) error {
+	w := csv.NewWriter(e.w)
+	record := []string{
+		"#trailer",
+		"dataset=" + trailer.Dataset,
+		"rows=" + strconv.FormatInt(trailer.Rows, 10),
+		"sha256=" + trailer.SHA256,
+		"next_cursor=" + trailer.NextCursor,
+		"complete=" + strconv.FormatBool(trailer.Complete),
+	}
+	if trailer.Error != "" {
+		record = append(record, "error="+trailer.Error)
+	}
+	if err := w.Write(record); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// formatCSVValue 将导出值格式化为CSV字段
+func formatCSVValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// ExportPackagesForMigration 以NDJSON流式导出全部包及其版本的完整元数据（管理员），用于迁移到其他仓库
+// 支持owner、updated_since筛选；include_download_urls=true时为每个版本附带预签名下载URL
+func (h *Handler) ExportPackagesForMigration(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	filter := &service.ExportFilter{Owner: c.Query("owner")}
+	if v := c.Query("updated_since"); v != "" {
+		t, err := parseExportTime(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "updated_since must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.UpdatedSince = &t
+	}
+	if v := c.Query("include_download_urls"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "include_download_urls must be a boolean")
+			return
+		}
+		filter.IncludeDownloadURLs = include
+	}
+	if filter.IncludeDownloadURLs && h.minioClient == nil {
+		middleware.ErrorResponse(c, http.StatusServiceUnavailable, "File storage is not available")
+		return
+	}
+
+	estimated, err := h.packageService.CountExportPackages(c.Request.Context(), filter)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to export packages")
+		return
+	}
+	details := gin.H{
+		"estimated_records":     estimated,
+		"owner":                 filter.Owner,
+		"updated_since":         filter.UpdatedSince,
+		"include_download_urls": filter.IncludeDownloadURLs,
+	}
+	if err := h.auditService.Record(c.Request.Context(), userID, "packages.export", "packages", details, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit package export: %v", err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=packages-%s.ndjson", time.Now().Format("2006-01-02")))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	// 响应已开始，出错时只能记录日志并结束输出，客户端可按行数与estimated_records比对
+	if err := h.packageService.BulkExportPackages(c.Request.Context(), c.Writer, filter); err != nil {
+		logger.Errorf("Package migration export interrupted: %v", err)
+	}
+}
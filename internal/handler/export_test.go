@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runExport 请求一次NDJSON导出，返回数据行和结束记录
+func runExport(t *testing.T, h *Handler, query url.Values) ([]map[string]interface{}, exportTrailer) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/export/packages?"+query.Encode(), nil)
+	h.ExportPackages(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	var rows []map[string]interface{}
+	var trailer exportTrailer
+	checksum := sha256.New()
+	scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, `"_trailer":true`) {
+			if err := json.Unmarshal([]byte(line), &trailer); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		checksum.Write([]byte(line + "\n"))
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+	if !trailer.Trailer {
+		t.Fatalf("response has no trailer: %s", w.Body.String())
+	}
+	if trailer.Rows != int64(len(rows)) {
+		t.Errorf("trailer rows = %d, got %d rows", trailer.Rows, len(rows))
+	}
+	if got := hex.EncodeToString(checksum.Sum(nil)); got != trailer.SHA256 {
+		t.Errorf("trailer sha256 = %s, computed %s", trailer.SHA256, got)
+	}
+	return rows, trailer
+}
+
+func TestExportNDJSONResume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, &models.User{}, &models.Package{})
+	owner := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := db.Create(owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := db.Create(&models.Package{Name: fmt.Sprintf("pkg-%d", i), OwnerID: owner.ID}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handler{cfg: &config.Config{}, exportService: service.NewExportService(db, config.ExportConfig{BatchSize: 2})}
+
+	first, trailer := runExport(t, h, url.Values{"limit": {"3"}})
+	if len(first) != 3 || trailer.Complete || trailer.NextCursor == "" {
+		t.Fatalf("first page: %d rows, trailer %+v; want 3 rows and a cursor", len(first), trailer)
+	}
+	for _, row := range first {
+		if _, ok := row["owner_email"]; ok {
+			t.Error("owner_email exported without include_pii")
+		}
+	}
+
+	rest, trailer := runExport(t, h, url.Values{"cursor": {trailer.NextCursor}})
+	if len(rest) != 2 || !trailer.Complete {
+		t.Fatalf("resumed export: %d rows, trailer %+v; want the remaining 2 rows", len(rest), trailer)
+	}
+	if rest[0]["name"] != "pkg-3" || rest[1]["name"] != "pkg-4" {
+		t.Errorf("resumed rows = %v, want pkg-3 and pkg-4", rest)
+	}
+}
+
+func TestExportRejectsInvalidLimitAndCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, &models.User{}, &models.Package{})
+	owner := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := db.Create(owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Package{Name: "pkg", OwnerID: owner.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{cfg: &config.Config{}, exportService: service.NewExportService(db, config.ExportConfig{})}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"negative limit", url.Values{"limit": {"-1"}}},
+		{"zero limit", url.Values{"limit": {"0"}}},
+		{"limit above maximum", url.Values{"limit": {strconv.Itoa(exportMaxLimit + 1)}}},
+		{"overflowing limit", url.Values{"limit": {"99999999999999999999"}}},
+		{"malformed limit", url.Values{"limit": {"10abc"}}},
+		{"cursor not base64", url.Values{"cursor": {"!!!"}}},
+		{"cursor padded base64", url.Values{"cursor": {base64.URLEncoding.EncodeToString([]byte("1"))}}},
+		{"cursor not a number", url.Values{"cursor": {b64([]byte("abc"))}}},
+		{"negative cursor", url.Values{"cursor": {b64([]byte("-1"))}}},
+		{"overflowing cursor", url.Values{"cursor": {b64([]byte("99999999999999999999999"))}}},
+		{"cursor with sql", url.Values{"cursor": {b64([]byte("1 OR 1=1"))}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/export/packages?"+tt.query.Encode(), nil)
+			h.ExportPackages(c)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// 格式正确但超出已有数据的cursor返回空结果，而不是报错或从头导出
+	rows, trailer := runExport(t, h, url.Values{"cursor": {service.EncodeExportCursor(1 << 40)}})
+	if len(rows) != 0 || !trailer.Complete {
+		t.Errorf("export past the end: %d rows, trailer %+v; want no rows and complete", len(rows), trailer)
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyFeatures 返回对当前用户启用的功能开关，客户端据此决定是否展示灰度中的功能
+func (h *Handler) GetMyFeatures(c *gin.Context) {
+	features := middleware.GetFeaturesFromContext(c)
+	if features == nil {
+		features = []string{}
+	}
+	middleware.SuccessResponse(c, gin.H{"features": features})
+}
+
+// ListFeatureFlags 列出全部功能开关（管理员）
+func (h *Handler) ListFeatureFlags(c *gin.Context) {
+	flags, err := h.flagService.ListFlags(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to list feature flags")
+		return
+	}
+	middleware.SuccessResponse(c, flags)
+}
+
+// CreateFeatureFlag 创建功能开关（管理员），记录features.create审计日志
+func (h *Handler) CreateFeatureFlag(c *gin.Context) {
+	actorID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.CreateFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	flag, err := h.flagService.CreateFlag(c.Request.Context(), &req)
+	if err != nil {
+		respondFeatureFlagError(c, err, "Failed to create feature flag")
+		return
+	}
+
+	h.auditFeatureFlag(c, actorID, "features.create", flag.Name, flag)
+	middleware.SuccessResponse(c, flag)
+}
+
+// UpdateFeatureFlag 修改功能开关（管理员），记录features.update审计日志
+func (h *Handler) UpdateFeatureFlag(c *gin.Context) {
+	actorID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.UpdateFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	flag, err := h.flagService.UpdateFlag(c.Request.Context(), c.Param("name"), &req)
+	if err != nil {
+		respondFeatureFlagError(c, err, "Failed to update feature flag")
+		return
+	}
+
+	h.auditFeatureFlag(c, actorID, "features.update", flag.Name, flag)
+	middleware.SuccessResponse(c, flag)
+}
+
+// DeleteFeatureFlag 删除功能开关（管理员），记录features.delete审计日志
+func (h *Handler) DeleteFeatureFlag(c *gin.Context) {
+	actorID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.flagService.DeleteFlag(c.Request.Context(), name); err != nil {
+		respondFeatureFlagError(c, err, "Failed to delete feature flag")
+		return
+	}
+
+	h.auditFeatureFlag(c, actorID, "features.delete", name, nil)
+	middleware.SuccessResponse(c, gin.H{"message": "Feature flag deleted successfully"})
+}
+
+// auditFeatureFlag 记录功能开关的修改
+func (h *Handler) auditFeatureFlag(c *gin.Context, actorID uint, action, name string, details interface{}) {
+	if err := h.auditService.Record(c.Request.Context(), actorID, action, "features/"+name, details, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit feature flag change: %v", err)
+	}
+}
+
+// respondFeatureFlagError 功能开关操作失败时的响应
+func respondFeatureFlagError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrFeatureFlagNotFound):
+		middleware.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrFeatureFlagExists):
+		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidFeatureFlagName):
+		middleware.ValidationErrorResponse(c, err.Error())
+	default:
+		middleware.InternalServerErrorResponse(c, fallback)
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldSet 资源允许通过fields参数选择的字段：字段名 -> 取值函数
+// 只有显式列出的字段可被选择，敏感字段（密码、邮箱、存储路径等）不在任何白名单中
+type fieldSet[T any] map[string]func(item T) interface{}
+
+// packageFields 包列表可选字段
+var packageFields = fieldSet[models.Package]{
+	"id":                         func(p models.Package) interface{} { return p.ID },
+	"name":                       func(p models.Package) interface{} { return p.Name },
+	"description":                func(p models.Package) interface{} { return p.Description },
+	"name_highlighted":           func(p models.Package) interface{} { return p.NameHighlighted },
+	"description_highlighted":    func(p models.Package) interface{} { return p.DescriptionHighlighted },
+	"author":                     func(p models.Package) interface{} { return p.Author },
+	"homepage":                   func(p models.Package) interface{} { return p.Homepage },
+	"repository":                 func(p models.Package) interface{} { return p.Repository },
+	"license":                    func(p models.Package) interface{} { return p.License },
+	"keywords":                   func(p models.Package) interface{} { return p.Keywords },
+	"categories":                 func(p models.Package) interface{} { return p.Categories },
+	"funding_url":                func(p models.Package) interface{} { return p.FundingURL },
+	"bug_tracker_url":            func(p models.Package) interface{} { return p.BugTrackerURL },
+	"documentation":              func(p models.Package) interface{} { return p.Documentation },
+	"icon_url":                   func(p models.Package) interface{} { return p.IconURL },
+	"is_private":                 func(p models.Package) interface{} { return p.IsPrivate },
+	"is_archived":                func(p models.Package) interface{} { return p.IsArchived },
+	"keep_recent_versions":       func(p models.Package) interface{} { return p.KeepRecentVersions },
+	"require_monotonic_versions": func(p models.Package) interface{} { return p.RequireMonotonicVersions },
+	"auto_prerelease_detection":  func(p models.Package) interface{} { return p.AutoPrereleaseDetection },
+	"disallow_prerelease_latest": func(p models.Package) interface{} { return p.DisallowPrereleaseLatest },
+	"trust_level":                func(p models.Package) interface{} { return p.TrustLevel },
+	"upload_locked":              func(p models.Package) interface{} { return p.UploadLocked },
+	"trusted_badge":              func(p models.Package) interface{} { return p.TrustedBadge },
+	"owner_id":                   func(p models.Package) interface{} { return p.OwnerID },
+	"owner":                      func(p models.Package) interface{} { return p.Owner.ToPublicUser() },
+	"created_at":                 func(p models.Package) interface{} { return p.CreatedAt },
+	"updated_at":                 func(p models.Package) interface{} { return p.UpdatedAt },
+}
+
+// packageDetailFields 包详情可选字段：列表中的字段加上只在详情中返回的字段
+var packageDetailFields = func() fieldSet[models.Package] {
+	set := fieldSet[models.Package]{
+		"long_description":              func(p models.Package) interface{} { return p.LongDescription },
+		"own_trust_level":               func(p models.Package) interface{} { return p.OwnTrustLevel },
+		"archived_at":                   func(p models.Package) interface{} { return p.ArchivedAt },
+		"lock_version":                  func(p models.Package) interface{} { return p.LockVersion },
+		"watch_count":                   func(p models.Package) interface{} { return p.WatchCount },
+		"monthly_bandwidth_limit_bytes": func(p models.Package) interface{} { return p.MonthlyBandwidthLimitBytes },
+		"versions":                      func(p models.Package) interface{} { return p.Versions },
+		"latest_version":                latestVersionField,
+		"recommendations":               func(p models.Package) interface{} { return p.Recommendations },
+	}
+	for name, value := range packageFields {
+		set[name] = value
+	}
+	return set
+}()
+
+// latestVersionField 最近发布的版本号（与关注列表相同，按发布顺序），没有版本时为null
+func latestVersionField(p models.Package) interface{} {
+	var latest *models.PackageVersion
+	for i := range p.Versions {
+		if latest == nil || p.Versions[i].ID > latest.ID {
+			latest = &p.Versions[i]
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return latest.Version
+}
+
+// versionFields 版本列表可选字段
+var versionFields = fieldSet[models.PackageVersion]{
+	"id":                  func(v models.PackageVersion) interface{} { return v.ID },
+	"package_id":          func(v models.PackageVersion) interface{} { return v.PackageID },
+	"version":             func(v models.PackageVersion) interface{} { return v.Version },
+	"description":         func(v models.PackageVersion) interface{} { return v.Description },
+	"changelog":           func(v models.PackageVersion) interface{} { return v.Changelog },
+	"dependencies":        func(v models.PackageVersion) interface{} { return v.Dependencies },
+	"file_size":           func(v models.PackageVersion) interface{} { return v.FileSize },
+	"file_hash":           func(v models.PackageVersion) interface{} { return v.FileHash },
+	"compressed_stored":   func(v models.PackageVersion) interface{} { return v.CompressedStored },
+	"storage_tier":        func(v models.PackageVersion) interface{} { return v.StorageTier },
+	"download_count":      func(v models.PackageVersion) interface{} { return v.DownloadCount },
+	"install_count":       func(v models.PackageVersion) interface{} { return v.InstallCount },
+	"is_prerelease":       func(v models.PackageVersion) interface{} { return v.IsPrerelease },
+	"pinned":              func(v models.PackageVersion) interface{} { return v.Pinned },
+	"locked":              func(v models.PackageVersion) interface{} { return v.Locked },
+	"scan_status":         func(v models.PackageVersion) interface{} { return v.ScanStatus },
+	"deprecated":          func(v models.PackageVersion) interface{} { return v.Deprecated },
+	"deprecation_message": func(v models.PackageVersion) interface{} { return v.DeprecationMessage },
+	"source_repository":   func(v models.PackageVersion) interface{} { return v.SourceRepository },
+	"source_commit":       func(v models.PackageVersion) interface{} { return v.SourceCommit },
+	"build_url":           func(v models.PackageVersion) interface{} { return v.BuildURL },
+	"published_manifest":  func(v models.PackageVersion) interface{} { return v.PublishedManifest },
+	"uploader_id":         func(v models.PackageVersion) interface{} { return v.UploaderID },
+	"uploader":            func(v models.PackageVersion) interface{} { return v.Uploader.ToPublicUser() },
+	"created_at":          func(v models.PackageVersion) interface{} { return v.CreatedAt },
+	"updated_at":          func(v models.PackageVersion) interface{} { return v.UpdatedAt },
+}
+
+// downloadFields 下载记录CSV列
+var downloadFields = fieldSet[models.PackageDownload]{
+	"id":                 func(d models.PackageDownload) interface{} { return d.ID },
+	"package_version_id": func(d models.PackageDownload) interface{} { return d.PackageVersionID },
+	"version":            func(d models.PackageDownload) interface{} { return d.PackageVersion.Version },
+	"user_id":            func(d models.PackageDownload) interface{} { return d.UserID },
+	"ip_address":         func(d models.PackageDownload) interface{} { return d.IPAddress },
+	"user_agent":         func(d models.PackageDownload) interface{} { return d.UserAgent },
+	"download_time":      func(d models.PackageDownload) interface{} { return d.DownloadTime },
+}
+
+// downloadCSVColumns 下载记录CSV默认列顺序
+var downloadCSVColumns = []string{"id", "package_version_id", "version", "user_id", "ip_address", "user_agent", "download_time"}
+
+// packageCSVColumns 包列表CSV默认列（未指定fields时）
+var packageCSVColumns = []string{"id", "name", "description", "author", "license", "is_private", "owner_id", "created_at", "updated_at"}
+
+// userFields 用户列表可选字段（基于公开用户信息）
+var userFields = fieldSet[*models.PublicUser]{
+	"id":            func(u *models.PublicUser) interface{} { return u.ID },
+	"username":      func(u *models.PublicUser) interface{} { return u.Username },
+	"nickname":      func(u *models.PublicUser) interface{} { return u.Nickname },
+	"avatar":        func(u *models.PublicUser) interface{} { return u.Avatar },
+	"status":        func(u *models.PublicUser) interface{} { return u.Status },
+	"trust_level":   func(u *models.PublicUser) interface{} { return u.TrustLevel },
+	"trusted_badge": func(u *models.PublicUser) interface{} { return u.TrustedBadge },
+	"created_at":    func(u *models.PublicUser) interface{} { return u.CreatedAt },
+}
+
+// names 返回排序后的可选字段名
+func (set fieldSet[T]) names() []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseFields 解析fields查询参数，未指定时返回nil表示返回完整对象
+func parseFields[T any](c *gin.Context, set fieldSet[T]) ([]string, error) {
+	raw := strings.TrimSpace(c.Query("fields"))
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields, unknown []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, ok := set[name]; !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		fields = append(fields, name)
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown fields: %s; valid fields: %s",
+			strings.Join(unknown, ", "), strings.Join(set.names(), ", "))
+	}
+	return fields, nil
+}
+
+// projectFields 按选择的字段将列表转换为精简的DTO
+func projectFields[T any](items []T, fields []string, set fieldSet[T]) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		result[i] = projectItem(item, fields, set)
+	}
+	return result
+}
+
+// projectItem 按选择的字段将单个对象转换为精简的DTO
+func projectItem[T any](item T, fields []string, set fieldSet[T]) map[string]interface{} {
+	row := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		row[name] = set[name](item)
+	}
+	return row
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fieldsContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+	return c
+}
+
+func TestParseFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr string
+	}{
+		{name: "not requested", query: "", want: nil},
+		{name: "selected fields", query: "fields=name,description", want: []string{"name", "description"}},
+		{name: "spaces and duplicates", query: "fields=+name+,,name,id", want: []string{"name", "id"}},
+		{name: "unknown field", query: "fields=name,bogus", wantErr: "unknown fields: bogus"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFields(fieldsContext(tt.query), packageFields)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), "valid fields: ") {
+					t.Errorf("error %q does not list the valid fields", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFields: %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldsRejectSensitiveFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 敏感字段不能通过fields参数加回响应
+	sensitive := []string{"password", "email", "minio_path", "min_io_path", "stored_size", "monthly_bandwidth_limit_bytes"}
+	for _, name := range sensitive {
+		if _, err := parseFields(fieldsContext("fields="+name), packageFields); err == nil {
+			t.Errorf("package field %q was accepted", name)
+		}
+		if _, err := parseFields(fieldsContext("fields="+name), versionFields); err == nil {
+			t.Errorf("version field %q was accepted", name)
+		}
+		if _, err := parseFields(fieldsContext("fields="+name), userFields); err == nil {
+			t.Errorf("user field %q was accepted", name)
+		}
+	}
+
+	// 展开的用户对象也只包含公开信息
+	pkg := models.Package{Owner: models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}}
+	data, _ := json.Marshal(projectItem(pkg, []string{"owner"}, packageFields))
+	if strings.Contains(string(data), "alice@example.com") || strings.Contains(string(data), "hash") {
+		t.Errorf("owner projection leaks private data: %s", data)
+	}
+}
+
+func TestPackageListDataKeepsPagination(t *testing.T) {
+	response := &models.PackageListResponse{
+		Packages:   []models.Package{{ID: 1, Name: "a", Description: "long text", Keywords: `["x"]`}},
+		Pagination: models.NewPagination(2, 10, 11),
+	}
+
+	if got := packageListData(response, nil); got != response {
+		t.Error("response without fields was modified")
+	}
+
+	data, err := json.Marshal(packageListData(response, []string{"name"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	json.Unmarshal(data, &decoded)
+	for _, key := range []string{"total", "page", "page_size", "total_pages", "has_next", "has_prev"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("pagination field %q missing from %s", key, data)
+		}
+	}
+	packages := decoded["packages"].([]interface{})
+	row := packages[0].(map[string]interface{})
+	if len(row) != 1 || row["name"] != "a" {
+		t.Errorf("package row = %v, want only name", row)
+	}
+	full, _ := json.Marshal(response)
+	if len(data) >= len(full) {
+		t.Errorf("filtered payload is %d bytes, full payload %d bytes", len(data), len(full))
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+// GraphQLRequest GraphQL查询请求结构体
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlViewerKey 解析器从context中读取当前用户ID的键
+type graphqlViewerKey struct{}
+
+// GraphQL 执行只读的GraphQL查询（包和版本），受graphql功能开关控制
+// 响应按GraphQL约定返回顶层的data和errors，解析错误（如包不存在）放在errors中，HTTP状态仍为200
+func (h *Handler) GraphQL(c *gin.Context) {
+	var req GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphqlViewerKey{}, optionalUserID(c))
+	result := graphql.Do(graphql.Params{
+		Schema:         h.graphqlSchema,
+		RequestString:  req.Query,
+		OperationName:  req.OperationName,
+		VariableValues: req.Variables,
+		Context:        ctx,
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// newGraphQLSchema 创建GraphQL schema，字段名与REST接口的JSON字段一致
+// 读取权限与REST接口相同：私有包只有所有者和协作者可以查看，搜索只返回公开包
+func newGraphQLSchema(packageService *service.PackageService) (graphql.Schema, error) {
+	versionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PackageVersion",
+		Fields: graphql.Fields{
+			"version":             &graphql.Field{Type: graphql.String},
+			"description":         &graphql.Field{Type: graphql.String},
+			"changelog":           &graphql.Field{Type: graphql.String},
+			"file_size":           &graphql.Field{Type: graphql.Int},
+			"file_hash":           &graphql.Field{Type: graphql.String},
+			"download_count":      &graphql.Field{Type: graphql.Int},
+			"is_prerelease":       &graphql.Field{Type: graphql.Boolean},
+			"deprecated":          &graphql.Field{Type: graphql.Boolean},
+			"deprecation_message": &graphql.Field{Type: graphql.String},
+			"created_at":          &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	packageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Package",
+		Fields: graphql.Fields{
+			"name":        &graphql.Field{Type: graphql.String},
+			"description": &graphql.Field{Type: graphql.String},
+			"author":      &graphql.Field{Type: graphql.String},
+			"homepage":    &graphql.Field{Type: graphql.String},
+			"repository":  &graphql.Field{Type: graphql.String},
+			"license":     &graphql.Field{Type: graphql.String},
+			"is_private":  &graphql.Field{Type: graphql.Boolean},
+			"is_archived": &graphql.Field{Type: graphql.Boolean},
+			"trust_level": &graphql.Field{Type: graphql.String},
+			"watch_count": &graphql.Field{Type: graphql.Int},
+			"created_at":  &graphql.Field{Type: graphql.DateTime},
+			"updated_at":  &graphql.Field{Type: graphql.DateTime},
+			// 搜索结果不预加载版本，返回空列表
+			"versions": &graphql.Field{Type: graphql.NewList(versionType)},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"package": &graphql.Field{
+				Type: packageType,
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					return packageService.GetPackage(p.Context, name, graphqlViewer(p.Context))
+				},
+			},
+			"packages": &graphql.Field{
+				Type: graphql.NewList(packageType),
+				Args: graphql.FieldConfigArgument{
+					"query":     &graphql.ArgumentConfig{Type: graphql.String},
+					"page":      &graphql.ArgumentConfig{Type: graphql.Int},
+					"page_size": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					isPrivate := false
+					req := &models.SearchPackagesRequest{IsPrivate: &isPrivate}
+					req.Query, _ = p.Args["query"].(string)
+					req.Page, _ = p.Args["page"].(int)
+					req.PageSize, _ = p.Args["page_size"].(int)
+					req.Page, req.PageSize = models.NormalizePage(req.Page, req.PageSize, models.DefaultPageSize, models.MaxPageSize)
+
+					response, err := packageService.SearchPackages(p.Context, req)
+					if err != nil {
+						return nil, err
+					}
+					return response.Packages, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// graphqlViewer 从解析器的context中取出当前用户ID
+func graphqlViewer(ctx context.Context) *uint {
+	viewerID, _ := ctx.Value(graphqlViewerKey{}).(*uint)
+	return viewerID
+}
@@ -1,44 +1,132 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"webservice/internal/analytics"
+	"webservice/internal/authz"
+	"webservice/internal/botdetect"
 	"webservice/internal/config"
+	"webservice/internal/events"
+	"webservice/internal/feature"
+	"webservice/internal/httpclient"
+	"webservice/internal/logger"
 	"webservice/internal/middleware"
 	"webservice/internal/minio"
 	"webservice/internal/models"
 	"webservice/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
 	"gorm.io/gorm"
 )
 
 // Handler 处理器结构体
 type Handler struct {
-	cfg            *config.Config
-	db             *gorm.DB
-	userService    *service.UserService
-	packageService *service.PackageService
-	PackageHandler *PackageHandler
+	cfg              *config.Config
+	db               *gorm.DB
+	userService      *service.UserService
+	packageService   *service.PackageService
+	bootstrapService *service.BootstrapService
+	sessionService   *service.SessionService
+	apiTokenService  *service.APITokenService
+	tieringService   *service.StorageTieringService
+	integrity        *service.IntegrityService
+	deprecations     *service.DeprecationService
+	exportService    *service.ExportService
+	auditService     *service.AuditService
+	watchService     *service.WatchService
+	categoryService  *service.CategoryService
+	billingService   *service.BillingService
+	wikiService      *service.WikiService
+	healthHistory    *service.HealthHistoryService
+	flagService      *service.FeatureFlagService
+	leaderboards     *service.LeaderboardService
+	graphqlSchema    graphql.Schema
+	minioClient      *minio.Client       // 可能为nil（存储不可用）
+	httpClients      *httpclient.Factory // 出站HTTP客户端，访问外部服务的功能通过它创建客户端
+	PackageHandler   *PackageHandler
+	Policy           *authz.Policy // 管理接口授权策略，路由和处理器共用
+
+	BotDetector *botdetect.Detector // 搜索防护和爬虫识别，路由和处理器共用
+
+	identityVerifiers map[string]service.IdentityVerifier // 外部身份提供方 -> 凭据校验器，通过RegisterIdentityVerifier注册
 }
 
 // NewHandler 创建处理器实例
-func NewHandler(cfg *config.Config, db *gorm.DB, minioClient *minio.Client) *Handler {
-	userService := service.NewUserService(db)
-	packageService := service.NewPackageService(db, minioClient)
-	packageHandler := NewPackageHandler(packageService)
+func NewHandler(cfg *config.Config, db *gorm.DB, minioClient *minio.Client, httpClients *httpclient.Factory, healthHistory *service.HealthHistoryService, leaderboards *service.LeaderboardService, featureFlags *feature.Flags) *Handler {
+	// 事件总线：下载记录等高频事件的异步处理；包/版本变更事件写入发件箱，由outbox.Dispatcher投递
+	eventBus := events.NewEventBus(events.DefaultWorkers)
+
+	userService := service.NewUserService(db, cfg.Password)
+	packagesCfg := cfg.Packages
+	if packagesCfg.DownloadURLSecret == "" {
+		packagesCfg.DownloadURLSecret = cfg.JWT.Secret
+	}
+	packageService := service.NewPackageService(db, minioClient, eventBus, packagesCfg)
+	analyticsService := newAnalyticsService(cfg.Analytics, db, len(cfg.MinIO.Mirrors) > 0)
+	if cfg.Analytics.Enabled {
+		eventBus.Subscribe(events.DownloadRecorded, analyticsService.OnDownloadRecorded)
+	}
+	botDetector := botdetect.New(cfg.SearchProtection, cfg.JWT.Secret)
+	packageHandler := NewPackageHandler(packageService, analyticsService, botDetector, cfg.Server.StrictAccept, cfg.Packages.AliasMode)
+	// schema是静态定义的，创建失败说明定义本身有误
+	graphqlSchema, err := newGraphQLSchema(packageService)
+	if err != nil {
+		panic(fmt.Sprintf("invalid graphql schema: %v", err))
+	}
 
 	return &Handler{
-		cfg:            cfg,
-		db:             db,
-		userService:    userService,
-		packageService: packageService,
-		PackageHandler: packageHandler,
+		cfg:              cfg,
+		db:               db,
+		userService:      userService,
+		packageService:   packageService,
+		bootstrapService: service.NewBootstrapService(db, cfg.Bootstrap, cfg.Password),
+		sessionService:   service.NewSessionService(db),
+		apiTokenService:  service.NewAPITokenService(db),
+		tieringService:   service.NewStorageTieringService(db, minioClient),
+		integrity:        service.NewIntegrityService(db, minioClient, cfg.Integrity),
+		deprecations:     service.NewDeprecationService(db),
+		exportService:    service.NewExportService(db, cfg.Export),
+		auditService:     service.NewAuditService(db),
+		watchService:     service.NewWatchService(db),
+		categoryService:  service.NewCategoryService(db),
+		billingService:   service.NewBillingService(db),
+		wikiService:      service.NewWikiService(db),
+		healthHistory:    healthHistory,
+		flagService:      service.NewFeatureFlagService(db, featureFlags),
+		leaderboards:     leaderboards,
+		graphqlSchema:    graphqlSchema,
+		minioClient:      minioClient,
+		httpClients:      httpClients,
+		PackageHandler:   packageHandler,
+		Policy:           authz.NewPolicy(cfg.Authz),
+		BotDetector:      botDetector,
 	}
 }
 
+// newAnalyticsService 创建下载分析服务，GeoIP数据库加载失败时只记录警告并跳过国家解析
+// 下载镜像按客户端国家选择，配置了镜像时即使未启用下载分析也加载GeoIP数据库
+func newAnalyticsService(cfg config.AnalyticsConfig, db *gorm.DB, needGeo bool) *service.DownloadAnalyticsService {
+	if !(cfg.Enabled || needGeo) || cfg.GeoIPDatabase == "" {
+		return service.NewDownloadAnalyticsService(db, nil)
+	}
+
+	geoDB, err := analytics.LoadCSVGeoDatabase(cfg.GeoIPDatabase)
+	if err != nil {
+		logger.Warnf("GeoIP database unavailable, country resolution disabled: %v", err)
+		return service.NewDownloadAnalyticsService(db, nil)
+	}
+	logger.Infof("Loaded GeoIP database with %d ranges", geoDB.Len())
+	return service.NewDownloadAnalyticsService(db, geoDB)
+}
+
 // HealthCheck 健康检查
 func (h *Handler) HealthCheck(c *gin.Context) {
 	// 检查数据库连接
@@ -69,14 +157,17 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	// 验证用户
-	user, err := h.userService.AuthenticateUser(req.Username, req.Password)
+	user, err := h.userService.AuthenticateUser(c.Request.Context(), req.Username, req.Password, req.TOTPCode)
 	if err != nil {
+		if respondAccountSuspended(c, err) {
+			return
+		}
 		middleware.UnauthorizedResponse(c, err.Error())
 		return
 	}
 
-	// 生成JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Role, h.cfg.JWT)
+	// 生成JWT token并记录会话
+	token, err := h.issueToken(c, user)
 	if err != nil {
 		middleware.InternalServerErrorResponse(c, "Failed to generate token")
 		return
@@ -97,14 +188,51 @@ func (h *Handler) Register(c *gin.Context) {
 	}
 
 	// 创建用户
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
 		return
 	}
 
-	// 生成JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Role, h.cfg.JWT)
+	// 生成JWT token并记录会话
+	token, err := h.issueToken(c, user)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to generate token")
+		return
+	}
+
+	middleware.SuccessResponse(c, models.LoginResponse{
+		User:  user.ToPublicUser(),
+		Token: token,
+	})
+}
+
+// Setup 首次启动创建管理员（需要启动日志中的一次性安装令牌）
+func (h *Handler) Setup(c *gin.Context) {
+	var req models.SetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	user, err := h.bootstrapService.CompleteSetup(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSetupCompleted):
+			middleware.ForbiddenResponse(c, err.Error())
+		case errors.Is(err, service.ErrInvalidSetupToken), errors.Is(err, service.ErrSetupTokenExpired):
+			middleware.UnauthorizedResponse(c, err.Error())
+		case errors.Is(err, service.ErrUsernameExists), errors.Is(err, service.ErrEmailExists):
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			logger.Errorf("Failed to complete setup: %v", err)
+			middleware.InternalServerErrorResponse(c, "Failed to complete setup")
+		}
+		return
+	}
+
+	// 生成JWT token并记录会话
+	token, err := h.issueToken(c, user)
 	if err != nil {
 		middleware.InternalServerErrorResponse(c, "Failed to generate token")
 		return
@@ -126,13 +254,37 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// 已吊销的会话不允许刷新
+	oldClaims, err := middleware.ParseToken(req.Token, h.cfg.JWT)
+	if err != nil {
+		middleware.UnauthorizedResponse(c, err.Error())
+		return
+	}
+	if oldClaims.ID != "" {
+		if _, active, err := h.sessionService.IsSessionActive(oldClaims.ID); err != nil || !active {
+			middleware.UnauthorizedResponse(c, "session has been revoked")
+			return
+		}
+	}
+
 	// 刷新token
-	newToken, err := middleware.RefreshToken(req.Token, h.cfg.JWT)
+	newToken, claims, err := middleware.RefreshToken(req.Token, h.cfg.JWT)
 	if err != nil {
 		middleware.UnauthorizedResponse(c, err.Error())
 		return
 	}
 
+	// 会话沿用到新token，旧token随之失效
+	if oldClaims.ID != "" {
+		err = h.sessionService.RotateSession(oldClaims.ID, claims.ID, claims.ExpiresAt.Time)
+	} else {
+		_, err = h.sessionService.CreateSession(claims.UserID, claims.ID, claims.ExpiresAt.Time, c.Request.UserAgent(), c.ClientIP())
+	}
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to refresh session")
+		return
+	}
+
 	middleware.SuccessResponse(c, gin.H{"token": newToken})
 }
 
@@ -144,13 +296,22 @@ func (h *Handler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(userID)
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
 		middleware.NotFoundResponse(c, "User not found")
 		return
 	}
 
-	middleware.SuccessResponse(c, user.ToPublicUser())
+	// 返回token有效期，客户端可据此在过期前自动刷新
+	expiresAt, ok := middleware.GetTokenExpiryFromContext(c)
+	if !ok {
+		middleware.SuccessResponse(c, user.ToPublicUser())
+		return
+	}
+	middleware.SuccessResponseWithMeta(c, user.ToPublicUser(), gin.H{
+		"token_expires_in": int(time.Until(expiresAt).Seconds()),
+		"token_expires_at": expiresAt.Format(time.RFC3339),
+	})
 }
 
 // UpdateProfile 更新用户个人资料
@@ -167,7 +328,7 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateProfile(userID, &req)
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID, &req)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
 		return
@@ -178,35 +339,112 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 
 // Logout 用户登出
 func (h *Handler) Logout(c *gin.Context) {
-	// 在实际应用中，这里可以将token加入黑名单
-	// 目前只是返回成功响应
+	// 吊销当前token对应的会话
+	userID, hasUser := middleware.GetUserIDFromContext(c)
+	sessionID, hasSession := middleware.GetSessionIDFromContext(c)
+	if hasUser && hasSession {
+		if err := h.sessionService.RevokeSession(userID, sessionID); err != nil && err.Error() != "session not found" {
+			middleware.InternalServerErrorResponse(c, "Failed to revoke session")
+			return
+		}
+	}
+
 	middleware.SuccessResponse(c, gin.H{"message": "Logged out successfully"})
 }
 
-// GetUsers 获取用户列表（管理员）
-func (h *Handler) GetUsers(c *gin.Context) {
-	// 获取查询参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	role := c.Query("role")
-	statusStr := c.Query("status")
-
-	var status models.UserStatus
-	if statusStr != "" {
-		if s, err := strconv.Atoi(statusStr); err == nil {
-			status = models.UserStatus(s)
+// GetSessions 获取当前用户的活跃会话（设备）列表
+func (h *Handler) GetSessions(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	sessions, err := h.sessionService.ListActiveSessions(userID)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get sessions")
+		return
+	}
+
+	// 标记发起请求的会话
+	currentID, _ := middleware.GetSessionIDFromContext(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	middleware.SuccessResponse(c, gin.H{"sessions": sessions})
+}
+
+// RevokeSession 远程吊销指定会话
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid session ID")
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(userID, uint(sessionID)); err != nil {
+		if err.Error() == "session not found" {
+			middleware.NotFoundResponse(c, err.Error())
+			return
 		}
+		middleware.InternalServerErrorResponse(c, "Failed to revoke session")
+		return
 	}
 
-	// 限制分页大小
-	if pageSize > 100 {
-		pageSize = 100
+	middleware.SuccessResponse(c, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeAllSessions 吊销当前用户的所有会话（登出所有设备）
+func (h *Handler) RevokeAllSessions(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
 	}
-	if page < 1 {
-		page = 1
+
+	revoked, err := h.sessionService.RevokeAllSessions(userID)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to revoke sessions")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"revoked": revoked})
+}
+
+// issueToken 为用户签发token并记录对应的设备会话
+func (h *Handler) issueToken(c *gin.Context, user *models.User) (string, error) {
+	token, claims, err := middleware.GenerateToken(user.ID, user.Username, user.Role, h.cfg.JWT)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := h.sessionService.CreateSession(user.ID, claims.ID, claims.ExpiresAt.Time, c.Request.UserAgent(), c.ClientIP()); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetUsers 获取用户列表（管理员）
+func (h *Handler) GetUsers(c *gin.Context) {
+	filter, ok := parseUserListFilter(c)
+	if !ok {
+		return
+	}
+
+	fields, err := parseFields(c, userFields)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
 	}
 
-	users, total, err := h.userService.GetUsers(page, pageSize, role, status)
+	users, total, err := h.userService.GetUsers(c.Request.Context(), filter)
 	if err != nil {
 		middleware.InternalServerErrorResponse(c, "Failed to get users")
 		return
@@ -218,15 +456,71 @@ func (h *Handler) GetUsers(c *gin.Context) {
 		publicUsers[i] = user.ToPublicUser()
 	}
 
-	middleware.SuccessResponse(c, gin.H{
-		"users": publicUsers,
-		"pagination": gin.H{
-			"page":       page,
-			"page_size":  pageSize,
-			"total":      total,
-			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
-		},
-	})
+	middleware.SuccessResponse(c, paginatedData("users", userListData(publicUsers, fields), models.NewPagination(filter.Page, filter.PageSize, total)))
+}
+
+// userSortFields 用户列表支持的排序字段
+var userSortFields = map[string]bool{
+	service.UserSortCreatedAt: true,
+	service.UserSortLastLogin: true,
+	service.UserSortUsername:  true,
+}
+
+// parseUserListFilter 解析用户列表的查询参数，参数无效时写入400响应并返回false
+// status可以是名称或数值，未提供时不按状态筛选；sort为created_at、last_login或username，order为asc或desc（默认）
+func parseUserListFilter(c *gin.Context) (*service.UserListFilter, bool) {
+	filter := &service.UserListFilter{
+		Role:  c.Query("role"),
+		Query: strings.TrimSpace(c.Query("q")),
+		Sort:  c.Query("sort"),
+	}
+	filter.Page, filter.PageSize = parsePage(c, 10, 100)
+
+	if v := c.Query("status"); v != "" {
+		status, err := models.ParseUserStatus(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, err.Error())
+			return nil, false
+		}
+		filter.Status = &status
+	}
+	if len(filter.Query) > 100 {
+		middleware.ValidationErrorResponse(c, "q must be at most 100 characters")
+		return nil, false
+	}
+	if v := c.Query("created_after"); v != "" {
+		t, err := parseExportTime(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "created_after must be RFC3339 or YYYY-MM-DD")
+			return nil, false
+		}
+		filter.CreatedAfter = &t
+	}
+	if v := c.Query("created_before"); v != "" {
+		t, err := parseExportTime(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "created_before must be RFC3339 or YYYY-MM-DD")
+			return nil, false
+		}
+		filter.CreatedBefore = &t
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		middleware.ValidationErrorResponse(c, "created_after must be before created_before")
+		return nil, false
+	}
+	if filter.Sort != "" && !userSortFields[filter.Sort] {
+		middleware.ValidationErrorResponse(c, "sort must be one of created_at, last_login, username")
+		return nil, false
+	}
+	switch strings.ToLower(c.Query("order")) {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		middleware.ValidationErrorResponse(c, "order must be asc or desc")
+		return nil, false
+	}
+	return filter, true
 }
 
 // GetUser 获取单个用户信息（管理员）
@@ -238,7 +532,7 @@ func (h *Handler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		middleware.NotFoundResponse(c, "User not found")
 		return
@@ -262,7 +556,25 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	target, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
+	if err != nil {
+		middleware.NotFoundResponse(c, "User not found")
+		return
+	}
+
+	// 按目标用户的角色检查权限，修改角色时新角色也需要在允许范围内（admin不能管理或提升为admin/super）
+	role := c.GetString("role")
+	if !h.Policy.Allowed(role, authz.ActionUserUpdate, target.Role) {
+		middleware.ForbiddenResponse(c, "Insufficient permissions to manage this user")
+		return
+	}
+	if req.Role != "" && req.Role != target.Role &&
+		(!h.Policy.Allowed(role, authz.ActionUserRole, target.Role) || !h.Policy.Allowed(role, authz.ActionUserRole, req.Role)) {
+		middleware.ForbiddenResponse(c, "Insufficient permissions to change this user's role")
+		return
+	}
+
+	user, err := h.userService.UpdateUser(c.Request.Context(), uint(id), &req)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
 		return
@@ -280,7 +592,17 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.DeleteUser(uint(id)); err != nil {
+	target, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
+	if err != nil {
+		middleware.NotFoundResponse(c, "User not found")
+		return
+	}
+	if !h.Policy.Allowed(c.GetString("role"), authz.ActionUserDelete, target.Role) {
+		middleware.ForbiddenResponse(c, "Insufficient permissions to delete this user")
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), uint(id)); err != nil {
 		middleware.InternalServerErrorResponse(c, "Failed to delete user")
 		return
 	}
@@ -291,32 +613,21 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 // GetPublicUsers 获取公开用户列表
 func (h *Handler) GetPublicUsers(c *gin.Context) {
 	// 获取查询参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	page, pageSize := parsePage(c, 10, 50)
 
-	// 限制分页大小
-	if pageSize > 50 {
-		pageSize = 50
-	}
-	if page < 1 {
-		page = 1
+	fields, err := parseFields(c, userFields)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
 	}
 
-	users, total, err := h.userService.GetPublicUsers(page, pageSize)
+	users, total, err := h.userService.GetPublicUsers(c.Request.Context(), page, pageSize)
 	if err != nil {
 		middleware.InternalServerErrorResponse(c, "Failed to get users")
 		return
 	}
 
-	middleware.SuccessResponse(c, gin.H{
-		"users": users,
-		"pagination": gin.H{
-			"page":       page,
-			"page_size":  pageSize,
-			"total":      total,
-			"total_page": (total + int64(pageSize) - 1) / int64(pageSize),
-		},
-	})
+	middleware.SuccessResponse(c, paginatedData("users", userListData(users, fields), models.NewPagination(page, pageSize, total)))
 }
 
 // GetPublicUser 获取公开用户信息
@@ -328,7 +639,7 @@ func (h *Handler) GetPublicUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(uint(id))
+	user, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
 	if err != nil {
 		middleware.NotFoundResponse(c, "User not found")
 		return
@@ -342,3 +653,137 @@ func (h *Handler) GetPublicUser(c *gin.Context) {
 
 	middleware.SuccessResponse(c, user.ToPublicUser())
 }
+
+// userListData 按fields参数生成用户列表数据
+func userListData(users []*models.PublicUser, fields []string) interface{} {
+	if fields == nil {
+		return users
+	}
+	return projectFields(users, fields, userFields)
+}
+
+// GetStorageTierSummary 获取包文件在各存储层级的分布（管理员）
+func (h *Handler) GetStorageTierSummary(c *gin.Context) {
+	summary, err := h.tieringService.GetTierSummary(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get storage tier summary")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"tiers": summary})
+}
+
+// GetStorageStats 获取存储统计（管理员）：各存储层级的分布、当前的上传限速配置和完整性校验结果
+func (h *Handler) GetStorageStats(c *gin.Context) {
+	summary, err := h.tieringService.GetTierSummary(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get storage stats")
+		return
+	}
+	integrity, err := h.integrity.GetReport(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get storage stats")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"available": h.minioClient != nil,
+		"tiers":     summary,
+		"throttle":  h.packageService.UploadThrottleSettings(),
+		"integrity": integrity,
+	})
+}
+
+// GetMirrorStatus 获取下载镜像的熔断状态（管理员），未配置镜像或存储不可用时返回空列表
+func (h *Handler) GetMirrorStatus(c *gin.Context) {
+	var selector *minio.MirrorSelector
+	if h.minioClient != nil {
+		selector = h.minioClient.Mirrors()
+	}
+
+	middleware.SuccessResponse(c, gin.H{"mirrors": selector.Status()})
+}
+
+// GetUnrecognizedLicenses 获取无法自动规范化的许可证值（管理员），附带相近的合法标识符
+func (h *Handler) GetUnrecognizedLicenses(c *gin.Context) {
+	licenses, err := h.packageService.GetUnrecognizedLicenses(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get unrecognized licenses")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"licenses": licenses})
+}
+
+// GetDeprecationUsage 获取弃用路由的调用统计（管理员），默认统计最近30天
+func (h *Handler) GetDeprecationUsage(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		middleware.ValidationErrorResponse(c, "days must be between 1 and 365")
+		return
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	stats, err := h.deprecations.GetUsageReport(c.Request.Context(), since)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get deprecated route usage")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"since":  since,
+		"routes": stats,
+	})
+}
+
+// ExportUsersCSV 导出用户列表CSV（仅super角色，用于合规报告）
+func (h *Handler) ExportUsersCSV(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	filters := &service.UserExportFilters{Role: c.Query("role")}
+	if v := c.Query("created_after"); v != "" {
+		t, err := parseExportTime(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "created_after must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filters.CreatedAfter = &t
+	}
+	if v := c.Query("created_before"); v != "" {
+		t, err := parseExportTime(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "created_before must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filters.CreatedBefore = &t
+	}
+	if v := c.Query("status"); v != "" {
+		status, err := models.ParseUserStatus(v)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		filters.Status = &status
+	}
+
+	reader, err := h.userService.ExportUsersCSV(c.Request.Context(), filters)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to export users")
+		return
+	}
+
+	if err := h.auditService.Record(c.Request.Context(), userID, "users.export", "users", filters, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit user export: %v", err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=users-%s.csv", time.Now().Format("2006-01-02")))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		logger.Errorf("User export interrupted: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"webservice/internal/middleware"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultHealthHistoryWindow 未指定window时的统计窗口
+const defaultHealthHistoryWindow = 24 * time.Hour
+
+// GetHealthHistory 获取最近window（默认24h）内数据库、存储和整体的可用率及停机区间
+func (h *Handler) GetHealthHistory(c *gin.Context) {
+	window := defaultHealthHistoryWindow
+	if raw := c.Query("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			middleware.ErrorResponse(c, http.StatusBadRequest, "Invalid window, use a duration such as 1h or 24h")
+			return
+		}
+		window = parsed
+	}
+
+	history, err := h.healthHistory.History(c.Request.Context(), window)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidHealthWindow) {
+			middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to get health history")
+		return
+	}
+
+	middleware.SuccessResponse(c, history)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/botdetect"
+	"webservice/internal/config"
+	"webservice/internal/migration"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// packageTestEnv 连接SQLite和内存存储的包处理器，路由由各测试按需注册
+type packageTestEnv struct {
+	t       *testing.T
+	db      *gorm.DB
+	service *service.PackageService
+	handler *PackageHandler
+	r       *gin.Engine
+}
+
+// newPackageTestEnv 创建包处理器测试环境，modify可在创建服务前修改包配置
+func newPackageTestEnv(t *testing.T, modify func(cfg *config.PackagesConfig)) *packageTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, migration.Models()...)
+
+	cfg := config.PackagesConfig{DownloadURLSecret: "test-secret"}
+	if modify != nil {
+		modify(&cfg)
+	}
+	packageService := service.NewPackageService(db, testutil.NewStorage(t, nil), nil, cfg)
+	h := NewPackageHandler(packageService, service.NewDownloadAnalyticsService(db, nil),
+		botdetect.New(config.SearchProtectionConfig{}, "test-secret"), false, cfg.AliasMode)
+	return &packageTestEnv{t: t, db: db, service: packageService, handler: h, r: gin.New()}
+}
+
+// asUser 模拟认证中间件，把用户ID写入上下文
+func asUser(userID uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
+// createUser 创建普通用户
+func (e *packageTestEnv) createUser(username string) *models.User {
+	e.t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", Password: "x", Role: models.RoleUser, Status: models.UserStatusActive}
+	if err := e.db.Create(user).Error; err != nil {
+		e.t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// createPackage 创建属于owner的公开包
+func (e *packageTestEnv) createPackage(name string, owner *models.User) *models.Package {
+	e.t.Helper()
+	pkg := &models.Package{Name: name, OwnerID: owner.ID}
+	if err := e.db.Create(pkg).Error; err != nil {
+		e.t.Fatalf("failed to create package: %v", err)
+	}
+	return pkg
+}
+
+// do 发送请求
+func (e *packageTestEnv) do(req *http.Request) *httptest.ResponseRecorder {
+	e.t.Helper()
+	w := httptest.NewRecorder()
+	e.r.ServeHTTP(w, req)
+	return w
+}
+
+// uploadRequest 构造版本上传的multipart请求，fields为普通表单字段
+func uploadRequest(t *testing.T, path string, fields map[string]string, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile("package_file", "package.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+// uploadVersion 通过包服务发布版本，返回文件内容
+func (e *packageTestEnv) uploadVersion(pkg *models.Package, version string, uploaderID uint) string {
+	e.t.Helper()
+	content := "content of " + pkg.Name + "@" + version
+	_, err := e.service.UploadPackageVersion(context.Background(), pkg.Name, &models.CreatePackageVersionRequest{Version: version},
+		strings.NewReader(content), int64(len(content)), uploaderID)
+	if err != nil {
+		e.t.Fatalf("failed to upload %s@%s: %v", pkg.Name, version, err)
+	}
+	return content
+}
+
+// countVersions 统计包未删除的版本数
+func (e *packageTestEnv) countVersions(packageName string) int64 {
+	e.t.Helper()
+	var count int64
+	err := e.db.Model(&models.PackageVersion{}).
+		Where("package_id = (SELECT id FROM packages WHERE name = ?)", packageName).
+		Count(&count).Error
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return count
+}
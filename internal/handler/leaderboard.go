@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// leaderboardPeriodMessage 周期参数无效时的提示
+var leaderboardPeriodMessage = "period must be one of: " + strings.Join(service.LeaderboardPeriods, ", ")
+
+// GetLeaderboard 获取包排行榜，数据由后台任务定期计算并发布到Redis
+func (h *Handler) GetLeaderboard(c *gin.Context) {
+	period := c.DefaultQuery("period", "weekly")
+	if !service.IsValidLeaderboardPeriod(period) {
+		middleware.ValidationErrorResponse(c, leaderboardPeriodMessage)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultLeaderboardLimit)))
+	if err != nil || limit < 1 || limit > service.MaxLeaderboardLimit {
+		middleware.ValidationErrorResponse(c, fmt.Sprintf("limit must be between 1 and %d", service.MaxLeaderboardLimit))
+		return
+	}
+
+	leaderboard, err := h.leaderboards.GetLeaderboard(c.Request.Context(), period, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrLeaderboardUnavailable) {
+			middleware.ErrorResponse(c, http.StatusServiceUnavailable, "Leaderboard is unavailable")
+			return
+		}
+		logger.Errorf("Failed to get %s leaderboard: %v", period, err)
+		middleware.InternalServerErrorResponse(c, "Failed to get leaderboard")
+		return
+	}
+
+	middleware.SuccessResponse(c, leaderboard)
+}
+
+// GetPackageRank 获取包在各周期排行榜中的当前排名
+func (h *Handler) GetPackageRank(c *gin.Context) {
+	ranks, err := h.leaderboards.GetPackageRanks(c.Request.Context(), c.Param("package"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if errors.Is(err, service.ErrLeaderboardUnavailable) {
+			middleware.ErrorResponse(c, http.StatusServiceUnavailable, "Leaderboard is unavailable")
+			return
+		}
+		logger.Errorf("Failed to get leaderboard ranks: %v", err)
+		middleware.InternalServerErrorResponse(c, "Failed to get package rank")
+		return
+	}
+
+	middleware.SuccessResponse(c, ranks)
+}
+
+// GetLeaderboardHistory 获取包最近若干周的排名走势，用于绘制图表
+func (h *Handler) GetLeaderboardHistory(c *gin.Context) {
+	packageName := strings.TrimSpace(c.Query("package"))
+	if packageName == "" {
+		middleware.ValidationErrorResponse(c, "package is required")
+		return
+	}
+	period := c.DefaultQuery("period", "weekly")
+	if !service.IsValidLeaderboardPeriod(period) {
+		middleware.ValidationErrorResponse(c, leaderboardPeriodMessage)
+		return
+	}
+	weeks, err := strconv.Atoi(c.DefaultQuery("weeks", strconv.Itoa(service.DefaultLeaderboardHistoryWeeks)))
+	if err != nil || weeks < 1 || weeks > service.MaxLeaderboardHistoryWeeks {
+		middleware.ValidationErrorResponse(c, fmt.Sprintf("weeks must be between 1 and %d", service.MaxLeaderboardHistoryWeeks))
+		return
+	}
+
+	history, err := h.leaderboards.GetRankHistory(c.Request.Context(), packageName, period, weeks)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		logger.Errorf("Failed to get leaderboard history for %s: %v", packageName, err)
+		middleware.InternalServerErrorResponse(c, "Failed to get leaderboard history")
+		return
+	}
+
+	middleware.SuccessResponse(c, history)
+}
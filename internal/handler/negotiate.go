@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"webservice/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 支持的响应表示
+const (
+	mimeJSON = "application/json"
+	mimeCSV  = "text/csv"
+	mimeYAML = "application/yaml"
+)
+
+// mimeAliases 常见的等价媒体类型
+var mimeAliases = map[string]string{
+	"application/x-yaml": mimeYAML,
+	"text/yaml":          mimeYAML,
+	"text/x-yaml":        mimeYAML,
+}
+
+// contentNegotiator 根据Accept请求头选择响应表示
+type contentNegotiator struct {
+	strict bool // 不支持请求的类型时返回406，否则回退为JSON
+}
+
+// acceptRange Accept请求头中的单个媒体范围
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept 解析Accept请求头并按q值从高到低排序
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		if alias, ok := mimeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+// negotiate 从offers中选择客户端可接受的类型，offers的第一个为默认类型
+// 无法满足时按配置返回406并返回false
+func (n contentNegotiator) negotiate(c *gin.Context, offers ...string) (string, bool) {
+	accept := strings.TrimSpace(c.GetHeader("Accept"))
+	if accept == "" {
+		return offers[0], true
+	}
+
+	for _, r := range parseAccept(accept) {
+		if r.quality <= 0 {
+			continue
+		}
+		for _, offer := range offers {
+			if mediaTypeMatches(r.mediaType, offer) {
+				return offer, true
+			}
+		}
+	}
+
+	if !n.strict {
+		return offers[0], true
+	}
+
+	middleware.ErrorResponse(c, http.StatusNotAcceptable,
+		fmt.Sprintf("Not acceptable; supported types: %s", strings.Join(offers, ", ")))
+	return "", false
+}
+
+// mediaTypeMatches 判断媒体范围是否匹配具体类型，支持*/*和type/*
+func mediaTypeMatches(mediaRange, offer string) bool {
+	if mediaRange == "*/*" || mediaRange == offer {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+// csvTable 可输出为CSV的表格数据
+type csvTable struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+// respondNegotiated 按协商结果输出响应：JSON使用统一响应格式，YAML直接输出数据，CSV输出表格
+func respondNegotiated(c *gin.Context, format string, data interface{}, table func() csvTable) {
+	switch format {
+	case mimeYAML:
+		// 先转换为JSON结构，保证字段名与JSON响应一致
+		raw, err := json.Marshal(data)
+		if err != nil {
+			middleware.InternalServerErrorResponse(c, "Failed to encode response")
+			return
+		}
+		var generic interface{}
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			middleware.InternalServerErrorResponse(c, "Failed to encode response")
+			return
+		}
+		c.YAML(http.StatusOK, generic)
+	case mimeCSV:
+		t := table()
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		w.Write(t.columns)
+		for _, row := range t.rows {
+			record := make([]string, len(row))
+			for i, v := range row {
+				record[i] = formatCSVValue(v)
+			}
+			w.Write(record)
+		}
+		w.Flush()
+	default:
+		middleware.SuccessResponse(c, data)
+	}
+}
+
+// fieldTable 使用可选字段集将列表转换为CSV表格
+func fieldTable[T any](items []T, columns []string, set fieldSet[T]) csvTable {
+	rows := make([][]interface{}, len(items))
+	for i, item := range items {
+		row := make([]interface{}, len(columns))
+		for j, name := range columns {
+			row[j] = set[name](item)
+		}
+		rows[i] = row
+	}
+	return csvTable{columns: columns, rows: rows}
+}
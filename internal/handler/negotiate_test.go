@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		strict     bool
+		accept     string
+		want       string
+		wantStatus int // 0表示协商成功
+	}{
+		{name: "no header", accept: "", want: mimeJSON},
+		{name: "wildcard", accept: "*/*", want: mimeJSON},
+		{name: "csv", accept: "text/csv", want: mimeCSV},
+		{name: "yaml alias", accept: "application/x-yaml", want: mimeYAML},
+		{name: "type wildcard", accept: "text/*", want: mimeCSV},
+		{name: "quality order", accept: "application/json;q=0.5, text/csv;q=0.9", want: mimeCSV},
+		{name: "zero quality skipped", accept: "text/csv;q=0, application/yaml", want: mimeYAML},
+		{name: "unsupported falls back", accept: "application/xml", want: mimeJSON},
+		{name: "unsupported strict", strict: true, accept: "application/xml", wantStatus: http.StatusNotAcceptable},
+		{name: "strict with wildcard", strict: true, accept: "application/xml, */*;q=0.1", want: mimeJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+
+			got, ok := contentNegotiator{strict: tt.strict}.negotiate(c, mimeJSON, mimeCSV, mimeYAML)
+			if tt.wantStatus != 0 {
+				if ok || w.Code != tt.wantStatus {
+					t.Fatalf("ok = %v, status = %d; want %d", ok, w.Code, tt.wantStatus)
+				}
+				if !strings.Contains(w.Body.String(), "supported types") {
+					t.Errorf("406 body does not list supported types: %s", w.Body.String())
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Errorf("negotiate = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadRecordsRepresentations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, &models.User{}, &models.Package{}, &models.PackageVersion{}, &models.PackageDownload{})
+	owner := &models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	db.Create(owner)
+	pkg := &models.Package{Name: "demo", OwnerID: owner.ID}
+	db.Create(pkg)
+	version := &models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", FileSize: 1, UploaderID: owner.ID}
+	db.Create(version)
+	db.Create(&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "10.0.0.1", UserAgent: "curl/8.0"})
+
+	h := NewPackageHandler(service.NewPackageService(db, nil, nil, config.PackagesConfig{}), nil, nil, true, "")
+	request := func(accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/packages/demo/downloads", nil)
+		c.Request.Header.Set("Accept", accept)
+		c.Params = gin.Params{{Key: "package", Value: "demo"}}
+		c.Set("user_id", owner.ID)
+		h.GetDownloadRecords(c)
+		return w
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		w := request("text/csv")
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+			t.Fatalf("status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || strings.Join(records[0], ",") != strings.Join(downloadCSVColumns, ",") {
+			t.Fatalf("csv = %v, want header and one row", records)
+		}
+		if records[1][2] != "1.0.0" || records[1][4] != "10.0.0.1" {
+			t.Errorf("row = %v", records[1])
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		w := request("application/yaml")
+		if w.Code != http.StatusOK || !strings.Contains(w.Header().Get("Content-Type"), "yaml") {
+			t.Fatalf("status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Body.String(), "ip_address: 10.0.0.1") {
+			t.Errorf("yaml body = %s", w.Body.String())
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		if w := request("application/xml"); w.Code != http.StatusNotAcceptable {
+			t.Errorf("status = %d, want 406", w.Code)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterIdentityVerifier 注册外部身份提供方的凭据校验器，provider与请求中的provider字段对应
+func (h *Handler) RegisterIdentityVerifier(provider string, verifier service.IdentityVerifier) {
+	if h.identityVerifiers == nil {
+		h.identityVerifiers = make(map[string]service.IdentityVerifier)
+	}
+	h.identityVerifiers[provider] = verifier
+}
+
+// verifyExternalCredential 使用提供方的校验器验证凭据，失败时写入响应并返回nil
+func (h *Handler) verifyExternalCredential(c *gin.Context, req *models.ExternalLoginRequest) *service.ExternalIdentityClaims {
+	verifier, ok := h.identityVerifiers[req.Provider]
+	if !ok {
+		middleware.ErrorResponse(c, http.StatusBadRequest, service.ErrUnknownIdentityProvider.Error())
+		return nil
+	}
+
+	claims, err := verifier.Verify(c.Request.Context(), req.Credential)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidExternalCredential) {
+			middleware.UnauthorizedResponse(c, err.Error())
+			return nil
+		}
+		logger.Warnf("Failed to verify %s credential: %v", req.Provider, err)
+		middleware.ErrorResponse(c, http.StatusBadGateway, "Failed to verify external credential")
+		return nil
+	}
+	if claims.Subject == "" {
+		middleware.UnauthorizedResponse(c, fmt.Sprintf("%s: missing subject", service.ErrInvalidExternalCredential.Error()))
+		return nil
+	}
+	claims.Provider = req.Provider
+	return claims
+}
+
+// OAuthCallback 外部身份登录：校验提供方凭据后查找或创建本地用户并签发JWT
+func (h *Handler) OAuthCallback(c *gin.Context) {
+	var req models.ExternalLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	claims := h.verifyExternalCredential(c, &req)
+	if claims == nil {
+		return
+	}
+
+	user, created, err := h.userService.LoginWithExternalIdentity(c.Request.Context(), claims, h.cfg.OAuth.EmailConflict)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrExternalEmailConflict), errors.Is(err, service.ErrExternalIdentityLinked):
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+		case errors.Is(err, service.ErrInvalidExternalCredential):
+			middleware.UnauthorizedResponse(c, err.Error())
+		case err.Error() == "user account is not active":
+			middleware.ForbiddenResponse(c, err.Error())
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to sign in with external identity")
+		}
+		return
+	}
+
+	// 生成JWT token并记录会话
+	token, err := h.issueToken(c, user)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to generate token")
+		return
+	}
+
+	if created {
+		logger.Infof("Created user %d (%s) from %s identity", user.ID, user.Username, claims.Provider)
+	}
+	middleware.SuccessResponse(c, models.LoginResponse{
+		User:  user.ToPublicUser(),
+		Token: token,
+	})
+}
+
+// LinkExternalIdentity 将外部账号关联到当前登录用户，之后可使用该提供方登录
+func (h *Handler) LinkExternalIdentity(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.ExternalLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	claims := h.verifyExternalCredential(c, &req)
+	if claims == nil {
+		return
+	}
+
+	identity, err := h.userService.LinkExternalIdentity(c.Request.Context(), userID, claims)
+	if err != nil {
+		if errors.Is(err, service.ErrExternalIdentityLinked) {
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to link external identity")
+		return
+	}
+
+	if err := h.auditService.Record(c.Request.Context(), userID, "users.link_identity", "users/"+fmt.Sprint(userID),
+		gin.H{"provider": identity.Provider, "subject": identity.Subject}, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit identity link: %v", err)
+	}
+
+	middleware.SuccessResponse(c, identity)
+}
+
+// GetExternalIdentities 获取当前用户关联的外部身份
+func (h *Handler) GetExternalIdentities(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	identities, err := h.userService.ListExternalIdentities(c.Request.Context(), userID)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get external identities")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"identities": identities})
+}
+
+// UnlinkExternalIdentity 解除当前用户的指定外部身份关联
+func (h *Handler) UnlinkExternalIdentity(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid identity ID")
+		return
+	}
+
+	identity, err := h.userService.UnlinkExternalIdentity(c.Request.Context(), userID, uint(identityID))
+	if err != nil {
+		if errors.Is(err, service.ErrExternalIdentityNotFound) {
+			middleware.NotFoundResponse(c, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to unlink external identity")
+		return
+	}
+
+	if err := h.auditService.Record(c.Request.Context(), userID, "users.unlink_identity", "users/"+fmt.Sprint(userID),
+		gin.H{"provider": identity.Provider, "subject": identity.Subject}, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit identity unlink: %v", err)
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "External identity unlinked successfully"})
+}
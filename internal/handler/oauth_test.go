@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/migration"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubVerifier 按凭据返回预设的身份信息，未知凭据校验失败
+type stubVerifier map[string]*service.ExternalIdentityClaims
+
+func (v stubVerifier) Verify(_ context.Context, credential string) (*service.ExternalIdentityClaims, error) {
+	claims, ok := v[credential]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown credential", service.ErrInvalidExternalCredential)
+	}
+	copied := *claims
+	return &copied, nil
+}
+
+// identityTestRouter 挂载外部身份相关路由，需要登录的路由从X-User-ID请求头读取当前用户
+func identityTestRouter(t *testing.T) (*gin.Engine, *Handler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testutil.NewDB(t, migration.Models()...)
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireTime: time.Hour}}
+	h := &Handler{
+		cfg:            cfg,
+		db:             db,
+		userService:    service.NewUserService(db, config.PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4}),
+		sessionService: service.NewSessionService(db),
+		auditService:   service.NewAuditService(db),
+	}
+	h.RegisterIdentityVerifier("github", stubVerifier{
+		"octo":  {Subject: "1001", Email: "octo@example.com", EmailVerified: true, Username: "octo"},
+		"other": {Subject: "2002", Email: "other@example.com", EmailVerified: true},
+	})
+
+	r := gin.New()
+	asHeaderUser := func(c *gin.Context) {
+		var id uint
+		fmt.Sscan(c.GetHeader("X-User-ID"), &id)
+		c.Set("user_id", id)
+		c.Next()
+	}
+	r.POST("/oauth/callback", h.OAuthCallback)
+	r.POST("/identities", asHeaderUser, h.LinkExternalIdentity)
+	r.GET("/identities", asHeaderUser, h.GetExternalIdentities)
+	r.DELETE("/identities/:id", asHeaderUser, h.UnlinkExternalIdentity)
+	return r, h
+}
+
+func identityRequest(r *gin.Engine, method, path string, userID uint, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		req.Header.Set("X-User-ID", fmt.Sprint(userID))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestExternalIdentityLinkAndUnlink(t *testing.T) {
+	r, h := identityTestRouter(t)
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "x", Role: models.RoleUser, Status: models.UserStatusActive}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "x", Role: models.RoleUser, Status: models.UserStatusActive}
+	for _, user := range []*models.User{alice, bob} {
+		if err := h.db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 凭据无效或提供方未注册
+	if w := identityRequest(r, http.MethodPost, "/identities", alice.ID, `{"provider":"github","credential":"forged"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("link with an invalid credential status = %d, want 401", w.Code)
+	}
+	if w := identityRequest(r, http.MethodPost, "/identities", alice.ID, `{"provider":"gitlab","credential":"octo"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("link with an unknown provider status = %d, want 400", w.Code)
+	}
+
+	// 关联后可以用外部身份登录为alice
+	w := identityRequest(r, http.MethodPost, "/identities", alice.ID, `{"provider":"github","credential":"octo"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("link status = %d, body %s", w.Code, w.Body.String())
+	}
+	var linked struct {
+		Data models.ExternalIdentity `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &linked); err != nil {
+		t.Fatal(err)
+	}
+	if linked.Data.UserID != alice.ID || linked.Data.Provider != "github" || linked.Data.Subject != "1001" {
+		t.Errorf("linked identity = %+v, want github/1001 for alice", linked.Data)
+	}
+	assertOAuthLogin(t, r, "octo", http.StatusOK, "alice")
+
+	// 重复关联到同一用户成功，关联到其他用户返回409
+	if w := identityRequest(r, http.MethodPost, "/identities", alice.ID, `{"provider":"github","credential":"octo"}`); w.Code != http.StatusOK {
+		t.Errorf("relink to alice status = %d, want 200", w.Code)
+	}
+	if w := identityRequest(r, http.MethodPost, "/identities", bob.ID, `{"provider":"github","credential":"octo"}`); w.Code != http.StatusConflict {
+		t.Errorf("link to bob status = %d, want 409, body %s", w.Code, w.Body.String())
+	}
+
+	w = identityRequest(r, http.MethodGet, "/identities", alice.ID, "")
+	var list struct {
+		Data struct {
+			Identities []models.ExternalIdentity `json:"identities"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data.Identities) != 1 || list.Data.Identities[0].ID != linked.Data.ID {
+		t.Errorf("alice identities = %+v, want the linked identity", list.Data.Identities)
+	}
+
+	// 其他用户不能解除alice的关联
+	path := fmt.Sprintf("/identities/%d", linked.Data.ID)
+	if w := identityRequest(r, http.MethodDelete, path, bob.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("unlink by bob status = %d, want 404", w.Code)
+	}
+	if w := identityRequest(r, http.MethodDelete, "/identities/abc", alice.ID, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("unlink with an invalid ID status = %d, want 400", w.Code)
+	}
+	if w := identityRequest(r, http.MethodDelete, path, alice.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("unlink status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := identityRequest(r, http.MethodDelete, path, alice.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second unlink status = %d, want 404", w.Code)
+	}
+	var audits int64
+	if err := h.db.Model(&models.AuditLog{}).Where("action IN ?", []string{"users.link_identity", "users.unlink_identity"}).Count(&audits).Error; err != nil {
+		t.Fatal(err)
+	}
+	if audits != 3 {
+		t.Errorf("audit entries = %d, want two links and one unlink", audits)
+	}
+
+	// 解除后外部身份登录创建新用户，不再登录为alice；之后bob可以关联另一个外部身份
+	assertOAuthLogin(t, r, "octo", http.StatusOK, "octo")
+	if w := identityRequest(r, http.MethodPost, "/identities", bob.ID, `{"provider":"github","credential":"other"}`); w.Code != http.StatusOK {
+		t.Errorf("link other to bob status = %d, want 200", w.Code)
+	}
+	assertOAuthLogin(t, r, "other", http.StatusOK, "bob")
+}
+
+// assertOAuthLogin 使用外部凭据登录，检查状态码和登录的用户名
+func assertOAuthLogin(t *testing.T, r *gin.Engine, credential string, wantCode int, wantUsername string) {
+	t.Helper()
+	w := identityRequest(r, http.MethodPost, "/oauth/callback", 0, `{"provider":"github","credential":"`+credential+`"}`)
+	if w.Code != wantCode {
+		t.Fatalf("oauth login status = %d, want %d, body %s", w.Code, wantCode, w.Body.String())
+	}
+	var resp struct {
+		Data models.LoginResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.User.Username != wantUsername || resp.Data.Token == "" {
+		t.Errorf("oauth login as %q with token %v, want %q", resp.Data.User.Username, resp.Data.Token != "", wantUsername)
+	}
+}
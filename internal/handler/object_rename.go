@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RenameVersionObject 修正版本文件的对象键（管理员），dry_run为true时只返回将执行的操作
+// 实际执行时记录rename_log审计日志
+func (h *Handler) RenameVersionObject(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.RenameObjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	result, err := h.packageService.RenameVersionObject(c.Request.Context(), c.Param("package"), c.Param("version"), req.Destination, req.DryRun, h.packageCaller(c, c.Param("package")))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+		case strings.Contains(err.Error(), "permission denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions")
+		case errors.Is(err, service.ErrObjectNotFound):
+			middleware.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrObjectExists), errors.Is(err, service.ErrSameObjectKey):
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+		case strings.Contains(err.Error(), "not available"):
+			middleware.ErrorResponse(c, http.StatusServiceUnavailable, "File storage is not available")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to rename object")
+		}
+		return
+	}
+
+	if result.Renamed {
+		resource := "packages/" + result.Package + "/" + result.Version
+		if err := h.auditService.Record(c.Request.Context(), userID, "rename_log", resource, result, c.ClientIP()); err != nil {
+			logger.Warnf("Failed to audit object rename: %v", err)
+		}
+	}
+
+	middleware.SuccessResponse(c, result)
+}
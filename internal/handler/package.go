@@ -1,27 +1,80 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"webservice/internal/botdetect"
+	"webservice/internal/license"
 	"webservice/internal/logger"
 	"webservice/internal/middleware"
+	"webservice/internal/minio"
 	"webservice/internal/models"
 	"webservice/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// publishPolicyErrorCodes 发布策略校验失败时返回的业务错误码，便于客户端区分具体原因
+var publishPolicyErrorCodes = map[error]int{
+	service.ErrInvalidSemver:       42201,
+	service.ErrVersionNotMonotonic: 42202,
+	service.ErrPrereleaseLatest:    42203,
+}
+
+// 许可证校验失败时返回的业务错误码
+const (
+	codeUnknownLicense    = 42204
+	codeLicenseNotAllowed = 42205
+)
+
+// codeDependencyConflict enforce模式下声明的依赖无法解析时返回的业务错误码
+const codeDependencyConflict = 42206
+
+// codeInvalidChangelog 启用更新日志格式检查时更新日志不符合要求返回的业务错误码
+const codeInvalidChangelog = 42207
+
+// codeBandwidthLimitExceeded 包本月下载流量超过管理员设置的上限时返回的业务错误码
+const codeBandwidthLimitExceeded = 42901
+
+// codeStaleUpdate 乐观锁冲突（if_version与当前lock_version不一致）时返回的业务错误码
+const codeStaleUpdate = 40901
+
+// 版本未通过病毒扫描时下载返回的业务错误码
+const (
+	codeVersionScanning    = 40902
+	codeVersionQuarantined = 45101
+)
+
+// versionScanRetryAfter 版本正在扫描时建议客户端重试的间隔
+const versionScanRetryAfter = 30 * time.Second
+
 // PackageHandler 包管理处理器
 type PackageHandler struct {
-	packageService *service.PackageService
+	packageService   *service.PackageService
+	analyticsService *service.DownloadAnalyticsService
+	negotiator       contentNegotiator
+	aliasRedirect    bool                // 访问旧包名时重定向，否则透明解析
+	botDetector      *botdetect.Detector // 识别爬虫流量，其下载不计数
 }
 
 // NewPackageHandler 创建包管理处理器
-func NewPackageHandler(packageService *service.PackageService) *PackageHandler {
+// strictAccept为true时，Accept请求头中的类型都不支持则返回406；aliasMode见config.PackagesConfig
+func NewPackageHandler(packageService *service.PackageService, analyticsService *service.DownloadAnalyticsService, botDetector *botdetect.Detector, strictAccept bool, aliasMode string) *PackageHandler {
 	return &PackageHandler{
-		packageService: packageService,
+		packageService:   packageService,
+		analyticsService: analyticsService,
+		negotiator:       contentNegotiator{strict: strictAccept},
+		aliasRedirect:    aliasMode != "transparent",
+		botDetector:      botDetector,
 	}
 }
 
@@ -38,11 +91,18 @@ func (h *PackageHandler) CreatePackage(c *gin.Context) {
 		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
+	if !middleware.TokenAllowsPackage(c, req.Name) {
+		middleware.TokenScopeViolationResponse(c, req.Name)
+		return
+	}
 
 	pkg, err := h.packageService.CreatePackage(c.Request.Context(), &req, userID.(uint))
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			middleware.ErrorResponse(c, http.StatusConflict, "Package already exists")
+		if respondPackageNameUnavailable(c, err) || respondInvalidLicense(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrLongDescriptionTooLong) || errors.Is(err, service.ErrUnknownCategory) || errors.Is(err, service.ErrInvalidLinkURL) {
+			middleware.ValidationErrorResponse(c, err.Error())
 			return
 		}
 		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to create package")
@@ -60,19 +120,171 @@ func (h *PackageHandler) GetPackage(c *gin.Context) {
 		return
 	}
 
-	pkg, err := h.packageService.GetPackage(c.Request.Context(), packageName)
+	packageName, ok := h.resolvePackageAlias(c, packageName)
+	if !ok {
+		return
+	}
+
+	fields, err := parseFields(c, packageDetailFields)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	pkg, err := h.packageService.GetPackage(c.Request.Context(), packageName, optionalUserID(c))
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
 			return
 		}
+		if strings.Contains(err.Error(), "access denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return
+		}
 		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get package")
 		return
 	}
 
+	// 推荐只是附加信息，获取失败不影响包详情
+	recommendations, err := h.packageService.GetRecommendations(c.Request.Context(), pkg.Name, service.DefaultRecommendationLimit)
+	if err != nil {
+		logger.Warnf("Failed to get recommendations for package %s: %v", pkg.Name, err)
+	}
+	for _, rec := range recommendations {
+		pkg.Recommendations = append(pkg.Recommendations, *rec)
+	}
+
+	if fields != nil {
+		middleware.SuccessResponse(c, projectItem(*pkg, fields, packageDetailFields))
+		return
+	}
+	middleware.SuccessResponse(c, pkg)
+}
+
+// GetPackageRecommendations 获取经常与该包一起下载的包，没有共同下载数据时返回同一作者的其他包
+func (h *PackageHandler) GetPackageRecommendations(c *gin.Context) {
+	packageName := c.Param("package")
+	if packageName == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name is required")
+		return
+	}
+
+	packageName, ok := h.resolvePackageAlias(c, packageName)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.DefaultRecommendationLimit)))
+	if err != nil || limit < 1 || limit > service.MaxRecommendationLimit {
+		middleware.ValidationErrorResponse(c, fmt.Sprintf("limit must be between 1 and %d", service.MaxRecommendationLimit))
+		return
+	}
+
+	packages, err := h.packageService.GetRecommendations(c.Request.Context(), packageName, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get recommendations")
+		return
+	}
+
+	middleware.SuccessResponse(c, packages)
+}
+
+// RenamePackage 重命名包，旧名称保留为别名
+func (h *PackageHandler) RenamePackage(c *gin.Context) {
+	packageName := c.Param("package")
+	if packageName == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name is required")
+		return
+	}
+
+	var req models.RenamePackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	pkg, err := h.packageService.RenamePackage(c.Request.Context(), packageName, req.NewName, userID.(uint))
+	if err != nil {
+		if respondPackageArchived(c, err) {
+			return
+		}
+		if respondPackageNameUnavailable(c, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to rename package")
+		return
+	}
+
 	middleware.SuccessResponse(c, pkg)
 }
 
+// CheckPackageNameAvailability 检查包名是否可用于创建新包
+// 可用的结果只允许缓存几秒，避免名称被占用后客户端仍使用旧结果
+func (h *PackageHandler) CheckPackageNameAvailability(c *gin.Context) {
+	result, err := h.packageService.CheckPackageNameAvailability(c.Request.Context(), c.Param("package"))
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to check package name availability")
+		return
+	}
+
+	if result.Available {
+		c.Header("Cache-Control", "public, max-age=5")
+	} else {
+		c.Header("Cache-Control", "public, max-age=60")
+	}
+	middleware.SuccessResponse(c, result)
+}
+
+// resolvePackageAlias 将重命名前的旧包名解析为当前包名，并通过Deprecation/Link响应头提示调用方
+// 重定向模式下直接返回301（GET/HEAD）或308，此时返回false，调用方应结束处理
+func (h *PackageHandler) resolvePackageAlias(c *gin.Context, packageName string) (string, bool) {
+	target, err := h.packageService.ResolvePackageAlias(c.Request.Context(), packageName)
+	if err != nil {
+		logger.Errorf("Failed to resolve package alias %s: %v", packageName, err)
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to resolve package")
+		return "", false
+	}
+	if target == "" {
+		return packageName, true
+	}
+
+	location := strings.Replace(c.Request.URL.Path, "/packages/"+packageName, "/packages/"+target, 1)
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Header("Deprecation", "true")
+	c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", location))
+	c.Header("X-Package-Renamed-To", target)
+
+	if h.aliasRedirect {
+		status := http.StatusPermanentRedirect
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		c.Redirect(status, location)
+		return "", false
+	}
+	return target, true
+}
+
 // UpdatePackage 更新包信息
 func (h *PackageHandler) UpdatePackage(c *gin.Context) {
 	packageName := c.Param("package")
@@ -95,6 +307,14 @@ func (h *PackageHandler) UpdatePackage(c *gin.Context) {
 
 	pkg, err := h.packageService.UpdatePackage(c.Request.Context(), packageName, &req, userID.(uint))
 	if err != nil {
+		if respondPackageArchived(c, err) || respondInvalidLicense(c, err) || respondStaleUpdate(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrLongDescriptionTooLong) || errors.Is(err, service.ErrUnknownCategory) || errors.Is(err, service.ErrInvalidLinkURL) ||
+			errors.Is(err, service.ErrUploadLockPermanent) {
+			middleware.ValidationErrorResponse(c, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
 			return
@@ -110,6 +330,52 @@ func (h *PackageHandler) UpdatePackage(c *gin.Context) {
 	middleware.SuccessResponse(c, pkg)
 }
 
+// UpdatePackageVersion 修改包版本的描述和更新日志
+func (h *PackageHandler) UpdatePackageVersion(c *gin.Context) {
+	var req models.UpdatePackageVersionRequest
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	// 发布快照中的字段不可修改，显式拒绝而不是静默忽略
+	var fields map[string]json.RawMessage
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	for _, field := range models.ImmutableVersionFields {
+		if _, ok := fields[field]; ok {
+			middleware.ValidationErrorResponse(c, fmt.Sprintf("%s cannot be changed after publish", field))
+			return
+		}
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	pkgVersion, err := h.packageService.UpdatePackageVersion(c.Request.Context(), c.Param("package"), c.Param("version"), &req, userID.(uint))
+	if err != nil {
+		if respondPackageArchived(c, err) || respondStaleUpdate(c, err) || respondInvalidChangelog(c, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to update package version")
+		return
+	}
+
+	middleware.SuccessResponse(c, pkgVersion)
+}
+
 // DeletePackage 删除包
 func (h *PackageHandler) DeletePackage(c *gin.Context) {
 	packageName := c.Param("package")
@@ -126,6 +392,9 @@ func (h *PackageHandler) DeletePackage(c *gin.Context) {
 
 	err := h.packageService.DeletePackage(c.Request.Context(), packageName, userID.(uint))
 	if err != nil {
+		if respondDeleteBusy(c, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
 			return
@@ -160,13 +429,9 @@ func (h *PackageHandler) UploadPackageVersion(c *gin.Context) {
 		return
 	}
 
-	version := c.PostForm("version")
-	description := c.PostForm("description")
-	changelog := c.PostForm("changelog")
-	isPrerelease := c.PostForm("is_prerelease") == "true"
-
-	if version == "" {
-		middleware.ErrorResponse(c, http.StatusBadRequest, "Version is required")
+	req, err := bindUploadMetadata(c)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
@@ -177,14 +442,6 @@ func (h *PackageHandler) UploadPackageVersion(c *gin.Context) {
 	}
 	defer file.Close()
 
-	req := &models.CreatePackageVersionRequest{
-		Version:      version,
-		Description:  description,
-		Changelog:    changelog,
-		IsPrerelease: isPrerelease,
-		Dependencies: make(map[string]string),
-	}
-
 	pkgVersion, err := h.packageService.UploadPackageVersion(
 		c.Request.Context(),
 		packageName,
@@ -194,177 +451,586 @@ func (h *PackageHandler) UploadPackageVersion(c *gin.Context) {
 		userID.(uint),
 	)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
-			return
-		}
-		if strings.Contains(err.Error(), "permission denied") {
-			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
-			return
-		}
-		if strings.Contains(err.Error(), "already exists") {
-			middleware.ErrorResponse(c, http.StatusConflict, "Version already exists")
-			return
-		}
-		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to upload package version")
+		h.respondPublishError(c, err, userID.(uint), "Failed to upload package version")
 		return
 	}
 
+	h.setQuotaHeaders(c, userID.(uint), false)
 	middleware.SuccessResponse(c, pkgVersion)
 }
 
-// DownloadPackageVersion 下载包版本
-func (h *PackageHandler) DownloadPackageVersion(c *gin.Context) {
-	packageName := c.Param("package")
-	version := c.Param("version")
-
-	if packageName == "" || version == "" {
-		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name and version are required")
+// CreateUploadSession 创建预签名上传会话，客户端使用返回的upload_url以PUT请求直接上传文件到存储，
+// 上传后调用upload-complete创建版本；请求体为与直接上传相同的版本元数据加上文件大小size
+func (h *PackageHandler) CreateUploadSession(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	var userID *uint
-	if id, exists := c.Get("user_id"); exists {
-		uid := id.(uint)
-		userID = &uid
+	var req models.CreateUploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
 	}
 
-	ipAddress := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-
-	reader, pkgVersion, err := h.packageService.DownloadPackageVersion(
-		c.Request.Context(),
-		packageName,
-		version,
-		userID,
-		ipAddress,
-		userAgent,
-	)
+	session, err := h.packageService.CreateUploadSession(c.Request.Context(), c.Param("package"), &req, userID)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
-			return
-		}
-		if strings.Contains(err.Error(), "access denied") {
-			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
-			return
-		}
-		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to download package")
+		h.respondPublishError(c, err, userID, "Failed to create upload session")
 		return
 	}
-	defer reader.Close()
-
-	filename := packageName + "-" + version + ".pkg"
-	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Length", strconv.FormatInt(pkgVersion.FileSize, 10))
-	c.Header("X-Package-Name", packageName)
-	c.Header("X-Package-Version", version)
-	c.Header("X-Package-Hash", pkgVersion.FileHash)
 
-	c.DataFromReader(http.StatusOK, pkgVersion.FileSize, "application/octet-stream", reader, map[string]string{})
+	middleware.SuccessResponse(c, session)
 }
 
-// GetPackageVersions 获取包的所有版本
-func (h *PackageHandler) GetPackageVersions(c *gin.Context) {
-	packageName := c.Param("package")
-	if packageName == "" {
-		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name is required")
+// CompleteUpload 预签名上传完成回调，确认文件已上传且大小一致后计算SHA256并创建版本
+// 小文件同步处理，返回200和创建的版本；大文件在后台处理，返回202和job_id，通过上传会话查询接口轮询结果
+func (h *PackageHandler) CompleteUpload(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	var req models.CompleteUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
 	}
 
-	response, err := h.packageService.GetPackageVersions(c.Request.Context(), packageName, page, pageSize)
+	session, pkgVersion, err := h.packageService.CompleteUpload(c.Request.Context(), c.Param("package"), c.Param("version"), &req, userID)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
-			return
-		}
-		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get package versions")
+		h.respondPublishError(c, err, userID, "Failed to complete upload")
 		return
 	}
 
-	middleware.SuccessResponse(c, response)
-}
-
-// DeletePackageVersion 删除包版本
-func (h *PackageHandler) DeletePackageVersion(c *gin.Context) {
-	packageName := c.Param("package")
-	version := c.Param("version")
-
-	if packageName == "" || version == "" {
-		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name and version are required")
+	if pkgVersion != nil {
+		h.setQuotaHeaders(c, userID, false)
+		middleware.SuccessResponse(c, gin.H{"upload": session, "version": pkgVersion})
 		return
 	}
+	if session.Status == models.UploadSessionProcessing {
+		middleware.CustomResponse(c, http.StatusAccepted, 0, "Upload is being processed", gin.H{
+			"job_id": session.ID,
+			"upload": session,
+		})
+		return
+	}
+	// 重复回调：返回会话的当前状态（completed或failed）
+	middleware.SuccessResponse(c, gin.H{"upload": session})
+}
 
-	userID, exists := c.Get("user_id")
+// GetUploadSession 查询上传会话的处理状态（上传者本人），completed时version_id为创建的版本
+func (h *PackageHandler) GetUploadSession(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
 		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
-	err := h.packageService.DeletePackageVersion(c.Request.Context(), packageName, version, userID.(uint))
+	session, err := h.packageService.GetUploadSession(c.Request.Context(), c.Param("package"), c.Param("version"), c.Param("upload_id"), userID)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
-			return
-		}
-		if strings.Contains(err.Error(), "permission denied") {
-			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
-			return
-		}
-		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete package version")
+		h.respondPublishError(c, err, userID, "Failed to get upload session")
 		return
 	}
 
-	middleware.SuccessResponse(c, gin.H{"message": "Package version deleted successfully"})
+	middleware.SuccessResponse(c, session)
 }
 
-// SearchPackages 搜索包
-func (h *PackageHandler) SearchPackages(c *gin.Context) {
-	var req models.SearchPackagesRequest
-	if err := c.ShouldBindQuery(&req); err != nil {
-		middleware.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters"+err.Error())
+// respondPublishError 发布版本（直接上传和预签名上传）的错误响应，message为未识别错误时的提示
+func (h *PackageHandler) respondPublishError(c *gin.Context, err error, userID uint, message string) {
+	if respondPackageArchived(c, err) {
 		return
 	}
-	logger.Info("SearchPackages request", "query", req.Query, "page", req.Page, "page_size", req.PageSize)
-	if req.Page < 1 {
-		req.Page = 1
-	}
-	if req.PageSize < 1 || req.PageSize > 100 {
-		req.PageSize = 20
+	if respondDependencyConflict(c, err) || respondInvalidChangelog(c, err) {
+		return
 	}
-
-	response, err := h.packageService.SearchPackages(c.Request.Context(), &req)
-	if err != nil {
-		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to search packages")
+	if code, ok := publishPolicyErrorCodes[err]; ok {
+		middleware.CustomResponse(c, http.StatusUnprocessableEntity, code, err.Error(), nil)
 		return
 	}
-
+	switch {
+	case errors.Is(err, service.ErrUploadSessionNotFound):
+		middleware.ErrorResponse(c, http.StatusNotFound, "Upload session not found")
+		return
+	case errors.Is(err, service.ErrUploadSessionExpired):
+		middleware.ErrorResponse(c, http.StatusGone, err.Error())
+		return
+	case errors.Is(err, service.ErrUploadObjectMissing):
+		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, service.ErrUploadSizeMismatch), errors.Is(err, service.ErrUploadETagMismatch):
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	if strings.Contains(err.Error(), "not found") {
+		middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		return
+	}
+	if strings.Contains(err.Error(), "permission denied") {
+		middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+		return
+	}
+	if errors.Is(err, service.ErrVersionExists) {
+		middleware.ErrorResponse(c, http.StatusConflict, "Version already exists")
+		return
+	}
+	if errors.Is(err, service.ErrVersionNumberLocked) {
+		middleware.ErrorResponse(c, http.StatusLocked, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrUploadInProgress) {
+		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		h.setQuotaHeaders(c, userID, true)
+		middleware.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	middleware.ErrorResponse(c, http.StatusInternalServerError, message)
+}
+
+// setQuotaHeaders 用量达到软限制（或always为true）时返回X-Quota-Used、X-Quota-Limit和X-Quota-Warning响应头
+// 未配置存储配额时不返回；查询用量失败不影响上传结果
+func (h *PackageHandler) setQuotaHeaders(c *gin.Context, userID uint, always bool) {
+	if !h.packageService.StorageQuotaEnabled() {
+		return
+	}
+	usage, err := h.packageService.GetQuotaUsage(c.Request.Context(), userID)
+	if err != nil || (!always && !usage.Warning) {
+		return
+	}
+
+	c.Header("X-Quota-Used", strconv.FormatInt(usage.Used, 10))
+	c.Header("X-Quota-Limit", strconv.FormatInt(usage.Limit, 10))
+	if usage.Warning {
+		c.Header("X-Quota-Warning", fmt.Sprintf("%d%% of storage quota used", usage.Used*100/usage.Limit))
+	}
+}
+
+// bindUploadMetadata 解析上传表单中的版本元数据
+// 优先使用metadata部分（与CreatePackageVersionRequest结构一致的JSON，可以是普通字段或文件），
+// 没有时回退到version、description等独立表单字段；两种方式使用相同的校验规则
+func bindUploadMetadata(c *gin.Context) (*models.CreatePackageVersionRequest, error) {
+	req := &models.CreatePackageVersionRequest{}
+
+	metadata, err := readMetadataPart(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata != nil {
+		if err := json.Unmarshal(metadata, req); err != nil {
+			return nil, fmt.Errorf("invalid metadata: %w", err)
+		}
+	} else {
+		req.Version = c.PostForm("version")
+		req.Description = c.PostForm("description")
+		req.Changelog = c.PostForm("changelog")
+		req.IsPrerelease = c.PostForm("is_prerelease") == "true"
+		req.ForceBackfill = c.PostForm("force_backfill") == "true"
+		if dependencies := c.PostForm("dependencies"); dependencies != "" {
+			if err := json.Unmarshal([]byte(dependencies), &req.Dependencies); err != nil {
+				return nil, fmt.Errorf("invalid dependencies: %w", err)
+			}
+		}
+	}
+
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+	if req.Dependencies == nil {
+		req.Dependencies = make(map[string]string)
+	}
+	return req, nil
+}
+
+// readMetadataPart 读取multipart中的metadata部分，不存在时返回nil
+func readMetadataPart(c *gin.Context) ([]byte, error) {
+	form := c.Request.MultipartForm
+	if values := form.Value["metadata"]; len(values) > 0 {
+		return []byte(values[0]), nil
+	}
+
+	files := form.File["metadata"]
+	if len(files) == 0 {
+		return nil, nil
+	}
+	part, err := files[0].Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+	defer part.Close()
+	return io.ReadAll(io.LimitReader(part, 1<<20))
+}
+
+// DownloadPackageVersion 下载包版本
+func (h *PackageHandler) DownloadPackageVersion(c *gin.Context) {
+	packageName := c.Param("package")
+	version := c.Param("version")
+
+	if packageName == "" || version == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name and version are required")
+		return
+	}
+
+	packageName, ok := h.resolvePackageAlias(c, packageName)
+	if !ok {
+		return
+	}
+
+	var userID *uint
+	if id, exists := c.Get("user_id"); exists {
+		uid := id.(uint)
+		userID = &uid
+	}
+
+	h.streamPackageVersion(c, packageName, version, userID)
+}
+
+// DownloadWithToken 通过app-signed下载链接下载包版本
+// 令牌已包含包名、版本和签发用户，无需登录；签名不匹配返回403，过期返回410
+func (h *PackageHandler) DownloadWithToken(c *gin.Context) {
+	token, err := h.packageService.VerifyDownloadToken(c.Param("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrDownloadTokenExpired) {
+			middleware.ErrorResponse(c, http.StatusGone, "Download link has expired")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusForbidden, "Invalid download link")
+		return
+	}
+
+	// 签发后包可能被重命名，按别名解析到当前包名
+	packageName, ok := h.resolvePackageAlias(c, token.Package)
+	if !ok {
+		return
+	}
+
+	var userID *uint
+	if token.UserID != 0 {
+		userID = &token.UserID
+	}
+
+	h.streamPackageVersion(c, packageName, token.Version, userID)
+}
+
+// streamPackageVersion 从存储读取包版本文件并写入响应，下载会被计数
+func (h *PackageHandler) streamPackageVersion(c *gin.Context, packageName, version string, userID *uint) {
+	ipAddress := c.ClientIP()
+	userAgent := c.GetHeader("User-Agent")
+
+	reader, pkgVersion, err := h.packageService.DownloadPackageVersion(
+		c.Request.Context(),
+		packageName,
+		version,
+		userID,
+		ipAddress,
+		userAgent,
+		h.botDetector.Excluded(ipAddress, userAgent),
+	)
+	if err != nil {
+		if respondVersionRestoring(c, err) || respondBandwidthLimitExceeded(c, err) || respondVersionScanBlocked(c, err) {
+			return
+		}
+		var limitErr *service.DownloadRateLimitError
+		if errors.As(err, &limitErr) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+			middleware.ErrorResponse(c, http.StatusTooManyRequests, "Download rate limit exceeded for this package, please retry later")
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "access denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to download package")
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Disposition", minio.AttachmentDisposition(minio.DownloadFilename(packageName, version)))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Length", strconv.FormatInt(pkgVersion.FileSize, 10))
+	c.Header("X-Package-Name", packageName)
+	c.Header("X-Package-Version", version)
+	c.Header("X-Package-Hash", pkgVersion.FileHash)
+
+	c.DataFromReader(http.StatusOK, pkgVersion.FileSize, "application/octet-stream", reader, map[string]string{})
+}
+
+// GetPackageVersions 获取包的所有版本
+func (h *PackageHandler) GetPackageVersions(c *gin.Context) {
+	packageName := c.Param("package")
+	if packageName == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name is required")
+		return
+	}
+
+	packageName, ok := h.resolvePackageAlias(c, packageName)
+	if !ok {
+		return
+	}
+
+	page, pageSize := parsePage(c, models.DefaultPageSize, models.MaxPageSize)
+
+	fields, err := parseFields(c, versionFields)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	response, err := h.packageService.GetPackageVersions(c.Request.Context(), packageName, page, pageSize, optionalUserID(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if strings.Contains(err.Error(), "access denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get package versions")
+		return
+	}
+
+	if fields != nil {
+		middleware.SuccessResponse(c, paginatedData("versions", projectFields(response.Versions, fields, versionFields), response.Pagination))
+		return
+	}
+
 	middleware.SuccessResponse(c, response)
 }
 
-// GetPackageStats 获取包统计信息
+// DeletePackageVersion 删除包版本
+func (h *PackageHandler) DeletePackageVersion(c *gin.Context) {
+	packageName := c.Param("package")
+	version := c.Param("version")
+
+	if packageName == "" || version == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name and version are required")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.packageService.DeletePackageVersion(c.Request.Context(), packageName, version, userID.(uint))
+	if err != nil {
+		if respondPackageArchived(c, err) || respondDeleteBusy(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrVersionLocked) {
+			middleware.ErrorResponse(c, http.StatusLocked, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete package version")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "Package version deleted successfully"})
+}
+
+// SearchPackages 搜索包
+func (h *PackageHandler) SearchPackages(c *gin.Context) {
+	var req models.SearchPackagesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters"+err.Error())
+		return
+	}
+	req.Page, req.PageSize = parsePage(c, models.DefaultPageSize, models.MaxPageSize)
+	logger.Info("SearchPackages request", "query", req.Query, "page", req.Page, "page_size", req.PageSize)
+
+	fields, err := parseFields(c, packageFields)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	format, ok := h.negotiator.negotiate(c, mimeJSON, mimeYAML, mimeCSV)
+	if !ok {
+		return
+	}
+
+	response, err := h.packageService.SearchPackages(c.Request.Context(), &req)
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to search packages")
+		return
+	}
+
+	respondNegotiated(c, format, packageListData(response, fields), func() csvTable {
+		columns := fields
+		if columns == nil {
+			columns = packageCSVColumns
+		}
+		return fieldTable(response.Packages, columns, packageFields)
+	})
+}
+
+// ListUserPackages 获取指定用户发布的包列表
+func (h *PackageHandler) ListUserPackages(c *gin.Context) {
+	owner := c.Param("id")
+	if owner == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "User is required")
+		return
+	}
+
+	page, pageSize := parsePage(c, models.DefaultPageSize, models.MaxPageSize)
+
+	var viewerID *uint
+	if id, exists := c.Get("user_id"); exists {
+		uid := id.(uint)
+		viewerID = &uid
+	}
+
+	fields, err := parseFields(c, packageFields)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	response, err := h.packageService.ListByOwner(c.Request.Context(), owner, viewerID, page, pageSize)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "User not found")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to list user packages")
+		return
+	}
+
+	middleware.SuccessResponse(c, packageListData(response, fields))
+}
+
+// packageListData 按fields参数生成包列表响应数据，分页信息保持不变
+func packageListData(response *models.PackageListResponse, fields []string) interface{} {
+	if fields == nil {
+		return response
+	}
+	return paginatedData("packages", projectFields(response.Packages, fields, packageFields), response.Pagination)
+}
+
+// GetPackageStats 获取包统计信息，rank_by指定热门包排序依据：installs（默认）或downloads
 func (h *PackageHandler) GetPackageStats(c *gin.Context) {
-	stats, err := h.packageService.GetPackageStats(c.Request.Context())
+	rankBy := c.DefaultQuery("rank_by", models.RankByInstalls)
+	if rankBy != models.RankByInstalls && rankBy != models.RankByDownloads {
+		middleware.ValidationErrorResponse(c, "rank_by must be installs or downloads")
+		return
+	}
+
+	format, ok := h.negotiator.negotiate(c, mimeJSON, mimeYAML)
+	if !ok {
+		return
+	}
+
+	stats, err := h.packageService.GetPackageStats(c.Request.Context(), rankBy)
 	if err != nil {
 		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get package stats "+err.Error())
 		return
 	}
 
-	middleware.SuccessResponse(c, stats)
+	respondNegotiated(c, format, stats, nil)
+}
+
+// GetEcosystemDistribution 获取包生态统计，各分布为{label, value}数组并附带建议的图表类型
+func (h *PackageHandler) GetEcosystemDistribution(c *gin.Context) {
+	dist, err := h.packageService.GetEcosystemDistribution(c.Request.Context())
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get ecosystem stats")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	middleware.SuccessResponse(c, dist)
+}
+
+// GetDownloadRecords 获取包的下载记录（包所有者），支持JSON、CSV和YAML
+func (h *PackageHandler) GetDownloadRecords(c *gin.Context) {
+	packageName := c.Param("package")
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	page, pageSize := parsePage(c, 100, 1000)
+
+	format, ok := h.negotiator.negotiate(c, mimeJSON, mimeCSV, mimeYAML)
+	if !ok {
+		return
+	}
+
+	response, err := h.packageService.GetDownloadRecords(c.Request.Context(), packageName, userID.(uint), page, pageSize)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get download records")
+		return
+	}
+
+	respondNegotiated(c, format, response, func() csvTable {
+		return fieldTable(response.Downloads, downloadCSVColumns, downloadFields)
+	})
 }
 
-// GetDownloadURL 获取下载URL
+// GetPackageAnalytics 获取包的下载分布（包所有者），按国家、客户端、客户端类别和操作系统分组
+// days参数指定统计最近多少天，默认30天
+func (h *PackageHandler) GetPackageAnalytics(c *gin.Context) {
+	packageName := c.Param("package")
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		middleware.ValidationErrorResponse(c, "days must be between 1 and 365")
+		return
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days)
+
+	response, err := h.analyticsService.GetPackageAnalytics(c.Request.Context(), packageName, userID.(uint), since)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to get package analytics")
+		return
+	}
+
+	middleware.SuccessResponse(c, response)
+}
+
+// GetDownloadURL 获取下载URL，返回有效期expires_in（秒）和过期时间expires_at，客户端可据此在过期前刷新
 func (h *PackageHandler) GetDownloadURL(c *gin.Context) {
 	packageName := c.Param("package")
 	version := c.Param("version")
@@ -374,14 +1040,23 @@ func (h *PackageHandler) GetDownloadURL(c *gin.Context) {
 		return
 	}
 
+	packageName, ok := h.resolvePackageAlias(c, packageName)
+	if !ok {
+		return
+	}
+
 	var userID *uint
 	if id, exists := c.Get("user_id"); exists {
 		uid := id.(uint)
 		userID = &uid
 	}
 
-	url, err := h.packageService.GetDownloadURL(c.Request.Context(), packageName, version, userID)
+	country := h.analyticsService.ResolveCountry(c.ClientIP())
+	downloadURL, filename, expiresAt, err := h.packageService.GetDownloadURL(c.Request.Context(), packageName, version, userID, country)
 	if err != nil {
+		if respondVersionRestoring(c, err) || respondBandwidthLimitExceeded(c, err) || respondVersionScanBlocked(c, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
 			return
@@ -394,8 +1069,382 @@ func (h *PackageHandler) GetDownloadURL(c *gin.Context) {
 		return
 	}
 
+	// app-signed模式未配置外部地址时返回的是相对路径，按当前请求补全
+	if strings.HasPrefix(downloadURL, "/") {
+		downloadURL = requestBaseURL(c) + downloadURL
+	}
+
 	middleware.SuccessResponse(c, gin.H{
-		"download_url": url,
-		"expires_in":   3600,
+		"download_url": downloadURL,
+		"filename":     filename,
+		"expires_in":   int(h.packageService.DownloadURLTTL().Seconds()),
+		"expires_at":   expiresAt,
 	})
 }
+
+// RefreshDownloadURL 重新签发下载URL，供缓存了链接的客户端在过期前（或过期后）换取新链接
+// 与GetDownloadURL执行相同的权限检查，旧链接在原过期时间前仍然有效
+func (h *PackageHandler) RefreshDownloadURL(c *gin.Context) {
+	h.GetDownloadURL(c)
+}
+
+// ListArchiveContents 分页列出包版本文件（zip/tar/tar.gz）中的条目
+func (h *PackageHandler) ListArchiveContents(c *gin.Context) {
+	packageName := c.Param("package")
+	version := c.Param("version")
+
+	if packageName == "" || version == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name and version are required")
+		return
+	}
+
+	packageName, ok := h.resolvePackageAlias(c, packageName)
+	if !ok {
+		return
+	}
+
+	var userID *uint
+	if id, exists := c.Get("user_id"); exists {
+		uid := id.(uint)
+		userID = &uid
+	}
+
+	page, _ := strconv.Atoi(c.Query("page"))
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	contents, err := h.packageService.ListArchiveContents(c.Request.Context(), packageName, version, userID, page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedArchive) || errors.Is(err, service.ErrInvalidArchive) {
+			middleware.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "access denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to list package contents")
+		return
+	}
+
+	middleware.SuccessResponse(c, contents)
+}
+
+// requestBaseURL 根据请求推断外部访问地址，支持反向代理设置的X-Forwarded-Proto
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// PinVersion 置顶包版本，置顶版本不会被清理
+func (h *PackageHandler) PinVersion(c *gin.Context) {
+	packageName := c.Param("package")
+	version := c.Param("version")
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	pin, err := h.packageService.PinVersion(c.Request.Context(), packageName, version, userID.(uint))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to pin version")
+		return
+	}
+
+	middleware.SuccessResponse(c, pin)
+}
+
+// UnpinVersion 取消包版本置顶
+func (h *PackageHandler) UnpinVersion(c *gin.Context) {
+	packageName := c.Param("package")
+	version := c.Param("version")
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	err := h.packageService.UnpinVersion(c.Request.Context(), packageName, version, userID.(uint))
+	if err != nil {
+		if strings.Contains(err.Error(), "not pinned") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Version is not pinned")
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to unpin version")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "Version unpinned successfully"})
+}
+
+// BulkDeprecateVersions 批量弃用包版本（包所有者和maintainer协作者），单次最多500个版本ID
+func (h *PackageHandler) BulkDeprecateVersions(c *gin.Context) {
+	packageName := c.Param("package")
+
+	var req models.BulkDeprecateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	result, err := h.packageService.BulkDeprecateVersions(c.Request.Context(), packageName, req.VersionIDs, req.Message, userID.(uint))
+	if err != nil {
+		h.respondBulkDeprecateError(c, err)
+		return
+	}
+
+	middleware.SuccessResponse(c, result)
+}
+
+// BulkUndeprecateVersions 批量取消版本弃用（包所有者和maintainer协作者）
+func (h *PackageHandler) BulkUndeprecateVersions(c *gin.Context) {
+	packageName := c.Param("package")
+
+	var req models.BulkDeprecateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	result, err := h.packageService.BulkUndeprecateVersions(c.Request.Context(), packageName, req.VersionIDs, userID.(uint))
+	if err != nil {
+		h.respondBulkDeprecateError(c, err)
+		return
+	}
+
+	middleware.SuccessResponse(c, result)
+}
+
+// respondBulkDeprecateError 批量弃用/取消弃用的错误响应
+func (h *PackageHandler) respondBulkDeprecateError(c *gin.Context, err error) {
+	switch {
+	case respondPackageArchived(c, err):
+	case strings.Contains(err.Error(), "not found"):
+		middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+	case strings.Contains(err.Error(), "permission denied"):
+		middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+	case strings.Contains(err.Error(), "too many version ids"):
+		middleware.ValidationErrorResponse(c, err.Error())
+	default:
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to update version deprecation")
+	}
+}
+
+// PruneVersions 清理旧版本，保留最近的版本和置顶版本
+func (h *PackageHandler) PruneVersions(c *gin.Context) {
+	packageName := c.Param("package")
+
+	var req models.PruneVersionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	result, err := h.packageService.PruneOldVersions(c.Request.Context(), packageName, req.Keep, userID.(uint))
+	if err != nil {
+		if respondPackageArchived(c, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusInternalServerError, "Failed to prune versions")
+		return
+	}
+
+	middleware.SuccessResponse(c, result)
+}
+
+// respondPackageArchived 包已归档时返回409 package_archived，返回true表示已写入响应
+func respondPackageArchived(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrPackageArchived) {
+		return false
+	}
+	middleware.ErrorResponse(c, http.StatusConflict, "package_archived")
+	return true
+}
+
+// respondInvalidLicense 许可证无法识别或不在允许列表中时返回422及相近的合法标识符，返回true表示已写入响应
+func respondInvalidLicense(c *gin.Context, err error) bool {
+	var licenseErr *license.ValidationError
+	if !errors.As(err, &licenseErr) {
+		return false
+	}
+	code := codeUnknownLicense
+	if errors.Is(err, license.ErrLicenseNotAllowed) {
+		code = codeLicenseNotAllowed
+	}
+	middleware.CustomResponse(c, http.StatusUnprocessableEntity, code, err.Error(), gin.H{
+		"license":     licenseErr.Value,
+		"suggestions": licenseErr.Suggestions,
+	})
+	return true
+}
+
+// respondStaleUpdate 乐观锁冲突时返回409及记录的当前状态，要求携带if_version但未携带时返回428，返回true表示已写入响应
+func respondStaleUpdate(c *gin.Context, err error) bool {
+	var staleErr *service.StaleUpdateError
+	switch {
+	case errors.As(err, &staleErr):
+		middleware.CustomResponse(c, http.StatusConflict, codeStaleUpdate, err.Error(), staleErr.Current)
+	case errors.Is(err, service.ErrIfVersionRequired):
+		middleware.ErrorResponse(c, http.StatusPreconditionRequired, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// respondDependencyConflict 依赖无法解析时返回422及发现的问题，返回true表示已写入响应
+func respondDependencyConflict(c *gin.Context, err error) bool {
+	var conflictErr *service.DependencyConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+	middleware.CustomResponse(c, http.StatusUnprocessableEntity, codeDependencyConflict, err.Error(), gin.H{
+		"conflicts": conflictErr.Conflicts,
+	})
+	return true
+}
+
+// respondDeleteBusy 同时执行的删除数已达上限时返回503，返回true表示已写入响应
+func respondDeleteBusy(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrDeleteBusy) {
+		return false
+	}
+	c.Header("Retry-After", "1")
+	middleware.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+	return true
+}
+
+// respondBandwidthLimitExceeded 包本月下载流量超过上限时返回429，返回true表示已写入响应
+func respondBandwidthLimitExceeded(c *gin.Context, err error) bool {
+	var limitErr *service.BandwidthLimitExceededError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	middleware.CustomResponse(c, http.StatusTooManyRequests, codeBandwidthLimitExceeded, "bandwidth_limit_exceeded", gin.H{
+		"package":     limitErr.Package,
+		"limit_bytes": limitErr.LimitBytes,
+		"used_bytes":  limitErr.UsedBytes,
+	})
+	return true
+}
+
+// respondVersionScanBlocked 版本已被隔离时返回451，正在扫描时返回409及Retry-After，返回true表示已写入响应
+func respondVersionScanBlocked(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrVersionQuarantined):
+		middleware.CustomResponse(c, http.StatusUnavailableForLegalReasons, codeVersionQuarantined, err.Error(), gin.H{
+			"scan_status": models.ScanStatusQuarantined,
+		})
+		return true
+	case errors.Is(err, service.ErrVersionScanning):
+		retryAfter := int(versionScanRetryAfter.Seconds())
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		middleware.CustomResponse(c, http.StatusConflict, codeVersionScanning, err.Error(), gin.H{
+			"scan_status": models.ScanStatusScanning,
+			"retry_after": retryAfter,
+		})
+		return true
+	}
+	return false
+}
+
+// respondVersionRestoring 版本文件正在从冷存储恢复时返回202及Retry-After，返回true表示已写入响应
+func respondVersionRestoring(c *gin.Context, err error) bool {
+	var restoringErr *service.VersionRestoringError
+	if !errors.As(err, &restoringErr) {
+		return false
+	}
+	retryAfter := int(math.Ceil(restoringErr.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	middleware.CustomResponse(c, http.StatusAccepted, 0, err.Error(), gin.H{
+		"storage_tier": models.StorageTierRestoring,
+		"retry_after":  retryAfter,
+	})
+	return true
+}
+
+// respondInvalidChangelog 更新日志超过最大字节数时返回400，格式不符合要求时返回422及各项校验错误，返回true表示已写入响应
+func respondInvalidChangelog(c *gin.Context, err error) bool {
+	if errors.Is(err, service.ErrChangelogTooLarge) {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return true
+	}
+	var changelogErr *service.ChangelogValidationError
+	if !errors.As(err, &changelogErr) {
+		return false
+	}
+	middleware.CustomResponse(c, http.StatusUnprocessableEntity, codeInvalidChangelog, err.Error(), gin.H{
+		"errors": changelogErr.Errors,
+	})
+	return true
+}
+
+// respondPackageNameUnavailable 包名格式不合法时返回400，已占用、保留或删除保留期内时返回409，返回true表示已写入响应
+func respondPackageNameUnavailable(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrInvalidPackageName):
+		middleware.ValidationErrorResponse(c, err.Error())
+	case errors.Is(err, service.ErrPackageExists):
+		middleware.ErrorResponse(c, http.StatusConflict, "Package already exists")
+	case errors.Is(err, service.ErrPackageNameAliased),
+		errors.Is(err, service.ErrPackageNameReserved),
+		errors.Is(err, service.ErrPackageNameRecentlyDeleted):
+		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		return false
+	}
+	return true
+}
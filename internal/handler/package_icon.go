@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// iconFormOverhead multipart表单除图标内容外的余量（分隔符、各部分的头）
+const iconFormOverhead = 16 << 10
+
+// UploadPackageIcon 上传或替换包图标，表单字段icon，支持png、webp和svg
+func (h *PackageHandler) UploadPackageIcon(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	maxBytes := h.packageService.IconMaxBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+iconFormOverhead)
+	file, _, err := c.Request.FormFile("icon")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.ErrorResponse(c, http.StatusRequestEntityTooLarge, service.ErrIconTooLarge.Error())
+			return
+		}
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Icon file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Failed to read icon file")
+		return
+	}
+
+	pkg, err := h.packageService.UploadPackageIcon(c.Request.Context(), c.Param("package"), userID, data)
+	if err != nil {
+		if respondPackageArchived(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrIconTooLarge):
+			middleware.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, service.ErrInvalidIcon):
+			middleware.ValidationErrorResponse(c, err.Error())
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		case strings.Contains(err.Error(), "permission denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to upload package icon")
+		}
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"icon_url": pkg.IconURL})
+}
+
+// GetPackageIcon 返回包图标，支持If-None-Match条件请求
+// 带有与当前图标一致的v参数（即icon_url）时长期缓存；不带v参数时每次按ETag重新验证
+func (h *PackageHandler) GetPackageIcon(c *gin.Context) {
+	packageName, ok := h.resolvePackageAlias(c, c.Param("package"))
+	if !ok {
+		return
+	}
+
+	icon, err := h.packageService.GetPackageIcon(c.Request.Context(), packageName, optionalUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIconNotFound):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package has no icon")
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		case strings.Contains(err.Error(), "access denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to get package icon")
+		}
+		return
+	}
+
+	etag := `"` + icon.ETag + `"`
+	scope := "public"
+	if icon.Private {
+		scope = "private"
+	}
+	if v := c.Query("v"); v != "" && strings.HasSuffix(models.PackageIconURL(packageName, icon.ETag), "?v="+v) {
+		c.Header("Cache-Control", scope+", max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", scope+", no-cache")
+	}
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	// 直接打开svg图标时禁止执行脚本和加载任何外部资源
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, icon.ContentType, icon.Data)
+}
+
+// etagMatches If-None-Match请求头是否包含指定的ETag（弱比较）
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"webservice/internal/authz"
+	"webservice/internal/middleware"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPackagePermissions 返回调用方对包的有效权限及原因（read、publish、edit_metadata、delete、manage_collaborators、admin_override）
+// 与实际执行时使用相同的规则；未登录时只有公开包的read
+// 原因为public、owner、maintainer、reader（协作者角色）或admin
+func (h *Handler) GetPackagePermissions(c *gin.Context) {
+	packageName := c.Param("package")
+	permissions, err := h.packageService.GetPackagePermissions(c.Request.Context(), packageName, h.packageCaller(c, packageName))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to get package permissions")
+		return
+	}
+
+	middleware.SuccessResponse(c, permissions)
+}
+
+// packageCaller 根据请求的用户、token范围和角色策略构造包权限检查的调用方
+func (h *Handler) packageCaller(c *gin.Context, packageName string) service.PackageCaller {
+	caller := service.PackageCaller{
+		UserID:     optionalUserID(c),
+		OutOfScope: !middleware.TokenAllowsPackage(c, packageName),
+	}
+	if role, ok := middleware.GetRoleFromContext(c); ok {
+		caller.AdminOverride = h.Policy.Allowed(role, authz.ActionPackageModerate, "*")
+	}
+	return caller
+}
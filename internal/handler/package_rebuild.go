@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebuildPackage 按源数据重建包的下载计数、存储状态、缓存和搜索索引（管理员），返回修正内容
+// 有修正时记录package.rebuild审计日志
+func (h *Handler) RebuildPackage(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	report, err := h.packageService.RebuildPackage(c.Request.Context(), c.Param("package"), h.packageCaller(c, c.Param("package")))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		case strings.Contains(err.Error(), "permission denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to rebuild package")
+		}
+		return
+	}
+
+	if report.Corrected() {
+		if err := h.auditService.Record(c.Request.Context(), userID, "package.rebuild", "packages/"+report.Package, report, c.ClientIP()); err != nil {
+			logger.Warnf("Failed to audit package rebuild: %v", err)
+		}
+	}
+
+	middleware.SuccessResponse(c, report)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"strconv"
+
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePage 解析page和page_size查询参数，非法值按models.NormalizePage处理，返回的pageSize总是大于0
+func parsePage(c *gin.Context, defaultSize, maxSize int) (int, int) {
+	return models.NormalizePage(queryInt(c, "page"), queryInt(c, "page_size"), defaultSize, maxSize)
+}
+
+// queryInt 解析整数查询参数，缺省、格式错误或超出int范围时返回0
+// strconv.Atoi溢出时返回的是边界值而不是0，不能直接忽略错误
+func queryInt(c *gin.Context, key string) int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// paginatedData 生成列表响应数据：key对应列表，分页字段与之平级，与models中各列表响应结构的JSON格式一致
+func paginatedData(key string, items interface{}, p models.Pagination) gin.H {
+	return gin.H{
+		key:           items,
+		"total":       p.Total,
+		"page":        p.Page,
+		"page_size":   p.PageSize,
+		"total_pages": p.TotalPages,
+		"has_next":    p.HasNext,
+		"has_prev":    p.HasPrev,
+	}
+}
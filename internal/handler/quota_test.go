@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+)
+
+func TestUploadQuotaHeaders(t *testing.T) {
+	env := newPackageTestEnv(t, func(cfg *config.PackagesConfig) {
+		cfg.StorageQuotaBytes = 100
+		cfg.QuotaSoftLimitPercent = 80
+	})
+	owner := env.createUser("alice")
+	env.createPackage("app", owner)
+	env.r.POST("/packages/:package/versions", asUser(owner.ID), env.handler.UploadPackageVersion)
+
+	upload := func(version string, size int) *http.Request {
+		return uploadRequest(t, "/packages/app/versions", map[string]string{"version": version}, strings.Repeat("x", size))
+	}
+
+	// 低于软限制：不返回配额响应头
+	w := env.do(upload("1.0.0", 50))
+	if w.Code != http.StatusOK {
+		t.Fatalf("first upload: status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Quota-Warning") != "" || w.Header().Get("X-Quota-Used") != "" {
+		t.Errorf("quota headers below the soft limit: %v", w.Header())
+	}
+
+	// 超过软限制：上传成功并带警告
+	w = env.do(upload("1.1.0", 35))
+	if w.Code != http.StatusOK {
+		t.Fatalf("upload over the soft limit: status = %d, body %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Quota-Used"); got != "85" {
+		t.Errorf("X-Quota-Used = %q, want 85", got)
+	}
+	if got := w.Header().Get("X-Quota-Limit"); got != "100" {
+		t.Errorf("X-Quota-Limit = %q, want 100", got)
+	}
+	if got := w.Header().Get("X-Quota-Warning"); !strings.Contains(got, "85%") {
+		t.Errorf("X-Quota-Warning = %q, want it to mention 85%%", got)
+	}
+
+	// 超过硬限制：拒绝上传
+	w = env.do(upload("1.2.0", 20))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("upload over the quota: status = %d, want 413, body %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Quota-Used"); got != "85" {
+		t.Errorf("X-Quota-Used on rejection = %q, want 85", got)
+	}
+	if n := env.countVersions("app"); n != 2 {
+		t.Errorf("%d versions stored, want 2", n)
+	}
+}
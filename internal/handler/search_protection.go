@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net"
+
+	"webservice/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LookupSearchProtectionIP 查询IP的搜索防护状态（管理员）：是否被标记、标记原因和当前窗口内的匿名搜索次数
+func (h *Handler) LookupSearchProtectionIP(c *gin.Context) {
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		middleware.ValidationErrorResponse(c, "Invalid IP address")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"enabled": h.BotDetector.Enabled(),
+		"status":  h.BotDetector.Lookup(ip.String()),
+	})
+}
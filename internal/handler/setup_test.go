@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetupErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	passwordCfg := config.PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4}
+
+	tests := []struct {
+		name       string
+		prepare    func(t *testing.T, h *Handler) string // 返回请求使用的令牌
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "invalid token",
+			prepare: func(t *testing.T, h *Handler) string {
+				return "not-a-token"
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "admin already exists",
+			prepare: func(t *testing.T, h *Handler) string {
+				if err := h.db.Create(&models.User{Username: "root", Email: "root@example.com", Password: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}).Error; err != nil {
+					t.Fatal(err)
+				}
+				return "not-a-token"
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "username taken",
+			prepare: func(t *testing.T, h *Handler) string {
+				if err := h.db.Create(&models.User{Username: "admin", Email: "other@example.com", Password: "x", Role: models.RoleUser, Status: models.UserStatusActive}).Error; err != nil {
+					t.Fatal(err)
+				}
+				token, _, err := h.bootstrapService.IssueSetupToken()
+				if err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			// 数据库错误不应以409返回，也不能把原始错误信息暴露给客户端
+			name: "database error",
+			prepare: func(t *testing.T, h *Handler) string {
+				sqlDB, err := h.db.DB()
+				if err != nil {
+					t.Fatal(err)
+				}
+				sqlDB.Close()
+				return "not-a-token"
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Failed to complete setup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t, &models.User{}, &models.SetupToken{})
+			h := &Handler{db: db, bootstrapService: service.NewBootstrapService(db, config.BootstrapConfig{}, passwordCfg)}
+			token := tt.prepare(t, h)
+
+			body, _ := json.Marshal(models.SetupRequest{Token: token, Username: "admin", Email: "admin@example.com", Password: "password123"})
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/setup", strings.NewReader(string(body)))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			h.Setup(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %q", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusInternalServerError && strings.Contains(w.Body.String(), "sql") {
+				t.Errorf("body leaks database error: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"webservice/internal/authz"
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// codeAccountSuspended 暂停的用户登录时返回的业务错误码
+const codeAccountSuspended = 40302
+
+// respondAccountSuspended 用户处于暂停状态时返回403及暂停原因和到期时间，返回true表示已写入响应
+func respondAccountSuspended(c *gin.Context, err error) bool {
+	var suspendedErr *service.UserSuspendedError
+	if !errors.As(err, &suspendedErr) {
+		return false
+	}
+	middleware.CustomResponse(c, http.StatusForbidden, codeAccountSuspended, err.Error(), gin.H{
+		"reason":     suspendedErr.Reason,
+		"expires_at": suspendedErr.ExpiresAt,
+	})
+	return true
+}
+
+// SuspendUser 暂停用户（管理员），可指定暂停时长，到期后自动解除
+func (h *Handler) SuspendUser(c *gin.Context) {
+	target, ok := h.suspensionTarget(c)
+	if !ok {
+		return
+	}
+
+	var req models.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	var duration *time.Duration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			middleware.ValidationErrorResponse(c, "Invalid duration, expected a positive Go duration such as 72h")
+			return
+		}
+		duration = &d
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(c)
+	if err := h.userService.SuspendUser(c.Request.Context(), target.ID, actorID, req.Reason, duration); err != nil {
+		switch {
+		case errors.Is(err, service.ErrCannotSuspendSelf):
+			middleware.ValidationErrorResponse(c, err.Error())
+		case errors.Is(err, service.ErrUserSuspended):
+			middleware.ErrorResponse(c, http.StatusConflict, "User is already suspended")
+		case strings.Contains(err.Error(), "not found"):
+			middleware.NotFoundResponse(c, "User not found")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to suspend user")
+		}
+		return
+	}
+
+	suspensions, err := h.userService.GetSuspensions(c.Request.Context(), target.ID)
+	if err != nil || len(suspensions) == 0 {
+		middleware.InternalServerErrorResponse(c, "Failed to get suspension")
+		return
+	}
+	if err := h.auditService.Record(c.Request.Context(), actorID, "users.suspend", "users/"+strconv.FormatUint(uint64(target.ID), 10), suspensions[0], c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit user suspension: %v", err)
+	}
+
+	middleware.SuccessResponse(c, suspensions[0])
+}
+
+// LiftUserSuspension 手动解除用户的暂停（管理员）
+func (h *Handler) LiftUserSuspension(c *gin.Context) {
+	target, ok := h.suspensionTarget(c)
+	if !ok {
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(c)
+	if err := h.userService.LiftSuspension(c.Request.Context(), target.ID, actorID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrUserNotSuspended):
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+		case strings.Contains(err.Error(), "not found"):
+			middleware.NotFoundResponse(c, "User not found")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to lift suspension")
+		}
+		return
+	}
+
+	if err := h.auditService.Record(c.Request.Context(), actorID, "users.lift_suspension", "users/"+strconv.FormatUint(uint64(target.ID), 10), nil, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit suspension lift: %v", err)
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "Suspension lifted"})
+}
+
+// GetUserSuspensions 获取用户的暂停历史（管理员），最近的在前
+func (h *Handler) GetUserSuspensions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid user ID")
+		return
+	}
+	if _, err := h.userService.GetUserByID(c.Request.Context(), uint(id)); err != nil {
+		middleware.NotFoundResponse(c, "User not found")
+		return
+	}
+
+	suspensions, err := h.userService.GetSuspensions(c.Request.Context(), uint(id))
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get suspensions")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"suspensions": suspensions})
+}
+
+// suspensionTarget 解析目标用户并按其角色检查暂停/解除权限（admin只能管理普通用户），失败时已写入响应
+func (h *Handler) suspensionTarget(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid user ID")
+		return nil, false
+	}
+
+	target, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
+	if err != nil {
+		middleware.NotFoundResponse(c, "User not found")
+		return nil, false
+	}
+	if !h.Policy.Allowed(c.GetString("role"), authz.ActionUserUpdate, target.Role) {
+		middleware.ForbiddenResponse(c, "Insufficient permissions to manage this user")
+		return nil, false
+	}
+	return target, true
+}
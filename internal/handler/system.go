@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"runtime"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/tracer"
+	"webservice/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVersion 获取构建信息（版本、提交、构建日期、Go版本），无需认证；未通过-ldflags注入时版本为dev
+func (h *Handler) GetVersion(c *gin.Context) {
+	middleware.SuccessResponse(c, version.Get())
+}
+
+// GetSystemConfig 获取当前运行的配置（密码、密钥等已脱敏），只读
+func (h *Handler) GetSystemConfig(c *gin.Context) {
+	middleware.SuccessResponse(c, h.cfg.Sanitized())
+}
+
+// GetSystemInfo 获取运行环境信息：Go版本、启动时间、系统架构、协程数、内存和日志级别
+func (h *Handler) GetSystemInfo(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	middleware.SuccessResponse(c, gin.H{
+		"version":    version.Get(),
+		"go_version": runtime.Version(),
+		"start_time": version.StartTime().UTC().Format(time.RFC3339),
+		"uptime":     version.Uptime().Round(time.Second).String(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+		"num_cpu":    runtime.NumCPU(),
+		"goroutines": runtime.NumGoroutine(),
+		"memory": gin.H{
+			"alloc_bytes":       mem.Alloc,
+			"total_alloc_bytes": mem.TotalAlloc,
+			"heap_inuse_bytes":  mem.HeapInuse,
+			"sys_bytes":         mem.Sys,
+			"num_gc":            mem.NumGC,
+		},
+		"log_level": logger.GetLogger().GetLevel().String(),
+		"features":  h.featureFlags(),
+	})
+}
+
+// GetSystemFeatures 获取功能开关状态
+func (h *Handler) GetSystemFeatures(c *gin.Context) {
+	middleware.SuccessResponse(c, h.featureFlags())
+}
+
+// featureFlags 根据配置和运行状态汇总各可选功能是否启用
+func (h *Handler) featureFlags() map[string]bool {
+	cfg := h.cfg
+	return map[string]bool{
+		"tracing_enabled":           cfg.Jaeger.Enabled && tracer.Enabled(),
+		"storage_available":         h.minioClient != nil,
+		"mirroring_enabled":         len(cfg.MinIO.Replicas) > 0,
+		"download_mirrors_enabled":  len(cfg.MinIO.Mirrors) > 0,
+		"compression_enabled":       cfg.MinIO.CompressArtifacts,
+		"upload_throttling_enabled": cfg.MinIO.UploadBandwidthLimitBytesPerSec > 0 || cfg.Packages.UserUploadBandwidthLimitBytesPerSec > 0,
+		"analytics_enabled":         cfg.Analytics.Enabled,
+		"geoip_enabled":             (cfg.Analytics.Enabled || len(cfg.MinIO.Mirrors) > 0) && cfg.Analytics.GeoIPDatabase != "",
+		"prerelease_expiry_enabled": cfg.Retention.PrereleaseMaxAge > 0,
+		"pprof_enabled":             cfg.Debug.Pprof,
+		"strict_startup":            cfg.Server.StrictStartup,
+		"block_private_networks":    cfg.Outbound.BlockPrivateNetworks,
+	}
+}
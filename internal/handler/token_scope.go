@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateScopedToken 创建只能操作指定包的token（如CI发布用），当前用户必须是每个包的所有者
+// 限定了包范围的token不能访问该接口（见middleware.ScopedTokenRoutePrefix），只有登录token可以创建
+func (h *Handler) CreateScopedToken(c *gin.Context) {
+	var req models.CreateScopedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	packages := make([]string, 0, len(req.Packages))
+	seen := make(map[string]bool, len(req.Packages))
+	for _, name := range req.Packages {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		packages = append(packages, name)
+	}
+	if len(packages) == 0 {
+		middleware.ValidationErrorResponse(c, "At least one package is required")
+		return
+	}
+
+	if err := h.packageService.CheckPublishRights(c.Request.Context(), userID, packages); err != nil {
+		if errors.Is(err, service.ErrNoPublishRights) {
+			middleware.ErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.NotFoundResponse(c, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to check package permissions")
+		return
+	}
+
+	user, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		middleware.NotFoundResponse(c, "User not found")
+		return
+	}
+
+	token, claims, err := middleware.GenerateScopedToken(user.ID, user.Username, packages, h.cfg.JWT)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to generate token")
+		return
+	}
+	session, err := h.sessionService.CreateScopedSession(user.ID, claims.ID, claims.ExpiresAt.Time, c.Request.UserAgent(), c.ClientIP(), packages)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to generate token")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"token":   token,
+		"session": session,
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupTOTP 生成两步验证密钥和otpauth URI，客户端展示二维码供身份验证器应用扫描
+func (h *Handler) SetupTOTP(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	setup, err := h.userService.SetupTOTP(c.Request.Context(), userID)
+	if err != nil {
+		respondTOTPError(c, err, "Failed to set up totp")
+		return
+	}
+	middleware.SuccessResponse(c, setup)
+}
+
+// EnableTOTP 校验验证码并启用两步验证
+func (h *Handler) EnableTOTP(c *gin.Context) {
+	h.changeTOTP(c, h.userService.EnableTOTP, "Failed to enable totp", "Two-factor authentication enabled")
+}
+
+// DisableTOTP 校验验证码并关闭两步验证；不受totp功能开关限制，开关关闭后已启用的用户仍可关闭
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	h.changeTOTP(c, h.userService.DisableTOTP, "Failed to disable totp", "Two-factor authentication disabled")
+}
+
+// changeTOTP 解析验证码请求并调用启用或关闭操作
+func (h *Handler) changeTOTP(c *gin.Context, change func(ctx context.Context, userID uint, code string) error, failure, message string) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	if err := change(c.Request.Context(), userID, req.Code); err != nil {
+		respondTOTPError(c, err, failure)
+		return
+	}
+	middleware.SuccessResponse(c, gin.H{"message": message})
+}
+
+// respondTOTPError 将两步验证服务错误映射为HTTP响应
+func respondTOTPError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTOTP):
+		middleware.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled), errors.Is(err, service.ErrTOTPNotEnabled), errors.Is(err, service.ErrTOTPNotSetUp):
+		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+	case err.Error() == "user not found":
+		middleware.NotFoundResponse(c, "User not found")
+	default:
+		logger.Errorf("%s: %v", failure, err)
+		middleware.InternalServerErrorResponse(c, failure)
+	}
+}
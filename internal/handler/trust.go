@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetPackageTrustLevel 设置包的信任等级（仅super角色）
+func (h *Handler) SetPackageTrustLevel(c *gin.Context) {
+	actorID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	packageName := c.Param("package")
+	if packageName == "" {
+		middleware.ErrorResponse(c, http.StatusBadRequest, "Package name is required")
+		return
+	}
+
+	var req models.UpdateTrustLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	pkg, change, err := h.packageService.SetPackageTrustLevel(c.Request.Context(), packageName, req.TrustLevel)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to update trust level")
+		return
+	}
+
+	if err := h.auditService.Record(c.Request.Context(), actorID, "packages.trust_level", "packages/"+pkg.Name, change, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit package trust level change: %v", err)
+	}
+
+	middleware.SuccessResponse(c, pkg)
+}
+
+// SetUserTrustLevel 设置用户的信任等级（仅super角色），用户的包会继承该等级
+func (h *Handler) SetUserTrustLevel(c *gin.Context) {
+	actorID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		middleware.ValidationErrorResponse(c, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateTrustLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	user, change, err := h.userService.SetUserTrustLevel(c.Request.Context(), uint(id), req.TrustLevel)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.NotFoundResponse(c, "User not found")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to update trust level")
+		return
+	}
+
+	if err := h.auditService.Record(c.Request.Context(), actorID, "users.trust_level", "users/"+strconv.FormatUint(uint64(user.ID), 10), change, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit user trust level change: %v", err)
+	}
+
+	middleware.SuccessResponse(c, user.ToPublicUser())
+}
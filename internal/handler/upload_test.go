@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestUploadStoresDependenciesFromMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{
+			name:   "metadata part",
+			fields: map[string]string{"metadata": `{"version":"1.0.0","dependencies":{"left-pad":"^1.0.0"},"add_keywords":["strings"]}`},
+		},
+		{
+			name:   "flat form fields",
+			fields: map[string]string{"version": "1.0.0", "dependencies": `{"left-pad":"^1.0.0"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newPackageTestEnv(t, nil)
+			owner := env.createUser("alice")
+			pkg := env.createPackage("app", owner)
+			env.r.POST("/packages/:package/versions", asUser(owner.ID), env.handler.UploadPackageVersion)
+
+			w := env.do(uploadRequest(t, "/packages/app/versions", tt.fields, "archive"))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+
+			var stored models.PackageVersion
+			if err := env.db.Where("package_id = ? AND version = ?", pkg.ID, "1.0.0").First(&stored).Error; err != nil {
+				t.Fatalf("version not stored: %v", err)
+			}
+			var deps map[string]string
+			if err := json.Unmarshal([]byte(stored.Dependencies), &deps); err != nil {
+				t.Fatalf("stored dependencies %q: %v", stored.Dependencies, err)
+			}
+			if deps["left-pad"] != "^1.0.0" || len(deps) != 1 {
+				t.Errorf("dependencies = %v, want left-pad ^1.0.0", deps)
+			}
+		})
+	}
+}
+
+func TestUploadRejectsInvalidMetadata(t *testing.T) {
+	env := newPackageTestEnv(t, nil)
+	owner := env.createUser("alice")
+	env.createPackage("app", owner)
+	env.r.POST("/packages/:package/versions", asUser(owner.ID), env.handler.UploadPackageVersion)
+
+	for _, metadata := range []string{`{"dependencies":{"left-pad":"1.0.0"}}`, `{"version":`} {
+		w := env.do(uploadRequest(t, "/packages/app/versions", map[string]string{"metadata": metadata}, "archive"))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("metadata %s: status = %d, want 400", metadata, w.Code)
+		}
+	}
+}
+
+func TestUploadRejectsOversizeChangelog(t *testing.T) {
+	env := newPackageTestEnv(t, func(cfg *config.PackagesConfig) { cfg.MaxChangelogBytes = 64 })
+	owner := env.createUser("alice")
+	env.createPackage("app", owner)
+	env.r.POST("/packages/:package/versions", asUser(owner.ID), env.handler.UploadPackageVersion)
+
+	changelog := "# 1.0.0\n" + strings.Repeat("- fix\n", 10) // 68字节
+	w := env.do(uploadRequest(t, "/packages/app/versions", map[string]string{"version": "1.0.0", "changelog": changelog}, "archive"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "changelog is too large") {
+		t.Fatalf("oversize changelog: status = %d, body %s, want 400", w.Code, w.Body.String())
+	}
+	if n := env.countVersions("app"); n != 0 {
+		t.Fatalf("versions after rejected upload = %d, want 0", n)
+	}
+
+	w = env.do(uploadRequest(t, "/packages/app/versions", map[string]string{"version": "1.0.0", "changelog": changelog[:64]}, "archive"))
+	if w.Code != http.StatusOK {
+		t.Errorf("changelog at the limit: status = %d, body %s", w.Code, w.Body.String())
+	}
+}
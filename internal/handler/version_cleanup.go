@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CleanupVersions 按条件批量清理包版本（包所有者和maintainer协作者），默认只返回预览，confirm为true时删除
+// 实际删除时记录packages.versions_cleanup审计日志，包含清理条件和结果
+func (h *Handler) CleanupVersions(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.ErrorResponse(c, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.VersionCleanupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	packageName := c.Param("package")
+	result, err := h.packageService.CleanupVersions(c.Request.Context(), packageName, &req, userID)
+	if err != nil {
+		if respondPackageArchived(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, service.ErrInvalidCleanupCriteria):
+			middleware.ValidationErrorResponse(c, err.Error())
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		case strings.Contains(err.Error(), "permission denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to clean up versions")
+		}
+		return
+	}
+
+	if !result.DryRun {
+		details := gin.H{
+			"criteria":  req,
+			"deleted":   result.Deleted,
+			"failed":    result.Failed,
+			"remaining": result.Remaining,
+		}
+		if err := h.auditService.Record(c.Request.Context(), userID, "packages.versions_cleanup", "packages/"+packageName, details, c.ClientIP()); err != nil {
+			logger.Warnf("Failed to audit version cleanup: %v", err)
+		}
+	}
+
+	middleware.SuccessResponse(c, result)
+}
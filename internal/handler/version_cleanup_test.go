@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+	"webservice/internal/service"
+)
+
+func TestCleanupVersionsAuditedOnlyWhenConfirmed(t *testing.T) {
+	env := newPackageTestEnv(t, nil)
+	owner := env.createUser("alice")
+	pkg := env.createPackage("app", owner)
+	for _, v := range []string{"1.0.0-beta.1", "1.0.0-beta.2", "1.0.0"} {
+		env.uploadVersion(pkg, v, owner.ID)
+	}
+	h := &Handler{packageService: env.service, auditService: service.NewAuditService(env.db)}
+	env.r.POST("/packages/:package/versions/cleanup", asUser(owner.ID), h.CleanupVersions)
+
+	cleanup := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/packages/app/versions/cleanup", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return env.do(req)
+	}
+	auditLogs := func() []models.AuditLog {
+		var logs []models.AuditLog
+		if err := env.db.Where("action = ?", "packages.versions_cleanup").Find(&logs).Error; err != nil {
+			t.Fatal(err)
+		}
+		return logs
+	}
+
+	// 预览和校验失败的请求不记录审计日志
+	for _, body := range []string{`{"version_glob":"*-beta.*"}`, `{"version_glob":"*-beta.*","confirm":false}`, `{"confirm":true}`, `{"version_glob":"[","confirm":true}`} {
+		cleanup(body)
+	}
+	if logs := auditLogs(); len(logs) != 0 {
+		t.Fatalf("audit logs after previews = %d, want 0", len(logs))
+	}
+	if n := env.countVersions("app"); n != 3 {
+		t.Fatalf("versions after previews = %d, want 3", n)
+	}
+
+	w := cleanup(`{"version_glob":"*-beta.*","keep_last":1,"confirm":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirmed cleanup status = %d, body %s", w.Code, w.Body.String())
+	}
+	logs := auditLogs()
+	if len(logs) != 1 {
+		t.Fatalf("audit logs after confirmed cleanup = %d, want 1", len(logs))
+	}
+	if logs[0].ActorID != owner.ID || logs[0].Resource != "packages/app" {
+		t.Errorf("audit log = %+v, want actor alice on packages/app", logs[0])
+	}
+	var details struct {
+		Criteria models.VersionCleanupRequest `json:"criteria"`
+		Deleted  []string                     `json:"deleted"`
+	}
+	if err := json.Unmarshal([]byte(logs[0].Details), &details); err != nil {
+		t.Fatalf("audit details %q: %v", logs[0].Details, err)
+	}
+	if details.Criteria.VersionGlob != "*-beta.*" || details.Criteria.KeepLast != 1 || !details.Criteria.Confirm || len(details.Deleted) != 1 || details.Deleted[0] != "1.0.0-beta.1" {
+		t.Errorf("audit details = %+v, want the criteria and the deleted 1.0.0-beta.1", details)
+	}
+}
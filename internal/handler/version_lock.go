@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockPackageVersion 立即锁定版本（管理员），锁定后版本不能删除
+func (h *Handler) LockPackageVersion(c *gin.Context) {
+	h.setVersionLocked(c, true)
+}
+
+// UnlockPackageVersion 解除版本锁定（默认仅super角色），记录package.version_unlock审计日志
+func (h *Handler) UnlockPackageVersion(c *gin.Context) {
+	h.setVersionLocked(c, false)
+}
+
+// setVersionLocked 修改版本锁定状态，状态发生变化时记录审计日志
+func (h *Handler) setVersionLocked(c *gin.Context, locked bool) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	packageName, version := c.Param("package"), c.Param("version")
+	pkgVersion, changed, err := h.packageService.SetVersionLocked(c.Request.Context(), packageName, version, locked, h.packageCaller(c, packageName))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to update version lock")
+		return
+	}
+
+	if changed {
+		action := "package.version_unlock"
+		if locked {
+			action = "package.version_lock"
+		}
+		resource := "packages/" + packageName + "/" + version
+		if err := h.auditService.Record(c.Request.Context(), userID, action, resource, gin.H{"locked": locked}, c.ClientIP()); err != nil {
+			logger.Warnf("Failed to audit version lock change: %v", err)
+		}
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"package":   packageName,
+		"version":   pkgVersion.Version,
+		"locked":    pkgVersion.Locked,
+		"locked_at": pkgVersion.LockedAt,
+	})
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"webservice/internal/middleware"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SatisfyVersion 返回满足constraint的最高版本，include_prerelease=true时预发布版本也参与匹配
+func (h *PackageHandler) SatisfyVersion(c *gin.Context) {
+	constraint := strings.TrimSpace(c.Query("constraint"))
+	if constraint == "" {
+		middleware.ValidationErrorResponse(c, "constraint is required")
+		return
+	}
+	includePrerelease := false
+	if raw := c.Query("include_prerelease"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			middleware.ValidationErrorResponse(c, "include_prerelease must be a boolean")
+			return
+		}
+		includePrerelease = parsed
+	}
+
+	packageName, ok := h.resolvePackageAlias(c, c.Param("package"))
+	if !ok {
+		return
+	}
+
+	version, err := h.packageService.MatchVersion(c.Request.Context(), packageName, constraint, includePrerelease, optionalUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidConstraint):
+			middleware.ValidationErrorResponse(c, err.Error())
+		case errors.Is(err, service.ErrNoMatchingVersion):
+			middleware.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		case strings.Contains(err.Error(), "access denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to match version")
+		}
+		return
+	}
+
+	middleware.SuccessResponse(c, version)
+}
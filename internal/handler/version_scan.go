@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVersionScanReport 获取版本的病毒扫描状态和扫描记录，仅包所有者、maintainer协作者和管理员（package.moderate）可以查看
+func (h *Handler) GetVersionScanReport(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+	packageName := c.Param("package")
+	caller := h.packageCaller(c, packageName)
+	caller.UserID = &userID
+	report, err := h.packageService.GetVersionScanReport(c.Request.Context(), packageName, c.Param("version"), caller)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Only package maintainers or an administrator can view scan results")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to get scan results")
+		return
+	}
+
+	middleware.SuccessResponse(c, report)
+}
+
+// RescanPackageVersion 立即重新扫描版本并返回结果，记录package.version_rescan审计日志
+// 发现病毒时隔离版本；未发现病毒不会解除已有的隔离，需要调用解除隔离接口
+func (h *Handler) RescanPackageVersion(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	packageName, version := c.Param("package"), c.Param("version")
+	result, pkgVersion, err := h.packageService.RescanVersion(c.Request.Context(), packageName, version, h.packageCaller(c, packageName))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			middleware.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+		if errors.Is(err, service.ErrScanFailed) {
+			logger.Warnf("Rescan of %s@%s failed: %v", packageName, version, err)
+			middleware.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to rescan package version")
+		return
+	}
+
+	resource := "packages/" + packageName + "/" + version
+	details := gin.H{"engine": result.Engine, "verdict": result.Verdict, "signature": result.Signature, "scan_status": pkgVersion.ScanStatus}
+	if err := h.auditService.Record(c.Request.Context(), userID, "package.version_rescan", resource, details, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit version rescan: %v", err)
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"package":     packageName,
+		"version":     pkgVersion.Version,
+		"scan_status": pkgVersion.ScanStatus,
+		"result":      result,
+	})
+}
+
+// ReleaseQuarantinedVersion 解除版本隔离（默认admin和super可用），必须提供原因，记录package.version_release审计日志
+func (h *Handler) ReleaseQuarantinedVersion(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.ReleaseQuarantineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		middleware.ValidationErrorResponse(c, "reason is required")
+		return
+	}
+
+	packageName, version := c.Param("package"), c.Param("version")
+	pkgVersion, err := h.packageService.ReleaseQuarantinedVersion(c.Request.Context(), packageName, version)
+	if err != nil {
+		if errors.Is(err, service.ErrVersionNotQuarantined) {
+			middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package version not found")
+			return
+		}
+		middleware.InternalServerErrorResponse(c, "Failed to release package version")
+		return
+	}
+
+	resource := "packages/" + packageName + "/" + version
+	if err := h.auditService.Record(c.Request.Context(), userID, "package.version_release", resource, gin.H{"reason": req.Reason}, c.ClientIP()); err != nil {
+		logger.Warnf("Failed to audit quarantine release: %v", err)
+	}
+
+	middleware.SuccessResponse(c, gin.H{
+		"package":     packageName,
+		"version":     pkgVersion.Version,
+		"scan_status": pkgVersion.ScanStatus,
+	})
+}
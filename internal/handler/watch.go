@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WatchPackage 关注包，包发布新版本时收到通知；重复关注不报错
+func (h *Handler) WatchPackage(c *gin.Context) {
+	h.setPackageWatched(c, true)
+}
+
+// UnwatchPackage 取消关注包；未关注时不报错
+func (h *Handler) UnwatchPackage(c *gin.Context) {
+	h.setPackageWatched(c, false)
+}
+
+// setPackageWatched 切换当前用户对包的关注状态，返回最新的关注数
+func (h *Handler) setPackageWatched(c *gin.Context, watch bool) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	packageName, ok := h.PackageHandler.resolvePackageAlias(c, c.Param("package"))
+	if !ok {
+		return
+	}
+
+	var (
+		status *models.WatchStatus
+		err    error
+	)
+	if watch {
+		status, err = h.watchService.Watch(c.Request.Context(), packageName, userID)
+	} else {
+		status, err = h.watchService.Unwatch(c.Request.Context(), packageName, userID)
+	}
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+		case strings.Contains(err.Error(), "access denied"):
+			middleware.ErrorResponse(c, http.StatusForbidden, "Access denied")
+		default:
+			middleware.InternalServerErrorResponse(c, "Failed to update package watch")
+		}
+		return
+	}
+
+	middleware.SuccessResponse(c, status)
+}
+
+// GetWatchedPackages 分页获取当前用户关注的包及各包的最新版本
+func (h *Handler) GetWatchedPackages(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	page, pageSize := parsePage(c, models.DefaultPageSize, models.MaxPageSize)
+	list, err := h.watchService.ListWatching(c.Request.Context(), userID, page, pageSize)
+	if err != nil {
+		middleware.InternalServerErrorResponse(c, "Failed to get watched packages")
+		return
+	}
+
+	middleware.SuccessResponse(c, list)
+}
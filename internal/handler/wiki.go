@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+	"webservice/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWikiPages 列出包的文档页面（不含内容）
+func (h *Handler) ListWikiPages(c *gin.Context) {
+	packageName, ok := h.PackageHandler.resolvePackageAlias(c, c.Param("package"))
+	if !ok {
+		return
+	}
+
+	pages, err := h.wikiService.ListPages(c.Request.Context(), packageName, optionalUserID(c))
+	if err != nil {
+		respondWikiError(c, err, "Failed to list wiki pages")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"pages": pages})
+}
+
+// GetWikiPage 获取文档页面
+func (h *Handler) GetWikiPage(c *gin.Context) {
+	packageName, ok := h.PackageHandler.resolvePackageAlias(c, c.Param("package"))
+	if !ok {
+		return
+	}
+
+	page, err := h.wikiService.GetPage(c.Request.Context(), packageName, c.Param("slug"), optionalUserID(c))
+	if err != nil {
+		respondWikiError(c, err, "Failed to get wiki page")
+		return
+	}
+
+	middleware.SuccessResponse(c, page)
+}
+
+// GetWikiPageRevisions 获取文档页面的修改历史，最新的在前
+func (h *Handler) GetWikiPageRevisions(c *gin.Context) {
+	packageName, ok := h.PackageHandler.resolvePackageAlias(c, c.Param("package"))
+	if !ok {
+		return
+	}
+
+	revisions, err := h.wikiService.GetRevisions(c.Request.Context(), packageName, c.Param("slug"), optionalUserID(c))
+	if err != nil {
+		respondWikiError(c, err, "Failed to get wiki page revisions")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"revisions": revisions})
+}
+
+// CreateWikiPage 创建文档页面（包所有者）
+func (h *Handler) CreateWikiPage(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.CreateWikiPageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	page, err := h.wikiService.CreatePage(c.Request.Context(), c.Param("package"), userID, &req)
+	if err != nil {
+		if respondPackageArchived(c, err) {
+			return
+		}
+		respondWikiError(c, err, "Failed to create wiki page")
+		return
+	}
+
+	middleware.SuccessResponse(c, page)
+}
+
+// UpdateWikiPage 修改文档页面（包所有者），携带if_revision时使用乐观锁，修订号不一致返回409
+func (h *Handler) UpdateWikiPage(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	var req models.UpdateWikiPageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	page, err := h.wikiService.UpdatePage(c.Request.Context(), c.Param("package"), c.Param("slug"), userID, &req)
+	if err != nil {
+		if respondStaleUpdate(c, err) || respondPackageArchived(c, err) {
+			return
+		}
+		respondWikiError(c, err, "Failed to update wiki page")
+		return
+	}
+
+	middleware.SuccessResponse(c, page)
+}
+
+// DeleteWikiPage 删除文档页面及其修改历史（包所有者）
+func (h *Handler) DeleteWikiPage(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		middleware.UnauthorizedResponse(c, "User not found")
+		return
+	}
+
+	if err := h.wikiService.DeletePage(c.Request.Context(), c.Param("package"), c.Param("slug"), userID); err != nil {
+		if respondPackageArchived(c, err) {
+			return
+		}
+		respondWikiError(c, err, "Failed to delete wiki page")
+		return
+	}
+
+	middleware.SuccessResponse(c, gin.H{"message": "Wiki page deleted successfully"})
+}
+
+// optionalUserID 获取可选认证的当前用户ID，未登录时返回nil
+func optionalUserID(c *gin.Context) *uint {
+	if userID, exists := middleware.GetUserIDFromContext(c); exists {
+		return &userID
+	}
+	return nil
+}
+
+// respondWikiError 文档页面接口的错误响应，message为未识别错误时的提示
+func respondWikiError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWikiSlug):
+		middleware.ValidationErrorResponse(c, err.Error())
+	case errors.Is(err, service.ErrWikiPageExists):
+		middleware.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrWikiPageNotFound):
+		middleware.ErrorResponse(c, http.StatusNotFound, "Wiki page not found")
+	case strings.Contains(err.Error(), "not found"):
+		middleware.ErrorResponse(c, http.StatusNotFound, "Package not found")
+	case strings.Contains(err.Error(), "access denied"), strings.Contains(err.Error(), "permission denied"):
+		middleware.ErrorResponse(c, http.StatusForbidden, "Permission denied")
+	default:
+		middleware.InternalServerErrorResponse(c, message)
+	}
+}
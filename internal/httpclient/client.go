@@ -0,0 +1,211 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	"webservice/internal/config"
+)
+
+// 未配置时使用的默认值
+const (
+	defaultDialTimeout           = 5 * time.Second
+	defaultTLSHandshakeTimeout   = 5 * time.Second
+	defaultResponseHeaderTimeout = 10 * time.Second
+	defaultRequestTimeout        = 30 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxIdleConns          = 100
+	defaultMaxIdleConnsPerHost   = 10
+)
+
+// ErrDestinationNotAllowed 连接目标IP被拒绝（SSRF防护）
+var ErrDestinationNotAllowed = errors.New("outbound destination is not allowed")
+
+// IPFilter 在建立连接前检查DNS解析后的目标IP，返回错误时拒绝连接
+// 检查发生在拨号阶段，即使域名在校验后被重新解析到内网地址（DNS rebinding）也会被拦截
+type IPFilter func(ip net.IP) error
+
+// Factory 出站HTTP客户端工厂
+// 所有访问外部服务的功能（Webhook、OAuth、上游代理、CDN预热等）都应通过它创建客户端，
+// 以统一使用出口代理、超时、CA证书和SSRF防护，并记录按目标主机的指标
+type Factory struct {
+	transport *http.Transport
+	timeout   time.Duration
+}
+
+// NewFactory 根据配置创建客户端工厂，filter为nil时按配置决定是否拦截内网地址
+func NewFactory(cfg config.OutboundHTTPConfig, filter IPFilter) (*Factory, error) {
+	if filter == nil && cfg.BlockPrivateNetworks {
+		filter = BlockPrivateNetworks
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   durationOr(cfg.DialTimeout, defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+	// 代理地址不经过IP过滤（出口代理通常位于内网），此时目标地址的检查由代理负责
+	proxyDialer := *dialer
+	if filter != nil {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("%w: unresolved address %s", ErrDestinationNotAllowed, address)
+			}
+			return filter(ip)
+		}
+	}
+
+	// 未配置proxy_url时直连，不读取HTTP_PROXY等环境变量
+	var proxyAddr string
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if proxyAddr != "" && addr == proxyAddr {
+				return proxyDialer.DialContext(ctx, network, addr)
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   durationOr(cfg.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: durationOr(cfg.ResponseHeaderTimeout, defaultResponseHeaderTimeout),
+		IdleConnTimeout:       durationOr(cfg.IdleConnTimeout, defaultIdleConnTimeout),
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          intOr(cfg.MaxIdleConns, defaultMaxIdleConns),
+		MaxIdleConnsPerHost:   intOr(cfg.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+	}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid outbound proxy url %q", cfg.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		proxyAddr = hostPort(proxyURL)
+	}
+
+	if cfg.CABundle != "" {
+		pool, err := loadCABundle(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &Factory{
+		transport: transport,
+		timeout:   durationOr(cfg.Timeout, defaultRequestTimeout),
+	}, nil
+}
+
+// Option 单个调用点对客户端的覆盖配置
+type Option func(*clientOptions)
+
+// clientOptions 客户端覆盖配置
+type clientOptions struct {
+	timeout               time.Duration
+	responseHeaderTimeout time.Duration
+}
+
+// WithTimeout 覆盖请求总超时（含读取响应体），0表示不限制，适用于大文件传输
+func WithTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = d
+	}
+}
+
+// WithResponseHeaderTimeout 覆盖等待响应头的超时，适用于上游处理较慢的调用
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.responseHeaderTimeout = d
+	}
+}
+
+// Client 创建HTTP客户端
+// 默认共享同一个连接池；覆盖响应头超时时会复制一份独立的Transport
+func (f *Factory) Client(opts ...Option) *http.Client {
+	o := clientOptions{timeout: f.timeout, responseHeaderTimeout: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	transport := f.transport
+	if o.responseHeaderTimeout >= 0 {
+		transport = f.transport.Clone()
+		transport.ResponseHeaderTimeout = o.responseHeaderTimeout
+	}
+
+	return &http.Client{
+		Transport: &instrumentedTransport{next: transport},
+		Timeout:   o.timeout,
+	}
+}
+
+// CloseIdleConnections 关闭空闲连接，服务退出时调用
+func (f *Factory) CloseIdleConnections() {
+	f.transport.CloseIdleConnections()
+}
+
+// BlockPrivateNetworks 拒绝回环、私有、链路本地、组播及未指定地址
+func BlockPrivateNetworks(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrDestinationNotAllowed, ip)
+	}
+	return nil
+}
+
+// loadCABundle 加载PEM格式的CA证书，追加到系统根证书之后
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// hostPort 返回URL的host:port，未指定端口时按协议补全
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// durationOr 未配置时返回默认值
+func durationOr(v, def time.Duration) time.Duration {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// intOr 未配置时返回默认值
+func intOr(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
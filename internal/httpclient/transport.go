@@ -0,0 +1,49 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"webservice/internal/metrics"
+)
+
+var (
+	outboundRequests = metrics.NewCounterVec(
+		"outbound_http_requests_total",
+		"Total number of outbound HTTP requests by destination host and status code.",
+		"host", "code",
+	)
+	outboundErrors = metrics.NewCounterVec(
+		"outbound_http_errors_total",
+		"Total number of outbound HTTP requests that failed without a response, by destination host.",
+		"host",
+	)
+	outboundDuration = metrics.NewHistogramVec(
+		"outbound_http_request_duration_seconds",
+		"Time until outbound HTTP response headers are received, by destination host.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		"host",
+	)
+)
+
+// instrumentedTransport 记录按目标主机的请求数、失败数和耗时
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip 执行请求并记录指标
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	start := time.Now()
+
+	resp, err := t.next.RoundTrip(req)
+	outboundDuration.Observe(time.Since(start).Seconds(), host)
+	if err != nil {
+		outboundErrors.Inc(host)
+		return nil, err
+	}
+
+	outboundRequests.Inc(host, strconv.Itoa(resp.StatusCode))
+	return resp, nil
+}
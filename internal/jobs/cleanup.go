@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// SessionCleanupJob 清理已过期的用户会话记录，并解除已到期的用户暂停
+type SessionCleanupJob struct {
+	sessionService *service.SessionService
+	userService    *service.UserService
+}
+
+// NewSessionCleanupJob 创建会话清理任务
+func NewSessionCleanupJob(sessionService *service.SessionService, userService *service.UserService) *SessionCleanupJob {
+	return &SessionCleanupJob{sessionService: sessionService, userService: userService}
+}
+
+// Name 任务名称
+func (j *SessionCleanupJob) Name() string {
+	return "session_cleanup"
+}
+
+// Run 删除过期的会话，解除已到期的暂停
+func (j *SessionCleanupJob) Run(ctx context.Context) error {
+	deleted, err := j.sessionService.CleanupExpiredSessions()
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logger.Infof("Cleaned up %d expired sessions", deleted)
+	}
+
+	lifted, err := j.userService.LiftExpiredSuspensions(ctx)
+	if err != nil {
+		return err
+	}
+	if lifted > 0 {
+		logger.Infof("Lifted %d expired user suspensions", lifted)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/feature"
+)
+
+// FeatureFlagRefreshJob 定期从数据库重新加载功能开关，使其他实例上的修改生效
+type FeatureFlagRefreshJob struct {
+	flags *feature.Flags
+}
+
+// NewFeatureFlagRefreshJob 创建功能开关刷新任务
+func NewFeatureFlagRefreshJob(flags *feature.Flags) *FeatureFlagRefreshJob {
+	return &FeatureFlagRefreshJob{flags: flags}
+}
+
+// Name 任务名称
+func (j *FeatureFlagRefreshJob) Name() string {
+	return "feature_flag_refresh"
+}
+
+// Run 重新加载一次功能开关
+func (j *FeatureFlagRefreshJob) Run(ctx context.Context) error {
+	return j.flags.Load(ctx)
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// HealthSampleJob 定期检查数据库和存储，记录可用性历史
+type HealthSampleJob struct {
+	history *service.HealthHistoryService
+	healthy *bool // 上一次检查的整体状态，状态变化时记录日志
+}
+
+// NewHealthSampleJob 创建依赖检查任务
+func NewHealthSampleJob(history *service.HealthHistoryService) *HealthSampleJob {
+	return &HealthSampleJob{history: history}
+}
+
+// Name 任务名称
+func (j *HealthSampleJob) Name() string {
+	return "health_sample"
+}
+
+// Run 检查一次依赖并记录结果
+func (j *HealthSampleJob) Run(ctx context.Context) error {
+	check, err := j.history.Sample(ctx)
+	if j.healthy != nil && *j.healthy != check.Healthy {
+		if check.Healthy {
+			logger.Infof("Dependencies recovered")
+		} else {
+			logger.Warnf("Dependency check failed (database up: %t)", check.DatabaseUp)
+		}
+	}
+	j.healthy = &check.Healthy
+	return err
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// IntegrityJob 抽样重新计算存储对象的SHA256，发现静默损坏
+type IntegrityJob struct {
+	integrityService *service.IntegrityService
+}
+
+// NewIntegrityJob 创建存储完整性校验任务
+func NewIntegrityJob(integrityService *service.IntegrityService) *IntegrityJob {
+	return &IntegrityJob{integrityService: integrityService}
+}
+
+// Name 任务名称
+func (j *IntegrityJob) Name() string {
+	return "storage_integrity"
+}
+
+// Run 执行一次完整性校验
+func (j *IntegrityJob) Run(ctx context.Context) error {
+	result, err := j.integrityService.VerifySample(ctx)
+	if err != nil {
+		return err
+	}
+	if result.Skipped {
+		logger.Debug("Storage integrity check is paused")
+		return nil
+	}
+	logger.Infof("Storage integrity check finished: %d verified, %d corrupted, %d failed, %d bytes read",
+		result.Checked, result.Corrupted, result.Failed, result.Bytes)
+	return nil
+}
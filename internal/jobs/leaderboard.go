@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// LeaderboardJob 根据下载量重新计算包排行榜（周榜、月榜、累计榜）并发布到Redis，每小时执行一次
+type LeaderboardJob struct {
+	leaderboards *service.LeaderboardService
+}
+
+// NewLeaderboardJob 创建排行榜计算任务
+func NewLeaderboardJob(leaderboards *service.LeaderboardService) *LeaderboardJob {
+	return &LeaderboardJob{leaderboards: leaderboards}
+}
+
+// Name 任务名称
+func (j *LeaderboardJob) Name() string {
+	return "package_leaderboard"
+}
+
+// Run 执行一次排行榜计算
+func (j *LeaderboardJob) Run(ctx context.Context) error {
+	count, err := j.leaderboards.ComputeLeaderboards(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Package leaderboards computed: %d entries", count)
+	return nil
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/minio"
+)
+
+// MirrorHealthJob 检查下载镜像的可用性并更新熔断状态
+type MirrorHealthJob struct {
+	mirrors *minio.MirrorSelector
+}
+
+// NewMirrorHealthJob 创建镜像健康检查任务
+func NewMirrorHealthJob(mirrors *minio.MirrorSelector) *MirrorHealthJob {
+	return &MirrorHealthJob{mirrors: mirrors}
+}
+
+// Name 任务名称
+func (j *MirrorHealthJob) Name() string {
+	return "mirror_health"
+}
+
+// Run 执行一次镜像健康检查
+func (j *MirrorHealthJob) Run(ctx context.Context) error {
+	j.mirrors.CheckHealth(ctx)
+	return nil
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// PrereleaseExpiryJob 删除超过保留期的预发布版本（置顶版本和包设置的保留版本除外）
+type PrereleaseExpiryJob struct {
+	packageService *service.PackageService
+	maxAge         time.Duration
+}
+
+// NewPrereleaseExpiryJob 创建预发布版本过期任务
+func NewPrereleaseExpiryJob(packageService *service.PackageService, maxAge time.Duration) *PrereleaseExpiryJob {
+	return &PrereleaseExpiryJob{
+		packageService: packageService,
+		maxAge:         maxAge,
+	}
+}
+
+// Name 任务名称
+func (j *PrereleaseExpiryJob) Name() string {
+	return "prerelease_expiry"
+}
+
+// Run 执行一次预发布版本过期清理
+func (j *PrereleaseExpiryJob) Run(ctx context.Context) error {
+	deleted, err := j.packageService.ExpirePrereleases(ctx, j.maxAge)
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		logger.Infof("Expired %d prerelease versions", deleted)
+	}
+	return nil
+}
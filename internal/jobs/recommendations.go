@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// RecommendationJob 根据最近的共同下载重新计算包推荐，每天执行一次
+type RecommendationJob struct {
+	packageService *service.PackageService
+}
+
+// NewRecommendationJob 创建包推荐计算任务
+func NewRecommendationJob(packageService *service.PackageService) *RecommendationJob {
+	return &RecommendationJob{packageService: packageService}
+}
+
+// Name 任务名称
+func (j *RecommendationJob) Name() string {
+	return "package_recommendations"
+}
+
+// Run 执行一次推荐计算
+func (j *RecommendationJob) Run(ctx context.Context) error {
+	count, err := j.packageService.ComputeRecommendations(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Package recommendations computed: %d entries", count)
+	return nil
+}
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"webservice/internal/logger"
+)
+
+// Job 后台定时任务接口
+type Job interface {
+	// Name 任务名称，用于日志
+	Name() string
+	// Run 执行一次任务
+	Run(ctx context.Context) error
+}
+
+// scheduledJob 已注册的任务及其执行间隔
+type scheduledJob struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler 后台任务调度器，每个任务在独立的goroutine中按固定间隔执行
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []scheduledJob
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewScheduler 创建任务调度器
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Register 注册任务，需在Start之前调用
+func (s *Scheduler) Register(job Job, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if interval <= 0 {
+		logger.Warnf("Job %s has non-positive interval, skipped", job.Name())
+		return
+	}
+	s.jobs = append(s.jobs, scheduledJob{job: job, interval: interval})
+}
+
+// Start 启动所有已注册的任务
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.running = true
+
+	for _, sj := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, sj)
+	}
+	logger.Infof("Job scheduler started with %d jobs", len(s.jobs))
+}
+
+// Stop 停止调度器并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.cancel()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	logger.Info("Job scheduler stopped")
+}
+
+// loop 按间隔执行单个任务
+func (s *Scheduler) loop(ctx context.Context, sj scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(sj.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, sj.job)
+		}
+	}
+}
+
+// runOnce 执行一次任务，捕获panic避免影响其他任务
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("Job %s panicked: %v", job.Name(), r)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		logger.Errorf("Job %s failed: %v", job.Name(), err)
+		return
+	}
+	logger.Debugf("Job %s finished in %s", job.Name(), time.Since(start))
+}
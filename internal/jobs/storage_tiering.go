@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// StorageTieringJob 按下载频率在存储层级之间迁移包文件，并将长期未下载的旧版本移入冷存储，每天执行一次
+type StorageTieringJob struct {
+	tieringService *service.StorageTieringService
+}
+
+// NewStorageTieringJob 创建存储分层任务
+func NewStorageTieringJob(tieringService *service.StorageTieringService) *StorageTieringJob {
+	return &StorageTieringJob{tieringService: tieringService}
+}
+
+// Name 任务名称
+func (j *StorageTieringJob) Name() string {
+	return "storage_tiering"
+}
+
+// Run 执行一次存储分层
+func (j *StorageTieringJob) Run(ctx context.Context) error {
+	result, err := j.tieringService.ApplyTiering(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Storage tiering finished: %d promoted, %d demoted, %d failed",
+		result.Promoted, result.Demoted, result.Failed)
+
+	cold, err := j.tieringService.ApplyColdTiering(ctx)
+	if err != nil {
+		return err
+	}
+	if cold.Demoted > 0 || cold.Restored > 0 || cold.Failed > 0 {
+		logger.Infof("Cold storage tiering finished: %d moved to cold, %d restored, %d failed",
+			cold.Demoted, cold.Restored, cold.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// UploadSessionCleanupJob 清理过期未完成和处理中断的预签名上传会话及其暂存对象，删除已结束较久的会话
+type UploadSessionCleanupJob struct {
+	packageService *service.PackageService
+}
+
+// NewUploadSessionCleanupJob 创建上传会话清理任务
+func NewUploadSessionCleanupJob(packageService *service.PackageService) *UploadSessionCleanupJob {
+	return &UploadSessionCleanupJob{packageService: packageService}
+}
+
+// Name 任务名称
+func (j *UploadSessionCleanupJob) Name() string {
+	return "upload_session_cleanup"
+}
+
+// Run 执行一次上传会话清理
+func (j *UploadSessionCleanupJob) Run(ctx context.Context) error {
+	failed, err := j.packageService.CleanupUploadSessions(ctx)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		logger.Infof("Cleaned up %d expired or interrupted upload sessions", failed)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// VersionLockJob 锁定发布超过lock_after_minutes的版本，锁定后不能删除
+type VersionLockJob struct {
+	packageService *service.PackageService
+	lockAfter      time.Duration
+}
+
+// NewVersionLockJob 创建版本锁定任务
+func NewVersionLockJob(packageService *service.PackageService, lockAfter time.Duration) *VersionLockJob {
+	return &VersionLockJob{
+		packageService: packageService,
+		lockAfter:      lockAfter,
+	}
+}
+
+// Name 任务名称
+func (j *VersionLockJob) Name() string {
+	return "version_lock"
+}
+
+// Run 执行一次版本锁定
+func (j *VersionLockJob) Run(ctx context.Context) error {
+	locked, err := j.packageService.LockPublishedVersions(ctx, j.lockAfter)
+	if err != nil {
+		return err
+	}
+	if locked > 0 {
+		logger.Infof("Locked %d published versions", locked)
+	}
+	return nil
+}
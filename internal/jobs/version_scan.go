@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+
+	"webservice/internal/logger"
+	"webservice/internal/service"
+)
+
+// VersionScanJob 重新扫描停留在scanning状态的版本（扫描期间进程退出、扫描引擎暂时不可用等）
+type VersionScanJob struct {
+	packages *service.PackageService
+}
+
+// NewVersionScanJob 创建版本重新扫描任务
+func NewVersionScanJob(packages *service.PackageService) *VersionScanJob {
+	return &VersionScanJob{packages: packages}
+}
+
+// Name 任务名称
+func (j *VersionScanJob) Name() string {
+	return "version_scan_retry"
+}
+
+// Run 执行一次重新扫描
+func (j *VersionScanJob) Run(ctx context.Context) error {
+	count, err := j.packages.RescanStaleVersions(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		logger.Infof("Rescanned %d versions pending malware scan", count)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package license
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed spdx.txt
+var spdxList string
+
+// maxSuggestions 校验失败时最多返回的相近标识符数量
+const maxSuggestions = 3
+
+var (
+	// ErrUnknownLicense 不是SPDX标识符或配置的自定义标识符
+	ErrUnknownLicense = errors.New("unknown license identifier")
+	// ErrLicenseNotAllowed 标识符合法但不在允许列表中
+	ErrLicenseNotAllowed = errors.New("license is not allowed")
+)
+
+// aliases 常见的非标准写法，只收录含义明确的写法；"GPL"、"BSD"等无法确定版本的写法不做转换
+// SPDX已弃用的GPL系列标识符（如GPL-3.0、GPL-3.0+）按SPDX规定映射为-only/-or-later
+var aliases = map[string]string{
+	"apache 2":                       "Apache-2.0",
+	"apache 2.0":                     "Apache-2.0",
+	"apache-2":                       "Apache-2.0",
+	"apache2":                        "Apache-2.0",
+	"apache license 2.0":             "Apache-2.0",
+	"apache license, version 2.0":    "Apache-2.0",
+	"apache software license 2.0":    "Apache-2.0",
+	"mit license":                    "MIT",
+	"the mit license":                "MIT",
+	"isc license":                    "ISC",
+	"bsd 2-clause":                   "BSD-2-Clause",
+	"simplified bsd":                 "BSD-2-Clause",
+	"bsd 3-clause":                   "BSD-3-Clause",
+	"new bsd":                        "BSD-3-Clause",
+	"modified bsd":                   "BSD-3-Clause",
+	"mpl 2.0":                        "MPL-2.0",
+	"mozilla public license 2.0":     "MPL-2.0",
+	"cc0":                            "CC0-1.0",
+	"boost software license 1.0":     "BSL-1.0",
+	"the unlicense":                  "Unlicense",
+	"gpl-2.0":                        "GPL-2.0-only",
+	"gpl-2.0+":                       "GPL-2.0-or-later",
+	"gpl-3.0":                        "GPL-3.0-only",
+	"gpl-3.0+":                       "GPL-3.0-or-later",
+	"lgpl-2.1":                       "LGPL-2.1-only",
+	"lgpl-2.1+":                      "LGPL-2.1-or-later",
+	"lgpl-3.0":                       "LGPL-3.0-only",
+	"lgpl-3.0+":                      "LGPL-3.0-or-later",
+	"agpl-3.0":                       "AGPL-3.0-only",
+	"agpl-3.0+":                      "AGPL-3.0-or-later",
+	"eclipse public license 2.0":     "EPL-2.0",
+	"european union public license":  "EUPL-1.2",
+	"mulan permissive software v2":   "MulanPSL-2.0",
+	"zlib license":                   "Zlib",
+	"python software foundation 2.0": "Python-2.0",
+}
+
+// ValidationError 许可证校验失败，附带相近的合法标识符供客户端提示
+type ValidationError struct {
+	Value       string
+	Suggestions []string
+	err         error
+}
+
+// Error 实现error接口
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %q", e.err, e.Value)
+}
+
+// Unwrap 返回ErrUnknownLicense或ErrLicenseNotAllowed
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// Registry 合法许可证标识符集合：内置SPDX列表加配置的自定义标识符，可选限制为允许列表
+type Registry struct {
+	ids     map[string]string   // 小写 -> 规范写法
+	allowed map[string]struct{} // 规范写法，为空表示不限制
+}
+
+// NewRegistry 创建许可证标识符集合
+// custom为额外接受的自定义标识符（如公司内部许可证）；allowed非空时只接受其中的标识符，按规范写法比较
+func NewRegistry(custom, allowed []string) *Registry {
+	r := &Registry{ids: make(map[string]string)}
+	for _, line := range strings.Split(spdxList, "\n") {
+		if id := strings.TrimSpace(line); id != "" && !strings.HasPrefix(id, "#") {
+			r.ids[strings.ToLower(id)] = id
+		}
+	}
+	for _, id := range custom {
+		if id = strings.TrimSpace(id); id != "" {
+			r.ids[strings.ToLower(id)] = id
+		}
+	}
+
+	if len(allowed) > 0 {
+		r.allowed = make(map[string]struct{}, len(allowed))
+		for _, id := range allowed {
+			if canonical, ok := r.Canonical(id); ok {
+				r.allowed[canonical] = struct{}{}
+			}
+		}
+	}
+	return r
+}
+
+// Canonical 返回标识符的规范写法（忽略大小写，并转换含义明确的常见写法），不检查允许列表
+func (r *Registry) Canonical(raw string) (string, bool) {
+	key := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if key == "" {
+		return "", false
+	}
+	if id, ok := r.ids[key]; ok {
+		return id, true
+	}
+	if id, ok := aliases[key]; ok {
+		return id, true
+	}
+	return "", false
+}
+
+// Normalize 校验许可证并返回规范写法，空值表示未声明许可证，原样返回
+// 无法识别或不在允许列表中时返回*ValidationError
+func (r *Registry) Normalize(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+
+	id, ok := r.Canonical(raw)
+	if !ok {
+		return "", &ValidationError{Value: raw, Suggestions: r.suggest(raw, r.candidates()), err: ErrUnknownLicense}
+	}
+	if r.allowed != nil {
+		if _, ok := r.allowed[id]; !ok {
+			return "", &ValidationError{Value: raw, Suggestions: r.suggest(raw, r.allowedList()), err: ErrLicenseNotAllowed}
+		}
+	}
+	return id, nil
+}
+
+// Suggest 返回与raw最相近的合法标识符
+func (r *Registry) Suggest(raw string) []string {
+	return r.suggest(raw, r.candidates())
+}
+
+// candidates 全部合法标识符
+func (r *Registry) candidates() []string {
+	ids := make([]string, 0, len(r.ids))
+	for _, id := range r.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// allowedList 允许列表中的标识符
+func (r *Registry) allowedList() []string {
+	ids := make([]string, 0, len(r.allowed))
+	for id := range r.allowed {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// suggest 按编辑距离选出最相近的候选标识符，距离过大的不返回
+func (r *Registry) suggest(raw string, candidates []string) []string {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	type match struct {
+		id       string
+		distance int
+	}
+	var matches []match
+	for _, id := range candidates {
+		if d := editDistance(key, strings.ToLower(id)); d <= maxDistance {
+			matches = append(matches, match{id: id, distance: d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].id < matches[j].id
+	})
+
+	suggestions := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, matches[i].id)
+	}
+	return suggestions
+}
+
+// editDistance 计算两个字符串的Levenshtein距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"context"
+
+	"webservice/internal/requestid"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithRequestID 将请求ID写入context，数据库日志、出站请求等通过context读取
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return requestid.NewContext(ctx, requestID)
+}
+
+// RequestIDFromContext 从context中读取请求ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	return requestid.FromContext(ctx)
+}
+
+// FromContext 返回带有context中请求ID字段的日志条目
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(log)
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	return entry
+}
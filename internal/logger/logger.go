@@ -11,7 +11,8 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var log *logrus.Logger
+// log 全局日志实例，Init之前（如命令行工具、测试）使用输出到stderr的默认配置
+var log = logrus.New()
 
 // Init 初始化日志系统
 func Init(cfg config.LogConfig) {
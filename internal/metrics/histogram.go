@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Histogram 直方图，按预设的上界统计观测值分布
+type Histogram struct {
+	metricName string
+	help       string
+	buckets    []float64
+
+	mu     sync.Mutex
+	counts []uint64 // 与buckets一一对应，非累计
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram 创建直方图并注册到默认注册表，buckets为各桶的上界
+func NewHistogram(name, help string, buckets ...float64) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &Histogram{
+		metricName: name,
+		help:       help,
+		buckets:    sorted,
+		counts:     make([]uint64, len(sorted)),
+	}
+	return DefaultRegistry.register(h).(*Histogram)
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if value <= upper {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// name 指标名称
+func (h *Histogram) name() string {
+	return h.metricName
+}
+
+// write 输出直方图，桶计数按Prometheus约定累计输出
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", h.metricName, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.metricName)
+
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.metricName, strconv.FormatFloat(upper, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.metricName, h.count)
+	fmt.Fprintf(w, "%s_sum %v\n", h.metricName, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", h.metricName, h.count)
+}
+
+// HistogramVec 带标签的直方图，每组标签值独立统计
+type HistogramVec struct {
+	metricName string
+	help       string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+// histogramSeries 一组标签值对应的直方图数据
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 创建带标签的直方图并注册到默认注册表
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		buckets:    sorted,
+		series:     make(map[string]*histogramSeries),
+	}
+	return DefaultRegistry.register(h).(*HistogramVec)
+}
+
+// Observe 记录指定标签值的一个观测值，标签值顺序与创建时的标签名一致
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", h.metricName, len(h.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// name 指标名称
+func (h *HistogramVec) name() string {
+	return h.metricName
+}
+
+// write 输出各组标签的直方图
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", h.metricName, h.help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.metricName)
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := h.series[key]
+		values := strings.Split(key, "\xff")
+		labelNames := append(append([]string(nil), h.labels...), "le")
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			le := strconv.FormatFloat(upper, 'g', -1, 64)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(labelNames, append(values, le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(labelNames, append(values, "+Inf")), s.count)
+		fmt.Fprintf(w, "%s_sum%s %v\n", h.metricName, formatLabels(h.labels, values), s.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, formatLabels(h.labels, values), s.count)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// collector 可输出为Prometheus文本格式的指标
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// DefaultRegistry 默认注册表，/metrics接口输出其中的全部指标
+var DefaultRegistry = NewRegistry()
+
+// register 注册指标，重名时返回已注册的指标
+func (r *Registry) register(c collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.collectors[c.name()]; ok {
+		return existing
+	}
+	r.collectors[c.name()] = c
+	return c
+}
+
+// WritePrometheus 以Prometheus文本格式输出所有指标
+func (r *Registry) WritePrometheus(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler 返回输出默认注册表的HTTP处理器
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		DefaultRegistry.WritePrometheus(w)
+	})
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	metricName string
+	help       string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec 创建带标签的计数器并注册到默认注册表
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		values:     make(map[string]float64),
+	}
+	return DefaultRegistry.register(c).(*CounterVec)
+}
+
+// Inc 指定标签值的计数加一，标签值顺序与创建时的标签名一致
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 指定标签值的计数增加delta
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", c.metricName, len(c.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+// name 指标名称
+func (c *CounterVec) name() string {
+	return c.metricName
+}
+
+// write 输出计数器
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", c.metricName, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.metricName)
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %v\n", c.metricName, formatLabels(c.labels, strings.Split(key, "\xff")), c.values[key])
+	}
+}
+
+// formatLabels 格式化标签为{name="value",...}
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(values[i])
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -2,13 +2,18 @@ package middleware
 
 import (
 	"errors"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"webservice/internal/authz"
 	"webservice/internal/config"
+	"webservice/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Claims JWT声明结构体
@@ -16,11 +21,29 @@ type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// RefreshCount 该token由登录token连续刷新的次数
+	RefreshCount int `json:"refresh_count"`
+	// Packages 令牌限定可操作的包名，为空表示不限制；刷新后的token沿用该范围
+	Packages []string `json:"packages,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// CodeTokenScopeViolation token限定了包范围且目标包不在其中时返回的业务错误码
+const CodeTokenScopeViolation = 40301
+
+// ScopedTokenRoutePrefix 限定包范围的token只能访问该前缀下的包管理路由，
+// 会话、身份关联、token创建和管理员路由（包括/admin/packages/:package/...）一律拒绝
+const ScopedTokenRoutePrefix = "/api/v1/packages/"
+
+// SessionChecker 会话状态检查接口，用于按设备吊销token
+type SessionChecker interface {
+	// IsSessionActive 根据token的jti返回会话ID及是否有效
+	IsSessionActive(jti string) (uint, bool, error)
+}
+
 // JWTAuth JWT认证中间件
-func JWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
+// sessions不为nil时会校验token对应的会话未被吊销；features不为nil时将对当前用户启用的功能开关写入上下文
+func JWTAuth(cfg config.JWTConfig, sessions SessionChecker, features FeatureResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取token
 		token := getTokenFromHeader(c)
@@ -38,28 +61,53 @@ func JWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
 			return
 		}
 
+		// 检查会话是否已被吊销
+		if err := checkSession(c, claims, sessions); err != nil {
+			UnauthorizedResponse(c, err.Error())
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储到上下文中
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		setClaimsToContext(c, claims)
+		setTokenExpiryHeaders(c, claims, cfg.RefreshWindow)
+		setFeaturesToContext(c, features, claims)
+
+		// 限定包范围的token只能访问包管理路由，且只能访问范围内的包
+		if _, scoped := GetTokenPackagesFromContext(c); scoped {
+			if !strings.HasPrefix(c.FullPath(), ScopedTokenRoutePrefix) {
+				CustomResponse(c, http.StatusForbidden, CodeTokenScopeViolation, "token_scope_violation", gin.H{"route": c.FullPath()})
+				c.Abort()
+				return
+			}
+			if packageName := c.Param("package"); packageName != "" && !TokenAllowsPackage(c, packageName) {
+				TokenScopeViolationResponse(c, packageName)
+				c.Abort()
+				return
+			}
+		}
 
 		c.Next()
 	}
 }
 
+// TokenScopeViolationResponse 目标包不在token的包范围内时返回403
+func TokenScopeViolationResponse(c *gin.Context, packageName string) {
+	CustomResponse(c, http.StatusForbidden, CodeTokenScopeViolation, "token_scope_violation", gin.H{"package": packageName})
+}
+
 // OptionalJWTAuth 可选的JWT认证中间件（不强制要求token）
-func OptionalJWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
+func OptionalJWTAuth(cfg config.JWTConfig, sessions SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取token
 		token := getTokenFromHeader(c)
 		if token != "" {
 			// 如果有token，尝试解析
 			claims, err := parseToken(token, cfg.Secret)
-			if err == nil {
+			if err == nil && checkSession(c, claims, sessions) == nil {
 				// 解析成功，将用户信息存储到上下文中
-				c.Set("user_id", claims.UserID)
-				c.Set("username", claims.Username)
-				c.Set("role", claims.Role)
+				setClaimsToContext(c, claims)
+				setTokenExpiryHeaders(c, claims, cfg.RefreshWindow)
 			}
 		}
 
@@ -67,6 +115,50 @@ func OptionalJWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
 	}
 }
 
+// checkSession 校验token对应的会话状态，未携带jti的旧token不做会话校验
+func checkSession(c *gin.Context, claims *Claims, sessions SessionChecker) error {
+	if sessions == nil || claims.ID == "" {
+		return nil
+	}
+
+	sessionID, active, err := sessions.IsSessionActive(claims.ID)
+	if err != nil {
+		return errors.New("failed to verify session")
+	}
+	if !active {
+		return errors.New("session has been revoked")
+	}
+
+	c.Set("session_id", sessionID)
+	return nil
+}
+
+// setClaimsToContext 将token中的用户信息存储到上下文中
+func setClaimsToContext(c *gin.Context, claims *Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	c.Set("token_id", claims.ID)
+	if len(claims.Packages) > 0 {
+		c.Set("token_packages", claims.Packages)
+	}
+	setRowScope(c, claims)
+}
+
+// setTokenExpiryHeaders 返回token剩余有效秒数，进入刷新窗口后提示客户端刷新，避免等到401才发现过期
+func setTokenExpiryHeaders(c *gin.Context, claims *Claims, refreshWindow time.Duration) {
+	if claims.ExpiresAt == nil {
+		return
+	}
+	c.Set("token_expires_at", claims.ExpiresAt.Time.UTC())
+
+	expiresIn := time.Until(claims.ExpiresAt.Time)
+	c.Header("X-Token-Expires-In", strconv.Itoa(int(expiresIn.Seconds())))
+	if expiresIn < refreshWindow {
+		c.Header("X-Token-Refresh-Recommended", "true")
+	}
+}
+
 // RoleAuth 角色权限中间件
 func RoleAuth(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -98,6 +190,26 @@ func RoleAuth(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission 按授权策略检查当前角色能否执行操作，针对具体资源（如目标用户的角色）的检查由处理器完成
+func RequirePermission(policy *authz.Policy, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			ForbiddenResponse(c, "User role not found")
+			c.Abort()
+			return
+		}
+
+		if !policy.Permits(role, action) {
+			ForbiddenResponse(c, "Insufficient permissions")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // getTokenFromHeader 从请求头中获取token
 func getTokenFromHeader(c *gin.Context) string {
 	// 从Authorization头获取
@@ -135,14 +247,36 @@ func parseToken(tokenString, secret string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username, role string, cfg config.JWTConfig) (string, error) {
+// ParseToken 解析并验证JWT token
+func ParseToken(tokenString string, cfg config.JWTConfig) (*Claims, error) {
+	return parseToken(tokenString, cfg.Secret)
+}
+
+// ErrRefreshLimitReached token的刷新次数已达上限，需要重新登录
+var ErrRefreshLimitReached = errors.New("token refresh limit reached, please log in again")
+
+// GenerateToken 生成JWT token，每个token带有唯一的jti用于会话管理
+func GenerateToken(userID uint, username, role string, cfg config.JWTConfig) (string, *Claims, error) {
+	return generateToken(userID, username, role, nil, 0, cfg)
+}
+
+// GenerateScopedToken 生成只能操作指定包的JWT token，用于CI等自动化发布
+// token的角色固定为普通用户，管理员创建的token也不带管理员权限
+func GenerateScopedToken(userID uint, username string, packages []string, cfg config.JWTConfig) (string, *Claims, error) {
+	return generateToken(userID, username, models.RoleUser, packages, 0, cfg)
+}
+
+// generateToken 生成带包范围和刷新次数的JWT token
+func generateToken(userID uint, username, role string, packages []string, refreshCount int, cfg config.JWTConfig) (string, *Claims, error) {
 	now := time.Now()
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+	claims := &Claims{
+		UserID:       userID,
+		Username:     username,
+		Role:         role,
+		RefreshCount: refreshCount,
+		Packages:     packages,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			Issuer:    cfg.Issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.ExpireTime)),
@@ -151,23 +285,32 @@ func GenerateToken(userID uint, username, role string, cfg config.JWTConfig) (st
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+	signed, err := token.SignedString([]byte(cfg.Secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
-// RefreshToken 刷新JWT token
-func RefreshToken(tokenString string, cfg config.JWTConfig) (string, error) {
+// RefreshToken 刷新JWT token，返回新token及其声明
+func RefreshToken(tokenString string, cfg config.JWTConfig) (string, *Claims, error) {
 	claims, err := parseToken(tokenString, cfg.Secret)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	// 检查token是否即将过期（在过期前RefreshWindow内可以刷新）
+	if time.Until(claims.ExpiresAt.Time) > cfg.RefreshWindow {
+		return "", nil, errors.New("token is not eligible for refresh")
 	}
 
-	// 检查token是否即将过期（在过期前30分钟内可以刷新）
-	if time.Until(claims.ExpiresAt.Time) > 30*time.Minute {
-		return "", errors.New("token is not eligible for refresh")
+	// 限制连续刷新次数，避免token无限续期
+	if cfg.MaxRefreshCount > 0 && claims.RefreshCount >= cfg.MaxRefreshCount {
+		return "", nil, ErrRefreshLimitReached
 	}
 
 	// 生成新token
-	return GenerateToken(claims.UserID, claims.Username, claims.Role, cfg)
+	return generateToken(claims.UserID, claims.Username, claims.Role, claims.Packages, claims.RefreshCount+1, cfg)
 }
 
 // GetUserIDFromContext 从上下文中获取用户ID
@@ -199,3 +342,47 @@ func GetRoleFromContext(c *gin.Context) (string, bool) {
 	r, ok := role.(string)
 	return r, ok
 }
+
+// GetTokenExpiryFromContext 从上下文中获取当前token的过期时间
+func GetTokenExpiryFromContext(c *gin.Context) (time.Time, bool) {
+	expiresAt, exists := c.Get("token_expires_at")
+	if !exists {
+		return time.Time{}, false
+	}
+	t, ok := expiresAt.(time.Time)
+	return t, ok
+}
+
+// GetSessionIDFromContext 从上下文中获取当前会话ID
+func GetSessionIDFromContext(c *gin.Context) (uint, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := sessionID.(uint)
+	return id, ok
+}
+
+// GetTokenPackagesFromContext 从上下文中获取当前token限定的包名，token不限定包时返回false
+func GetTokenPackagesFromContext(c *gin.Context) ([]string, bool) {
+	packages, exists := c.Get("token_packages")
+	if !exists {
+		return nil, false
+	}
+	names, ok := packages.([]string)
+	return names, ok && len(names) > 0
+}
+
+// TokenAllowsPackage 当前token能否操作指定的包，不限定包的token总是返回true
+func TokenAllowsPackage(c *gin.Context, packageName string) bool {
+	packages, scoped := GetTokenPackagesFromContext(c)
+	if !scoped {
+		return true
+	}
+	for _, name := range packages {
+		if name == packageName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestRefreshTokenStopsAtMaxRefreshCount(t *testing.T) {
+	cfg := config.JWTConfig{Secret: "test-secret", ExpireTime: 10 * time.Minute, RefreshWindow: 30 * time.Minute, MaxRefreshCount: 10}
+	token, _, err := GenerateScopedToken(1, "alice", []string{"app"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= cfg.MaxRefreshCount; i++ {
+		var claims *Claims
+		token, claims, err = RefreshToken(token, cfg)
+		if err != nil {
+			t.Fatalf("refresh %d: %v", i, err)
+		}
+		if claims.RefreshCount != i {
+			t.Fatalf("refresh %d: refresh_count = %d", i, claims.RefreshCount)
+		}
+		if len(claims.Packages) != 1 || claims.Packages[0] != "app" || claims.Role != models.RoleUser {
+			t.Fatalf("refresh %d: scope or role not carried over: %+v", i, claims)
+		}
+	}
+
+	if _, _, err := RefreshToken(token, cfg); !errors.Is(err, ErrRefreshLimitReached) {
+		t.Fatalf("refresh %d = %v, want ErrRefreshLimitReached", cfg.MaxRefreshCount+1, err)
+	}
+
+	// 重新登录后计数从0开始
+	fresh, _, err := GenerateToken(1, "alice", models.RoleUser, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, claims, err := RefreshToken(fresh, cfg); err != nil || claims.RefreshCount != 1 {
+		t.Fatalf("refresh after login = %+v, %v, want refresh_count 1", claims, err)
+	}
+}
+
+func TestRefreshTokenRespectsRefreshWindow(t *testing.T) {
+	cfg := config.JWTConfig{Secret: "test-secret", ExpireTime: time.Hour, RefreshWindow: 30 * time.Minute}
+	token, _, err := GenerateToken(1, "alice", models.RoleUser, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := RefreshToken(token, cfg); err == nil {
+		t.Error("token expiring in an hour was refreshed with a 30m window")
+	}
+
+	cfg.RefreshWindow = 2 * time.Hour
+	if _, _, err := RefreshToken(token, cfg); err != nil {
+		t.Errorf("token inside a 2h window: %v", err)
+	}
+
+	// 未设置上限时不限制刷新次数
+	cfg.MaxRefreshCount = 0
+	for i := 0; i < 20; i++ {
+		if token, _, err = RefreshToken(token, cfg); err != nil {
+			t.Fatalf("refresh %d without limit: %v", i+1, err)
+		}
+	}
+}
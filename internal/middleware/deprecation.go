@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// deprecatedRouteRequests 已弃用路由的调用次数
+var deprecatedRouteRequests = metrics.NewCounterVec(
+	"deprecated_route_requests_total",
+	"Total number of requests to deprecated routes.",
+	"method", "route",
+)
+
+// DeprecatedRoute 已弃用路由定义
+type DeprecatedRoute struct {
+	Method      string    // HTTP方法
+	Path        string    // 相对于路由分组的路径，与注册路由时一致
+	Sunset      time.Time // 计划下线时间
+	Replacement string    // 替代接口路径
+}
+
+// DeprecationRecorder 弃用路由调用记录接口，用于统计仍在使用旧接口的客户端
+type DeprecationRecorder interface {
+	RecordDeprecatedUsage(method, route string, userID *uint, tokenID, clientIP, userAgent string) error
+}
+
+// DeprecationRegistry 弃用路由注册表，按路由分组在代码中声明，随路由一起版本化
+type DeprecationRegistry struct {
+	routes   map[string]DeprecatedRoute
+	recorder DeprecationRecorder
+}
+
+// NewDeprecationRegistry 创建弃用路由注册表
+func NewDeprecationRegistry(recorder DeprecationRecorder) *DeprecationRegistry {
+	return &DeprecationRegistry{
+		routes:   make(map[string]DeprecatedRoute),
+		recorder: recorder,
+	}
+}
+
+// Deprecate 声明分组下的弃用路由并为分组挂载弃用中间件，需在注册分组路由之前调用
+func (r *DeprecationRegistry) Deprecate(group *gin.RouterGroup, routes ...DeprecatedRoute) {
+	for _, route := range routes {
+		route.Path = joinRoutePath(group.BasePath(), route.Path)
+		r.routes[deprecationKey(route.Method, route.Path)] = route
+	}
+	group.Use(r.Middleware())
+}
+
+// Routes 返回所有已声明的弃用路由
+func (r *DeprecationRegistry) Routes() []DeprecatedRoute {
+	routes := make([]DeprecatedRoute, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// Middleware 为弃用路由添加Deprecation、Sunset和Link响应头并记录调用方
+func (r *DeprecationRegistry) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, ok := r.routes[deprecationKey(c.Request.Method, c.FullPath())]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		c.Header("Deprecation", "true")
+		if !route.Sunset.IsZero() {
+			c.Header("Sunset", route.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if route.Replacement != "" {
+			c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", expandRouteParams(c, route.Replacement)))
+		}
+
+		c.Next()
+
+		// 认证中间件执行后才能拿到用户信息，因此在请求处理完成后记录
+		r.record(c, route)
+	}
+}
+
+// record 记录一次弃用路由调用
+func (r *DeprecationRegistry) record(c *gin.Context, route DeprecatedRoute) {
+	recordDeprecatedUsage(c, r.recorder, route.Method, route.Path, route.Replacement)
+}
+
+// recordDeprecatedUsage 记录弃用接口调用：计数、日志，以及持久化以便找出未迁移的调用方
+func recordDeprecatedUsage(c *gin.Context, recorder DeprecationRecorder, method, route, replacement string) {
+	deprecatedRouteRequests.Inc(method, route)
+
+	var userID *uint
+	if id, ok := GetUserIDFromContext(c); ok {
+		userID = &id
+	}
+	tokenID := c.GetString("token_id")
+
+	logger.WithFields(logrus.Fields{
+		"method":      method,
+		"route":       route,
+		"user_id":     userID,
+		"token_id":    tokenID,
+		"client_ip":   c.ClientIP(),
+		"replacement": replacement,
+	}).Warn("Deprecated route called")
+
+	if recorder == nil {
+		return
+	}
+	if err := recorder.RecordDeprecatedUsage(method, route, userID, tokenID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		logger.Warnf("Failed to record deprecated route usage: %v", err)
+	}
+}
+
+// deprecationRule 解析后的弃用映射规则
+type deprecationRule struct {
+	config.DeprecationRule
+	prefix string    // 前缀匹配时的路径前缀
+	sunset time.Time // 计划移除时间
+}
+
+// matches 判断请求是否命中规则
+func (r *deprecationRule) matches(req *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+	if r.Header != "" && req.Header.Get(r.Header) == "" {
+		return false
+	}
+	if r.prefix != "" {
+		return req.URL.Path == r.prefix || strings.HasPrefix(req.URL.Path, r.prefix+"/")
+	}
+	matched, err := path.Match(r.PathPattern, req.URL.Path)
+	return err == nil && matched
+}
+
+// routeLabel 统计时使用的路由标识
+func (r *deprecationRule) routeLabel() string {
+	if r.Header != "" {
+		return r.PathPattern + " (header " + r.Header + ")"
+	}
+	return r.PathPattern
+}
+
+// DeprecationMiddleware 兼容性检查中间件，请求命中弃用映射时返回弃用警告响应头（RFC 8594）
+func DeprecationMiddleware(rules []config.DeprecationRule, recorder DeprecationRecorder) gin.HandlerFunc {
+	compiled := make([]*deprecationRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.PathPattern == "" {
+			continue
+		}
+		if _, err := path.Match(rule.PathPattern, "/"); err != nil {
+			logger.Warnf("Invalid deprecation path pattern %q, ignored: %v", rule.PathPattern, err)
+			continue
+		}
+
+		dr := &deprecationRule{DeprecationRule: rule}
+		if strings.HasSuffix(rule.PathPattern, "/**") {
+			dr.prefix = strings.TrimSuffix(rule.PathPattern, "/**")
+		}
+		if rule.RemovalDate != "" {
+			sunset, err := time.Parse("2006-01-02", rule.RemovalDate)
+			if err != nil {
+				logger.Warnf("Invalid removal_date %q for %s: %v", rule.RemovalDate, rule.PathPattern, err)
+			}
+			dr.sunset = sunset
+		}
+		compiled = append(compiled, dr)
+	}
+
+	return func(c *gin.Context) {
+		var matched *deprecationRule
+		for _, rule := range compiled {
+			if rule.matches(c.Request) {
+				matched = rule
+				break
+			}
+		}
+		if matched == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("Deprecation", "true")
+		if !matched.sunset.IsZero() {
+			c.Header("Sunset", matched.sunset.UTC().Format(http.TimeFormat))
+		}
+		if matched.Alternative != "" {
+			c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", matched.Alternative))
+		}
+
+		c.Next()
+
+		recordDeprecatedUsage(c, recorder, c.Request.Method, matched.routeLabel(), matched.Alternative)
+	}
+}
+
+// expandRouteParams 将替代路径中的:param占位符替换为当前请求的路径参数
+func expandRouteParams(c *gin.Context, routePath string) string {
+	segments := strings.Split(routePath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			if value := c.Param(segment[1:]); value != "" {
+				segments[i] = value
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// deprecationKey 弃用路由查找键
+func deprecationKey(method, path string) string {
+	return method + " " + path
+}
+
+// joinRoutePath 拼接分组路径与相对路径，与gin计算完整路由的方式一致
+func joinRoutePath(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	finalPath := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"webservice/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeDeprecationRecorder 记录弃用路由调用，供断言使用
+type fakeDeprecationRecorder struct {
+	mu    sync.Mutex
+	calls []recordedUsage
+}
+
+type recordedUsage struct {
+	method, route string
+	userID        *uint
+}
+
+func (r *fakeDeprecationRecorder) RecordDeprecatedUsage(method, route string, userID *uint, tokenID, clientIP, userAgent string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, recordedUsage{method: method, route: route, userID: userID})
+	return nil
+}
+
+// counterValue 从Prometheus输出中读取指定序列的值
+func counterValue(t *testing.T, series string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			return strings.TrimPrefix(line, series+" ")
+		}
+	}
+	return ""
+}
+
+func TestDeprecationRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	recorder := &fakeDeprecationRecorder{}
+	registry := NewDeprecationRegistry(recorder)
+
+	r := gin.New()
+	// 模拟认证中间件设置的用户
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Test-User") != "" {
+			c.Set("user_id", uint(7))
+		}
+	})
+	group := r.Group("/api/v1/registry-test")
+	registry.Deprecate(group, DeprecatedRoute{
+		Method:      http.MethodPut,
+		Path:        "/:package",
+		Sunset:      sunset,
+		Replacement: "/api/v1/packages/:package",
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.PUT("/:package", ok)
+	group.GET("/:package", ok)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/registry-test/demo", nil)
+	req.Header.Set("X-Test-User", "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q, want true", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Fri, 30 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `</api/v1/packages/demo>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+	if len(recorder.calls) != 1 {
+		t.Fatalf("recorded %d calls, want 1", len(recorder.calls))
+	}
+	call := recorder.calls[0]
+	if call.route != "/api/v1/registry-test/:package" || call.method != http.MethodPut || call.userID == nil || *call.userID != 7 {
+		t.Errorf("recorded %+v, want PUT /api/v1/registry-test/:package by user 7", call)
+	}
+	if got := counterValue(t, `deprecated_route_requests_total{method="PUT",route="/api/v1/registry-test/:package"}`); got != "1" {
+		t.Errorf("usage counter = %q, want 1", got)
+	}
+
+	// 同一路径的其他方法未弃用
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/registry-test/demo", nil))
+	if w.Header().Get("Deprecation") != "" || len(recorder.calls) != 1 {
+		t.Error("non-deprecated route was treated as deprecated")
+	}
+}
+
+func TestJoinRoutePath(t *testing.T) {
+	tests := []struct{ base, rel, want string }{
+		{"/api/v1/packages", "/:package", "/api/v1/packages/:package"},
+		{"/api/v1/packages", "", "/api/v1/packages"},
+		{"/api/v1/packages", "/", "/api/v1/packages/"},
+	}
+	for _, tt := range tests {
+		if got := joinRoutePath(tt.base, tt.rel); got != tt.want {
+			t.Errorf("joinRoutePath(%q, %q) = %q, want %q", tt.base, tt.rel, got, tt.want)
+		}
+	}
+}
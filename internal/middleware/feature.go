@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureResolver 返回对用户启用的功能开关，由feature.Flags实现
+type FeatureResolver interface {
+	EnabledFor(userID uint, role string) []string
+}
+
+// setFeaturesToContext 将对当前用户启用的功能开关写入上下文
+func setFeaturesToContext(c *gin.Context, features FeatureResolver, claims *Claims) {
+	if features == nil {
+		return
+	}
+	c.Set("features", features.EnabledFor(claims.UserID, claims.Role))
+}
+
+// GetFeaturesFromContext 从上下文获取对当前用户启用的功能开关，未经过JWTAuth时为空
+func GetFeaturesFromContext(c *gin.Context) []string {
+	features, _ := c.Get("features")
+	names, _ := features.([]string)
+	return names
+}
+
+// FeatureEnabled 功能开关是否对当前用户启用
+func FeatureEnabled(c *gin.Context, name string) bool {
+	return slices.Contains(GetFeaturesFromContext(c), name)
+}
+
+// RequireFeature 功能开关未对当前用户启用时返回404，使灰度中的接口对其他用户不可见；需放在JWTAuth之后
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !FeatureEnabled(c, name) {
+			NotFoundResponse(c, "Route not found")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
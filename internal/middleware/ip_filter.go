@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IP过滤的默认动作
+const (
+	IPFilterDefaultAllow = "allow"
+	IPFilterDefaultDeny  = "deny"
+)
+
+// ipFilterHealthPaths exempt_health开启时不受IP过滤限制的健康检查路径
+var ipFilterHealthPaths = map[string]bool{"/health": true, "/ping": true}
+
+// ipFilter 解析后的IP过滤规则
+type ipFilter struct {
+	allow        []netip.Prefix
+	deny         []netip.Prefix
+	defaultAllow bool
+}
+
+// newIPFilter 按配置解析IP过滤规则，无效的CIDR记录警告后忽略，无效的默认动作按deny处理
+func newIPFilter(cfg config.IPFilterConfig) *ipFilter {
+	f := &ipFilter{
+		allow:        parseIPPrefixes(cfg.Allow, "allow"),
+		deny:         parseIPPrefixes(cfg.Deny, "deny"),
+		defaultAllow: true,
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.DefaultAction)) {
+	case "", IPFilterDefaultAllow:
+	case IPFilterDefaultDeny:
+		f.defaultAllow = false
+	default:
+		logger.Warnf("Invalid ip_filter.default_action %q, using %q", cfg.DefaultAction, IPFilterDefaultDeny)
+		f.defaultAllow = false
+	}
+	return f
+}
+
+// parseIPPrefixes 解析CIDR列表，单个IP按/32（IPv6为/128）处理
+func parseIPPrefixes(entries []string, list string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				logger.Warnf("Ignoring invalid ip_filter.%s entry %q: %v", list, entry, err)
+				continue
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			logger.Warnf("Ignoring invalid ip_filter.%s entry %q: %v", list, entry, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// allowed 判断地址能否访问：命中deny时拒绝，命中allow时允许，都未命中时按默认动作；无法解析的地址一律拒绝
+func (f *ipFilter) allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if containsAddr(f.deny, addr) {
+		return false
+	}
+	if containsAddr(f.allow, addr) {
+		return true
+	}
+	return f.defaultAllow
+}
+
+// containsAddr 地址是否属于任一网段
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPFilter 按客户端IP限制访问，被拒绝的请求返回403
+// 客户端IP取自c.ClientIP()，只有来自信任代理（server.trusted_proxies）的请求才使用X-Forwarded-For等请求头
+func IPFilter(cfg config.IPFilterConfig) gin.HandlerFunc {
+	filter := newIPFilter(cfg)
+	return func(c *gin.Context) {
+		if cfg.ExemptHealth && ipFilterHealthPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+		if !filter.allowed(c.ClientIP()) {
+			ErrorResponse(c, http.StatusForbidden, "Access denied from this network")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webservice/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newIPFilterRouter 创建挂载IP过滤的路由，不信任任何代理
+func newIPFilterRouter(t *testing.T, cfg config.IPFilterConfig) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(IPFilter(cfg))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/health", ok)
+	r.GET("/api/v1/packages", ok)
+	return r
+}
+
+// requestFrom 以指定客户端地址发送请求，返回状态码
+func requestFrom(r *gin.Engine, path, remoteAddr string, header map[string]string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.IPFilterConfig
+		remote string
+		path   string
+		want   int
+	}{
+		{
+			name:   "default deny allows listed network",
+			cfg:    config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny, Allow: []string{"10.0.0.0/8"}},
+			remote: "10.1.2.3:1234", want: http.StatusOK,
+		},
+		{
+			name:   "default deny blocks unlisted address",
+			cfg:    config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny, Allow: []string{"10.0.0.0/8"}},
+			remote: "192.0.2.1:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "deny takes precedence over allow",
+			cfg:    config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny, Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.5"}},
+			remote: "10.0.0.5:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "default allow passes unlisted address",
+			cfg:    config.IPFilterConfig{Deny: []string{"203.0.113.0/24"}},
+			remote: "192.0.2.1:1234", want: http.StatusOK,
+		},
+		{
+			name:   "default allow blocks denied network",
+			cfg:    config.IPFilterConfig{Deny: []string{"203.0.113.0/24"}},
+			remote: "203.0.113.9:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "ipv4-mapped ipv6 matches ipv4 rule",
+			cfg:    config.IPFilterConfig{Deny: []string{"203.0.113.0/24"}},
+			remote: "[::ffff:203.0.113.9]:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "ipv6 network",
+			cfg:    config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny, Allow: []string{"2001:db8::/32"}},
+			remote: "[2001:db8::1]:1234", want: http.StatusOK,
+		},
+		{
+			name:   "invalid default action denies",
+			cfg:    config.IPFilterConfig{DefaultAction: "maybe"},
+			remote: "192.0.2.1:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "health exempt",
+			cfg:    config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny, ExemptHealth: true},
+			remote: "192.0.2.1:1234", path: "/health", want: http.StatusOK,
+		},
+		{
+			name:   "health not exempt by default",
+			cfg:    config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny},
+			remote: "192.0.2.1:1234", path: "/health", want: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.path
+			if path == "" {
+				path = "/api/v1/packages"
+			}
+			if got := requestFrom(newIPFilterRouter(t, tt.cfg), path, tt.remote, nil); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPFilterIgnoresForwardedForFromUntrustedPeer(t *testing.T) {
+	r := newIPFilterRouter(t, config.IPFilterConfig{DefaultAction: IPFilterDefaultDeny, Allow: []string{"10.0.0.0/8"}})
+	got := requestFrom(r, "/api/v1/packages", "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.1"})
+	if got != http.StatusForbidden {
+		t.Errorf("spoofed X-Forwarded-For: status = %d, want 403", got)
+	}
+}
@@ -3,8 +3,10 @@ package middleware
 import (
 	"bytes"
 	"io"
+	"strings"
 	"time"
 
+	"webservice/internal/config"
 	"webservice/internal/logger"
 
 	"github.com/gin-gonic/gin"
@@ -23,9 +25,73 @@ func (w *responseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// routeLogSkip 路由日志级别配置为skip时，成功请求不记录日志
+const routeLogSkip = "skip"
+
+// routeLogLevels 按路由模式解析请求日志级别
+type routeLogLevels struct {
+	exact    map[string]string
+	prefixes []routeLogPrefix
+}
+
+// routeLogPrefix 前缀匹配的路由日志配置
+type routeLogPrefix struct {
+	prefix string
+	level  string
+}
+
+// newRouteLogLevels 根据配置构建路由日志级别表
+func newRouteLogLevels(routes []config.RouteLogConfig) *routeLogLevels {
+	levels := &routeLogLevels{exact: make(map[string]string)}
+	for _, route := range routes {
+		level := strings.ToLower(strings.TrimSpace(route.Level))
+		if level != routeLogSkip {
+			if _, err := logrus.ParseLevel(level); err != nil {
+				logger.Warnf("Invalid log level %q for route %s, ignored", route.Level, route.Path)
+				continue
+			}
+		}
+
+		if strings.HasSuffix(route.Path, "*") {
+			levels.prefixes = append(levels.prefixes, routeLogPrefix{
+				prefix: strings.TrimSuffix(route.Path, "*"),
+				level:  level,
+			})
+			continue
+		}
+		levels.exact[route.Path] = level
+	}
+	return levels
+}
+
+// lookup 返回路由对应的日志级别，精确匹配优先，其次是最长前缀，默认info
+func (l *routeLogLevels) lookup(fullPath string) string {
+	if level, ok := l.exact[fullPath]; ok {
+		return level
+	}
+
+	level, matched := "info", 0
+	for _, p := range l.prefixes {
+		if strings.HasPrefix(fullPath, p.prefix) && len(p.prefix) > matched {
+			level, matched = p.level, len(p.prefix)
+		}
+	}
+	return level
+}
+
 // LoggerMiddleware 日志中间件
-func LoggerMiddleware() gin.HandlerFunc {
+// 成功请求按路由配置的级别记录（或跳过），4xx/5xx始终以warn/error记录
+func LoggerMiddleware(cfg config.LogConfig) gin.HandlerFunc {
+	routeLevels := newRouteLogLevels(cfg.Routes)
+
 	return func(c *gin.Context) {
+		// 未匹配路由时FullPath为空，按实际路径查找
+		routePath := c.FullPath()
+		if routePath == "" {
+			routePath = c.Request.URL.Path
+		}
+		routeLevel := routeLevels.lookup(routePath)
+
 		// 记录开始时间
 		startTime := time.Now()
 
@@ -87,17 +153,28 @@ func LoggerMiddleware() gin.HandlerFunc {
 			fields["user_id"] = userID
 		}
 
-		// 根据状态码选择日志级别
+		// 根据状态码选择日志级别，错误请求不受路由配置影响
 		logEntry := logger.WithFields(fields)
 		switch {
 		case statusCode >= 500:
 			logEntry.Error("Server error")
 		case statusCode >= 400:
 			logEntry.Warn("Client error")
+		case routeLevel == routeLogSkip:
+			return
 		case statusCode >= 300:
-			logEntry.Info("Redirection")
+			logEntry.Log(parseRouteLogLevel(routeLevel), "Redirection")
 		default:
-			logEntry.Info("Request completed")
+			logEntry.Log(parseRouteLogLevel(routeLevel), "Request completed")
 		}
 	}
 }
+
+// parseRouteLogLevel 将配置的级别转换为logrus级别
+func parseRouteLogLevel(level string) logrus.Level {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return logrus.InfoLevel
+	}
+	return parsed
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// captureLogs 捕获测试期间写入全局日志的记录
+func captureLogs(t *testing.T) *test.Hook {
+	t.Helper()
+	log := logger.GetLogger()
+	level, out, hooks := log.Level, log.Out, log.Hooks
+	hook := test.NewLocal(log)
+	log.SetLevel(logrus.DebugLevel)
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		log.SetLevel(level)
+		log.SetOutput(out)
+		log.ReplaceHooks(hooks)
+	})
+	return hook
+}
+
+func TestLoggerMiddlewareRouteLevels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hook := captureLogs(t)
+
+	cfg := config.LogConfig{Routes: []config.RouteLogConfig{
+		{Path: "/health", Level: "skip"},
+		{Path: "/health/*", Level: "skip"},
+		{Path: "/api/v1/packages/*", Level: "debug"},
+		{Path: "/api/v1/packages/:package/:version/download", Level: "warn"},
+		{Path: "/bad", Level: "loud"}, // 无效级别被忽略，按默认info记录
+	}}
+	r := gin.New()
+	r.Use(LoggerMiddleware(cfg))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/health", ok)
+	r.GET("/api/v1/packages/:package", ok)
+	r.GET("/api/v1/packages/:package/:version/download", ok)
+	r.GET("/api/v1/users", ok)
+	r.GET("/bad", ok)
+	r.GET("/health/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	tests := []struct {
+		path      string
+		wantLog   bool
+		wantLevel logrus.Level
+	}{
+		{path: "/health", wantLog: false},
+		{path: "/api/v1/packages/demo", wantLog: true, wantLevel: logrus.DebugLevel},
+		{path: "/api/v1/packages/demo/1.0.0/download", wantLog: true, wantLevel: logrus.WarnLevel},
+		{path: "/api/v1/users", wantLog: true, wantLevel: logrus.InfoLevel},
+		{path: "/bad", wantLog: true, wantLevel: logrus.InfoLevel},
+		// 错误响应不受skip配置影响
+		{path: "/health/fail", wantLog: true, wantLevel: logrus.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			hook.Reset()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			entries := hook.AllEntries()
+			if !tt.wantLog {
+				if len(entries) != 0 {
+					t.Fatalf("got %d log entries, want none", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d log entries, want 1", len(entries))
+			}
+			if entries[0].Level != tt.wantLevel {
+				t.Errorf("level = %s, want %s", entries[0].Level, tt.wantLevel)
+			}
+			if entries[0].Data["path"] != tt.path {
+				t.Errorf("path field = %v, want %s", entries[0].Data["path"], tt.path)
+			}
+		})
+	}
+}
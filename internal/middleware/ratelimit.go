@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userWindow 单个用户在当前窗口内的请求计数
+type userWindow struct {
+	start time.Time
+	count int
+}
+
+// UserRateLimit 按用户限制请求频率（固定窗口），未登录时按客户端IP计数
+func UserRateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	windows := make(map[string]*userWindow)
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, ok := GetUserIDFromContext(c); ok {
+			key = fmt.Sprintf("user:%d", userID)
+		}
+
+		now := time.Now()
+		mu.Lock()
+		// 顺带清理已过期的窗口，避免map无限增长
+		for k, w := range windows {
+			if now.Sub(w.start) >= window {
+				delete(windows, k)
+			}
+		}
+
+		w, ok := windows[key]
+		if !ok {
+			w = &userWindow{start: now}
+			windows[key] = w
+		}
+		if w.count >= limit {
+			retryAfter := window - now.Sub(w.start)
+			mu.Unlock()
+
+			c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(retryAfter.Seconds()))))
+			ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded, please retry later")
+			c.Abort()
+			return
+		}
+		w.count++
+		mu.Unlock()
+
+		c.Next()
+	}
+}
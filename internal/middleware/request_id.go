@@ -1,32 +1,39 @@
 package middleware
 
 import (
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/requestid"
+
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 const (
 	// RequestIDHeader 请求ID头名称
-	RequestIDHeader = "X-Request-ID"
+	RequestIDHeader = requestid.Header
 	// RequestIDKey 在gin上下文中存储请求ID的键名
 	RequestIDKey = "request_id"
 )
 
 // RequestIDMiddleware 请求ID中间件
 // 为每个请求生成唯一的ID，用于日志追踪和链路追踪
-func RequestIDMiddleware() gin.HandlerFunc {
+// 客户端传入的请求ID需通过长度和字符集校验，否则重新生成，防止日志注入
+func RequestIDMiddleware(cfg config.RequestIDConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 尝试从请求头中获取请求ID
 		requestID := c.GetHeader(RequestIDHeader)
 
-		// 如果请求头中没有请求ID，则生成一个新的
-		if requestID == "" {
-			requestID = generateRequestID()
+		// 如果请求头中没有合法的请求ID，则生成一个新的
+		if !requestid.IsValid(requestID, cfg.MaxLength) {
+			requestID = generateRequestID(cfg.Format)
 		}
 
 		// 将请求ID存储到gin上下文中
 		c.Set(RequestIDKey, requestID)
 
+		// 将请求ID写入请求的context，供下游调用（MinIO等）透传，数据库日志也从中读取
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+
 		// 将请求ID添加到响应头中
 		c.Header(RequestIDHeader, requestID)
 
@@ -35,8 +42,8 @@ func RequestIDMiddleware() gin.HandlerFunc {
 }
 
 // generateRequestID 生成唯一的请求ID
-func generateRequestID() string {
-	return uuid.New().String()
+func generateRequestID(format string) string {
+	return requestid.Generate(format)
 }
 
 // GetRequestIDFromContext 从gin上下文中获取请求ID
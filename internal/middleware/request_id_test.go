@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "valid id passes through", incoming: "client-trace_01.abc:2", keep: true},
+		{name: "missing id is generated", incoming: ""},
+		{name: "oversized id is replaced", incoming: strings.Repeat("a", requestid.DefaultMaxLength+1)},
+		{name: "log injection is replaced", incoming: "abc\nlevel=error msg=forged"},
+		{name: "html is replaced", incoming: "<script>alert(1)</script>"},
+		{name: "spaces are replaced", incoming: "abc def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxID, ginID string
+			r := gin.New()
+			r.Use(RequestIDMiddleware(config.RequestIDConfig{}))
+			r.GET("/", func(c *gin.Context) {
+				ginID = GetRequestIDFromContext(c)
+				ctxID = logger.RequestIDFromContext(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tt.keep && got != tt.incoming {
+				t.Fatalf("response id = %q, want %q", got, tt.incoming)
+			}
+			if !tt.keep && (got == tt.incoming || !requestid.IsValid(got, 0)) {
+				t.Fatalf("response id = %q, want a freshly generated id", got)
+			}
+			if ginID != got || ctxID != got {
+				t.Fatalf("gin id %q / context id %q differ from response id %q", ginID, ctxID, got)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddlewareFormatAndMaxLength(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware(config.RequestIDConfig{Format: requestid.FormatKSUID, MaxLength: 8}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "123456789")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); len(got) != 27 {
+		t.Fatalf("expected a 27 character ksuid replacing the over-long id, got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +13,7 @@ type Response struct {
 	Code      int         `json:"code"`
 	Message   string      `json:"message"`
 	Data      interface{} `json:"data,omitempty"`
+	Meta      interface{} `json:"meta,omitempty"` // 与data无关的附加信息，如token有效期
 	Timestamp int64       `json:"timestamp"`
 	RequestID string      `json:"request_id,omitempty"`
 }
@@ -42,6 +44,15 @@ func ResponseMiddleware() gin.HandlerFunc {
 	}
 }
 
+// writeJSON 写入JSON响应，请求带有pretty=true时输出缩进格式，便于调试时直接阅读
+func writeJSON(c *gin.Context, httpCode int, obj interface{}) {
+	if pretty, _ := strconv.ParseBool(c.Query("pretty")); pretty {
+		c.IndentedJSON(httpCode, obj)
+		return
+	}
+	c.JSON(httpCode, obj)
+}
+
 // SuccessResponse 成功响应
 func SuccessResponse(c *gin.Context, data interface{}) {
 	response := Response{
@@ -51,7 +62,20 @@ func SuccessResponse(c *gin.Context, data interface{}) {
 		Timestamp: time.Now().Unix(),
 		RequestID: c.GetString("request_id"),
 	}
-	c.JSON(http.StatusOK, response)
+	writeJSON(c, http.StatusOK, response)
+}
+
+// SuccessResponseWithMeta 带附加信息的成功响应
+func SuccessResponseWithMeta(c *gin.Context, data, meta interface{}) {
+	response := Response{
+		Code:      0,
+		Message:   "success",
+		Data:      data,
+		Meta:      meta,
+		Timestamp: time.Now().Unix(),
+		RequestID: c.GetString("request_id"),
+	}
+	writeJSON(c, http.StatusOK, response)
 }
 
 // ErrorResponse 错误响应
@@ -62,7 +86,7 @@ func ErrorResponse(c *gin.Context, httpCode int, message string) {
 		Timestamp: time.Now().Unix(),
 		RequestID: c.GetString("request_id"),
 	}
-	c.JSON(httpCode, response)
+	writeJSON(c, httpCode, response)
 }
 
 // CustomResponse 自定义响应
@@ -74,7 +98,7 @@ func CustomResponse(c *gin.Context, httpCode int, code int, message string, data
 		Timestamp: time.Now().Unix(),
 		RequestID: c.GetString("request_id"),
 	}
-	c.JSON(httpCode, response)
+	writeJSON(c, httpCode, response)
 }
 
 // ValidationErrorResponse 参数验证错误响应
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"webservice/internal/database"
+	"webservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RowScope 为请求写入匿名的行级安全访问范围，认证中间件解析token后替换为当前用户
+// 没有访问范围的context按后台任务处理，不受行级安全限制，因此需要在所有访问数据库的中间件之前注册
+func RowScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(database.WithRowScope(c.Request.Context(), database.RowScope{}))
+		c.Next()
+	}
+}
+
+// setRowScope 将token中的用户写入请求的行级安全访问范围，管理员不受限制
+func setRowScope(c *gin.Context, claims *Claims) {
+	scope := database.RowScope{
+		UserID: claims.UserID,
+		Admin:  claims.Role == models.RoleAdmin || claims.Role == models.RoleSuper,
+	}
+	c.Request = c.Request.WithContext(database.WithRowScope(c.Request.Context(), scope))
+}
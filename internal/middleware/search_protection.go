@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"webservice/internal/botdetect"
+	"webservice/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 工作量证明相关的请求头
+const (
+	headerSearchProof               = "X-Search-Proof"                // 客户端提交的答案：<challenge>:<nonce>
+	headerSearchChallenge           = "X-Search-Challenge"            // 超过匿名限制时返回的题目
+	headerSearchChallengeDifficulty = "X-Search-Challenge-Difficulty" // 题目要求的前导零比特数
+)
+
+// searchBotCacheMaxEntries 爬虫搜索结果缓存的最大条目数，已满时不再缓存新的查询
+const searchBotCacheMaxEntries = 1000
+
+var (
+	// searchRejectedTotal 按原因统计被拒绝的搜索请求
+	searchRejectedTotal = metrics.NewCounterVec("search_protection_rejected_total",
+		"Search requests rejected by search protection by reason", "reason")
+	// searchBotRequestsTotal 爬虫User-Agent的搜索请求，result为hit（缓存命中）或miss
+	searchBotRequestsTotal = metrics.NewCounterVec("search_protection_bot_requests_total",
+		"Search requests from bot user agents by cache result", "result")
+)
+
+// cachedSearch 缓存的搜索响应
+type cachedSearch struct {
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// searchBotCache 爬虫搜索结果缓存，按请求URI和Accept请求头区分
+type searchBotCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedSearch
+}
+
+// get 读取未过期的缓存
+func (s *searchBotCache) get(key string, now time.Time) *cachedSearch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil
+	}
+	return entry
+}
+
+// put 写入缓存，顺带清理已过期的条目，仍已满时放弃写入
+func (s *searchBotCache) put(key string, entry *cachedSearch, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	if len(s.entries) >= searchBotCacheMaxEntries {
+		return
+	}
+	s.entries[key] = entry
+}
+
+// SearchProtection 包搜索防护：匿名请求按IP、登录请求按用户限流，匿名超过限制时标记IP并返回429
+// 启用工作量证明时，429响应通过X-Search-Challenge返回题目，附带有效X-Search-Proof的请求不受匿名限制
+// User-Agent匹配爬虫的请求会标记IP，并使用按bot_cache_ttl缓存的搜索结果；需放在OptionalJWTAuth之后
+func SearchProtection(detector *botdetect.Detector) gin.HandlerFunc {
+	cache := &searchBotCache{entries: make(map[string]*cachedSearch)}
+
+	return func(c *gin.Context) {
+		if !detector.Enabled() {
+			c.Next()
+			return
+		}
+
+		ip, userAgent := c.ClientIP(), c.GetHeader("User-Agent")
+		var userID *uint
+		if id, ok := GetUserIDFromContext(c); ok {
+			userID = &id
+		}
+
+		isBot := detector.IsBot(userAgent)
+		if isBot {
+			detector.Flag(ip, botdetect.ReasonBotUserAgent, userAgent)
+		}
+
+		decision := detector.Check(ip, userID)
+		if !decision.Allowed && !allowWithProof(c, detector, ip, userID) {
+			if userID == nil {
+				detector.Flag(ip, botdetect.ReasonRateLimit, userAgent)
+			}
+			rejectSearch(c, detector, ip, userID, decision)
+			return
+		}
+
+		if !isBot {
+			c.Next()
+			return
+		}
+		serveBotSearch(c, cache, detector.BotCacheTTL())
+	}
+}
+
+// allowWithProof 匿名请求超过限制时，检查是否附带了有效的工作量证明
+func allowWithProof(c *gin.Context, detector *botdetect.Detector, ip string, userID *uint) bool {
+	proof := c.GetHeader(headerSearchProof)
+	if userID != nil || proof == "" || !detector.ChallengeEnabled() {
+		return false
+	}
+	if err := detector.VerifyProof(ip, proof); err != nil {
+		searchRejectedTotal.Inc("invalid_proof")
+		return false
+	}
+	return true
+}
+
+// rejectSearch 返回429，匿名请求在启用工作量证明时附带新题目
+func rejectSearch(c *gin.Context, detector *botdetect.Detector, ip string, userID *uint, decision botdetect.Decision) {
+	c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(decision.RetryAfter.Seconds()))))
+	if userID == nil && detector.ChallengeEnabled() {
+		if challenge, err := detector.IssueChallenge(ip); err == nil {
+			c.Header(headerSearchChallenge, challenge.Challenge)
+			c.Header(headerSearchChallengeDifficulty, strconv.Itoa(challenge.Difficulty))
+		}
+		searchRejectedTotal.Inc("challenge_required")
+		ErrorResponse(c, http.StatusTooManyRequests, "Search rate limit exceeded, sign in or solve the proof-of-work challenge to continue")
+		c.Abort()
+		return
+	}
+
+	searchRejectedTotal.Inc("rate_limit")
+	ErrorResponse(c, http.StatusTooManyRequests, "Search rate limit exceeded, please retry later")
+	c.Abort()
+}
+
+// serveBotSearch 爬虫请求优先返回缓存的搜索结果，未命中时执行搜索并缓存成功的响应
+func serveBotSearch(c *gin.Context, cache *searchBotCache, ttl time.Duration) {
+	key := c.Request.URL.RequestURI() + "|" + c.GetHeader("Accept")
+	now := time.Now()
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+
+	if entry := cache.get(key, now); entry != nil {
+		searchBotRequestsTotal.Inc("hit")
+		c.Data(entry.status, entry.contentType, entry.body)
+		c.Abort()
+		return
+	}
+
+	searchBotRequestsTotal.Inc("miss")
+	writer := &responseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+	c.Writer = writer
+	c.Next()
+
+	if writer.Status() == http.StatusOK {
+		cache.put(key, &cachedSearch{
+			status:      http.StatusOK,
+			contentType: writer.Header().Get("Content-Type"),
+			body:        writer.body.Bytes(),
+			expiresAt:   now.Add(ttl),
+		}, now)
+	}
+}
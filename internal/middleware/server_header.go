@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"webservice/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServerHeader 在响应中添加Server头，标识服务版本
+func ServerHeader() gin.HandlerFunc {
+	serverHeader := "webservice/" + version.Get().Version
+	return func(c *gin.Context) {
+		c.Header("Server", serverHeader)
+		c.Next()
+	}
+}
@@ -3,6 +3,9 @@ package middleware
 import (
 	"fmt"
 
+	"webservice/internal/config"
+	"webservice/internal/tracer"
+
 	"github.com/gin-gonic/gin"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -10,7 +13,14 @@ import (
 )
 
 // TracingMiddleware 链路追踪中间件
-func TracingMiddleware() gin.HandlerFunc {
+// 配置关闭追踪或tracer未成功初始化（全局tracer为NoopTracer）时直接放行，不创建span也不设置上下文键
+func TracingMiddleware(cfg config.JaegerConfig) gin.HandlerFunc {
+	if !cfg.Enabled || !tracer.Enabled() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
 	return func(c *gin.Context) {
 		// 获取全局tracer
 		tracer := opentracing.GlobalTracer()
@@ -51,6 +61,8 @@ func TracingMiddleware() gin.HandlerFunc {
 		// 将span上下文存储到gin上下文中
 		c.Set("tracing_span", span)
 		c.Set("tracing_context", span.Context())
+		// 同时放入请求的context，服务层和数据库操作据此创建子span
+		c.Request = c.Request.WithContext(opentracing.ContextWithSpan(c.Request.Context(), span))
 
 		// 处理请求
 		c.Next()
@@ -81,10 +93,13 @@ func TracingMiddleware() gin.HandlerFunc {
 	}
 }
 
-// GetSpanFromContext 从gin上下文中获取span
+// GetSpanFromContext 从gin上下文中获取span，追踪未启用时返回nil
 func GetSpanFromContext(c *gin.Context) opentracing.Span {
+	if c == nil {
+		return nil
+	}
 	if span, exists := c.Get("tracing_span"); exists {
-		if s, ok := span.(opentracing.Span); ok {
+		if s, ok := span.(opentracing.Span); ok && s != nil {
 			return s
 		}
 	}
@@ -102,6 +117,7 @@ func GetSpanContextFromContext(c *gin.Context) opentracing.SpanContext {
 }
 
 // StartChildSpan 在当前请求上下文中开始一个子span
+// 没有父span时按全局tracer开始新span，追踪未启用时得到的是NoopTracer的空span
 func StartChildSpan(c *gin.Context, operationName string) opentracing.Span {
 	parentSpan := GetSpanFromContext(c)
 	if parentSpan != nil {
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/tracer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// tracingRouter 挂载追踪中间件，记录处理函数中看到的span
+func tracingRouter(cfg config.JaegerConfig, seen func(c *gin.Context)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TracingMiddleware(cfg))
+	r.GET("/packages/:package", func(c *gin.Context) {
+		if seen != nil {
+			seen(c)
+		}
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+// assertNoSpan 检查请求上下文中没有追踪span，子span退化为不记录的空span
+func assertNoSpan(t *testing.T, cfg config.JaegerConfig) {
+	t.Helper()
+	called := false
+	r := tracingRouter(cfg, func(c *gin.Context) {
+		called = true
+		if span := GetSpanFromContext(c); span != nil {
+			t.Errorf("GetSpanFromContext() = %v, want nil", span)
+		}
+		if sc := GetSpanContextFromContext(c); sc != nil {
+			t.Errorf("GetSpanContextFromContext() = %v, want nil", sc)
+		}
+		if span := opentracing.SpanFromContext(c.Request.Context()); span != nil {
+			t.Errorf("request context span = %v, want nil", span)
+		}
+		child := StartChildSpan(c, "child")
+		if child == nil {
+			t.Fatal("StartChildSpan() = nil, want a usable span")
+		}
+		child.Finish()
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/packages/demo", nil))
+	if !called {
+		t.Fatal("handler was not called")
+	}
+}
+
+// 全局tracer一旦注册无法撤销，此测试需在注册mocktracer的测试之前运行
+func TestTracingMiddlewareSkipsNoopTracer(t *testing.T) {
+	if tracer.Enabled() {
+		t.Skip("a global tracer is already registered")
+	}
+	assertNoSpan(t, config.JaegerConfig{Enabled: true})
+}
+
+func TestTracingMiddlewareDisabledByConfig(t *testing.T) {
+	mock := mocktracer.New()
+	opentracing.SetGlobalTracer(mock)
+
+	assertNoSpan(t, config.JaegerConfig{Enabled: false})
+	// StartChildSpan没有父span时使用全局tracer，不经过中间件的span
+	if spans := mock.FinishedSpans(); len(spans) != 1 || spans[0].OperationName != "child" {
+		t.Errorf("finished spans = %v, want only the child span", spans)
+	}
+}
+
+func TestTracingMiddlewareEnabled(t *testing.T) {
+	mock := mocktracer.New()
+	opentracing.SetGlobalTracer(mock)
+
+	r := tracingRouter(config.JaegerConfig{Enabled: true}, func(c *gin.Context) {
+		if GetSpanFromContext(c) == nil {
+			t.Error("GetSpanFromContext() = nil, want the request span")
+		}
+		if opentracing.SpanFromContext(c.Request.Context()) == nil {
+			t.Error("request context span = nil, want the request span")
+		}
+		StartChildSpan(c, "child").Finish()
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/packages/demo", nil))
+
+	spans := mock.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("finished spans = %d, want 2", len(spans))
+	}
+	child, request := spans[0], spans[1]
+	if request.OperationName != "GET /packages/:package" {
+		t.Errorf("request span = %q, want GET /packages/:package", request.OperationName)
+	}
+	if child.ParentID != request.SpanContext.SpanID {
+		t.Errorf("child parent = %d, want request span %d", child.ParentID, request.SpanContext.SpanID)
+	}
+	if got := request.Tag("http.status_code"); got != uint16(http.StatusNoContent) {
+		t.Errorf("http.status_code tag = %v, want 204", got)
+	}
+}
+
+func BenchmarkTracingMiddleware(b *testing.B) {
+	mock := mocktracer.New()
+	opentracing.SetGlobalTracer(mock)
+
+	for _, bc := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"disabled", false},
+		{"enabled", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			r := tracingRouter(config.JaegerConfig{Enabled: bc.enabled}, nil)
+			req := httptest.NewRequest(http.MethodGet, "/packages/demo", nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.ServeHTTP(httptest.NewRecorder(), req)
+				// mocktracer保留所有已结束的span，定期清空避免内存增长影响结果
+				if i%1000 == 999 {
+					mock.Reset()
+				}
+			}
+		})
+	}
+}
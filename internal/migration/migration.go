@@ -1,23 +1,68 @@
 package migration
 
 import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"webservice/internal/config"
+	"webservice/internal/license"
 	"webservice/internal/logger"
+	"webservice/internal/minio"
 	"webservice/internal/models"
+	"webservice/internal/password"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Models 返回需要迁移的全部模型
+func Models() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.Package{},
+		&models.PackageVersion{},
+		&models.PackageDownload{},
+		&models.PackageVersionPin{},
+		&models.PackageAlias{},
+		&models.PackageWatcher{},
+		&models.PackageCollaborator{},
+		&models.WikiPage{},
+		&models.WikiPageRevision{},
+		&models.PackageBandwidthUsage{},
+		&models.UploadSession{},
+		&models.Category{},
+		&models.PackageCategory{},
+		&models.UserSession{},
+		&models.UserSuspension{},
+		&models.ExternalIdentity{},
+		&models.APIToken{},
+		&models.SetupToken{},
+		&models.StorageTierChange{},
+		&models.DeprecatedRouteUsage{},
+		&models.AuditLog{},
+		&models.PackageRecommendation{},
+		&models.OutboxEvent{},
+		&models.OutboxOffset{},
+		&models.HealthCheck{},
+		&models.FeatureFlag{},
+		&models.LeaderboardEntry{},
+		&models.VersionScanResult{},
+	}
+}
+
 // AutoMigrate 自动迁移数据库表结构
 func AutoMigrate(db *gorm.DB) error {
 	logger.Info("Starting database migration...")
 
+	if err := dropLegacyIndexes(db); err != nil {
+		logger.Errorf("Failed to drop legacy indexes: %v", err)
+		return err
+	}
+
 	// 一次性迁移所有模型，这样更高效
-	if err := db.AutoMigrate(
-		&models.User{},
-		&models.Package{},
-		&models.PackageVersion{},
-		&models.PackageDownload{},
-	); err != nil {
+	if err := db.AutoMigrate(Models()...); err != nil {
 		logger.Errorf("Failed to migrate database: %v", err)
 		return err
 	}
@@ -26,42 +71,321 @@ func AutoMigrate(db *gorm.DB) error {
 	return nil
 }
 
+// legacyIndexes 已被替换的索引（表名、索引名）
+// idx_package_version 早期只建在version列上，使版本号在所有包之间唯一，现由(package_id, version)组合唯一索引替代
+var legacyIndexes = []struct {
+	Table string
+	Name  string
+}{
+	{Table: "package_versions", Name: "idx_package_version"},
+}
+
+// dropLegacyIndexes 删除已被替换的索引，必须在AutoMigrate之前执行，否则旧索引会继续拒绝合法的写入
+func dropLegacyIndexes(db *gorm.DB) error {
+	for _, idx := range legacyIndexes {
+		if !db.Migrator().HasTable(idx.Table) || !db.Migrator().HasIndex(idx.Table, idx.Name) {
+			continue
+		}
+		if err := db.Migrator().DropIndex(idx.Table, idx.Name); err != nil {
+			return fmt.Errorf("drop index %s: %w", idx.Name, err)
+		}
+		logger.Infof("Dropped legacy index %s on %s", idx.Name, idx.Table)
+	}
+	return nil
+}
+
+// indexDefinition 索引定义
+type indexDefinition struct {
+	Name       string
+	Table      string
+	Columns    string // 列列表或表达式
+	Unique     bool
+	Expression bool // 是否为表达式索引（如LOWER(name)）
+}
+
+// packageIndexes 包搜索及下载统计相关索引
+// AutoMigrate不支持表达式索引，因此通过原生SQL创建
+var packageIndexes = []indexDefinition{
+	{Name: "idx_packages_name_lower", Table: "packages", Columns: "LOWER(name)", Expression: true},
+	{Name: "idx_packages_description_lower", Table: "packages", Columns: "LOWER(description)", Expression: true},
+	{Name: "idx_packages_author_lower", Table: "packages", Columns: "LOWER(author)", Expression: true},
+	{Name: "idx_packages_keywords_lower", Table: "packages", Columns: "LOWER(keywords)", Expression: true},
+	{Name: "idx_package_downloads_version_time", Table: "package_downloads", Columns: "package_version_id, download_time"},
+	{Name: "idx_package_downloads_user_time", Table: "package_downloads", Columns: "user_id, download_time"},
+	{Name: "idx_package_versions_package_version", Table: "package_versions", Columns: "package_id, version", Unique: true},
+	{Name: "idx_package_versions_package_created", Table: "package_versions", Columns: "package_id, created_at"},
+}
+
 // CreateIndexes 创建数据库索引
 func CreateIndexes(db *gorm.DB) error {
-	logger.Info("Skipping database indexes creation for faster startup...")
-	// 暂时跳过索引创建以加快启动速度
+	dialect := db.Dialector.Name()
+	logger.Infof("Creating database indexes (dialect: %s)...", dialect)
+
+	for _, idx := range packageIndexes {
+		// 已有重复数据时无法创建唯一索引，列出重复的值后跳过，不阻止启动；清理重复数据后下次启动会自动创建
+		if idx.Unique && !idx.Expression {
+			duplicates, err := findDuplicateKeys(db, idx)
+			if err != nil {
+				logger.Warnf("Failed to check duplicates for unique index %s (skipping): %v", idx.Name, err)
+				continue
+			}
+			if len(duplicates) > 0 {
+				logger.Warnf("Skipping unique index %s: %s has duplicate (%s) values, remove them and restart to create the index: %s",
+					idx.Name, idx.Table, idx.Columns, strings.Join(duplicates, "; "))
+				continue
+			}
+		}
+		if err := createIndex(db, dialect, idx); err != nil {
+			// 表达式索引依赖数据库版本（如MySQL 8.0.13+），失败时仅告警
+			if idx.Expression {
+				logger.Warnf("Failed to create expression index %s (skipping): %v", idx.Name, err)
+				continue
+			}
+			logger.Errorf("Failed to create index %s: %v", idx.Name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxReportedDuplicates 唯一索引无法创建时最多列出的重复值数量
+const maxReportedDuplicates = 20
+
+// findDuplicateKeys 查找违反唯一索引的重复值（包括软删除的行，唯一索引同样包含它们），每项形如 "1, 1.0.0 (2 rows)"
+func findDuplicateKeys(db *gorm.DB, idx indexDefinition) ([]string, error) {
+	columns := strings.Split(idx.Columns, ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	if !db.Migrator().HasTable(idx.Table) {
+		return nil, nil
+	}
+
+	rows, err := db.Table(idx.Table).
+		Select(strings.Join(columns, ", ") + ", COUNT(*)").
+		Group(strings.Join(columns, ", ")).
+		Having("COUNT(*) > 1").
+		Limit(maxReportedDuplicates).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns)+1)
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		parts := make([]string, len(columns))
+		for i := range columns {
+			parts[i] = fmt.Sprint(formatKeyValue(values[i]))
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s (%v rows)", strings.Join(parts, ", "), formatKeyValue(values[len(columns)])))
+	}
+	return duplicates, rows.Err()
+}
+
+// formatKeyValue 驱动可能以[]byte返回字符串列，转为字符串以便输出
+func formatKeyValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// createIndex 按数据库方言创建单个索引
+func createIndex(db *gorm.DB, dialect string, idx indexDefinition) error {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+
+	switch dialect {
+	case "postgres", "sqlite":
+		// PostgreSQL和SQLite均支持IF NOT EXISTS及表达式索引
+		return db.Exec(fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s (%s)", unique, idx.Name, idx.Table, idx.Columns)).Error
+	case "mysql":
+		// MySQL不支持CREATE INDEX IF NOT EXISTS，先查询information_schema
+		var count int64
+		if err := db.Raw(
+			"SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+			idx.Table, idx.Name,
+		).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		columns := idx.Columns
+		if idx.Expression {
+			// MySQL函数索引需要额外的括号包裹表达式
+			columns = "(" + columns + ")"
+		}
+		return db.Exec(fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, idx.Name, idx.Table, columns)).Error
+	default:
+		logger.Warnf("Unsupported dialect %s, skipping index %s", dialect, idx.Name)
+		return nil
+	}
+}
+
+// BackfillObjectKeys 为早期版本补全对象键
+// 早期的MinIOPath只记录了 packages/{name}/{version} 目录，实际对象均按legacy方案命名，
+// 下载等操作改为直接读取该列后需要替换为完整的对象键
+func BackfillObjectKeys(db *gorm.DB) error {
+	legacy, err := minio.NewObjectNamer(minio.NamingLegacy)
+	if err != nil {
+		return err
+	}
+
+	var rows []struct {
+		ID          uint
+		PackageName string
+		Version     string
+	}
+	err = db.Table("package_versions AS pv").
+		Select("pv.id, p.name AS package_name, pv.version").
+		Joins("JOIN packages p ON p.id = pv.package_id").
+		Where("pv.min_io_path = '' OR pv.min_io_path IS NULL OR pv.min_io_path NOT LIKE ?", "%.pkg").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		key := legacy.ObjectKey(row.PackageName, row.Version)
+		if err := db.Model(&models.PackageVersion{}).Where("id = ?", row.ID).Update("min_io_path", key).Error; err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		logger.Infof("Backfilled object keys for %d package versions", len(rows))
+	}
+	return nil
+}
+
+// BackfillPublishedManifests 为没有发布快照的旧版本按当前字段补全快照
+// 旧版本的描述可能在发布后修改过，补全的快照标记为backfilled；依赖声明发布后无法修改，与发布时一致
+func BackfillPublishedManifests(db *gorm.DB) error {
+	var versions []models.PackageVersion
+	err := db.Unscoped().Preload("Package", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Where("published_manifest IS NULL OR published_manifest = ''").
+		Find(&versions).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range versions {
+		manifest := models.NewPublishedManifest(versions[i].Package.Name, &versions[i])
+		manifest.Backfilled = true
+		// 按结构体更新，使字段的JSON序列化生效
+		if err := db.Model(&versions[i]).Unscoped().Select("published_manifest").Updates(&models.PackageVersion{PublishedManifest: manifest}).Error; err != nil {
+			return err
+		}
+	}
+	if len(versions) > 0 {
+		logger.Infof("Backfilled published manifests for %d package versions", len(versions))
+	}
+	return nil
+}
+
+// NormalizeLicenses 将包的许可证统一为SPDX规范写法（如mit、MIT License改为MIT）
+// 无法确定对应标识符的值保持不变，可通过 GET /api/v1/admin/licenses/unrecognized 查看后人工处理
+func NormalizeLicenses(db *gorm.DB, registry *license.Registry) error {
+	var values []string
+	if err := db.Model(&models.Package{}).Unscoped().Where("license <> ''").Distinct().Pluck("license", &values).Error; err != nil {
+		return err
+	}
+
+	normalized, unrecognized := 0, 0
+	for _, value := range values {
+		id, ok := registry.Canonical(value)
+		if !ok {
+			unrecognized++
+			continue
+		}
+		if id == value {
+			continue
+		}
+		result := db.Model(&models.Package{}).Unscoped().Where("license = ?", value).Update("license", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		normalized += int(result.RowsAffected)
+	}
+
+	if normalized > 0 {
+		logger.Infof("Normalized license identifiers for %d packages", normalized)
+	}
+	if unrecognized > 0 {
+		logger.Warnf("%d license values could not be normalized, see GET /api/v1/admin/licenses/unrecognized", unrecognized)
+	}
 	return nil
 }
 
 // SeedData 初始化种子数据
-func SeedData(db *gorm.DB) error {
+// 已存在管理员的数据库保持不变；未存在时仅当配置了初始管理员账号才创建，
+// 否则交由首次启动引导流程（一次性安装令牌）处理
+// 由RunMigrations在迁移锁内调用，其他实例已创建的用户只记录警告
+func SeedData(db *gorm.DB, cfg config.BootstrapConfig, hasher *password.Hasher) error {
 	logger.Info("Seeding initial data...")
 
 	// 检查是否已存在管理员用户
 	var adminCount int64
-	if err := db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount).Error; err != nil {
+	if err := db.Model(&models.User{}).Where("role IN ?", []string{models.RoleAdmin, models.RoleSuper}).Count(&adminCount).Error; err != nil {
 		logger.Errorf("Failed to count admin users: %v", err)
 		return err
 	}
 
-	if adminCount == 0 {
-		// 创建默认管理员用户
+	switch {
+	case adminCount > 0:
+		logger.Info("Admin user already exists, skipping creation")
+	case cfg.AdminUsername != "" || cfg.AdminPassword != "":
+		// 使用配置提供的初始管理员账号
+		if err := validateBootstrapAdmin(cfg); err != nil {
+			logger.Errorf("Invalid bootstrap admin configuration: %v", err)
+			return err
+		}
+
+		hashedPassword, err := hasher.Hash(cfg.AdminPassword)
+		if err != nil {
+			return err
+		}
+
 		adminUser := &models.User{
-			Username: "admin",
-			Email:    "admin@example.com",
-			Password: "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // password
+			Username: cfg.AdminUsername,
+			Email:    cfg.AdminEmail,
+			Password: hashedPassword,
 			Nickname: "Administrator",
 			Role:     models.RoleAdmin,
 			Status:   models.UserStatusActive,
 		}
 
-		if err := db.Create(adminUser).Error; err != nil {
+		created, err := createSeedUser(db, adminUser)
+		if err != nil {
 			logger.Errorf("Failed to create admin user: %v", err)
 			return err
 		}
-		logger.Info("Default admin user created successfully (username: admin, password: password)")
-	} else {
-		logger.Info("Admin user already exists, skipping creation")
+		if created {
+			logger.Infof("Bootstrap admin user created from configuration (username: %s)", cfg.AdminUsername)
+		} else {
+			logger.Warnf("Bootstrap admin user %s or its email already exists (created concurrently by another instance?), skipping creation", cfg.AdminUsername)
+		}
+	default:
+		logger.Info("No admin user configured, first-run setup token will be issued")
+	}
+
+	// 测试用户仅在显式开启开发标志时创建
+	if !cfg.SeedTestUser {
+		logger.Info("Data seeding completed successfully")
+		return nil
 	}
 
 	// 检查是否已存在测试用户
@@ -76,17 +400,22 @@ func SeedData(db *gorm.DB) error {
 		testUser := &models.User{
 			Username: "testuser",
 			Email:    "test@example.com",
-			Password: "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // password
+			Password: "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // password（bcrypt，首次登录后升级为当前算法）
 			Nickname: "Test User",
 			Role:     models.RoleUser,
 			Status:   models.UserStatusActive,
 		}
 
-		if err := db.Create(testUser).Error; err != nil {
+		created, err := createSeedUser(db, testUser)
+		if err != nil {
 			logger.Errorf("Failed to create test user: %v", err)
 			return err
 		}
-		logger.Info("Test user created successfully (username: testuser, password: password)")
+		if created {
+			logger.Warn("Test user created (username: testuser, password: password) - development only")
+		} else {
+			logger.Warn("Test user or its email already exists (created concurrently by another instance?), skipping creation")
+		}
 	} else {
 		logger.Info("Test user already exists, skipping creation")
 	}
@@ -95,8 +424,43 @@ func SeedData(db *gorm.DB) error {
 	return nil
 }
 
+// createSeedUser 创建种子用户，用户名或邮箱冲突时不创建也不报错，返回是否创建
+// 不加锁执行时，同时启动的实例可能都通过了存在性检查，由唯一索引保证只创建一次
+func createSeedUser(db *gorm.DB, user *models.User) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(user)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// validateBootstrapAdmin 校验配置提供的初始管理员账号
+func validateBootstrapAdmin(cfg config.BootstrapConfig) error {
+	if len(cfg.AdminUsername) < 3 || len(cfg.AdminUsername) > 50 {
+		return errors.New("bootstrap admin_username must be 3-50 characters")
+	}
+	if _, err := mail.ParseAddress(cfg.AdminEmail); err != nil {
+		return errors.New("bootstrap admin_email is not a valid email address")
+	}
+	if len(cfg.AdminPassword) < 8 {
+		return errors.New("bootstrap admin_password must be at least 8 characters")
+	}
+	if strings.EqualFold(cfg.AdminPassword, "password") || strings.EqualFold(cfg.AdminPassword, cfg.AdminUsername) {
+		return errors.New("bootstrap admin_password is too weak")
+	}
+	return nil
+}
+
 // RunMigrations 运行所有迁移
-func RunMigrations(db *gorm.DB) error {
+// 多个实例同时启动时通过数据库咨询锁依次执行，后执行的实例看到已完成的表结构和种子数据
+func RunMigrations(db *gorm.DB, cfg *config.Config) error {
+	return withMigrationLock(db, cfg.Bootstrap.SeedLockTimeout, func(tx *gorm.DB) error {
+		return runMigrations(tx, cfg)
+	})
+}
+
+// runMigrations 在持有迁移锁时运行所有迁移
+func runMigrations(db *gorm.DB, cfg *config.Config) error {
 	logger.Info("Starting migrations...")
 
 	// 自动迁移表结构
@@ -115,9 +479,41 @@ func RunMigrations(db *gorm.DB) error {
 	}
 	logger.Info("CreateIndexes completed successfully")
 
+	// 补全早期版本的对象键
+	logger.Info("Running BackfillObjectKeys...")
+	if err := BackfillObjectKeys(db); err != nil {
+		logger.Errorf("BackfillObjectKeys failed: %v", err)
+		return err
+	}
+	logger.Info("BackfillObjectKeys completed successfully")
+
+	// 为旧版本补全发布快照
+	logger.Info("Running BackfillPublishedManifests...")
+	if err := BackfillPublishedManifests(db); err != nil {
+		logger.Errorf("BackfillPublishedManifests failed: %v", err)
+		return err
+	}
+	logger.Info("BackfillPublishedManifests completed successfully")
+
+	// 规范化已有的许可证标识符，不受允许列表限制
+	logger.Info("Running NormalizeLicenses...")
+	if err := NormalizeLicenses(db, license.NewRegistry(cfg.Packages.CustomLicenses, nil)); err != nil {
+		logger.Errorf("NormalizeLicenses failed: %v", err)
+		return err
+	}
+	logger.Info("NormalizeLicenses completed successfully")
+
+	// PostgreSQL上为packages表开启或关闭行级安全
+	logger.Info("Running ConfigureRowLevelSecurity...")
+	if err := ConfigureRowLevelSecurity(db, cfg.Database.RowLevelSecurity); err != nil {
+		logger.Errorf("ConfigureRowLevelSecurity failed: %v", err)
+		return err
+	}
+	logger.Info("ConfigureRowLevelSecurity completed successfully")
+
 	// 初始化种子数据
 	logger.Info("Running SeedData...")
-	if err := SeedData(db); err != nil {
+	if err := SeedData(db, cfg.Bootstrap, password.NewHasher(cfg.Password)); err != nil {
 		logger.Errorf("SeedData failed: %v", err)
 		return err
 	}
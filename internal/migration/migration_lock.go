@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"webservice/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// migrationLockName 迁移咨询锁的名称，所有实例共用
+	migrationLockName = "webservice:migrations"
+	// defaultMigrationLockTimeout 未配置时等待迁移锁的最长时间
+	defaultMigrationLockTimeout = time.Minute
+	// migrationLockPollInterval PostgreSQL轮询咨询锁的间隔
+	migrationLockPollInterval = 200 * time.Millisecond
+)
+
+// localMigrationLock 不支持咨询锁的数据库（如SQLite）只能被本进程访问，用进程内互斥锁串行执行迁移
+var localMigrationLock sync.Mutex
+
+// withMigrationLock 在持有数据库咨询锁的连接上执行fn，多个实例同时启动时依次迁移表结构和写入种子数据，后执行的实例能看到先执行的实例的结果
+// 锁属于数据库会话，fn使用同一个连接，连接池只有一个连接时也不会死锁
+// 不支持咨询锁的数据库（如SQLite）使用进程内互斥锁；等待超时时不加锁执行，并发创建的种子用户冲突由fn按已存在处理
+func withMigrationLock(db *gorm.DB, timeout time.Duration, fn func(tx *gorm.DB) error) error {
+	if timeout <= 0 {
+		timeout = defaultMigrationLockTimeout
+	}
+	dialect := db.Dialector.Name()
+	if dialect != "mysql" && dialect != "postgres" {
+		localMigrationLock.Lock()
+		defer localMigrationLock.Unlock()
+		return fn(db)
+	}
+
+	return db.Connection(func(tx *gorm.DB) error {
+		locked, err := acquireMigrationLock(tx, dialect, timeout)
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if !locked {
+			logger.Warnf("Timed out after %s waiting for the migration lock held by another instance, migrating without it", timeout)
+			return fn(tx)
+		}
+		defer releaseMigrationLock(tx, dialect)
+		return fn(tx)
+	})
+}
+
+// acquireMigrationLock 获取迁移锁，超时未获取到时返回false
+func acquireMigrationLock(tx *gorm.DB, dialect string, timeout time.Duration) (bool, error) {
+	if dialect == "mysql" {
+		var got *int64 // 出错时GET_LOCK返回NULL
+		if err := tx.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, int(timeout.Seconds())).Scan(&got).Error; err != nil {
+			return false, err
+		}
+		return got != nil && *got == 1, nil
+	}
+
+	// pg_advisory_lock无法设置等待时间，轮询pg_try_advisory_lock直到超时
+	deadline := time.Now().Add(timeout)
+	for {
+		var got bool
+		if err := tx.Raw("SELECT pg_try_advisory_lock(hashtext(?))", migrationLockName).Scan(&got).Error; err != nil {
+			return false, err
+		}
+		if got {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		time.Sleep(migrationLockPollInterval)
+	}
+}
+
+// releaseMigrationLock 释放迁移锁，失败时只记录警告，连接关闭时数据库也会释放
+func releaseMigrationLock(tx *gorm.DB, dialect string) {
+	query := "SELECT pg_advisory_unlock(hashtext(?))"
+	if dialect == "mysql" {
+		query = "SELECT RELEASE_LOCK(?)"
+	}
+	if err := tx.Exec(query, migrationLockName).Error; err != nil {
+		logger.Warnf("Failed to release migration lock: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"sync"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+func TestConcurrentRunMigrationsSeedOneAdmin(t *testing.T) {
+	db := testutil.NewDB(t)
+
+	cfg := &config.Config{}
+	cfg.Password = config.PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4}
+	cfg.Bootstrap = config.BootstrapConfig{
+		AdminUsername: "root",
+		AdminEmail:    "root@example.com",
+		AdminPassword: "correct-horse-battery",
+		SeedTestUser:  true,
+	}
+
+	const instances = 2
+	errs := make([]error, instances)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = RunMigrations(db, cfg)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("instance %d: RunMigrations: %v", i, err)
+		}
+	}
+
+	var admins, testUsers int64
+	if err := db.Model(&models.User{}).Where("role IN ?", []string{models.RoleAdmin, models.RoleSuper}).Count(&admins).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.User{}).Where("username = ?", "testuser").Count(&testUsers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if admins != 1 || testUsers != 1 {
+		t.Errorf("%d admins and %d test users, want exactly one of each", admins, testUsers)
+	}
+}
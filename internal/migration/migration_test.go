@@ -0,0 +1,158 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+
+	"webservice/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+const createVersionsTable = `CREATE TABLE package_versions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	package_id INTEGER NOT NULL,
+	version TEXT NOT NULL,
+	created_at DATETIME,
+	deleted_at DATETIME
+)`
+
+func TestCreateIndexesSkipsUniqueIndexWithDuplicates(t *testing.T) {
+	db := testutil.NewDB(t)
+	for _, stmt := range []string{
+		createVersionsTable,
+		"CREATE TABLE packages (id INTEGER PRIMARY KEY, name TEXT, description TEXT, author TEXT, keywords TEXT)",
+		"CREATE TABLE package_downloads (id INTEGER PRIMARY KEY, package_version_id INTEGER, user_id INTEGER, download_time DATETIME)",
+		"INSERT INTO package_versions (package_id, version) VALUES (1, '1.0.0'), (1, '1.0.0'), (1, '2.0.0'), (2, '1.0.0')",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	duplicates, err := findDuplicateKeys(db, indexDefinition{Table: "package_versions", Columns: "package_id, version", Unique: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(duplicates) != 1 || !strings.HasPrefix(duplicates[0], "1, 1.0.0 (2 rows)") {
+		t.Fatalf("duplicates = %v, want [1, 1.0.0 (2 rows)]", duplicates)
+	}
+
+	if err := CreateIndexes(db); err != nil {
+		t.Fatalf("CreateIndexes failed on a table with duplicates: %v", err)
+	}
+	if db.Migrator().HasIndex("package_versions", "idx_package_versions_package_version") {
+		t.Fatal("unique index should be skipped while duplicates exist")
+	}
+	if !db.Migrator().HasIndex("package_versions", "idx_package_versions_package_created") {
+		t.Fatal("non-unique indexes should still be created")
+	}
+
+	// 清理重复数据后再次执行时创建唯一索引
+	if err := db.Exec("DELETE FROM package_versions WHERE id = 2").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateIndexes(db); err != nil {
+		t.Fatal(err)
+	}
+	if !db.Migrator().HasIndex("package_versions", "idx_package_versions_package_version") {
+		t.Fatal("unique index should be created once duplicates are removed")
+	}
+	if err := db.Exec("INSERT INTO package_versions (package_id, version) VALUES (2, '1.0.0')").Error; err == nil {
+		t.Fatal("unique index does not reject duplicates")
+	}
+}
+
+// migrateSchema 与启动时相同，先迁移表结构再创建索引
+func migrateSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := AutoMigrate(db); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	if err := CreateIndexes(db); err != nil {
+		t.Fatalf("CreateIndexes: %v", err)
+	}
+}
+
+func TestAutoMigrateReplacesGlobalVersionIndex(t *testing.T) {
+	db := testutil.NewDB(t)
+	migrateSchema(t, db)
+	// 还原为旧版本的索引：version列上单独的唯一索引
+	for _, stmt := range []string{
+		"DROP INDEX idx_package_versions_package_version",
+		"CREATE UNIQUE INDEX idx_package_version ON package_versions (version)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrateSchema(t, db)
+	if db.Migrator().HasIndex("package_versions", "idx_package_version") {
+		t.Fatal("legacy single-column version index was not dropped")
+	}
+
+	// 同一版本号可以发布到不同的包，同一个包内仍然唯一
+	insert := "INSERT INTO package_versions (package_id, version, file_size, uploader_id) VALUES (?, ?, 1, 1)"
+	if err := db.Exec(insert, 1, "1.0.0").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(insert, 2, "1.0.0").Error; err != nil {
+		t.Fatalf("same version under another package rejected: %v", err)
+	}
+	if err := db.Exec(insert, 1, "1.0.0").Error; err == nil {
+		t.Fatal("duplicate version within one package accepted")
+	}
+}
+
+func TestMigrateSchemaWithDuplicateVersions(t *testing.T) {
+	db := testutil.NewDB(t)
+	migrateSchema(t, db)
+	if err := db.Exec("DROP INDEX idx_package_versions_package_version").Error; err != nil {
+		t.Fatal(err)
+	}
+	insert := "INSERT INTO package_versions (package_id, version, file_size, uploader_id) VALUES (1, '1.0.0', 1, 1)"
+	for i := 0; i < 2; i++ {
+		if err := db.Exec(insert).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 表结构迁移本身不创建唯一索引，已有重复数据时启动不失败，只跳过该索引
+	migrateSchema(t, db)
+	if db.Migrator().HasIndex("package_versions", "idx_package_versions_package_version") {
+		t.Fatal("unique index should be skipped while duplicates exist")
+	}
+}
+
+func TestBackfillObjectKeys(t *testing.T) {
+	db := testutil.NewDB(t)
+	if err := AutoMigrate(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO packages (id, name, owner_id) VALUES (1, 'app', 1)",
+		// 早期版本只记录目录，已有完整键的版本保持不变
+		"INSERT INTO package_versions (id, package_id, version, file_size, uploader_id, min_io_path) VALUES (1, 1, '1.0.0', 1, 1, 'packages/app/1.0.0')",
+		"INSERT INTO package_versions (id, package_id, version, file_size, uploader_id, min_io_path) VALUES (2, 1, '2.0.0', 1, 1, 'packages/app/app-2.0.0.pkg')",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := BackfillObjectKeys(db); err != nil {
+		t.Fatalf("BackfillObjectKeys: %v", err)
+	}
+
+	want := map[uint]string{1: "packages/app/1.0.0/app-1.0.0.pkg", 2: "packages/app/app-2.0.0.pkg"}
+	for id, key := range want {
+		var got string
+		if err := db.Raw("SELECT min_io_path FROM package_versions WHERE id = ?", id).Scan(&got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got != key {
+			t.Errorf("version %d key = %q, want %q", id, got, key)
+		}
+	}
+}
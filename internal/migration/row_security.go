@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"webservice/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// rowSecurityStatements 开启packages表行级安全的语句，每次启动重新创建策略，可重复执行
+// pkg_access：公开包、当前用户（app.user_id）拥有或作为协作者的包对所有角色可见；应用的连接角色在app.bypass_rls为on时（管理员请求、后台任务）可见全部包，
+// app_user角色不能通过设置app.bypass_rls绕过限制
+// pkg_admin_access：app_admin角色可见全部包
+// FORCE使表的所有者（应用的连接角色）也受策略限制；超级用户和带BYPASSRLS属性的角色始终不受限制
+var rowSecurityStatements = []string{
+	`DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'app_user') THEN
+		CREATE ROLE app_user NOLOGIN;
+	END IF;
+	IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'app_admin') THEN
+		CREATE ROLE app_admin NOLOGIN;
+	END IF;
+END
+$$`,
+	"GRANT SELECT, INSERT, UPDATE, DELETE ON packages TO app_user, app_admin",
+	"ALTER TABLE packages ENABLE ROW LEVEL SECURITY",
+	"ALTER TABLE packages FORCE ROW LEVEL SECURITY",
+	"DROP POLICY IF EXISTS pkg_access ON packages",
+	`CREATE POLICY pkg_access ON packages USING (
+	is_private = false
+	OR owner_id = NULLIF(current_setting('app.user_id', true), '')::bigint
+	OR id IN (SELECT package_id FROM package_collaborators WHERE user_id = NULLIF(current_setting('app.user_id', true), '')::bigint)
+	OR (current_setting('app.bypass_rls', true) = 'on' AND NOT pg_has_role(current_user, 'app_user', 'MEMBER'))
+)`,
+	"DROP POLICY IF EXISTS pkg_admin_access ON packages",
+	"CREATE POLICY pkg_admin_access ON packages TO app_admin USING (true)",
+}
+
+// ConfigureRowLevelSecurity 按配置开启或关闭packages表的行级安全（仅PostgreSQL）
+// 开启后私有包只对所有者、协作者和管理员可见，作为服务层权限检查之外的第二道防线；关闭时移除之前开启的限制，策略保留但不生效
+// 其他数据库不需要迁移，由database.RegisterRowVisibilityCallbacks在应用层限制
+func ConfigureRowLevelSecurity(db *gorm.DB, enabled bool) error {
+	if db.Dialector.Name() != "postgres" {
+		if enabled {
+			logger.Infof("database.row_level_security on %s is enforced in the application layer for packages queries", db.Dialector.Name())
+		}
+		return nil
+	}
+
+	if !enabled {
+		var active bool
+		if err := db.Raw("SELECT relrowsecurity FROM pg_class WHERE oid = 'packages'::regclass").Scan(&active).Error; err != nil {
+			return err
+		}
+		if !active {
+			return nil
+		}
+		if err := db.Exec("ALTER TABLE packages NO FORCE ROW LEVEL SECURITY").Error; err != nil {
+			return err
+		}
+		if err := db.Exec("ALTER TABLE packages DISABLE ROW LEVEL SECURITY").Error; err != nil {
+			return err
+		}
+		logger.Info("Row level security disabled on packages")
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range rowSecurityStatements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Row level security enabled on packages")
+	return nil
+}
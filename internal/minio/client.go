@@ -2,14 +2,17 @@ package minio
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"webservice/internal/config"
 	"webservice/internal/logger"
+	"webservice/internal/metrics"
+	"webservice/internal/requestid"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -20,6 +23,13 @@ type Client struct {
 	client     *minio.Client
 	bucketName string
 	config     config.MinIOConfig
+	namer      ObjectNamer // 新上传对象的命名方案
+	replicas   []*Client   // 只读镜像，RaceDownload时与主节点并发请求
+	cold       *Client     // 冷存储bucket，未配置时为nil
+
+	mirrors *MirrorSelector // 按地域分发下载的镜像，未配置时为nil
+
+	downloads *downloadGroup // 合并同一包版本的并发下载，未开启时为nil
 }
 
 // PackageInfo 包信息
@@ -31,40 +41,158 @@ type PackageInfo struct {
 	ContentType string    `json:"content_type"`
 	ETag        string    `json:"etag"`
 	DownloadURL string    `json:"download_url,omitempty"`
+	Compressed  bool      `json:"compressed"`  // 是否以gzip压缩形式存储
+	StoredSize  int64     `json:"stored_size"` // 实际存储大小（压缩后）
+	ObjectKey   string    `json:"object_key"`  // 存储中的对象键
 }
 
 // UploadOptions 上传选项
 type UploadOptions struct {
 	ContentType string
 	Metadata    map[string]string
+	// FilenameOverride 非空时作为对象的Content-Disposition文件名，直接访问对象时浏览器按该文件名保存
+	FilenameOverride string
 }
 
 // NewClient 创建MinIO客户端
 func NewClient(cfg config.MinIOConfig) (*Client, error) {
-	// 初始化MinIO客户端
-	minioClient, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
-		Secure: cfg.UseSSL,
-		Region: cfg.Region,
-	})
+	namer, err := NewObjectNamer(cfg.ObjectNaming)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create minio client: %w", err)
+		return nil, err
+	}
+
+	minioClient, err := newMinioClient(cfg.Endpoint, cfg.AccessKey, cfg.SecretKey, cfg.UseSSL, cfg.Region)
+	if err != nil {
+		return nil, err
 	}
 
 	client := &Client{
 		client:     minioClient,
 		bucketName: cfg.BucketName,
 		config:     cfg,
+		namer:      namer,
+	}
+
+	// 确保bucket存在，MinIO尚未就绪时按配置重试
+	if err := client.ensureBucketWithRetry(); err != nil {
+		return nil, err
+	}
+
+	// 冷存储与主bucket使用同一连接，只是bucket不同
+	if cfg.ColdTier.Bucket != "" {
+		client.cold = &Client{
+			client:     minioClient,
+			bucketName: cfg.ColdTier.Bucket,
+			config:     cfg,
+			namer:      namer,
+		}
+		if err := client.cold.ensureBucket(); err != nil {
+			return nil, fmt.Errorf("cold tier bucket: %w", err)
+		}
 	}
 
-	// 确保bucket存在
-	if err := client.ensureBucket(); err != nil {
-		return nil, fmt.Errorf("failed to ensure bucket exists: %w", err)
+	if cfg.DownloadCoalescing.Enabled {
+		client.downloads = newDownloadGroup()
+		if client.cold != nil {
+			client.cold.downloads = newDownloadGroup()
+		}
 	}
 
+	// 镜像节点只用于读取，创建失败时跳过，不影响主节点
+	for _, replica := range cfg.Replicas {
+		replicaClient, err := newMinioClient(replica.Endpoint, replica.AccessKey, replica.SecretKey, replica.UseSSL, replica.Region)
+		if err != nil {
+			logger.Warnf("Skipping MinIO replica %s: %v", replica.Endpoint, err)
+			continue
+		}
+		client.replicas = append(client.replicas, &Client{
+			client:     replicaClient,
+			bucketName: cfg.BucketName,
+			config:     cfg,
+			namer:      namer,
+		})
+	}
+
+	// 下载镜像各自使用独立的连接，只用于签发下载URL
+	client.mirrors = newMirrors(cfg, namer)
+
 	return client, nil
 }
 
+// newMinioClient 创建底层MinIO客户端
+func newMinioClient(endpoint, accessKey, secretKey string, useSSL bool, region string) (*minio.Client, error) {
+	// 构建传输层，在访问MinIO时透传请求ID便于跨服务关联
+	baseTransport, err := minio.DefaultTransport(useSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio transport: %w", err)
+	}
+
+	minioClient, err := minio.New(endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure:    useSSL,
+		Region:    region,
+		Transport: &requestid.Transport{Base: baseTransport},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create minio client: %w", err)
+	}
+	return minioClient, nil
+}
+
+// 启动重试的退避间隔
+const (
+	startupRetryInitialDelay = time.Second
+	startupRetryMaxDelay     = 30 * time.Second
+)
+
+// StartupRetryError 重试后仍无法确认bucket存在
+type StartupRetryError struct {
+	Retries int
+	Elapsed time.Duration
+	Err     error
+}
+
+// Error 实现error接口
+func (e *StartupRetryError) Error() string {
+	return fmt.Sprintf("failed to ensure bucket exists after %d retries over %d seconds: %v", e.Retries, int(e.Elapsed.Seconds()), e.Err)
+}
+
+// Unwrap 返回最后一次失败的原因
+func (e *StartupRetryError) Unwrap() error {
+	return e.Err
+}
+
+// ensureBucketWithRetry 确保bucket存在，失败时按指数退避重试（docker-compose中MinIO通常比应用晚几秒就绪）
+// 重试次数达到StartupRetries或总等待时间将超过MaxStartupWait时返回*StartupRetryError
+func (c *Client) ensureBucketWithRetry() error {
+	retries := c.config.StartupRetries
+	if !c.config.StartupRetriesEnabled || retries <= 0 {
+		retries = 0
+	}
+
+	start := time.Now()
+	delay := startupRetryInitialDelay
+	for attempt := 0; ; attempt++ {
+		err := c.ensureBucket()
+		if err == nil {
+			if attempt > 0 {
+				logger.Infof("MinIO became available after %d retries (%s)", attempt, time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		}
+
+		elapsed := time.Since(start)
+		maxWait := c.config.MaxStartupWait
+		if attempt >= retries || (maxWait > 0 && elapsed+delay > maxWait) {
+			return &StartupRetryError{Retries: attempt, Elapsed: elapsed, Err: err}
+		}
+		logger.Warnf("MinIO is not available (attempt %d/%d, elapsed %s), retrying in %s: %v",
+			attempt+1, retries+1, elapsed.Round(time.Millisecond), delay, err)
+		time.Sleep(delay)
+		delay = min(delay*2, startupRetryMaxDelay)
+	}
+}
+
 // ensureBucket 确保bucket存在
 func (c *Client) ensureBucket() error {
 	ctx := context.Background()
@@ -106,15 +234,33 @@ func (c *Client) UploadPackage(ctx context.Context, packageName, version string,
 		UserMetadata: map[string]string{
 			"package-name":    packageName,
 			"package-version": version,
-			"upload-time":     time.Now().Format(time.RFC3339),
+			"upload-time":     time.Now().UTC().Format(time.RFC3339),
 		},
 	}
 
+	if opts.FilenameOverride != "" {
+		uploadOpts.ContentDisposition = AttachmentDisposition(opts.FilenameOverride)
+	}
+
 	// 添加自定义元数据
 	for k, v := range opts.Metadata {
 		uploadOpts.UserMetadata[k] = v
 	}
 
+	// 可选的透明gzip压缩，已压缩格式跳过；调用方的哈希基于未压缩内容计算
+	originalSize := size
+	compressed := false
+	if c.config.CompressArtifacts {
+		reader, size, compressed = prepareCompression(reader, size)
+		if compressed {
+			uploadOpts.UserMetadata[metaCompression] = compressionGzip
+			uploadOpts.UserMetadata[metaUncompressedSize] = strconv.FormatInt(originalSize, 10)
+		}
+	}
+
+	// 单次上传限速，每次上传使用独立的令牌桶
+	reader = NewThrottledReader(ctx, reader, NewBandwidthLimiter(c.config.UploadBandwidthLimitBytesPerSec), ThrottleScopeUpload)
+
 	// 上传文件
 	info, err := c.client.PutObject(ctx, c.bucketName, objectName, reader, size, uploadOpts)
 	if err != nil {
@@ -127,23 +273,31 @@ func (c *Client) UploadPackage(ctx context.Context, packageName, version string,
 		return nil, fmt.Errorf("failed to get object info: %w", err)
 	}
 
-	packageInfo := &PackageInfo{
-		Name:        packageName,
-		Version:     version,
-		Size:        objInfo.Size,
-		UploadTime:  objInfo.LastModified,
-		ContentType: objInfo.ContentType,
-		ETag:        objInfo.ETag,
-	}
+	packageInfo := newPackageInfo(packageName, version, objInfo)
+	packageInfo.ObjectKey = objectName
 
-	logger.Info(fmt.Sprintf("Package uploaded successfully: %s@%s (size: %d bytes)", packageName, version, info.Size))
+	logger.Info(fmt.Sprintf("Package uploaded successfully: %s@%s (size: %d bytes, stored: %d bytes, compressed: %t)",
+		packageName, version, packageInfo.Size, info.Size, compressed))
 	return packageInfo, nil
 }
 
-// DownloadPackage 下载包文件
+// UploadBandwidthLimit 返回单次上传的限速值（字节/秒），0表示不限速
+func (c *Client) UploadBandwidthLimit() int64 {
+	return c.config.UploadBandwidthLimitBytesPerSec
+}
+
+// DownloadPackage 按当前命名方案下载包文件
 func (c *Client) DownloadPackage(ctx context.Context, packageName, version string) (io.ReadCloser, *PackageInfo, error) {
-	objectName := c.buildObjectName(packageName, version)
+	reader, packageInfo, err := c.DownloadObject(ctx, c.buildObjectName(packageName, version))
+	if err != nil {
+		return nil, nil, err
+	}
+	packageInfo.Name, packageInfo.Version = packageName, version
+	return reader, packageInfo, nil
+}
 
+// DownloadObject 按对象键下载包文件，包名和版本取自上传时写入的元数据
+func (c *Client) DownloadObject(ctx context.Context, objectName string) (io.ReadCloser, *PackageInfo, error) {
 	// 获取对象信息
 	objInfo, err := c.client.StatObject(ctx, c.bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
@@ -156,32 +310,148 @@ func (c *Client) DownloadPackage(ctx context.Context, packageName, version strin
 		return nil, nil, fmt.Errorf("failed to download package: %w", err)
 	}
 
-	packageInfo := &PackageInfo{
-		Name:        packageName,
-		Version:     version,
-		Size:        objInfo.Size,
-		UploadTime:  objInfo.LastModified,
-		ContentType: objInfo.ContentType,
-		ETag:        objInfo.ETag,
+	packageInfo := newPackageInfo(lookupMetadata(objInfo.UserMetadata, "package-name"),
+		lookupMetadata(objInfo.UserMetadata, "package-version"), objInfo)
+	packageInfo.ObjectKey = objectName
+
+	// 压缩存储的对象透明解压，返回原始内容
+	if packageInfo.Compressed {
+		reader, err := newGzipReadCloser(object)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress package: %w", err)
+		}
+		return reader, packageInfo, nil
 	}
 
 	return object, packageInfo, nil
 }
 
-// DeletePackage 删除包文件
+// raceWinnerIndex 记录RaceDownload中最先成功响应的节点序号（0为主节点，1起为镜像）
+var raceWinnerIndex = metrics.NewHistogram("minio_race_winner_index",
+	"Index of the MinIO endpoint that answered a raced download first (0 = primary)", 0, 1, 2, 3, 4, 5, 6, 7)
+
+// raceResult 单个节点的下载结果
+type raceResult struct {
+	index  int
+	reader io.ReadCloser
+	info   *PackageInfo
+	cancel context.CancelFunc
+	err    error
+}
+
+// cancelOnClose 关闭读取器时释放对应节点的context
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close 关闭读取器并取消context
+func (r *cancelOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
+
+// RaceDownload 按对象键同时从主节点和所有镜像下载包文件，返回最先成功响应的结果
+// 其余请求会被取消，晚到的成功结果在后台关闭；全部失败时返回汇总的错误
+func (c *Client) RaceDownload(ctx context.Context, objectName string) (io.ReadCloser, *PackageInfo, error) {
+	if len(c.replicas) == 0 {
+		return c.DownloadObject(ctx, objectName)
+	}
+
+	endpoints := append([]*Client{c}, c.replicas...)
+	// 带缓冲，保证落败的goroutine不会因无人接收而阻塞
+	results := make(chan raceResult, len(endpoints))
+	cancels := make([]context.CancelFunc, len(endpoints))
+
+	for i, endpoint := range endpoints {
+		// 每个节点使用独立的context：获胜者的对象读取是惰性的，不能随其他节点一起取消
+		endpointCtx, cancel := context.WithCancel(ctx)
+		cancels[i] = cancel
+		go func(i int, endpoint *Client, endpointCtx context.Context, cancel context.CancelFunc) {
+			reader, info, err := endpoint.DownloadObject(endpointCtx, objectName)
+			results <- raceResult{index: i, reader: reader, info: info, cancel: cancel, err: err}
+		}(i, endpoint, endpointCtx, cancel)
+	}
+
+	var errs []error
+	for received := 0; received < len(endpoints); received++ {
+		result := <-results
+		if result.err != nil {
+			result.cancel()
+			errs = append(errs, fmt.Errorf("endpoint %d: %w", result.index, result.err))
+			continue
+		}
+
+		// 取消其余节点，并在后台关闭晚到的读取器避免连接泄漏
+		for i, cancel := range cancels {
+			if i != result.index {
+				cancel()
+			}
+		}
+		go drainRaceResults(results, len(endpoints)-received-1)
+
+		raceWinnerIndex.Observe(float64(result.index))
+		return &cancelOnClose{ReadCloser: result.reader, cancel: result.cancel}, result.info, nil
+	}
+
+	return nil, nil, fmt.Errorf("all %d endpoints failed: %w", len(endpoints), errors.Join(errs...))
+}
+
+// drainRaceResults 接收落败节点的结果并关闭其读取器
+func drainRaceResults(results <-chan raceResult, remaining int) {
+	for i := 0; i < remaining; i++ {
+		result := <-results
+		if result.reader != nil {
+			result.reader.Close()
+		}
+		result.cancel()
+	}
+}
+
+// CopyObject 在bucket内复制对象（保留元数据），用于包重命名和对象布局迁移
+func (c *Client) CopyObject(ctx context.Context, srcObjectName, dstObjectName string) error {
+	src := minio.CopySrcOptions{
+		Bucket: c.bucketName,
+		Object: srcObjectName,
+	}
+	dst := minio.CopyDestOptions{
+		Bucket: c.bucketName,
+		Object: dstObjectName,
+	}
+
+	if _, err := c.client.CopyObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to copy package: %w", err)
+	}
+	return nil
+}
+
+// RenameObject 在bucket内重命名对象（复制后删除源对象）
+// 复制成功但删除失败时目标对象已可用，返回错误由调用方决定是否清理源对象
+func (c *Client) RenameObject(ctx context.Context, srcObjectName, dstObjectName string) error {
+	if err := c.CopyObject(ctx, srcObjectName, dstObjectName); err != nil {
+		return err
+	}
+	return c.DeleteObject(ctx, srcObjectName)
+}
+
+// DeletePackage 按当前命名方案删除包文件
 func (c *Client) DeletePackage(ctx context.Context, packageName, version string) error {
-	objectName := c.buildObjectName(packageName, version)
+	return c.DeleteObject(ctx, c.buildObjectName(packageName, version))
+}
 
+// DeleteObject 按对象键删除包文件
+func (c *Client) DeleteObject(ctx context.Context, objectName string) error {
 	err := c.client.RemoveObject(ctx, c.bucketName, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete package: %w", err)
 	}
 
-	logger.Info(fmt.Sprintf("Package deleted successfully: %s@%s", packageName, version))
+	logger.Info(fmt.Sprintf("Package object deleted successfully: %s", objectName))
 	return nil
 }
 
-// ListPackageVersions 列出包的所有版本
+// ListPackageVersions 列出包的所有版本（仅识别legacy命名方案的对象）
 func (c *Client) ListPackageVersions(ctx context.Context, packageName string) ([]*PackageInfo, error) {
 	prefix := fmt.Sprintf("packages/%s/", packageName)
 
@@ -217,7 +487,7 @@ func (c *Client) ListPackageVersions(ctx context.Context, packageName string) ([
 	return packages, nil
 }
 
-// ListAllPackages 列出所有包
+// ListAllPackages 列出所有包（仅识别legacy命名方案的对象）
 func (c *Client) ListAllPackages(ctx context.Context) (map[string][]*PackageInfo, error) {
 	prefix := "packages/"
 
@@ -253,13 +523,19 @@ func (c *Client) ListAllPackages(ctx context.Context) (map[string][]*PackageInfo
 	return packages, nil
 }
 
-// GetDownloadURL 获取包的下载URL
+// GetDownloadURL 按当前命名方案获取包的下载URL，响应以 {name}-{version}.pkg 作为附件文件名
 func (c *Client) GetDownloadURL(ctx context.Context, packageName, version string, expiry time.Duration) (string, error) {
-	objectName := c.buildObjectName(packageName, version)
+	return c.GetObjectURL(ctx, c.buildObjectName(packageName, version), expiry, &DownloadURLOptions{
+		Filename:    DownloadFilename(packageName, version),
+		ContentType: "application/octet-stream",
+	})
+}
 
+// GetObjectURL 按对象键生成预签名下载URL，opts可覆盖响应的Content-Disposition和Content-Type
+// 覆盖参数包含在签名中，客户端无法修改
+func (c *Client) GetObjectURL(ctx context.Context, objectName string, expiry time.Duration, opts *DownloadURLOptions) (string, error) {
 	// 生成预签名URL
-	reqParams := make(url.Values)
-	presignedURL, err := c.client.PresignedGetObject(ctx, c.bucketName, objectName, expiry, reqParams)
+	presignedURL, err := c.client.PresignedGetObject(ctx, c.bucketName, objectName, expiry, opts.queryParams())
 	if err != nil {
 		return "", fmt.Errorf("failed to generate download URL: %w", err)
 	}
@@ -269,8 +545,11 @@ func (c *Client) GetDownloadURL(ctx context.Context, packageName, version string
 
 // PackageExists 检查包是否存在
 func (c *Client) PackageExists(ctx context.Context, packageName, version string) (bool, error) {
-	objectName := c.buildObjectName(packageName, version)
+	return c.ObjectExists(ctx, c.buildObjectName(packageName, version))
+}
 
+// ObjectExists 按对象键检查对象是否存在
+func (c *Client) ObjectExists(ctx context.Context, objectName string) (bool, error) {
 	_, err := c.client.StatObject(ctx, c.bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
 		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
@@ -282,13 +561,14 @@ func (c *Client) PackageExists(ctx context.Context, packageName, version string)
 	return true, nil
 }
 
+// ObjectKey 按当前命名方案生成包文件的对象键
+func (c *Client) ObjectKey(packageName, version string) string {
+	return c.buildObjectName(packageName, version)
+}
+
 // buildObjectName 构建对象名称
 func (c *Client) buildObjectName(packageName, version string) string {
-	// 清理包名和版本中的特殊字符
-	cleanPackageName := strings.ReplaceAll(packageName, "/", "_")
-	cleanVersion := strings.ReplaceAll(version, "/", "_")
-
-	return fmt.Sprintf("packages/%s/%s/%s-%s.pkg", cleanPackageName, cleanVersion, cleanPackageName, cleanVersion)
+	return c.namer.ObjectKey(packageName, version)
 }
 
 // extractVersionFromObjectName 从对象名中提取版本信息
@@ -322,12 +602,27 @@ func (c *Client) GetPackageInfo(ctx context.Context, packageName, version string
 		return nil, fmt.Errorf("package not found: %w", err)
 	}
 
-	return &PackageInfo{
+	return newPackageInfo(packageName, version, objInfo), nil
+}
+
+// newPackageInfo 根据对象信息构建包信息，压缩存储时Size为原始大小
+func newPackageInfo(packageName, version string, objInfo minio.ObjectInfo) *PackageInfo {
+	info := &PackageInfo{
 		Name:        packageName,
 		Version:     version,
 		Size:        objInfo.Size,
+		StoredSize:  objInfo.Size,
 		UploadTime:  objInfo.LastModified,
 		ContentType: objInfo.ContentType,
 		ETag:        objInfo.ETag,
-	}, nil
+	}
+
+	if lookupMetadata(objInfo.UserMetadata, metaCompression) == compressionGzip {
+		info.Compressed = true
+		if size, err := strconv.ParseInt(lookupMetadata(objInfo.UserMetadata, metaUncompressedSize), 10, 64); err == nil {
+			info.Size = size
+		}
+	}
+
+	return info
 }
@@ -0,0 +1,197 @@
+package minio
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"webservice/internal/metrics"
+)
+
+// coalescedDownloads 加入了正在进行的同一对象读取、没有单独访问存储的下载数
+var coalescedDownloads = metrics.NewCounterVec("minio_download_coalesced_total",
+	"Downloads served by an in-flight read of the same object instead of a separate storage request")
+
+// coalesceChunkSize 共享读取每次从存储读取的字节数
+const coalesceChunkSize = 32 * 1024
+
+// fetchFunc 从存储打开对象的读取器
+type fetchFunc func(ctx context.Context) (io.ReadCloser, *PackageInfo, error)
+
+// downloadFlight 一次共享的对象读取：内容从存储读出后追加到buf，各读取方按自己的偏移量读取
+type downloadFlight struct {
+	ready chan struct{} // 存储响应（成功或失败）后关闭
+	info  *PackageInfo
+	err   error // 打开对象失败的错误
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     []byte
+	done    bool  // 已读完或读取失败
+	readErr error // 读取中途失败的错误，读完时为nil
+	readers int   // 尚未关闭的读取方
+	cancel  context.CancelFunc
+}
+
+// downloadGroup 合并同一key的并发下载：读取进行期间到达的请求共享同一次存储读取，读完后不保留内容
+// 内容在读取期间保存在内存中，调用方只应对大小受限的对象使用
+type downloadGroup struct {
+	mu      sync.Mutex
+	flights map[string]*downloadFlight
+}
+
+// newDownloadGroup 创建下载合并组
+func newDownloadGroup() *downloadGroup {
+	return &downloadGroup{flights: make(map[string]*downloadFlight)}
+}
+
+// download 返回key对应对象的读取器，已有相同key的读取正在进行时加入该读取，否则调用fetch开始新的读取
+// 共享的读取与调用方的取消信号分离，所有读取方都关闭后才中断
+func (g *downloadGroup) download(ctx context.Context, key string, fetch fetchFunc) (io.ReadCloser, *PackageInfo, error) {
+	g.mu.Lock()
+	flight, inFlight := g.flights[key]
+	if !inFlight {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		flight = &downloadFlight{ready: make(chan struct{}), cancel: cancel}
+		flight.cond = sync.NewCond(&flight.mu)
+		g.flights[key] = flight
+		go g.run(flightCtx, key, flight, fetch)
+	}
+	flight.mu.Lock()
+	flight.readers++
+	flight.mu.Unlock()
+	g.mu.Unlock()
+
+	if inFlight {
+		coalescedDownloads.Inc()
+	}
+
+	reader := &flightReader{group: g, key: key, flight: flight}
+	select {
+	case <-flight.ready:
+	case <-ctx.Done():
+		reader.Close()
+		return nil, nil, ctx.Err()
+	}
+	if flight.err != nil {
+		reader.Close()
+		return nil, nil, flight.err
+	}
+	return reader, flight.info, nil
+}
+
+// run 打开对象并将内容读入共享缓冲区，结束后移除flight，之后的请求重新读取
+func (g *downloadGroup) run(ctx context.Context, key string, flight *downloadFlight, fetch fetchFunc) {
+	defer flight.cancel()
+	defer g.forget(key, flight)
+
+	source, info, err := fetch(ctx)
+	flight.info, flight.err = info, err
+	close(flight.ready)
+	if err != nil {
+		flight.finish(err)
+		return
+	}
+	defer source.Close()
+
+	chunk := make([]byte, coalesceChunkSize)
+	for {
+		n, err := source.Read(chunk)
+		if n > 0 {
+			flight.mu.Lock()
+			flight.buf = append(flight.buf, chunk[:n]...)
+			flight.cond.Broadcast()
+			flight.mu.Unlock()
+		}
+		if err == io.EOF {
+			flight.finish(nil)
+			return
+		}
+		if err != nil {
+			flight.finish(err)
+			return
+		}
+	}
+}
+
+// forget 移除已结束的flight，不影响同一key上新开始的读取
+func (g *downloadGroup) forget(key string, flight *downloadFlight) {
+	g.mu.Lock()
+	if g.flights[key] == flight {
+		delete(g.flights, key)
+	}
+	g.mu.Unlock()
+}
+
+// finish 标记读取结束并唤醒等待数据的读取方
+func (f *downloadFlight) finish(err error) {
+	f.mu.Lock()
+	f.done, f.readErr = true, err
+	f.cond.Broadcast()
+	f.mu.Unlock()
+}
+
+// flightReader 共享读取的一个读取方
+type flightReader struct {
+	group  *downloadGroup
+	key    string
+	flight *downloadFlight
+	offset int
+	closed bool
+}
+
+// Read 读取共享缓冲区中该读取方尚未读取的内容，没有新内容时等待存储读取
+func (r *flightReader) Read(p []byte) (int, error) {
+	f := r.flight
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for r.offset >= len(f.buf) && !f.done && !r.closed {
+		f.cond.Wait()
+	}
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if r.offset < len(f.buf) {
+		n := copy(p, f.buf[r.offset:])
+		r.offset += n
+		return n, nil
+	}
+	if f.readErr != nil {
+		return 0, f.readErr
+	}
+	return 0, io.EOF
+}
+
+// Close 关闭读取方，最后一个读取方在读取完成前关闭时中断存储读取
+func (r *flightReader) Close() error {
+	g, f := r.group, r.flight
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	f.readers--
+	f.cond.Broadcast()
+	if f.readers == 0 && !f.done {
+		// 没有读取方了，不再让新请求加入即将中断的读取
+		if g.flights[r.key] == f {
+			delete(g.flights, r.key)
+		}
+		f.cancel()
+	}
+	return nil
+}
+
+// CoalescedDownload 按对象键下载包文件（与RaceDownload相同），同一key（包名@版本）的并发下载只从存储读取一次，
+// 读取进行期间到达的请求共享读出的内容；未开启download_coalescing或size超过max_object_bytes时直接调用RaceDownload
+func (c *Client) CoalescedDownload(ctx context.Context, key, objectName string, size int64) (io.ReadCloser, *PackageInfo, error) {
+	if c.downloads == nil || size > c.config.DownloadCoalescing.MaxObjectBytes {
+		return c.RaceDownload(ctx, objectName)
+	}
+	return c.downloads.download(ctx, key, func(ctx context.Context) (io.ReadCloser, *PackageInfo, error) {
+		return c.RaceDownload(ctx, objectName)
+	})
+}
@@ -0,0 +1,181 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowReader 在release关闭前阻塞读取，模拟响应缓慢的上游存储
+type slowReader struct {
+	io.Reader
+	release <-chan struct{}
+	ctx     context.Context
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.release:
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	}
+	return r.Reader.Read(p)
+}
+
+func (r *slowReader) Close() error { return nil }
+
+func TestCoalescedDownloadFetchesOnceForConcurrentMisses(t *testing.T) {
+	const readers = 50
+	content := []byte(strings.Repeat("package bytes\n", 10000))
+	release := make(chan struct{})
+	var fetches int32
+	fetch := func(ctx context.Context) (io.ReadCloser, *PackageInfo, error) {
+		atomic.AddInt32(&fetches, 1)
+		return &slowReader{Reader: bytes.NewReader(content), release: release, ctx: ctx}, &PackageInfo{Size: int64(len(content))}, nil
+	}
+
+	group := newDownloadGroup()
+	var started, wg sync.WaitGroup
+	started.Add(readers)
+	results := make([][]byte, readers)
+	errs := make([]error, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			reader, _, err := group.download(context.Background(), "demo@1.0.0", fetch)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer reader.Close()
+			results[i], errs[i] = io.ReadAll(reader)
+		}(i)
+	}
+	started.Wait()
+	// 等所有请求都加入读取后再让上游返回内容
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		group.mu.Lock()
+		flight := group.flights["demo@1.0.0"]
+		group.mu.Unlock()
+		joined := 0
+		if flight != nil {
+			flight.mu.Lock()
+			joined = flight.readers
+			flight.mu.Unlock()
+		}
+		if joined == readers {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d downloads joined the in-flight read", joined, readers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("upstream fetched %d times, want 1", got)
+	}
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatalf("download %d failed: %v", i, errs[i])
+		}
+		if !bytes.Equal(results[i], content) {
+			t.Fatalf("download %d got %d bytes, want the full %d-byte object", i, len(results[i]), len(content))
+		}
+	}
+}
+
+func TestCoalescedDownloadFetchesAgainAfterCompletion(t *testing.T) {
+	var fetches int32
+	fetch := func(ctx context.Context) (io.ReadCloser, *PackageInfo, error) {
+		atomic.AddInt32(&fetches, 1)
+		return io.NopCloser(strings.NewReader("content")), &PackageInfo{}, nil
+	}
+
+	group := newDownloadGroup()
+	for i := 0; i < 2; i++ {
+		reader, _, err := group.download(context.Background(), "demo@1.0.0", fetch)
+		if err != nil {
+			t.Fatalf("download %d: %v", i, err)
+		}
+		if _, err := io.ReadAll(reader); err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+		reader.Close()
+		waitForFlightsDone(t, group)
+	}
+	// 读取结束后不保留内容，下一次下载重新访问存储
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Errorf("upstream fetched %d times for two sequential downloads, want 2", got)
+	}
+}
+
+// waitForFlightsDone 等待所有共享读取结束并从组中移除
+func waitForFlightsDone(t *testing.T, group *downloadGroup) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		group.mu.Lock()
+		remaining := len(group.flights)
+		group.mu.Unlock()
+		if remaining == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d reads still in flight", remaining)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoalescedDownloadSharesFetchError(t *testing.T) {
+	fetchErr := errors.New("object not found")
+	group := newDownloadGroup()
+	_, _, err := group.download(context.Background(), "demo@1.0.0", func(ctx context.Context) (io.ReadCloser, *PackageInfo, error) {
+		return nil, nil, fetchErr
+	})
+	if !errors.Is(err, fetchErr) {
+		t.Fatalf("err = %v, want %v", err, fetchErr)
+	}
+}
+
+func TestCoalescedDownloadCancelsFetchWhenLastReaderCloses(t *testing.T) {
+	fetchCtx := make(chan context.Context, 1)
+	fetch := func(ctx context.Context) (io.ReadCloser, *PackageInfo, error) {
+		fetchCtx <- ctx
+		return &slowReader{Reader: strings.NewReader("content"), release: make(chan struct{}), ctx: ctx}, &PackageInfo{}, nil
+	}
+
+	group := newDownloadGroup()
+	first, _, err := group.download(context.Background(), "demo@1.0.0", fetch)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	second, _, err := group.download(context.Background(), "demo@1.0.0", fetch)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	ctx := <-fetchCtx
+
+	first.Close()
+	if ctx.Err() != nil {
+		t.Fatal("upstream read was cancelled while a reader was still open")
+	}
+	second.Close()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream read was not cancelled after the last reader closed")
+	}
+}
@@ -0,0 +1,53 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+
+	"webservice/internal/config"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// 冷存储版本的下载方式
+const (
+	ColdDownloadTransparent = "transparent" // 直接从冷存储bucket读取
+	ColdDownloadRestore     = "restore"     // 先异步移回主bucket，移回前下载返回202
+)
+
+// HasColdTier 是否配置了冷存储bucket
+func (c *Client) HasColdTier() bool {
+	return c.cold != nil
+}
+
+// ColdTierConfig 获取冷存储配置
+func (c *Client) ColdTierConfig() config.ColdTierConfig {
+	return c.config.ColdTier
+}
+
+// Cold 获取冷存储bucket的客户端，未配置冷存储时返回自身
+// 冷存储不使用只读镜像，下载时直接读取
+func (c *Client) Cold() *Client {
+	if c.cold == nil {
+		return c
+	}
+	return c.cold
+}
+
+// TransferObject 将对象以相同的对象键复制到dst所在的bucket（保留元数据），不删除源对象
+// 冷热存储之间迁移时由调用方在更新记录后删除源对象，失败时删除已复制的目标对象
+func (c *Client) TransferObject(ctx context.Context, dst *Client, objectName string) error {
+	src := minio.CopySrcOptions{
+		Bucket: c.bucketName,
+		Object: objectName,
+	}
+	dstOpts := minio.CopyDestOptions{
+		Bucket: dst.bucketName,
+		Object: objectName,
+	}
+
+	if _, err := c.client.CopyObject(ctx, dstOpts, src); err != nil {
+		return fmt.Errorf("failed to copy %s from %s to %s: %w", objectName, c.bucketName, dst.bucketName, err)
+	}
+	return nil
+}
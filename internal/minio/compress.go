@@ -0,0 +1,102 @@
+package minio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// metaCompression 存储压缩算法的对象元数据键
+	metaCompression = "compression"
+	// metaUncompressedSize 原始（未压缩）大小的对象元数据键
+	metaUncompressedSize = "uncompressed-size"
+	// compressionGzip gzip压缩
+	compressionGzip = "gzip"
+)
+
+// compressedMagics 常见已压缩格式的文件头
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b},                       // gzip
+	{'P', 'K', 0x03, 0x04},             // zip / jar / whl
+	{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+	{'B', 'Z', 'h'},                    // bzip2
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	{0x04, 0x22, 0x4d, 0x18},           // lz4
+	{0x89, 'P', 'N', 'G'},              // png
+	{0xff, 0xd8, 0xff},                 // jpeg
+}
+
+// isAlreadyCompressed 根据文件头判断内容是否已经是压缩格式
+func isAlreadyCompressed(header []byte) bool {
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(header, magic) {
+			return true
+		}
+	}
+
+	contentType := http.DetectContentType(header)
+	return strings.HasPrefix(contentType, "image/") ||
+		strings.HasPrefix(contentType, "video/") ||
+		strings.HasPrefix(contentType, "audio/") ||
+		contentType == "application/zip" ||
+		contentType == "application/x-gzip"
+}
+
+// prepareCompression 探测内容类型，可压缩时返回gzip压缩后的流（大小未知，返回-1）
+// 不可压缩时返回原始流及原始大小
+func prepareCompression(reader io.Reader, size int64) (io.Reader, int64, bool) {
+	buffered := bufio.NewReaderSize(reader, 512)
+	header, _ := buffered.Peek(512)
+	if isAlreadyCompressed(header) {
+		return buffered, size, false
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		if _, err := io.Copy(gz, buffered); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(gz.Close())
+	}()
+
+	return pr, -1, true
+}
+
+// gzipReadCloser 解压读取器，关闭时同时关闭底层对象
+type gzipReadCloser struct {
+	*gzip.Reader
+	underlying io.Closer
+}
+
+// Close 关闭解压读取器及底层对象
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.underlying.Close()
+}
+
+// newGzipReadCloser 包装压缩存储的对象为透明解压的读取器
+func newGzipReadCloser(rc io.ReadCloser) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: gz, underlying: rc}, nil
+}
+
+// lookupMetadata 忽略大小写读取对象用户元数据
+func lookupMetadata(metadata map[string]string, key string) string {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package minio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	original := []byte(strings.Repeat("package content that compresses well\n", 1000))
+
+	reader, size, compressed := prepareCompression(bytes.NewReader(original), int64(len(original)))
+	if !compressed {
+		t.Fatal("compressible content was not compressed")
+	}
+	if size != -1 {
+		t.Errorf("size = %d, want -1 for a compressed stream", size)
+	}
+	stored, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read compressed stream: %v", err)
+	}
+	if len(stored) >= len(original) {
+		t.Errorf("stored object is %d bytes, want it smaller than the original %d bytes", len(stored), len(original))
+	}
+
+	rc, err := newGzipReadCloser(io.NopCloser(bytes.NewReader(stored)))
+	if err != nil {
+		t.Fatalf("newGzipReadCloser: %v", err)
+	}
+	defer rc.Close()
+	restored, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if !bytes.Equal(restored, original) {
+		t.Error("decompressed content does not match the original")
+	}
+}
+
+func TestCompressionSkipsCompressedContent(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte(strings.Repeat("a", 4096)))
+	w.Close()
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "gzip", content: gz.Bytes()},
+		{name: "zip", content: append([]byte{'P', 'K', 0x03, 0x04}, make([]byte, 100)...)},
+		{name: "png", content: append([]byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}, make([]byte, 100)...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, size, compressed := prepareCompression(bytes.NewReader(tt.content), int64(len(tt.content)))
+			if compressed {
+				t.Fatal("already-compressed content was compressed again")
+			}
+			if size != int64(len(tt.content)) {
+				t.Errorf("size = %d, want %d", size, len(tt.content))
+			}
+			got, _ := io.ReadAll(reader)
+			if !bytes.Equal(got, tt.content) {
+				t.Error("content was modified")
+			}
+		})
+	}
+}
+
+func TestCompressionShortContent(t *testing.T) {
+	// 内容不足512字节时Peek返回EOF，仍需完整读出
+	original := []byte("short text")
+	reader, _, compressed := prepareCompression(bytes.NewReader(original), int64(len(original)))
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressed {
+		rc, err := newGzipReadCloser(io.NopCloser(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ = io.ReadAll(rc)
+	}
+	if !bytes.Equal(data, original) {
+		t.Errorf("round trip = %q, want %q", data, original)
+	}
+}
@@ -0,0 +1,85 @@
+package minio
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// DownloadURLOptions 预签名下载URL的响应头覆盖，由存储在响应中返回
+type DownloadURLOptions struct {
+	Filename    string // 非空时设置response-content-disposition，浏览器按该文件名保存
+	ContentType string // 非空时设置response-content-type
+}
+
+// DownloadFilename 包文件下载时的默认文件名：{name}-{version}.pkg
+func DownloadFilename(packageName, version string) string {
+	return packageName + "-" + version + ".pkg"
+}
+
+// AttachmentDisposition 生成附件形式的Content-Disposition值
+// filename使用带引号的ASCII形式（非ASCII字符替换为_），包含非ASCII字符时额外附带RFC 5987编码的filename*
+func AttachmentDisposition(filename string) string {
+	var ascii strings.Builder
+	isASCII := true
+	for _, r := range filename {
+		switch {
+		case r >= utf8.RuneSelf || r < 0x20 || r == 0x7f:
+			isASCII = false
+			ascii.WriteByte('_')
+		case r == '"' || r == '\\':
+			ascii.WriteByte('\\')
+			ascii.WriteRune(r)
+		default:
+			ascii.WriteRune(r)
+		}
+	}
+
+	value := `attachment; filename="` + ascii.String() + `"`
+	if !isASCII {
+		value += "; filename*=UTF-8''" + encodeExtValue(filename)
+	}
+	return value
+}
+
+// encodeExtValue 按RFC 5987对ext-value进行百分号编码，attr-char以外的字节全部编码
+// url.PathEscape会保留:、=、@等不属于attr-char的字符，不能直接使用
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
+
+// isAttrChar RFC 5987中的attr-char
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// queryParams 转换为预签名URL的查询参数
+func (o *DownloadURLOptions) queryParams() url.Values {
+	params := make(url.Values)
+	if o == nil {
+		return params
+	}
+	if o.Filename != "" {
+		params.Set("response-content-disposition", AttachmentDisposition(o.Filename))
+	}
+	if o.ContentType != "" {
+		params.Set("response-content-type", o.ContentType)
+	}
+	return params
+}
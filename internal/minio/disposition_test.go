@@ -0,0 +1,63 @@
+package minio
+
+import (
+	"mime"
+	"net/url"
+	"testing"
+)
+
+func TestAttachmentDisposition(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"ascii", "app-1.0.0.pkg", `attachment; filename="app-1.0.0.pkg"`},
+		{"quote and backslash", `say"hi\-1.0.0.pkg`, `attachment; filename="say\"hi\\-1.0.0.pkg"`},
+		{"non-ascii", "包-1.0.0.pkg", `attachment; filename="_-1.0.0.pkg"; filename*=UTF-8''%E5%8C%85-1.0.0.pkg`},
+		{"accented", "café-1.0.0.pkg", `attachment; filename="caf_-1.0.0.pkg"; filename*=UTF-8''caf%C3%A9-1.0.0.pkg`},
+		{"non-ascii with quote", `naïve"-1.0.pkg`, `attachment; filename="na_ve\"-1.0.pkg"; filename*=UTF-8''na%C3%AFve%22-1.0.pkg`},
+		{"control character", "app\n-1.0.0.pkg", `attachment; filename="app_-1.0.0.pkg"; filename*=UTF-8''app%0A-1.0.0.pkg`},
+		// url.PathEscape保留的:=@;'等字符不属于attr-char，必须编码
+		{"non attr-char", "ü:=@;'() .pkg", `attachment; filename="_:=@;'() .pkg"; filename*=UTF-8''%C3%BC%3A%3D%40%3B%27%28%29%20.pkg`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AttachmentDisposition(tt.filename)
+			if got != tt.want {
+				t.Errorf("AttachmentDisposition(%q) = %s, want %s", tt.filename, got, tt.want)
+			}
+			// 按RFC 6266解析后得到原始文件名：有filename*时优先使用
+			disposition, params, err := mime.ParseMediaType(got)
+			if err != nil {
+				t.Fatalf("ParseMediaType(%s): %v", got, err)
+			}
+			if disposition != "attachment" || params["filename"] != tt.filename {
+				t.Errorf("parsed = %s filename %q, want attachment %q", disposition, params["filename"], tt.filename)
+			}
+		})
+	}
+}
+
+func TestDownloadURLOptionsQueryParams(t *testing.T) {
+	params := (&DownloadURLOptions{Filename: "包-1.0.0.pkg", ContentType: "application/octet-stream"}).queryParams()
+	if got, want := params.Get("response-content-disposition"), AttachmentDisposition("包-1.0.0.pkg"); got != want {
+		t.Errorf("response-content-disposition = %s, want %s", got, want)
+	}
+	if got := params.Get("response-content-type"); got != "application/octet-stream" {
+		t.Errorf("response-content-type = %s", got)
+	}
+	// 编码为预签名URL的查询参数后可以原样解码
+	decoded, err := url.ParseQuery(params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.Get("response-content-disposition"); got != params.Get("response-content-disposition") {
+		t.Errorf("decoded disposition = %s, want %s", got, params.Get("response-content-disposition"))
+	}
+
+	var none *DownloadURLOptions
+	if params := none.queryParams(); len(params) != 0 {
+		t.Errorf("nil options params = %v, want none", params)
+	}
+}
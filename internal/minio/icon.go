@@ -0,0 +1,43 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// iconObjectPrefix 包图标对象的前缀，与包文件路径隔离
+const iconObjectPrefix = "package-icons/"
+
+// IconObjectKey 包图标的对象键，按包ID和内容哈希命名，包重命名后不变，替换图标时写入新对象
+func IconObjectKey(packageID uint, etag string) string {
+	return fmt.Sprintf("%s%d/%s", iconObjectPrefix, packageID, etag)
+}
+
+// PutIcon 写入包图标
+func (c *Client) PutIcon(ctx context.Context, objectName string, data []byte, contentType string) error {
+	_, err := c.client.PutObject(ctx, c.bucketName, objectName, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to upload icon: %w", err)
+	}
+	return nil
+}
+
+// GetIcon 读取包图标，图标文件很小，直接读入内存
+func (c *Client) GetIcon(ctx context.Context, objectName string) ([]byte, error) {
+	object, err := c.client.GetObject(ctx, c.bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read icon: %w", err)
+	}
+	return data, nil
+}
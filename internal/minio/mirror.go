@@ -0,0 +1,306 @@
+package minio
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+)
+
+// 镜像熔断状态
+const (
+	CircuitClosed   = "closed"    // 正常，下载URL可以由该镜像签发
+	CircuitOpen     = "open"      // 连续失败已熔断，熔断期内跳过该镜像
+	CircuitHalfOpen = "half-open" // 熔断期已过，下一次请求或健康检查成功后恢复
+)
+
+// mirrorHealthTimeout 后台检查单个镜像可用性的超时时间
+const mirrorHealthTimeout = 5 * time.Second
+
+// mirrorSelectionTotal 按镜像名称统计签发的下载URL，回源主节点时标签为primary
+var mirrorSelectionTotal = metrics.NewCounterVec("mirror_selection_total",
+	"Download URLs issued per mirror (primary = no mirror selected)", "mirror")
+
+// countryRegions 国家代码（ISO 3166-1 alpha-2）到镜像地域的对应关系，未列出的国家不选择镜像
+var countryRegions = map[string]string{
+	// 亚洲
+	"CN": "asia", "HK": "asia", "MO": "asia", "TW": "asia", "JP": "asia", "KR": "asia", "MN": "asia",
+	"SG": "asia", "MY": "asia", "TH": "asia", "VN": "asia", "PH": "asia", "ID": "asia", "KH": "asia",
+	"LA": "asia", "MM": "asia", "BN": "asia", "IN": "asia", "PK": "asia", "BD": "asia", "LK": "asia",
+	"NP": "asia", "KZ": "asia", "UZ": "asia", "AE": "asia", "SA": "asia", "IL": "asia", "TR": "asia",
+	"QA": "asia", "KW": "asia", "BH": "asia", "OM": "asia", "JO": "asia", "IR": "asia", "IQ": "asia",
+	// 欧洲
+	"GB": "europe", "IE": "europe", "FR": "europe", "DE": "europe", "NL": "europe", "BE": "europe",
+	"LU": "europe", "CH": "europe", "AT": "europe", "IT": "europe", "ES": "europe", "PT": "europe",
+	"SE": "europe", "NO": "europe", "DK": "europe", "FI": "europe", "IS": "europe", "PL": "europe",
+	"CZ": "europe", "SK": "europe", "HU": "europe", "RO": "europe", "BG": "europe", "GR": "europe",
+	"HR": "europe", "SI": "europe", "RS": "europe", "EE": "europe", "LV": "europe", "LT": "europe",
+	"UA": "europe", "BY": "europe", "RU": "europe",
+	// 北美洲
+	"US": "north-america", "CA": "north-america", "MX": "north-america", "GT": "north-america",
+	"CR": "north-america", "PA": "north-america", "CU": "north-america", "DO": "north-america",
+	"PR": "north-america", "JM": "north-america",
+	// 南美洲
+	"BR": "south-america", "AR": "south-america", "CL": "south-america", "CO": "south-america",
+	"PE": "south-america", "VE": "south-america", "EC": "south-america", "UY": "south-america",
+	"PY": "south-america", "BO": "south-america",
+	// 大洋洲
+	"AU": "oceania", "NZ": "oceania", "FJ": "oceania", "PG": "oceania",
+	// 非洲
+	"ZA": "africa", "EG": "africa", "NG": "africa", "KE": "africa", "MA": "africa", "DZ": "africa",
+	"TN": "africa", "GH": "africa", "ET": "africa", "TZ": "africa",
+}
+
+// CountryRegion 返回国家代码对应的镜像地域，未知国家返回空字符串
+func CountryRegion(countryCode string) string {
+	return countryRegions[strings.ToUpper(countryCode)]
+}
+
+// MirrorStatus 镜像的配置和熔断状态
+type MirrorStatus struct {
+	Name                string     `json:"name"`
+	Endpoint            string     `json:"endpoint"`
+	Region              string     `json:"region"`
+	Priority            int        `json:"priority"`
+	State               string     `json:"state"` // closed, open, half-open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"` // 熔断期结束、允许重新尝试的时间
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// circuitBreaker 单个镜像的熔断器：连续失败达到阈值后熔断，熔断期过后允许重新尝试，成功一次即恢复
+type circuitBreaker struct {
+	threshold int
+	openFor   time.Duration
+
+	mu            sync.Mutex
+	state         string
+	failures      int
+	lastError     string
+	openedAt      time.Time
+	lastCheckedAt time.Time
+}
+
+// allow 判断当前是否可以使用该镜像，熔断期已过时转为half-open
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen {
+		if now.Sub(b.openedAt) < b.openFor {
+			return false
+		}
+		b.state = CircuitHalfOpen
+	}
+	return true
+}
+
+// record 记录一次请求结果，返回熔断状态是否发生变化
+func (b *circuitBreaker) record(err error, now time.Time) (changed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastCheckedAt = now
+	previous := b.state
+	if err == nil {
+		b.state, b.failures, b.lastError = CircuitClosed, 0, ""
+		return previous != CircuitClosed
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	// half-open时的尝试失败直接重新熔断
+	if b.state == CircuitHalfOpen || (b.state == CircuitClosed && b.failures >= b.threshold) {
+		b.state = CircuitOpen
+		b.openedAt = now
+	}
+	return previous != b.state
+}
+
+// mirror 一个下载镜像
+type mirror struct {
+	config  config.MirrorConfig
+	client  *Client
+	breaker *circuitBreaker
+}
+
+// MirrorSelector 按客户端所在地域选择下载镜像，跳过已熔断的镜像
+type MirrorSelector struct {
+	mirrors []*mirror // 按优先级排序
+}
+
+// newMirrorSelector 创建镜像选择器，镜像按优先级排序（相同时保持配置顺序）
+func newMirrorSelector(mirrors []*mirror) *MirrorSelector {
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		return mirrors[i].config.Priority < mirrors[j].config.Priority
+	})
+	return &MirrorSelector{mirrors: mirrors}
+}
+
+// candidates 返回与国家同地域的镜像，按优先级排序；未知国家没有候选镜像
+func (s *MirrorSelector) candidates(countryCode string) []*mirror {
+	region := CountryRegion(countryCode)
+	if region == "" {
+		return nil
+	}
+	var result []*mirror
+	for _, m := range s.mirrors {
+		if strings.EqualFold(m.config.Region, region) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// SelectMirror 返回离国家最近且未熔断的镜像，没有可用镜像时返回nil（使用主节点）
+func (s *MirrorSelector) SelectMirror(countryCode string) *config.MirrorConfig {
+	if s == nil {
+		return nil
+	}
+	now := time.Now()
+	for _, m := range s.candidates(countryCode) {
+		if m.breaker.allow(now) {
+			cfg := m.config
+			return &cfg
+		}
+	}
+	return nil
+}
+
+// recordResult 记录镜像的请求结果，熔断状态变化时记录日志
+func (s *MirrorSelector) recordResult(m *mirror, err error) {
+	if !m.breaker.record(err, time.Now()) {
+		return
+	}
+	if err != nil {
+		logger.Warnf("Mirror %s circuit opened: %v", m.config.Name, err)
+		return
+	}
+	logger.Infof("Mirror %s circuit closed", m.config.Name)
+}
+
+// CheckHealth 检查所有镜像的bucket是否可访问并更新熔断状态，熔断期内的镜像不检查
+func (s *MirrorSelector) CheckHealth(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	for _, m := range s.mirrors {
+		if !m.breaker.allow(now) {
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, mirrorHealthTimeout)
+		err := m.client.Ping(checkCtx)
+		cancel()
+		s.recordResult(m, err)
+	}
+}
+
+// Status 返回各镜像的熔断状态，按优先级排序
+func (s *MirrorSelector) Status() []MirrorStatus {
+	if s == nil {
+		return []MirrorStatus{}
+	}
+	statuses := make([]MirrorStatus, 0, len(s.mirrors))
+	for _, m := range s.mirrors {
+		b := m.breaker
+		b.mu.Lock()
+		status := MirrorStatus{
+			Name:                m.config.Name,
+			Endpoint:            m.config.Endpoint,
+			Region:              m.config.Region,
+			Priority:            m.config.Priority,
+			State:               b.state,
+			ConsecutiveFailures: b.failures,
+			LastError:           b.lastError,
+		}
+		if b.state != CircuitClosed {
+			openedAt, retryAt := b.openedAt, b.openedAt.Add(b.openFor)
+			status.OpenedAt, status.RetryAt = &openedAt, &retryAt
+		}
+		if !b.lastCheckedAt.IsZero() {
+			checkedAt := b.lastCheckedAt
+			status.LastCheckedAt = &checkedAt
+		}
+		b.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Mirrors 返回下载镜像选择器，未配置镜像时为nil
+func (c *Client) Mirrors() *MirrorSelector {
+	return c.mirrors
+}
+
+// GetMirroredObjectURL 为国家所在地域最近的可用镜像生成预签名下载URL
+// 镜像签发失败时计入熔断并尝试同地域的下一个镜像，均不可用时由主节点签发
+func (c *Client) GetMirroredObjectURL(ctx context.Context, countryCode, objectName string, expiry time.Duration, opts *DownloadURLOptions) (string, error) {
+	if c.mirrors != nil {
+		now := time.Now()
+		for _, m := range c.mirrors.candidates(countryCode) {
+			if !m.breaker.allow(now) {
+				continue
+			}
+			url, err := m.client.GetObjectURL(ctx, objectName, expiry, opts)
+			c.mirrors.recordResult(m, err)
+			if err != nil {
+				continue
+			}
+			mirrorSelectionTotal.Inc(m.config.Name)
+			return url, nil
+		}
+	}
+
+	url, err := c.GetObjectURL(ctx, objectName, expiry, opts)
+	if err != nil {
+		return "", err
+	}
+	mirrorSelectionTotal.Inc("primary")
+	return url, nil
+}
+
+// newMirrors 为每个镜像创建独立的MinIO客户端，创建失败的镜像跳过；未配置镜像时返回nil
+func newMirrors(cfg config.MinIOConfig, namer ObjectNamer) *MirrorSelector {
+	if len(cfg.Mirrors) == 0 {
+		return nil
+	}
+	threshold := cfg.MirrorHealth.FailureThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	var mirrors []*mirror
+	for _, mc := range cfg.Mirrors {
+		if mc.Name == "" {
+			mc.Name = mc.Endpoint
+		}
+		mirrorClient, err := newMinioClient(mc.Endpoint, mc.AccessKey, mc.SecretKey, mc.UseSSL, mc.BucketRegion)
+		if err != nil {
+			logger.Warnf("Skipping download mirror %s: %v", mc.Name, err)
+			continue
+		}
+		mirrors = append(mirrors, &mirror{
+			config: mc,
+			client: &Client{
+				client:     mirrorClient,
+				bucketName: cfg.BucketName,
+				config:     cfg,
+				namer:      namer,
+			},
+			breaker: &circuitBreaker{
+				threshold: threshold,
+				openFor:   cfg.MirrorHealth.OpenDuration,
+				state:     CircuitClosed,
+			},
+		})
+	}
+	return newMirrorSelector(mirrors)
+}
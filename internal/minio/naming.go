@@ -0,0 +1,72 @@
+package minio
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 对象命名方案
+const (
+	// NamingLegacy packages/{name}/{version}/{name}-{version}.pkg（默认，与早期版本一致）
+	NamingLegacy = "legacy"
+	// NamingFlat packages/{name}/{name}-{version}.pkg，每个包只有一层目录
+	NamingFlat = "flat"
+	// NamingHashSharded packages/{hh}/{hh}/{name}/{version}/{name}-{version}.pkg，按包名哈希分散前缀，提高大量包时的列举性能
+	NamingHashSharded = "hash-sharded"
+)
+
+// ObjectNamer 根据包名和版本生成对象键
+// 已上传版本的对象键保存在PackageVersion.MinIOPath中，读取时直接使用该值，因此更换方案不影响已有对象
+type ObjectNamer interface {
+	ObjectKey(packageName, version string) string
+}
+
+// NewObjectNamer 根据方案名称创建对象命名器，为空时使用legacy
+func NewObjectNamer(scheme string) (ObjectNamer, error) {
+	switch scheme {
+	case "", NamingLegacy:
+		return legacyNamer{}, nil
+	case NamingFlat:
+		return flatNamer{}, nil
+	case NamingHashSharded:
+		return hashShardedNamer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown object naming scheme: %s", scheme)
+	}
+}
+
+// legacyNamer 按包名/版本分层的命名方案
+type legacyNamer struct{}
+
+// ObjectKey 生成对象键
+func (legacyNamer) ObjectKey(packageName, version string) string {
+	name, ver := cleanSegment(packageName), cleanSegment(version)
+	return fmt.Sprintf("packages/%s/%s/%s-%s.pkg", name, ver, name, ver)
+}
+
+// flatNamer 每个包一层目录的命名方案
+type flatNamer struct{}
+
+// ObjectKey 生成对象键
+func (flatNamer) ObjectKey(packageName, version string) string {
+	name, ver := cleanSegment(packageName), cleanSegment(version)
+	return fmt.Sprintf("packages/%s/%s-%s.pkg", name, name, ver)
+}
+
+// hashShardedNamer 按包名哈希分片的命名方案
+type hashShardedNamer struct{}
+
+// ObjectKey 生成对象键
+func (hashShardedNamer) ObjectKey(packageName, version string) string {
+	name, ver := cleanSegment(packageName), cleanSegment(version)
+	sum := sha256.Sum256([]byte(name))
+	shard := hex.EncodeToString(sum[:2])
+	return fmt.Sprintf("packages/%s/%s/%s/%s/%s-%s.pkg", shard[:2], shard[2:], name, ver, name, ver)
+}
+
+// cleanSegment 清理包名和版本中的特殊字符
+func cleanSegment(s string) string {
+	return strings.ReplaceAll(s, "/", "_")
+}
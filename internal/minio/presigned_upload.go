@@ -0,0 +1,93 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"webservice/internal/logger"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// stagingPrefix 预签名上传的暂存对象前缀，上传完成并校验后才复制到正式的对象键
+const stagingPrefix = "uploads/"
+
+// StagingObjectKey 预签名上传会话的暂存对象键
+// 客户端直接写入暂存对象，不会覆盖已发布版本的文件
+func StagingObjectKey(uploadID string) string {
+	return stagingPrefix + uploadID
+}
+
+// GetUploadURL 生成预签名上传URL，客户端使用PUT请求直接上传到对象
+func (c *Client) GetUploadURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) {
+	presignedURL, err := c.client.PresignedPutObject(ctx, c.bucketName, objectName, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate upload URL: %w", err)
+	}
+	return presignedURL.String(), nil
+}
+
+// StatObject 获取对象的大小和ETag，对象不存在时返回的错误包含not found
+func (c *Client) StatObject(ctx context.Context, objectName string) (*PackageInfo, error) {
+	objInfo, err := c.client.StatObject(ctx, c.bucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("object not found: %s", objectName)
+		}
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	info := newPackageInfo(lookupMetadata(objInfo.UserMetadata, "package-name"),
+		lookupMetadata(objInfo.UserMetadata, "package-version"), objInfo)
+	info.ObjectKey = objectName
+	return info, nil
+}
+
+// PromoteUpload 将预签名上传的暂存对象复制到包版本的正式对象键并删除暂存对象
+// 复制时写入与UploadPackage相同的元数据，暂存对象删除失败只记录警告
+func (c *Client) PromoteUpload(ctx context.Context, stagingKey, packageName, version string, opts *UploadOptions) (*PackageInfo, error) {
+	objectName := c.buildObjectName(packageName, version)
+
+	dst := minio.CopyDestOptions{
+		Bucket:          c.bucketName,
+		Object:          objectName,
+		ReplaceMetadata: true,
+		UserMetadata: map[string]string{
+			"package-name":    packageName,
+			"package-version": version,
+			"upload-time":     time.Now().UTC().Format(time.RFC3339),
+		},
+		ContentType: "application/octet-stream",
+	}
+	if opts != nil {
+		if opts.ContentType != "" {
+			dst.ContentType = opts.ContentType
+		}
+		if opts.FilenameOverride != "" {
+			dst.ContentDisposition = AttachmentDisposition(opts.FilenameOverride)
+		}
+		for k, v := range opts.Metadata {
+			dst.UserMetadata[k] = v
+		}
+	}
+	src := minio.CopySrcOptions{
+		Bucket: c.bucketName,
+		Object: stagingKey,
+	}
+
+	// 单次CopyObject最大支持5GiB，ComposeObject对大对象按分片复制
+	if _, err := c.client.ComposeObject(ctx, dst, src); err != nil {
+		return nil, fmt.Errorf("failed to copy uploaded object: %w", err)
+	}
+	if err := c.DeleteObject(ctx, stagingKey); err != nil {
+		logger.Warnf("Failed to delete staging object %s: %v", stagingKey, err)
+	}
+
+	info, err := c.StatObject(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
+	info.Name, info.Version = packageName, version
+	return info, nil
+}
@@ -0,0 +1,63 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// probeObjectPrefix 启动自检探针对象的前缀，与包文件路径隔离
+const probeObjectPrefix = ".selftest/"
+
+// Probe 写入、读取并删除一个探针对象，验证bucket可读写
+func (c *Client) Probe(ctx context.Context) error {
+	objectName := fmt.Sprintf("%sprobe-%d", probeObjectPrefix, time.Now().UnixNano())
+	payload := []byte("webservice startup probe")
+
+	_, err := c.client.PutObject(ctx, c.bucketName, objectName, bytes.NewReader(payload), int64(len(payload)),
+		minio.PutObjectOptions{ContentType: "text/plain"})
+	if err != nil {
+		return fmt.Errorf("failed to write probe object: %w", err)
+	}
+
+	// 无论读取是否成功都要清理探针对象
+	readErr := c.readProbe(ctx, objectName, payload)
+	if err := c.client.RemoveObject(ctx, c.bucketName, objectName, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete probe object: %w", err)
+	}
+	return readErr
+}
+
+// readProbe 读取探针对象并校验内容
+func (c *Client) readProbe(ctx context.Context, objectName string, expected []byte) error {
+	object, err := c.client.GetObject(ctx, c.bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read probe object: %w", err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return fmt.Errorf("failed to read probe object: %w", err)
+	}
+	if !bytes.Equal(data, expected) {
+		return fmt.Errorf("probe object content mismatch")
+	}
+	return nil
+}
+
+// Ping 检查存储服务是否可访问（只读，不写入对象）
+func (c *Client) Ping(ctx context.Context) error {
+	exists, err := c.client.BucketExists(ctx, c.bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to reach storage: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", c.bucketName)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package minio
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/minio/minio-go/v7"
+)
+
+const raceObjectName = "packages/demo/1.0.0/demo-1.0.0.pkg"
+
+// newRaceEndpoint 启动内存S3服务，delay非零时读取测试对象的请求先等待（客户端取消时立即返回）
+// content非空时写入测试对象，返回的endpoint可用作主节点或镜像
+func newRaceEndpoint(t *testing.T, delay time.Duration, content string) string {
+	t.Helper()
+	backend := gofakes3.New(s3mem.New()).Server()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if delay > 0 && r.Method != http.MethodPut && strings.HasSuffix(r.URL.Path, raceObjectName) {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	endpoint := strings.TrimPrefix(server.URL, "http://")
+
+	seed, err := NewClient(config.MinIOConfig{Endpoint: endpoint, AccessKey: "test", SecretKey: "test-secret", BucketName: "packages"})
+	if err != nil {
+		t.Fatalf("failed to create client for %s: %v", endpoint, err)
+	}
+	if content != "" {
+		if _, err := seed.client.PutObject(context.Background(), seed.bucketName, raceObjectName,
+			strings.NewReader(content), int64(len(content)), minio.PutObjectOptions{}); err != nil {
+			t.Fatalf("failed to seed %s: %v", endpoint, err)
+		}
+	}
+	return endpoint
+}
+
+// newRaceClient 以第一个endpoint为主节点、其余为镜像创建客户端
+func newRaceClient(t *testing.T, endpoints ...string) *Client {
+	t.Helper()
+	cfg := config.MinIOConfig{Endpoint: endpoints[0], AccessKey: "test", SecretKey: "test-secret", BucketName: "packages"}
+	for _, endpoint := range endpoints[1:] {
+		cfg.Replicas = append(cfg.Replicas, config.MinIOReplicaConfig{Endpoint: endpoint, AccessKey: "test", SecretKey: "test-secret"})
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestRaceDownloadPicksFastestEndpoint(t *testing.T) {
+	const slow = 2 * time.Second
+	tests := []struct {
+		name      string
+		primary   time.Duration
+		replica   time.Duration
+		wantBytes string
+	}{
+		{name: "slow primary", primary: slow, wantBytes: "from replica"},
+		{name: "slow replica", replica: slow, wantBytes: "from primary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newRaceClient(t,
+				newRaceEndpoint(t, tt.primary, "from primary"),
+				newRaceEndpoint(t, tt.replica, "from replica"))
+
+			start := time.Now()
+			reader, _, err := client.RaceDownload(context.Background(), raceObjectName)
+			if err != nil {
+				t.Fatalf("RaceDownload: %v", err)
+			}
+			// 落败节点被取消后，获胜者的对象仍需可完整读取
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatalf("failed to read winner: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed >= slow {
+				t.Errorf("RaceDownload took %s, want it to return before the slow endpoint (%s)", elapsed, slow)
+			}
+			if string(data) != tt.wantBytes {
+				t.Errorf("content = %q, want %q", data, tt.wantBytes)
+			}
+		})
+	}
+}
+
+func TestRaceDownloadFallsBackOnFailure(t *testing.T) {
+	// 主节点先响应但对象不存在，镜像稍慢但可用
+	client := newRaceClient(t,
+		newRaceEndpoint(t, 0, ""),
+		newRaceEndpoint(t, 50*time.Millisecond, "from replica"))
+
+	reader, _, err := client.RaceDownload(context.Background(), raceObjectName)
+	if err != nil {
+		t.Fatalf("RaceDownload: %v", err)
+	}
+	defer reader.Close()
+	if data, _ := io.ReadAll(reader); string(data) != "from replica" {
+		t.Errorf("content = %q, want the replica's copy", data)
+	}
+}
+
+func TestRaceDownloadAllEndpointsFail(t *testing.T) {
+	client := newRaceClient(t, newRaceEndpoint(t, 0, ""), newRaceEndpoint(t, 10*time.Millisecond, ""))
+
+	_, _, err := client.RaceDownload(context.Background(), raceObjectName)
+	if err == nil || !strings.Contains(err.Error(), "all 2 endpoints failed") {
+		t.Fatalf("err = %v, want all endpoints failed", err)
+	}
+	for _, endpoint := range []string{"endpoint 0", "endpoint 1"} {
+		if !strings.Contains(err.Error(), endpoint) {
+			t.Errorf("err = %v, want it to include %s", err, endpoint)
+		}
+	}
+}
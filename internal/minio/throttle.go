@@ -0,0 +1,83 @@
+package minio
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"webservice/internal/metrics"
+
+	"golang.org/x/time/rate"
+)
+
+// minThrottleBurst 令牌桶的最小容量，避免限速很低时每次只能读取几个字节
+const minThrottleBurst = 32 * 1024
+
+// throttleWaitSeconds 每次上传因限速累计等待的时间，scope区分单次上传限速和按用户限速
+var throttleWaitSeconds = metrics.NewHistogramVec(
+	"upload_throttle_wait_seconds_total",
+	"Total time an upload spent waiting on bandwidth throttling.",
+	[]float64{0, 0.1, 0.5, 1, 5, 15, 30, 60, 300},
+	"scope",
+)
+
+// 限速范围
+const (
+	ThrottleScopeUpload    = "upload"
+	ThrottleScopeUser      = "user"
+	ThrottleScopeIntegrity = "integrity" // 完整性校验任务读取对象
+)
+
+// NewBandwidthLimiter 创建按字节计的令牌桶（golang.org/x/time/rate），容量为1秒的流量且不小于minThrottleBurst
+// 可被多个读取器共享（如同一用户的并发上传共用一个限速器），bytesPerSec<=0时返回nil表示不限速
+func NewBandwidthLimiter(bytesPerSec int64) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := bytesPerSec
+	if burst < minThrottleBurst {
+		burst = minThrottleBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), int(burst))
+}
+
+// throttledReader 按令牌桶限速的读取器，读到末尾时记录累计等待时间
+type throttledReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiter  *rate.Limiter
+	scope    string
+	waited   time.Duration
+	observed bool
+}
+
+// NewThrottledReader 包装读取器，limiter为nil时原样返回
+func NewThrottledReader(ctx context.Context, reader io.Reader, limiter *rate.Limiter, scope string) io.Reader {
+	if limiter == nil {
+		return reader
+	}
+	return &throttledReader{ctx: ctx, reader: reader, limiter: limiter, scope: scope}
+}
+
+// Read 单次读取不超过令牌桶容量（WaitN不接受超过容量的请求），读取后按实际字节数等待令牌
+// 并发读取器共用限速器时按预留顺序依次等待；ctx取消时立即返回ctx的错误
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		start := time.Now()
+		waitErr := r.limiter.WaitN(r.ctx, n)
+		r.waited += time.Since(start)
+		if waitErr != nil {
+			return n, waitErr
+		}
+	}
+	if err == io.EOF && !r.observed {
+		r.observed = true
+		throttleWaitSeconds.Observe(r.waited.Seconds(), r.scope)
+	}
+	return n, err
+}
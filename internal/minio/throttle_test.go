@@ -0,0 +1,149 @@
+package minio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/metrics"
+)
+
+// throttleWaitObservation 从默认注册表读取指定scope的等待时间直方图的观测次数与总和
+func throttleWaitObservation(t *testing.T, scope string) (count int, sum float64) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	metrics.DefaultRegistry.WritePrometheus(&buf)
+	labels := `{scope="` + scope + `"} `
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "upload_throttle_wait_seconds_total_count"+labels):
+			n, err := strconv.Atoi(strings.TrimPrefix(line, "upload_throttle_wait_seconds_total_count"+labels))
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", line, err)
+			}
+			count = n
+		case strings.HasPrefix(line, "upload_throttle_wait_seconds_total_sum"+labels):
+			v, err := strconv.ParseFloat(strings.TrimPrefix(line, "upload_throttle_wait_seconds_total_sum"+labels), 64)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", line, err)
+			}
+			sum = v
+		}
+	}
+	return count, sum
+}
+
+func TestNewBandwidthLimiterDisabled(t *testing.T) {
+	for _, limit := range []int64{0, -1} {
+		if limiter := NewBandwidthLimiter(limit); limiter != nil {
+			t.Errorf("NewBandwidthLimiter(%d) = %v, want nil", limit, limiter)
+		}
+	}
+
+	reader := strings.NewReader("payload")
+	if got := NewThrottledReader(context.Background(), reader, nil, ThrottleScopeUpload); got != io.Reader(reader) {
+		t.Error("NewThrottledReader without a limiter should return the reader unchanged")
+	}
+}
+
+func TestNewBandwidthLimiterMinimumBurst(t *testing.T) {
+	if burst := NewBandwidthLimiter(1024).Burst(); burst != minThrottleBurst {
+		t.Errorf("burst = %d, want %d", burst, minThrottleBurst)
+	}
+	if burst := NewBandwidthLimiter(1 << 20).Burst(); burst != 1<<20 {
+		t.Errorf("burst = %d, want %d", burst, 1<<20)
+	}
+}
+
+func TestThrottledReaderLimitsThroughput(t *testing.T) {
+	const bytesPerSec = 1 << 20
+	limiter := NewBandwidthLimiter(bytesPerSec)
+	payload := bytes.Repeat([]byte("x"), bytesPerSec*3/2)
+
+	start := time.Now()
+	got, err := io.ReadAll(NewThrottledReader(context.Background(), bytes.NewReader(payload), limiter, "test-throughput"))
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("read %d bytes, want %d", len(got), len(payload))
+	}
+
+	// 令牌桶初始为满，前1MiB不等待，剩余0.5MiB需要约0.5秒
+	if elapsed < 400*time.Millisecond {
+		t.Errorf("read 1.5MiB at 1MiB/s in %v, want at least ~500ms", elapsed)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("read 1.5MiB at 1MiB/s in %v, want well under 3s", elapsed)
+	}
+
+	count, sum := throttleWaitObservation(t, "test-throughput")
+	if count != 1 {
+		t.Errorf("wait observations = %d, want 1", count)
+	}
+	if sum < 0.4 || sum > elapsed.Seconds() {
+		t.Errorf("observed wait = %vs, want between 0.4s and %v", sum, elapsed)
+	}
+}
+
+func TestThrottledReaderSharedLimiter(t *testing.T) {
+	const bytesPerSec = 256 * 1024
+	limiter := NewBandwidthLimiter(bytesPerSec)
+
+	// 两个读取器共用一个限速器，合计0.5MiB：首个令牌桶容量不等待，其余约需1秒
+	start := time.Now()
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			payload := bytes.NewReader(make([]byte, bytesPerSec))
+			_, err := io.Copy(io.Discard, NewThrottledReader(context.Background(), payload, limiter, "test-shared"))
+			done <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("copy error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("shared limiter let 512KiB through in %v at 256KiB/s, want at least ~1s", elapsed)
+	}
+}
+
+func TestThrottledReaderCancelledMidWait(t *testing.T) {
+	limiter := NewBandwidthLimiter(minThrottleBurst)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := NewThrottledReader(ctx, bytes.NewReader(make([]byte, 4*minThrottleBurst)), limiter, "test-cancel")
+	buf := make([]byte, minThrottleBurst)
+
+	// 第一次读取耗尽令牌桶，第二次读取需要等待约1秒
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("first read error = %v", err)
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := reader.Read(buf)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Read() error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Read() returned %v after cancellation, want prompt return", elapsed)
+	}
+
+	if count, _ := throttleWaitObservation(t, "test-cancel"); count != 0 {
+		t.Errorf("wait observations = %d, want 0 for an aborted read", count)
+	}
+}
@@ -0,0 +1,63 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+
+	"webservice/internal/logger"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// 存储层级（S3存储类型）
+const (
+	StorageClassStandard   = "STANDARD"
+	StorageClassStandardIA = "STANDARD_IA"
+)
+
+// GetStorageClass 获取对象当前的存储类型，未设置时视为STANDARD
+func (c *Client) GetStorageClass(ctx context.Context, objectName string) (string, error) {
+	objInfo, err := c.client.StatObject(ctx, c.bucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("package not found: %w", err)
+	}
+
+	if objInfo.StorageClass == "" {
+		return StorageClassStandard, nil
+	}
+	return objInfo.StorageClass, nil
+}
+
+// SetObjectStorageClass 修改对象的存储类型
+// S3通过就地复制对象并替换存储类型实现迁移，原有元数据（如压缩标记）保持不变
+func (c *Client) SetObjectStorageClass(ctx context.Context, objectName, storageClass string) error {
+	objInfo, err := c.client.StatObject(ctx, c.bucketName, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("package not found: %w", err)
+	}
+
+	metadata := make(map[string]string, len(objInfo.UserMetadata)+1)
+	for k, v := range objInfo.UserMetadata {
+		metadata[k] = v
+	}
+	metadata["X-Amz-Storage-Class"] = storageClass
+
+	dst := minio.CopyDestOptions{
+		Bucket:          c.bucketName,
+		Object:          objectName,
+		ReplaceMetadata: true,
+		UserMetadata:    metadata,
+		ContentType:     objInfo.ContentType,
+	}
+	src := minio.CopySrcOptions{
+		Bucket: c.bucketName,
+		Object: objectName,
+	}
+
+	if _, err := c.client.CopyObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to change storage class: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("Package storage class changed: %s -> %s", objectName, storageClass))
+	return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// APIToken 供内部服务调用只读gRPC接口使用的API令牌，只保存令牌的SHA-256摘要，明文仅在创建时返回一次
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	Prefix     string     `json:"prefix" gorm:"size:8"` // 令牌的前8个字符，用于在列表中辨认令牌
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 为空表示不过期
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// IsActive 令牌是否有效（未吊销且未过期）
+func (t *APIToken) IsActive() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt))
+}
+
+// CreateAPITokenRequest 创建API令牌请求
+type CreateAPITokenRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=3650"` // 有效天数，0表示不过期
+}
+
+// CreateAPITokenResponse 创建API令牌响应，token为明文，之后无法再次获取
+type CreateAPITokenResponse struct {
+	Token    string    `json:"token"`
+	APIToken *APIToken `json:"api_token"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 归档条目类型
+const (
+	ArchiveEntryFile    = "file"
+	ArchiveEntryDir     = "dir"
+	ArchiveEntrySymlink = "symlink"
+	ArchiveEntryOther   = "other"
+)
+
+// ArchiveEntry 包版本文件（zip/tar/tar.gz）中的一个条目
+type ArchiveEntry struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Mode     string    `json:"mode"`
+	ModTime  time.Time `json:"mod_time"`
+	Linkname string    `json:"linkname,omitempty"` // 符号链接的目标
+}
+
+// ArchiveContentPage 包版本文件的一页条目，按条目在归档中的顺序排列
+type ArchiveContentPage struct {
+	Entries  []ArchiveEntry `json:"entries"`
+	Total    int            `json:"total"` // 条目总数，首次完整扫描后缓存
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	HasMore  bool           `json:"has_more"`
+}
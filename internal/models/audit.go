@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditLog 审计日志模型，记录敏感操作
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	ActorID   uint      `json:"actor_id" gorm:"not null;index"`
+	Action    string    `json:"action" gorm:"size:100;not null;index"`
+	Resource  string    `json:"resource" gorm:"size:255"`
+	Details   string    `json:"details" gorm:"type:text"` // JSON格式的操作参数
+	IPAddress string    `json:"ip_address" gorm:"size:45"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// PackageBandwidthUsage 包每月累计的下载流量，由下载计数协程在每次下载后更新
+type PackageBandwidthUsage struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	PackageID uint      `json:"package_id" gorm:"not null;uniqueIndex:idx_bandwidth_package_month"`
+	Month     string    `json:"month" gorm:"size:7;not null;uniqueIndex:idx_bandwidth_package_month"` // UTC月份，如2026-10
+	Downloads int64     `json:"downloads" gorm:"not null;default:0"`
+	Bytes     int64     `json:"bytes" gorm:"not null;default:0"` // 按下载版本的文件大小累计
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PackageBandwidthUsage) TableName() string {
+	return "bandwidth_usage"
+}
+
+// BandwidthUsageMonth 单月的下载流量
+type BandwidthUsageMonth struct {
+	Month     string `json:"month"`
+	Downloads int64  `json:"downloads"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// BandwidthUsage 包在时间范围内的下载流量统计
+type BandwidthUsage struct {
+	Package   string    `json:"package"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Downloads int64     `json:"downloads"` // 范围内的下载记录数
+	Bytes     int64     `json:"bytes"`     // 范围内每条下载记录对应版本的文件大小之和
+	// EstimatedTotalBytes 所有版本file_size*download_count之和，不受时间范围限制的近似值
+	EstimatedTotalBytes int64 `json:"estimated_total_bytes"`
+	// Months 范围内各月的累计流量（bandwidth_usage表）
+	Months            []BandwidthUsageMonth `json:"months"`
+	MonthlyLimitBytes *int64                `json:"monthly_limit_bytes"`
+	CurrentMonthBytes int64                 `json:"current_month_bytes"`
+}
+
+// SetBandwidthLimitRequest 设置包每月下载流量上限请求，为null时取消限制
+type SetBandwidthLimitRequest struct {
+	MonthlyBandwidthLimitBytes *int64 `json:"monthly_bandwidth_limit_bytes" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Category 包分类，由管理员维护的固定分类表（如 web、cli、database），与自由填写的关键字互补
+type Category struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Slug        string    `json:"slug" gorm:"uniqueIndex;not null;size:50"`
+	Name        string    `json:"name" gorm:"not null;size:100"`
+	Description string    `json:"description" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Category) TableName() string {
+	return "categories"
+}
+
+// PackageCategory 包与分类的多对多关联
+type PackageCategory struct {
+	PackageID  uint `gorm:"primaryKey"`
+	CategoryID uint `gorm:"primaryKey;index"`
+}
+
+// TableName 指定表名
+func (PackageCategory) TableName() string {
+	return "package_categories"
+}
+
+// CategoryWithCount 分类及其下的公开包数
+type CategoryWithCount struct {
+	Category
+	PackageCount int64 `json:"package_count"`
+}
+
+// CreateCategoryRequest 创建分类请求
+type CreateCategoryRequest struct {
+	Slug        string `json:"slug" binding:"required,max=50"` // 小写字母、数字和-，创建后不可修改
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+// UpdateCategoryRequest 更新分类请求，未设置的字段保持不变
+type UpdateCategoryRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=255"`
+}
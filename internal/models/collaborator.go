@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// 协作者角色
+const (
+	CollaboratorRoleMaintainer = "maintainer" // 可以发布版本和修改元数据，不能删除包或管理协作者
+	CollaboratorRoleReader     = "reader"     // 只能查看私有包
+)
+
+// PackageCollaborator 包的协作者，由包所有者授予角色
+type PackageCollaborator struct {
+	ID        uint      `json:"-" gorm:"primarykey"`
+	PackageID uint      `json:"-" gorm:"not null;uniqueIndex:idx_package_collaborator"`
+	UserID    uint      `json:"-" gorm:"not null;uniqueIndex:idx_package_collaborator;index"`
+	Role      string    `json:"role" gorm:"not null;size:20"`
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CollaboratorRequest 添加或修改协作者的请求
+type CollaboratorRequest struct {
+	Role string `json:"role" binding:"required,oneof=maintainer reader"`
+}
+
+// CollaboratorInfo 协作者列表中的一项
+type CollaboratorInfo struct {
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
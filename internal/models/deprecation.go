@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// DeprecatedRouteUsage 弃用路由调用记录
+type DeprecatedRouteUsage struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Method    string    `json:"method" gorm:"size:10;not null"`
+	Route     string    `json:"route" gorm:"size:255;not null;index"`
+	UserID    *uint     `json:"user_id" gorm:"index"` // 匿名调用时为空
+	TokenID   string    `json:"token_id" gorm:"size:36"`
+	ClientIP  string    `json:"client_ip" gorm:"size:45"`
+	UserAgent string    `json:"user_agent" gorm:"size:500"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (DeprecatedRouteUsage) TableName() string {
+	return "deprecated_route_usages"
+}
+
+// DeprecatedRouteUsageStat 弃用路由调用统计
+type DeprecatedRouteUsageStat struct {
+	Method      string    `json:"method"`
+	Route       string    `json:"route"`
+	Hits        int64     `json:"hits"`
+	UniqueUsers int64     `json:"unique_users"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ExternalIdentity 外部身份提供方（OAuth/SSO）账号与本地用户的关联
+type ExternalIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Provider  string    `json:"provider" gorm:"not null;size:32;uniqueIndex:idx_external_identity"`
+	Subject   string    `json:"subject" gorm:"not null;size:255;uniqueIndex:idx_external_identity"` // 提供方内的用户唯一标识（sub）
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Email     string    `json:"email" gorm:"size:100"` // 关联时提供方返回的邮箱
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ExternalIdentity) TableName() string {
+	return "external_identities"
+}
+
+// ExternalLoginRequest 外部身份登录/关联请求结构体
+type ExternalLoginRequest struct {
+	Provider   string `json:"provider" binding:"required,max=32"`
+	Credential string `json:"credential" binding:"required"` // 提供方签发的凭据（如ID Token），由对应的校验器验证
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// FeatureFlag 功能开关，用于新功能的灰度发布
+// 全局启用、角色命中、用户命中或落入灰度比例之一即对该用户启用
+type FeatureFlag struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	Name              string    `json:"name" gorm:"uniqueIndex;size:100;not null"`
+	Description       string    `json:"description" gorm:"size:500"`
+	EnabledGlobally   bool      `json:"enabled_globally" gorm:"default:false"`
+	EnabledForRoles   []string  `json:"enabled_for_roles" gorm:"serializer:json;type:text"`
+	EnabledForUserIDs []uint    `json:"enabled_for_user_ids" gorm:"serializer:json;type:text"`
+	RolloutPercentage int       `json:"rollout_percentage" gorm:"default:0"` // 0-100，按hash(name+用户ID)%100分桶
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (FeatureFlag) TableName() string {
+	return "feature_flags"
+}
+
+// CreateFeatureFlagRequest 创建功能开关请求
+type CreateFeatureFlagRequest struct {
+	Name              string   `json:"name" binding:"required,max=100"`
+	Description       string   `json:"description" binding:"max=500"`
+	EnabledGlobally   bool     `json:"enabled_globally"`
+	EnabledForRoles   []string `json:"enabled_for_roles" binding:"dive,oneof=user admin super support"`
+	EnabledForUserIDs []uint   `json:"enabled_for_user_ids"`
+	RolloutPercentage int      `json:"rollout_percentage" binding:"min=0,max=100"`
+}
+
+// UpdateFeatureFlagRequest 修改功能开关请求，未提供的字段保持不变
+type UpdateFeatureFlagRequest struct {
+	Description       *string  `json:"description" binding:"omitempty,max=500"`
+	EnabledGlobally   *bool    `json:"enabled_globally"`
+	EnabledForRoles   []string `json:"enabled_for_roles" binding:"omitempty,dive,oneof=user admin super support"`
+	EnabledForUserIDs []uint   `json:"enabled_for_user_ids"`
+	RolloutPercentage *int     `json:"rollout_percentage" binding:"omitempty,min=0,max=100"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// HealthCheck 依赖检查的一次采样结果（health.persist开启时写入health_checks表）
+type HealthCheck struct {
+	ID                uint      `json:"id" gorm:"primarykey"`
+	CheckedAt         time.Time `json:"checked_at" gorm:"not null;index"`
+	DatabaseUp        bool      `json:"database_up"`
+	DatabaseLatencyMs int64     `json:"database_latency_ms"`
+	StorageUp         *bool     `json:"storage_up"` // 未配置存储时为nil
+	StorageLatencyMs  int64     `json:"storage_latency_ms"`
+	Healthy           bool      `json:"healthy"` // 所有已配置的依赖都可用
+}
+
+// HealthHistory 时间窗口内各依赖的可用性
+type HealthHistory struct {
+	Window       string                   `json:"window"`
+	From         *time.Time               `json:"from,omitempty"` // 窗口内最早的采样时间，没有采样时为空
+	To           *time.Time               `json:"to,omitempty"`
+	Samples      int                      `json:"samples"`
+	Source       string                   `json:"source"` // memory或database
+	Dependencies []DependencyAvailability `json:"dependencies"`
+}
+
+// DependencyAvailability 单个依赖（database、storage或overall）的可用性
+type DependencyAvailability struct {
+	Name                string             `json:"name"`
+	Samples             int                `json:"samples"`
+	Failures            int                `json:"failures"`
+	AvailabilityPercent *float64           `json:"availability_percent"` // 没有采样时为null
+	AvgLatencyMs        *float64           `json:"avg_latency_ms,omitempty"`
+	Downtime            []DowntimeInterval `json:"downtime"`
+}
+
+// DowntimeInterval 连续检查失败的时间段
+type DowntimeInterval struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	Ongoing         bool      `json:"ongoing"` // 窗口内最后一次检查仍然失败
+}
@@ -0,0 +1,19 @@
+package models
+
+import "net/url"
+
+// iconURLVersionLength icon_url中v参数使用的内容哈希长度
+const iconURLVersionLength = 12
+
+// PackageIconURL 包图标的访问地址，hash为空（未上传图标）时返回空字符串
+// v参数取自内容哈希，替换图标后地址变化，客户端和CDN按新地址重新获取
+func PackageIconURL(packageName, hash string) string {
+	if hash == "" {
+		return ""
+	}
+	version := hash
+	if len(version) > iconURLVersionLength {
+		version = version[:iconURLVersionLength]
+	}
+	return "/api/v1/packages/" + url.PathEscape(packageName) + "/icon?v=" + version
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// 排行榜周期
+const (
+	LeaderboardWeekly  = "weekly"  // 本周（UTC周一起）的下载量
+	LeaderboardMonthly = "monthly" // 本月（UTC自然月）的下载量
+	LeaderboardAllTime = "alltime" // 累计下载量，每周保存一次快照
+)
+
+// LeaderboardEntry 排行榜条目，由后台任务定期计算；同一周期的条目每次计算时整体替换，历史周期的条目保留用于排名走势
+type LeaderboardEntry struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	PeriodType  string    `json:"period_type" gorm:"size:16;not null;index:idx_leaderboard_period,priority:1"`
+	PeriodStart time.Time `json:"period_start" gorm:"not null;index:idx_leaderboard_period,priority:2"` // 周期开始时间，累计榜为快照所在周的开始时间
+	Rank        int       `json:"rank" gorm:"not null"`
+	PackageID   uint      `json:"package_id" gorm:"not null;index"`
+	Score       float64   `json:"score"` // 周期内的有效下载次数，不含疑似爬虫流量
+	ComputedAt  time.Time `json:"computed_at"`
+}
+
+// TableName 指定表名
+func (LeaderboardEntry) TableName() string {
+	return "leaderboard_entries"
+}
+
+// LeaderboardItem 排行榜中的一个包
+type LeaderboardItem struct {
+	Rank         int      `json:"rank"`
+	Score        float64  `json:"score"`
+	PreviousRank *int     `json:"previous_rank"` // 上一周期的排名，未上榜时为空
+	RankDelta    *int     `json:"rank_delta"`    // 排名变化，正数表示上升，上一周期未上榜时为空
+	Package      *Package `json:"package"`
+}
+
+// LeaderboardResponse 排行榜
+type LeaderboardResponse struct {
+	Period      string            `json:"period"`
+	PeriodStart *time.Time        `json:"period_start"` // 尚未计算过排行榜时为空
+	ComputedAt  *time.Time        `json:"computed_at"`
+	Items       []LeaderboardItem `json:"items"`
+}
+
+// PackageRank 包在一个周期排行榜中的排名
+type PackageRank struct {
+	Rank         int       `json:"rank"`
+	Score        float64   `json:"score"`
+	PreviousRank *int      `json:"previous_rank"`
+	RankDelta    *int      `json:"rank_delta"`
+	PeriodStart  time.Time `json:"period_start"`
+}
+
+// PackageRankResponse 包在各周期排行榜中的当前排名，未上榜的周期为空
+type PackageRankResponse struct {
+	Package string                  `json:"package"`
+	Ranks   map[string]*PackageRank `json:"ranks"`
+}
+
+// LeaderboardHistoryPoint 排名走势中的一个周期，未上榜时rank为空、score为0
+type LeaderboardHistoryPoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	Rank        *int      `json:"rank"`
+	Score       float64   `json:"score"`
+}
+
+// LeaderboardHistoryResponse 包的排名走势，按周期开始时间升序
+type LeaderboardHistoryResponse struct {
+	Package string                    `json:"package"`
+	Period  string                    `json:"period"`
+	Points  []LeaderboardHistoryPoint `json:"points"`
+}
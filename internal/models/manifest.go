@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// PublishedManifest 发布时捕获的版本元数据快照，写入后不再修改
+// 依赖解析等需要稳定结果的功能读取快照，之后修改版本的描述、更新日志等可编辑字段不会改变历史解析结果
+type PublishedManifest struct {
+	Name             string            `json:"name"`
+	Version          string            `json:"version"`
+	Description      string            `json:"description,omitempty"`
+	Dependencies     map[string]string `json:"dependencies,omitempty"`
+	FileSize         int64             `json:"file_size"`
+	FileHash         string            `json:"file_hash"`
+	IsPrerelease     bool              `json:"is_prerelease"`
+	SourceRepository string            `json:"source_repository,omitempty"`
+	SourceCommit     string            `json:"source_commit,omitempty"`
+	BuildURL         string            `json:"build_url,omitempty"`
+	PublishedAt      time.Time         `json:"published_at"`
+	Backfilled       bool              `json:"backfilled,omitempty"` // 由迁移根据已有数据补全，而不是发布时捕获
+}
+
+// ImmutableVersionFields 发布后不可修改的版本字段（以快照为准），修改版本的请求包含这些字段时被拒绝
+var ImmutableVersionFields = []string{
+	"version", "dependencies", "file_size", "file_hash", "is_prerelease",
+	"source_repository", "source_commit", "build_url", "published_manifest",
+}
+
+// NewPublishedManifest 根据版本记录的当前字段生成快照
+func NewPublishedManifest(packageName string, v *PackageVersion) *PublishedManifest {
+	return &PublishedManifest{
+		Name:             packageName,
+		Version:          v.Version,
+		Description:      v.Description,
+		Dependencies:     parseDependencies(v.Dependencies),
+		FileSize:         v.FileSize,
+		FileHash:         v.FileHash,
+		IsPrerelease:     v.IsPrerelease,
+		SourceRepository: v.SourceRepository,
+		SourceCommit:     v.SourceCommit,
+		BuildURL:         v.BuildURL,
+		PublishedAt:      v.CreatedAt,
+	}
+}
+
+// PublishedDependencies 发布时声明的依赖，没有快照的旧数据读取dependencies列
+func (v *PackageVersion) PublishedDependencies() map[string]string {
+	if v.PublishedManifest != nil {
+		return v.PublishedManifest.Dependencies
+	}
+	return parseDependencies(v.Dependencies)
+}
+
+// parseDependencies 解析JSON存储的依赖关系，无法解析时视为没有依赖
+func parseDependencies(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	var deps map[string]string
+	_ = json.Unmarshal([]byte(raw), &deps)
+	return deps
+}
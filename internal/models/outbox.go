@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// OutboxEvent 发件箱事件，与包/版本变更在同一事务中写入，由分发任务按ID顺序投递给各消费者
+type OutboxEvent struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	EventType   string    `json:"event_type" gorm:"size:64;not null;index"`
+	PackageID   uint      `json:"package_id"`
+	PackageName string    `json:"package_name" gorm:"size:100"`
+	Version     string    `json:"version" gorm:"size:50"`
+	UserID      uint      `json:"user_id"`
+	Payload     string    `json:"payload" gorm:"type:text"` // JSON格式的事件附加数据
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// OutboxOffset 消费者的投递进度，LastEventID及之前的事件均已处理
+type OutboxOffset struct {
+	Consumer    string    `json:"consumer" gorm:"primarykey;size:64"`
+	LastEventID uint      `json:"last_event_id" gorm:"not null;default:0"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// TableName 指定表名
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// TableName 指定表名
+func (OutboxOffset) TableName() string {
+	return "outbox_offsets"
+}
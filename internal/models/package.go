@@ -8,42 +8,142 @@ import (
 
 // Package 包模型
 type Package struct {
-	ID          uint             `json:"id" gorm:"primarykey"`
-	Name        string           `json:"name" gorm:"uniqueIndex:idx_package_name;not null;size:100" binding:"required,min=1,max=100"`
-	Description string           `json:"description" gorm:"size:500"`
-	Author      string           `json:"author" gorm:"size:100"`
-	Homepage    string           `json:"homepage" gorm:"size:255"`
-	Repository  string           `json:"repository" gorm:"size:255"`
-	License     string           `json:"license" gorm:"size:50"`
-	Keywords    string           `json:"keywords" gorm:"size:500"` // JSON数组存储为字符串
-	IsPrivate   bool             `json:"is_private" gorm:"default:false"`
-	OwnerID     uint             `json:"owner_id" gorm:"not null"`
-	Owner       User             `json:"owner" gorm:"foreignKey:OwnerID"`
-	Versions    []PackageVersion `json:"versions,omitempty" gorm:"foreignKey:PackageID"`
-	CreatedAt   time.Time        `json:"created_at"`
-	UpdatedAt   time.Time        `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt   `json:"-" gorm:"index"`
+	ID                 uint       `json:"id" gorm:"primarykey"`
+	Name               string     `json:"name" gorm:"uniqueIndex:idx_package_name;not null;size:100" binding:"required,min=1,max=100"`
+	Description        string     `json:"description" gorm:"size:500"`
+	Author             string     `json:"author" gorm:"size:100"`
+	Homepage           string     `json:"homepage" gorm:"size:255"`
+	Repository         string     `json:"repository" gorm:"size:255"`
+	License            string     `json:"license" gorm:"size:50;index"`
+	Keywords           string     `json:"keywords" gorm:"size:500"` // JSON数组存储为字符串
+	IsPrivate          bool       `json:"is_private" gorm:"default:false"`
+	IsArchived         bool       `json:"is_archived" gorm:"default:false;index"` // 归档后只读：仍可下载和搜索，不能上传或修改
+	ArchivedAt         *time.Time `json:"archived_at,omitempty"`
+	KeepRecentVersions int        `json:"keep_recent_versions" gorm:"default:0"` // 清理时始终保留的最近版本数
+	// 发布策略
+	RequireMonotonicVersions bool `json:"require_monotonic_versions" gorm:"default:false"` // 新版本不得低于当前最高版本
+	AutoPrereleaseDetection  bool `json:"auto_prerelease_detection" gorm:"default:false"`  // 版本号含-alpha/-beta/-rc时强制标记为预发布
+	DisallowPrereleaseLatest bool `json:"disallow_prerelease_latest" gorm:"default:false"` // 预发布版本不能成为最新版本
+	UploadLocked             bool `json:"upload_locked" gorm:"default:false"`              // 不可变包：发布过的版本号（包括已删除的）不能再次上传，开启后不能关闭
+	// 详细信息，列表接口不返回long_description
+	LongDescription string `json:"long_description,omitempty" gorm:"type:text"`
+	FundingURL      string `json:"funding_url,omitempty" gorm:"size:255"`     // 赞助链接
+	BugTrackerURL   string `json:"bug_tracker_url,omitempty" gorm:"size:255"` // 问题反馈地址
+	Documentation   string `json:"documentation,omitempty" gorm:"size:255"`   // 文档地址
+	// 信任等级：查询时取包自身等级与所有者等级中较高者
+	TrustLevel    string           `json:"trust_level" gorm:"size:16;not null;default:unverified;index"`
+	OwnTrustLevel string           `json:"own_trust_level" gorm:"-"` // 包自身设置的信任等级
+	TrustedBadge  bool             `json:"trusted_badge" gorm:"-"`
+	OwnerID       uint             `json:"owner_id" gorm:"not null"`
+	Owner         User             `json:"owner" gorm:"foreignKey:OwnerID"`
+	Versions      []PackageVersion `json:"versions,omitempty" gorm:"foreignKey:PackageID"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt   `json:"-" gorm:"index"`
+	// 乐观锁版本号，每次修改元数据加1；更新请求携带if_version时只在未被他人修改的情况下生效
+	LockVersion int `json:"lock_version" gorm:"not null;default:1"`
+	// 关注该包的用户数，关注和取消关注时更新
+	WatchCount int64 `json:"watch_count" gorm:"not null;default:0"`
+	// 所属分类，只能从管理员维护的分类表中选择
+	Categories []Category `json:"categories,omitempty" gorm:"many2many:package_categories"`
+	// 每月下载流量上限（字节，按UTC自然月），由管理员设置，为空表示不限制；超出后下载返回429
+	MonthlyBandwidthLimitBytes *int64 `json:"monthly_bandwidth_limit_bytes,omitempty"`
+	// 包图标，未上传时对象键为空；icon_url带有图标的内容哈希，替换图标后URL随之变化
+	IconObjectKey   string `json:"-" gorm:"size:255"`
+	IconHash        string `json:"-" gorm:"size:64"`
+	IconContentType string `json:"-" gorm:"size:50"`
+	IconURL         string `json:"icon_url,omitempty" gorm:"-"`
+	// 搜索高亮结果，仅在搜索请求设置highlight=true时返回
+	NameHighlighted        string `json:"name_highlighted,omitempty" gorm:"-"`
+	DescriptionHighlighted string `json:"description_highlighted,omitempty" gorm:"-"`
+	// 推荐包，仅在获取包详情时返回
+	Recommendations []Package `json:"recommendations,omitempty" gorm:"-"`
 }
 
 // PackageVersion 包版本模型
 type PackageVersion struct {
-	ID            uint           `json:"id" gorm:"primarykey"`
-	PackageID     uint           `json:"package_id" gorm:"not null"`
-	Package       Package        `json:"package,omitempty" gorm:"foreignKey:PackageID"`
-	Version       string         `json:"version" gorm:"uniqueIndex:idx_package_version;not null;size:50" binding:"required"`
-	Description   string         `json:"description" gorm:"size:500"`
-	Changelog     string         `json:"changelog" gorm:"type:text"`
-	Dependencies  string         `json:"dependencies" gorm:"type:text"` // JSON存储依赖关系
-	FileSize      int64          `json:"file_size" gorm:"not null"`
-	FileHash      string         `json:"file_hash" gorm:"size:64"`   // SHA256哈希
-	MinIOPath     string         `json:"minio_path" gorm:"size:255"` // MinIO中的存储路径
-	DownloadCount int64          `json:"download_count" gorm:"default:0"`
-	IsPrerelease  bool           `json:"is_prerelease" gorm:"default:false"`
-	UploaderID    uint           `json:"uploader_id" gorm:"not null"`
-	Uploader      User           `json:"uploader" gorm:"foreignKey:UploaderID"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primarykey"`
+	PackageID          uint           `json:"package_id" gorm:"not null"`
+	Package            Package        `json:"package,omitempty" gorm:"foreignKey:PackageID"`
+	Version            string         `json:"version" gorm:"not null;size:50" binding:"required"`
+	Description        string         `json:"description" gorm:"size:500"`
+	Changelog          string         `json:"changelog" gorm:"type:text"`
+	Dependencies       string         `json:"dependencies" gorm:"type:text"` // JSON存储依赖关系
+	FileSize           int64          `json:"file_size" gorm:"not null"`
+	FileHash           string         `json:"file_hash" gorm:"size:64"`                      // SHA256哈希（未压缩内容）
+	StoredSize         int64          `json:"stored_size"`                                   // 实际存储大小
+	CompressedStored   bool           `json:"compressed_stored" gorm:"default:false"`        // 是否以gzip压缩形式存储
+	MinIOPath          string         `json:"minio_path" gorm:"size:255"`                    // MinIO中的存储路径
+	StorageClass       string         `json:"storage_class" gorm:"size:32;default:STANDARD"` // 存储层级
+	DownloadCount      int64          `json:"download_count" gorm:"default:0"`
+	InstallCount       int64          `json:"install_count" gorm:"default:0"` // 去重后的完整下载次数
+	IsPrerelease       bool           `json:"is_prerelease" gorm:"default:false"`
+	SourceRepository   string         `json:"source_repository,omitempty" gorm:"size:255"` // 构建来源仓库
+	SourceCommit       string         `json:"source_commit,omitempty" gorm:"size:64"`      // 构建来源提交
+	BuildURL           string         `json:"build_url,omitempty" gorm:"size:255"`         // 构建任务地址
+	Deprecated         bool           `json:"deprecated" gorm:"default:false;index"`
+	DeprecationMessage string         `json:"deprecation_message,omitempty" gorm:"size:500"`
+	LockVersion        int            `json:"lock_version" gorm:"not null;default:1"`  // 乐观锁版本号，每次修改元数据加1
+	LastVerifiedAt     *time.Time     `json:"last_verified_at,omitempty" gorm:"index"` // 完整性校验任务最近一次读取对象的时间
+	Corrupted          bool           `json:"corrupted" gorm:"default:false;index"`    // 最近一次校验发现内容与FileHash不一致或对象丢失
+	Pinned             bool           `json:"pinned" gorm:"-"`                         // 是否被置顶（不参与自动清理）
+	UploaderID         uint           `json:"uploader_id" gorm:"not null"`
+	Uploader           User           `json:"uploader" gorm:"foreignKey:UploaderID"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// DependencyWarnings 发布时依赖解析检查发现的问题（warn模式），JSON存储
+	DependencyWarnings []DependencyConflict `json:"dependency_warnings,omitempty" gorm:"serializer:json;type:text"`
+	// PublishedManifest 发布时的元数据快照（不可修改），依赖解析读取快照而不是可编辑的字段
+	PublishedManifest *PublishedManifest `json:"published_manifest,omitempty" gorm:"serializer:json;type:text"`
+
+	// StorageTier 包文件所在的存储：hot（主bucket）、cold（冷存储bucket）、restoring（正在移回主bucket，文件仍在冷存储）
+	StorageTier string `json:"storage_tier" gorm:"size:16;not null;default:hot;index"`
+	// StorageTierChangedAt 最近一次在冷热存储之间迁移的时间
+	StorageTierChangedAt *time.Time `json:"storage_tier_changed_at,omitempty"`
+
+	// Locked 锁定的版本不能删除，发布lock_after_minutes分钟后由后台任务锁定，管理员也可以立即锁定，只有超级管理员可以解锁
+	Locked   bool       `json:"locked" gorm:"default:false;index"`
+	LockedAt *time.Time `json:"locked_at,omitempty"`
+
+	// ScanStatus 病毒扫描状态：scanning（扫描中，不能下载）、active（可下载）、quarantined（发现病毒已隔离，不能下载）
+	ScanStatus string `json:"scan_status" gorm:"size:16;not null;default:active;index"`
+}
+
+// 包文件所在的存储
+const (
+	StorageTierHot       = "hot"
+	StorageTierCold      = "cold"
+	StorageTierRestoring = "restoring"
+)
+
+// InColdStorage 包文件是否位于冷存储bucket（移回主bucket完成前仍从冷存储读取）
+func (v *PackageVersion) InColdStorage() bool {
+	return v.StorageTier == StorageTierCold || v.StorageTier == StorageTierRestoring
+}
+
+// 依赖问题类型
+const (
+	DependencyConflictUnsatisfiable     = "conflict"            // 多个依赖方的版本约束没有共同满足的版本
+	DependencyConflictNotFound          = "not_found"           // 依赖的包不存在
+	DependencyConflictNoMatch           = "no_matching_version" // 依赖的包没有满足约束的版本
+	DependencyConflictInvalidConstraint = "invalid_constraint"  // 版本约束无法解析
+	DependencyConflictIncomplete        = "incomplete"          // 达到深度、节点数或时间上限，解析未完成
+)
+
+// DependencyConflict 发布时依赖解析发现的问题
+type DependencyConflict struct {
+	Package      string                  `json:"package,omitempty"`
+	Reason       string                  `json:"reason"`
+	Message      string                  `json:"message"`
+	Requirements []DependencyRequirement `json:"requirements,omitempty"` // 对该包的所有版本约束及其来源
+}
+
+// DependencyRequirement 依赖方对某个包的版本约束
+type DependencyRequirement struct {
+	RequiredBy string `json:"required_by"` // 依赖方，格式为 包名@版本
+	Constraint string `json:"constraint"`
 }
 
 // PackageDownload 包下载记录模型
@@ -55,57 +155,250 @@ type PackageDownload struct {
 	User             *User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	IPAddress        string         `json:"ip_address" gorm:"size:45"` // 支持IPv6
 	UserAgent        string         `json:"user_agent" gorm:"size:500"`
-	DownloadTime     time.Time      `json:"download_time" gorm:"autoCreateTime"`
+	// 异步解析的访问信息，未开启下载分析或尚未解析时为空
+	Client        *string    `json:"client,omitempty" gorm:"size:64"`
+	ClientVersion *string    `json:"client_version,omitempty" gorm:"size:64"`
+	OS            *string    `json:"os,omitempty" gorm:"size:32"`
+	Tool          *string    `json:"tool,omitempty" gorm:"size:32;index"`
+	Country       *string    `json:"country,omitempty" gorm:"size:2;index"`
+	EnrichedAt    *time.Time `json:"enriched_at,omitempty"`
+	DownloadTime  time.Time  `json:"download_time" gorm:"autoCreateTime"`
+	// Flagged 疑似爬虫流量（User-Agent匹配或IP被搜索防护标记），不计入下载数和安装数
+	Flagged bool `json:"flagged" gorm:"not null;default:false;index"`
+}
+
+// AnalyticsBucket 下载分析的单个分组
+type AnalyticsBucket struct {
+	Key       string `json:"key"` // 为空表示未知
+	Downloads int64  `json:"downloads"`
+}
+
+// PackageAnalyticsResponse 包下载分析响应
+type PackageAnalyticsResponse struct {
+	Package        string            `json:"package"`
+	Since          time.Time         `json:"since"`
+	TotalDownloads int64             `json:"total_downloads"`
+	Enriched       int64             `json:"enriched"` // 已完成解析的下载数
+	ByCountry      []AnalyticsBucket `json:"by_country"`
+	ByClient       []AnalyticsBucket `json:"by_client"`
+	ByTool         []AnalyticsBucket `json:"by_tool"`
+	ByOS           []AnalyticsBucket `json:"by_os"`
+}
+
+// PackageVersionPin 包版本置顶记录，置顶版本不会被清理或过期删除
+type PackageVersionPin struct {
+	ID               uint      `json:"id" gorm:"primarykey"`
+	PackageVersionID uint      `json:"package_version_id" gorm:"not null;uniqueIndex"`
+	PinnedBy         uint      `json:"pinned_by" gorm:"not null"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// PackageAlias 包重命名后保留的旧名称，访问旧名称时解析到当前包
+type PackageAlias struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null;size:100"`
+	PackageID uint      `json:"package_id" gorm:"not null;index"`
+	CreatedBy uint      `json:"created_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RenamePackageRequest 重命名包请求
+type RenamePackageRequest struct {
+	NewName string `json:"new_name" binding:"required,min=1,max=100"`
+}
+
+// 包名可用性检查的不可用原因
+const (
+	NameUnavailableTaken           = "taken"            // 已被其他包使用或是其他包的旧名称
+	NameUnavailableReserved        = "reserved"         // 在保留名单中
+	NameUnavailableRecentlyDeleted = "recently-deleted" // 同名包在保留期内被删除
+	NameUnavailableInvalidFormat   = "invalid-format"   // 不符合包名格式
+)
+
+// PackageNameAvailability 包名可用性检查结果
+type PackageNameAvailability struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// BulkDeprecateRequest 批量弃用/取消弃用版本请求
+type BulkDeprecateRequest struct {
+	VersionIDs []uint `json:"version_ids" binding:"required,min=1,max=500"`
+	Message    string `json:"message" binding:"max=500"`
+}
+
+// BulkDeprecateResult 批量弃用版本结果
+type BulkDeprecateResult struct {
+	DeprecatedCount        int    `json:"deprecated_count"`
+	AlreadyDeprecatedCount int    `json:"already_deprecated_count"`
+	NotFoundIDs            []uint `json:"not_found_ids"`
+}
+
+// BulkUndeprecateResult 批量取消弃用结果
+type BulkUndeprecateResult struct {
+	UndeprecatedCount  int    `json:"undeprecated_count"`
+	NotDeprecatedCount int    `json:"not_deprecated_count"`
+	NotFoundIDs        []uint `json:"not_found_ids"`
+}
+
+// PruneVersionsRequest 清理旧版本请求
+type PruneVersionsRequest struct {
+	Keep int `json:"keep" binding:"required,min=1,max=1000"` // 保留最近的版本数
+}
+
+// PruneResult 版本清理结果
+type PruneResult struct {
+	Deleted       []string `json:"deleted"`
+	SkippedPinned []string `json:"skipped_pinned"`
+	SkippedLocked []string `json:"skipped_locked"`
+	Kept          int      `json:"kept"`
+}
+
+// StorageTierChange 包版本存储层级变更记录
+type StorageTierChange struct {
+	ID               uint      `json:"id" gorm:"primarykey"`
+	PackageVersionID uint      `json:"package_version_id" gorm:"not null;index"`
+	FromTier         string    `json:"from_tier" gorm:"size:32"`
+	ToTier           string    `json:"to_tier" gorm:"size:32"`
+	Direction        string    `json:"direction" gorm:"size:16"` // promote, demote；冷热存储迁移时FromTier/ToTier为hot、cold
+	DownloadsPerDay  float64   `json:"downloads_per_day"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UploadThrottleSettings 上传限速配置，0表示不限速
+type UploadThrottleSettings struct {
+	UploadBandwidthLimitBytesPerSec int64 `json:"upload_bandwidth_limit_bytes_per_sec"`      // 单次上传
+	UserBandwidthLimitBytesPerSec   int64 `json:"user_upload_bandwidth_limit_bytes_per_sec"` // 每个用户合计
+	TrackedUsers                    int   `json:"tracked_users"`                             // 已创建限速器的用户数
+}
+
+// StorageTierSummary 存储层级分布统计
+type StorageTierSummary struct {
+	StorageClass string `json:"storage_class"`
+	Versions     int64  `json:"versions"`
+	TotalSize    int64  `json:"total_size"`
+}
+
+// IntegrityReport 存储完整性校验概况
+type IntegrityReport struct {
+	Paused            bool               `json:"paused"`
+	Verified          int64              `json:"verified"`       // 至少校验过一次的版本数
+	NeverVerified     int64              `json:"never_verified"` // 从未校验的版本数
+	Corrupted         int64              `json:"corrupted"`
+	OldestVerifiedAt  *time.Time         `json:"oldest_verified_at,omitempty"`
+	CorruptedVersions []CorruptedVersion `json:"corrupted_versions"` // 最多返回100个
+}
+
+// CorruptedVersion 校验失败的包版本
+type CorruptedVersion struct {
+	PackageName    string     `json:"package_name"`
+	Version        string     `json:"version"`
+	MinIOPath      string     `json:"minio_path"`
+	LastVerifiedAt *time.Time `json:"last_verified_at"`
 }
 
 // CreatePackageRequest 创建包请求
 type CreatePackageRequest struct {
-	Name        string   `json:"name" binding:"required,min=1,max=100"`
-	Description string   `json:"description" binding:"max=500"`
-	Author      string   `json:"author" binding:"max=100"`
-	Homepage    string   `json:"homepage" binding:"max=255,url"`
-	Repository  string   `json:"repository" binding:"max=255,url"`
-	License     string   `json:"license" binding:"max=50"`
-	Keywords    []string `json:"keywords"`
-	IsPrivate   bool     `json:"is_private"`
+	Name                     string   `json:"name" binding:"required,min=1,max=100"`
+	Description              string   `json:"description" binding:"max=500"`
+	Author                   string   `json:"author" binding:"max=100"`
+	Homepage                 string   `json:"homepage" binding:"max=255,url"`
+	Repository               string   `json:"repository" binding:"max=255,url"`
+	License                  string   `json:"license" binding:"max=50"`
+	Keywords                 []string `json:"keywords"`
+	LongDescription          string   `json:"long_description"` // 最大长度见packages.max_long_description_length
+	FundingURL               string   `json:"funding_url" binding:"omitempty,max=255,url"`
+	BugTrackerURL            string   `json:"bug_tracker_url" binding:"omitempty,max=255,url"`
+	Documentation            string   `json:"documentation" binding:"omitempty,max=255,url"`
+	IsPrivate                bool     `json:"is_private"`
+	RequireMonotonicVersions bool     `json:"require_monotonic_versions"`
+	AutoPrereleaseDetection  bool     `json:"auto_prerelease_detection"`
+	DisallowPrereleaseLatest bool     `json:"disallow_prerelease_latest"`
+	UploadLocked             bool     `json:"upload_locked"`
+	CategoryIDs              []uint   `json:"category_ids" binding:"max=5"` // 分类ID，必须是已有的分类
 }
 
 // UpdatePackageRequest 更新包请求
 type UpdatePackageRequest struct {
-	Description string   `json:"description" binding:"max=500"`
-	Author      string   `json:"author" binding:"max=100"`
-	Homepage    string   `json:"homepage" binding:"max=255,url"`
-	Repository  string   `json:"repository" binding:"max=255,url"`
-	License     string   `json:"license" binding:"max=50"`
-	Keywords    []string `json:"keywords"`
-	IsPrivate   *bool    `json:"is_private"` // 使用指针以区分false和未设置
+	Description              string   `json:"description" binding:"max=500"`
+	Author                   string   `json:"author" binding:"max=100"`
+	Homepage                 string   `json:"homepage" binding:"max=255,url"`
+	Repository               string   `json:"repository" binding:"max=255,url"`
+	License                  string   `json:"license" binding:"max=50"`
+	Keywords                 []string `json:"keywords"`
+	LongDescription          string   `json:"long_description"`
+	FundingURL               string   `json:"funding_url" binding:"omitempty,max=255,url"`
+	BugTrackerURL            string   `json:"bug_tracker_url" binding:"omitempty,max=255,url"`
+	Documentation            string   `json:"documentation" binding:"omitempty,max=255,url"`
+	IsPrivate                *bool    `json:"is_private"` // 使用指针以区分false和未设置
+	KeepRecentVersions       *int     `json:"keep_recent_versions" binding:"omitempty,min=0,max=1000"`
+	RequireMonotonicVersions *bool    `json:"require_monotonic_versions"`
+	AutoPrereleaseDetection  *bool    `json:"auto_prerelease_detection"`
+	DisallowPrereleaseLatest *bool    `json:"disallow_prerelease_latest"`
+	UploadLocked             *bool    `json:"upload_locked"`
+	IfVersion                *int     `json:"if_version"` // 客户端读取到的lock_version，不一致时返回409
+
+	// CategoryIDs 设置时替换包的分类，空数组清除所有分类
+	CategoryIDs []uint `json:"category_ids" binding:"max=5"`
+}
+
+// UpdatePackageVersionRequest 更新包版本元数据请求，未设置的字段保持不变
+type UpdatePackageVersionRequest struct {
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	Changelog   *string `json:"changelog"`
+	IfVersion   *int    `json:"if_version"` // 客户端读取到的lock_version，不一致时返回409
 }
 
 // CreatePackageVersionRequest 创建包版本请求
 type CreatePackageVersionRequest struct {
-	Version      string            `json:"version" binding:"required,max=50"`
-	Description  string            `json:"description" binding:"max=500"`
-	Changelog    string            `json:"changelog"`
-	Dependencies map[string]string `json:"dependencies"` // package_name: version
-	IsPrerelease bool              `json:"is_prerelease"`
+	Version       string            `json:"version" binding:"required,max=50"`
+	Description   string            `json:"description" binding:"max=500"`
+	Changelog     string            `json:"changelog"`
+	Dependencies  map[string]string `json:"dependencies"` // package_name: version
+	IsPrerelease  bool              `json:"is_prerelease"`
+	ForceBackfill bool              `json:"force_backfill"` // 所有者补发低于最高版本的旧版本时跳过单调性检查
+	// AddKeywords 追加到包的关键字（去重）
+	AddKeywords []string `json:"add_keywords" binding:"omitempty,max=20,dive,min=1,max=50"`
+	// 来源信息
+	SourceRepository string `json:"source_repository" binding:"omitempty,max=255,url"`
+	SourceCommit     string `json:"source_commit" binding:"omitempty,max=64"`
+	BuildURL         string `json:"build_url" binding:"omitempty,max=255,url"`
 }
 
 // PackageListResponse 包列表响应
 type PackageListResponse struct {
-	Packages   []Package `json:"packages"`
-	Total      int64     `json:"total"`
-	Page       int       `json:"page"`
-	PageSize   int       `json:"page_size"`
-	TotalPages int       `json:"total_pages"`
+	Packages []Package `json:"packages"`
+	Pagination
 }
 
 // PackageVersionListResponse 包版本列表响应
 type PackageVersionListResponse struct {
-	Versions   []PackageVersion `json:"versions"`
-	Total      int64            `json:"total"`
-	Page       int              `json:"page"`
-	PageSize   int              `json:"page_size"`
-	TotalPages int              `json:"total_pages"`
+	Versions []PackageVersion `json:"versions"`
+	Pagination
+}
+
+// RenameObjectRequest 修正版本对象键请求
+type RenameObjectRequest struct {
+	Destination string `json:"destination"` // 目标对象键，为空时使用当前命名方案生成的键
+	DryRun      bool   `json:"dry_run"`     // 为true时只校验并返回将执行的操作
+}
+
+// RenameObjectResult 修正版本对象键的结果
+type RenameObjectResult struct {
+	Package     string `json:"package"`
+	Version     string `json:"version"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	DryRun      bool   `json:"dry_run"`
+	Renamed     bool   `json:"renamed"` // 是否已执行，dry_run时为false
+}
+
+// UnrecognizedLicense 无法自动规范化的许可证值及使用它的包数量
+type UnrecognizedLicense struct {
+	License      string   `json:"license"`
+	PackageCount int64    `json:"package_count"`
+	Suggestions  []string `json:"suggestions"` // 相近的合法标识符
 }
 
 // SearchPackagesRequest 包搜索请求
@@ -115,19 +408,71 @@ type SearchPackagesRequest struct {
 	Keywords  string `json:"keywords" form:"keywords"`
 	License   string `json:"license" form:"license"`
 	IsPrivate *bool  `json:"is_private" form:"is_private"`
-	Page      int    `json:"page" form:"page"`
-	PageSize  int    `json:"page_size" form:"page_size"`
+	// TrustLevel 按有效信任等级过滤
+	TrustLevel string `json:"trust_level" form:"trust_level" binding:"omitempty,oneof=unverified verified official"`
+	// Category 按分类slug过滤
+	Category string `json:"category" form:"category"`
+	// Highlight 为true时在name_highlighted和description_highlighted中用<em>标记匹配的搜索词
+	Highlight bool `json:"highlight" form:"highlight"`
+	// Page、PageSize 查询参数由handler按parsePage解析，非法值使用默认值而不是返回400
+	Page     int `json:"page" form:"-"`
+	PageSize int `json:"page_size" form:"-"`
+}
+
+// PackageDownloadListResponse 下载记录列表响应
+type PackageDownloadListResponse struct {
+	Downloads []PackageDownload `json:"downloads"`
+	Pagination
 }
 
+// 热门包排序依据
+const (
+	RankByInstalls  = "installs"
+	RankByDownloads = "downloads"
+)
+
 // PackageStatsResponse 包统计响应
 type PackageStatsResponse struct {
 	TotalPackages   int64            `json:"total_packages"`
 	TotalVersions   int64            `json:"total_versions"`
 	TotalDownloads  int64            `json:"total_downloads"`
+	TotalInstalls   int64            `json:"total_installs"`
+	RankedBy        string           `json:"ranked_by"`        // 热门包的排序依据：installs, downloads
 	RecentDownloads int64            `json:"recent_downloads"` // 最近30天下载量
 	PopularPackages []Package        `json:"popular_packages"` // 热门包
 	RecentPackages  []Package        `json:"recent_packages"`  // 最新包
 	RecentVersions  []PackageVersion `json:"recent_versions"`  // 最新版本
+
+	ComputedAt time.Time `json:"computed_at"` // 统计的计算时间，启用缓存时可能早于请求时间
+}
+
+// 图表类型
+const (
+	ChartTypeBar  = "bar"
+	ChartTypePie  = "pie"
+	ChartTypeLine = "line"
+)
+
+// ChartPoint 图表中的一个数据点
+type ChartPoint struct {
+	Label string `json:"label"`
+	Value int64  `json:"value"`
+}
+
+// Distribution 一组可直接用于绘图的分布数据
+type Distribution struct {
+	ChartType string       `json:"chart_type"` // 建议的图表类型：bar, pie, line
+	Data      []ChartPoint `json:"data"`
+}
+
+// EcosystemDistribution 包生态统计，只统计公开包
+type EcosystemDistribution struct {
+	Licenses           Distribution `json:"licenses"`             // 许可证分布，未声明的记为"none"
+	Keywords           Distribution `json:"keywords"`             // 包数最多的20个关键字
+	VersionCounts      Distribution `json:"version_counts"`       // 按版本数分组的包数
+	FileSizes          Distribution `json:"file_sizes"`           // 按文件大小分组的版本数
+	MonthlyNewPackages Distribution `json:"monthly_new_packages"` // 最近12个月每月新建的包数
+	GeneratedAt        time.Time    `json:"generated_at"`
 }
 
 // TableName 指定Package表名
@@ -144,3 +489,13 @@ func (PackageVersion) TableName() string {
 func (PackageDownload) TableName() string {
 	return "package_downloads"
 }
+
+// TableName 指定StorageTierChange表名
+func (StorageTierChange) TableName() string {
+	return "storage_tier_changes"
+}
+
+// TableName 指定PackageVersionPin表名
+func (PackageVersionPin) TableName() string {
+	return "package_version_pins"
+}
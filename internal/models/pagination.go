@@ -0,0 +1,75 @@
+package models
+
+import "math"
+
+// 分页默认值
+const (
+	// DefaultPageSize 未指定或指定了非法page_size时的每页数量
+	DefaultPageSize = 20
+	// MaxPageSize 未单独限制时的每页数量上限
+	MaxPageSize = 100
+	// MaxPage page的上限，超过时按此值处理，避免计算偏移量时溢出
+	MaxPage = math.MaxInt32
+)
+
+// Pagination 列表响应的分页信息，嵌入各列表响应中，序列化后与列表数据位于同一层级
+type Pagination struct {
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalPages int   `json:"total_pages"`
+	HasNext    bool  `json:"has_next"`
+	HasPrev    bool  `json:"has_prev"`
+}
+
+// NormalizePage 规范化分页参数：page小于1时为1，超过MaxPage时为MaxPage；pageSize小于1时为defaultSize，超过maxSize时为maxSize
+// defaultSize、maxSize小于1时分别使用DefaultPageSize、MaxPageSize，保证返回的pageSize大于0
+func NormalizePage(page, pageSize, defaultSize, maxSize int) (int, int) {
+	if maxSize < 1 {
+		maxSize = MaxPageSize
+	}
+	if defaultSize < 1 || defaultSize > maxSize {
+		defaultSize = min(DefaultPageSize, maxSize)
+	}
+	if page < 1 {
+		page = 1
+	} else if page > MaxPage {
+		page = MaxPage
+	}
+	if pageSize < 1 {
+		pageSize = defaultSize
+	} else if pageSize > maxSize {
+		pageSize = maxSize
+	}
+	return page, pageSize
+}
+
+// NewPagination 根据分页参数和总数计算分页信息，非法的page/pageSize按NormalizePage的默认规则处理
+func NewPagination(page, pageSize int, total int64) Pagination {
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if page < 1 {
+		page = 1
+	} else if page > MaxPage {
+		page = MaxPage
+	}
+	if total < 0 {
+		total = 0
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return Pagination{
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
+// Offset 当前页第一条记录的偏移量
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
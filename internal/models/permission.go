@@ -0,0 +1,15 @@
+package models
+
+// PackagePermission 调用方对包的一项权限
+type PackagePermission struct {
+	Granted bool `json:"granted"`
+	// Reason 授予时为public、owner、maintainer、reader或admin；拒绝时为archived（包已归档）、token_scope（token不包含该包），其他情况为空
+	Reason string `json:"reason,omitempty"`
+}
+
+// PackagePermissionsResponse 调用方对包的有效权限
+type PackagePermissionsResponse struct {
+	Package       string                       `json:"package"`
+	Authenticated bool                         `json:"authenticated"`
+	Permissions   map[string]PackagePermission `json:"permissions"` // 权限名 -> 是否授予及原因
+}
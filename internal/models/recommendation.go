@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PackageRecommendation 包推荐关系，由后台任务根据同一用户同一天内共同下载的包计算
+type PackageRecommendation struct {
+	ID                   uint      `json:"id" gorm:"primarykey"`
+	PackageID            uint      `json:"package_id" gorm:"not null;uniqueIndex:idx_package_recommendation"`
+	RecommendedPackageID uint      `json:"recommended_package_id" gorm:"not null;uniqueIndex:idx_package_recommendation"`
+	Score                float64   `json:"score"` // 下载过该包的会话中同时下载推荐包的比例
+	ComputedAt           time.Time `json:"computed_at"`
+}
+
+// TableName 指定表名
+func (PackageRecommendation) TableName() string {
+	return "package_recommendations"
+}
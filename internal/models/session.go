@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// UserSession 用户登录会话模型（每个JWT对应一条记录，用于按设备吊销）
+type UserSession struct {
+	ID                uint       `json:"id" gorm:"primarykey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	DeviceName        string     `json:"device_name" gorm:"size:100"`
+	DeviceFingerprint string     `json:"device_fingerprint" gorm:"size:64"`
+	IPAddress         string     `json:"ip_address" gorm:"size:45"`
+	JWTJTI            string     `json:"-" gorm:"column:jwt_jti;uniqueIndex;not null;size:36"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	Current           bool       `json:"current" gorm:"-"` // 是否为当前请求所用会话
+
+	// Packages token限定可操作的包名，为空表示不限制
+	Packages []string `json:"packages,omitempty" gorm:"serializer:json;type:text"`
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive 会话是否有效（未吊销且未过期）
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// CreateScopedTokenRequest 创建限定包范围的token请求
+type CreateScopedTokenRequest struct {
+	Packages []string `json:"packages" binding:"required,min=1,max=50,dive,required"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// UserSuspension 用户暂停记录，同一用户同时最多一条生效中的记录
+type UserSuspension struct {
+	ID                uint       `json:"id" gorm:"primarykey"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	SuspendedByUserID uint       `json:"suspended_by_user_id" gorm:"not null"`
+	Reason            string     `json:"reason" gorm:"size:500;not null"`
+	ExpiresAt         *time.Time `json:"expires_at" gorm:"index"` // 为空表示无限期，直到手动解除
+	LiftedAt          *time.Time `json:"lifted_at"`               // 解除时间（手动解除或到期后由后台任务解除）
+	LiftedByUserID    *uint      `json:"lifted_by_user_id"`       // 手动解除的管理员，到期自动解除时为空
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserSuspension) TableName() string {
+	return "user_suspensions"
+}
+
+// IsActive 暂停是否仍然生效（未解除且未到期）
+func (s *UserSuspension) IsActive(now time.Time) bool {
+	return s.LiftedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
+// SuspendUserRequest 暂停用户请求结构体
+type SuspendUserRequest struct {
+	Reason   string `json:"reason" binding:"required,max=500"`
+	Duration string `json:"duration"` // 暂停时长（如 72h），为空表示无限期
+}
@@ -0,0 +1,72 @@
+package models
+
+import "gorm.io/gorm"
+
+// 信任等级，由高到低为 official > verified > unverified
+const (
+	TrustLevelUnverified = "unverified"
+	TrustLevelVerified   = "verified"
+	TrustLevelOfficial   = "official"
+)
+
+// trustLevelRanks 信任等级排序值
+var trustLevelRanks = map[string]int{
+	TrustLevelUnverified: 0,
+	TrustLevelVerified:   1,
+	TrustLevelOfficial:   2,
+}
+
+// TrustLevelRank 返回信任等级的排序值，未知或空值视为unverified
+func TrustLevelRank(level string) int {
+	return trustLevelRanks[level]
+}
+
+// MaxTrustLevel 返回两个信任等级中较高的一个
+func MaxTrustLevel(a, b string) string {
+	if TrustLevelRank(b) > TrustLevelRank(a) {
+		return b
+	}
+	if a == "" {
+		return TrustLevelUnverified
+	}
+	return a
+}
+
+// TrustLevelsUpTo 返回不高于指定等级的所有信任等级
+func TrustLevelsUpTo(level string) []string {
+	levels := make([]string, 0, len(trustLevelRanks))
+	for _, l := range []string{TrustLevelUnverified, TrustLevelVerified, TrustLevelOfficial} {
+		if TrustLevelRank(l) <= TrustLevelRank(level) {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+// IsTrusted 信任等级是否显示认证徽章
+func IsTrusted(level string) bool {
+	return TrustLevelRank(level) >= TrustLevelRank(TrustLevelVerified)
+}
+
+// UpdateTrustLevelRequest 设置信任等级请求（超级管理员使用）
+type UpdateTrustLevelRequest struct {
+	TrustLevel string `json:"trust_level" binding:"required,oneof=unverified verified official"`
+}
+
+// TrustLevelChange 信任等级变更结果
+type TrustLevelChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// AfterFind GORM钩子：计算包的有效信任等级和图标地址
+// 包的信任等级不低于所有者的信任等级，所有者需被预加载才会参与计算
+func (p *Package) AfterFind(tx *gorm.DB) error {
+	p.IconURL = PackageIconURL(p.Name, p.IconHash)
+	p.OwnTrustLevel = p.TrustLevel
+	if p.Owner.ID != 0 {
+		p.TrustLevel = MaxTrustLevel(p.TrustLevel, p.Owner.TrustLevel)
+	}
+	p.TrustedBadge = IsTrusted(p.TrustLevel)
+	return nil
+}
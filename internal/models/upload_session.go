@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// 预签名上传会话状态
+const (
+	UploadSessionPending    = "pending"    // 已签发上传URL，等待客户端上传并回调
+	UploadSessionProcessing = "processing" // 已确认对象，正在计算哈希并创建版本
+	UploadSessionCompleted  = "completed"  // 版本已创建
+	UploadSessionFailed     = "failed"     // 处理失败，原因见error，暂存对象已删除
+)
+
+// UploadSession 预签名上传会话，客户端直接上传到暂存对象，回调upload-complete后由服务端校验并创建版本
+// upload_id同时作为处理任务的job_id
+type UploadSession struct {
+	ID          string     `json:"upload_id" gorm:"primarykey;size:32"`
+	PackageID   uint       `json:"package_id" gorm:"not null;index"`
+	PackageName string     `json:"package" gorm:"size:100;not null"`
+	Version     string     `json:"version" gorm:"size:50;not null"`
+	UploaderID  uint       `json:"uploader_id" gorm:"not null;index"`
+	ObjectKey   string     `json:"-" gorm:"size:255;not null"` // 暂存对象键
+	Size        int64      `json:"size" gorm:"not null"`       // 创建会话时声明的文件大小，回调时必须一致
+	Metadata    string     `json:"-" gorm:"type:text"`         // 版本元数据（CreatePackageVersionRequest的JSON）
+	Status      string     `json:"status" gorm:"size:20;not null;index"`
+	Error       string     `json:"error,omitempty" gorm:"size:500"`
+	VersionID   *uint      `json:"version_id,omitempty"` // 完成后创建的版本
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null;index"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// CreateUploadSessionRequest 创建预签名上传会话请求，版本元数据与直接上传相同
+type CreateUploadSessionRequest struct {
+	CreatePackageVersionRequest
+	Size int64 `json:"size" binding:"required,min=1"` // 文件大小（字节）
+}
+
+// CreateUploadSessionResponse 创建预签名上传会话响应
+type CreateUploadSessionResponse struct {
+	UploadID  string    `json:"upload_id"`
+	UploadURL string    `json:"upload_url"` // 使用PUT请求上传文件内容
+	ExpiresAt time.Time `json:"expires_at"` // 上传URL和会话的过期时间
+}
+
+// CompleteUploadRequest 预签名上传完成回调请求
+type CompleteUploadRequest struct {
+	UploadID string `json:"upload_id" binding:"required,max=32"`
+	ETag     string `json:"etag" binding:"max=100"` // 上传响应中的ETag，非空时与存储中的对象比对
+	Size     int64  `json:"size" binding:"required,min=1"`
+}
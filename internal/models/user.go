@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,18 +11,23 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        uint           `json:"id" gorm:"primarykey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null;size:50" binding:"required,min=3,max=50"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null;size:100" binding:"required,email"`
-	Password  string         `json:"-" gorm:"not null;size:255" binding:"required,min=6"`
-	Nickname  string         `json:"nickname" gorm:"size:50"`
-	Avatar    string         `json:"avatar" gorm:"size:255"`
-	Role      string         `json:"role" gorm:"not null;default:user;size:20"`
-	Status    UserStatus     `json:"status" gorm:"not null;default:1"`
-	LastLogin *time.Time     `json:"last_login"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID         uint           `json:"id" gorm:"primarykey"`
+	Username   string         `json:"username" gorm:"uniqueIndex;not null;size:50" binding:"required,min=3,max=50"`
+	Email      string         `json:"email" gorm:"uniqueIndex;not null;size:100" binding:"required,email"`
+	Password   string         `json:"-" gorm:"not null;size:255" binding:"required,min=6"`
+	Nickname   string         `json:"nickname" gorm:"size:50"`
+	Avatar     string         `json:"avatar" gorm:"size:255"`
+	Role       string         `json:"role" gorm:"not null;default:user;size:20"`
+	Status     UserStatus     `json:"status" gorm:"not null;default:1"`
+	TrustLevel string         `json:"trust_level" gorm:"size:16;not null;default:unverified"`
+	LastLogin  *time.Time     `json:"last_login"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// 两步验证（TOTP），TOTPSecret在设置时生成，启用前须用一次验证码确认
+	TOTPSecret  string `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPEnabled bool   `json:"totp_enabled" gorm:"column:totp_enabled;not null;default:false"`
 }
 
 // UserStatus 用户状态枚举
@@ -52,11 +60,23 @@ func (s UserStatus) String() string {
 	}
 }
 
+// ParseUserStatus 按名称（inactive、active、suspended、banned，不区分大小写）或数值（0-3）解析用户状态
+func ParseUserStatus(value string) (UserStatus, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for s := UserStatusInactive; s <= UserStatusBanned; s++ {
+		if value == s.String() || value == strconv.Itoa(int(s)) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid status %q, expected inactive, active, suspended, banned or 0-3", value)
+}
+
 // UserRole 用户角色常量
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
-	RoleSuper = "super"
+	RoleUser    = "user"
+	RoleAdmin   = "admin"
+	RoleSuper   = "super"
+	RoleSupport = "support" // 客服，只能查看用户等信息，权限见authz.DefaultRules
 )
 
 // TableName 指定表名
@@ -78,6 +98,10 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.Nickname == "" {
 		u.Nickname = u.Username
 	}
+	// 设置默认信任等级
+	if u.TrustLevel == "" {
+		u.TrustLevel = TrustLevelUnverified
+	}
 	return nil
 }
 
@@ -99,29 +123,46 @@ func (u *User) IsSuper() bool {
 // ToPublicUser 转换为公开用户信息（隐藏敏感信息）
 func (u *User) ToPublicUser() *PublicUser {
 	return &PublicUser{
-		ID:        u.ID,
-		Username:  u.Username,
-		Nickname:  u.Nickname,
-		Avatar:    u.Avatar,
-		Status:    u.Status.String(),
-		CreatedAt: u.CreatedAt,
+		ID:           u.ID,
+		Username:     u.Username,
+		Nickname:     u.Nickname,
+		Avatar:       u.Avatar,
+		Status:       u.Status.String(),
+		TrustLevel:   MaxTrustLevel(u.TrustLevel, ""),
+		TrustedBadge: IsTrusted(u.TrustLevel),
+		CreatedAt:    u.CreatedAt,
 	}
 }
 
 // PublicUser 公开用户信息结构体
 type PublicUser struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Nickname  string    `json:"nickname"`
-	Avatar    string    `json:"avatar"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID           uint      `json:"id"`
+	Username     string    `json:"username"`
+	Nickname     string    `json:"nickname"`
+	Avatar       string    `json:"avatar"`
+	Status       string    `json:"status"`
+	TrustLevel   string    `json:"trust_level"`
+	TrustedBadge bool      `json:"trusted_badge"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 // LoginRequest 登录请求结构体
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// TOTPCode 已启用两步验证的用户必须提供
+	TOTPCode string `json:"totp_code"`
+}
+
+// TOTPCodeRequest 启用或关闭两步验证请求结构体
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TOTPSetupResponse 两步验证设置响应结构体
+type TOTPSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
 }
 
 // RegisterRequest 注册请求结构体
@@ -144,7 +185,7 @@ type UpdateUserRequest struct {
 	Nickname string     `json:"nickname" binding:"max=50"`
 	Avatar   string     `json:"avatar" binding:"max=255"`
 	Email    string     `json:"email" binding:"email"`
-	Role     string     `json:"role" binding:"oneof=user admin super"`
+	Role     string     `json:"role" binding:"oneof=user admin super support"`
 	Status   UserStatus `json:"status" binding:"oneof=0 1 2 3"`
 }
 
@@ -153,3 +194,25 @@ type LoginResponse struct {
 	User  *PublicUser `json:"user"`
 	Token string      `json:"token"`
 }
+
+// SetupToken 首次启动的一次性安装令牌，只保存SHA-256摘要；每个实例启动时各写入一条，创建管理员后全部删除
+type SetupToken struct {
+	ID        uint      `gorm:"primarykey"`
+	TokenHash string    `gorm:"uniqueIndex;size:64;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// TableName 指定表名
+func (SetupToken) TableName() string {
+	return "setup_tokens"
+}
+
+// SetupRequest 首次启动创建管理员请求结构体
+type SetupRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+	Nickname string `json:"nickname" binding:"max=50"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// 批量清理时版本受保护的原因
+const (
+	CleanupSkipPinned   = "pinned"   // 置顶版本
+	CleanupSkipLocked   = "locked"   // 锁定版本
+	CleanupSkipDefault  = "default"  // 最新版本或最新正式版本
+	CleanupSkipRetained = "retained" // 包设置的最近保留版本
+)
+
+// VersionCleanupRequest 批量清理版本请求，各条件同时满足的版本才会被清理，至少需要prerelease_only、older_than、version_glob之一
+type VersionCleanupRequest struct {
+	PrereleaseOnly bool       `json:"prerelease_only"`
+	OlderThan      *time.Time `json:"older_than"`                         // 只清理早于该时间发布的版本
+	VersionGlob    string     `json:"version_glob" binding:"max=128"`     // 版本号匹配模式，如0.0.0-ci.*
+	KeepLast       int        `json:"keep_last" binding:"min=0,max=1000"` // 符合条件的版本中保留最近发布的N个
+	Confirm        bool       `json:"confirm"`                            // 为false时只返回预览，不删除
+}
+
+// VersionCleanupItem 符合清理条件的版本
+type VersionCleanupItem struct {
+	Version      string    `json:"version"`
+	IsPrerelease bool      `json:"is_prerelease"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// VersionCleanupSkip 符合条件但受保护而未清理的版本
+type VersionCleanupSkip struct {
+	Version string `json:"version"`
+	Reason  string `json:"reason"` // pinned、locked、default或retained
+}
+
+// VersionCleanupFailure 删除失败的版本
+type VersionCleanupFailure struct {
+	Version string `json:"version"`
+	Error   string `json:"error"`
+}
+
+// VersionCleanupResult 批量清理版本结果，dry_run为true时matched为将要删除的版本
+type VersionCleanupResult struct {
+	DryRun    bool                    `json:"dry_run"`
+	Matched   []VersionCleanupItem    `json:"matched"`
+	Deleted   []string                `json:"deleted"`
+	Failed    []VersionCleanupFailure `json:"failed"`
+	Skipped   []VersionCleanupSkip    `json:"skipped"`
+	Remaining int                     `json:"remaining"` // 超出单次上限未处理的版本数，再次调用可继续清理
+}
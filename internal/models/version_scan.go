@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 版本的病毒扫描状态
+const (
+	ScanStatusScanning    = "scanning"
+	ScanStatusActive      = "active"
+	ScanStatusQuarantined = "quarantined"
+)
+
+// 单次扫描的结论
+const (
+	ScanVerdictClean    = "clean"
+	ScanVerdictInfected = "infected"
+	ScanVerdictError    = "error" // 扫描未完成（引擎不可用、超时等），版本保持scanning，由后台任务重试
+)
+
+// VersionScanResult 版本的一次病毒扫描记录，上传后的扫描和管理员触发的重新扫描都会记录
+type VersionScanResult struct {
+	ID               uint      `json:"id" gorm:"primarykey"`
+	PackageVersionID uint      `json:"package_version_id" gorm:"not null;index"`
+	Engine           string    `json:"engine" gorm:"size:32;not null"`
+	Verdict          string    `json:"verdict" gorm:"size:16;not null"`
+	Signature        string    `json:"signature,omitempty" gorm:"size:255"` // 命中的病毒特征名
+	Error            string    `json:"error,omitempty" gorm:"size:500"`
+	TriggeredBy      *uint     `json:"triggered_by,omitempty"` // 触发重新扫描的管理员，上传后的自动扫描为空
+	ScannedAt        time.Time `json:"scanned_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (VersionScanResult) TableName() string {
+	return "version_scan_results"
+}
+
+// VersionScanReport 版本的扫描状态和扫描记录（最新的在前），仅包所有者和管理员可见
+type VersionScanReport struct {
+	Package    string              `json:"package"`
+	Version    string              `json:"version"`
+	ScanStatus string              `json:"scan_status"`
+	Results    []VersionScanResult `json:"results"`
+}
+
+// ReleaseQuarantineRequest 解除隔离请求
+type ReleaseQuarantineRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"` // 解除原因，记录在审计日志中
+}
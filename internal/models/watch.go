@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PackageWatcher 用户关注的包，包发布新版本时通知关注者
+type PackageWatcher struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	PackageID uint      `json:"package_id" gorm:"not null;uniqueIndex:idx_package_watcher"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_package_watcher;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WatchStatus 关注或取消关注后的状态
+type WatchStatus struct {
+	Package    string `json:"package"`
+	Watching   bool   `json:"watching"`
+	WatchCount int64  `json:"watch_count"`
+}
+
+// WatchedPackage 用户关注的包及其最新版本
+type WatchedPackage struct {
+	Package       string     `json:"package"`
+	Description   string     `json:"description"`
+	WatchCount    int64      `json:"watch_count"`
+	LatestVersion string     `json:"latest_version,omitempty"` // 没有版本时为空
+	PublishedAt   *time.Time `json:"published_at,omitempty"`   // 最新版本的发布时间
+	WatchedAt     time.Time  `json:"watched_at"`
+}
+
+// WatchedPackageList 关注列表分页响应
+type WatchedPackageList struct {
+	Packages []WatchedPackage `json:"packages"`
+	Pagination
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// WikiPage 包的文档页面，内容存储在数据库中（页面较小，不使用MinIO）
+type WikiPage struct {
+	ID              uint      `json:"id" gorm:"primarykey"`
+	PackageID       uint      `json:"package_id" gorm:"not null;uniqueIndex:idx_wiki_package_slug"`
+	Slug            string    `json:"slug" gorm:"size:100;not null;uniqueIndex:idx_wiki_package_slug"`
+	Title           string    `json:"title" gorm:"size:200;not null"`
+	ContentMarkdown string    `json:"content_markdown,omitempty" gorm:"type:text"` // 保存前已清理可能导致XSS的内容
+	AuthorUserID    uint      `json:"author_user_id" gorm:"not null"`              // 最近一次修改的用户
+	Revision        int       `json:"revision" gorm:"not null;default:1"`          // 每次修改加1
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (WikiPage) TableName() string {
+	return "package_wiki_pages"
+}
+
+// WikiPageRevision 文档页面的历史版本，每次创建或修改页面时记录
+type WikiPageRevision struct {
+	ID              uint      `json:"id" gorm:"primarykey"`
+	PageID          uint      `json:"page_id" gorm:"not null;uniqueIndex:idx_wiki_page_revision"`
+	Revision        int       `json:"revision" gorm:"not null;uniqueIndex:idx_wiki_page_revision"`
+	Title           string    `json:"title" gorm:"size:200;not null"`
+	ContentMarkdown string    `json:"content_markdown" gorm:"type:text"`
+	AuthorUserID    uint      `json:"author_user_id" gorm:"not null"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// CreateWikiPageRequest 创建文档页面请求
+type CreateWikiPageRequest struct {
+	Slug            string `json:"slug" binding:"required,max=100"`
+	Title           string `json:"title" binding:"required,max=200"`
+	ContentMarkdown string `json:"content_markdown" binding:"max=200000"`
+}
+
+// UpdateWikiPageRequest 修改文档页面请求，未提供的字段保持不变
+// 携带if_revision时只在页面未被他人修改的情况下生效，否则返回409
+type UpdateWikiPageRequest struct {
+	Title           *string `json:"title" binding:"omitempty,min=1,max=200"`
+	ContentMarkdown *string `json:"content_markdown" binding:"omitempty,max=200000"`
+	IfRevision      *int    `json:"if_revision"`
+}
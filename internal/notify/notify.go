@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"webservice/internal/config"
+	"webservice/internal/httpclient"
+	"webservice/internal/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 通知渠道
+const (
+	ChannelLog     = "log"
+	ChannelWebhook = "webhook"
+)
+
+// Notification 发送给单个用户的通知
+type Notification struct {
+	Type     string `json:"type"` // 通知类型，如version_published
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Package  string `json:"package"`
+	Version  string `json:"version,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Notifier 通知发送渠道，实现需支持并发调用
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// New 按配置创建通知渠道，未配置时写入日志
+func New(cfg config.NotifyConfig, httpClients *httpclient.Factory) (Notifier, error) {
+	switch cfg.Channel {
+	case "", ChannelLog:
+		return LogNotifier{}, nil
+	case ChannelWebhook:
+		if cfg.WebhookURL == "" {
+			return nil, fmt.Errorf("notify.webhook_url is required for the webhook channel")
+		}
+		return &WebhookNotifier{url: cfg.WebhookURL, client: httpClients.Client()}, nil
+	default:
+		return nil, fmt.Errorf("unknown notify channel: %s", cfg.Channel)
+	}
+}
+
+// LogNotifier 将通知写入日志，用于开发环境或由日志系统转发
+type LogNotifier struct{}
+
+// Notify 记录一条通知日志
+func (LogNotifier) Notify(_ context.Context, n Notification) error {
+	logger.WithFields(logrus.Fields{
+		"type":    n.Type,
+		"user_id": n.UserID,
+		"package": n.Package,
+		"version": n.Version,
+	}).Info(n.Message)
+	return nil
+}
+
+// WebhookNotifier 将通知以JSON POST到配置的地址，由接收方转发为邮件、IM消息等
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Notify 发送一条通知，非2xx响应视为失败
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/events"
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 未配置时的分发参数
+const (
+	defaultDispatchInterval = 2 * time.Second
+	defaultBatchSize        = 100
+	defaultRetention        = 7 * 24 * time.Hour
+)
+
+// settleDelay 只投递创建超过该时长的事件
+// 并发事务的提交顺序可能与ID顺序不同，留出时间让较小ID的事务先提交，避免消费进度越过尚未可见的事件
+const settleDelay = 5 * time.Second
+
+var (
+	// outboxDelivered 成功投递的事件数，按消费者区分
+	outboxDelivered = metrics.NewCounterVec(
+		"outbox_events_delivered_total",
+		"Total number of outbox events delivered to consumers.",
+		"consumer",
+	)
+	// outboxFailures 投递失败次数，失败的事件会在下一轮重试
+	outboxFailures = metrics.NewCounterVec(
+		"outbox_delivery_failures_total",
+		"Total number of failed outbox event deliveries.",
+		"consumer",
+	)
+)
+
+// Consumer 发件箱事件消费者
+// 投递语义为至少一次：处理成功但进度未保存时（如进程崩溃）事件会再次投递，消费者需按event.ID保证幂等
+type Consumer interface {
+	// Name 消费者名称，作为投递进度的键，修改名称会从头开始消费
+	Name() string
+	// Handle 处理单个事件，返回错误时停止该消费者本轮投递，下一轮从该事件重试
+	Handle(ctx context.Context, event events.Event) error
+}
+
+// Dispatcher 发件箱分发任务，在任务调度器中定期执行，按事件ID顺序投递给各消费者并分别记录进度
+type Dispatcher struct {
+	db        *gorm.DB
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+
+	mu        sync.Mutex
+	consumers []Consumer
+}
+
+// NewDispatcher 创建发件箱分发任务
+func NewDispatcher(db *gorm.DB, cfg config.OutboxConfig) *Dispatcher {
+	d := &Dispatcher{
+		db:        db,
+		interval:  cfg.DispatchInterval,
+		batchSize: cfg.BatchSize,
+		retention: cfg.Retention,
+	}
+	if d.interval <= 0 {
+		d.interval = defaultDispatchInterval
+	}
+	if d.batchSize <= 0 {
+		d.batchSize = defaultBatchSize
+	}
+	if d.retention <= 0 {
+		d.retention = defaultRetention
+	}
+	return d
+}
+
+// Register 注册消费者，新消费者从发件箱中最早保留的事件开始消费
+func (d *Dispatcher) Register(consumer Consumer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.consumers = append(d.consumers, consumer)
+}
+
+// Interval 分发间隔
+func (d *Dispatcher) Interval() time.Duration {
+	return d.interval
+}
+
+// Name 任务名称
+func (d *Dispatcher) Name() string {
+	return "outbox_dispatcher"
+}
+
+// Run 执行一轮分发：每个消费者从上次的进度继续处理，单个消费者失败不影响其他消费者
+// 之后清理所有消费者都已处理且超过保留期的事件
+func (d *Dispatcher) Run(ctx context.Context) error {
+	d.mu.Lock()
+	consumers := append([]Consumer(nil), d.consumers...)
+	d.mu.Unlock()
+
+	failed := 0
+	for _, consumer := range consumers {
+		if err := d.dispatch(ctx, consumer); err != nil {
+			failed++
+			logger.Warnf("Outbox consumer %s stopped: %v", consumer.Name(), err)
+		}
+	}
+
+	if err := d.purge(ctx, consumers); err != nil {
+		logger.Warnf("Failed to purge delivered outbox events: %v", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d outbox consumers failed", failed)
+	}
+	return nil
+}
+
+// dispatch 将进度之后的事件按ID顺序投递给消费者，每处理一个事件保存一次进度
+func (d *Dispatcher) dispatch(ctx context.Context, consumer Consumer) error {
+	name := consumer.Name()
+	offset, err := d.loadOffset(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var records []models.OutboxEvent
+		err := d.db.WithContext(ctx).
+			Where("id > ? AND created_at <= ?", offset, time.Now().UTC().Add(-settleDelay)).
+			Order("id").
+			Limit(d.batchSize).
+			Find(&records).Error
+		if err != nil {
+			return fmt.Errorf("failed to load outbox events: %w", err)
+		}
+
+		for i := range records {
+			record := &records[i]
+			event, err := toEvent(record)
+			if err != nil {
+				// 无法解析的事件重试也不会成功，跳过以免阻塞后续事件
+				logger.Errorf("Skipping outbox event %d for consumer %s: %v", record.ID, name, err)
+			} else if err := handle(ctx, consumer, event); err != nil {
+				outboxFailures.Inc(name)
+				return fmt.Errorf("event %d (%s): %w", record.ID, record.EventType, err)
+			} else {
+				outboxDelivered.Inc(name)
+			}
+
+			offset = record.ID
+			if err := d.saveOffset(ctx, name, offset); err != nil {
+				return err
+			}
+		}
+
+		if len(records) < d.batchSize {
+			return nil
+		}
+	}
+}
+
+// handle 调用消费者，panic视为处理失败
+func handle(ctx context.Context, consumer Consumer, event events.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("consumer panicked: %v", r)
+		}
+	}()
+	return consumer.Handle(ctx, event)
+}
+
+// loadOffset 读取消费者的进度，不存在时创建
+func (d *Dispatcher) loadOffset(ctx context.Context, name string) (uint, error) {
+	err := d.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.OutboxOffset{Consumer: name, ProcessedAt: time.Now().UTC()}).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize outbox offset: %w", err)
+	}
+
+	var offset models.OutboxOffset
+	if err := d.db.WithContext(ctx).Where("consumer = ?", name).First(&offset).Error; err != nil {
+		return 0, fmt.Errorf("failed to load outbox offset: %w", err)
+	}
+	return offset.LastEventID, nil
+}
+
+// saveOffset 保存消费者的进度，进度只前进不后退（多实例同时分发时以较大者为准）
+func (d *Dispatcher) saveOffset(ctx context.Context, name string, eventID uint) error {
+	err := d.db.WithContext(ctx).Model(&models.OutboxOffset{}).
+		Where("consumer = ? AND last_event_id < ?", name, eventID).
+		Updates(map[string]interface{}{"last_event_id": eventID, "processed_at": time.Now().UTC()}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save outbox offset: %w", err)
+	}
+	return nil
+}
+
+// purge 删除所有已注册消费者都已处理且超过保留期的事件
+func (d *Dispatcher) purge(ctx context.Context, consumers []Consumer) error {
+	if len(consumers) == 0 {
+		return nil
+	}
+	names := make([]string, len(consumers))
+	for i, c := range consumers {
+		names[i] = c.Name()
+	}
+
+	var minOffset uint
+	err := d.db.WithContext(ctx).Model(&models.OutboxOffset{}).
+		Where("consumer IN ?", names).
+		Select("COALESCE(MIN(last_event_id), 0)").
+		Scan(&minOffset).Error
+	if err != nil {
+		return err
+	}
+	if minOffset == 0 {
+		return nil
+	}
+
+	return d.db.WithContext(ctx).
+		Where("id <= ? AND created_at < ?", minOffset, time.Now().UTC().Add(-d.retention)).
+		Delete(&models.OutboxEvent{}).Error
+}
+
+// eventConsumer 将按事件类型订阅的处理函数包装为消费者
+type eventConsumer struct {
+	name     string
+	handlers map[events.EventType]events.EventHandler
+}
+
+// NewEventConsumer 创建按事件类型分派的消费者，未订阅的事件类型直接跳过
+func NewEventConsumer(name string, handlers map[events.EventType]events.EventHandler) Consumer {
+	return &eventConsumer{name: name, handlers: handlers}
+}
+
+// Name 消费者名称
+func (c *eventConsumer) Name() string {
+	return c.name
+}
+
+// Handle 调用对应事件类型的处理函数
+func (c *eventConsumer) Handle(ctx context.Context, event events.Event) error {
+	if handler, ok := c.handlers[event.Type]; ok {
+		handler(event)
+	}
+	return nil
+}
+
+// RegisterDefaultConsumers 注册默认消费者：搜索索引、新版本通知和操作记录
+func RegisterDefaultConsumers(d *Dispatcher) {
+	d.Register(NewEventConsumer("search_index", map[events.EventType]events.EventHandler{
+		events.PackageCreated:   events.SearchIndexer{}.OnPackageCreated,
+		events.PackageReindexed: events.SearchIndexer{}.OnPackageReindexed,
+	}))
+	d.Register(NewEventConsumer("notifications", map[events.EventType]events.EventHandler{
+		events.VersionUploaded: events.NotificationSender{}.OnVersionUploaded,
+	}))
+	d.Register(NewEventConsumer("activity", map[events.EventType]events.EventHandler{
+		events.PackageDeleted: events.ActivityRecorder{}.OnPackageDeleted,
+	}))
+}
@@ -0,0 +1,290 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/events"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// appliedEvent 幂等消费者已处理的事件，event_id唯一
+type appliedEvent struct {
+	EventID uint `gorm:"primarykey;autoIncrement:false"`
+}
+
+func newOutboxDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testutil.NewDB(t, &models.OutboxEvent{}, &models.OutboxOffset{}, &appliedEvent{})
+}
+
+// appendEvents 写入已过投递等待期的事件
+func appendEvents(t *testing.T, db *gorm.DB, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		event := events.Event{Type: events.VersionUploaded, PackageName: name, Version: "1.0.0", OccurredAt: time.Now().UTC().Add(-time.Minute)}
+		if err := Append(db, event); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+}
+
+// recordingConsumer 记录收到的事件，crashOn中的事件在处理后返回错误，模拟处理完成但进度未保存时进程崩溃
+type recordingConsumer struct {
+	name     string
+	received []string
+	crashOn  map[string]bool
+}
+
+func (c *recordingConsumer) Name() string { return c.name }
+
+func (c *recordingConsumer) Handle(_ context.Context, event events.Event) error {
+	c.received = append(c.received, event.PackageName)
+	if c.crashOn[event.PackageName] {
+		delete(c.crashOn, event.PackageName)
+		return errors.New("crashed")
+	}
+	return nil
+}
+
+// idempotentConsumer 按event.ID去重：先写入去重记录，已存在时跳过处理
+type idempotentConsumer struct {
+	db         *gorm.DB
+	applied    []string
+	duplicates int
+}
+
+func (c *idempotentConsumer) Name() string { return "idempotent" }
+
+func (c *idempotentConsumer) Handle(ctx context.Context, event events.Event) error {
+	result := c.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&appliedEvent{EventID: event.ID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		c.duplicates++
+		return nil
+	}
+	c.applied = append(c.applied, event.PackageName)
+	return nil
+}
+
+func offsetOf(t *testing.T, db *gorm.DB, consumer string) uint {
+	t.Helper()
+	var offset models.OutboxOffset
+	if err := db.Where("consumer = ?", consumer).First(&offset).Error; err != nil {
+		t.Fatalf("failed to load offset: %v", err)
+	}
+	return offset.LastEventID
+}
+
+func TestDispatcherDeliversInOrderAndSkipsUnsettled(t *testing.T) {
+	db := newOutboxDB(t)
+	appendEvents(t, db, "a", "b", "c")
+	// 刚写入的事件在等待期内，本轮不投递
+	if err := Append(db, events.Event{Type: events.VersionUploaded, PackageName: "fresh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	consumer := &recordingConsumer{name: "recorder"}
+	d := NewDispatcher(db, config.OutboxConfig{BatchSize: 2})
+	d.Register(consumer)
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if fmt.Sprint(consumer.received) != "[a b c]" {
+		t.Errorf("received = %v, want [a b c]", consumer.received)
+	}
+	if got := offsetOf(t, db, "recorder"); got != 3 {
+		t.Errorf("offset = %d, want 3", got)
+	}
+
+	// 再次运行不会重复投递
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(consumer.received) != 3 {
+		t.Errorf("received after second run = %v, want no redelivery", consumer.received)
+	}
+}
+
+func TestDispatcherRollbackDiscardsEvent(t *testing.T) {
+	db := newOutboxDB(t)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := Append(tx, events.Event{Type: events.PackageCreated, PackageName: "rolled-back", OccurredAt: time.Now().Add(-time.Minute)}); err != nil {
+			return err
+		}
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("transaction should have failed")
+	}
+
+	consumer := &recordingConsumer{name: "recorder"}
+	d := NewDispatcher(db, config.OutboxConfig{})
+	d.Register(consumer)
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(consumer.received) != 0 {
+		t.Errorf("received = %v, want nothing from a rolled back transaction", consumer.received)
+	}
+}
+
+func TestDispatcherRedeliversAfterRestart(t *testing.T) {
+	db := newOutboxDB(t)
+	appendEvents(t, db, "a", "b", "c")
+
+	// 第一个进程处理完a，处理b时崩溃，进度停在a
+	first := &recordingConsumer{name: "recorder", crashOn: map[string]bool{"b": true}}
+	d := NewDispatcher(db, config.OutboxConfig{})
+	d.Register(first)
+	if err := d.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil, want the consumer failure")
+	}
+	if fmt.Sprint(first.received) != "[a b]" {
+		t.Fatalf("received before crash = %v, want [a b]", first.received)
+	}
+	if got := offsetOf(t, db, "recorder"); got != 1 {
+		t.Fatalf("offset after crash = %d, want 1", got)
+	}
+
+	// 崩溃后写入但未投递的事件也在重启后投递
+	appendEvents(t, db, "d")
+
+	// 重启：新的分发器从保存的进度继续，b被再次投递
+	second := &recordingConsumer{name: "recorder"}
+	restarted := NewDispatcher(db, config.OutboxConfig{})
+	restarted.Register(second)
+	if err := restarted.Run(context.Background()); err != nil {
+		t.Fatalf("Run() after restart error = %v", err)
+	}
+	if fmt.Sprint(second.received) != "[b c d]" {
+		t.Errorf("received after restart = %v, want [b c d]", second.received)
+	}
+	if got := offsetOf(t, db, "recorder"); got != 4 {
+		t.Errorf("offset after restart = %d, want 4", got)
+	}
+}
+
+func TestDispatcherIdempotentConsumerAppliesDuplicateOnce(t *testing.T) {
+	db := newOutboxDB(t)
+	appendEvents(t, db, "a", "b")
+
+	consumer := &idempotentConsumer{db: db}
+	d := NewDispatcher(db, config.OutboxConfig{})
+	d.Register(consumer)
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// 模拟处理成功但进度未保存：进度回退后事件被再次投递
+	if err := db.Model(&models.OutboxOffset{}).Where("consumer = ?", "idempotent").Update("last_event_id", 0).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() redelivery error = %v", err)
+	}
+
+	if fmt.Sprint(consumer.applied) != "[a b]" {
+		t.Errorf("applied = %v, want each event applied exactly once", consumer.applied)
+	}
+	if consumer.duplicates != 2 {
+		t.Errorf("duplicates = %d, want 2 redelivered events skipped", consumer.duplicates)
+	}
+	if got := offsetOf(t, db, "idempotent"); got != 2 {
+		t.Errorf("offset = %d, want 2", got)
+	}
+}
+
+func TestDispatcherFailingConsumerDoesNotBlockOthers(t *testing.T) {
+	db := newOutboxDB(t)
+	appendEvents(t, db, "a", "b")
+
+	failing := &recordingConsumer{name: "failing", crashOn: map[string]bool{"a": true}}
+	healthy := &recordingConsumer{name: "healthy"}
+	d := NewDispatcher(db, config.OutboxConfig{})
+	d.Register(failing)
+	d.Register(healthy)
+	if err := d.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil, want the failing consumer reported")
+	}
+	if fmt.Sprint(healthy.received) != "[a b]" {
+		t.Errorf("healthy consumer received %v, want [a b]", healthy.received)
+	}
+	if got := offsetOf(t, db, "failing"); got != 0 {
+		t.Errorf("failing consumer offset = %d, want 0", got)
+	}
+
+	// 失败的事件在下一轮重试
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() retry error = %v", err)
+	}
+	if fmt.Sprint(failing.received) != "[a a b]" {
+		t.Errorf("failing consumer received %v, want [a a b]", failing.received)
+	}
+}
+
+func TestDispatcherOffsetNeverMovesBackwards(t *testing.T) {
+	db := newOutboxDB(t)
+	appendEvents(t, db, "a", "b", "c")
+
+	d := NewDispatcher(db, config.OutboxConfig{})
+	d.Register(&recordingConsumer{name: "recorder"})
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 另一个实例持有较旧的进度时，保存较小的进度不生效
+	if err := d.saveOffset(context.Background(), "recorder", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := offsetOf(t, db, "recorder"); got != 3 {
+		t.Errorf("offset = %d, want 3", got)
+	}
+}
+
+func TestDispatcherPurgesEventsProcessedByAllConsumers(t *testing.T) {
+	db := newOutboxDB(t)
+	for _, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, time.Minute} {
+		if err := Append(db, events.Event{Type: events.PackageCreated, PackageName: fmt.Sprint(age), OccurredAt: time.Now().UTC().Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remaining := func() string {
+		t.Helper()
+		var ids []uint
+		if err := db.Model(&models.OutboxEvent{}).Order("id").Pluck("id", &ids).Error; err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(ids)
+	}
+
+	// 较慢的消费者只处理了第一个事件，其余事件即使超过保留期也不清理
+	fast := &recordingConsumer{name: "fast"}
+	slow := &recordingConsumer{name: "slow", crashOn: map[string]bool{"2h0m0s": true}}
+	d := NewDispatcher(db, config.OutboxConfig{Retention: time.Hour})
+	d.Register(fast)
+	d.Register(slow)
+	if err := d.Run(context.Background()); err == nil {
+		t.Fatal("Run() error = nil, want the slow consumer failure")
+	}
+	if got := remaining(); got != "[2 3]" {
+		t.Errorf("remaining events = %v, want [2 3]", got)
+	}
+
+	// 两个消费者都处理完后，只保留未超过保留期的事件
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := remaining(); got != "[3]" {
+		t.Errorf("remaining events = %v, want [3]", got)
+	}
+}
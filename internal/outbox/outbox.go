@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Append 在tx所在的事务中写入发件箱事件，事务回滚时事件一并丢弃，提交后由Dispatcher投递
+func Append(tx *gorm.DB, event events.Event) error {
+	payload := ""
+	if len(event.Payload) > 0 {
+		data, err := json.Marshal(event.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode outbox event payload: %w", err)
+		}
+		payload = string(data)
+	}
+
+	record := &models.OutboxEvent{
+		EventType:   string(event.Type),
+		PackageID:   event.PackageID,
+		PackageName: event.PackageName,
+		Version:     event.Version,
+		UserID:      event.UserID,
+		Payload:     payload,
+	}
+	if !event.OccurredAt.IsZero() {
+		record.CreatedAt = event.OccurredAt
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+	return nil
+}
+
+// toEvent 将发件箱记录还原为事件
+func toEvent(record *models.OutboxEvent) (events.Event, error) {
+	event := events.Event{
+		ID:          record.ID,
+		Type:        events.EventType(record.EventType),
+		PackageID:   record.PackageID,
+		PackageName: record.PackageName,
+		Version:     record.Version,
+		UserID:      record.UserID,
+		OccurredAt:  record.CreatedAt,
+	}
+	if record.Payload != "" {
+		if err := json.Unmarshal([]byte(record.Payload), &event.Payload); err != nil {
+			return event, fmt.Errorf("failed to decode outbox event %d payload: %w", record.ID, err)
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,201 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"webservice/internal/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 支持的哈希算法
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// Argon2id默认参数（未配置时使用）
+const (
+	defaultArgon2Time       = 3
+	defaultArgon2MemoryKB   = 64 * 1024
+	defaultArgon2Threads    = 2
+	defaultArgon2KeyLength  = 32
+	defaultArgon2SaltLength = 16
+)
+
+var (
+	// ErrMismatch 密码与哈希不匹配
+	ErrMismatch = errors.New("password does not match")
+	// ErrUnknownFormat 无法识别的哈希格式
+	ErrUnknownFormat = errors.New("unknown password hash format")
+)
+
+// argon2Params Argon2id参数
+type argon2Params struct {
+	time       uint32
+	memoryKB   uint32
+	threads    uint8
+	keyLength  uint32
+	saltLength uint32
+}
+
+// Hasher 密码哈希器
+// 生成的哈希自带算法标识：Argon2id使用PHC格式（$argon2id$v=19$m=...,t=...,p=...$salt$hash），
+// bcrypt使用其标准格式（$2a$/$2b$），校验时按前缀选择对应算法
+type Hasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+// NewHasher 根据配置创建密码哈希器，未配置的参数使用默认值
+func NewHasher(cfg config.PasswordConfig) *Hasher {
+	h := &Hasher{
+		algorithm:  strings.ToLower(cfg.Algorithm),
+		bcryptCost: cfg.BcryptCost,
+		argon2: argon2Params{
+			time:       cfg.Argon2.Time,
+			memoryKB:   cfg.Argon2.MemoryKB,
+			threads:    cfg.Argon2.Threads,
+			keyLength:  cfg.Argon2.KeyLength,
+			saltLength: cfg.Argon2.SaltLength,
+		},
+	}
+
+	if h.algorithm != AlgorithmBcrypt {
+		h.algorithm = AlgorithmArgon2id
+	}
+	if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+		h.bcryptCost = bcrypt.DefaultCost
+	}
+	if h.argon2.time == 0 {
+		h.argon2.time = defaultArgon2Time
+	}
+	if h.argon2.memoryKB == 0 {
+		h.argon2.memoryKB = defaultArgon2MemoryKB
+	}
+	if h.argon2.threads == 0 {
+		h.argon2.threads = defaultArgon2Threads
+	}
+	if h.argon2.keyLength == 0 {
+		h.argon2.keyLength = defaultArgon2KeyLength
+	}
+	if h.argon2.saltLength == 0 {
+		h.argon2.saltLength = defaultArgon2SaltLength
+	}
+	return h
+}
+
+// Hash 使用配置的算法生成密码哈希
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.algorithm == AlgorithmBcrypt {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	}
+
+	salt := make([]byte, h.argon2.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.time, h.argon2.memoryKB, h.argon2.threads, h.argon2.keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2.memoryKB, h.argon2.time, h.argon2.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验密码，根据哈希中记录的算法选择校验方式
+func (h *Hasher) Verify(password, encoded string) error {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return err
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.time, params.memoryKB, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(actual, key) != 1 {
+			return ErrMismatch
+		}
+		return nil
+	case isBcrypt(encoded):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return ErrMismatch
+			}
+			return err
+		}
+		return nil
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// NeedsRehash 判断哈希是否使用了与当前配置不同的算法或参数，登录成功后据此透明升级
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if h.algorithm == AlgorithmBcrypt {
+		if !isBcrypt(encoded) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost < h.bcryptCost
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return true
+	}
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.time != h.argon2.time ||
+		params.memoryKB != h.argon2.memoryKB ||
+		params.threads != h.argon2.threads ||
+		uint32(len(key)) != h.argon2.keyLength ||
+		uint32(len(salt)) != h.argon2.saltLength
+}
+
+// isBcrypt 判断是否为bcrypt哈希
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// decodeArgon2id 解析PHC格式的Argon2id哈希
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memoryKB, &params.time, &params.threads); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 hash: %w", err)
+	}
+	params.saltLength = uint32(len(salt))
+	params.keyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,86 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+)
+
+// testArgon2 测试使用的低成本Argon2id参数
+var testArgon2 = config.Argon2Config{Time: 1, MemoryKB: 1024, Threads: 1}
+
+func TestVerifyDispatchesByHashFormat(t *testing.T) {
+	hashers := map[string]*Hasher{
+		AlgorithmBcrypt:   NewHasher(config.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 4}),
+		AlgorithmArgon2id: NewHasher(config.PasswordConfig{Algorithm: AlgorithmArgon2id, Argon2: testArgon2}),
+	}
+	prefixes := map[string]string{AlgorithmBcrypt: "$2a$", AlgorithmArgon2id: "$argon2id$v=19$"}
+
+	for algorithm, hasher := range hashers {
+		t.Run(algorithm, func(t *testing.T) {
+			encoded, err := hasher.Hash("secret-password")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !strings.HasPrefix(encoded, prefixes[algorithm]) {
+				t.Errorf("hash %q does not start with %q", encoded, prefixes[algorithm])
+			}
+
+			// 任一配置的哈希器都能校验两种格式的哈希
+			for name, verifier := range hashers {
+				if err := verifier.Verify("secret-password", encoded); err != nil {
+					t.Errorf("%s hasher: Verify = %v", name, err)
+				}
+				if err := verifier.Verify("wrong-password", encoded); !errors.Is(err, ErrMismatch) {
+					t.Errorf("%s hasher: Verify with wrong password = %v, want ErrMismatch", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUnknownFormat(t *testing.T) {
+	h := NewHasher(config.PasswordConfig{Argon2: testArgon2})
+	for _, encoded := range []string{"", "plaintext", "$argon2id$v=19$broken"} {
+		if err := h.Verify("secret", encoded); err == nil {
+			t.Errorf("Verify(%q) succeeded", encoded)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	bcryptHasher := NewHasher(config.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 4})
+	argonHasher := NewHasher(config.PasswordConfig{Argon2: testArgon2})
+	strongerArgon := NewHasher(config.PasswordConfig{Argon2: config.Argon2Config{Time: 2, MemoryKB: 1024, Threads: 1}})
+
+	bcryptHash, err := bcryptHasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := argonHasher.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		hasher  *Hasher
+		encoded string
+		want    bool
+	}{
+		{name: "bcrypt hash under argon2id config", hasher: argonHasher, encoded: bcryptHash, want: true},
+		{name: "argon2id hash with current parameters", hasher: argonHasher, encoded: argonHash, want: false},
+		{name: "argon2id hash with old parameters", hasher: strongerArgon, encoded: argonHash, want: true},
+		{name: "argon2id hash under bcrypt config", hasher: bcryptHasher, encoded: argonHash, want: true},
+		{name: "bcrypt hash under bcrypt config", hasher: bcryptHasher, encoded: bcryptHash, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Header 请求ID头名称
+	Header = "X-Request-ID"
+
+	// FormatUUID UUID格式（默认）
+	FormatUUID = "uuid"
+	// FormatKSUID ksuid风格格式（时间有序、27位base62）
+	FormatKSUID = "ksuid"
+
+	// DefaultMaxLength 默认允许的最大请求ID长度
+	DefaultMaxLength = 64
+)
+
+// ctxKey 在context.Context中存储请求ID的键类型
+type ctxKey struct{}
+
+// base62Alphabet base62编码字符表
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ksuidEpoch ksuid时间戳起点（2014-05-13）
+const ksuidEpoch = 1400000000
+
+// NewContext 将请求ID写入context
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, requestID)
+}
+
+// FromContext 从context中读取请求ID
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(ctxKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// Generate 按指定格式生成请求ID
+func Generate(format string) string {
+	if format == FormatKSUID {
+		return generateKSUID()
+	}
+	return uuid.New().String()
+}
+
+// IsValid 校验客户端传入的请求ID（长度上限及字符集）
+func IsValid(requestID string, maxLength int) bool {
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if requestID == "" || len(requestID) > maxLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		ch := requestID[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// generateKSUID 生成ksuid风格的ID：4字节时间戳 + 16字节随机数，base62编码为27位
+func generateKSUID() string {
+	var raw [20]byte
+	binary.BigEndian.PutUint32(raw[:4], uint32(time.Now().Unix()-ksuidEpoch))
+	if _, err := rand.Read(raw[4:]); err != nil {
+		return uuid.New().String()
+	}
+
+	n := new(big.Int).SetBytes(raw[:])
+	base := big.NewInt(62)
+	mod := new(big.Int)
+	out := make([]byte, 27)
+	for i := len(out) - 1; i >= 0; i-- {
+		n.DivMod(n, base, mod)
+		out[i] = base62Alphabet[mod.Int64()]
+	}
+	return string(out)
+}
+
+// Transport 在出站HTTP请求中携带请求ID的RoundTripper
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	requestID := FromContext(req.Context())
+	if requestID == "" || req.Header.Get(Header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	// RoundTripper不应修改原始请求，克隆后再设置头
+	clone := req.Clone(req.Context())
+	clone.Header.Set(Header, requestID)
+	return base.RoundTrip(clone)
+}
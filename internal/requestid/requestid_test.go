@@ -0,0 +1,94 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsValid(t *testing.T) {
+	tests := []struct {
+		id        string
+		maxLength int
+		want      bool
+	}{
+		{"550e8400-e29b-41d4-a716-446655440000", 0, true},
+		{"trace_01.span:2", 0, true},
+		{"", 0, false},
+		{strings.Repeat("a", DefaultMaxLength), 0, true},
+		{strings.Repeat("a", DefaultMaxLength+1), 0, false},
+		{"abcdef", 5, false},
+		{"abc\r\nX-Injected: 1", 0, false},
+		{"id with space", 0, false},
+		{"id/with/slash", 0, false},
+		{"ünïcode", 0, false},
+	}
+	for _, tt := range tests {
+		if got := IsValid(tt.id, tt.maxLength); got != tt.want {
+			t.Errorf("IsValid(%q, %d) = %v, want %v", tt.id, tt.maxLength, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, format := range []string{FormatUUID, FormatKSUID, ""} {
+		for i := 0; i < 100; i++ {
+			id := Generate(format)
+			if !IsValid(id, 0) {
+				t.Fatalf("Generate(%q) produced invalid id %q", format, id)
+			}
+			if seen[id] {
+				t.Fatalf("Generate(%q) produced duplicate id %q", format, id)
+			}
+			seen[id] = true
+		}
+	}
+	if id := Generate(FormatKSUID); len(id) != 27 {
+		t.Fatalf("ksuid length = %d, want 27", len(id))
+	}
+	if id := Generate(FormatUUID); len(id) != 36 {
+		t.Fatalf("uuid length = %d, want 36", len(id))
+	}
+}
+
+func TestTransportPropagatesRequestID(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(Header))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{}}
+	send := func(ctx context.Context, header string) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set(Header, header)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if header == "" && req.Header.Get(Header) != "" {
+			t.Fatal("transport modified the caller's request")
+		}
+	}
+
+	send(NewContext(context.Background(), "req-123"), "")
+	send(context.Background(), "")
+	send(NewContext(context.Background(), "req-123"), "explicit")
+
+	want := []string{"req-123", "", "explicit"}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Errorf("request %d carried %q, want %q", i, received[i], want[i])
+		}
+	}
+}
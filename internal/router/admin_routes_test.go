@@ -0,0 +1,250 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+// adminRoutePrefixes 管理接口的路径前缀，其下每个路由都必须通过RequirePermission声明所需的操作
+var adminRoutePrefixes = []string{"/api/v1/admin/", "/debug/pprof"}
+
+// routeParamValues 枚举路由时替换路径参数的值
+var routeParamValues = map[string]string{
+	":package":  "demo",
+	":version":  "1.0.0",
+	":id":       "1",
+	":name":     "flag",
+	":ip":       "192.0.2.1",
+	"*filepath": "",
+}
+
+// concretePath 把路由中的参数替换为示例值
+func concretePath(t *testing.T, route string) string {
+	t.Helper()
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		value, ok := routeParamValues[segment]
+		if !ok {
+			t.Fatalf("route %s has parameter %s with no example value, add one to routeParamValues", route, segment)
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/")
+}
+
+// errorMessage 解析统一响应格式中的message字段
+func errorMessage(t *testing.T, body []byte) string {
+	t.Helper()
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", body, err)
+	}
+	return resp.Message
+}
+
+func TestEveryAdminRouteDeclaresPermission(t *testing.T) {
+	tr := newTestRouter(t, func(cfg *config.Config) { cfg.Debug.Pprof = true })
+	_, userToken := tr.createUser("alice", models.RoleUser)
+
+	checked := 0
+	for _, route := range tr.r.Routes() {
+		admin := false
+		for _, prefix := range adminRoutePrefixes {
+			admin = admin || strings.HasPrefix(route.Path, prefix)
+		}
+		if !admin {
+			continue
+		}
+		checked++
+		path := concretePath(t, route.Path)
+		t.Run(route.Method+" "+route.Path, func(t *testing.T) {
+			if w := tr.do(route.Method, path, "", ""); w.Code != http.StatusUnauthorized {
+				t.Errorf("anonymous status = %d, want 401", w.Code)
+			}
+			// 普通用户没有任何管理操作的权限，RequirePermission在处理函数之前拒绝请求
+			w := tr.do(route.Method, path, userToken, "")
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("user status = %d, want 403 (body %s)", w.Code, w.Body.String())
+			}
+			if msg := errorMessage(t, w.Body.Bytes()); msg != "Insufficient permissions" {
+				t.Errorf("user rejected with %q, want the RequirePermission message", msg)
+			}
+		})
+	}
+	if checked == 0 {
+		t.Fatal("no admin routes were enumerated")
+	}
+}
+
+func TestAdminPackageRoutesUseAdminOverride(t *testing.T) {
+	// 通过authz.rules授予普通用户package.moderate后，路由和服务层的admin_override检查都放行
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Authz.Rules = []config.PolicyRuleConfig{{Role: models.RoleUser, Action: "package.moderate", Resource: "*", Effect: "allow"}}
+	})
+	owner, _ := tr.createUser("alice", models.RoleUser)
+	_, moderatorToken := tr.createUser("mod", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	tr.createPackage("demo", owner, true)
+
+	if w := tr.do(http.MethodPost, "/api/v1/admin/packages/demo/rebuild", moderatorToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("moderator rebuild status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/admin/packages/demo/rebuild", adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("admin rebuild status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodGet, "/api/v1/packages/demo/permissions", moderatorToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("permissions status = %d, body %s", w.Code, w.Body.String())
+	} else {
+		var data models.PackagePermissionsResponse
+		decodeData(t, w, &data)
+		if got := data.Permissions["admin_override"]; !got.Granted || got.Reason != "admin" {
+			t.Errorf("admin_override = %+v, want granted by admin", got)
+		}
+	}
+}
+
+// assertAdminOnly 检查接口拒绝匿名（401）和普通用户（403）的请求，管理员可以访问
+func assertAdminOnly(t *testing.T, tr *testRouter, path, userToken, adminToken string) *httptest.ResponseRecorder {
+	t.Helper()
+	if w := tr.do(http.MethodGet, path, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous GET %s status = %d, want 401", path, w.Code)
+	}
+	if w := tr.do(http.MethodGet, path, userToken, ""); w.Code != http.StatusForbidden {
+		t.Errorf("user GET %s status = %d, want 403", path, w.Code)
+	}
+	w := tr.do(http.MethodGet, path, adminToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("admin GET %s status = %d, body %s", path, w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestStorageTierSummaryRequiresAdmin(t *testing.T) {
+	tr := newTestRouter(t)
+	_, userToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	assertAdminOnly(t, tr, "/api/v1/admin/storage/tier-summary", userToken, adminToken)
+}
+
+func TestStorageStatsRequiresAdmin(t *testing.T) {
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Packages.UserUploadBandwidthLimitBytesPerSec = 1 << 20
+	})
+	_, userToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	w := assertAdminOnly(t, tr, "/api/v1/admin/storage/stats", userToken, adminToken)
+
+	var data struct {
+		Available bool                          `json:"available"`
+		Throttle  models.UploadThrottleSettings `json:"throttle"`
+	}
+	decodeData(t, w, &data)
+	if data.Available {
+		t.Error("available = true without a storage client")
+	}
+	if data.Throttle.UserBandwidthLimitBytesPerSec != 1<<20 {
+		t.Errorf("user bandwidth limit = %d, want %d", data.Throttle.UserBandwidthLimitBytesPerSec, 1<<20)
+	}
+}
+
+func TestDeprecationUsageRequiresAdmin(t *testing.T) {
+	tr := newTestRouter(t)
+	_, userToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	assertAdminOnly(t, tr, "/api/v1/admin/deprecations/usage", userToken, adminToken)
+
+	// 参数校验在权限检查之后，普通用户无法借此探测接口
+	if w := tr.do(http.MethodGet, "/api/v1/admin/deprecations/usage?days=0", userToken, ""); w.Code != http.StatusForbidden {
+		t.Errorf("user with invalid days status = %d, want 403", w.Code)
+	}
+	if w := tr.do(http.MethodGet, "/api/v1/admin/deprecations/usage?days=0", adminToken, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("admin with invalid days status = %d, want 400", w.Code)
+	}
+}
+
+// firstExportRow 解析NDJSON导出的第一行数据
+func firstExportRow(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	line, _, _ := strings.Cut(w.Body.String(), "\n")
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &row); err != nil {
+		t.Fatalf("failed to decode export row %q: %v", line, err)
+	}
+	return row
+}
+
+func TestExportRoutesRequireAdminAndGatePII(t *testing.T) {
+	// support角色通过authz.rules获得导出权限，但不是管理员
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Authz.Rules = []config.PolicyRuleConfig{{Role: models.RoleSupport, Action: "data.export", Resource: "*", Effect: "allow"}}
+	})
+	owner, userToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	_, supportToken := tr.createUser("helpdesk", models.RoleSupport)
+	tr.createPackage("demo", owner, false)
+
+	for _, dataset := range []string{"packages", "versions", "downloads"} {
+		t.Run(dataset, func(t *testing.T) {
+			assertAdminOnly(t, tr, "/api/v1/admin/export/"+dataset, userToken, adminToken)
+		})
+	}
+
+	const path = "/api/v1/admin/export/packages"
+	tests := []struct {
+		name      string
+		token     string
+		query     string
+		wantEmail bool
+	}{
+		{"admin without include_pii", adminToken, "", false},
+		{"admin with include_pii", adminToken, "?include_pii=true", true},
+		{"non-admin with include_pii", supportToken, "?include_pii=true", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tr.do(http.MethodGet, path+tt.query, tt.token, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			row := firstExportRow(t, w)
+			if row["name"] != "demo" {
+				t.Fatalf("first row = %v, want package demo", row)
+			}
+			email, ok := row["owner_email"]
+			if ok != tt.wantEmail {
+				t.Fatalf("owner_email present = %v, want %v (row %v)", ok, tt.wantEmail, row)
+			}
+			if ok && email != "alice@example.com" {
+				t.Errorf("owner_email = %v, want alice@example.com", email)
+			}
+		})
+	}
+}
+
+func TestPprofNotRegisteredWhenDisabled(t *testing.T) {
+	tr := newTestRouter(t)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+
+	for _, route := range tr.r.Routes() {
+		if strings.HasPrefix(route.Path, "/debug/pprof") {
+			t.Fatalf("pprof route %s registered although debug.pprof is off", route.Path)
+		}
+	}
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/profile"} {
+		// 管理员同样返回404，不暴露接口是否存在
+		if w := tr.do(http.MethodGet, path, adminToken, ""); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want 404", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+)
+
+// uploadVersion 以multipart表单上传版本文件
+func (tr *testRouter) uploadVersion(packageName, version, token string) *httptest.ResponseRecorder {
+	tr.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("version", version); err != nil {
+		tr.t.Fatal(err)
+	}
+	part, err := form.CreateFormFile("package_file", packageName+".tgz")
+	if err != nil {
+		tr.t.Fatal(err)
+	}
+	if _, err := part.Write([]byte("package contents")); err != nil {
+		tr.t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		tr.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/packages/"+packageName+"/versions", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	tr.r.ServeHTTP(w, req)
+	return w
+}
+
+func TestArchivedPackageRejectsWrites(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	tr.createUser("bob", models.RoleUser)
+	pkg := tr.createPackage("frozen", owner, false)
+	version := &models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", MinIOPath: "frozen/1.0.0", FileHash: "x"}
+	if err := tr.db.Create(version).Error; err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen/archive", ownerToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("archive status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	updatePackage := `{"description":"new","homepage":"https://example.com","repository":"https://example.com/repo"}`
+	blocked := []struct {
+		name string
+		send func() *httptest.ResponseRecorder
+	}{
+		{"update package", func() *httptest.ResponseRecorder {
+			return tr.do(http.MethodPut, "/api/v1/packages/frozen", ownerToken, updatePackage)
+		}},
+		{"update version", func() *httptest.ResponseRecorder {
+			return tr.do(http.MethodPatch, "/api/v1/packages/frozen/1.0.0", ownerToken, `{"description":"new"}`)
+		}},
+		{"upload version", func() *httptest.ResponseRecorder {
+			return tr.uploadVersion("frozen", "1.1.0", ownerToken)
+		}},
+		{"deprecate versions", func() *httptest.ResponseRecorder {
+			return tr.do(http.MethodPost, "/api/v1/packages/frozen/deprecate-bulk", ownerToken, fmt.Sprintf(`{"version_ids":[%d]}`, version.ID))
+		}},
+		{"add collaborator", func() *httptest.ResponseRecorder {
+			return tr.do(http.MethodPut, "/api/v1/packages/frozen/collaborators/bob", ownerToken, `{"role":"maintainer"}`)
+		}},
+		{"remove collaborator", func() *httptest.ResponseRecorder {
+			return tr.do(http.MethodDelete, "/api/v1/packages/frozen/collaborators/bob", ownerToken, "")
+		}},
+	}
+	for _, tt := range blocked {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.send()
+			if w.Code != http.StatusConflict {
+				t.Fatalf("status = %d, want 409, body %s", w.Code, w.Body.String())
+			}
+			if msg := errorMessage(t, w.Body.Bytes()); msg != "package_archived" {
+				t.Errorf("error = %q, want package_archived", msg)
+			}
+		})
+	}
+
+	// 归档的包仍可读取
+	if w := tr.do(http.MethodGet, "/api/v1/packages/frozen", "", ""); w.Code != http.StatusOK {
+		t.Errorf("get archived package status = %d, want 200", w.Code)
+	}
+	if w := tr.do(http.MethodGet, "/api/v1/packages/frozen/versions", "", ""); w.Code != http.StatusOK {
+		t.Errorf("list archived package versions status = %d, want 200", w.Code)
+	}
+
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen/unarchive", ownerToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("unarchive status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodPut, "/api/v1/packages/frozen", ownerToken, updatePackage); w.Code != http.StatusOK {
+		t.Errorf("update after unarchive status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodPut, "/api/v1/packages/frozen/collaborators/bob", ownerToken, `{"role":"maintainer"}`); w.Code != http.StatusOK {
+		t.Errorf("add collaborator after unarchive status = %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestArchivePackageOwnerOnlyAndRecorded(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	_, strangerToken := tr.createUser("mallory", models.RoleUser)
+	tr.createPackage("frozen", owner, false)
+
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen/archive", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous archive status = %d, want 401", w.Code)
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen/archive", strangerToken, ""); w.Code != http.StatusForbidden {
+		t.Errorf("stranger archive status = %d, want 403", w.Code)
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/packages/missing/archive", ownerToken, ""); w.Code != http.StatusNotFound {
+		t.Errorf("archive missing package status = %d, want 404", w.Code)
+	}
+
+	w := tr.do(http.MethodPost, "/api/v1/packages/frozen/archive", ownerToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("archive status = %d, body %s", w.Code, w.Body.String())
+	}
+	var pkg models.Package
+	decodeData(t, w, &pkg)
+	if !pkg.IsArchived || pkg.ArchivedAt == nil {
+		t.Errorf("archived package = is_archived %v archived_at %v, want both set", pkg.IsArchived, pkg.ArchivedAt)
+	}
+
+	// 重复归档不产生新的事件和审计记录
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen/archive", ownerToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("repeated archive status = %d", w.Code)
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen/unarchive", ownerToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("unarchive status = %d", w.Code)
+	}
+
+	var reloaded models.Package
+	if err := tr.db.Where("name = ?", "frozen").First(&reloaded).Error; err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.IsArchived || reloaded.ArchivedAt != nil {
+		t.Errorf("unarchived package = is_archived %v archived_at %v, want both cleared", reloaded.IsArchived, reloaded.ArchivedAt)
+	}
+
+	var eventTypes []string
+	if err := tr.db.Model(&models.OutboxEvent{}).Where("package_id = ?", reloaded.ID).Order("id").Pluck("event_type", &eventTypes).Error; err != nil {
+		t.Fatal(err)
+	}
+	wantEvents := []string{string(events.PackageArchived), string(events.PackageUnarchived)}
+	if fmt.Sprint(eventTypes) != fmt.Sprint(wantEvents) {
+		t.Errorf("events = %v, want %v", eventTypes, wantEvents)
+	}
+
+	var actions []string
+	if err := tr.db.Model(&models.AuditLog{}).Where("resource = ?", "packages/frozen").Order("id").Pluck("action", &actions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(actions) != "[packages.archive packages.unarchive]" {
+		t.Errorf("audit actions = %v, want [packages.archive packages.unarchive]", actions)
+	}
+}
+
+func TestArchivedPackageSearchAndPopular(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	for _, name := range []string{"frozen-lib", "active-lib"} {
+		pkg := tr.createPackage(name, owner, false)
+		version := &models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", MinIOPath: name + "/1.0.0", FileHash: "x", InstallCount: 100}
+		if err := tr.db.Create(version).Error; err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/packages/frozen-lib/archive", ownerToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("archive status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	w := tr.do(http.MethodGet, "/api/v1/packages/?q=lib", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("search status = %d, body %s", w.Code, w.Body.String())
+	}
+	var list models.PackageListResponse
+	decodeData(t, w, &list)
+	archived := map[string]bool{}
+	for _, pkg := range list.Packages {
+		archived[pkg.Name] = pkg.IsArchived
+	}
+	if len(archived) != 2 || !archived["frozen-lib"] || archived["active-lib"] {
+		t.Errorf("search results archived flags = %v, want frozen-lib archived and active-lib not", archived)
+	}
+
+	w = tr.do(http.MethodGet, "/api/v1/packages/stats", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("stats status = %d, body %s", w.Code, w.Body.String())
+	}
+	var stats models.PackageStatsResponse
+	decodeData(t, w, &stats)
+	var popular []string
+	for _, pkg := range stats.PopularPackages {
+		popular = append(popular, pkg.Name)
+	}
+	if fmt.Sprint(popular) != "[active-lib]" {
+		t.Errorf("popular packages = %v, want [active-lib]", popular)
+	}
+}
@@ -0,0 +1,81 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestPackageNameAvailability(t *testing.T) {
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Packages.ReservedNames = []string{"Admin", " core "}
+		cfg.Packages.DeletedNameHold = time.Hour
+	})
+	owner, _ := tr.createUser("alice", models.RoleUser)
+	taken := tr.createPackage("taken-pkg", owner, false)
+	if err := tr.db.Create(&models.PackageAlias{PackageID: taken.ID, Name: "old-name"}).Error; err != nil {
+		t.Fatalf("failed to create alias: %v", err)
+	}
+	recent := tr.createPackage("recent-pkg", owner, false)
+	if err := tr.db.Delete(recent).Error; err != nil {
+		t.Fatalf("failed to delete package: %v", err)
+	}
+	expired := tr.createPackage("expired-pkg", owner, false)
+	if err := tr.db.Unscoped().Model(expired).Update("deleted_at", time.Now().Add(-2*time.Hour)).Error; err != nil {
+		t.Fatalf("failed to delete package: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		available bool
+		reason    string
+		maxAge    string
+	}{
+		{"fresh-pkg", true, "", "public, max-age=5"},
+		{"expired-pkg", true, "", "public, max-age=5"},
+		{"taken-pkg", false, models.NameUnavailableTaken, "public, max-age=60"},
+		{"old-name", false, models.NameUnavailableTaken, "public, max-age=60"},
+		{"ADMIN", false, models.NameUnavailableReserved, "public, max-age=60"},
+		{"core", false, models.NameUnavailableReserved, "public, max-age=60"},
+		{"recent-pkg", false, models.NameUnavailableRecentlyDeleted, "public, max-age=60"},
+		{"-leading-dash", false, models.NameUnavailableInvalidFormat, "public, max-age=60"},
+		{"bad%20name", false, models.NameUnavailableInvalidFormat, "public, max-age=60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tr.do(http.MethodGet, "/api/v1/packages/"+tt.name+"/availability", "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var result models.PackageNameAvailability
+			decodeData(t, w, &result)
+			if result.Available != tt.available || result.Reason != tt.reason {
+				t.Errorf("availability = %+v, want available %v reason %q", result, tt.available, tt.reason)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.maxAge {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.maxAge)
+			}
+		})
+	}
+}
+
+func TestPackageNameAvailabilityRateLimited(t *testing.T) {
+	tr := newTestRouter(t)
+
+	// 每个IP每分钟60次，超出后返回429
+	for i := 0; i < 60; i++ {
+		if w := tr.do(http.MethodGet, "/api/v1/packages/probe/availability", "", ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i+1, w.Code)
+		}
+	}
+	w := tr.do(http.MethodGet, "/api/v1/packages/probe/availability", "", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request 61 status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("rate limited response is missing Retry-After")
+	}
+}
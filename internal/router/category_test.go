@@ -0,0 +1,223 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+// createCategory 通过管理接口创建分类
+func (tr *testRouter) createCategory(adminToken, slug string) *models.Category {
+	tr.t.Helper()
+	w := tr.do(http.MethodPost, "/api/v1/admin/categories", adminToken, fmt.Sprintf(`{"slug":%q,"name":%q}`, slug, strings.ToUpper(slug)))
+	if w.Code != http.StatusOK {
+		tr.t.Fatalf("create category %s: status = %d, body %s", slug, w.Code, w.Body.String())
+	}
+	var category models.Category
+	decodeData(tr.t, w, &category)
+	return &category
+}
+
+// packageLinks 创建和更新包时必填的链接字段
+const packageLinks = `"homepage":"https://example.com","repository":"https://example.com/repo"`
+
+// categorySlugs 返回包的分类slug，按字母排序
+func categorySlugs(categories []models.Category) string {
+	slugs := make([]string, len(categories))
+	for i, category := range categories {
+		slugs[i] = category.Slug
+	}
+	sort.Strings(slugs)
+	return fmt.Sprint(slugs)
+}
+
+func TestCategoryCRUD(t *testing.T) {
+	tr := newTestRouter(t)
+	_, userToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+
+	// 只有管理员可以维护分类表
+	body := `{"slug":"web","name":"Web"}`
+	if w := tr.do(http.MethodPost, "/api/v1/admin/categories", "", body); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous create status = %d, want 401", w.Code)
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/admin/categories", userToken, body); w.Code != http.StatusForbidden {
+		t.Errorf("user create status = %d, want 403", w.Code)
+	}
+
+	web := tr.createCategory(adminToken, "web")
+	if web.ID == 0 || web.Slug != "web" || web.Name != "WEB" {
+		t.Errorf("created category = %+v", web)
+	}
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{"slug":"web","name":"Again"}`, http.StatusConflict},
+		{`{"slug":"Web Frameworks","name":"Web"}`, http.StatusBadRequest},
+		{`{"slug":"-web","name":"Web"}`, http.StatusBadRequest},
+		{`{"slug":"cli"}`, http.StatusBadRequest},
+	} {
+		if w := tr.do(http.MethodPost, "/api/v1/admin/categories", adminToken, tt.body); w.Code != tt.code {
+			t.Errorf("create %s status = %d, want %d", tt.body, w.Code, tt.code)
+		}
+	}
+
+	// 修改名称和描述，slug不变
+	path := fmt.Sprintf("/api/v1/admin/categories/%d", web.ID)
+	if w := tr.do(http.MethodPut, path, userToken, `{"name":"Web"}`); w.Code != http.StatusForbidden {
+		t.Errorf("user update status = %d, want 403", w.Code)
+	}
+	w := tr.do(http.MethodPut, path, adminToken, `{"name":"Web development","description":"Servers and frameworks"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body %s", w.Code, w.Body.String())
+	}
+	var updated models.Category
+	decodeData(t, w, &updated)
+	if updated.Slug != "web" || updated.Name != "Web development" || updated.Description != "Servers and frameworks" {
+		t.Errorf("updated category = %+v", updated)
+	}
+	if w := tr.do(http.MethodPut, "/api/v1/admin/categories/999", adminToken, `{"name":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("update missing status = %d, want 404", w.Code)
+	}
+	if w := tr.do(http.MethodPut, "/api/v1/admin/categories/abc", adminToken, `{"name":"x"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("update invalid id status = %d, want 400", w.Code)
+	}
+
+	// 删除后不再出现在分类表中，再次删除返回404
+	tr.createCategory(adminToken, "cli")
+	if w := tr.do(http.MethodDelete, path, userToken, ""); w.Code != http.StatusForbidden {
+		t.Errorf("user delete status = %d, want 403", w.Code)
+	}
+	if w := tr.do(http.MethodDelete, path, adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodDelete, path, adminToken, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", w.Code)
+	}
+	w = tr.do(http.MethodGet, "/api/v1/categories", "", "")
+	var list struct {
+		Categories []models.CategoryWithCount `json:"categories"`
+	}
+	decodeData(t, w, &list)
+	if len(list.Categories) != 1 || list.Categories[0].Slug != "cli" {
+		t.Errorf("categories after delete = %+v, want only cli", list.Categories)
+	}
+}
+
+func TestPackageCategoriesAssignAndFilter(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	web := tr.createCategory(adminToken, "web")
+	cli := tr.createCategory(adminToken, "cli")
+	database := tr.createCategory(adminToken, "database")
+
+	// 创建时只接受分类表中的ID，重复的ID只保留一个
+	w := tr.do(http.MethodPost, "/api/v1/packages/", ownerToken, fmt.Sprintf(`{"name":"server",%s,"category_ids":[%d,%d,%d]}`, packageLinks, web.ID, cli.ID, web.ID))
+	if w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		t.Fatalf("create package status = %d, body %s", w.Code, w.Body.String())
+	}
+	var created models.Package
+	decodeData(t, w, &created)
+	if got := categorySlugs(created.Categories); got != "[cli web]" {
+		t.Errorf("created categories = %s, want [cli web]", got)
+	}
+	w = tr.do(http.MethodPost, "/api/v1/packages/", ownerToken, fmt.Sprintf(`{"name":"bad",%s,"category_ids":[%d,999]}`, packageLinks, web.ID))
+	if w.Code != http.StatusBadRequest || !strings.Contains(errorMessage(t, w.Body.Bytes()), "unknown category") {
+		t.Errorf("create with unknown category status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodGet, "/api/v1/packages/bad", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("package with an unknown category was created: status %d", w.Code)
+	}
+
+	// 更新：未知ID被拒绝且不修改，设置时替换，空数组清除
+	update := func(body string) *models.Package {
+		t.Helper()
+		w := tr.do(http.MethodPut, "/api/v1/packages/server", ownerToken, "{"+packageLinks+","+body[1:])
+		if w.Code != http.StatusOK {
+			t.Fatalf("update %s status = %d, body %s", body, w.Code, w.Body.String())
+		}
+		var pkg models.Package
+		decodeData(t, w, &pkg)
+		return &pkg
+	}
+	if w := tr.do(http.MethodPut, "/api/v1/packages/server", ownerToken, `{`+packageLinks+`,"category_ids":[999]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("update with unknown category status = %d, want 400", w.Code)
+	}
+	if got := categorySlugs(update(`{"description":"unchanged categories"}`).Categories); got != "[cli web]" {
+		t.Errorf("categories after update without category_ids = %s, want [cli web]", got)
+	}
+	if got := categorySlugs(update(fmt.Sprintf(`{"category_ids":[%d]}`, database.ID)).Categories); got != "[database]" {
+		t.Errorf("categories after replace = %s, want [database]", got)
+	}
+	if got := categorySlugs(update(`{"category_ids":[]}`).Categories); got != "[]" {
+		t.Errorf("categories after clear = %s, want []", got)
+	}
+	update(fmt.Sprintf(`{"category_ids":[%d,%d]}`, web.ID, cli.ID))
+
+	other := tr.do(http.MethodPost, "/api/v1/packages/", ownerToken, fmt.Sprintf(`{"name":"tool",%s,"category_ids":[%d]}`, packageLinks, cli.ID))
+	if other.Code != http.StatusOK && other.Code != http.StatusCreated {
+		t.Fatalf("create tool status = %d", other.Code)
+	}
+	// 按分类slug过滤搜索结果
+	for _, tt := range []struct {
+		category string
+		want     string
+	}{
+		{"web", "[server]"},
+		{"cli", "[server tool]"},
+		{"database", "[]"},
+		{"missing", "[]"},
+	} {
+		w := tr.do(http.MethodGet, "/api/v1/packages/?category="+tt.category, "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("search category=%s status = %d", tt.category, w.Code)
+		}
+		var result struct {
+			Packages []models.Package `json:"packages"`
+		}
+		decodeData(t, w, &result)
+		names := []string{}
+		for _, pkg := range result.Packages {
+			names = append(names, pkg.Name)
+		}
+		sort.Strings(names)
+		if got := fmt.Sprint(names); got != tt.want {
+			t.Errorf("search category=%s = %s, want %s", tt.category, got, tt.want)
+		}
+	}
+
+	// 分类表中的包数只统计公开包
+	hidden := tr.createPackage("hidden", owner, true)
+	if err := tr.db.Create(&models.PackageCategory{PackageID: hidden.ID, CategoryID: cli.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = tr.do(http.MethodGet, "/api/v1/categories", "", "")
+	var list struct {
+		Categories []models.CategoryWithCount `json:"categories"`
+	}
+	decodeData(t, w, &list)
+	counts := map[string]int64{}
+	for _, category := range list.Categories {
+		counts[category.Slug] = category.PackageCount
+	}
+	if counts["web"] != 1 || counts["cli"] != 2 || counts["database"] != 0 {
+		t.Errorf("package counts = %v, want web 1, cli 2, database 0", counts)
+	}
+
+	// 删除分类同时移除包上的关联
+	if w := tr.do(http.MethodDelete, fmt.Sprintf("/api/v1/admin/categories/%d", web.ID), adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete category status = %d", w.Code)
+	}
+	w = tr.do(http.MethodGet, "/api/v1/packages/server", "", "")
+	var pkg models.Package
+	decodeData(t, w, &pkg)
+	if got := categorySlugs(pkg.Categories); got != "[cli]" {
+		t.Errorf("categories after deleting web = %s, want [cli]", got)
+	}
+}
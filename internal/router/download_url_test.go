@@ -0,0 +1,89 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/service"
+)
+
+// downloadURLResponse 获取下载链接接口的响应
+type downloadURLResponse struct {
+	DownloadURL string    `json:"download_url"`
+	Filename    string    `json:"filename"`
+	ExpiresIn   int       `json:"expires_in"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func TestRefreshDownloadURL(t *testing.T) {
+	packagesCfg := config.PackagesConfig{DownloadURLMode: service.DownloadURLModeAppSigned, DownloadURLSecret: "download-secret", PublicBaseURL: "https://pkg.example.com"}
+	tr := newTestRouter(t, func(cfg *config.Config) { cfg.Packages = packagesCfg })
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	_, strangerToken := tr.createUser("mallory", models.RoleUser)
+	pkg := tr.createPackage("secret", owner, true)
+	version := &models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", MinIOPath: "packages/secret/1.0.0", FileHash: "x", ScanStatus: models.ScanStatusActive}
+	if err := tr.db.Create(version).Error; err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	// 用与服务端相同的密钥校验签发的令牌
+	verifier := service.NewPackageService(tr.db, nil, nil, packagesCfg)
+
+	issue := func(method, path string) downloadURLResponse {
+		t.Helper()
+		w := tr.do(method, path, ownerToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s status = %d, body %s", method, path, w.Code, w.Body.String())
+		}
+		var resp downloadURLResponse
+		decodeData(t, w, &resp)
+		token, ok := strings.CutPrefix(resp.DownloadURL, "https://pkg.example.com/download/")
+		if !ok {
+			t.Fatalf("download_url = %q, want an app-signed link", resp.DownloadURL)
+		}
+		claims, err := verifier.VerifyDownloadToken(token)
+		if err != nil {
+			t.Fatalf("issued link is not valid: %v", err)
+		}
+		if claims.Package != "secret" || claims.Version != "1.0.0" || claims.UserID != owner.ID {
+			t.Errorf("token claims = %+v, want secret@1.0.0 for the owner", claims)
+		}
+		if claims.ExpiresAt != resp.ExpiresAt.Unix() {
+			t.Errorf("expires_at = %v, token expires at %d", resp.ExpiresAt, claims.ExpiresAt)
+		}
+		if resp.ExpiresIn != int(time.Hour.Seconds()) || resp.Filename != "secret-1.0.0.pkg" {
+			t.Errorf("response = %+v, want a one-hour secret-1.0.0.pkg link", resp)
+		}
+		return resp
+	}
+
+	original := issue(http.MethodGet, "/api/v1/packages/secret/1.0.0/download-url")
+	// 过期时间精确到秒，等待进入下一秒后刷新
+	time.Sleep(time.Until(original.ExpiresAt.Add(-time.Hour).Add(time.Second)))
+	refreshed := issue(http.MethodPost, "/api/v1/packages/secret/1.0.0/download-url/refresh")
+	if refreshed.DownloadURL == original.DownloadURL {
+		t.Error("refresh returned the same link")
+	}
+	if !refreshed.ExpiresAt.After(original.ExpiresAt) {
+		t.Errorf("refreshed expires_at = %v, want later than %v", refreshed.ExpiresAt, original.ExpiresAt)
+	}
+
+	// 刷新与获取执行相同的权限检查
+	for _, tt := range []struct {
+		name  string
+		path  string
+		token string
+		code  int
+	}{
+		{"anonymous", "/api/v1/packages/secret/1.0.0/download-url/refresh", "", http.StatusForbidden},
+		{"stranger", "/api/v1/packages/secret/1.0.0/download-url/refresh", strangerToken, http.StatusForbidden},
+		{"missing version", "/api/v1/packages/secret/9.9.9/download-url/refresh", ownerToken, http.StatusNotFound},
+	} {
+		if w := tr.do(http.MethodPost, tt.path, tt.token, ""); w.Code != tt.code {
+			t.Errorf("%s refresh status = %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+}
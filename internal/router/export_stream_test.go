@@ -0,0 +1,160 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// holdSecondBatch 导出查询第二批数据前等待release关闭，超时仍未关闭时记录到timedOut
+// 若响应没有流式写出，客户端在等待期间收不到首行，release不会被关闭
+func holdSecondBatch(t *testing.T, db *gorm.DB, table string, release <-chan struct{}, timedOut *atomic.Bool) {
+	t.Helper()
+	var batches atomic.Int32
+	err := db.Callback().Row().Before("gorm:row").Register("test:hold_second_batch", func(tx *gorm.DB) {
+		if tx.Statement.Table != table || batches.Add(1) != 2 {
+			return
+		}
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+			timedOut.Store(true)
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+}
+
+// readStream 通过真实的HTTP连接读取导出响应，读到首行后关闭release，返回全部行
+func readStream(t *testing.T, url, token string, release chan<- struct{}) (*http.Response, []string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) == 1 {
+			close(release)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	return resp, lines
+}
+
+func TestExportStreamsPackagesInChunks(t *testing.T) {
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Export = config.ExportConfig{BatchSize: 10, FlushRows: 10}
+	})
+	owner, _ := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	for i := 0; i < 45; i++ {
+		tr.createPackage(fmt.Sprintf("pkg-%02d", i), owner, false)
+	}
+
+	release := make(chan struct{})
+	var timedOut atomic.Bool
+	holdSecondBatch(t, tr.db, "packages", release, &timedOut)
+	srv := httptest.NewServer(tr.r)
+	defer srv.Close()
+
+	resp, lines := readStream(t, srv.URL+"/api/v1/admin/packages/export", adminToken, release)
+	if timedOut.Load() {
+		t.Fatal("first row did not reach the client before the second batch was queried")
+	}
+	if resp.ContentLength != -1 || len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("content length %d, transfer encoding %v; want a chunked response", resp.ContentLength, resp.TransferEncoding)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+	}
+
+	if len(lines) != 46 {
+		t.Fatalf("got %d lines, want 45 rows and a trailer", len(lines))
+	}
+	for i, line := range lines[:45] {
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("pkg-%02d", i); row["name"] != want {
+			t.Errorf("line %d name = %v, want %s", i, row["name"], want)
+		}
+	}
+	var trailer struct {
+		Trailer  bool  `json:"_trailer"`
+		Rows     int64 `json:"rows"`
+		Complete bool  `json:"complete"`
+	}
+	if err := json.Unmarshal([]byte(lines[45]), &trailer); err != nil {
+		t.Fatal(err)
+	}
+	if !trailer.Trailer || trailer.Rows != 45 || !trailer.Complete {
+		t.Errorf("trailer = %+v, want 45 rows and complete", trailer)
+	}
+}
+
+func TestExportStreamsDownloadsCSVInChunks(t *testing.T) {
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Export = config.ExportConfig{BatchSize: 10, FlushRows: 5}
+	})
+	owner, _ := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	pkg := tr.createPackage("lib", owner, false)
+	version := &models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", FileHash: "x"}
+	if err := tr.db.Create(version).Error; err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	for i := 0; i < 25; i++ {
+		if err := tr.db.Create(&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1"}).Error; err != nil {
+			t.Fatalf("failed to create download: %v", err)
+		}
+	}
+
+	release := make(chan struct{})
+	var timedOut atomic.Bool
+	holdSecondBatch(t, tr.db, "package_downloads", release, &timedOut)
+	srv := httptest.NewServer(tr.r)
+	defer srv.Close()
+
+	resp, lines := readStream(t, srv.URL+"/api/v1/admin/downloads?format=csv", adminToken, release)
+	if timedOut.Load() {
+		t.Fatal("CSV header did not reach the client before the second batch was queried")
+	}
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("transfer encoding = %v, want chunked", resp.TransferEncoding)
+	}
+	// 列名行、25行数据和#trailer记录
+	if len(lines) != 27 {
+		t.Fatalf("got %d lines, want a header, 25 rows and a trailer", len(lines))
+	}
+	if !strings.HasPrefix(lines[26], "#trailer,dataset=downloads,rows=25,") || !strings.HasSuffix(lines[26], "complete=true") {
+		t.Errorf("trailer = %q, want 25 rows and complete", lines[26])
+	}
+}
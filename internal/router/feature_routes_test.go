@@ -0,0 +1,184 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"webservice/internal/feature"
+	"webservice/internal/models"
+	"webservice/internal/password"
+	"webservice/internal/totp"
+)
+
+func TestFeatureGuardedRoutesRequireFlag(t *testing.T) {
+	routes := []struct {
+		flag   string
+		method string
+		path   string
+		body   string
+	}{
+		{flag: feature.GraphQL, method: http.MethodPost, path: "/api/v1/graphql", body: `{"query":"{ packages { name } }"}`},
+		{flag: feature.TOTP, method: http.MethodPost, path: "/api/v1/auth/totp/setup"},
+		{flag: feature.TOTP, method: http.MethodPost, path: "/api/v1/auth/totp/enable", body: `{"code":"000000"}`},
+		{flag: feature.APIV2, method: http.MethodGet, path: "/api/v2/packages/public-pkg"},
+		{flag: feature.APIV2, method: http.MethodGet, path: "/api/v2/packages/public-pkg/versions"},
+	}
+
+	for _, rt := range routes {
+		t.Run(rt.method+" "+rt.path, func(t *testing.T) {
+			tr := newTestRouter(t)
+			enabled, enabledToken := tr.createUser("alice", models.RoleUser)
+			_, otherToken := tr.createUser("bob", models.RoleUser)
+			tr.createPackage("public-pkg", enabled, false)
+			tr.enableFeature(rt.flag, enabled.ID)
+
+			if w := tr.do(rt.method, rt.path, "", rt.body); w.Code != http.StatusUnauthorized {
+				t.Errorf("anonymous: status = %d, want 401", w.Code)
+			}
+			if w := tr.do(rt.method, rt.path, otherToken, rt.body); w.Code != http.StatusNotFound {
+				t.Errorf("flag off: status = %d, want 404, body %s", w.Code, w.Body.String())
+			}
+			if w := tr.do(rt.method, rt.path, enabledToken, rt.body); w.Code == http.StatusNotFound {
+				t.Errorf("flag on: status = 404, body %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGraphQLRespectsPackageVisibility(t *testing.T) {
+	tr := newTestRouter(t)
+	alice, aliceToken := tr.createUser("alice", models.RoleUser)
+	bob, _ := tr.createUser("bob", models.RoleUser)
+	pkg := tr.createPackage("public-pkg", bob, false)
+	tr.createPackage("private-pkg", bob, true)
+	if err := tr.db.Create(&models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", FileSize: 1, UploaderID: bob.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	tr.enableFeature(feature.GraphQL, alice.ID)
+
+	query := func(q string) (map[string]json.RawMessage, []interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"query": q})
+		w := tr.do(http.MethodPost, "/api/v1/graphql", aliceToken, string(body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data   map[string]json.RawMessage `json:"data"`
+			Errors []interface{}              `json:"errors"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode %s: %v", w.Body.String(), err)
+		}
+		return resp.Data, resp.Errors
+	}
+
+	data, errs := query(`{ package(name: "public-pkg") { name versions { version } } }`)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	var got struct {
+		Name     string `json:"name"`
+		Versions []struct {
+			Version string `json:"version"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(data["package"], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "public-pkg" || len(got.Versions) != 1 || got.Versions[0].Version != "1.0.0" {
+		t.Errorf("package = %+v", got)
+	}
+
+	if _, errs := query(`{ package(name: "private-pkg") { name } }`); len(errs) == 0 {
+		t.Error("private package of another user resolved without error")
+	}
+
+	data, errs = query(`{ packages { name } }`)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	var list []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data["packages"], &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "public-pkg" {
+		t.Errorf("packages = %+v, want only public-pkg", list)
+	}
+}
+
+func TestTOTPEnrollmentAndLogin(t *testing.T) {
+	tr := newTestRouter(t)
+	user, token := tr.createUser("alice", models.RoleUser)
+	hashed, err := password.NewHasher(tr.cfg.Password).Hash("correct-horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.db.Model(user).Update("password", hashed).Error; err != nil {
+		t.Fatal(err)
+	}
+	tr.enableFeature(feature.TOTP, user.ID)
+
+	w := tr.do(http.MethodPost, "/api/v1/auth/totp/setup", token, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("setup: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var setup models.TOTPSetupResponse
+	decodeData(t, w, &setup)
+	if setup.Secret == "" || setup.ProvisioningURI == "" {
+		t.Fatalf("setup = %+v", setup)
+	}
+
+	code := func() string {
+		t.Helper()
+		c, err := totp.Code(setup.Secret, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	codeBody := func(c string) string { return fmt.Sprintf(`{"code":%q}`, c) }
+
+	wrong := []byte(code())
+	wrong[0] = '0' + (wrong[0]-'0'+1)%10
+	if w := tr.do(http.MethodPost, "/api/v1/auth/totp/enable", token, codeBody(string(wrong))); w.Code != http.StatusBadRequest {
+		t.Errorf("enable with wrong code: status = %d, want 400", w.Code)
+	}
+	if w := tr.do(http.MethodPost, "/api/v1/auth/totp/enable", token, codeBody(code())); w.Code != http.StatusOK {
+		t.Fatalf("enable: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	login := func(totpCode string) int {
+		body, _ := json.Marshal(models.LoginRequest{Username: "alice", Password: "correct-horse", TOTPCode: totpCode})
+		return tr.do(http.MethodPost, "/api/v1/public/login", "", string(body)).Code
+	}
+	if status := login(""); status != http.StatusUnauthorized {
+		t.Errorf("login without code: status = %d, want 401", status)
+	}
+	if status := login(code()); status != http.StatusOK {
+		t.Errorf("login with code: status = %d, want 200", status)
+	}
+
+	// 关闭开关后已启用的用户仍须提供验证码，并且仍可关闭两步验证
+	if err := tr.db.Where("name = ?", feature.TOTP).Delete(&models.FeatureFlag{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.flags.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if status := login(""); status != http.StatusUnauthorized {
+		t.Errorf("login without code after flag removed: status = %d, want 401", status)
+	}
+	if w := tr.do(http.MethodDelete, "/api/v1/auth/totp", token, codeBody(code())); w.Code != http.StatusOK {
+		t.Fatalf("disable: status = %d, body %s", w.Code, w.Body.String())
+	}
+	if status := login(""); status != http.StatusOK {
+		t.Errorf("login after disable: status = %d, want 200", status)
+	}
+}
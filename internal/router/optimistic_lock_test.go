@@ -0,0 +1,46 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestUpdatePackageStaleIfVersionReturnsConflict(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	maintainer, maintainerToken := tr.createUser("bob", models.RoleUser)
+	pkg := tr.createPackage("shared", owner, false)
+	if err := tr.db.Create(&models.PackageCollaborator{PackageID: pkg.ID, UserID: maintainer.ID, Role: models.CollaboratorRoleMaintainer}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个维护者读取到相同的lock_version后先后提交
+	body := func(description string) string {
+		return `{"description":"` + description + `","homepage":"https://example.com","repository":"https://example.com/repo","if_version":1}`
+	}
+	if w := tr.do(http.MethodPut, "/api/v1/packages/shared", ownerToken, body("owner edit")); w.Code != http.StatusOK {
+		t.Fatalf("first update status = %d, body %s", w.Code, w.Body.String())
+	}
+	w := tr.do(http.MethodPut, "/api/v1/packages/shared", maintainerToken, body("maintainer edit"))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("second update status = %d, want 409, body %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Code    int            `json:"code"`
+		Message string         `json:"message"`
+		Data    models.Package `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 40901 || resp.Message != "conflict_stale_update" {
+		t.Errorf("conflict = code %d message %q, want 40901 conflict_stale_update", resp.Code, resp.Message)
+	}
+	if resp.Data.Description != "owner edit" || resp.Data.LockVersion != 2 {
+		t.Errorf("conflict data = description %q lock_version %d, want the owner's edit at 2", resp.Data.Description, resp.Data.LockVersion)
+	}
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestPackageRichMetadataRoutes(t *testing.T) {
+	tr := newTestRouter(t, func(cfg *config.Config) {
+		cfg.Packages.MaxLongDescriptionLength = 100
+	})
+	_, token := tr.createUser("alice", models.RoleUser)
+
+	body := `{"name":"rich","homepage":"https://example.com","repository":"https://example.com/rich.git",` +
+		`"long_description":"# Rich\n\nlonger text","funding_url":"https://example.com/sponsor",` +
+		`"bug_tracker_url":"https://example.com/issues","documentation":"https://example.com/docs"}`
+	if w := tr.do(http.MethodPost, "/api/v1/packages/", token, body); w.Code != http.StatusOK {
+		t.Fatalf("create status = %d, body %s", w.Code, w.Body.String())
+	}
+	update := `{"homepage":"https://example.com","repository":"https://example.com/rich.git","funding_url":"https://example.com/fund"}`
+	if w := tr.do(http.MethodPut, "/api/v1/packages/rich", token, update); w.Code != http.StatusOK {
+		t.Fatalf("update status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	w := tr.do(http.MethodGet, "/api/v1/packages/rich", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body %s", w.Code, w.Body.String())
+	}
+	var pkg models.Package
+	decodeData(t, w, &pkg)
+	if pkg.LongDescription != "# Rich\n\nlonger text" || pkg.FundingURL != "https://example.com/fund" ||
+		pkg.BugTrackerURL != "https://example.com/issues" || pkg.Documentation != "https://example.com/docs" {
+		t.Errorf("package = %+v, want the rich metadata", pkg)
+	}
+
+	overlong := "https://example.com/" + strings.Repeat("a", 236)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"long description", fmt.Sprintf(`{"long_description":%q}`, strings.Repeat("x", 101))},
+		{"javascript funding", `{"funding_url":"javascript:alert(1)"}`},
+		{"ftp documentation", `{"documentation":"ftp://example.com/docs"}`},
+		{"overlong bug tracker", fmt.Sprintf(`{"bug_tracker_url":%q}`, overlong)},
+		{"javascript homepage", `{"homepage":"javascript:alert(1)","repository":"https://example.com/rich.git"}`},
+		{"overlong homepage", fmt.Sprintf(`{"homepage":%q,"repository":"https://example.com/rich.git"}`, overlong)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 补全创建和更新请求中必填的链接字段
+			fields := strings.TrimSuffix(strings.TrimPrefix(tt.body, "{"), "}")
+			if !strings.Contains(fields, `"homepage"`) {
+				fields += `,"homepage":"https://example.com","repository":"https://example.com/rich.git"`
+			}
+			if w := tr.do(http.MethodPost, "/api/v1/packages/", token, `{"name":"rejected",`+fields+`}`); w.Code != http.StatusBadRequest {
+				t.Errorf("create status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+			if w := tr.do(http.MethodPut, "/api/v1/packages/rich", token, "{"+fields+"}"); w.Code != http.StatusBadRequest {
+				t.Errorf("update status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestListEndpointsPathologicalPagination(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	var pkg *models.Package
+	for i := 0; i < 3; i++ {
+		pkg = tr.createPackage(fmt.Sprintf("lib-%d", i), owner, false)
+	}
+	for i := 0; i < 3; i++ {
+		version := &models.PackageVersion{PackageID: pkg.ID, Version: fmt.Sprintf("1.0.%d", i), FileHash: "x"}
+		if err := tr.db.Create(version).Error; err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		if err := tr.db.Create(&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1"}).Error; err != nil {
+			t.Fatalf("failed to create download: %v", err)
+		}
+		if err := tr.db.Create(&models.PackageWatcher{UserID: owner.ID, PackageID: pkg.ID - uint(i)}).Error; err != nil {
+			t.Fatalf("failed to create watch: %v", err)
+		}
+	}
+
+	endpoints := []struct {
+		path        string
+		token       string
+		key         string
+		total       int
+		defaultSize int
+		maxSize     int
+	}{
+		{"/api/v1/packages/", "", "packages", 3, models.DefaultPageSize, models.MaxPageSize},
+		{fmt.Sprintf("/api/v1/users/%d/packages", owner.ID), "", "packages", 3, models.DefaultPageSize, models.MaxPageSize},
+		{"/api/v1/packages/lib-2/versions", "", "versions", 3, models.DefaultPageSize, models.MaxPageSize},
+		{"/api/v1/packages/lib-2/downloads", ownerToken, "downloads", 3, 100, 1000},
+		{"/api/v1/auth/watching", ownerToken, "packages", 3, models.DefaultPageSize, models.MaxPageSize},
+		{"/api/v1/users/", "", "users", 2, 10, 50},
+		{"/api/v1/admin/users", adminToken, "users", 2, 10, 100},
+	}
+	for _, ep := range endpoints {
+		tests := []struct {
+			query    string
+			page     int
+			pageSize int
+		}{
+			{"", 1, ep.defaultSize},
+			{"page=-1&page_size=-5", 1, ep.defaultSize},
+			{"page=0&page_size=0", 1, ep.defaultSize},
+			{"page_size=1000000", 1, ep.maxSize},
+			{"page=99999999999999999999&page_size=99999999999999999999", 1, ep.defaultSize},
+			{"page=abc&page_size=1e3", 1, ep.defaultSize},
+			{"page=%00&page_size=%20", 1, ep.defaultSize},
+			{"page=2&page_size=1", 2, 1},
+			{"page=1000&page_size=2", 1000, 2},
+			{"page=9223372036854775807&page_size=1", models.MaxPage, 1},
+		}
+		for _, tt := range tests {
+			t.Run(ep.path+"?"+tt.query, func(t *testing.T) {
+				w := tr.do(http.MethodGet, ep.path+"?"+tt.query, ep.token, "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+				}
+				var data map[string]interface{}
+				decodeData(t, w, &data)
+
+				items, ok := data[ep.key].([]interface{})
+				if !ok {
+					t.Fatalf("data[%q] = %T, want a list", ep.key, data[ep.key])
+				}
+				page, pageSize := int(data["page"].(float64)), int(data["page_size"].(float64))
+				total, totalPages := int(data["total"].(float64)), int(data["total_pages"].(float64))
+				if page != tt.page || pageSize != tt.pageSize {
+					t.Errorf("page/page_size = %d/%d, want %d/%d", page, pageSize, tt.page, tt.pageSize)
+				}
+				if total != ep.total {
+					t.Errorf("total = %d, want %d", total, ep.total)
+				}
+				if want := (total + pageSize - 1) / pageSize; totalPages != want {
+					t.Errorf("total_pages = %d, want %d", totalPages, want)
+				}
+				if data["has_next"] != (page < totalPages) || data["has_prev"] != (page > 1) {
+					t.Errorf("has_next/has_prev = %v/%v for page %d of %d", data["has_next"], data["has_prev"], page, totalPages)
+				}
+				wantItems := 0
+				if start := (page - 1) * pageSize; start < total {
+					wantItems = min(pageSize, total-start)
+				}
+				if len(items) != wantItems {
+					t.Errorf("items = %d, want %d", len(items), wantItems)
+				}
+			})
+		}
+	}
+}
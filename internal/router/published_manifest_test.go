@@ -0,0 +1,68 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestPublishedManifestIsImmutable(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	pkg := tr.createPackage("app", owner, false)
+	// 路由测试不连接存储，按上传时的方式直接写入带快照的版本
+	version := &models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", Description: "original", Dependencies: `{"left-pad":"^1.0.0"}`, FileHash: "x"}
+	version.PublishedManifest = models.NewPublishedManifest(pkg.Name, version)
+	if err := tr.db.Create(version).Error; err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	manifestOf := func() *models.PublishedManifest {
+		t.Helper()
+		var version models.PackageVersion
+		if err := tr.db.Where("package_id = ? AND version = ?", pkg.ID, "1.0.0").First(&version).Error; err != nil {
+			t.Fatalf("failed to load version: %v", err)
+		}
+		return version.PublishedManifest
+	}
+	published := manifestOf()
+	if published == nil || published.Dependencies["left-pad"] != "^1.0.0" {
+		t.Fatalf("published manifest = %+v, want the dependencies at publish", published)
+	}
+
+	// 修改快照或快照中的字段被拒绝，快照保持不变
+	for _, body := range []string{
+		`{"published_manifest":{"name":"app","version":"1.0.0","dependencies":{"evil":"1.0.0"}}}`,
+		`{"dependencies":{"evil":"1.0.0"}}`,
+		`{"file_hash":"forged"}`,
+		`{"description":"edited","version":"2.0.0"}`,
+	} {
+		w := tr.do(http.MethodPatch, "/api/v1/packages/app/1.0.0", ownerToken, body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("PATCH %s status = %d, want 400", body, w.Code)
+			continue
+		}
+		if msg := errorMessage(t, w.Body.Bytes()); !strings.Contains(msg, "cannot be changed after publish") {
+			t.Errorf("PATCH %s message = %q", body, msg)
+		}
+		if got := manifestOf(); !reflect.DeepEqual(got, published) {
+			t.Errorf("manifest after PATCH %s = %+v, want %+v", body, got, published)
+		}
+	}
+
+	// 可编辑字段照常修改，快照保留发布时的值
+	w := tr.do(http.MethodPatch, "/api/v1/packages/app/1.0.0", ownerToken, `{"description":"edited"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH description status = %d, body %s", w.Code, w.Body.String())
+	}
+	var updated models.PackageVersion
+	decodeData(t, w, &updated)
+	if updated.Description != "edited" {
+		t.Errorf("description = %q, want edited", updated.Description)
+	}
+	if got := manifestOf(); !reflect.DeepEqual(got, published) || got.Description != "original" {
+		t.Errorf("manifest after editing description = %+v, want %+v", got, published)
+	}
+}
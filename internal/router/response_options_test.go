@@ -0,0 +1,138 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+// dataKeys 返回响应data对象的字段名，按字母排序
+func dataKeys(t *testing.T, body []byte) ([]string, map[string]interface{}) {
+	t.Helper()
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to decode %s: %v", body, err)
+	}
+	keys := make([]string, 0, len(resp.Data))
+	for key := range resp.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, resp.Data
+}
+
+func TestPackageDetailFields(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, _ := tr.createUser("alice", models.RoleUser)
+	pkg := tr.createPackage("app", owner, false)
+	if err := tr.db.Model(pkg).Updates(map[string]interface{}{"description": "an app", "long_description": "a long story"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		if err := tr.db.Create(&models.PackageVersion{PackageID: pkg.ID, Version: v, FileHash: "x"}).Error; err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		keys  string
+	}{
+		{"fields=name,description,latest_version", "[description latest_version name]"},
+		{"fields=+name+,,name", "[name]"},
+		{"fields=long_description,versions", "[long_description versions]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := tr.do(http.MethodGet, "/api/v1/packages/app?"+tt.query, "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			keys, _ := dataKeys(t, w.Body.Bytes())
+			if got := strings.Join(keys, " "); "["+got+"]" != tt.keys {
+				t.Errorf("keys = [%s], want %s", got, tt.keys)
+			}
+		})
+	}
+
+	w := tr.do(http.MethodGet, "/api/v1/packages/app?fields=name,description,latest_version", "", "")
+	_, data := dataKeys(t, w.Body.Bytes())
+	if data["name"] != "app" || data["description"] != "an app" || data["latest_version"] != "1.1.0" {
+		t.Errorf("data = %v, want app / an app / 1.1.0", data)
+	}
+
+	// 不带fields时返回完整对象
+	full := tr.do(http.MethodGet, "/api/v1/packages/app", "", "")
+	if keys, _ := dataKeys(t, full.Body.Bytes()); len(keys) < 10 {
+		t.Errorf("full detail keys = %v, want the whole package", keys)
+	}
+	if len(w.Body.Bytes()) >= len(full.Body.Bytes()) {
+		t.Errorf("filtered response is %d bytes, full response %d bytes", w.Body.Len(), full.Body.Len())
+	}
+
+	// 未知字段和敏感字段返回400并列出可选字段
+	for _, field := range []string{"bogus", "minio_path", "owner_id,password"} {
+		w := tr.do(http.MethodGet, "/api/v1/packages/app?fields="+field, "", "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("fields=%s status = %d, want 400", field, w.Code)
+			continue
+		}
+		if msg := errorMessage(t, w.Body.Bytes()); !strings.Contains(msg, "valid fields: ") || !strings.Contains(msg, "latest_version") {
+			t.Errorf("fields=%s message = %q, want the valid detail fields", field, msg)
+		}
+	}
+}
+
+func TestPrettyResponses(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, _ := tr.createUser("alice", models.RoleUser)
+	tr.createPackage("app", owner, false)
+
+	for _, path := range []string{
+		"/api/v1/packages/app",
+		"/api/v1/packages/app?fields=name,description",
+		"/api/v1/packages/missing", // 错误响应同样适用
+	} {
+		t.Run(path, func(t *testing.T) {
+			sep := "?"
+			if strings.Contains(path, "?") {
+				sep = "&"
+			}
+			compact := tr.do(http.MethodGet, path, "", "")
+			pretty := tr.do(http.MethodGet, path+sep+"pretty=true", "", "")
+			notPretty := tr.do(http.MethodGet, path+sep+"pretty=false", "", "")
+			if pretty.Code != compact.Code {
+				t.Fatalf("pretty status = %d, compact status = %d", pretty.Code, compact.Code)
+			}
+
+			if bytes.Contains(bytes.TrimSpace(compact.Body.Bytes()), []byte("\n")) || bytes.Contains(bytes.TrimSpace(notPretty.Body.Bytes()), []byte("\n")) {
+				t.Errorf("response without pretty=true is indented: %s", compact.Body.String())
+			}
+			if !strings.HasPrefix(pretty.Body.String(), "{\n    \"") {
+				t.Errorf("pretty response is not indented: %s", pretty.Body.String())
+			}
+			// 除请求ID和时间戳外内容相同
+			strip := func(body []byte) map[string]interface{} {
+				var resp map[string]interface{}
+				if err := json.Unmarshal(body, &resp); err != nil {
+					t.Fatal(err)
+				}
+				delete(resp, "request_id")
+				delete(resp, "timestamp")
+				return resp
+			}
+			a, _ := json.Marshal(strip(pretty.Body.Bytes()))
+			b, _ := json.Marshal(strip(compact.Body.Bytes()))
+			if !bytes.Equal(a, b) {
+				t.Errorf("pretty content %s differs from compact %s", a, b)
+			}
+		})
+	}
+}
@@ -2,11 +2,18 @@ package router
 
 import (
 	"net/http"
+	"time"
 
+	"webservice/internal/authz"
 	"webservice/internal/config"
+	"webservice/internal/feature"
 	"webservice/internal/handler"
+	"webservice/internal/httpclient"
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
 	"webservice/internal/middleware"
 	"webservice/internal/minio"
+	"webservice/internal/service"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,62 +21,107 @@ import (
 )
 
 // Setup 设置路由
-func Setup(cfg *config.Config, db *gorm.DB, minioClient *minio.Client) *gin.Engine {
+func Setup(cfg *config.Config, db *gorm.DB, minioClient *minio.Client, httpClients *httpclient.Factory, healthHistory *service.HealthHistoryService, leaderboards *service.LeaderboardService, featureFlags *feature.Flags) *gin.Engine {
 	// 设置Gin模式
 	gin.SetMode(cfg.Server.Mode)
 
 	// 创建Gin引擎
 	r := gin.New()
 
-	// 设置信任的代理
-	r.SetTrustedProxies([]string{"127.0.0.1", "::1"})
+	// 设置信任的代理，客户端IP（限流、IP过滤、审计日志）只信任来自这些代理的转发请求头
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Warnf("Invalid server.trusted_proxies, trusting no proxies: %v", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 
 	// 全局中间件
-	setupMiddleware(r, cfg)
+	setupMiddleware(r, cfg, db)
 
 	// 设置路由组
-	setupRoutes(r, cfg, db, minioClient)
+	setupRoutes(r, cfg, db, minioClient, httpClients, healthHistory, leaderboards, featureFlags)
 
 	return r
 }
 
 // setupMiddleware 设置全局中间件
-func setupMiddleware(r *gin.Engine, cfg *config.Config) {
+func setupMiddleware(r *gin.Engine, cfg *config.Config, db *gorm.DB) {
 	// 恢复中间件（处理panic）
 	r.Use(gin.Recovery())
 
 	// 请求ID中间件
-	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.RequestIDMiddleware(cfg.RequestID))
 
 	// 链路追踪中间件
-	r.Use(middleware.TracingMiddleware())
+	r.Use(middleware.TracingMiddleware(cfg.Jaeger))
 
 	// 日志中间件
-	r.Use(middleware.LoggerMiddleware())
+	r.Use(middleware.LoggerMiddleware(cfg.Log))
+
+	// Server响应头，标识服务版本
+	r.Use(middleware.ServerHeader())
+
+	// 行级安全：请求默认按匿名用户访问packages表，认证后按当前用户
+	if cfg.Database.RowLevelSecurity {
+		r.Use(middleware.RowScope())
+	}
+
+	// IP过滤中间件，放在日志之后以便记录被拒绝的请求
+	if cfg.IPFilter.Enabled {
+		r.Use(middleware.IPFilter(cfg.IPFilter))
+	}
 
 	// CORS中间件
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "X-Token", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID", "Deprecation", "Sunset", "Link", "X-Token-Expires-In", "X-Token-Refresh-Recommended"},
 		AllowCredentials: true,
 	}))
 
+	// API兼容性检查中间件：命中弃用映射的请求返回Deprecation/Sunset/Link响应头
+	deprecationRules, err := config.LoadDeprecationMap(cfg.Deprecation.MapFile)
+	if err != nil {
+		logger.Warnf("Failed to load deprecation map %s: %v", cfg.Deprecation.MapFile, err)
+	}
+	r.Use(middleware.DeprecationMiddleware(deprecationRules, service.NewDeprecationService(db)))
+
 	// 响应格式化中间件
 	r.Use(middleware.ResponseMiddleware())
 }
 
+// packagesUpdateSunset /packages/update分组旧路径的计划下线时间
+var packagesUpdateSunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+
 // setupRoutes 设置路由组
-func setupRoutes(r *gin.Engine, cfg *config.Config, db *gorm.DB, minioClient *minio.Client) {
+func setupRoutes(r *gin.Engine, cfg *config.Config, db *gorm.DB, minioClient *minio.Client, httpClients *httpclient.Factory, healthHistory *service.HealthHistoryService, leaderboards *service.LeaderboardService, featureFlags *feature.Flags) {
 	// 创建处理器
-	h := handler.NewHandler(cfg, db, minioClient)
+	h := handler.NewHandler(cfg, db, minioClient, httpClients, healthHistory, leaderboards, featureFlags)
+
+	// 会话管理接口必须识别当前用户，单独挂载JWT认证（校验会话是否已吊销）
+	sessionService := service.NewSessionService(db)
+	jwtAuth := middleware.JWTAuth(cfg.JWT, sessionService, featureFlags)
+	// 携带token时解析用户，不携带时按匿名访问，用于同时服务公开和私有内容的接口
+	optionalAuth := middleware.OptionalJWTAuth(cfg.JWT, sessionService)
+
+	// 弃用路由注册表，弃用声明随各路由分组一起维护
+	deprecations := middleware.NewDeprecationRegistry(service.NewDeprecationService(db))
 
 	// 健康检查路由 - 用于监控服务状态
 	r.GET("/health", h.HealthCheck)       // 返回服务健康状态信息
 	r.GET("/ping", func(c *gin.Context) { // 简单的连通性测试接口
 		middleware.SuccessResponse(c, gin.H{"message": "pong"})
 	})
+	r.GET("/metrics", gin.WrapH(metrics.Handler())) // Prometheus格式的运行指标
+	r.GET("/version", h.GetVersion)                 // 构建版本、提交和构建日期
+
+	// app-signed下载链接 - 令牌自带签名和有效期，无需登录
+	r.GET("/download/:token", h.PackageHandler.DownloadWithToken) // 校验下载令牌并转发包文件
+
+	// pprof性能分析接口 - 需要配置开启，仅管理员可访问
+	if cfg.Debug.Pprof {
+		handler.RegisterPprof(r.Group("/debug/pprof", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionAdminDiagnostics)))
+	}
 
 	// API版本1路由组 - 所有业务API的根路径
 	v1 := r.Group("/api/v1")
@@ -80,52 +132,244 @@ func setupRoutes(r *gin.Engine, cfg *config.Config, db *gorm.DB, minioClient *mi
 			public.POST("/login", h.Login)          // 用户登录接口 - 验证用户名密码并返回JWT token
 			public.POST("/register", h.Register)    // 用户注册接口 - 创建新用户账户
 			public.POST("/refresh", h.RefreshToken) // Token刷新接口 - 在token即将过期时获取新token
+			public.POST("/setup", h.Setup)          // 首次启动引导接口 - 使用一次性安装令牌创建首个管理员
+
+			// 外部身份（OAuth/SSO）登录，提供方凭据由注册的校验器验证
+			public.POST("/oauth/callback", h.OAuthCallback) // 查找或创建关联的本地用户并返回JWT token
 		}
 
 		// 需要认证的路由 - 必须携带有效JWT token才能访问
 		auth := v1.Group("/auth")
-		// auth.Use(middleware.JWTAuth(cfg.JWT)) // 应用JWT认证中间件
+		// auth.Use(middleware.JWTAuth(cfg.JWT, sessionService)) // 应用JWT认证中间件
 		{
-			auth.GET("/profile", h.GetProfile)    // 获取当前用户个人资料
-			auth.PUT("/profile", h.UpdateProfile) // 更新当前用户个人资料
-			auth.POST("/logout", h.Logout)        // 用户登出接口
+			auth.GET("/profile", jwtAuth, h.GetProfile)    // 获取当前用户个人资料，meta中返回token有效期
+			auth.PUT("/profile", jwtAuth, h.UpdateProfile) // 更新当前用户个人资料
+			auth.POST("/logout", h.Logout)                 // 用户登出接口
+
+			auth.GET("/sessions", jwtAuth, h.GetSessions)          // 获取当前用户已登录的设备会话列表
+			auth.DELETE("/sessions/:id", jwtAuth, h.RevokeSession) // 远程吊销指定设备会话
+			auth.DELETE("/sessions", jwtAuth, h.RevokeAllSessions) // 吊销全部会话（登出所有设备）
+
+			auth.POST("/api-tokens", jwtAuth, h.CreateAPIToken)       // 创建API令牌（供内部服务调用gRPC接口）
+			auth.GET("/api-tokens", jwtAuth, h.GetAPITokens)          // 获取当前用户的API令牌列表
+			auth.DELETE("/api-tokens/:id", jwtAuth, h.RevokeAPIToken) // 吊销指定API令牌
+
+			auth.POST("/identities", jwtAuth, h.LinkExternalIdentity)         // 将外部身份关联到当前用户
+			auth.GET("/identities", jwtAuth, h.GetExternalIdentities)         // 获取当前用户关联的外部身份
+			auth.DELETE("/identities/:id", jwtAuth, h.UnlinkExternalIdentity) // 解除指定外部身份关联
+
+			// 限定包范围的token（如CI发布用），只能操作所列的包，会话列表中返回其范围
+			auth.POST("/tokens", jwtAuth, h.CreateScopedToken) // 创建限定包范围的token，当前用户须为每个包的所有者
+
+			auth.GET("/watching", jwtAuth, h.GetWatchedPackages) // 获取当前用户关注的包及各包的最新版本
+
+			// 对当前用户启用的功能开关（全局、角色、用户或灰度比例命中），开关每分钟从数据库刷新
+			auth.GET("/features", jwtAuth, h.GetMyFeatures)
+
+			// 两步验证（TOTP）：设置和启用受totp功能开关控制；关闭不受开关限制，已启用的用户始终可以关闭
+			// 启用后登录须在totp_code中提供验证码，与开关是否启用无关
+			auth.POST("/totp/setup", jwtAuth, middleware.RequireFeature(feature.TOTP), h.SetupTOTP)   // 生成密钥和otpauth URI
+			auth.POST("/totp/enable", jwtAuth, middleware.RequireFeature(feature.TOTP), h.EnableTOTP) // 用验证码确认密钥并启用
+			auth.DELETE("/totp", jwtAuth, h.DisableTOTP)                                              // 用验证码确认并关闭
 		}
 
+		// GraphQL只读查询（包和版本），受graphql功能开关控制，未启用的用户得到404
+		v1.POST("/graphql", jwtAuth, middleware.RequireFeature(feature.GraphQL), h.GraphQL)
+
 		// 管理员路由 - 只有管理员角色才能访问的接口
 		admin := v1.Group("/admin")
-		// admin.Use(middleware.JWTAuth(cfg.JWT))  // 应用JWT认证中间件
-		// admin.Use(middleware.RoleAuth("admin")) // 应用角色权限中间件，限制只有admin角色可访问
+		// admin.Use(middleware.JWTAuth(cfg.JWT, sessionService))  // 应用JWT认证中间件
+		// 每个路由通过RequirePermission声明所需的操作，角色与操作的对应关系见authz.DefaultRules
 		{
-			admin.GET("/users", h.GetUsers)          // 获取用户列表 - 支持分页和筛选
-			admin.GET("/users/:id", h.GetUser)       // 根据ID获取指定用户详细信息
-			admin.PUT("/users/:id", h.UpdateUser)    // 更新指定用户信息
-			admin.DELETE("/users/:id", h.DeleteUser) // 删除指定用户（软删除）
+			admin.GET("/users", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserList), h.GetUsers)            // 获取用户列表 - 支持分页和筛选
+			admin.GET("/users/:id", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserRead), h.GetUser)         // 根据ID获取指定用户详细信息
+			admin.PUT("/users/:id", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserUpdate), h.UpdateUser)    // 更新指定用户信息（admin只能管理普通用户）
+			admin.DELETE("/users/:id", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserDelete), h.DeleteUser) // 删除指定用户（软删除，admin只能删除普通用户）
+
+			// 包分类表管理，删除分类时同时移除包与其的关联
+			admin.POST("/categories", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionCategoryManage), h.CreateCategory)       // 创建分类
+			admin.PUT("/categories/:id", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionCategoryManage), h.UpdateCategory)    // 修改分类名称和描述
+			admin.DELETE("/categories/:id", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionCategoryManage), h.DeleteCategory) // 删除分类
+
+			// 用户暂停（附原因和可选时长，到期由会话清理任务自动解除），暂停和解除记录到审计日志
+			admin.POST("/users/:id/suspensions", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserUpdate), h.SuspendUser)          // 暂停用户并吊销其所有会话
+			admin.DELETE("/users/:id/suspensions", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserUpdate), h.LiftUserSuspension) // 手动解除暂停
+			admin.GET("/users/:id/suspensions", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserRead), h.GetUserSuspensions)      // 暂停历史
+
+			// 信任等级（默认仅super角色），变更记录到审计日志
+			admin.PUT("/users/:id/trust-level", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionTrustManage), h.SetUserTrustLevel)            // 设置用户信任等级，其包自动继承
+			admin.PUT("/packages/:package/trust-level", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionTrustManage), h.SetPackageTrustLevel) // 设置包信任等级
+
+			admin.GET("/debug/info", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionAdminDiagnostics), h.GetDebugInfo) // 构建信息和运行时诊断数据
+
+			// 依赖可用性历史，由后台任务按health.sample_interval检查数据库和存储
+			admin.GET("/health/history", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionAdminDiagnostics), h.GetHealthHistory) // 可用率和停机区间，支持window参数
+
+			// 系统信息（只读，默认仅super角色），配置中的密码和密钥已脱敏
+			systemAuth := middleware.RequirePermission(h.Policy, authz.ActionAdminSystem)
+			admin.GET("/system/config", jwtAuth, systemAuth, h.GetSystemConfig)     // 当前运行配置
+			admin.GET("/system/info", jwtAuth, systemAuth, h.GetSystemInfo)         // Go版本、启动时间、内存、日志级别等
+			admin.GET("/system/features", jwtAuth, systemAuth, h.GetSystemFeatures) // 功能开关状态
+
+			admin.GET("/storage/tier-summary", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionStorageRead), h.GetStorageTierSummary)  // 获取包文件在各存储层级的分布
+			admin.GET("/storage/stats", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionStorageRead), h.GetStorageStats)               // 获取存储分层分布和上传限速配置
+			admin.GET("/deprecations/usage", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionAdminDiagnostics), h.GetDeprecationUsage) // 获取弃用路由的调用统计（默认最近30天）
+
+			// 下载镜像的熔断状态，由后台任务按minio.mirror_health.check_interval检查
+			admin.GET("/mirrors/status", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionStorageRead), h.GetMirrorStatus) // 各镜像的地域、优先级和熔断状态
+
+			// 搜索防护：按IP查询是否被标记为爬虫流量及标记原因
+			admin.GET("/search-protection/ips/:ip", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionAdminDiagnostics), h.LookupSearchProtectionIP)
+
+			admin.GET("/licenses/unrecognized", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionLicenseRead), h.GetUnrecognizedLicenses) // 无法自动规范化的许可证值
+
+			// 修正版本文件的对象键（复制、更新记录、删除旧对象），执行时记录审计日志
+			admin.POST("/packages/:package/:version/rename-object", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionPackageModerate), h.RenameVersionObject) // 支持dry_run预览
+
+			// 版本锁定 - 锁定的版本不能删除，发布超过lock_after_minutes的版本由后台任务自动锁定，解锁记录审计日志
+			admin.POST("/packages/:package/:version/lock", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionPackageModerate), h.LockPackageVersion)   // 立即锁定
+			admin.DELETE("/packages/:package/:version/lock", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionVersionUnlock), h.UnlockPackageVersion) // 解除锁定，默认仅super角色
+
+			// 病毒扫描 - 发现病毒的版本被隔离（下载返回451），重新扫描和解除隔离记录审计日志
+			admin.POST("/packages/:package/:version/rescan", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionPackageModerate), h.RescanPackageVersion)      // 立即重新扫描，不会解除已有的隔离
+			admin.POST("/packages/:package/:version/release", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionVersionRelease), h.ReleaseQuarantinedVersion) // 解除隔离，需要提供reason
+
+			// 包的下载流量统计（供计费使用）和每月流量上限，超出上限后下载返回429
+			admin.GET("/packages/:package/bandwidth", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionBillingRead), h.GetPackageBandwidth)              // 支持from/to参数，默认本月
+			admin.PUT("/packages/:package/bandwidth-limit", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionBillingManage), h.SetPackageBandwidthLimit) // null表示不限制
+
+			// 按源数据重建包的下载计数、存储状态、缓存和搜索索引，健康的包不产生修正
+			admin.POST("/packages/:package/rebuild", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionPackageModerate), h.RebuildPackage)
+
+			// BI数据导出 - 流式输出NDJSON/CSV，支持cursor续传，同一时间只允许一个导出
+			admin.GET("/export/packages", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionDataExport), h.ExportPackages)   // 导出包数据
+			admin.GET("/export/versions", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionDataExport), h.ExportVersions)   // 导出版本数据
+			admin.GET("/export/downloads", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionDataExport), h.ExportDownloads) // 导出下载记录，支持since参数
+
+			// 与上面的导出相同，供按资源路径访问的调用方使用
+			admin.GET("/downloads", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionDataExport), h.ExportDownloads)      // 流式导出下载记录
+			admin.GET("/packages/export", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionDataExport), h.ExportPackages) // 流式导出包数据
+
+			// 迁移导出 - 每行一个包的完整元数据和全部版本（含对象键和文件哈希），按200个包分批读取，导出记录到审计日志
+			admin.GET("/export/migration/packages", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionPackageExport), h.ExportPackagesForMigration) // 支持owner、updated_since、include_download_urls
+
+			// 功能开关管理 - 修改立即在当前实例生效，其他实例在一分钟内刷新，修改记录审计日志
+			admin.GET("/features", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionFeatureManage), h.ListFeatureFlags)           // 列出全部功能开关
+			admin.POST("/features", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionFeatureManage), h.CreateFeatureFlag)         // 创建功能开关
+			admin.PUT("/features/:name", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionFeatureManage), h.UpdateFeatureFlag)    // 修改功能开关，未提供的字段保持不变
+			admin.DELETE("/features/:name", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionFeatureManage), h.DeleteFeatureFlag) // 删除功能开关
+
+			// 用户列表CSV导出（合规报告）- 默认仅super角色，每人每小时一次，记录审计日志
+			admin.GET("/export/users", jwtAuth, middleware.RequirePermission(h.Policy, authz.ActionUserExport), middleware.UserRateLimit(1, time.Hour), h.ExportUsersCSV)
 		}
 
 		// 用户路由 - 公开的用户信息查询接口
 		users := v1.Group("/users")
-		// users.Use(middleware.OptionalJWTAuth(cfg.JWT)) // 可选认证中间件，有token时解析用户信息，无token时也允许访问
+		// users.Use(middleware.OptionalJWTAuth(cfg.JWT, sessionService)) // 可选认证中间件，有token时解析用户信息，无token时也允许访问
+		{
+			users.GET("/", h.GetPublicUsers)                                            // 获取公开用户列表 - 只返回公开信息
+			users.GET("/:id", h.GetPublicUser)                                          // 根据ID获取指定用户的公开信息
+			users.GET("/:id/packages", optionalAuth, h.PackageHandler.ListUserPackages) // 获取指定用户（ID或用户名）发布的包，本人可见私有包
+		}
+
+		// 包生态统计 - 许可证、关键字、版本数、文件大小和每月新包分布（公开，缓存1小时）
+		v1.GET("/stats/ecosystem", h.PackageHandler.GetEcosystemDistribution)
+
+		// 包排行榜 - 由后台任务每小时计算，请求只读取缓存的结果
+		leaderboard := v1.Group("/leaderboard")
 		{
-			users.GET("/", h.GetPublicUsers)   // 获取公开用户列表 - 只返回公开信息
-			users.GET("/:id", h.GetPublicUser) // 根据ID获取指定用户的公开信息
+			leaderboard.GET("", h.GetLeaderboard)                // 排行榜，period为weekly、monthly或alltime
+			leaderboard.GET("/history", h.GetLeaderboardHistory) // 指定包的排名走势
+			leaderboard.GET("/:package/rank", h.GetPackageRank)  // 指定包在各周期的当前排名
 		}
 
+		// 包分类表 - 由管理员维护，包创建和更新时通过category_ids选择，搜索时按category过滤
+		v1.GET("/categories", h.ListCategories)
+
 		// 包管理路由 - 包的创建、更新、删除等操作
 		packages := v1.Group("/packages")
 		{
+			// 包搜索：匿名请求按IP使用更严格的限制，爬虫User-Agent使用缓存的结果，见search_protection配置
+			packages.GET("/", optionalAuth, middleware.SearchProtection(h.BotDetector), h.PackageHandler.SearchPackages) // 搜索包列表 - 支持关键词、作者等筛选
+
 			// 公开的包相关接口（不需要认证）
-			packages.GET("/", h.PackageHandler.SearchPackages)                      // 搜索包列表 - 支持关键词、作者等筛选
-			packages.GET("/stats", h.PackageHandler.GetPackageStats)                // 获取包统计信息 - 总数、下载量等
-			packages.GET("/:package", h.PackageHandler.GetPackage)                  // 获取指定包的详细信息
-			packages.GET("/:package/versions", h.PackageHandler.GetPackageVersions) // 获取指定包的所有版本列表
+			packages.GET("/stats", h.PackageHandler.GetPackageStats)                              // 获取包统计信息 - 总数、下载量等
+			packages.GET("/:package", optionalAuth, h.PackageHandler.GetPackage)                  // 获取指定包的详细信息，私有包只有所有者和协作者可以查看
+			packages.GET("/:package/versions", optionalAuth, h.PackageHandler.GetPackageVersions) // 获取指定包的所有版本列表，私有包只有所有者和协作者可以查看
+			packages.GET("/:package/downloads", jwtAuth, h.PackageHandler.GetDownloadRecords)     // 获取包的下载记录（仅所有者，支持CSV/YAML）
+			packages.GET("/:package/analytics", jwtAuth, h.PackageHandler.GetPackageAnalytics)    // 获取包的下载分布（国家、客户端，仅所有者）
+
+			// 包名可用性检查，可被用于枚举包名，按IP限流
+			nameCheckLimit := middleware.UserRateLimit(60, time.Minute)
+			packages.GET("/:package/availability", nameCheckLimit, h.PackageHandler.CheckPackageNameAvailability) // 检查包名是否可用
+
+			// 基于共同下载的包推荐，无数据时回退为同一作者的其他包
+			packages.GET("/:package/recommendations", h.PackageHandler.GetPackageRecommendations) // 获取推荐包
+
+			// 包文档页面，携带token时解析用户，私有包只有所有者和协作者可以查看
+			packages.GET("/:package/wiki", optionalAuth, h.ListWikiPages)                        // 列出文档页面（不含内容）
+			packages.GET("/:package/wiki/:slug", optionalAuth, h.GetWikiPage)                    // 获取文档页面
+			packages.GET("/:package/wiki/:slug/revisions", optionalAuth, h.GetWikiPageRevisions) // 修改历史，最新的在前
+
+			// 调用方对包的有效权限及原因，供界面决定显示哪些操作，与实际执行时使用相同的规则
+			packages.GET("/:package/permissions", optionalAuth, h.GetPackagePermissions)
+
+			// 包图标，带icon_url中的v参数时长期缓存；私有包只有所有者和协作者可以查看
+			packages.GET("/:package/icon", optionalAuth, h.PackageHandler.GetPackageIcon)
+
+			// 满足版本约束（如^1.2.0、~1.2.0、>=1.0.0 <2.0.0）的最高版本，已弃用的版本不参与匹配，没有满足的版本时返回404
+			packages.GET("/:package/satisfy", optionalAuth, h.PackageHandler.SatisfyVersion)
 
 			// 包版本下载接口（支持匿名下载公开包）
-			packages.GET("/:package/:version/download", h.PackageHandler.DownloadPackageVersion) // 直接下载包文件
-			packages.GET("/:package/:version/download-url", h.PackageHandler.GetDownloadURL)     // 获取下载链接
+			packages.GET("/:package/:version/download", h.PackageHandler.DownloadPackageVersion)           // 直接下载包文件
+			packages.GET("/:package/:version/download-url", optionalAuth, h.PackageHandler.GetDownloadURL) // 获取下载链接，私有包需要登录
+
+			// 重新签发下载链接（权限检查与获取时相同），响应中的expires_at为链接过期时间
+			packages.POST("/:package/:version/download-url/refresh", optionalAuth, h.PackageHandler.RefreshDownloadURL)
+
+			// 包版本文件内容（zip/tar/tar.gz），从存储流式读取，page_size最大1000
+			packages.GET("/:package/:version/contents", h.PackageHandler.ListArchiveContents) // 分页列出版本文件中的条目
+
+			// 需要认证的包管理接口（REST风格路径）
+			packages.POST("/", jwtAuth, h.PackageHandler.CreatePackage)                                    // 创建新包
+			packages.PUT("/:package", jwtAuth, h.PackageHandler.UpdatePackage)                             // 更新包信息
+			packages.DELETE("/:package", jwtAuth, h.PackageHandler.DeletePackage)                          // 删除包
+			packages.POST("/:package/rename", jwtAuth, h.PackageHandler.RenamePackage)                     // 重命名包，旧名称保留为别名
+			packages.POST("/:package/archive", jwtAuth, h.ArchivePackage)                                  // 归档包（只读，仍可下载和搜索）
+			packages.POST("/:package/unarchive", jwtAuth, h.UnarchivePackage)                              // 取消归档
+			packages.POST("/:package/versions", jwtAuth, h.PackageHandler.UploadPackageVersion)            // 上传新版本
+			packages.DELETE("/:package/:version", jwtAuth, h.PackageHandler.DeletePackageVersion)          // 删除指定版本
+			packages.POST("/:package/versions/prune", jwtAuth, h.PackageHandler.PruneVersions)             // 清理旧版本（保留最近版本和置顶版本）
+			packages.POST("/:package/deprecate-bulk", jwtAuth, h.PackageHandler.BulkDeprecateVersions)     // 批量弃用版本（最多500个）
+			packages.POST("/:package/undeprecate-bulk", jwtAuth, h.PackageHandler.BulkUndeprecateVersions) // 批量取消版本弃用
+			packages.PUT("/:package/:version/pin", jwtAuth, h.PackageHandler.PinVersion)                   // 置顶版本，使其不被清理
+			packages.DELETE("/:package/:version/pin", jwtAuth, h.PackageHandler.UnpinVersion)              // 取消版本置顶
+
+			// 关注包，发布新版本时通知关注者（发布者本人除外），通知由发件箱在后台分批发送
+			packages.PUT("/:package/watch", jwtAuth, h.WatchPackage)      // 关注包，重复关注不报错
+			packages.DELETE("/:package/watch", jwtAuth, h.UnwatchPackage) // 取消关注，未关注时不报错
 
-			// 需要认证的包管理接口
+			// 包协作者：maintainer可以发布版本和修改元数据，reader可以查看私有包；仅所有者和管理员可以添加、修改和移除
+			packages.GET("/:package/collaborators", jwtAuth, h.ListCollaborators)
+			packages.PUT("/:package/collaborators/:username", jwtAuth, h.SetCollaborator)
+			packages.DELETE("/:package/collaborators/:username", jwtAuth, h.RemoveCollaborator)
+
+			// 版本的病毒扫描状态（scanning、active、quarantined）和扫描记录，仅包所有者和管理员可以查看
+			packages.GET("/:package/:version/scan", jwtAuth, h.GetVersionScanReport)
+
+			// 修改版本元数据，携带if_version时使用乐观锁，版本号不一致返回409
+			packages.PATCH("/:package/:version", jwtAuth, h.PackageHandler.UpdatePackageVersion) // 修改版本描述和更新日志
+
+			// 需要认证的包管理接口（旧路径，已被上面的REST风格路径替代，保留至下线日期）
 			packagesAuth := packages.Group("/update")
-			// packagesAuth.Use(middleware.JWTAuth(cfg.JWT))
+			// packagesAuth.Use(middleware.JWTAuth(cfg.JWT, sessionService))
+			deprecations.Deprecate(packagesAuth,
+				middleware.DeprecatedRoute{Method: http.MethodPost, Path: "/", Sunset: packagesUpdateSunset, Replacement: "/api/v1/packages/"},
+				middleware.DeprecatedRoute{Method: http.MethodPut, Path: "/:package", Sunset: packagesUpdateSunset, Replacement: "/api/v1/packages/:package"},
+				middleware.DeprecatedRoute{Method: http.MethodDelete, Path: "/:package", Sunset: packagesUpdateSunset, Replacement: "/api/v1/packages/:package"},
+				middleware.DeprecatedRoute{Method: http.MethodPost, Path: "/:package/versions", Sunset: packagesUpdateSunset, Replacement: "/api/v1/packages/:package/versions"},
+				middleware.DeprecatedRoute{Method: http.MethodDelete, Path: "/:package/:version", Sunset: packagesUpdateSunset, Replacement: "/api/v1/packages/:package/:version"},
+			)
 			{
 				packagesAuth.POST("/", h.PackageHandler.CreatePackage)                           // 创建新包
 				packagesAuth.PUT("/:package", h.PackageHandler.UpdatePackage)                    // 更新包信息
@@ -133,6 +377,39 @@ func setupRoutes(r *gin.Engine, cfg *config.Config, db *gorm.DB, minioClient *mi
 				packagesAuth.POST("/:package/versions", h.PackageHandler.UploadPackageVersion)   // 上传新版本
 				packagesAuth.DELETE("/:package/:version", h.PackageHandler.DeletePackageVersion) // 删除指定版本
 			}
+
+			// 预签名上传：客户端直接上传到存储，回调后由服务端校验大小、计算SHA256并创建版本，大文件后台处理并返回202和job_id
+			packagesAuth.POST("/:package/versions/uploads", jwtAuth, h.PackageHandler.CreateUploadSession)        // 创建上传会话，返回upload_url
+			packagesAuth.POST("/:package/:version/upload-complete", jwtAuth, h.PackageHandler.CompleteUpload)     // 上传完成回调
+			packagesAuth.GET("/:package/:version/uploads/:upload_id", jwtAuth, h.PackageHandler.GetUploadSession) // 查询处理状态（job_id即upload_id）
+
+			// 包所有者查看包的下载流量，支持from/to参数，默认本月
+			packagesAuth.GET("/:package/bandwidth", jwtAuth, h.GetOwnedPackageBandwidth)
+
+			// 包所有者按条件批量清理版本（预发布、发布时间、版本号模式），默认只预览，confirm=true时删除，单次最多500个
+			packagesAuth.POST("/:package/versions/cleanup", jwtAuth, h.CleanupVersions)
+
+			// 包所有者上传或替换包图标（png、webp、svg），svg保存前移除脚本和外部引用
+			packagesAuth.POST("/:package/icon", jwtAuth, h.PackageHandler.UploadPackageIcon)
+
+			// 包所有者维护文档页面，内容保存前清理可能导致XSS的HTML和链接
+			packagesAuth.POST("/:package/wiki", jwtAuth, h.CreateWikiPage)         // 创建文档页面
+			packagesAuth.PUT("/:package/wiki/:slug", jwtAuth, h.UpdateWikiPage)    // 修改文档页面，支持if_revision
+			packagesAuth.DELETE("/:package/wiki/:slug", jwtAuth, h.DeleteWikiPage) // 删除文档页面及其修改历史
+		}
+	}
+
+	// API版本2路由组 - 灰度中，受v2_api功能开关控制，未启用的用户得到404；弃用的v1接口以此处的路径作为替代
+	v2 := r.Group("/api/v2", jwtAuth, middleware.RequireFeature(feature.APIV2))
+	{
+		v2Packages := v2.Group("/packages")
+		{
+			v2Packages.GET("/", middleware.SearchProtection(h.BotDetector), h.PackageHandler.SearchPackages) // 搜索包列表
+			v2Packages.GET("/:package", h.PackageHandler.GetPackage)                                         // 获取指定包的详细信息
+			v2Packages.GET("/:package/versions", h.PackageHandler.GetPackageVersions)                        // 获取指定包的所有版本列表
+			v2Packages.GET("/:package/satisfy", h.PackageHandler.SatisfyVersion)                             // 满足版本约束的最高版本
+			v2Packages.GET("/:package/:version/download", h.PackageHandler.DownloadPackageVersion)           // 直接下载包文件
+			v2Packages.GET("/:package/:version/download-url", h.PackageHandler.GetDownloadURL)               // 获取下载链接
 		}
 	}
 
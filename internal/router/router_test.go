@@ -0,0 +1,192 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/feature"
+	"webservice/internal/httpclient"
+	"webservice/internal/middleware"
+	"webservice/internal/migration"
+	"webservice/internal/models"
+	"webservice/internal/service"
+	"webservice/internal/testutil"
+	"webservice/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// testRouter 使用SQLite、不连接存储的完整路由，用于验证路由上挂载的认证中间件
+type testRouter struct {
+	t     *testing.T
+	cfg   *config.Config
+	db    *gorm.DB
+	r     *gin.Engine
+	flags *feature.Flags
+}
+
+// newTestRouter 创建测试路由，modify可在创建路由前修改配置
+func newTestRouter(t *testing.T, modify ...func(cfg *config.Config)) *testRouter {
+	t.Helper()
+	db := testutil.NewDB(t)
+	if err := migration.AutoMigrate(db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Server.Mode = gin.TestMode
+	cfg.JWT = config.JWTConfig{Secret: "test-secret", ExpireTime: time.Hour}
+	cfg.Password = config.PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4}
+	for _, m := range modify {
+		m(cfg)
+	}
+
+	httpClients, err := httpclient.NewFactory(cfg.Outbound, nil)
+	if err != nil {
+		t.Fatalf("failed to create http client factory: %v", err)
+	}
+	healthHistory := service.NewHealthHistoryService(db, nil, cfg.Health)
+	flags := feature.New(db)
+	r := Setup(cfg, db, nil, httpClients, healthHistory, service.NewLeaderboardService(db, nil), flags)
+	return &testRouter{t: t, cfg: cfg, db: db, r: r, flags: flags}
+}
+
+// enableFeature 为指定用户启用功能开关并重新加载
+func (tr *testRouter) enableFeature(name string, userIDs ...uint) {
+	tr.t.Helper()
+	flag := &models.FeatureFlag{Name: name, EnabledForUserIDs: userIDs}
+	if err := tr.db.Create(flag).Error; err != nil {
+		tr.t.Fatalf("failed to create feature flag: %v", err)
+	}
+	if err := tr.flags.Load(context.Background()); err != nil {
+		tr.t.Fatalf("failed to load feature flags: %v", err)
+	}
+}
+
+// createUser 创建用户并返回其登录token
+func (tr *testRouter) createUser(username, role string) (*models.User, string) {
+	tr.t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", Password: "x", Role: role, Status: models.UserStatusActive}
+	if err := tr.db.Create(user).Error; err != nil {
+		tr.t.Fatalf("failed to create user: %v", err)
+	}
+	token, claims, err := middleware.GenerateToken(user.ID, user.Username, user.Role, tr.cfg.JWT)
+	if err != nil {
+		tr.t.Fatalf("failed to generate token: %v", err)
+	}
+	if _, err := service.NewSessionService(tr.db).CreateSession(user.ID, claims.ID, claims.ExpiresAt.Time, "test", "127.0.0.1"); err != nil {
+		tr.t.Fatalf("failed to create session: %v", err)
+	}
+	return user, token
+}
+
+// do 发送请求，token为空时匿名访问
+func (tr *testRouter) do(method, path, token, body string) *httptest.ResponseRecorder {
+	tr.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	tr.r.ServeHTTP(w, req)
+	return w
+}
+
+// decodeData 解析统一响应格式中的data字段
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %s: %v", w.Body.String(), err)
+	}
+	if err := json.Unmarshal(resp.Data, v); err != nil {
+		t.Fatalf("failed to decode data %s: %v", resp.Data, err)
+	}
+}
+
+// createPackage 创建属于owner的包
+func (tr *testRouter) createPackage(name string, owner *models.User, private bool) *models.Package {
+	tr.t.Helper()
+	pkg := &models.Package{Name: name, OwnerID: owner.ID, IsPrivate: private}
+	if err := tr.db.Create(pkg).Error; err != nil {
+		tr.t.Fatalf("failed to create package: %v", err)
+	}
+	// default:false的字段为零值时会被GORM忽略，单独更新
+	if private {
+		if err := tr.db.Model(pkg).Update("is_private", true).Error; err != nil {
+			tr.t.Fatalf("failed to mark package private: %v", err)
+		}
+	}
+	return pkg
+}
+
+func TestListUserPackagesVisibility(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, ownerToken := tr.createUser("alice", models.RoleUser)
+	_, strangerToken := tr.createUser("bob", models.RoleUser)
+	tr.createPackage("public-pkg", owner, false)
+	tr.createPackage("private-pkg", owner, true)
+
+	tests := []struct {
+		name  string
+		token string
+		want  []string
+	}{
+		{name: "anonymous", token: "", want: []string{"public-pkg"}},
+		{name: "stranger", token: strangerToken, want: []string{"public-pkg"}},
+		{name: "owner", token: ownerToken, want: []string{"private-pkg", "public-pkg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tr.do(http.MethodGet, "/api/v1/users/alice/packages", tt.token, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var data models.PackageListResponse
+			decodeData(t, w, &data)
+			var got []string
+			for _, pkg := range data.Packages {
+				got = append(got, pkg.Name)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("packages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionEndpointWithoutBuildInfo(t *testing.T) {
+	tr := newTestRouter(t)
+
+	// 测试二进制未通过-ldflags注入构建信息
+	w := tr.do(http.MethodGet, "/version", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var info version.Info
+	decodeData(t, w, &info)
+	if info.Version != "dev" || info.Commit != "unknown" || info.BuildDate != "unknown" {
+		t.Errorf("version = %+v, want dev/unknown/unknown", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("go_version = %q, want %q", info.GoVersion, runtime.Version())
+	}
+	if got := w.Header().Get("Server"); got != "webservice/dev" {
+		t.Errorf("Server = %q, want webservice/dev", got)
+	}
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+func TestTimestampsUTCUnderNonUTCTimezone(t *testing.T) {
+	testutil.SetLocalTimezone(t, time.FixedZone("UTC+8", 8*60*60))
+	tr := newTestRouter(t)
+	_, token := tr.createUser("alice", models.RoleUser)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+
+	body := `{"name":"clock","homepage":"https://example.com","repository":"https://example.com/clock.git"}`
+	if w := tr.do(http.MethodPost, "/api/v1/packages/", token, body); w.Code != http.StatusOK {
+		t.Fatalf("create status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	// 接口返回的时间为RFC3339格式的UTC时间
+	w := tr.do(http.MethodGet, "/api/v1/packages/clock", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body %s", w.Code, w.Body.String())
+	}
+	var pkg map[string]interface{}
+	decodeData(t, w, &pkg)
+	for _, field := range []string{"created_at", "updated_at"} {
+		value, _ := pkg[field].(string)
+		if _, err := time.Parse(time.RFC3339, value); err != nil || !strings.HasSuffix(value, "Z") {
+			t.Errorf("%s = %q, want an RFC3339 UTC timestamp", field, value)
+		}
+	}
+
+	// 带时区偏移的since按同一时刻过滤
+	version := &models.PackageVersion{PackageID: uint(pkg["id"].(float64)), Version: "1.0.0", FileHash: "x"}
+	if err := tr.db.Create(version).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []string{"2026-01-01T23:00:00Z", "2026-01-02T01:00:00Z"} {
+		downloadTime, _ := time.Parse(time.RFC3339, at)
+		if err := tr.db.Create(&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1", DownloadTime: downloadTime}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		since string
+		rows  int
+	}{
+		{"2026-01-02", 1},                // 只有日期时按UTC零点
+		{"2026-01-02T08:00:00+08:00", 1}, // 即2026-01-02T00:00:00Z
+		{"2026-01-02T06:00:00+08:00", 2}, // 即2026-01-01T22:00:00Z
+		{"2026-01-01T20:30:00-05:00", 0}, // 即2026-01-02T01:30:00Z
+	}
+	for _, tt := range tests {
+		w := tr.do(http.MethodGet, "/api/v1/admin/export/downloads?since="+url.QueryEscape(tt.since), adminToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("export since %s status = %d, body %s", tt.since, w.Code, w.Body.String())
+		}
+		rows := 0
+		scanner := bufio.NewScanner(strings.NewReader(w.Body.String()))
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Fatal(err)
+			}
+			if row["_trailer"] == true {
+				continue
+			}
+			rows++
+		}
+		if rows != tt.rows {
+			t.Errorf("export since %s = %d rows, want %d", tt.since, rows, tt.rows)
+		}
+	}
+}
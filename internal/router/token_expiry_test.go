@@ -0,0 +1,69 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestTokenExpiryHeaders(t *testing.T) {
+	tests := []struct {
+		name          string
+		refreshWindow time.Duration
+		recommended   string
+	}{
+		{name: "outside refresh window", refreshWindow: 30 * time.Minute, recommended: ""},
+		{name: "inside refresh window", refreshWindow: 2 * time.Hour, recommended: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestRouter(t, func(cfg *config.Config) { cfg.JWT.RefreshWindow = tt.refreshWindow })
+			_, token := tr.createUser("alice", models.RoleUser)
+
+			w := tr.do(http.MethodGet, "/api/v1/auth/profile", token, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			expiresIn, err := strconv.Atoi(w.Header().Get("X-Token-Expires-In"))
+			if err != nil || expiresIn <= 0 || expiresIn > int(time.Hour.Seconds()) {
+				t.Errorf("X-Token-Expires-In = %q, want seconds within the 1h expiry", w.Header().Get("X-Token-Expires-In"))
+			}
+			if got := w.Header().Get("X-Token-Refresh-Recommended"); got != tt.recommended {
+				t.Errorf("X-Token-Refresh-Recommended = %q, want %q", got, tt.recommended)
+			}
+
+			var resp struct {
+				Meta struct {
+					TokenExpiresIn int    `json:"token_expires_in"`
+					TokenExpiresAt string `json:"token_expires_at"`
+				} `json:"meta"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response %s: %v", w.Body.String(), err)
+			}
+			if resp.Meta.TokenExpiresIn <= 0 || resp.Meta.TokenExpiresIn > int(time.Hour.Seconds()) {
+				t.Errorf("meta.token_expires_in = %d", resp.Meta.TokenExpiresIn)
+			}
+			if expiresAt, err := time.Parse(time.RFC3339, resp.Meta.TokenExpiresAt); err != nil || time.Until(expiresAt) > time.Hour {
+				t.Errorf("meta.token_expires_at = %q", resp.Meta.TokenExpiresAt)
+			}
+		})
+	}
+}
+
+func TestTokenExpiryHeadersAbsentWithoutToken(t *testing.T) {
+	tr := newTestRouter(t)
+	w := tr.do(http.MethodGet, "/api/v1/auth/profile", "", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if got := w.Header().Get("X-Token-Expires-In"); got != "" {
+		t.Errorf("X-Token-Expires-In = %q on anonymous request", got)
+	}
+}
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"webservice/internal/middleware"
+	"webservice/internal/models"
+)
+
+// createScopedToken 用登录token创建限定包范围的token
+func (tr *testRouter) createScopedToken(loginToken string, packages ...string) string {
+	tr.t.Helper()
+	body, _ := json.Marshal(models.CreateScopedTokenRequest{Packages: packages})
+	w := tr.do(http.MethodPost, "/api/v1/auth/tokens", loginToken, string(body))
+	if w.Code != http.StatusOK {
+		tr.t.Fatalf("create scoped token: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var data struct {
+		Token string `json:"token"`
+	}
+	decodeData(tr.t, w, &data)
+	return data.Token
+}
+
+// assertScopeViolation 检查响应为token_scope_violation
+func assertScopeViolation(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	var payload struct {
+		Code int `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response %s: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusForbidden || payload.Code != middleware.CodeTokenScopeViolation {
+		t.Errorf("status = %d, code = %d, want 403 with token_scope_violation; body %s", w.Code, payload.Code, w.Body.String())
+	}
+}
+
+func TestScopedTokenLimitedToItsPackages(t *testing.T) {
+	tr := newTestRouter(t)
+	// 管理员创建的token同样不能访问管理员路由
+	owner, loginToken := tr.createUser("alice", models.RoleAdmin)
+	tr.createPackage("ci-pkg", owner, false)
+	tr.createPackage("other-pkg", owner, false)
+	scoped := tr.createScopedToken(loginToken, "ci-pkg")
+
+	if w := tr.do(http.MethodPut, "/api/v1/packages/ci-pkg/watch", scoped, ""); w.Code != http.StatusOK {
+		t.Errorf("scoped token on its package: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	denied := []struct {
+		method, path, body string
+	}{
+		{http.MethodPut, "/api/v1/packages/other-pkg/watch", ""},
+		{http.MethodDelete, "/api/v1/packages/other-pkg", ""},
+		{http.MethodDelete, "/api/v1/auth/sessions", ""},
+		{http.MethodPost, "/api/v1/auth/identities", `{"provider":"github","subject":"1"}`},
+		{http.MethodPost, "/api/v1/auth/tokens", `{"packages":["ci-pkg"]}`},
+		{http.MethodPut, "/api/v1/admin/packages/ci-pkg/trust-level", `{"trust_level":"verified"}`},
+		{http.MethodGet, "/api/v1/admin/users", ""},
+	}
+	for _, tt := range denied {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			assertScopeViolation(t, tr.do(tt.method, tt.path, scoped, tt.body))
+		})
+	}
+
+	// 登录token不受影响
+	if w := tr.do(http.MethodPut, "/api/v1/packages/other-pkg/watch", loginToken, ""); w.Code != http.StatusOK {
+		t.Errorf("login token: status = %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestScopedTokenHasUserRole(t *testing.T) {
+	tr := newTestRouter(t)
+	owner, loginToken := tr.createUser("alice", models.RoleAdmin)
+	tr.createPackage("ci-pkg", owner, false)
+
+	claims, err := middleware.ParseToken(tr.createScopedToken(loginToken, "ci-pkg"), tr.cfg.JWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != models.RoleUser {
+		t.Errorf("scoped token role = %q, want %q", claims.Role, models.RoleUser)
+	}
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+)
+
+func TestAdminCannotManageSuper(t *testing.T) {
+	tr := newTestRouter(t)
+	_, superToken := tr.createUser("root", models.RoleSuper)
+	_, adminToken := tr.createUser("admin", models.RoleAdmin)
+	otherSuper, _ := tr.createUser("root2", models.RoleSuper)
+	user, _ := tr.createUser("alice", models.RoleUser)
+
+	path := func(u *models.User) string { return fmt.Sprintf("/api/v1/admin/users/%d", u.ID) }
+	update := func(u *models.User, role string) string {
+		return fmt.Sprintf(`{"email":%q,"role":%q,"status":%d}`, u.Email, role, models.UserStatusActive)
+	}
+
+	if w := tr.do(http.MethodDelete, path(otherSuper), adminToken, ""); w.Code != http.StatusForbidden {
+		t.Errorf("admin deleting super: status = %d, want 403", w.Code)
+	}
+	if w := tr.do(http.MethodPut, path(otherSuper), adminToken, update(otherSuper, models.RoleUser)); w.Code != http.StatusForbidden {
+		t.Errorf("admin demoting super: status = %d, want 403", w.Code)
+	}
+	if w := tr.do(http.MethodPut, path(user), adminToken, update(user, models.RoleSuper)); w.Code != http.StatusForbidden {
+		t.Errorf("admin promoting user to super: status = %d, want 403", w.Code)
+	}
+
+	var stored models.User
+	if err := tr.db.First(&stored, otherSuper.ID).Error; err != nil {
+		t.Fatalf("super was removed by admin: %v", err)
+	}
+	if stored.Role != models.RoleSuper {
+		t.Fatalf("super role changed to %s by admin", stored.Role)
+	}
+
+	if w := tr.do(http.MethodDelete, path(user), adminToken, ""); w.Code != http.StatusOK {
+		t.Errorf("admin deleting user: status = %d, want 200, body %s", w.Code, w.Body.String())
+	}
+	if w := tr.do(http.MethodDelete, path(otherSuper), superToken, ""); w.Code != http.StatusOK {
+		t.Errorf("super deleting super: status = %d, want 200, body %s", w.Code, w.Body.String())
+	}
+	if err := tr.db.First(&models.User{}, otherSuper.ID).Error; err == nil {
+		t.Error("super still exists after deletion by super")
+	}
+}
+
+func TestAdminUserListFilters(t *testing.T) {
+	tr := newTestRouter(t)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+	date := func(s string) *time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &d
+	}
+	for _, u := range []struct {
+		name, nickname, email string
+		status                models.UserStatus
+		created, lastLogin    string
+	}{
+		{"alice", "Wonder_Land", "alice@example.com", models.UserStatusActive, "2026-01-10", "2026-03-01"},
+		{"bob", "", "bob@example.com", models.UserStatusInactive, "2026-02-10", "2026-01-15"},
+		{"carol", "", "carol@corp.test", models.UserStatusSuspended, "2026-03-10", "2026-02-01"},
+		{"dave", "100%dave", "dave@example.com", models.UserStatusBanned, "2026-04-10", "2026-05-01"},
+	} {
+		user := &models.User{Username: u.name, Nickname: u.nickname, Email: u.email, Password: "x", Role: models.RoleUser,
+			CreatedAt: *date(u.created), LastLogin: date(u.lastLogin)}
+		if err := tr.db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		// status的数据库默认值会覆盖零值，创建后再设置
+		if err := tr.db.Model(user).Update("status", u.status).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) (string, int64) {
+		t.Helper()
+		w := tr.do(http.MethodGet, "/api/v1/admin/users?"+query, adminToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("list %s: status = %d, body %s", query, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Users []models.PublicUser `json:"users"`
+			Total int64               `json:"total"`
+		}
+		decodeData(t, w, &resp)
+		names := []string{}
+		for _, u := range resp.Users {
+			names = append(names, u.Username)
+		}
+		return fmt.Sprint(names), resp.Total
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "[root dave carol bob alice]"},
+		{"status=inactive", "[bob]"},
+		{"status=0", "[bob]"},
+		{"status=BANNED", "[dave]"},
+		{"status=2", "[carol]"},
+		{"role=admin", "[root]"},
+		{"q=WONDER", "[alice]"},
+		{"q=corp.test", "[carol]"},
+		{"q=%25", "[dave]"},
+		{"q=_", "[alice]"},
+		{"q=+bob+", "[bob]"},
+		{"created_after=2026-02-01&created_before=2026-04-01", "[carol bob]"},
+		{"created_after=2026-03-10T00:00:00Z&role=user", "[dave carol]"},
+		{"created_after=2026-03-10T08:00:00%2B08:00&role=user", "[dave carol]"},
+		{"created_before=2026-02-10", "[alice]"},
+		{"sort=username&order=asc", "[alice bob carol dave root]"},
+		{"sort=username&order=DESC", "[root dave carol bob alice]"},
+		{"sort=last_login&order=asc&role=user", "[bob carol alice dave]"},
+		{"sort=created_at&order=asc&status=active", "[alice root]"},
+		{"role=user&status=banned&q=dave&created_after=2026-04-01", "[dave]"},
+	}
+	for _, tt := range tests {
+		if got, total := list(tt.query); got != tt.want || total != int64(strings.Count(tt.want, " ")+1) {
+			t.Errorf("list %q = %s (total %d), want %s", tt.query, got, total, tt.want)
+		}
+	}
+	// 过滤后的总数和分页
+	if got, total := list("sort=username&order=asc&role=user&page=2&page_size=3"); got != "[dave]" || total != 4 {
+		t.Errorf("second page = %s (total %d), want [dave] of 4", got, total)
+	}
+	if got, total := list("q=nobody"); got != "[]" || total != 0 {
+		t.Errorf("no match = %s (total %d), want none", got, total)
+	}
+}
+
+func TestAdminUserListRejectsMalformedFilters(t *testing.T) {
+	tr := newTestRouter(t)
+	_, adminToken := tr.createUser("root", models.RoleAdmin)
+
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{"status=abc", "invalid status"},
+		{"status=4", "invalid status"},
+		{"status=-1", "invalid status"},
+		{"q=" + strings.Repeat("a", 101), "q must be at most 100 characters"},
+		{"created_after=yesterday", "created_after must be RFC3339 or YYYY-MM-DD"},
+		{"created_after=2026-13-01", "created_after must be RFC3339 or YYYY-MM-DD"},
+		{"created_before=2026-03-01T10:00", "created_before must be RFC3339 or YYYY-MM-DD"},
+		{"created_after=2026-03-01&created_before=2026-03-01", "created_after must be before created_before"},
+		{"created_after=2026-04-01&created_before=2026-03-01", "created_after must be before created_before"},
+		{"sort=password", "sort must be one of created_at, last_login, username"},
+		{"order=up", "order must be asc or desc"},
+	}
+	for _, tt := range tests {
+		w := tr.do(http.MethodGet, "/api/v1/admin/users?"+tt.query, adminToken, "")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("list %q: status = %d, want 400", tt.query, w.Code)
+			continue
+		}
+		if msg := errorMessage(t, w.Body.Bytes()); !strings.Contains(msg, tt.message) {
+			t.Errorf("list %q: message = %q, want %q", tt.query, msg, tt.message)
+		}
+	}
+}
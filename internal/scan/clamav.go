@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamAVChunkSize INSTREAM每个数据块的大小，需小于clamd的StreamMaxLength
+const clamAVChunkSize = 64 * 1024
+
+// ClamAV 通过TCP连接clamd，使用INSTREAM命令发送文件内容扫描
+type ClamAV struct {
+	address string
+	timeout time.Duration
+}
+
+// Name 引擎名称
+func (c *ClamAV) Name() string {
+	return EngineClamAV
+}
+
+// Scan 将内容按块发送给clamd并解析结果
+// 协议：发送zINSTREAM\0，之后每块为4字节大端长度加数据，以长度0结束；响应为"stream: OK"、"stream: <特征名> FOUND"或"<原因> ERROR"
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// 取消时立即中断读写，不等到超时
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("failed to send command to clamd: %w", err)
+	}
+
+	buf := make([]byte, 4+clamAVChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd超过StreamMaxLength时会先返回错误并关闭连接，尽量读取原因
+				if reply, replyErr := readClamAVReply(conn); replyErr == nil {
+					return parseClamAVReply(reply)
+				}
+				return Result{}, fmt.Errorf("failed to stream content to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return Result{}, fmt.Errorf("failed to read content: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Result{}, fmt.Errorf("failed to finish stream to clamd: %w", err)
+	}
+
+	reply, err := readClamAVReply(conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return Result{}, fmt.Errorf("clamd scan timed out: %w", ctx.Err())
+		}
+		return Result{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(reply)
+}
+
+// readClamAVReply 读取以\0结尾的响应
+func readClamAVReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseClamAVReply 解析扫描结果
+func parseClamAVReply(reply string) (Result, error) {
+	// 响应以"stream: "开头，部分版本带有请求序号前缀（如"1: stream: OK"）
+	if i := strings.Index(reply, "stream: "); i >= 0 {
+		reply = reply[i+len("stream: "):]
+	}
+	switch {
+	case reply == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSpace(strings.TrimSuffix(reply, " FOUND"))}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return Result{}, fmt.Errorf("clamd error: %s", strings.TrimSpace(strings.TrimSuffix(reply, " ERROR")))
+	default:
+		return Result{}, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
@@ -0,0 +1,86 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"webservice/internal/config"
+)
+
+// 扫描引擎
+const (
+	EngineNone   = "none"
+	EngineClamAV = "clamav"
+)
+
+// 扫描模式
+const (
+	ModeAsync = "async"
+	ModeSync  = "sync"
+)
+
+const (
+	defaultClamAVAddress = "127.0.0.1:3310"
+	defaultClamAVTimeout = 2 * time.Minute
+)
+
+// Result 一次扫描的结果
+type Result struct {
+	Infected  bool
+	Signature string // 命中的病毒特征名，未发现病毒时为空
+}
+
+// Scanner 病毒扫描引擎，实现需支持并发调用
+// Scan返回error表示扫描未完成（引擎不可用、超时等），不代表文件有问题
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// New 按配置创建扫描引擎，未配置时不扫描
+func New(cfg config.ScanConfig) (Scanner, error) {
+	switch cfg.Mode {
+	case "", ModeAsync, ModeSync:
+	default:
+		return nil, fmt.Errorf("unknown scan mode: %s", cfg.Mode)
+	}
+
+	switch cfg.Engine {
+	case "", EngineNone:
+		return Noop{}, nil
+	case EngineClamAV:
+		if cfg.ClamAV.Address == "" {
+			cfg.ClamAV.Address = defaultClamAVAddress
+		}
+		if cfg.ClamAV.Timeout <= 0 {
+			cfg.ClamAV.Timeout = defaultClamAVTimeout
+		}
+		return &ClamAV{address: cfg.ClamAV.Address, timeout: cfg.ClamAV.Timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown scan engine: %s", cfg.Engine)
+	}
+}
+
+// Noop 不扫描，所有文件视为无病毒
+type Noop struct{}
+
+// Name 引擎名称
+func (Noop) Name() string {
+	return EngineNone
+}
+
+// Scan 直接返回未发现病毒
+func (Noop) Scan(context.Context, io.Reader) (Result, error) {
+	return Result{}, nil
+}
+
+// Enabled 扫描引擎是否会实际扫描文件
+func Enabled(s Scanner) bool {
+	if s == nil {
+		return false
+	}
+	_, noop := s.(Noop)
+	return !noop
+}
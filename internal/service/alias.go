@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+	"webservice/internal/outbox"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// RenamePackage 重命名包（包所有者和maintainer协作者），旧名称记录为别名，之后访问旧名称会解析到该包
+// 存储中的包文件按当前命名方案复制到新名称下，数据库更新成功后再删除旧文件
+func (s *PackageService) RenamePackage(ctx context.Context, packageName, newName string, userID uint) (*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.RenamePackage")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightEditMetadata); err != nil {
+		return nil, err
+	}
+	if newName == pkg.Name {
+		return &pkg, nil
+	}
+
+	if err := s.checkPackageNameAvailable(ctx, newName, pkg.ID); err != nil {
+		return nil, err
+	}
+
+	var versions []models.PackageVersion
+	if err := s.db.WithContext(ctx).Where("package_id = ?", pkg.ID).Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get package versions: %w", err)
+	}
+
+	// 先复制包文件（冷存储中的版本在冷存储bucket内复制），失败时清理已复制的文件，旧文件保持不变
+	newKeys := make(map[uint]string, len(versions))
+	for _, v := range versions {
+		newKey := s.minioClient.ObjectKey(newName, v.Version)
+		if err := objectStore(s.minioClient, &v).CopyObject(ctx, v.MinIOPath, newKey); err != nil {
+			s.deleteObjects(ctx, versions, newKeys)
+			return nil, fmt.Errorf("failed to move package files: %w", err)
+		}
+		newKeys[v.ID] = newKey
+	}
+
+	oldName := pkg.Name
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 改回曾经使用过的名称时，该名称不再是别名
+		if err := tx.Where("name = ? AND package_id = ?", newName, pkg.ID).Delete(&models.PackageAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.PackageAlias{Name: oldName, PackageID: pkg.ID, CreatedBy: userID}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&pkg).Update("name", newName).Error; err != nil {
+			return err
+		}
+		// 复制期间版本可能被移入或移出冷存储，此时新文件不在记录所在的bucket，放弃重命名
+		for _, v := range versions {
+			update := tx.Model(&models.PackageVersion{}).Where("id = ? AND storage_tier = ?", v.ID, v.StorageTier).Update("min_io_path", newKeys[v.ID])
+			if update.Error != nil {
+				return update.Error
+			}
+			if update.RowsAffected == 0 {
+				return errors.New("package version storage tier was changed concurrently")
+			}
+		}
+		return outbox.Append(tx, events.Event{
+			Type:        events.PackageRenamed,
+			PackageID:   pkg.ID,
+			PackageName: newName,
+			UserID:      userID,
+			Payload:     map[string]interface{}{"old_name": oldName},
+		})
+	})
+	if err != nil {
+		s.deleteObjects(ctx, versions, newKeys)
+		if isDuplicateKeyError(err) {
+			return nil, ErrPackageExists
+		}
+		return nil, fmt.Errorf("failed to rename package: %w", err)
+	}
+
+	for _, v := range versions {
+		if err := objectStore(s.minioClient, &v).DeleteObject(ctx, v.MinIOPath); err != nil {
+			fmt.Printf("Warning: failed to delete package file from MinIO: %v\n", err)
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Preload("Owner").First(&pkg, pkg.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load package with associations: %w", err)
+	}
+
+	return &pkg, nil
+}
+
+// deleteObjects 清理重命名失败时已复制的对象
+func (s *PackageService) deleteObjects(ctx context.Context, versions []models.PackageVersion, keys map[uint]string) {
+	for _, v := range versions {
+		if key, ok := keys[v.ID]; ok {
+			objectStore(s.minioClient, &v).DeleteObject(ctx, key)
+		}
+	}
+}
+
+// ResolvePackageAlias 查找别名对应的当前包名，name不是别名时返回空字符串
+func (s *PackageService) ResolvePackageAlias(ctx context.Context, name string) (string, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ResolvePackageAlias")
+	defer span.Finish()
+
+	var alias models.PackageAlias
+	if err := s.db.WithContext(ctx).Where("name = ?", name).First(&alias).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to find package alias: %w", err)
+	}
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Select("name").First(&pkg, alias.PackageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to find aliased package: %w", err)
+	}
+	return pkg.Name, nil
+}
+
+// checkPackageNameAvailable 检查包名格式合法、不在保留名单中、未被其他包使用，也不是其他包的别名
+// packageID为重命名的包自身，其旧名称可以重新使用；同名包删除超过保留期时释放该名称
+func (s *PackageService) checkPackageNameAvailable(ctx context.Context, name string, packageID uint) error {
+	tombstone, err := s.packageNameStatus(ctx, name, packageID)
+	if err != nil {
+		return err
+	}
+	if tombstone != nil {
+		return s.releaseDeletedPackageName(ctx, tombstone)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"webservice/internal/analytics"
+	"webservice/internal/events"
+	"webservice/internal/logger"
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// DownloadAnalyticsService 下载分析服务：解析下载记录的User-Agent与IP归属地并提供聚合统计
+type DownloadAnalyticsService struct {
+	db  *gorm.DB
+	geo analytics.GeoResolver // 可能为nil（未配置GeoIP数据库）
+}
+
+// NewDownloadAnalyticsService 创建下载分析服务实例，geo为nil时不解析国家
+func NewDownloadAnalyticsService(db *gorm.DB, geo analytics.GeoResolver) *DownloadAnalyticsService {
+	return &DownloadAnalyticsService{db: db, geo: geo}
+}
+
+// ResolveCountry 解析IP地址所在国家，未配置GeoIP数据库或无法解析时返回空字符串
+func (s *DownloadAnalyticsService) ResolveCountry(ip string) string {
+	if s.geo == nil {
+		return ""
+	}
+	return s.geo.Country(net.ParseIP(ip))
+}
+
+// OnDownloadRecorded 下载记录写入后解析访问信息，在事件总线的协程池中执行
+func (s *DownloadAnalyticsService) OnDownloadRecorded(event events.Event) {
+	downloadID, ok := event.Payload["download_id"].(uint)
+	if !ok {
+		return
+	}
+	if err := s.EnrichDownload(context.Background(), downloadID); err != nil {
+		logger.Warnf("Failed to enrich download %d: %v", downloadID, err)
+	}
+}
+
+// EnrichDownload 解析单条下载记录并写入客户端、操作系统和国家字段
+func (s *DownloadAnalyticsService) EnrichDownload(ctx context.Context, downloadID uint) error {
+	var download models.PackageDownload
+	if err := s.db.WithContext(ctx).First(&download, downloadID).Error; err != nil {
+		return err
+	}
+
+	info := analytics.ParseUserAgent(download.UserAgent)
+	updates := map[string]interface{}{
+		"client":         nullableString(info.Client),
+		"client_version": nullableString(info.ClientVersion),
+		"os":             nullableString(info.OS),
+		"tool":           nullableString(info.Tool),
+		"enriched_at":    time.Now().UTC(),
+	}
+	if s.geo != nil {
+		updates["country"] = nullableString(s.geo.Country(net.ParseIP(download.IPAddress)))
+	}
+
+	return s.db.WithContext(ctx).Model(&download).Updates(updates).Error
+}
+
+// GetPackageAnalytics 获取包自since起的下载分布（仅包所有者）
+func (s *DownloadAnalyticsService) GetPackageAnalytics(ctx context.Context, packageName string, userID uint, since time.Time) (*models.PackageAnalyticsResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "DownloadAnalyticsService.GetPackageAnalytics")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if pkg.OwnerID != userID {
+		return nil, errors.New("permission denied")
+	}
+
+	downloads := func() *gorm.DB {
+		return s.db.WithContext(ctx).Model(&models.PackageDownload{}).
+			Where("package_version_id IN (SELECT id FROM package_versions WHERE package_id = ?)", pkg.ID).
+			Where("download_time >= ?", since)
+	}
+
+	response := &models.PackageAnalyticsResponse{Package: pkg.Name, Since: since}
+	if err := downloads().Count(&response.TotalDownloads).Error; err != nil {
+		return nil, fmt.Errorf("failed to count downloads: %w", err)
+	}
+	if err := downloads().Where("enriched_at IS NOT NULL").Count(&response.Enriched).Error; err != nil {
+		return nil, fmt.Errorf("failed to count enriched downloads: %w", err)
+	}
+
+	for column, target := range map[string]*[]models.AnalyticsBucket{
+		"country": &response.ByCountry,
+		"client":  &response.ByClient,
+		"tool":    &response.ByTool,
+		"os":      &response.ByOS,
+	} {
+		buckets, err := groupDownloads(downloads(), column)
+		if err != nil {
+			return nil, err
+		}
+		*target = buckets
+	}
+
+	return response, nil
+}
+
+// groupDownloads 按列统计已解析的下载记录，列值为空的记录归入空key
+func groupDownloads(query *gorm.DB, column string) ([]models.AnalyticsBucket, error) {
+	var buckets []models.AnalyticsBucket
+	err := query.
+		Select(fmt.Sprintf("COALESCE(%s, '') AS `key`, COUNT(*) AS downloads", column)).
+		Where("enriched_at IS NOT NULL").
+		Group(column).
+		Order("downloads DESC").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate downloads by %s: %w", column, err)
+	}
+	return buckets, nil
+}
+
+// nullableString 空字符串写入NULL
+func nullableString(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
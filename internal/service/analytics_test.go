@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+)
+
+// stubGeoResolver 按IP字符串查表的GeoIP解析器
+type stubGeoResolver map[string]string
+
+func (r stubGeoResolver) Country(ip net.IP) string {
+	return r[ip.String()]
+}
+
+func TestDownloadAnalyticsAggregation(t *testing.T) {
+	db := newTestDB(t)
+	s := NewDownloadAnalyticsService(db, stubGeoResolver{"192.0.2.1": "DE", "192.0.2.2": "US"})
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	stranger := createTestUser(t, db, "bob", models.RoleUser)
+	pkg := createTestPackage(t, db, "demo", owner, false)
+	v1 := createTestVersion(t, db, pkg, "1.0.0", nil)
+	v2 := createTestVersion(t, db, pkg, "2.0.0", nil)
+
+	chrome := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	downloads := []*models.PackageDownload{
+		{PackageVersionID: v1.ID, IPAddress: "192.0.2.1", UserAgent: "curl/8.4.0"},
+		{PackageVersionID: v2.ID, IPAddress: "192.0.2.1", UserAgent: "curl/8.5.0"},
+		{PackageVersionID: v2.ID, IPAddress: "192.0.2.2", UserAgent: chrome},
+		{PackageVersionID: v2.ID, IPAddress: "198.51.100.1", UserAgent: ""}, // 未知国家和客户端
+	}
+	for _, d := range downloads {
+		if err := db.Create(d).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := s.EnrichDownload(context.Background(), d.ID); err != nil {
+			t.Fatalf("EnrichDownload: %v", err)
+		}
+	}
+	// 尚未解析的下载只计入总数
+	if err := db.Create(&models.PackageDownload{PackageVersionID: v1.ID, IPAddress: "192.0.2.2", UserAgent: "curl/8.4.0"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// since之前的下载不计入
+	old := &models.PackageDownload{PackageVersionID: v1.ID, IPAddress: "192.0.2.1", UserAgent: "curl/8.4.0"}
+	if err := db.Create(old).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(old).Update("download_time", time.Now().AddDate(0, 0, -60)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var enriched models.PackageDownload
+	if err := db.First(&enriched, downloads[2].ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if enriched.Country == nil || *enriched.Country != "US" || enriched.Client == nil || *enriched.Client != "Chrome" ||
+		enriched.OS == nil || *enriched.OS != "Windows" || enriched.EnrichedAt == nil {
+		t.Errorf("enriched download = %+v, want US/Chrome/Windows", enriched)
+	}
+
+	resp, err := s.GetPackageAnalytics(context.Background(), "demo", owner.ID, time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("GetPackageAnalytics: %v", err)
+	}
+	if resp.TotalDownloads != 5 || resp.Enriched != 4 {
+		t.Errorf("total = %d, enriched = %d; want 5 and 4", resp.TotalDownloads, resp.Enriched)
+	}
+	assertBuckets(t, "country", resp.ByCountry, map[string]int64{"DE": 2, "US": 1, "": 1})
+	assertBuckets(t, "client", resp.ByClient, map[string]int64{"curl": 2, "Chrome": 1, "": 1})
+	assertBuckets(t, "tool", resp.ByTool, map[string]int64{"cli": 2, "browser": 1, "": 1})
+	assertBuckets(t, "os", resp.ByOS, map[string]int64{"": 3, "Windows": 1})
+	if resp.ByCountry[0].Key != "DE" {
+		t.Errorf("country buckets = %+v, want the largest first", resp.ByCountry)
+	}
+
+	if _, err := s.GetPackageAnalytics(context.Background(), "demo", stranger.ID, time.Now().AddDate(0, 0, -30)); err == nil {
+		t.Error("GetPackageAnalytics allowed a user who does not own the package")
+	}
+}
+
+func TestEnrichDownloadWithoutGeoResolver(t *testing.T) {
+	db := newTestDB(t)
+	s := NewDownloadAnalyticsService(db, nil)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	version := createTestVersion(t, db, createTestPackage(t, db, "demo", owner, false), "1.0.0", nil)
+
+	download := &models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1", UserAgent: "pip/23.3.1"}
+	if err := db.Create(download).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.EnrichDownload(context.Background(), download.ID); err != nil {
+		t.Fatalf("EnrichDownload: %v", err)
+	}
+	if err := db.First(download, download.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if download.Country != nil {
+		t.Errorf("country = %q without a GeoIP database, want NULL", *download.Country)
+	}
+	if download.Tool == nil || *download.Tool != "package-manager" {
+		t.Errorf("tool = %v, want package-manager", download.Tool)
+	}
+	if got := s.ResolveCountry("192.0.2.1"); got != "" {
+		t.Errorf("ResolveCountry without a GeoIP database = %q, want empty", got)
+	}
+}
+
+// assertBuckets 比较分组结果，不关心顺序
+func assertBuckets(t *testing.T, name string, got []models.AnalyticsBucket, want map[string]int64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s buckets = %+v, want %v", name, got, want)
+		return
+	}
+	for _, b := range got {
+		if want[b.Key] != b.Downloads {
+			t.Errorf("%s bucket %q = %d, want %d", name, b.Key, b.Downloads, want[b.Key])
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// apiTokenTouchInterval 更新令牌最近使用时间的最小间隔，避免每次调用都写数据库
+const apiTokenTouchInterval = time.Minute
+
+// APITokenService API令牌存储：令牌以SHA-256摘要保存，用于内部服务调用gRPC接口时的认证
+type APITokenService struct {
+	db *gorm.DB
+}
+
+// NewAPITokenService 创建API令牌服务实例
+func NewAPITokenService(db *gorm.DB) *APITokenService {
+	return &APITokenService{db: db}
+}
+
+// CreateToken 为用户创建API令牌，返回只在此时可见的明文令牌
+func (s *APITokenService) CreateToken(ctx context.Context, userID uint, req *models.CreateAPITokenRequest) (string, *models.APIToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("failed to generate api token: %w", err)
+	}
+	plain := hex.EncodeToString(raw)
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: hashAPIToken(plain),
+		Prefix:    plain[:8],
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.db.WithContext(ctx).Create(token).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to create api token: %w", err)
+	}
+	return plain, token, nil
+}
+
+// ListTokens 获取用户未吊销的API令牌（包括已过期的）
+func (s *APITokenService) ListTokens(ctx context.Context, userID uint) ([]*models.APIToken, error) {
+	var tokens []*models.APIToken
+	err := s.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken 吊销用户的指定API令牌
+func (s *APITokenService) RevokeToken(ctx context.Context, userID, tokenID uint) error {
+	result := s.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke api token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验明文令牌，返回令牌及其所属用户
+// 令牌不存在、已吊销、已过期或用户不是正常状态时返回ErrInvalidAPIToken
+func (s *APITokenService) Authenticate(ctx context.Context, plain string) (*models.APIToken, *models.User, error) {
+	if plain == "" {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	var token models.APIToken
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", hashAPIToken(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("failed to find api token: %w", err)
+	}
+	if !token.IsActive() {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, token.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("failed to find api token user: %w", err)
+	}
+	if !user.IsActive() {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	s.touch(ctx, &token)
+	return &token, &user, nil
+}
+
+// touch 更新令牌的最近使用时间，失败不影响认证
+func (s *APITokenService) touch(ctx context.Context, token *models.APIToken) {
+	now := time.Now().UTC()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < apiTokenTouchInterval {
+		return
+	}
+	s.db.WithContext(ctx).Model(&models.APIToken{}).Where("id = ?", token.ID).Update("last_used_at", now)
+	token.LastUsedAt = &now
+}
+
+// hashAPIToken 计算令牌的SHA-256摘要
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
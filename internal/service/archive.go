@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+	"webservice/internal/outbox"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// SetPackageArchived 归档或取消归档包（仅包所有者）
+// 归档后包只读，仍可下载和搜索；返回的changed为false表示包已处于目标状态
+func (s *PackageService) SetPackageArchived(ctx context.Context, packageName string, archived bool, userID uint) (*models.Package, bool, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.SetPackageArchived")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, errors.New("package not found")
+		}
+		return nil, false, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if pkg.OwnerID != userID {
+		return nil, false, errors.New("permission denied")
+	}
+
+	changed := pkg.IsArchived != archived
+	if changed {
+		var archivedAt *time.Time
+		if archived {
+			now := time.Now().UTC()
+			archivedAt = &now
+		}
+		eventType := events.PackageUnarchived
+		if archived {
+			eventType = events.PackageArchived
+		}
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Model(&pkg).Updates(map[string]interface{}{
+				"is_archived": archived,
+				"archived_at": archivedAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+			return outbox.Append(tx, events.Event{
+				Type:        eventType,
+				PackageID:   pkg.ID,
+				PackageName: pkg.Name,
+				UserID:      userID,
+			})
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update package archive state: %w", err)
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Preload("Owner").First(&pkg, pkg.ID).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to reload package: %w", err)
+	}
+
+	return &pkg, changed, nil
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// 归档内容列表的分页大小
+const (
+	defaultArchivePageSize = 100
+	maxArchivePageSize     = 1000
+)
+
+var (
+	// ErrUnsupportedArchive 包版本文件不是zip、tar或tar.gz格式
+	ErrUnsupportedArchive = errors.New("package file is not a zip, tar or tar.gz archive")
+	// ErrInvalidArchive 归档文件已损坏或被截断
+	ErrInvalidArchive = errors.New("package archive is corrupted")
+)
+
+// ArchivePageSize 规范化归档内容列表的分页参数
+func ArchivePageSize(page, pageSize int) (int, int) {
+	return models.NormalizePage(page, pageSize, defaultArchivePageSize, maxArchivePageSize)
+}
+
+// ListArchiveContents 分页列出包版本文件（zip/tar/tar.gz）中的条目
+// tar从MinIO流式读取，跳过页面之前的条目（内容读入io.Discard），读完当前页后关闭；
+// 条目总数在首次完整扫描后按版本缓存（版本文件不会变化），之后的请求读到页面末尾即停止
+// zip需要读取末尾的中央目录，直接按偏移读取对象
+func (s *PackageService) ListArchiveContents(ctx context.Context, packageName, version string, userID *uint, page, pageSize int) (*models.ArchiveContentPage, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ListArchiveContents")
+	defer span.Finish()
+
+	page, pageSize = ArchivePageSize(page, pageSize)
+
+	var pkgVersion models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package version not found")
+		}
+		return nil, fmt.Errorf("failed to find package version: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, PackageCaller{UserID: userID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+	if s.minioClient == nil {
+		return nil, errors.New("file storage is not available")
+	}
+
+	reader, info, err := objectStore(s.minioClient, &pkgVersion).DownloadObject(ctx, pkgVersion.MinIOPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download package from storage: %w", err)
+	}
+	defer reader.Close()
+
+	result := &models.ArchiveContentPage{Entries: []models.ArchiveEntry{}, Page: page, PageSize: pageSize}
+	buffered := bufio.NewReader(reader)
+	header, _ := buffered.Peek(512)
+
+	switch {
+	case bytes.HasPrefix(header, []byte{'P', 'K', 0x03, 0x04}):
+		// 压缩存储的对象不能按偏移读取；zip本身已压缩，上传时不会再压缩存储
+		readerAt, ok := reader.(io.ReaderAt)
+		if !ok || info.Compressed {
+			return nil, ErrUnsupportedArchive
+		}
+		if err := listZipEntries(readerAt, info.Size, result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer gz.Close()
+		return s.listTarEntries(ctx, pkgVersion.ID, tar.NewReader(gz), result)
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return s.listTarEntries(ctx, pkgVersion.ID, tar.NewReader(buffered), result)
+	}
+	return nil, ErrUnsupportedArchive
+}
+
+// listTarEntries 顺序读取tar条目，填充当前页
+// 未缓存总数时读到归档末尾统计总数并缓存，否则读完当前页及下一个条目（判断是否还有更多）后停止
+func (s *PackageService) listTarEntries(ctx context.Context, versionID uint, tr *tar.Reader, result *models.ArchiveContentPage) (*models.ArchiveContentPage, error) {
+	skip := (result.Page - 1) * result.PageSize
+	cachedTotal, cached := s.archiveTotals.Load(versionID)
+
+	index := 0
+	for ; ; index++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if err := checkTarEntryPath(hdr); err != nil {
+			return nil, err
+		}
+
+		if index >= skip+result.PageSize {
+			result.HasMore = true
+			if cached {
+				break
+			}
+		}
+		if index >= skip && index < skip+result.PageSize {
+			result.Entries = append(result.Entries, tarEntry(hdr))
+			continue
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+	}
+
+	if cached {
+		result.Total = cachedTotal.(int)
+	} else {
+		result.Total = index
+		s.archiveTotals.Store(versionID, index)
+	}
+	return result, nil
+}
+
+// listZipEntries 从中央目录读取zip条目，条目总数即中央目录中的记录数
+func listZipEntries(r io.ReaderAt, size int64, result *models.ArchiveContentPage) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	for _, f := range zr.File {
+		if err := checkArchivePath(f.Name, f.Name); err != nil {
+			return err
+		}
+	}
+
+	result.Total = len(zr.File)
+	start := (result.Page - 1) * result.PageSize
+	for i := start; i < len(zr.File) && i < start+result.PageSize; i++ {
+		result.Entries = append(result.Entries, zipEntry(zr.File[i]))
+	}
+	result.HasMore = start+result.PageSize < len(zr.File)
+	return nil
+}
+
+// checkTarEntryPath 检查tar条目名及链接目标，符号链接的目标相对于条目所在目录，硬链接的目标相对于归档根目录
+func checkTarEntryPath(hdr *tar.Header) error {
+	if err := checkArchivePath(hdr.Name, hdr.Name); err != nil {
+		return err
+	}
+	switch hdr.Typeflag {
+	case tar.TypeSymlink:
+		target := strings.ReplaceAll(hdr.Linkname, `\`, "/")
+		if !path.IsAbs(target) {
+			target = path.Join(path.Dir(strings.ReplaceAll(hdr.Name, `\`, "/")), target)
+		}
+		return checkArchivePath(hdr.Name, target)
+	case tar.TypeLink:
+		return checkArchivePath(hdr.Name, hdr.Linkname)
+	}
+	return nil
+}
+
+// checkArchivePath 路径为绝对路径或指向归档根目录之外时返回ErrInvalidArchive，反斜杠按路径分隔符处理
+func checkArchivePath(entry, p string) error {
+	p = strings.ReplaceAll(p, `\`, "/")
+	cleaned := path.Clean(p)
+	drive := len(p) >= 2 && p[1] == ':' && ('a' <= p[0]|0x20 && p[0]|0x20 <= 'z') // Windows盘符，如C:
+	if path.IsAbs(p) || drive || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("%w: entry %q points outside the archive", ErrInvalidArchive, entry)
+	}
+	return nil
+}
+
+// tarEntry 转换tar条目头
+func tarEntry(hdr *tar.Header) models.ArchiveEntry {
+	entry := models.ArchiveEntry{
+		Name:    hdr.Name,
+		Size:    hdr.Size,
+		Mode:    hdr.FileInfo().Mode().String(),
+		ModTime: hdr.ModTime,
+	}
+	switch hdr.Typeflag {
+	case tar.TypeReg:
+		entry.Type = models.ArchiveEntryFile
+	case tar.TypeDir:
+		entry.Type = models.ArchiveEntryDir
+	case tar.TypeSymlink, tar.TypeLink:
+		entry.Type = models.ArchiveEntrySymlink
+		entry.Linkname = hdr.Linkname
+	default:
+		entry.Type = models.ArchiveEntryOther
+	}
+	return entry
+}
+
+// zipEntry 转换zip条目头
+func zipEntry(f *zip.File) models.ArchiveEntry {
+	mode := f.Mode()
+	entry := models.ArchiveEntry{
+		Name:    f.Name,
+		Size:    int64(f.UncompressedSize64),
+		Mode:    mode.String(),
+		ModTime: f.Modified,
+		Type:    models.ArchiveEntryFile,
+	}
+	switch {
+	case mode.IsDir():
+		entry.Type = models.ArchiveEntryDir
+	case mode&os.ModeSymlink != 0:
+		entry.Type = models.ArchiveEntrySymlink
+	}
+	return entry
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+)
+
+// tarGzFixture 生成tar.gz归档，普通文件的内容为其名称
+func tarGzFixture(t *testing.T, headers ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range headers {
+		var content []byte
+		if hdr.Typeflag == tar.TypeReg {
+			content = []byte(hdr.Name)
+			hdr.Size = int64(len(content))
+		}
+		if hdr.Mode == 0 {
+			hdr.Mode = 0o644
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write tar header %q: %v", hdr.Name, err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadArchive 通过上传接口发布以archive为文件内容的版本
+func uploadArchive(t *testing.T, s *PackageService, pkg *models.Package, version string, archive []byte) {
+	t.Helper()
+	_, err := s.UploadPackageVersion(context.Background(), pkg.Name, &models.CreatePackageVersionRequest{Version: version},
+		bytes.NewReader(archive), int64(len(archive)), pkg.OwnerID)
+	if err != nil {
+		t.Fatalf("upload %s@%s: %v", pkg.Name, version, err)
+	}
+}
+
+func TestListArchiveContentsTarGz(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "browse", owner, false)
+
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	headers := []*tar.Header{
+		{Name: "pkg/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: modTime},
+		{Name: "pkg/README.md", Typeflag: tar.TypeReg, ModTime: modTime},
+		{Name: "pkg/latest", Typeflag: tar.TypeSymlink, Linkname: "README.md", ModTime: modTime},
+		{Name: "pkg/docs/../LICENSE", Typeflag: tar.TypeReg, ModTime: modTime},
+	}
+	for i := 0; i < 246; i++ {
+		headers = append(headers, &tar.Header{Name: fmt.Sprintf("pkg/src/file-%03d.go", i), Typeflag: tar.TypeReg, ModTime: modTime})
+	}
+	uploadArchive(t, s, pkg, "1.0.0", tarGzFixture(t, headers...))
+
+	first, err := s.ListArchiveContents(context.Background(), "browse", "1.0.0", nil, 1, 100)
+	if err != nil {
+		t.Fatalf("ListArchiveContents page 1: %v", err)
+	}
+	if first.Total != 250 || len(first.Entries) != 100 || !first.HasMore || first.Page != 1 || first.PageSize != 100 {
+		t.Fatalf("page 1 = total %d, %d entries, has_more %v; want 250, 100, true", first.Total, len(first.Entries), first.HasMore)
+	}
+	want := []models.ArchiveEntry{
+		{Name: "pkg/", Type: models.ArchiveEntryDir, Mode: "drwxr-xr-x", ModTime: modTime},
+		{Name: "pkg/README.md", Type: models.ArchiveEntryFile, Size: int64(len("pkg/README.md")), Mode: "-rw-r--r--", ModTime: modTime},
+		{Name: "pkg/latest", Type: models.ArchiveEntrySymlink, Mode: "Lrw-r--r--", ModTime: modTime, Linkname: "README.md"},
+		{Name: "pkg/docs/../LICENSE", Type: models.ArchiveEntryFile, Size: int64(len("pkg/docs/../LICENSE")), Mode: "-rw-r--r--", ModTime: modTime},
+	}
+	for i, entry := range want {
+		got := first.Entries[i]
+		got.ModTime = got.ModTime.UTC()
+		if got != entry {
+			t.Errorf("entry %d = %+v, want %+v", i, got, entry)
+		}
+	}
+	if first.Entries[99].Name != "pkg/src/file-095.go" {
+		t.Errorf("last entry of page 1 = %q, want pkg/src/file-095.go", first.Entries[99].Name)
+	}
+
+	// 总数已缓存，之后的页面读到页面末尾即停止
+	last, err := s.ListArchiveContents(context.Background(), "browse", "1.0.0", nil, 3, 100)
+	if err != nil {
+		t.Fatalf("ListArchiveContents page 3: %v", err)
+	}
+	if last.Total != 250 || len(last.Entries) != 50 || last.HasMore {
+		t.Errorf("page 3 = total %d, %d entries, has_more %v; want 250, 50, false", last.Total, len(last.Entries), last.HasMore)
+	}
+	if last.Entries[0].Name != "pkg/src/file-196.go" || last.Entries[49].Name != "pkg/src/file-245.go" {
+		t.Errorf("page 3 spans %q..%q, want file-196..file-245", last.Entries[0].Name, last.Entries[49].Name)
+	}
+
+	beyond, err := s.ListArchiveContents(context.Background(), "browse", "1.0.0", nil, 10, 100)
+	if err != nil {
+		t.Fatalf("ListArchiveContents past the end: %v", err)
+	}
+	if beyond.Total != 250 || len(beyond.Entries) != 0 || beyond.HasMore {
+		t.Errorf("page 10 = total %d, %d entries, has_more %v; want 250, none, false", beyond.Total, len(beyond.Entries), beyond.HasMore)
+	}
+}
+
+func TestListArchiveContentsRejectsPathTraversal(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "traversal", owner, false)
+
+	tests := []struct {
+		name   string
+		header *tar.Header
+	}{
+		{"parent directory", &tar.Header{Name: "../evil.sh", Typeflag: tar.TypeReg}},
+		{"nested parent directory", &tar.Header{Name: "pkg/../../evil.sh", Typeflag: tar.TypeReg}},
+		{"absolute path", &tar.Header{Name: "/etc/cron.d/evil", Typeflag: tar.TypeReg}},
+		{"backslash parent directory", &tar.Header{Name: `..\evil.sh`, Typeflag: tar.TypeReg}},
+		{"windows drive", &tar.Header{Name: `C:\Windows\evil.dll`, Typeflag: tar.TypeReg}},
+		{"symlink to parent directory", &tar.Header{Name: "pkg/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}},
+		{"symlink to absolute path", &tar.Header{Name: "pkg/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+		{"hard link outside", &tar.Header{Name: "pkg/link", Typeflag: tar.TypeLink, Linkname: "../secret"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := fmt.Sprintf("1.0.%d", i)
+			// 不安全的条目位于第一页之后，也会在首次完整扫描时被发现
+			headers := []*tar.Header{{Name: "pkg/README.md", Typeflag: tar.TypeReg}, {Name: "pkg/index.js", Typeflag: tar.TypeReg}, tt.header}
+			uploadArchive(t, s, pkg, version, tarGzFixture(t, headers...))
+
+			for attempt := 0; attempt < 2; attempt++ {
+				_, err := s.ListArchiveContents(context.Background(), "traversal", version, nil, 1, 1)
+				if !errors.Is(err, ErrInvalidArchive) {
+					t.Fatalf("attempt %d: ListArchiveContents() error = %v, want ErrInvalidArchive", attempt+1, err)
+				}
+			}
+		})
+	}
+
+	// 归档内部的符号链接和..不越出根目录时允许
+	uploadArchive(t, s, pkg, "2.0.0", tarGzFixture(t,
+		&tar.Header{Name: "pkg/docs/index.md", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "pkg/docs/link", Typeflag: tar.TypeSymlink, Linkname: "../README.md"},
+		&tar.Header{Name: "pkg/hard", Typeflag: tar.TypeLink, Linkname: "pkg/docs/index.md"},
+	))
+	if _, err := s.ListArchiveContents(context.Background(), "traversal", "2.0.0", nil, 1, 100); err != nil {
+		t.Errorf("ListArchiveContents() with safe links error = %v", err)
+	}
+}
+
+func TestListArchiveContentsZipRejectsPathTraversal(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "zipped", owner, false)
+
+	zipFixture := func(names ...string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprint(w, name)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	uploadArchive(t, s, pkg, "1.0.0", zipFixture("pkg/a.txt", "pkg/b.txt", "pkg/c.txt"))
+	page, err := s.ListArchiveContents(context.Background(), "zipped", "1.0.0", nil, 2, 2)
+	if err != nil {
+		t.Fatalf("ListArchiveContents(): %v", err)
+	}
+	if page.Total != 3 || len(page.Entries) != 1 || page.Entries[0].Name != "pkg/c.txt" || page.HasMore {
+		t.Errorf("page 2 = %+v, want only pkg/c.txt of 3", page)
+	}
+
+	uploadArchive(t, s, pkg, "1.0.1", zipFixture("pkg/a.txt", "../../evil.sh"))
+	if _, err := s.ListArchiveContents(context.Background(), "zipped", "1.0.1", nil, 1, 1); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("ListArchiveContents() error = %v, want ErrInvalidArchive", err)
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditService 审计日志服务
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService 创建审计日志服务实例
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record 记录一条审计日志，details会序列化为JSON
+func (s *AuditService) Record(ctx context.Context, actorID uint, action, resource string, details interface{}, ipAddress string) error {
+	detailsJSON := ""
+	if details != nil {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		detailsJSON = string(raw)
+	}
+
+	entry := &models.AuditLog{
+		ActorID:   actorID,
+		Action:    action,
+		Resource:  resource,
+		Details:   detailsJSON,
+		IPAddress: ipAddress,
+	}
+	if err := s.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to record audit log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+)
+
+// defaultDeletedNameHold 未配置时包删除后名称的保留期
+const defaultDeletedNameHold = 30 * 24 * time.Hour
+
+// packageNamePattern 包名格式：字母或数字开头，只包含字母、数字、'.'、'_'、'-'，最长100个字符
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+
+// newReservedNames 将配置的保留包名转换为小写集合
+func newReservedNames(names []string) map[string]struct{} {
+	reserved := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			reserved[name] = struct{}{}
+		}
+	}
+	return reserved
+}
+
+// CheckPackageNameAvailability 检查包名是否可用于创建新包
+// 格式和保留名单在内存中判断，之后只按名称索引查询一次packages表（包含已删除的包），必要时再查询别名表
+func (s *PackageService) CheckPackageNameAvailability(ctx context.Context, name string) (*models.PackageNameAvailability, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.CheckPackageNameAvailability")
+	defer span.Finish()
+
+	result := &models.PackageNameAvailability{Name: name}
+
+	_, err := s.packageNameStatus(ctx, name, 0)
+	switch {
+	case err == nil:
+		result.Available = true
+	case errors.Is(err, ErrInvalidPackageName):
+		result.Reason = models.NameUnavailableInvalidFormat
+	case errors.Is(err, ErrPackageNameReserved):
+		result.Reason = models.NameUnavailableReserved
+	case errors.Is(err, ErrPackageNameRecentlyDeleted):
+		result.Reason = models.NameUnavailableRecentlyDeleted
+	case errors.Is(err, ErrPackageExists), errors.Is(err, ErrPackageNameAliased):
+		result.Reason = models.NameUnavailableTaken
+	default:
+		return nil, err
+	}
+	return result, nil
+}
+
+// packageNameStatus 判断包名能否被packageID对应的包（新建时为0）使用，不可用时返回对应错误
+// 同名包已删除且超过保留期时返回其记录，调用方在使用该名称前需要先释放
+func (s *PackageService) packageNameStatus(ctx context.Context, name string, packageID uint) (*models.Package, error) {
+	if !packageNamePattern.MatchString(name) {
+		return nil, ErrInvalidPackageName
+	}
+	if _, ok := s.reservedNames[strings.ToLower(name)]; ok {
+		return nil, ErrPackageNameReserved
+	}
+
+	var existing models.Package
+	if err := s.db.WithContext(ctx).Unscoped().Select("id", "name", "deleted_at").
+		Where("name = ?", name).Limit(1).Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check package existence: %w", err)
+	}
+	if existing.ID != 0 {
+		if !existing.DeletedAt.Valid {
+			return nil, ErrPackageExists
+		}
+		if time.Since(existing.DeletedAt.Time) < s.deletedNameHold {
+			return nil, ErrPackageNameRecentlyDeleted
+		}
+		return &existing, nil
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.PackageAlias{}).Where("name = ? AND package_id <> ?", name, packageID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check package alias: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrPackageNameAliased
+	}
+	return nil, nil
+}
+
+// releaseDeletedPackageName 彻底删除超过保留期的已删除包记录，释放其在唯一索引中占用的名称
+func (s *PackageService) releaseDeletedPackageName(ctx context.Context, tombstone *models.Package) error {
+	if err := s.db.WithContext(ctx).Unscoped().Delete(&models.Package{}, tombstone.ID).Error; err != nil {
+		return fmt.Errorf("failed to release deleted package name: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bandwidthMonthLayout bandwidth_usage表中月份的格式
+const bandwidthMonthLayout = "2006-01"
+
+// ErrBandwidthLimitExceeded 包本月下载流量已超过管理员设置的上限
+var ErrBandwidthLimitExceeded = errors.New("bandwidth_limit_exceeded")
+
+// BandwidthLimitExceededError 包本月下载流量超过上限，附带上限和已用流量
+type BandwidthLimitExceededError struct {
+	Package    string
+	LimitBytes int64
+	UsedBytes  int64
+}
+
+// Error 实现error接口
+func (e *BandwidthLimitExceededError) Error() string {
+	return fmt.Sprintf("%s for %s", ErrBandwidthLimitExceeded.Error(), e.Package)
+}
+
+// Unwrap 返回ErrBandwidthLimitExceeded
+func (e *BandwidthLimitExceededError) Unwrap() error {
+	return ErrBandwidthLimitExceeded
+}
+
+// BillingService 计费服务，统计包的下载流量
+type BillingService struct {
+	db *gorm.DB
+}
+
+// NewBillingService 创建计费服务实例
+func NewBillingService(db *gorm.DB) *BillingService {
+	return &BillingService{db: db}
+}
+
+// GetPackageBandwidthUsage 统计包在[from, to)内的下载流量
+// 按下载记录乘以对应版本的文件大小计算，同时返回各月的累计流量和不限时间范围的近似总量
+func (s *BillingService) GetPackageBandwidthUsage(ctx context.Context, packageName string, from, to time.Time) (*models.BandwidthUsage, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "BillingService.GetPackageBandwidthUsage")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	usage := &models.BandwidthUsage{
+		Package:           pkg.Name,
+		From:              from,
+		To:                to,
+		Months:            []models.BandwidthUsageMonth{},
+		MonthlyLimitBytes: pkg.MonthlyBandwidthLimitBytes,
+	}
+
+	var totals struct {
+		Downloads int64
+		Bytes     int64
+	}
+	err := s.db.WithContext(ctx).Table("package_downloads AS pd").
+		Select("COUNT(*) AS downloads, COALESCE(SUM(pv.file_size), 0) AS bytes").
+		Joins("JOIN package_versions pv ON pv.id = pd.package_version_id").
+		Where("pv.package_id = ? AND pd.download_time >= ? AND pd.download_time < ?", pkg.ID, from, to).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum downloaded bytes: %w", err)
+	}
+	usage.Downloads, usage.Bytes = totals.Downloads, totals.Bytes
+
+	err = s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Select("COALESCE(SUM(file_size * download_count), 0)").
+		Where("package_id = ?", pkg.ID).
+		Scan(&usage.EstimatedTotalBytes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate total bandwidth: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Model(&models.PackageBandwidthUsage{}).
+		Select("month, downloads, bytes").
+		Where("package_id = ? AND month >= ? AND month <= ?", pkg.ID, from.UTC().Format(bandwidthMonthLayout), to.UTC().Format(bandwidthMonthLayout)).
+		Order("month").
+		Scan(&usage.Months).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monthly bandwidth usage: %w", err)
+	}
+
+	usage.CurrentMonthBytes, err = monthlyBandwidthBytes(ctx, s.db, pkg.ID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetOwnedPackageBandwidthUsage 包所有者查看包的下载流量，非所有者返回permission denied
+func (s *BillingService) GetOwnedPackageBandwidthUsage(ctx context.Context, packageName string, userID uint, from, to time.Time) (*models.BandwidthUsage, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Select("id, owner_id").Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if pkg.OwnerID != userID {
+		return nil, errors.New("permission denied")
+	}
+	return s.GetPackageBandwidthUsage(ctx, packageName, from, to)
+}
+
+// SetBandwidthLimit 设置包每月下载流量上限（管理员），limit为nil时取消限制
+func (s *BillingService) SetBandwidthLimit(ctx context.Context, packageName string, limit *int64) (*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "BillingService.SetBandwidthLimit")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Model(&pkg).Update("monthly_bandwidth_limit_bytes", limit).Error; err != nil {
+		return nil, fmt.Errorf("failed to set bandwidth limit: %w", err)
+	}
+	pkg.MonthlyBandwidthLimitBytes = limit
+	return &pkg, nil
+}
+
+// monthlyBandwidthBytes 获取包在at所在月份已累计的下载流量
+func monthlyBandwidthBytes(ctx context.Context, db *gorm.DB, packageID uint, at time.Time) (int64, error) {
+	var bytes int64
+	err := db.WithContext(ctx).Model(&models.PackageBandwidthUsage{}).
+		Select("COALESCE(SUM(bytes), 0)").
+		Where("package_id = ? AND month = ?", packageID, at.UTC().Format(bandwidthMonthLayout)).
+		Scan(&bytes).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to get monthly bandwidth usage: %w", err)
+	}
+	return bytes, nil
+}
+
+// checkBandwidthLimit 包设置了每月流量上限且本月已用流量达到上限时返回*BandwidthLimitExceededError
+func checkBandwidthLimit(ctx context.Context, db *gorm.DB, pkg *models.Package) error {
+	if pkg.MonthlyBandwidthLimitBytes == nil {
+		return nil
+	}
+	used, err := monthlyBandwidthBytes(ctx, db, pkg.ID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if used >= *pkg.MonthlyBandwidthLimitBytes {
+		return &BandwidthLimitExceededError{Package: pkg.Name, LimitBytes: *pkg.MonthlyBandwidthLimitBytes, UsedBytes: used}
+	}
+	return nil
+}
+
+// recordBandwidth 将一次下载计入包当月的流量
+func recordBandwidth(db *gorm.DB, packageID uint, bytes int64, at time.Time) error {
+	usage := &models.PackageBandwidthUsage{
+		PackageID: packageID,
+		Month:     at.UTC().Format(bandwidthMonthLayout),
+		Downloads: 1,
+		Bytes:     bytes,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "package_id"}, {Name: "month"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"downloads":  gorm.Expr("downloads + ?", 1),
+			"bytes":      gorm.Expr("bytes + ?", bytes),
+			"updated_at": time.Now().UTC(),
+		}),
+	}).Create(usage).Error
+}
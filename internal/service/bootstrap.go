@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultSetupTokenTTL 安装令牌默认有效期
+const defaultSetupTokenTTL = 30 * time.Minute
+
+// BootstrapService 首次启动引导服务
+type BootstrapService struct {
+	db          *gorm.DB
+	cfg         config.BootstrapConfig
+	userService *UserService
+}
+
+// NewBootstrapService 创建首次启动引导服务实例
+func NewBootstrapService(db *gorm.DB, cfg config.BootstrapConfig, passwordCfg config.PasswordConfig) *BootstrapService {
+	return &BootstrapService{
+		db:          db,
+		cfg:         cfg,
+		userService: NewUserService(db, passwordCfg),
+	}
+}
+
+// AdminExists 检查是否已存在管理员
+func (s *BootstrapService) AdminExists() (bool, error) {
+	return adminExists(s.db)
+}
+
+// adminExists 在db（可以是事务）中检查是否已存在管理员
+func adminExists(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Where("role IN ?", []string{models.RoleAdmin, models.RoleSuper}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IssueSetupToken 在没有管理员时生成一次性安装令牌，返回明文令牌及过期时间
+// 令牌摘要保存在数据库中，多个实例或重启后仍可使用；已存在管理员时返回空字符串
+func (s *BootstrapService) IssueSetupToken() (string, time.Time, error) {
+	exists, err := s.AdminExists()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if exists {
+		return "", time.Time{}, nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(raw)
+
+	ttl := s.cfg.SetupTokenTTL
+	if ttl <= 0 {
+		ttl = defaultSetupTokenTTL
+	}
+	expiresAt := time.Now().UTC().Add(ttl)
+
+	// 顺带清理之前启动时留下的过期令牌
+	if err := s.db.Where("expires_at < ?", time.Now().UTC()).Delete(&models.SetupToken{}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.db.Create(&models.SetupToken{TokenHash: hashSetupToken(token), ExpiresAt: expiresAt}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// CompleteSetup 使用安装令牌创建首个管理员，令牌的消耗与管理员的创建在同一事务中，成功后所有安装令牌失效
+func (s *BootstrapService) CompleteSetup(req *models.SetupRequest) (*models.User, error) {
+	exists, err := s.AdminExists()
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrSetupCompleted
+	}
+
+	var setupToken models.SetupToken
+	if err := s.db.Where("token_hash = ?", hashSetupToken(req.Token)).First(&setupToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSetupToken
+		}
+		return nil, err
+	}
+	if time.Now().After(setupToken.ExpiresAt) {
+		if err := s.db.Delete(&setupToken).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrSetupTokenExpired
+	}
+
+	hashedPassword, err := s.userService.hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &models.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: hashedPassword,
+		Nickname: req.Nickname,
+		Role:     models.RoleAdmin,
+		Status:   models.UserStatusActive,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 先删除令牌占用它：并发请求中只有一个能删除成功，其余的视为令牌已使用
+		result := tx.Delete(&models.SetupToken{}, setupToken.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidSetupToken
+		}
+		if exists, err := adminExists(tx); err != nil {
+			return err
+		} else if exists {
+			return ErrSetupCompleted
+		}
+		if err := tx.Create(admin).Error; err != nil {
+			if isDuplicateKeyError(err) {
+				return s.userService.duplicateUserError(req.Username)
+			}
+			return err
+		}
+		// 其他实例签发的令牌一并失效
+		return tx.Where("1 = 1").Delete(&models.SetupToken{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// hashSetupToken 计算安装令牌的SHA-256摘要
+func hashSetupToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+func newTestBootstrapService(t *testing.T, ttl time.Duration) *BootstrapService {
+	t.Helper()
+	db := testutil.NewDB(t, &models.User{}, &models.SetupToken{})
+	return NewBootstrapService(db, config.BootstrapConfig{SetupTokenTTL: ttl}, testPasswordConfig)
+}
+
+func setupRequest(token string) *models.SetupRequest {
+	return &models.SetupRequest{Token: token, Username: "admin", Email: "admin@example.com", Password: "password123"}
+}
+
+func TestCompleteSetupWithValidToken(t *testing.T) {
+	s := newTestBootstrapService(t, 0)
+
+	token, expiresAt, err := s.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+	if token == "" || !expiresAt.After(time.Now()) {
+		t.Fatalf("IssueSetupToken = %q, %v; want a token that has not expired", token, expiresAt)
+	}
+
+	admin, err := s.CompleteSetup(setupRequest(token))
+	if err != nil {
+		t.Fatalf("CompleteSetup: %v", err)
+	}
+	if admin.Role != models.RoleAdmin || admin.Password == "password123" {
+		t.Errorf("admin role = %q, password stored in plain text = %v", admin.Role, admin.Password == "password123")
+	}
+	if exists, err := s.AdminExists(); err != nil || !exists {
+		t.Errorf("AdminExists = %v, %v; want true", exists, err)
+	}
+}
+
+func TestCompleteSetupRejectsInvalidTokens(t *testing.T) {
+	s := newTestBootstrapService(t, 0)
+
+	if _, err := s.CompleteSetup(setupRequest("anything")); !errors.Is(err, ErrInvalidSetupToken) {
+		t.Errorf("CompleteSetup before a token was issued: err = %v, want invalid setup token", err)
+	}
+
+	token, _, err := s.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+	if _, err := s.CompleteSetup(setupRequest(token + "x")); !errors.Is(err, ErrInvalidSetupToken) {
+		t.Errorf("CompleteSetup with wrong token: err = %v, want invalid setup token", err)
+	}
+}
+
+func TestCompleteSetupRejectsExpiredToken(t *testing.T) {
+	s := newTestBootstrapService(t, time.Millisecond)
+
+	token, _, err := s.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := s.CompleteSetup(setupRequest(token)); !errors.Is(err, ErrSetupTokenExpired) {
+		t.Fatalf("CompleteSetup with expired token: err = %v, want setup token expired", err)
+	}
+	// 过期后令牌被清除，再次使用视为无效
+	if _, err := s.CompleteSetup(setupRequest(token)); !errors.Is(err, ErrInvalidSetupToken) {
+		t.Errorf("CompleteSetup after expiry: err = %v, want invalid setup token", err)
+	}
+	if exists, _ := s.AdminExists(); exists {
+		t.Error("admin was created with an expired token")
+	}
+}
+
+func TestSetupRefusedWhenAdminExists(t *testing.T) {
+	s := newTestBootstrapService(t, 0)
+
+	token, _, err := s.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+	if _, err := s.CompleteSetup(setupRequest(token)); err != nil {
+		t.Fatalf("CompleteSetup: %v", err)
+	}
+
+	// 令牌已使用且管理员已存在
+	req := setupRequest(token)
+	req.Username, req.Email = "admin2", "admin2@example.com"
+	if _, err := s.CompleteSetup(req); !errors.Is(err, ErrSetupCompleted) {
+		t.Errorf("reused token: err = %v, want setup already completed", err)
+	}
+
+	again, _, err := s.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+	if again != "" {
+		t.Error("IssueSetupToken issued a token although an admin exists")
+	}
+}
+
+func TestSetupTokenSurvivesRestart(t *testing.T) {
+	first := newTestBootstrapService(t, 0)
+	token, _, err := first.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+
+	// 重启后（或由另一个实例处理请求）使用同一数据库的新服务实例
+	restarted := NewBootstrapService(first.db, config.BootstrapConfig{}, testPasswordConfig)
+	if _, err := restarted.CompleteSetup(setupRequest(token)); err != nil {
+		t.Fatalf("CompleteSetup after restart: %v", err)
+	}
+
+	var left int64
+	if err := first.db.Model(&models.SetupToken{}).Count(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d setup tokens left after setup completed", left)
+	}
+}
+
+func TestCompleteSetupConsumesTokenOnce(t *testing.T) {
+	s := newTestBootstrapService(t, 0)
+	token, _, err := s.IssueSetupToken()
+	if err != nil {
+		t.Fatalf("IssueSetupToken: %v", err)
+	}
+
+	errs := runConcurrently(5, func(i int) error {
+		req := setupRequest(token)
+		req.Username = fmt.Sprintf("admin%d", i)
+		req.Email = req.Username + "@example.com"
+		_, err := s.CompleteSetup(req)
+		// 晚到的请求可能在管理员创建之后才开始检查
+		if errors.Is(err, ErrSetupCompleted) {
+			return ErrInvalidSetupToken
+		}
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrInvalidSetupToken)
+
+	var admins int64
+	if err := s.db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+		t.Fatal(err)
+	}
+	if admins != 1 {
+		t.Fatalf("%d admins exist, want exactly one", admins)
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownCategory 包设置的分类ID不在分类表中
+	ErrUnknownCategory = errors.New("unknown category")
+	// ErrInvalidCategorySlug 分类slug只能包含小写字母、数字和-
+	ErrInvalidCategorySlug = errors.New("category slug must start with a lowercase letter or digit and contain only lowercase letters, digits or '-'")
+	// ErrCategoryExists 分类slug已存在
+	ErrCategoryExists = errors.New("category already exists")
+	// ErrCategoryNotFound 分类不存在
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
+// categorySlugPattern 分类slug格式
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// CategoryService 包分类表管理
+type CategoryService struct {
+	db *gorm.DB
+}
+
+// NewCategoryService 创建包分类服务实例
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db}
+}
+
+// ListCategories 返回所有分类及各分类下的公开包数，按slug排序
+func (s *CategoryService) ListCategories(ctx context.Context) ([]models.CategoryWithCount, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "CategoryService.ListCategories")
+	defer span.Finish()
+
+	var categories []models.Category
+	if err := s.db.WithContext(ctx).Order("slug").Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	var rows []struct {
+		CategoryID uint
+		Count      int64
+	}
+	err := s.db.WithContext(ctx).Table("package_categories").
+		Select("package_categories.category_id, COUNT(*) AS count").
+		Joins("JOIN packages ON packages.id = package_categories.package_id").
+		Where("packages.is_private = ? AND packages.deleted_at IS NULL", false).
+		Group("package_categories.category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count category packages: %w", err)
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+
+	result := make([]models.CategoryWithCount, len(categories))
+	for i, category := range categories {
+		result[i] = models.CategoryWithCount{Category: category, PackageCount: counts[category.ID]}
+	}
+	return result, nil
+}
+
+// CreateCategory 创建分类（管理员）
+func (s *CategoryService) CreateCategory(ctx context.Context, req *models.CreateCategoryRequest) (*models.Category, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "CategoryService.CreateCategory")
+	defer span.Finish()
+
+	if !categorySlugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidCategorySlug
+	}
+
+	category := &models.Category{Slug: req.Slug, Name: req.Name, Description: req.Description}
+	if err := s.db.WithContext(ctx).Create(category).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	return category, nil
+}
+
+// UpdateCategory 修改分类的名称和描述（管理员），slug不可修改
+func (s *CategoryService) UpdateCategory(ctx context.Context, id uint, req *models.UpdateCategoryRequest) (*models.Category, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "CategoryService.UpdateCategory")
+	defer span.Finish()
+
+	var category models.Category
+	if err := s.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to find category: %w", err)
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if len(updates) > 0 {
+		if err := s.db.WithContext(ctx).Model(&category).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update category: %w", err)
+		}
+	}
+	return &category, nil
+}
+
+// DeleteCategory 删除分类（管理员），同时移除所有包与该分类的关联
+func (s *CategoryService) DeleteCategory(ctx context.Context, id uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "CategoryService.DeleteCategory")
+	defer span.Finish()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", id).Delete(&models.PackageCategory{}).Error; err != nil {
+			return fmt.Errorf("failed to remove category from packages: %w", err)
+		}
+		result := tx.Delete(&models.Category{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete category: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrCategoryNotFound
+		}
+		return nil
+	})
+}
+
+// resolveCategories 按ID查找分类，有不存在的ID时返回包装了ErrUnknownCategory的错误；重复的ID只保留一个
+func (s *PackageService) resolveCategories(ctx context.Context, ids []uint) ([]models.Category, error) {
+	if len(ids) == 0 {
+		return []models.Category{}, nil
+	}
+
+	var categories []models.Category
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to find categories: %w", err)
+	}
+	found := make(map[uint]bool, len(categories))
+	for _, category := range categories {
+		found[category.ID] = true
+	}
+	var unknown []uint
+	for _, id := range ids {
+		if !found[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+		return nil, fmt.Errorf("%w: %v", ErrUnknownCategory, unknown)
+	}
+	return categories, nil
+}
+
+// replacePackageCategories 在事务内将包的分类替换为categories
+func replacePackageCategories(tx *gorm.DB, packageID uint, categories []models.Category) error {
+	if err := tx.Where("package_id = ?", packageID).Delete(&models.PackageCategory{}).Error; err != nil {
+		return fmt.Errorf("failed to clear package categories: %w", err)
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+	links := make([]models.PackageCategory, len(categories))
+	for i, category := range categories {
+		links[i] = models.PackageCategory{PackageID: packageID, CategoryID: category.ID}
+	}
+	if err := tx.Create(&links).Error; err != nil {
+		return fmt.Errorf("failed to set package categories: %w", err)
+	}
+	return nil
+}
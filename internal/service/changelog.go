@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"webservice/internal/validation"
+)
+
+// 更新日志超过最大字节数时的处理方式
+const (
+	ChangelogOverflowReject   = "reject"   // 拒绝上传或修改
+	ChangelogOverflowTruncate = "truncate" // 截断并追加说明
+)
+
+const (
+	// defaultMaxChangelogBytes 未配置时更新日志的最大字节数
+	defaultMaxChangelogBytes = 64 << 10
+	// changelogTruncatedNote 截断后追加在更新日志末尾的说明，计入最大字节数
+	changelogTruncatedNote = "\n\n*(changelog truncated)*\n"
+)
+
+var (
+	// ErrInvalidChangelog 启用更新日志格式检查时更新日志不符合要求
+	ErrInvalidChangelog = errors.New("invalid changelog")
+	// ErrChangelogTooLarge 更新日志超过配置的最大字节数（reject模式）
+	ErrChangelogTooLarge = errors.New("changelog is too large")
+)
+
+// ChangelogValidationError 更新日志格式不符合要求，附带各项校验错误
+type ChangelogValidationError struct {
+	Errors []validation.ValidationError
+}
+
+// Error 实现error接口
+func (e *ChangelogValidationError) Error() string {
+	return fmt.Sprintf("%s: %d problem(s) found", ErrInvalidChangelog.Error(), len(e.Errors))
+}
+
+// Unwrap 返回ErrInvalidChangelog
+func (e *ChangelogValidationError) Unwrap() error {
+	return ErrInvalidChangelog
+}
+
+// checkChangelog 启用格式检查时校验更新日志，不符合要求时返回*ChangelogValidationError
+// 更新日志是可选的，为空时不检查
+func (s *PackageService) checkChangelog(changelog string) error {
+	if !s.enforceChangelogFormat || strings.TrimSpace(changelog) == "" {
+		return nil
+	}
+	if errs := validation.ValidateChangelog(changelog); len(errs) > 0 {
+		return &ChangelogValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// limitChangelog 检查更新日志的字节数，超过上限时按配置拒绝或截断
+// 截断在UTF-8字符边界进行，结果（含截断说明）不超过上限
+func (s *PackageService) limitChangelog(changelog string) (string, error) {
+	if len(changelog) <= s.maxChangelogBytes {
+		return changelog, nil
+	}
+	if s.changelogOverflow != ChangelogOverflowTruncate {
+		return "", fmt.Errorf("%w: %d bytes, at most %d", ErrChangelogTooLarge, len(changelog), s.maxChangelogBytes)
+	}
+
+	cut, note := s.maxChangelogBytes-len(changelogTruncatedNote), changelogTruncatedNote
+	if cut <= 0 {
+		// 上限比截断说明还短时只截断，不追加说明
+		cut, note = s.maxChangelogBytes, ""
+	}
+	for cut > 0 && !utf8.RuneStart(changelog[cut]) {
+		cut--
+	}
+	return strings.TrimRight(changelog[:cut], " \t\r\n") + note, nil
+}
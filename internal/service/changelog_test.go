@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+func TestLimitChangelog(t *testing.T) {
+	note := changelogTruncatedNote
+	tests := []struct {
+		name      string
+		max       int
+		overflow  string
+		changelog string
+		want      string
+		wantErr   error
+	}{
+		{"under the limit", 100, ChangelogOverflowReject, "# 1.0.0\n- fixed", "# 1.0.0\n- fixed", nil},
+		{"exactly at the limit", 10, ChangelogOverflowReject, strings.Repeat("a", 10), strings.Repeat("a", 10), nil},
+		{"one byte over rejected", 10, ChangelogOverflowReject, strings.Repeat("a", 11), "", ErrChangelogTooLarge},
+		{"unknown policy rejects", 10, "", strings.Repeat("a", 11), "", ErrChangelogTooLarge},
+		{"exactly at the limit not truncated", 40, ChangelogOverflowTruncate, strings.Repeat("a", 40), strings.Repeat("a", 40), nil},
+		{"truncated with note", len(note) + 5, ChangelogOverflowTruncate, strings.Repeat("a", 100), "aaaaa" + note, nil},
+		{"trailing whitespace trimmed before note", len(note) + 5, ChangelogOverflowTruncate, "aaa\n\n" + strings.Repeat("b", 100), "aaa" + note, nil},
+		{"cut on a rune boundary", len(note) + 5, ChangelogOverflowTruncate, "aaaa" + strings.Repeat("中", 10), "aaaa" + note, nil},
+		{"limit shorter than the note", 5, ChangelogOverflowTruncate, "aaaa中文", "aaaa", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &PackageService{maxChangelogBytes: tt.max, changelogOverflow: tt.overflow}
+			got, err := s.limitChangelog(tt.changelog)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("limitChangelog() err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("limitChangelog() = %q, want %q", got, tt.want)
+			}
+			if len(got) > tt.max || !utf8.ValidString(got) {
+				t.Errorf("limitChangelog() = %d bytes (valid UTF-8: %v), want at most %d", len(got), utf8.ValidString(got), tt.max)
+			}
+		})
+	}
+}
+
+func TestDefaultChangelogLimit(t *testing.T) {
+	s := newTestPackageService(t, newTestDB(t))
+	if s.maxChangelogBytes != 64<<10 {
+		t.Fatalf("default max changelog bytes = %d, want 65536", s.maxChangelogBytes)
+	}
+	if _, err := s.limitChangelog(strings.Repeat("a", 64<<10)); err != nil {
+		t.Errorf("changelog at the default limit: %v", err)
+	}
+	if _, err := s.limitChangelog(strings.Repeat("a", 64<<10+1)); !errors.Is(err, ErrChangelogTooLarge) {
+		t.Errorf("changelog over the default limit err = %v, want ErrChangelogTooLarge", err)
+	}
+}
+
+func TestOversizeChangelogOnUploadAndUpdate(t *testing.T) {
+	const limit = 100
+	oversize := "# 1.0.0\n" + strings.Repeat("- fixed a bug\n", 20)
+	upload := func(s *PackageService, pkg *models.Package, version, changelog string, uploaderID uint) (*models.PackageVersion, error) {
+		content := "content of " + pkg.Name + "@" + version
+		return s.UploadPackageVersion(context.Background(), pkg.Name, &models.CreatePackageVersionRequest{Version: version, Changelog: changelog},
+			strings.NewReader(content), int64(len(content)), uploaderID)
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		s := NewPackageService(newTestDB(t), testutil.NewStorage(t, nil), nil, config.PackagesConfig{MaxChangelogBytes: limit})
+		owner := createTestUser(t, s.db, "alice", models.RoleUser)
+		pkg := createTestPackage(t, s.db, "app", owner, false)
+
+		if _, err := upload(s, pkg, "1.0.0", oversize, owner.ID); !errors.Is(err, ErrChangelogTooLarge) {
+			t.Fatalf("upload with oversize changelog err = %v, want ErrChangelogTooLarge", err)
+		}
+		var count int64
+		s.db.Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Count(&count)
+		if count != 0 {
+			t.Fatalf("versions after rejected upload = %d, want 0", count)
+		}
+
+		original, err := upload(s, pkg, "1.0.0", oversize[:limit], owner.ID)
+		if err != nil {
+			t.Fatalf("upload with changelog at the limit: %v", err)
+		}
+		_, err = s.UpdatePackageVersion(context.Background(), "app", "1.0.0", &models.UpdatePackageVersionRequest{Changelog: &oversize}, owner.ID)
+		if !errors.Is(err, ErrChangelogTooLarge) {
+			t.Fatalf("update with oversize changelog err = %v, want ErrChangelogTooLarge", err)
+		}
+		var stored models.PackageVersion
+		s.db.Where("package_id = ?", pkg.ID).First(&stored)
+		if stored.Changelog != original.Changelog {
+			t.Errorf("changelog after rejected update = %q, want the original", stored.Changelog)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		s := NewPackageService(newTestDB(t), testutil.NewStorage(t, nil), nil, config.PackagesConfig{MaxChangelogBytes: limit, ChangelogOverflow: ChangelogOverflowTruncate})
+		owner := createTestUser(t, s.db, "alice", models.RoleUser)
+		pkg := createTestPackage(t, s.db, "app", owner, false)
+
+		version, err := upload(s, pkg, "1.0.0", oversize, owner.ID)
+		if err != nil {
+			t.Fatalf("upload with oversize changelog: %v", err)
+		}
+		if len(version.Changelog) > limit || !strings.HasSuffix(version.Changelog, "*(changelog truncated)*\n") {
+			t.Errorf("uploaded changelog = %d bytes %q, want truncated within %d bytes", len(version.Changelog), version.Changelog, limit)
+		}
+
+		longer := oversize + strings.Repeat("- another fix\n", 20)
+		updated, err := s.UpdatePackageVersion(context.Background(), "app", "1.0.0", &models.UpdatePackageVersionRequest{Changelog: &longer}, owner.ID)
+		if err != nil {
+			t.Fatalf("update with oversize changelog: %v", err)
+		}
+		if len(updated.Changelog) > limit || !strings.HasPrefix(updated.Changelog, "# 1.0.0\n- fixed a bug") {
+			t.Errorf("updated changelog = %d bytes %q, want truncated within %d bytes", len(updated.Changelog), updated.Changelog, limit)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"webservice/internal/logger"
+)
+
+// checksumReader 边读取边计算SHA256，读到末尾时与期望值比对
+// 不一致时记录错误并以ErrChecksumMismatch代替io.EOF返回，避免镜像中损坏的文件被当作完整内容
+type checksumReader struct {
+	io.ReadCloser
+	hasher   hash.Hash
+	expected string
+}
+
+// newChecksumReader 包装读取器，expected为十六进制SHA256
+func newChecksumReader(rc io.ReadCloser, expected string) io.ReadCloser {
+	return &checksumReader{ReadCloser: rc, hasher: sha256.New(), expected: expected}
+}
+
+// Read 读取数据并更新摘要
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hasher.Write(p[:n])
+	if err == io.EOF {
+		if actual := hex.EncodeToString(r.hasher.Sum(nil)); actual != r.expected {
+			logger.Errorf("Package checksum mismatch: expected %s, got %s", r.expected, actual)
+			return n, ErrChecksumMismatch
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestChecksumReader(t *testing.T) {
+	content := "package bytes"
+	sum := sha256.Sum256([]byte(content))
+	expected := hex.EncodeToString(sum[:])
+
+	data, err := io.ReadAll(newChecksumReader(io.NopCloser(strings.NewReader(content)), expected))
+	if err != nil || string(data) != content {
+		t.Errorf("matching checksum: data = %q, err = %v", data, err)
+	}
+
+	// 镜像中的文件损坏时读到末尾返回错误，而不是当作完整内容
+	_, err = io.ReadAll(newChecksumReader(io.NopCloser(strings.NewReader(content+"!")), expected))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("corrupted content: err = %v, want ErrChecksumMismatch", err)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"webservice/internal/database"
+	"webservice/internal/metrics"
+)
+
+// coalescedReads 因相同的并发读取而共享查询结果的请求数，按操作区分
+var coalescedReads = metrics.NewCounterVec(
+	"package_read_coalesced_total",
+	"Total number of package metadata reads served by an in-flight identical query.",
+	"operation",
+)
+
+// flightCall 正在执行的一次查询
+type flightCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+// flightGroup 合并相同key的并发读取：查询执行期间到达的调用方等待并共享同一结果，查询结束后不保留结果
+// 零值可以直接使用
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// do 执行key对应的查询，已有相同key的查询在执行时等待其结果
+// 查询使用与调用方取消信号分离的上下文，某个调用方放弃等待（ctx取消）只会让该调用方返回ctx.Err()，不会中断其他调用方共享的查询
+// 返回的值由所有调用方共享，调用方需要修改时应先复制，并在得到结果后按各自的身份检查访问权限
+func (g *flightGroup[T]) do(ctx context.Context, operation, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	call, inFlight := g.calls[key]
+	if !inFlight {
+		call = &flightCall[T]{done: make(chan struct{})}
+		g.calls[key] = call
+		go g.run(sharedContext(ctx), key, call, fn)
+	}
+	g.mu.Unlock()
+
+	if inFlight {
+		coalescedReads.Inc(operation)
+	}
+
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// sharedContext 共享查询使用的上下文：与发起查询的调用方的取消信号分离，并替换其行级安全范围
+// 查询结果会返回给所有等待的调用方，不能按第一个调用方的身份过滤，因此按系统范围查询，由各调用方在得到结果后检查权限
+func sharedContext(ctx context.Context) context.Context {
+	return database.WithRowScope(context.WithoutCancel(ctx), database.RowScope{Admin: true})
+}
+
+// run 执行查询并唤醒所有等待的调用方
+func (g *flightGroup[T]) run(ctx context.Context, key string, call *flightCall[T], fn func(ctx context.Context) (T, error)) {
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.val, call.err = fn(ctx)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// countPackageQueries 统计对packages表的查询次数，每次查询延迟delay使并发的调用方能够赶上同一次查询
+func countPackageQueries(t *testing.T, db *gorm.DB, delay time.Duration) *int64 {
+	t.Helper()
+	var count int64
+	err := db.Callback().Query().Before("gorm:query").Register("test:count_package_queries", func(tx *gorm.DB) {
+		if tx.Statement.Table == "packages" {
+			atomic.AddInt64(&count, 1)
+			time.Sleep(delay)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &count
+}
+
+func TestGetPackageCoalescesConcurrentReaders(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "popular", owner, false)
+	s := newTestPackageService(t, db)
+	queries := countPackageQueries(t, db, 100*time.Millisecond)
+
+	errs := runConcurrently(100, func(int) error {
+		pkg, err := s.GetPackage(context.Background(), "popular", nil)
+		if err == nil {
+			// 每个调用方得到独立的副本
+			pkg.Description = "modified"
+		}
+		return err
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("GetPackage: %v", err)
+		}
+	}
+	if got := atomic.LoadInt64(queries); got > 2 {
+		t.Errorf("100 concurrent readers ran %d package queries, want about 1", got)
+	}
+
+	pkg, err := s.GetPackage(context.Background(), "popular", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Description == "modified" {
+		t.Error("a caller's change leaked into the shared result")
+	}
+}
+
+func TestCoalescedReadsCheckAccessPerCaller(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	other := createTestUser(t, db, "bob", models.RoleUser)
+	createTestPackage(t, db, "secret", owner, true)
+	s := newTestPackageService(t, db)
+	countPackageQueries(t, db, 100*time.Millisecond)
+
+	// 所有者和其他调用方同时读取，共享同一次查询，只有所有者得到结果
+	viewers := []*uint{&owner.ID, nil, &other.ID}
+	pkgErrs := runConcurrently(len(viewers)*10, func(i int) error {
+		_, err := s.GetPackage(context.Background(), "secret", viewers[i%len(viewers)])
+		return err
+	})
+	versionErrs := runConcurrently(len(viewers)*10, func(i int) error {
+		_, err := s.GetPackageVersions(context.Background(), "secret", 1, 20, viewers[i%len(viewers)])
+		return err
+	})
+	for name, errs := range map[string][]error{"GetPackage": pkgErrs, "GetPackageVersions": versionErrs} {
+		for i, err := range errs {
+			if viewers[i%len(viewers)] == &owner.ID {
+				if err != nil {
+					t.Errorf("%s by owner: %v", name, err)
+				}
+			} else if err == nil {
+				t.Errorf("%s returned a private package to a non-owner", name)
+			}
+		}
+	}
+}
+
+func TestCoalescedReadSurvivesCallerCancellation(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "popular", owner, false)
+	s := newTestPackageService(t, db)
+	countPackageQueries(t, db, 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := s.GetPackage(ctx, "popular", nil)
+		first <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	second := make(chan error, 1)
+	go func() {
+		_, err := s.GetPackage(context.Background(), "popular", nil)
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-first; err != context.Canceled {
+		t.Errorf("cancelled caller got %v, want context.Canceled", err)
+	}
+	if err := <-second; err != nil {
+		t.Errorf("waiting caller failed after another caller gave up: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 冷存储默认配置
+const (
+	defaultColdTierAfter             = 90 * 24 * time.Hour
+	defaultColdTierKeepRecent        = 5
+	defaultColdTierRestoreRetryAfter = 30 * time.Second
+	coldTierRestoreStuckAfter        = 10 * time.Minute // restoring状态超过该时间视为恢复中断（如进程重启），由分层任务重新恢复
+)
+
+// ErrVersionRestoring 版本文件正在从冷存储移回主bucket，返回*VersionRestoringError
+var ErrVersionRestoring = errors.New("package version is being restored from cold storage")
+
+// VersionRestoringError 版本文件正在从冷存储恢复，附带建议的重试间隔
+type VersionRestoringError struct {
+	RetryAfter time.Duration
+}
+
+// Error 实现error接口
+func (e *VersionRestoringError) Error() string {
+	return ErrVersionRestoring.Error()
+}
+
+// Unwrap 返回ErrVersionRestoring
+func (e *VersionRestoringError) Unwrap() error {
+	return ErrVersionRestoring
+}
+
+// coldTierPolicy 填充默认值后的冷存储配置
+type coldTierPolicy struct {
+	after        time.Duration
+	keepRecent   int
+	downloadMode string
+	retryAfter   time.Duration
+}
+
+// newColdTierPolicy 根据配置创建冷存储策略，未配置的项使用默认值
+func newColdTierPolicy(cfg config.ColdTierConfig) coldTierPolicy {
+	policy := coldTierPolicy{
+		after:        cfg.After,
+		keepRecent:   cfg.KeepRecent,
+		downloadMode: strings.ToLower(cfg.DownloadMode),
+		retryAfter:   cfg.RestoreRetryAfter,
+	}
+	if policy.after <= 0 {
+		policy.after = defaultColdTierAfter
+	}
+	if policy.keepRecent <= 0 {
+		policy.keepRecent = defaultColdTierKeepRecent
+	}
+	if policy.downloadMode != minio.ColdDownloadRestore {
+		policy.downloadMode = minio.ColdDownloadTransparent
+	}
+	if policy.retryAfter <= 0 {
+		policy.retryAfter = defaultColdTierRestoreRetryAfter
+	}
+	return policy
+}
+
+// objectStore 返回版本文件所在bucket的客户端
+func objectStore(client *minio.Client, v *models.PackageVersion) *minio.Client {
+	if v.InColdStorage() {
+		return client.Cold()
+	}
+	return client
+}
+
+// tierMove 一次冷热存储迁移
+type tierMove struct {
+	ID          uint
+	PackageName string
+	Version     string
+	MinIOPath   string
+	StorageTier string // 迁移前的状态，只在记录仍处于该状态时更新
+}
+
+// moveVersionTier 在主bucket和冷存储bucket之间迁移版本文件：复制对象、条件更新记录、删除源对象
+// 记录更新失败（包括被并发修改）时删除已复制的目标对象，源对象保持不变；成功后记录层级变更和审计日志
+func moveVersionTier(ctx context.Context, db *gorm.DB, client *minio.Client, move tierMove, toCold bool, actorID uint) error {
+	src, dst := client, client.Cold()
+	fromTier, toTier, direction := models.StorageTierHot, models.StorageTierCold, TierDirectionDemote
+	if !toCold {
+		src, dst = dst, src
+		fromTier, toTier, direction = models.StorageTierCold, models.StorageTierHot, TierDirectionPromote
+	}
+
+	if err := src.TransferObject(ctx, dst, move.MinIOPath); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		update := tx.Model(&models.PackageVersion{}).
+			Where("id = ? AND storage_tier = ? AND min_io_path = ?", move.ID, move.StorageTier, move.MinIOPath).
+			Updates(map[string]interface{}{"storage_tier": toTier, "storage_tier_changed_at": now})
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return errors.New("package version was changed concurrently")
+		}
+		return tx.Create(&models.StorageTierChange{
+			PackageVersionID: move.ID,
+			FromTier:         fromTier,
+			ToTier:           toTier,
+			Direction:        direction,
+		}).Error
+	})
+	if err != nil {
+		if delErr := dst.DeleteObject(ctx, move.MinIOPath); delErr != nil {
+			logger.Warnf("Failed to remove copied object %s after rollback: %v", move.MinIOPath, delErr)
+		}
+		return fmt.Errorf("failed to record storage tier change: %w", err)
+	}
+
+	// 记录已指向新位置，源对象删除失败只会残留文件
+	if err := src.DeleteObject(ctx, move.MinIOPath); err != nil {
+		logger.Warnf("Failed to delete %s object %s after moving to %s: %v", fromTier, move.MinIOPath, toTier, err)
+	}
+
+	storageTierTransitions.Inc(direction)
+	logger.Infof("Storage tier %s: %s@%s %s -> %s", direction, move.PackageName, move.Version, fromTier, toTier)
+
+	details := map[string]interface{}{"from": fromTier, "to": toTier, "object_key": move.MinIOPath}
+	resource := "packages/" + move.PackageName + "/versions/" + move.Version
+	if err := NewAuditService(db).Record(ctx, actorID, "versions.storage_tier", resource, details, ""); err != nil {
+		logger.Warnf("Failed to record audit log for storage tier change of %s@%s: %v", move.PackageName, move.Version, err)
+	}
+	return nil
+}
+
+// ColdTieringResult 一次冷存储迁移的结果
+type ColdTieringResult struct {
+	Demoted  int `json:"demoted"`
+	Restored int `json:"restored"` // 重新完成的中断恢复
+	Failed   int `json:"failed"`
+}
+
+// ApplyColdTiering 将长期未下载的旧版本移入冷存储，并重新恢复中断的restoring版本
+// 候选版本：发布早于after、after内没有下载、未被置顶、不属于包最新的keep_recent个版本，且最近一次迁移早于after
+func (s *StorageTieringService) ApplyColdTiering(ctx context.Context) (*ColdTieringResult, error) {
+	if s.minioClient == nil {
+		return nil, errors.New("file storage is not available")
+	}
+	result := &ColdTieringResult{}
+	if !s.minioClient.HasColdTier() {
+		return result, nil
+	}
+
+	policy := newColdTierPolicy(s.minioClient.ColdTierConfig())
+	now := time.Now().UTC()
+	cutoff := now.Add(-policy.after)
+
+	var candidates []tierMove
+	err := s.db.WithContext(ctx).Table("package_versions AS pv").
+		Select("pv.id, p.name AS package_name, pv.version, pv.min_io_path, pv.storage_tier").
+		Joins("JOIN packages p ON p.id = pv.package_id AND p.deleted_at IS NULL").
+		Where("pv.deleted_at IS NULL AND pv.min_io_path <> '' AND pv.storage_tier = ? AND pv.created_at < ?", models.StorageTierHot, cutoff).
+		Where("pv.storage_tier_changed_at IS NULL OR pv.storage_tier_changed_at < ?", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM package_downloads pd WHERE pd.package_version_id = pv.id AND pd.download_time >= ?)", cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM package_version_pins pin WHERE pin.package_version_id = pv.id)").
+		Where(`(SELECT COUNT(*) FROM package_versions newer
+			WHERE newer.package_id = pv.package_id AND newer.deleted_at IS NULL AND newer.created_at > pv.created_at) >= ?`, policy.keepRecent).
+		Order("pv.id").
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to select versions for cold storage: %w", err)
+	}
+
+	for _, move := range candidates {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err := moveVersionTier(ctx, s.db, s.minioClient, move, true, 0); err != nil {
+			logger.Warnf("Failed to move %s@%s to cold storage: %v", move.PackageName, move.Version, err)
+			result.Failed++
+			continue
+		}
+		result.Demoted++
+	}
+
+	// 恢复过程中进程退出的版本停留在restoring，文件仍在冷存储，重新移回主bucket
+	var stuck []tierMove
+	err = s.db.WithContext(ctx).Table("package_versions AS pv").
+		Select("pv.id, p.name AS package_name, pv.version, pv.min_io_path, pv.storage_tier").
+		Joins("JOIN packages p ON p.id = pv.package_id").
+		Where("pv.deleted_at IS NULL AND pv.storage_tier = ? AND pv.storage_tier_changed_at < ?", models.StorageTierRestoring, now.Add(-coldTierRestoreStuckAfter)).
+		Scan(&stuck).Error
+	if err != nil {
+		return result, fmt.Errorf("failed to select interrupted restores: %w", err)
+	}
+	for _, move := range stuck {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err := moveVersionTier(ctx, s.db, s.minioClient, move, false, 0); err != nil {
+			logger.Warnf("Failed to restore %s@%s from cold storage: %v", move.PackageName, move.Version, err)
+			result.Failed++
+			continue
+		}
+		result.Restored++
+	}
+
+	return result, nil
+}
+
+// checkColdDownload 检查冷存储版本能否直接下载
+// transparent模式下直接从冷存储读取；restore模式下首次请求将版本标记为restoring并在后台移回主bucket，
+// 恢复完成前返回*VersionRestoringError
+func (s *PackageService) checkColdDownload(ctx context.Context, v *models.PackageVersion, userID *uint) error {
+	if !v.InColdStorage() {
+		return nil
+	}
+	policy := newColdTierPolicy(s.minioClient.ColdTierConfig())
+	if policy.downloadMode != minio.ColdDownloadRestore {
+		return nil
+	}
+
+	if v.StorageTier == models.StorageTierCold {
+		// 条件更新保证并发请求只触发一次恢复
+		update := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+			Where("id = ? AND storage_tier = ?", v.ID, models.StorageTierCold).
+			Updates(map[string]interface{}{"storage_tier": models.StorageTierRestoring, "storage_tier_changed_at": time.Now().UTC()})
+		if update.Error != nil {
+			return fmt.Errorf("failed to start restore from cold storage: %w", update.Error)
+		}
+		if update.RowsAffected > 0 {
+			var actorID uint
+			if userID != nil {
+				actorID = *userID
+			}
+			move := tierMove{ID: v.ID, PackageName: v.Package.Name, Version: v.Version, MinIOPath: v.MinIOPath, StorageTier: models.StorageTierRestoring}
+			go s.restoreFromCold(move, actorID)
+		}
+	}
+	return &VersionRestoringError{RetryAfter: policy.retryAfter}
+}
+
+// restoreFromCold 将版本文件移回主bucket，失败时恢复为cold状态，下次下载重新触发
+func (s *PackageService) restoreFromCold(move tierMove, actorID uint) {
+	ctx := context.Background()
+	if err := moveVersionTier(ctx, s.db, s.minioClient, move, false, actorID); err != nil {
+		logger.Warnf("Failed to restore %s@%s from cold storage: %v", move.PackageName, move.Version, err)
+		if err := s.db.Model(&models.PackageVersion{}).
+			Where("id = ? AND storage_tier = ?", move.ID, models.StorageTierRestoring).
+			Update("storage_tier", models.StorageTierCold).Error; err != nil {
+			logger.Warnf("Failed to reset storage tier of %s@%s: %v", move.PackageName, move.Version, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+func TestApplyColdTieringMovesOldVersions(t *testing.T) {
+	storage := testutil.NewStorage(t, func(cfg *config.MinIOConfig) {
+		cfg.ColdTier = config.ColdTierConfig{Bucket: "packages-cold", After: time.Hour, KeepRecent: 1}
+	})
+	s := NewPackageService(newTestDB(t), storage, nil, config.PackagesConfig{})
+	ctx := context.Background()
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+	old, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := uploadTestVersion(s, pkg, "2.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 两个版本都早于after，最新的一个由keep_recent保留在主bucket
+	for i, v := range []*models.PackageVersion{old, latest} {
+		createdAt := time.Now().Add(-time.Duration(3-i) * time.Hour)
+		if err := s.db.Model(v).Update("created_at", createdAt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := NewStorageTieringService(s.db, storage).ApplyColdTiering(ctx)
+	if err != nil {
+		t.Fatalf("ApplyColdTiering: %v", err)
+	}
+	if result.Demoted != 1 || result.Failed != 0 {
+		t.Fatalf("result = %+v, want 1 demoted", result)
+	}
+
+	var moved models.PackageVersion
+	if err := s.db.First(&moved, old.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if moved.StorageTier != models.StorageTierCold {
+		t.Errorf("1.0.0 storage tier = %q, want cold", moved.StorageTier)
+	}
+	if exists, err := storage.ObjectExists(ctx, old.MinIOPath); err != nil || exists {
+		t.Errorf("1.0.0 still in the hot bucket: exists = %v, err = %v", exists, err)
+	}
+	if exists, err := storage.Cold().ObjectExists(ctx, old.MinIOPath); err != nil || !exists {
+		t.Errorf("1.0.0 missing from the cold bucket: exists = %v, err = %v", exists, err)
+	}
+
+	// 默认transparent模式直接从冷存储读取
+	reader, _, err := s.DownloadPackageVersion(ctx, pkg.Name, "1.0.0", nil, "127.0.0.1", "test", false)
+	if err != nil {
+		t.Fatalf("download cold version: %v", err)
+	}
+	defer reader.Close()
+	if content, err := io.ReadAll(reader); err != nil || string(content) != "content of app@1.0.0" {
+		t.Errorf("cold download = %q, %v", content, err)
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// findCollaboratorPackage 查找包并检查调用方对其的权限
+func (s *PackageService) findCollaboratorPackage(ctx context.Context, packageName string, caller PackageCaller, right string) (*models.Package, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, caller, right); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// ListCollaborators 列出包的协作者及其角色（按添加时间），能读取包的用户都可以查看
+func (s *PackageService) ListCollaborators(ctx context.Context, packageName string, caller PackageCaller) ([]models.CollaboratorInfo, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ListCollaborators")
+	defer span.Finish()
+
+	pkg, err := s.findCollaboratorPackage(ctx, packageName, caller, PackageRightRead)
+	if err != nil {
+		return nil, err
+	}
+
+	var collaborators []models.PackageCollaborator
+	if err := s.db.WithContext(ctx).Preload("User").Where("package_id = ?", pkg.ID).Order("id").Find(&collaborators).Error; err != nil {
+		return nil, fmt.Errorf("failed to list collaborators: %w", err)
+	}
+	infos := make([]models.CollaboratorInfo, 0, len(collaborators))
+	for _, c := range collaborators {
+		infos = append(infos, models.CollaboratorInfo{Username: c.User.Username, Role: c.Role, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt})
+	}
+	return infos, nil
+}
+
+// SetCollaborator 添加协作者或修改已有协作者的角色，需要manage_collaborators权限
+func (s *PackageService) SetCollaborator(ctx context.Context, packageName, username, role string, caller PackageCaller) (*models.CollaboratorInfo, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.SetCollaborator")
+	defer span.Finish()
+
+	pkg, err := s.findCollaboratorPackage(ctx, packageName, caller, PackageRightManageCollaborators)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.findCollaboratorUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == pkg.OwnerID {
+		return nil, ErrCollaboratorIsOwner
+	}
+
+	collaborator := models.PackageCollaborator{PackageID: pkg.ID, UserID: user.ID, Role: role}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "package_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&collaborator).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save collaborator: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Where("package_id = ? AND user_id = ?", pkg.ID, user.ID).First(&collaborator).Error; err != nil {
+		return nil, fmt.Errorf("failed to load collaborator: %w", err)
+	}
+	return &models.CollaboratorInfo{Username: user.Username, Role: collaborator.Role, CreatedAt: collaborator.CreatedAt, UpdatedAt: collaborator.UpdatedAt}, nil
+}
+
+// RemoveCollaborator 移除协作者，用户不是协作者时不报错；需要manage_collaborators权限
+func (s *PackageService) RemoveCollaborator(ctx context.Context, packageName, username string, caller PackageCaller) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.RemoveCollaborator")
+	defer span.Finish()
+
+	pkg, err := s.findCollaboratorPackage(ctx, packageName, caller, PackageRightManageCollaborators)
+	if err != nil {
+		return err
+	}
+	user, err := s.findCollaboratorUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Where("package_id = ? AND user_id = ?", pkg.ID, user.ID).Delete(&models.PackageCollaborator{}).Error; err != nil {
+		return fmt.Errorf("failed to remove collaborator: %w", err)
+	}
+	return nil
+}
+
+// findCollaboratorUser 按用户名查找协作者用户
+func (s *PackageService) findCollaboratorUser(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDeleteBusy 同时执行的删除事务数已达上限，且在等待时间内没有空闲名额
+var ErrDeleteBusy = errors.New("too many deletes in progress")
+
+// deleteLimiter 限制同时执行的删除事务数，避免批量删除占满数据库连接池
+type deleteLimiter struct {
+	slots chan struct{}
+	wait  time.Duration // 名额已满时的最长排队时间，0表示立即失败
+}
+
+// newDeleteLimiter 按配置创建删除限流，maxConcurrent不大于0时不限制（返回nil）
+func newDeleteLimiter(maxConcurrent int, wait time.Duration) *deleteLimiter {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return &deleteLimiter{slots: make(chan struct{}, maxConcurrent), wait: wait}
+}
+
+// acquire 获取一个删除名额，最多排队wait，请求取消或超时时返回ErrDeleteBusy
+func (l *deleteLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if l.wait == 0 {
+		return nil, ErrDeleteBusy
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrDeleteBusy
+	case <-ctx.Done():
+		return nil, ErrDeleteBusy
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+// saturate 占满限流的全部名额，返回释放函数
+func saturate(t *testing.T, l *deleteLimiter) []func() {
+	t.Helper()
+	var releases []func()
+	for i := 0; i < cap(l.slots); i++ {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire slot %d: %v", i+1, err)
+		}
+		releases = append(releases, release)
+	}
+	return releases
+}
+
+func TestDeleteLimiterRejectsWhenSaturated(t *testing.T) {
+	l := newDeleteLimiter(2, 0)
+	releases := saturate(t, l)
+
+	// 不排队时第N+1个删除立即失败
+	start := time.Now()
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrDeleteBusy) {
+		t.Fatalf("acquire beyond limit err = %v, want ErrDeleteBusy", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("rejection took %v, want immediate", elapsed)
+	}
+
+	// 释放一个名额后可以再次获取
+	releases[0]()
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	release()
+	releases[1]()
+}
+
+func TestDeleteLimiterQueueTimeout(t *testing.T) {
+	l := newDeleteLimiter(1, 50*time.Millisecond)
+	defer saturate(t, l)[0]()
+
+	start := time.Now()
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrDeleteBusy) {
+		t.Fatalf("acquire err = %v, want ErrDeleteBusy after the queue timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %v, want about the 50ms queue timeout", elapsed)
+	}
+}
+
+func TestDeleteLimiterQueuedDeleteProceedsOnRelease(t *testing.T) {
+	l := newDeleteLimiter(1, 5*time.Second)
+	release := saturate(t, l)[0]
+
+	acquired := make(chan error, 1)
+	go func() {
+		next, err := l.acquire(context.Background())
+		if err == nil {
+			next()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("queued acquire returned %v before a slot was released", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Errorf("queued acquire err = %v, want a slot", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued acquire did not proceed after release")
+	}
+}
+
+func TestDeleteLimiterStopsWaitingWhenRequestEnds(t *testing.T) {
+	l := newDeleteLimiter(1, time.Minute)
+	defer saturate(t, l)[0]()
+
+	// 请求超时早于排队超时时按请求超时返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := l.acquire(ctx); !errors.Is(err, ErrDeleteBusy) {
+		t.Fatalf("acquire err = %v, want ErrDeleteBusy", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v after the request deadline", elapsed)
+	}
+}
+
+func TestDeleteLimiterDisabled(t *testing.T) {
+	if l := newDeleteLimiter(0, time.Second); l != nil {
+		t.Fatalf("newDeleteLimiter(0) = %+v, want nil", l)
+	}
+	var l *deleteLimiter
+	for i := 0; i < 100; i++ {
+		if _, err := l.acquire(context.Background()); err != nil {
+			t.Fatalf("unlimited acquire err = %v", err)
+		}
+	}
+}
+
+func TestDeletePackageVersionShedWhenSaturated(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "app", owner, false)
+	createTestVersion(t, db, pkg, "1.0.0", nil)
+	s := NewPackageService(db, nil, nil, config.PackagesConfig{MaxConcurrentDeletes: 1, DeleteQueueTimeout: 20 * time.Millisecond})
+
+	// 另一个删除占用唯一的名额，本次删除排队超时后失败，版本保留
+	release := saturate(t, s.deleteLimiter)[0]
+	if err := s.DeletePackageVersion(context.Background(), "app", "1.0.0", owner.ID); !errors.Is(err, ErrDeleteBusy) {
+		t.Fatalf("DeletePackageVersion err = %v, want ErrDeleteBusy", err)
+	}
+	if err := s.DeletePackage(context.Background(), "app", owner.ID); !errors.Is(err, ErrDeleteBusy) {
+		t.Fatalf("DeletePackage err = %v, want ErrDeleteBusy", err)
+	}
+	var count int64
+	db.Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Count(&count)
+	if count != 1 {
+		t.Fatalf("versions after shed delete = %d, want 1", count)
+	}
+
+	// 名额释放后删除成功，且删除结束后归还名额
+	release()
+	if err := s.DeletePackageVersion(context.Background(), "app", "1.0.0", owner.ID); err != nil {
+		t.Fatalf("DeletePackageVersion after release: %v", err)
+	}
+	if len(s.deleteLimiter.slots) != 0 {
+		t.Errorf("slots in use after delete = %d, want 0", len(s.deleteLimiter.slots))
+	}
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// 发布时依赖检查模式
+const (
+	DependencyCheckOff     = "off"
+	DependencyCheckWarn    = "warn"
+	DependencyCheckEnforce = "enforce"
+)
+
+// 未配置时的依赖检查上限
+const (
+	defaultDependencyCheckMaxDepth = 10
+	defaultDependencyCheckTimeout  = 3 * time.Second
+	dependencyCheckMaxSteps        = 1000 // 最多解析的包次数（重新选择版本会重复解析），防止约束反复变化时不收敛
+)
+
+// ErrDependencyConflict enforce模式下声明的依赖无法解析
+var ErrDependencyConflict = errors.New("dependency conflict")
+
+// DependencyConflictError 依赖无法解析，附带发现的问题
+type DependencyConflictError struct {
+	Conflicts []models.DependencyConflict
+}
+
+// Error 实现error接口
+func (e *DependencyConflictError) Error() string {
+	return fmt.Sprintf("%s: %d problem(s) found", ErrDependencyConflict.Error(), len(e.Conflicts))
+}
+
+// Unwrap 返回ErrDependencyConflict
+func (e *DependencyConflictError) Unwrap() error {
+	return ErrDependencyConflict
+}
+
+// checkDependencies 按配置检查新版本声明的依赖能否解析为每个包一个版本
+// 返回发现的问题，由调用方记录到版本中；enforce模式下有冲突时返回*DependencyConflictError
+// 解析达到深度、步数或时间上限时只记录incomplete警告，不拒绝上传
+func (s *PackageService) checkDependencies(ctx context.Context, pkg *models.Package, version string, deps map[string]string, uploaderID uint) ([]models.DependencyConflict, error) {
+	if s.dependencyCheck != DependencyCheckWarn && s.dependencyCheck != DependencyCheckEnforce {
+		return nil, nil
+	}
+	if len(deps) == 0 {
+		return nil, nil
+	}
+
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.checkDependencies")
+	defer span.Finish()
+
+	ctx, cancel := context.WithTimeout(ctx, s.dependencyCheckTimeout)
+	defer cancel()
+
+	r := newDependencyResolver(s.db.WithContext(ctx), uploaderID, s.dependencyCheckMaxDepth)
+	conflicts, err := r.resolve(ctx, pkg.Name, version, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.dependencyCheck == DependencyCheckEnforce {
+		for _, conflict := range conflicts {
+			if conflict.Reason != models.DependencyConflictIncomplete {
+				return nil, &DependencyConflictError{Conflicts: conflicts}
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// dependencyCandidate 依赖包的一个可选版本
+type dependencyCandidate struct {
+	version string
+	semver  semVersion
+	deps    map[string]string
+}
+
+// dependencyRequirement 依赖方对某个包的版本约束
+type dependencyRequirement struct {
+	from       string // 包名@版本
+	raw        string
+	constraint versionConstraint
+}
+
+// dependencyResolver 有界的依赖解析器
+// 从根版本开始逐层为每个包选择满足所有已知约束的最高版本，选择变化时撤销旧版本带来的约束并重新解析受影响的包
+// 不做完整的回溯搜索，结果可能把可解的依赖报告为冲突，但不会漏报根版本直接声明的不可满足约束
+type dependencyResolver struct {
+	db         *gorm.DB
+	uploaderID uint
+	maxDepth   int
+
+	candidates map[string][]dependencyCandidate // 包名 -> 按版本降序的可选版本，nil表示包不存在或不可见
+	reqs       map[string][]dependencyRequirement
+	selected   map[string]*dependencyCandidate
+	depth      map[string]int
+	queue      []string
+
+	root       string // 发布中的包名，版本固定为新版本
+	rootSemver bool   // 新版本是否为语义化版本，否则不检查其他包对发布中的包的约束
+	problems   []models.DependencyConflict
+	incomplete string // 解析未完成的原因
+}
+
+// newDependencyResolver 创建依赖解析器，只考虑公开包和上传者自己的私有包
+func newDependencyResolver(db *gorm.DB, uploaderID uint, maxDepth int) *dependencyResolver {
+	return &dependencyResolver{
+		db:         db,
+		uploaderID: uploaderID,
+		maxDepth:   maxDepth,
+		candidates: make(map[string][]dependencyCandidate),
+		reqs:       make(map[string][]dependencyRequirement),
+		selected:   make(map[string]*dependencyCandidate),
+		depth:      make(map[string]int),
+	}
+}
+
+// resolve 解析root@version及其依赖，返回发现的问题（按包名排序）
+func (r *dependencyResolver) resolve(ctx context.Context, root, version string, deps map[string]string) ([]models.DependencyConflict, error) {
+	rootVersion, ok := parseSemver(version)
+	r.root = root
+	r.rootSemver = ok
+	r.selected[root] = &dependencyCandidate{version: version, semver: rootVersion, deps: deps}
+	r.addRequirements(root+"@"+version, deps, 1)
+
+	for steps := 0; len(r.queue) > 0; steps++ {
+		if steps >= dependencyCheckMaxSteps {
+			r.incomplete = fmt.Sprintf("stopped after resolving %d packages", dependencyCheckMaxSteps)
+			break
+		}
+		if ctx.Err() != nil {
+			r.incomplete = "dependency check timed out"
+			break
+		}
+		name := r.queue[0]
+		r.queue = r.queue[1:]
+		if err := r.selectVersion(name); err != nil {
+			if ctx.Err() != nil {
+				r.incomplete = "dependency check timed out"
+				break
+			}
+			return nil, err
+		}
+	}
+
+	return r.report(), nil
+}
+
+// addRequirements 记录from声明的依赖约束并将依赖的包加入解析队列
+func (r *dependencyResolver) addRequirements(from string, deps map[string]string, depth int) {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw := deps[name]
+		constraint, err := parseVersionConstraint(raw)
+		if err != nil {
+			r.problems = append(r.problems, models.DependencyConflict{
+				Package:      name,
+				Reason:       models.DependencyConflictInvalidConstraint,
+				Message:      err.Error(),
+				Requirements: []models.DependencyRequirement{{RequiredBy: from, Constraint: raw}},
+			})
+			continue
+		}
+		r.reqs[name] = append(r.reqs[name], dependencyRequirement{from: from, raw: raw, constraint: constraint})
+		if d, ok := r.depth[name]; !ok || depth < d {
+			r.depth[name] = depth
+		}
+		r.queue = append(r.queue, name)
+	}
+}
+
+// dropRequirements 撤销from声明的约束，受影响的包重新解析
+func (r *dependencyResolver) dropRequirements(from string) {
+	for name, reqs := range r.reqs {
+		kept := reqs[:0]
+		for _, req := range reqs {
+			if req.from != from {
+				kept = append(kept, req)
+			}
+		}
+		if len(kept) != len(reqs) {
+			r.reqs[name] = kept
+			r.queue = append(r.queue, name)
+		}
+	}
+}
+
+// selectVersion 为包选择满足所有约束的最高版本，选择变化时更新其依赖带来的约束
+func (r *dependencyResolver) selectVersion(name string) error {
+	if name == r.root {
+		return nil
+	}
+
+	prev := r.selected[name]
+	var best *dependencyCandidate
+	if len(r.reqs[name]) > 0 {
+		candidates, err := r.load(name)
+		if err != nil {
+			return err
+		}
+		for i := range candidates {
+			if r.satisfiesAll(name, candidates[i].semver) {
+				best = &candidates[i]
+				break
+			}
+		}
+	}
+
+	if best == prev {
+		return nil
+	}
+	if prev != nil {
+		r.dropRequirements(name + "@" + prev.version)
+		delete(r.selected, name)
+	}
+	if best == nil {
+		return nil
+	}
+
+	r.selected[name] = best
+	if len(best.deps) == 0 {
+		return nil
+	}
+	if r.depth[name] >= r.maxDepth {
+		r.incomplete = fmt.Sprintf("dependency tree is deeper than %d levels", r.maxDepth)
+		return nil
+	}
+	r.addRequirements(name+"@"+best.version, best.deps, r.depth[name]+1)
+	return nil
+}
+
+// satisfiesAll 版本是否满足对该包的所有约束
+func (r *dependencyResolver) satisfiesAll(name string, v semVersion) bool {
+	for _, req := range r.reqs[name] {
+		if !req.constraint.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// load 读取包的所有语义化版本（按版本降序），结果在本次解析中缓存
+func (r *dependencyResolver) load(name string) ([]dependencyCandidate, error) {
+	if candidates, ok := r.candidates[name]; ok {
+		return candidates, nil
+	}
+
+	var pkg models.Package
+	err := r.db.Select("id").
+		Where("name = ? AND (is_private = ? OR owner_id = ?)", name, false, r.uploaderID).
+		First(&pkg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.candidates[name] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dependency %s: %w", name, err)
+	}
+
+	var versions []models.PackageVersion
+	if err := r.db.Select("version, dependencies, published_manifest").Where("package_id = ?", pkg.ID).Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list versions of dependency %s: %w", name, err)
+	}
+
+	candidates := make([]dependencyCandidate, 0, len(versions))
+	for _, v := range versions {
+		sv, ok := parseSemver(v.Version)
+		if !ok {
+			continue
+		}
+		// 按发布时的快照解析，之后修改版本元数据不影响解析结果
+		candidate := dependencyCandidate{version: v.Version, semver: sv, deps: v.PublishedDependencies()}
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return compareSemver(candidates[i].semver, candidates[j].semver) > 0
+	})
+	r.candidates[name] = candidates
+	return candidates, nil
+}
+
+// report 汇总解析结束时仍无法满足的约束
+func (r *dependencyResolver) report() []models.DependencyConflict {
+	names := make([]string, 0, len(r.reqs))
+	for name := range r.reqs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := r.problems
+	for _, name := range names {
+		reqs := r.reqs[name]
+		if len(reqs) == 0 || (name == r.root && !r.rootSemver) {
+			continue
+		}
+		if selected := r.selected[name]; selected != nil && r.satisfiesAll(name, selected.semver) {
+			continue
+		}
+
+		conflict := models.DependencyConflict{Package: name, Requirements: make([]models.DependencyRequirement, 0, len(reqs))}
+		for _, req := range reqs {
+			conflict.Requirements = append(conflict.Requirements, models.DependencyRequirement{RequiredBy: req.from, Constraint: req.raw})
+		}
+
+		candidates, loaded := r.candidates[name]
+		switch {
+		case name == r.root:
+			conflict.Reason = models.DependencyConflictUnsatisfiable
+			conflict.Message = fmt.Sprintf("published version %s does not satisfy all constraints on %s", r.selected[name].version, name)
+		case loaded && candidates == nil:
+			conflict.Reason = models.DependencyConflictNotFound
+			conflict.Message = fmt.Sprintf("package %s does not exist", name)
+		case !loaded:
+			// 解析提前结束，尚未读取该包
+			continue
+		case r.anyUnmatched(name, candidates):
+			conflict.Reason = models.DependencyConflictNoMatch
+			conflict.Message = fmt.Sprintf("no version of %s satisfies the constraint", name)
+		default:
+			conflict.Reason = models.DependencyConflictUnsatisfiable
+			conflict.Message = fmt.Sprintf("no single version of %s satisfies all constraints", name)
+		}
+		problems = append(problems, conflict)
+	}
+
+	if r.incomplete != "" {
+		problems = append(problems, models.DependencyConflict{
+			Reason:  models.DependencyConflictIncomplete,
+			Message: r.incomplete,
+		})
+	}
+	return problems
+}
+
+// anyUnmatched 是否有某个约束单独就没有满足的版本
+func (r *dependencyResolver) anyUnmatched(name string, candidates []dependencyCandidate) bool {
+	for _, req := range r.reqs[name] {
+		matched := false
+		for _, candidate := range candidates {
+			if req.constraint.matches(candidate.semver) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return true
+		}
+	}
+	return false
+}
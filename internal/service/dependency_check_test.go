@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// dependencyGraph 包名 -> 版本 -> 该版本声明的依赖
+type dependencyGraph map[string]map[string]map[string]string
+
+// createDependencyGraph 按图创建公开包及其版本
+func createDependencyGraph(t *testing.T, db *gorm.DB, owner *models.User, graph dependencyGraph) {
+	t.Helper()
+	for name, versions := range graph {
+		pkg := createTestPackage(t, db, name, owner, false)
+		for version, deps := range versions {
+			raw, err := json.Marshal(deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			createTestVersion(t, db, pkg, version, func(v *models.PackageVersion) {
+				v.Dependencies = string(raw)
+			})
+		}
+	}
+}
+
+// chainGraph 创建p0 -> p1 -> ... -> p(n-1)的依赖链
+func chainGraph(n int) dependencyGraph {
+	graph := dependencyGraph{}
+	for i := 0; i < n; i++ {
+		var deps map[string]string
+		if i+1 < n {
+			deps = map[string]string{fmt.Sprintf("p%d", i+1): "^1.0.0"}
+		}
+		graph[fmt.Sprintf("p%d", i)] = map[string]map[string]string{"1.0.0": deps}
+	}
+	return graph
+}
+
+func TestCheckDependenciesReport(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph dependencyGraph
+		deps  map[string]string
+		want  []models.DependencyConflict
+	}{
+		{
+			name: "cycle between dependencies",
+			graph: dependencyGraph{
+				"a": {"1.0.0": {"b": "^1.0.0"}},
+				"b": {"1.0.0": {"a": "^1.0.0"}},
+			},
+			deps: map[string]string{"a": "^1.0.0"},
+		},
+		{
+			name: "cycle back to the published version",
+			graph: dependencyGraph{
+				"a": {"1.0.0": {"app": "^1.0.0"}},
+			},
+			deps: map[string]string{"a": "^1.0.0"},
+		},
+		{
+			name: "cycle back to the published version with an unsatisfied constraint",
+			graph: dependencyGraph{
+				"a": {"1.0.0": {"b": "^1.0.0"}},
+				"b": {"1.0.0": {"app": "^2.0.0"}},
+			},
+			deps: map[string]string{"a": "^1.0.0"},
+			want: []models.DependencyConflict{{
+				Package:      "app",
+				Reason:       models.DependencyConflictUnsatisfiable,
+				Message:      "published version 1.0.0 does not satisfy all constraints on app",
+				Requirements: []models.DependencyRequirement{{RequiredBy: "b@1.0.0", Constraint: "^2.0.0"}},
+			}},
+		},
+		{
+			name: "diamond with a common version",
+			graph: dependencyGraph{
+				"foo": {"1.0.0": {"bar": "^1.0.0"}},
+				"baz": {"1.0.0": {"bar": ">=1.2.0"}},
+				"bar": {"1.0.0": nil, "1.5.0": nil, "2.0.0": nil},
+			},
+			deps: map[string]string{"foo": "^1.0.0", "baz": "^1.0.0"},
+		},
+		{
+			name: "diamond conflict",
+			graph: dependencyGraph{
+				"foo": {"1.0.0": {"bar": "^1.0.0"}},
+				"baz": {"1.0.0": {"bar": "^2.0.0"}},
+				"bar": {"1.0.0": nil, "2.0.0": nil},
+			},
+			deps: map[string]string{"foo": "^1.0.0", "baz": "^1.0.0"},
+			want: []models.DependencyConflict{{
+				Package: "bar",
+				Reason:  models.DependencyConflictUnsatisfiable,
+				Message: "no single version of bar satisfies all constraints",
+				Requirements: []models.DependencyRequirement{
+					{RequiredBy: "baz@1.0.0", Constraint: "^2.0.0"},
+					{RequiredBy: "foo@1.0.0", Constraint: "^1.0.0"},
+				},
+			}},
+		},
+		{
+			name: "diamond resolved by an older version of one side",
+			graph: dependencyGraph{
+				"foo": {"1.0.0": {"bar": "^1.0.0"}},
+				"baz": {"1.0.0": {"bar": "^1.0.0"}, "1.1.0": {"bar": "^2.0.0"}},
+				"bar": {"1.0.0": nil, "2.0.0": nil},
+			},
+			deps: map[string]string{"foo": "^1.0.0", "baz": "~1.0.0"},
+		},
+		{
+			name: "diamond conflict between the root and a transitive dependency",
+			graph: dependencyGraph{
+				"foo": {"1.0.0": {"bar": "^2.0.0"}},
+				"bar": {"1.0.0": nil, "2.0.0": nil},
+			},
+			deps: map[string]string{"foo": "^1.0.0", "bar": "^1.0.0"},
+			want: []models.DependencyConflict{{
+				Package: "bar",
+				Reason:  models.DependencyConflictUnsatisfiable,
+				Message: "no single version of bar satisfies all constraints",
+				Requirements: []models.DependencyRequirement{
+					{RequiredBy: "app@1.0.0", Constraint: "^1.0.0"},
+					{RequiredBy: "foo@1.0.0", Constraint: "^2.0.0"},
+				},
+			}},
+		},
+		{
+			name:  "missing package, no matching version and invalid constraint",
+			graph: dependencyGraph{"bar": {"1.0.0": nil}},
+			deps:  map[string]string{"ghost": "^1.0.0", "bar": "^3.0.0", "broken": ">>1"},
+			want: []models.DependencyConflict{
+				{
+					Package:      "broken",
+					Reason:       models.DependencyConflictInvalidConstraint,
+					Message:      constraintError(t, ">>1"),
+					Requirements: []models.DependencyRequirement{{RequiredBy: "app@1.0.0", Constraint: ">>1"}},
+				},
+				{
+					Package:      "bar",
+					Reason:       models.DependencyConflictNoMatch,
+					Message:      "no version of bar satisfies the constraint",
+					Requirements: []models.DependencyRequirement{{RequiredBy: "app@1.0.0", Constraint: "^3.0.0"}},
+				},
+				{
+					Package:      "ghost",
+					Reason:       models.DependencyConflictNotFound,
+					Message:      "package ghost does not exist",
+					Requirements: []models.DependencyRequirement{{RequiredBy: "app@1.0.0", Constraint: "^1.0.0"}},
+				},
+			},
+		},
+		{
+			name:  "deep chain within the depth limit",
+			graph: chainGraph(10),
+			deps:  map[string]string{"p0": "^1.0.0"},
+		},
+		{
+			name:  "deep chain beyond the depth limit",
+			graph: chainGraph(15),
+			deps:  map[string]string{"p0": "^1.0.0"},
+			want: []models.DependencyConflict{{
+				Reason:  models.DependencyConflictIncomplete,
+				Message: "dependency tree is deeper than 10 levels",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createTestUser(t, db, "alice", models.RoleUser)
+			createDependencyGraph(t, db, owner, tt.graph)
+			app := createTestPackage(t, db, "app", owner, false)
+			s := NewPackageService(db, nil, nil, config.PackagesConfig{DependencyCheck: DependencyCheckWarn})
+
+			got, err := s.checkDependencies(context.Background(), app, "1.0.0", tt.deps, owner.ID)
+			if err != nil {
+				t.Fatalf("checkDependencies() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				wantJSON, _ := json.MarshalIndent(tt.want, "", "  ")
+				t.Errorf("report =\n%s\nwant\n%s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+// constraintError 返回约束解析失败的错误信息，报告中原样使用
+func constraintError(t *testing.T, raw string) string {
+	t.Helper()
+	_, err := parseVersionConstraint(raw)
+	if err == nil {
+		t.Fatalf("constraint %q unexpectedly parsed", raw)
+	}
+	return err.Error()
+}
+
+func TestCheckDependenciesEnforce(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createDependencyGraph(t, db, owner, dependencyGraph{
+		"foo": {"1.0.0": {"bar": "^1.0.0"}},
+		"baz": {"1.0.0": {"bar": "^2.0.0"}},
+		"bar": {"1.0.0": nil, "2.0.0": nil},
+	})
+	for name, versions := range chainGraph(15) {
+		createDependencyGraph(t, db, owner, dependencyGraph{"chain-" + name: renameChain(versions)})
+	}
+	app := createTestPackage(t, db, "app", owner, false)
+	s := NewPackageService(db, nil, nil, config.PackagesConfig{DependencyCheck: DependencyCheckEnforce})
+
+	// 菱形冲突拒绝上传，错误中带有冲突详情
+	_, err := s.checkDependencies(context.Background(), app, "1.0.0", map[string]string{"foo": "^1.0.0", "baz": "^1.0.0"}, owner.ID)
+	var conflictErr *DependencyConflictError
+	if !errors.As(err, &conflictErr) || !errors.Is(err, ErrDependencyConflict) {
+		t.Fatalf("diamond conflict error = %v, want DependencyConflictError", err)
+	}
+	if len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].Package != "bar" {
+		t.Errorf("conflicts = %+v, want the conflict on bar", conflictErr.Conflicts)
+	}
+
+	// 超过深度上限只记录incomplete，不拒绝上传
+	conflicts, err := s.checkDependencies(context.Background(), app, "1.0.0", map[string]string{"chain-p0": "^1.0.0"}, owner.ID)
+	if err != nil {
+		t.Fatalf("deep chain error = %v, want the upload accepted", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Reason != models.DependencyConflictIncomplete {
+		t.Errorf("deep chain conflicts = %+v, want a single incomplete warning", conflicts)
+	}
+
+	// 调高深度上限后完整解析
+	deep := NewPackageService(db, nil, nil, config.PackagesConfig{DependencyCheck: DependencyCheckEnforce, DependencyCheckMaxDepth: 20})
+	conflicts, err = deep.checkDependencies(context.Background(), app, "1.0.0", map[string]string{"chain-p0": "^1.0.0"}, owner.ID)
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("deep chain with max depth 20 = %+v, %v; want no problems", conflicts, err)
+	}
+}
+
+// renameChain 为chainGraph中的依赖包名加上chain-前缀
+func renameChain(versions map[string]map[string]string) map[string]map[string]string {
+	renamed := make(map[string]map[string]string, len(versions))
+	for version, deps := range versions {
+		if deps == nil {
+			renamed[version] = nil
+			continue
+		}
+		renamed[version] = make(map[string]string, len(deps))
+		for name, constraint := range deps {
+			renamed[version]["chain-"+name] = constraint
+		}
+	}
+	return renamed
+}
+
+func TestCheckDependenciesStopsOnTimeout(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createDependencyGraph(t, db, owner, chainGraph(5))
+	app := createTestPackage(t, db, "app", owner, false)
+	s := NewPackageService(db, nil, nil, config.PackagesConfig{DependencyCheck: DependencyCheckEnforce, DependencyCheckTimeout: time.Nanosecond})
+
+	// 超时只记录incomplete警告，不拒绝上传
+	conflicts, err := s.checkDependencies(context.Background(), app, "1.0.0", map[string]string{"p0": "^1.0.0"}, owner.ID)
+	if err != nil {
+		t.Fatalf("checkDependencies() error = %v, want the upload accepted", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Reason != models.DependencyConflictIncomplete || conflicts[0].Message != "dependency check timed out" {
+		t.Errorf("conflicts = %+v, want a single timed out warning", conflicts)
+	}
+}
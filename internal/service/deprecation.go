@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DeprecationService 弃用路由调用统计服务
+type DeprecationService struct {
+	db *gorm.DB
+}
+
+// NewDeprecationService 创建弃用路由统计服务实例
+func NewDeprecationService(db *gorm.DB) *DeprecationService {
+	return &DeprecationService{db: db}
+}
+
+// RecordDeprecatedUsage 记录一次弃用路由调用（实现middleware.DeprecationRecorder）
+func (s *DeprecationService) RecordDeprecatedUsage(method, route string, userID *uint, tokenID, clientIP, userAgent string) error {
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	return s.db.Create(&models.DeprecatedRouteUsage{
+		Method:    method,
+		Route:     route,
+		UserID:    userID,
+		TokenID:   tokenID,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+	}).Error
+}
+
+// GetUsageReport 统计指定时间以来各弃用路由的调用次数
+func (s *DeprecationService) GetUsageReport(ctx context.Context, since time.Time) ([]models.DeprecatedRouteUsageStat, error) {
+	var stats []models.DeprecatedRouteUsageStat
+	err := s.db.WithContext(ctx).Model(&models.DeprecatedRouteUsage{}).
+		Select("method, route, COUNT(*) AS hits, COUNT(DISTINCT user_id) AS unique_users, MAX(created_at) AS last_used_at").
+		Where("created_at >= ?", since).
+		Group("method, route").
+		Order("hits DESC").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deprecated route usage: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+// GetUsageReport的MAX(created_at)在SQLite中以文本返回，无法扫描为time.Time，这里只测试记录部分
+func TestRecordDeprecatedUsage(t *testing.T) {
+	db := testutil.NewDB(t, &models.DeprecatedRouteUsage{})
+	s := NewDeprecationService(db)
+
+	userID := uint(7)
+	if err := s.RecordDeprecatedUsage("PUT", "/api/v1/packages/update/:package", &userID, "token-1", "10.0.0.1", strings.Repeat("a", 600)); err != nil {
+		t.Fatalf("RecordDeprecatedUsage: %v", err)
+	}
+	if err := s.RecordDeprecatedUsage("GET", "/api/v1/old", nil, "", "10.0.0.2", "curl"); err != nil {
+		t.Fatalf("RecordDeprecatedUsage: %v", err)
+	}
+
+	var rows []models.DeprecatedRouteUsage
+	if err := db.Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].UserID == nil || *rows[0].UserID != 7 || rows[0].TokenID != "token-1" || rows[0].Route != "/api/v1/packages/update/:package" {
+		t.Errorf("first row = %+v", rows[0])
+	}
+	if len(rows[0].UserAgent) != 500 {
+		t.Errorf("user agent stored with %d bytes, want it truncated to 500", len(rows[0].UserAgent))
+	}
+	if rows[1].UserID != nil {
+		t.Errorf("anonymous call stored user %d", *rows[1].UserID)
+	}
+}
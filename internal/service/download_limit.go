@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"webservice/internal/config"
+)
+
+// defaultDownloadRateLimitInterval 未配置时包下载限流的窗口长度
+const defaultDownloadRateLimitInterval = time.Minute
+
+// ErrDownloadRateLimited 包在当前窗口内的下载次数或字节数已达上限
+var ErrDownloadRateLimited = errors.New("package download rate limit exceeded")
+
+// DownloadRateLimitError 包下载被限流，附带建议的重试等待时间
+type DownloadRateLimitError struct {
+	Package    string
+	RetryAfter time.Duration
+}
+
+// Error 实现error接口
+func (e *DownloadRateLimitError) Error() string {
+	return fmt.Sprintf("%s for %s", ErrDownloadRateLimited.Error(), e.Package)
+}
+
+// Unwrap 返回ErrDownloadRateLimited
+func (e *DownloadRateLimitError) Unwrap() error {
+	return ErrDownloadRateLimited
+}
+
+// packageDownloadWindow 单个包在当前窗口内的下载计数
+type packageDownloadWindow struct {
+	start    time.Time
+	requests int
+	bytes    int64
+}
+
+// packageDownloadLimiter 按包名限制下载频率（固定窗口），同一进程内所有请求共享
+type packageDownloadLimiter struct {
+	interval  time.Duration
+	global    config.DownloadRateLimitRule
+	overrides map[string]config.DownloadRateLimitRule // 小写包名 -> 覆盖规则
+
+	mu      sync.Mutex
+	windows map[string]*packageDownloadWindow
+}
+
+// newPackageDownloadLimiter 创建包下载限流器，全局和所有覆盖规则都不限流时返回nil
+func newPackageDownloadLimiter(cfg config.DownloadRateLimitConfig) *packageDownloadLimiter {
+	enabled := cfg.Requests > 0 || cfg.Bytes > 0
+	overrides := make(map[string]config.DownloadRateLimitRule, len(cfg.Overrides))
+	for name, rule := range cfg.Overrides {
+		overrides[strings.ToLower(name)] = rule
+		enabled = enabled || rule.Requests > 0 || rule.Bytes > 0
+	}
+	if !enabled {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultDownloadRateLimitInterval
+	}
+	return &packageDownloadLimiter{
+		interval:  cfg.Interval,
+		global:    config.DownloadRateLimitRule{Requests: cfg.Requests, Bytes: cfg.Bytes},
+		overrides: overrides,
+		windows:   make(map[string]*packageDownloadWindow),
+	}
+}
+
+// rule 包适用的限制，覆盖规则整体替换全局规则
+func (l *packageDownloadLimiter) rule(packageName string) config.DownloadRateLimitRule {
+	if rule, ok := l.overrides[strings.ToLower(packageName)]; ok {
+		return rule
+	}
+	return l.global
+}
+
+// Allow 记录一次size字节的下载，当前窗口内次数或字节数已达上限时返回*DownloadRateLimitError
+// 字节数在下载开始时按文件大小计入，窗口内第一次下载总是允许，避免大于字节上限的文件永远无法下载
+func (l *packageDownloadLimiter) Allow(packageName string, size int64) error {
+	if l == nil {
+		return nil
+	}
+	rule := l.rule(packageName)
+	if rule.Requests <= 0 && rule.Bytes <= 0 {
+		return nil
+	}
+
+	key := strings.ToLower(packageName)
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 顺带清理已过期的窗口，避免map无限增长
+	for k, w := range l.windows {
+		if now.Sub(w.start) >= l.interval {
+			delete(l.windows, k)
+		}
+	}
+
+	w, ok := l.windows[key]
+	if !ok {
+		w = &packageDownloadWindow{start: now}
+		l.windows[key] = w
+	}
+	if (rule.Requests > 0 && w.requests >= rule.Requests) || (rule.Bytes > 0 && w.requests > 0 && w.bytes+size > rule.Bytes) {
+		return &DownloadRateLimitError{Package: packageName, RetryAfter: l.interval - now.Sub(w.start)}
+	}
+	w.requests++
+	w.bytes += size
+	return nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestGetDownloadURLForCompressedVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "gz-pkg", owner, false)
+	createTestVersion(t, db, pkg, "1.0.0", func(v *models.PackageVersion) { v.CompressedStored = true })
+
+	// presign模式下也不能直接签发存储链接，否则客户端会拿到gzip压缩后的内容
+	s := NewPackageService(db, nil, nil, config.PackagesConfig{DownloadURLMode: DownloadURLModePresign, DownloadURLSecret: "secret"})
+	url, filename, _, err := s.GetDownloadURL(context.Background(), "gz-pkg", "1.0.0", &owner.ID, "")
+	if err != nil {
+		t.Fatalf("GetDownloadURL: %v", err)
+	}
+	if !strings.HasPrefix(url, "/download/") {
+		t.Fatalf("url = %q, want an app-signed /download/ link", url)
+	}
+	if filename == "" {
+		t.Error("filename is empty")
+	}
+
+	token, err := s.VerifyDownloadToken(strings.TrimPrefix(url, "/download/"))
+	if err != nil {
+		t.Fatalf("VerifyDownloadToken: %v", err)
+	}
+	if token.Package != "gz-pkg" || token.Version != "1.0.0" || token.UserID != owner.ID {
+		t.Errorf("token = %+v, want gz-pkg@1.0.0 for user %d", token, owner.ID)
+	}
+}
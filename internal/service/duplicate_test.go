@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+// runConcurrently 同时启动n个调用并收集返回的错误
+func runConcurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// assertOneSucceeded 检查只有一个调用成功，其余都返回want
+func assertOneSucceeded(t *testing.T, errs []error, want error) {
+	t.Helper()
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, want):
+			t.Errorf("unexpected error: %v (want %v)", err, want)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d calls succeeded, want exactly 1", succeeded)
+	}
+}
+
+func TestConcurrentCreatePackage(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	s := newTestPackageService(t, db)
+
+	errs := runConcurrently(8, func(int) error {
+		_, err := s.CreatePackage(context.Background(), &models.CreatePackageRequest{Name: "race-pkg"}, owner.ID)
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrPackageExists)
+}
+
+func TestConcurrentCreateUser(t *testing.T) {
+	s := NewUserService(newTestDB(t), testPasswordConfig)
+
+	t.Run("same username", func(t *testing.T) {
+		errs := runConcurrently(8, func(i int) error {
+			_, err := s.CreateUser(context.Background(), &models.RegisterRequest{Username: "racer", Email: fmt.Sprintf("racer%d@example.com", i), Password: "password123"})
+			return err
+		})
+		assertOneSucceeded(t, errs, ErrUsernameExists)
+	})
+
+	t.Run("same email", func(t *testing.T) {
+		errs := runConcurrently(8, func(i int) error {
+			_, err := s.CreateUser(context.Background(), &models.RegisterRequest{Username: fmt.Sprintf("mailer%d", i), Email: "shared@example.com", Password: "password123"})
+			return err
+		})
+		assertOneSucceeded(t, errs, ErrEmailExists)
+	})
+}
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	db := newTestDB(t)
+	createTestUser(t, db, "alice", models.RoleUser)
+
+	err := db.Create(&models.User{Username: "alice", Email: "other@example.com", Password: "x"}).Error
+	if !isDuplicateKeyError(err) {
+		t.Errorf("isDuplicateKeyError(%v) = false, want true", err)
+	}
+	if isDuplicateKeyError(errors.New("connection refused")) || isDuplicateKeyError(nil) {
+		t.Error("isDuplicateKeyError reported an unrelated error as a duplicate")
+	}
+}
+
+func TestPublishUnrelatedConstraintViolationRemovesObject(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	a := createTestPackage(t, s.db, "a", owner, false)
+	b := createTestPackage(t, s.db, "b", owner, false)
+
+	// 与版本号无关的唯一约束：两个包的内容大小相同时第二次写入失败
+	if err := s.db.Exec("CREATE UNIQUE INDEX idx_test_file_size ON package_versions (file_size)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uploadTestVersion(s, a, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("upload a@1.0.0: %v", err)
+	}
+
+	_, err := uploadTestVersion(s, b, "1.0.0", owner.ID)
+	if err == nil || errors.Is(err, ErrVersionExists) {
+		t.Fatalf("upload b@1.0.0 = %v, want the constraint error", err)
+	}
+	exists, statErr := s.minioClient.ObjectExists(context.Background(), s.minioClient.ObjectKey("b", "1.0.0"))
+	if statErr != nil {
+		t.Fatal(statErr)
+	}
+	if exists {
+		t.Error("object of the failed upload was left in storage")
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+)
+
+// ecosystemCacheTTL 生态统计的缓存时间，统计需要扫描全部包和版本
+const ecosystemCacheTTL = time.Hour
+
+// topKeywordsLimit 关键字分布返回的关键字数量
+const topKeywordsLimit = 20
+
+// versionCountBuckets 版本数分组，max为0表示不设上限
+var versionCountBuckets = []struct {
+	label    string
+	min, max int64
+}{
+	{"1", 1, 1},
+	{"2-5", 2, 5},
+	{"6-20", 6, 20},
+	{">20", 21, 0},
+}
+
+// fileSizeBuckets 文件大小分组（字节），max为0表示不设上限
+var fileSizeBuckets = []struct {
+	label    string
+	min, max int64
+}{
+	{"<100KB", 0, 100<<10 - 1},
+	{"100KB-1MB", 100 << 10, 1<<20 - 1},
+	{"1-10MB", 1 << 20, 10<<20 - 1},
+	{"10-100MB", 10 << 20, 100<<20 - 1},
+	{">=100MB", 100 << 20, 0},
+}
+
+// ecosystemCache 进程内缓存的生态统计
+type ecosystemCache struct {
+	mu        sync.Mutex
+	value     *models.EcosystemDistribution
+	expiresAt time.Time
+}
+
+// GetEcosystemDistribution 获取包生态统计（许可证、关键字、版本数、文件大小、每月新包），结果缓存1小时
+func (s *PackageService) GetEcosystemDistribution(ctx context.Context) (*models.EcosystemDistribution, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetEcosystemDistribution")
+	defer span.Finish()
+
+	// 持有锁计算，缓存过期时并发请求只计算一次
+	s.ecosystem.mu.Lock()
+	defer s.ecosystem.mu.Unlock()
+	now := time.Now().UTC()
+	if s.ecosystem.value != nil && now.Before(s.ecosystem.expiresAt) {
+		return s.ecosystem.value, nil
+	}
+
+	dist := &models.EcosystemDistribution{GeneratedAt: now}
+	var err error
+	if dist.Licenses, err = s.licenseDistribution(ctx); err != nil {
+		return nil, err
+	}
+	if dist.Keywords, err = s.keywordDistribution(ctx); err != nil {
+		return nil, err
+	}
+	if dist.VersionCounts, err = s.versionCountDistribution(ctx); err != nil {
+		return nil, err
+	}
+	if dist.FileSizes, err = s.fileSizeDistribution(ctx); err != nil {
+		return nil, err
+	}
+	if dist.MonthlyNewPackages, err = s.monthlyNewPackages(ctx, now); err != nil {
+		return nil, err
+	}
+
+	s.ecosystem.value = dist
+	s.ecosystem.expiresAt = now.Add(ecosystemCacheTTL)
+	return dist, nil
+}
+
+// licenseDistribution 各许可证的包数，按包数降序
+func (s *PackageService) licenseDistribution(ctx context.Context) (models.Distribution, error) {
+	var rows []struct {
+		License string
+		Count   int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Package{}).
+		Select("license, COUNT(*) AS count").
+		Where("is_private = ?", false).
+		Group("license").
+		Order("count DESC, license").
+		Scan(&rows).Error
+	if err != nil {
+		return models.Distribution{}, fmt.Errorf("failed to get license distribution: %w", err)
+	}
+
+	dist := models.Distribution{ChartType: models.ChartTypePie, Data: make([]models.ChartPoint, 0, len(rows))}
+	for _, row := range rows {
+		label := row.License
+		if label == "" {
+			label = "none"
+		}
+		dist.Data = append(dist.Data, models.ChartPoint{Label: label, Value: row.Count})
+	}
+	return dist, nil
+}
+
+// keywordDistribution 包数最多的关键字，关键字以JSON数组存储，在应用中统计
+func (s *PackageService) keywordDistribution(ctx context.Context) (models.Distribution, error) {
+	var keywordLists []string
+	err := s.db.WithContext(ctx).Model(&models.Package{}).
+		Where("is_private = ? AND keywords <> ''", false).
+		Pluck("keywords", &keywordLists).Error
+	if err != nil {
+		return models.Distribution{}, fmt.Errorf("failed to get keywords: %w", err)
+	}
+
+	counts := make(map[string]int64)
+	for _, raw := range keywordLists {
+		var keywords []string
+		if err := json.Unmarshal([]byte(raw), &keywords); err != nil {
+			continue
+		}
+		// 同一个包重复的关键字只计一次
+		seen := make(map[string]bool, len(keywords))
+		for _, keyword := range keywords {
+			if keyword != "" && !seen[keyword] {
+				seen[keyword] = true
+				counts[keyword]++
+			}
+		}
+	}
+
+	points := make([]models.ChartPoint, 0, len(counts))
+	for keyword, count := range counts {
+		points = append(points, models.ChartPoint{Label: keyword, Value: count})
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].Value != points[j].Value {
+			return points[i].Value > points[j].Value
+		}
+		return points[i].Label < points[j].Label
+	})
+	if len(points) > topKeywordsLimit {
+		points = points[:topKeywordsLimit]
+	}
+	return models.Distribution{ChartType: models.ChartTypeBar, Data: points}, nil
+}
+
+// versionCountDistribution 按版本数分组的包数，没有版本的包不计入
+func (s *PackageService) versionCountDistribution(ctx context.Context) (models.Distribution, error) {
+	dist := models.Distribution{ChartType: models.ChartTypeBar, Data: make([]models.ChartPoint, 0, len(versionCountBuckets))}
+	for _, bucket := range versionCountBuckets {
+		query := s.db.WithContext(ctx).Table("package_versions AS pv").
+			Select("pv.package_id").
+			Joins("JOIN packages p ON p.id = pv.package_id").
+			Where("pv.deleted_at IS NULL AND p.deleted_at IS NULL AND p.is_private = ?", false).
+			Group("pv.package_id").
+			Having("COUNT(*) >= ?", bucket.min)
+		if bucket.max > 0 {
+			query = query.Having("COUNT(*) <= ?", bucket.max)
+		}
+
+		var count int64
+		if err := s.db.WithContext(ctx).Table("(?) AS t", query).Count(&count).Error; err != nil {
+			return models.Distribution{}, fmt.Errorf("failed to get version count distribution: %w", err)
+		}
+		dist.Data = append(dist.Data, models.ChartPoint{Label: bucket.label, Value: count})
+	}
+	return dist, nil
+}
+
+// fileSizeDistribution 按文件大小分组的版本数
+func (s *PackageService) fileSizeDistribution(ctx context.Context) (models.Distribution, error) {
+	dist := models.Distribution{ChartType: models.ChartTypeBar, Data: make([]models.ChartPoint, 0, len(fileSizeBuckets))}
+	for _, bucket := range fileSizeBuckets {
+		query := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+			Joins("JOIN packages p ON p.id = package_versions.package_id").
+			Where("p.deleted_at IS NULL AND p.is_private = ?", false).
+			Where("package_versions.file_size >= ?", bucket.min)
+		if bucket.max > 0 {
+			query = query.Where("package_versions.file_size <= ?", bucket.max)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return models.Distribution{}, fmt.Errorf("failed to get file size distribution: %w", err)
+		}
+		dist.Data = append(dist.Data, models.ChartPoint{Label: bucket.label, Value: count})
+	}
+	return dist, nil
+}
+
+// monthlyNewPackages 最近12个月（含当月）每月新建的包数，没有新包的月份为0
+// 按月分组的SQL各数据库写法不同，取出创建时间在应用中分组
+func (s *PackageService) monthlyNewPackages(ctx context.Context, now time.Time) (models.Distribution, error) {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -11, 0)
+
+	var createdAt []time.Time
+	err := s.db.WithContext(ctx).Model(&models.Package{}).
+		Where("is_private = ? AND created_at >= ?", false, start).
+		Pluck("created_at", &createdAt).Error
+	if err != nil {
+		return models.Distribution{}, fmt.Errorf("failed to get monthly new packages: %w", err)
+	}
+
+	counts := make(map[string]int64, 12)
+	for _, t := range createdAt {
+		counts[t.In(now.Location()).Format("2006-01")]++
+	}
+
+	dist := models.Distribution{ChartType: models.ChartTypeLine, Data: make([]models.ChartPoint, 0, 12)}
+	for month := start; !month.After(now); month = month.AddDate(0, 1, 0) {
+		label := month.Format("2006-01")
+		dist.Data = append(dist.Data, models.ChartPoint{Label: label, Value: counts[label]})
+	}
+	return dist, nil
+}
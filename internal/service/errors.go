@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPackageExists 包名已存在
+	ErrPackageExists = errors.New("package name already exists")
+	// ErrVersionExists 版本已存在
+	ErrVersionExists = errors.New("version already exists")
+	// ErrUsernameExists 用户名已存在
+	ErrUsernameExists = errors.New("username already exists")
+	// ErrEmailExists 邮箱已存在
+	ErrEmailExists = errors.New("email already exists")
+
+	// ErrInvalidSemver 启用版本单调策略时版本号不是合法的语义化版本
+	ErrInvalidSemver = errors.New("version is not a valid semantic version")
+	// ErrVersionNotMonotonic 版本号低于当前最高版本（未设置force_backfill）
+	ErrVersionNotMonotonic = errors.New("version is lower than the current highest version")
+	// ErrPrereleaseLatest 预发布版本不能成为最新版本
+	ErrPrereleaseLatest = errors.New("prerelease version cannot become the latest version")
+
+	// ErrPackageNameAliased 包名是其他包重命名前的旧名称，不能再使用
+	ErrPackageNameAliased = errors.New("package name is reserved as an alias of another package")
+
+	// ErrInvalidPackageName 包名不符合格式要求
+	ErrInvalidPackageName = errors.New("package name must start with a letter or digit and contain only letters, digits, '.', '_' or '-'")
+	// ErrPackageNameReserved 包名在保留名单中
+	ErrPackageNameReserved = errors.New("package name is reserved")
+	// ErrPackageNameRecentlyDeleted 同名包刚被删除，保留期内不能重新使用
+	ErrPackageNameRecentlyDeleted = errors.New("package name was recently deleted and is on hold")
+
+	// ErrPackageArchived 包已归档（只读），不能上传新版本或修改
+	ErrPackageArchived = errors.New("package is archived")
+	// ErrLongDescriptionTooLong 包详细介绍超过配置的最大长度
+	ErrLongDescriptionTooLong = errors.New("long_description is too long")
+	// ErrInvalidLinkURL 包的链接字段使用了不允许的协议或包含用户名密码
+	ErrInvalidLinkURL = errors.New("invalid link")
+
+	// ErrVersionLocked 版本已锁定，不能删除
+	ErrVersionLocked = errors.New("version is locked")
+	// ErrVersionNumberLocked 不可变包中发布过的版本号（包括已删除的）不能再次上传
+	ErrVersionNumberLocked = errors.New("version was published before and the package does not allow re-uploading it")
+	// ErrUploadLockPermanent 包的upload_locked开启后不能关闭
+	ErrUploadLockPermanent = errors.New("upload_locked cannot be disabled once enabled")
+
+	// ErrInvalidCleanupCriteria 批量清理版本的条件为空或版本匹配模式无效
+	ErrInvalidCleanupCriteria = errors.New("invalid cleanup criteria")
+
+	// ErrInvalidLeaderboardPeriod 排行榜周期不是weekly、monthly或alltime
+	ErrInvalidLeaderboardPeriod = errors.New("invalid leaderboard period")
+	// ErrLeaderboardUnavailable 未配置Redis，无法读取排行榜
+	ErrLeaderboardUnavailable = errors.New("leaderboard is unavailable")
+
+	// ErrObjectNotFound 版本记录的对象在存储中不存在
+	ErrObjectNotFound = errors.New("source object does not exist")
+	// ErrObjectExists 目标对象键已存在或已被其他版本使用
+	ErrObjectExists = errors.New("destination object already exists")
+	// ErrSameObjectKey 目标对象键与当前对象键相同
+	ErrSameObjectKey = errors.New("destination is the same as the current object key")
+
+	// ErrInvalidIcon 包图标格式不支持、内容无效或尺寸超过限制
+	ErrInvalidIcon = errors.New("invalid icon")
+	// ErrIconTooLarge 包图标超过配置的最大字节数
+	ErrIconTooLarge = errors.New("icon is too large")
+	// ErrIconNotFound 包没有上传图标
+	ErrIconNotFound = errors.New("package has no icon")
+
+	// ErrChecksumMismatch 下载内容的SHA256与上传时记录的不一致
+	ErrChecksumMismatch = errors.New("package checksum mismatch")
+
+	// ErrVersionQuarantined 版本扫描发现病毒已被隔离，不能下载
+	ErrVersionQuarantined = errors.New("package version is quarantined")
+	// ErrVersionScanning 版本正在进行病毒扫描，扫描完成前不能下载
+	ErrVersionScanning = errors.New("package version is being scanned for malware")
+	// ErrVersionNotQuarantined 解除隔离时版本未被隔离
+	ErrVersionNotQuarantined = errors.New("package version is not quarantined")
+	// ErrScanFailed 扫描引擎未能完成扫描（不可用、超时等）
+	ErrScanFailed = errors.New("malware scan failed")
+
+	// ErrCollaboratorIsOwner 包所有者不能被添加为协作者
+	ErrCollaboratorIsOwner = errors.New("the package owner cannot be a collaborator")
+
+	// ErrTOTPRequired 用户已启用两步验证，登录须提供验证码
+	ErrTOTPRequired = errors.New("totp code required")
+	// ErrInvalidTOTP 两步验证码错误或已过期
+	ErrInvalidTOTP = errors.New("invalid totp code")
+	// ErrTOTPAlreadyEnabled 两步验证已启用
+	ErrTOTPAlreadyEnabled = errors.New("totp is already enabled")
+	// ErrTOTPNotEnabled 两步验证未启用
+	ErrTOTPNotEnabled = errors.New("totp is not enabled")
+	// ErrTOTPNotSetUp 启用两步验证前须先生成密钥
+	ErrTOTPNotSetUp = errors.New("totp secret has not been set up")
+
+	// ErrSetupCompleted 已存在管理员，安装接口不再可用
+	ErrSetupCompleted = errors.New("setup already completed")
+	// ErrInvalidSetupToken 安装令牌不存在或已使用
+	ErrInvalidSetupToken = errors.New("invalid setup token")
+	// ErrSetupTokenExpired 安装令牌已过期
+	ErrSetupTokenExpired = errors.New("setup token expired")
+
+	// ErrInvalidConstraint 版本约束无法解析
+	ErrInvalidConstraint = errors.New("invalid version constraint")
+	// ErrNoMatchingVersion 没有满足约束的版本
+	ErrNoMatchingVersion = errors.New("no version satisfies the constraint")
+
+	// ErrInvalidAPIToken API令牌不存在、已吊销、已过期或所属用户不可用
+	ErrInvalidAPIToken = errors.New("invalid or revoked api token")
+	// ErrAPITokenNotFound 当前用户没有该API令牌
+	ErrAPITokenNotFound = errors.New("api token not found")
+)
+
+// isDuplicateKeyError 判断是否为唯一约束冲突错误
+// 兼容MySQL(1062)、PostgreSQL(23505)及SQLite的错误信息
+func isDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "SQLSTATE 23505") ||
+		strings.Contains(msg, "duplicate key value violates unique constraint") ||
+		strings.Contains(msg, "UNIQUE constraint failed")
+}
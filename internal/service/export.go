@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"webservice/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// 未配置时的导出参数
+const (
+	defaultExportBatchSize     = 1000 // 每批读取的行数，避免一次性加载全部结果
+	defaultExportMaxConcurrent = 1    // 同时执行的导出数
+)
+
+// ErrExportInProgress 同时执行的导出数已达上限
+var ErrExportInProgress = errors.New("too many exports in progress")
+
+// exportColumn 导出列定义
+type exportColumn struct {
+	Name string // 输出列名
+	Expr string // 查询表达式
+	PII  bool   // 是否为个人敏感信息，仅在显式要求时导出
+}
+
+// exportDataset 导出数据集定义，只导出显式列出的列
+type exportDataset struct {
+	table     string
+	idColumn  string
+	joins     []string
+	where     string
+	timeField string // since过滤使用的时间列
+	columns   []exportColumn
+}
+
+// exportDatasets 可导出的数据集，第一列必须是用于续传的自增ID
+var exportDatasets = map[string]exportDataset{
+	"packages": {
+		table:    "packages",
+		idColumn: "packages.id",
+		joins:    []string{"LEFT JOIN users ON users.id = packages.owner_id"},
+		where:    "packages.deleted_at IS NULL",
+		columns: []exportColumn{
+			{Name: "id", Expr: "packages.id"},
+			{Name: "name", Expr: "packages.name"},
+			{Name: "description", Expr: "packages.description"},
+			{Name: "author", Expr: "packages.author"},
+			{Name: "license", Expr: "packages.license"},
+			{Name: "keywords", Expr: "packages.keywords"},
+			{Name: "is_private", Expr: "packages.is_private"},
+			{Name: "owner_id", Expr: "packages.owner_id"},
+			{Name: "owner_username", Expr: "users.username"},
+			{Name: "owner_email", Expr: "users.email", PII: true},
+			{Name: "created_at", Expr: "packages.created_at"},
+			{Name: "updated_at", Expr: "packages.updated_at"},
+		},
+		timeField: "packages.updated_at",
+	},
+	"versions": {
+		table:    "package_versions",
+		idColumn: "package_versions.id",
+		joins: []string{
+			"JOIN packages ON packages.id = package_versions.package_id",
+			"LEFT JOIN users ON users.id = package_versions.uploader_id",
+		},
+		where: "package_versions.deleted_at IS NULL",
+		columns: []exportColumn{
+			{Name: "id", Expr: "package_versions.id"},
+			{Name: "package_id", Expr: "package_versions.package_id"},
+			{Name: "package_name", Expr: "packages.name"},
+			{Name: "version", Expr: "package_versions.version"},
+			{Name: "file_size", Expr: "package_versions.file_size"},
+			{Name: "file_hash", Expr: "package_versions.file_hash"},
+			{Name: "download_count", Expr: "package_versions.download_count"},
+			{Name: "install_count", Expr: "package_versions.install_count"},
+			{Name: "is_prerelease", Expr: "package_versions.is_prerelease"},
+			{Name: "uploader_id", Expr: "package_versions.uploader_id"},
+			{Name: "uploader_email", Expr: "users.email", PII: true},
+			{Name: "created_at", Expr: "package_versions.created_at"},
+		},
+		timeField: "package_versions.created_at",
+	},
+	"downloads": {
+		table:    "package_downloads",
+		idColumn: "package_downloads.id",
+		columns: []exportColumn{
+			{Name: "id", Expr: "package_downloads.id"},
+			{Name: "package_version_id", Expr: "package_downloads.package_version_id"},
+			{Name: "user_id", Expr: "package_downloads.user_id"},
+			{Name: "ip_address", Expr: "package_downloads.ip_address", PII: true},
+			{Name: "user_agent", Expr: "package_downloads.user_agent", PII: true},
+			{Name: "download_time", Expr: "package_downloads.download_time"},
+		},
+		timeField: "package_downloads.download_time",
+	},
+}
+
+// ExportOptions 导出参数
+type ExportOptions struct {
+	Cursor     string    // 续传令牌，为空时从头开始
+	Since      time.Time // 只导出该时间之后的数据，零值表示不限
+	Limit      int       // 本次最多导出的行数，达到后返回续传令牌
+	IncludePII bool      // 是否包含邮箱、IP等敏感列
+}
+
+// ExportSummary 导出结束时的汇总信息
+type ExportSummary struct {
+	Rows       int64  // 导出行数
+	NextCursor string // 未导出完时的续传令牌
+}
+
+// ExportService BI数据导出服务
+type ExportService struct {
+	db        *gorm.DB
+	batchSize int
+	slots     chan struct{} // 限制同时执行的导出数
+}
+
+// NewExportService 创建数据导出服务实例
+func NewExportService(db *gorm.DB, cfg config.ExportConfig) *ExportService {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultExportBatchSize
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = defaultExportMaxConcurrent
+	}
+	return &ExportService{db: db, batchSize: cfg.BatchSize, slots: make(chan struct{}, cfg.MaxConcurrent)}
+}
+
+// Columns 返回数据集本次导出的列名
+func (s *ExportService) Columns(dataset string, includePII bool) ([]string, error) {
+	ds, ok := exportDatasets[dataset]
+	if !ok {
+		return nil, fmt.Errorf("unknown export dataset: %s", dataset)
+	}
+
+	var names []string
+	for _, col := range ds.columns {
+		if col.PII && !includePII {
+			continue
+		}
+		names = append(names, col.Name)
+	}
+	return names, nil
+}
+
+// Export 按ID顺序分批读取数据集，每行回调一次；同时执行的导出数超过上限时返回ErrExportInProgress
+func (s *ExportService) Export(ctx context.Context, dataset string, opts ExportOptions, emit func(row []interface{}) error) (*ExportSummary, error) {
+	ds, ok := exportDatasets[dataset]
+	if !ok {
+		return nil, fmt.Errorf("unknown export dataset: %s", dataset)
+	}
+
+	afterID, err := DecodeExportCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		return nil, ErrExportInProgress
+	}
+
+	var exprs []string
+	for _, col := range ds.columns {
+		if col.PII && !opts.IncludePII {
+			continue
+		}
+		exprs = append(exprs, col.Expr)
+	}
+
+	summary := &ExportSummary{}
+	for {
+		batch := s.batchSize
+		if opts.Limit > 0 {
+			remaining := int64(opts.Limit) - summary.Rows
+			if remaining <= 0 {
+				break
+			}
+			if remaining < int64(batch) {
+				batch = int(remaining)
+			}
+		}
+
+		query := s.db.WithContext(ctx).Table(ds.table).Select(exprs)
+		for _, join := range ds.joins {
+			query = query.Joins(join)
+		}
+		if ds.where != "" {
+			query = query.Where(ds.where)
+		}
+		if !opts.Since.IsZero() && ds.timeField != "" {
+			query = query.Where(ds.timeField+" >= ?", opts.Since)
+		}
+
+		rows, err := query.Where(ds.idColumn+" > ?", afterID).Order(ds.idColumn).Limit(batch).Rows()
+		if err != nil {
+			return summary, fmt.Errorf("failed to query export batch: %w", err)
+		}
+
+		count, lastID, err := scanExportRows(rows, len(exprs), emit)
+		rows.Close()
+		if err != nil {
+			return summary, err
+		}
+
+		summary.Rows += int64(count)
+		if count > 0 {
+			afterID = lastID
+		}
+		if count < batch {
+			// 数据已全部导出
+			return summary, nil
+		}
+	}
+
+	// 达到本次导出上限，返回续传令牌
+	summary.NextCursor = EncodeExportCursor(afterID)
+	return summary, nil
+}
+
+// scanExportRows 逐行扫描查询结果并回调，返回行数和最后一行的ID
+func scanExportRows(rows *sql.Rows, columns int, emit func(row []interface{}) error) (int, uint64, error) {
+	count := 0
+	var lastID uint64
+	for rows.Next() {
+		values := make([]interface{}, columns)
+		ptrs := make([]interface{}, columns)
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, lastID, fmt.Errorf("failed to scan export row: %w", err)
+		}
+
+		// 驱动返回的字符串为[]byte，统一转换便于编码
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+
+		id, err := strconv.ParseUint(fmt.Sprint(values[0]), 10, 64)
+		if err != nil {
+			return count, lastID, fmt.Errorf("invalid export row id: %v", values[0])
+		}
+		lastID = id
+
+		if err := emit(values); err != nil {
+			return count, lastID, err
+		}
+		count++
+	}
+	return count, lastID, rows.Err()
+}
+
+// EncodeExportCursor 将最后导出的ID编码为续传令牌
+func EncodeExportCursor(lastID uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(lastID, 10)))
+}
+
+// DecodeExportCursor 解析续传令牌，空令牌表示从头开始
+func DecodeExportCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid export cursor")
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid export cursor")
+	}
+	return id, nil
+}
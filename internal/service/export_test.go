@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestExportResumesFromCursor(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	for i := 0; i < 7; i++ {
+		createTestPackage(t, db, fmt.Sprintf("pkg-%d", i), owner, false)
+	}
+	s := NewExportService(db, config.ExportConfig{BatchSize: 2})
+
+	var names []string
+	cursor := ""
+	for round := 0; ; round++ {
+		if round > 5 {
+			t.Fatal("export did not finish")
+		}
+		summary, err := s.Export(context.Background(), "packages", ExportOptions{Cursor: cursor, Limit: 3}, func(row []interface{}) error {
+			names = append(names, fmt.Sprint(row[1]))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		if summary.NextCursor == "" {
+			break
+		}
+		if summary.Rows != 3 {
+			t.Errorf("round %d exported %d rows before returning a cursor, want 3", round, summary.Rows)
+		}
+		cursor = summary.NextCursor
+	}
+
+	if len(names) != 7 {
+		t.Fatalf("exported %d rows, want 7: %v", len(names), names)
+	}
+	for i, name := range names {
+		if name != fmt.Sprintf("pkg-%d", i) {
+			t.Errorf("row %d = %s, want pkg-%d (rows must not repeat or be skipped)", i, name, i)
+		}
+	}
+}
+
+func TestExportColumnsExcludePII(t *testing.T) {
+	s := NewExportService(newTestDB(t), config.ExportConfig{})
+
+	columns, err := s.Columns("packages", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range columns {
+		if name == "owner_email" {
+			t.Error("owner_email exported without include_pii")
+		}
+	}
+	withPII, _ := s.Columns("packages", true)
+	if len(withPII) != len(columns)+1 {
+		t.Errorf("include_pii added %d columns, want 1", len(withPII)-len(columns))
+	}
+	if _, err := s.Columns("users", false); err == nil {
+		t.Error("unknown dataset was accepted")
+	}
+}
+
+func TestExportCursorRoundTrip(t *testing.T) {
+	id, err := DecodeExportCursor(EncodeExportCursor(42))
+	if err != nil || id != 42 {
+		t.Errorf("round trip = %d, %v; want 42", id, err)
+	}
+	if id, err := DecodeExportCursor(""); err != nil || id != 0 {
+		t.Errorf("empty cursor = %d, %v; want 0", id, err)
+	}
+	if _, err := DecodeExportCursor("not base64!"); err == nil {
+		t.Error("invalid cursor was accepted")
+	}
+}
+
+func TestExportRejectsConcurrentExports(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "pkg", owner, false)
+	s := NewExportService(db, config.ExportConfig{})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Export(context.Background(), "packages", ExportOptions{}, func([]interface{}) error {
+			close(started)
+			<-release
+			return nil
+		})
+		done <- err
+	}()
+	<-started
+
+	if _, err := s.Export(context.Background(), "packages", ExportOptions{}, func([]interface{}) error { return nil }); !errors.Is(err, ErrExportInProgress) {
+		t.Errorf("second export err = %v, want ErrExportInProgress", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first export: %v", err)
+	}
+	// 第一次导出结束后可以再次导出
+	if _, err := s.Export(context.Background(), "packages", ExportOptions{}, func([]interface{}) error { return nil }); err != nil {
+		t.Errorf("export after the first finished: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// 外部账号邮箱已属于本地账号时的处理方式
+const (
+	EmailConflictReject = "reject"
+	EmailConflictLink   = "link"
+)
+
+var (
+	// ErrUnknownIdentityProvider 未注册该提供方的凭据校验器
+	ErrUnknownIdentityProvider = errors.New("unknown identity provider")
+	// ErrInvalidExternalCredential 提供方凭据校验失败
+	ErrInvalidExternalCredential = errors.New("invalid external credential")
+	// ErrExternalEmailConflict 外部账号的邮箱已属于本地账号，需登录后主动关联
+	ErrExternalEmailConflict = errors.New("email already belongs to an existing account, sign in and link the identity instead")
+	// ErrExternalIdentityLinked 外部账号已关联到其他用户
+	ErrExternalIdentityLinked = errors.New("external identity is already linked to another user")
+	// ErrExternalIdentityNotFound 当前用户没有该外部身份关联
+	ErrExternalIdentityNotFound = errors.New("external identity not found")
+)
+
+// ExternalIdentityClaims 提供方校验通过的身份信息
+type ExternalIdentityClaims struct {
+	Provider      string
+	Subject       string // 提供方内的用户唯一标识
+	Email         string
+	EmailVerified bool   // 提供方确认邮箱属于该用户，只有已验证的邮箱才会关联到本地账号
+	Username      string // 建议的用户名（可选），创建本地用户时使用
+	Nickname      string
+}
+
+// IdentityVerifier 外部身份凭据校验器，每个提供方（如GitHub、企业SSO）一个实现
+// 校验失败时应返回包装了ErrInvalidExternalCredential的错误
+type IdentityVerifier interface {
+	Verify(ctx context.Context, credential string) (*ExternalIdentityClaims, error)
+}
+
+// usernameInvalidChars 生成用户名时替换的字符
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// FindByExternalIdentity 根据提供方和subject查找已关联的本地用户
+func (s *UserService) FindByExternalIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.FindByExternalIdentity")
+	defer span.Finish()
+
+	var identity models.ExternalIdentity
+	if err := s.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, identity.UserID)
+}
+
+// LinkExternalIdentity 将外部账号关联到本地用户，已关联到同一用户时直接返回
+func (s *UserService) LinkExternalIdentity(ctx context.Context, userID uint, claims *ExternalIdentityClaims) (*models.ExternalIdentity, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.LinkExternalIdentity")
+	defer span.Finish()
+
+	return linkExternalIdentity(s.db.WithContext(ctx), userID, claims)
+}
+
+// ListExternalIdentities 获取用户关联的外部身份
+func (s *UserService) ListExternalIdentities(ctx context.Context, userID uint) ([]*models.ExternalIdentity, error) {
+	var identities []*models.ExternalIdentity
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("failed to list external identities: %w", err)
+	}
+	return identities, nil
+}
+
+// UnlinkExternalIdentity 解除用户的指定外部身份关联，之后该外部账号登录时按未关联处理
+func (s *UserService) UnlinkExternalIdentity(ctx context.Context, userID, identityID uint) (*models.ExternalIdentity, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.UnlinkExternalIdentity")
+	defer span.Finish()
+
+	var identity models.ExternalIdentity
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrExternalIdentityNotFound
+			}
+			return fmt.Errorf("failed to find external identity: %w", err)
+		}
+		result := tx.Where("id = ? AND user_id = ?", identityID, userID).Delete(&models.ExternalIdentity{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to unlink external identity: %w", result.Error)
+		}
+		// 并发解除时只有一个请求成功
+		if result.RowsAffected == 0 {
+			return ErrExternalIdentityNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// linkExternalIdentity 在给定的连接（可能是事务）中关联外部账号
+func linkExternalIdentity(db *gorm.DB, userID uint, claims *ExternalIdentityClaims) (*models.ExternalIdentity, error) {
+	var existing models.ExternalIdentity
+	err := db.Where("provider = ? AND subject = ?", claims.Provider, claims.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrExternalIdentityLinked
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find external identity: %w", err)
+	}
+
+	identity := &models.ExternalIdentity{
+		Provider: claims.Provider,
+		Subject:  claims.Subject,
+		UserID:   userID,
+		Email:    claims.Email,
+	}
+	if err := db.Create(identity).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrExternalIdentityLinked
+		}
+		return nil, fmt.Errorf("failed to link external identity: %w", err)
+	}
+	return identity, nil
+}
+
+// LoginWithExternalIdentity 外部身份登录：已关联时返回关联的用户；未关联时按邮箱处理
+// 邮箱不属于任何本地账号时创建新用户（随机密码，只能通过外部身份登录）；
+// 邮箱已属于本地账号时，emailConflict为link且提供方确认邮箱已验证则关联到该账号，否则返回ErrExternalEmailConflict
+// 返回的created表示是否新建了本地用户
+func (s *UserService) LoginWithExternalIdentity(ctx context.Context, claims *ExternalIdentityClaims, emailConflict string) (*models.User, bool, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.LoginWithExternalIdentity")
+	defer span.Finish()
+
+	user, err := s.FindByExternalIdentity(ctx, claims.Provider, claims.Subject)
+	created := false
+	switch {
+	case err == nil:
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, false, fmt.Errorf("failed to find external identity: %w", err)
+	default:
+		user, created, err = s.linkOrCreateExternalUser(ctx, claims, emailConflict)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if !user.IsActive() {
+		return nil, false, errors.New("user account is not active")
+	}
+
+	now := time.Now().UTC()
+	user.LastLogin = &now
+	s.db.WithContext(ctx).Model(user).Update("last_login", now)
+	return user, created, nil
+}
+
+// linkOrCreateExternalUser 未关联的外部账号：按邮箱关联到已有账号或创建新用户
+func (s *UserService) linkOrCreateExternalUser(ctx context.Context, claims *ExternalIdentityClaims, emailConflict string) (*models.User, bool, error) {
+	if claims.Email != "" {
+		existing, err := s.GetUserByEmail(ctx, claims.Email)
+		if err == nil {
+			if emailConflict != EmailConflictLink || !claims.EmailVerified {
+				return nil, false, ErrExternalEmailConflict
+			}
+			if _, err := s.LinkExternalIdentity(ctx, existing.ID, claims); err != nil {
+				return nil, false, err
+			}
+			return existing, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	user, err := s.createExternalUser(ctx, claims)
+	if err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// createExternalUser 为外部账号创建本地用户并关联，用户名冲突时追加数字后缀
+func (s *UserService) createExternalUser(ctx context.Context, claims *ExternalIdentityClaims) (*models.User, error) {
+	if claims.Email == "" {
+		return nil, fmt.Errorf("%w: provider did not return an email", ErrInvalidExternalCredential)
+	}
+
+	// 随机密码，没有人知道明文，该用户只能通过外部身份登录
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := s.hashPassword(hex.EncodeToString(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	base := externalUsername(claims)
+	var user *models.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		username, err := availableUsername(tx, base)
+		if err != nil {
+			return err
+		}
+		user = &models.User{
+			Username: username,
+			Email:    claims.Email,
+			Password: hashedPassword,
+			Nickname: claims.Nickname,
+			Role:     models.RoleUser,
+			Status:   models.UserStatusActive,
+		}
+		if err := tx.Create(user).Error; err != nil {
+			if isDuplicateKeyError(err) {
+				return ErrExternalEmailConflict
+			}
+			return err
+		}
+		_, err = linkExternalIdentity(tx, user.ID, claims)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// externalUsername 根据提供方建议的用户名或邮箱前缀生成合法的用户名（3-50个字符）
+func externalUsername(claims *ExternalIdentityClaims) string {
+	base := claims.Username
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Trim(usernameInvalidChars.ReplaceAllString(base, "-"), "-.")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if len(base) < 3 {
+		base = claims.Provider + "-user"
+	}
+	return base
+}
+
+// availableUsername 返回未被占用的用户名：base、base-2、base-3……，尝试一定次数后使用随机后缀
+func availableUsername(db *gorm.DB, base string) (string, error) {
+	for i := 1; i <= 20; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		var count int64
+		if err := db.Model(&models.User{}).Unscoped().Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate username: %w", err)
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
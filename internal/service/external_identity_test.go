@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestLoginWithExternalIdentity(t *testing.T) {
+	db := newTestDB(t)
+	s := NewUserService(db, testPasswordConfig)
+	claims := &ExternalIdentityClaims{Provider: "github", Subject: "1001", Email: "octo@example.com", EmailVerified: true, Username: "octo cat"}
+
+	// 首次登录创建本地用户并关联
+	user, created, err := s.LoginWithExternalIdentity(context.Background(), claims, EmailConflictReject)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if !created || user.Username != "octo-cat" || user.Email != "octo@example.com" {
+		t.Errorf("first login = %+v created %v, want a new octo-cat user", user, created)
+	}
+
+	// 再次登录返回已关联的用户
+	again, created, err := s.LoginWithExternalIdentity(context.Background(), claims, EmailConflictReject)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if created || again.ID != user.ID || again.LastLogin == nil {
+		t.Errorf("second login = user %d created %v, want the linked user %d", again.ID, created, user.ID)
+	}
+
+	// 同名用户已存在时追加数字后缀
+	other, _, err := s.LoginWithExternalIdentity(context.Background(), &ExternalIdentityClaims{Provider: "gitlab", Subject: "7", Email: "octo2@example.com", Username: "octo-cat"}, EmailConflictReject)
+	if err != nil {
+		t.Fatalf("login with a taken username: %v", err)
+	}
+	if other.Username != "octo-cat-2" {
+		t.Errorf("username = %q, want octo-cat-2", other.Username)
+	}
+}
+
+func TestLoginWithExternalIdentityEmailConflict(t *testing.T) {
+	db := newTestDB(t)
+	s := NewUserService(db, testPasswordConfig)
+	local := createTestUser(t, db, "alice", models.RoleUser)
+
+	verified := &ExternalIdentityClaims{Provider: "github", Subject: "1001", Email: local.Email, EmailVerified: true}
+	unverified := &ExternalIdentityClaims{Provider: "github", Subject: "1002", Email: local.Email}
+
+	for _, tt := range []struct {
+		name   string
+		claims *ExternalIdentityClaims
+		mode   string
+	}{
+		{"reject", verified, EmailConflictReject},
+		{"default", verified, ""},
+		{"link with unverified email", unverified, EmailConflictLink},
+	} {
+		if _, _, err := s.LoginWithExternalIdentity(context.Background(), tt.claims, tt.mode); !errors.Is(err, ErrExternalEmailConflict) {
+			t.Errorf("%s: error = %v, want ErrExternalEmailConflict", tt.name, err)
+		}
+	}
+
+	user, created, err := s.LoginWithExternalIdentity(context.Background(), verified, EmailConflictLink)
+	if err != nil {
+		t.Fatalf("link with verified email: %v", err)
+	}
+	if created || user.ID != local.ID {
+		t.Errorf("link with verified email = user %d created %v, want the existing user %d", user.ID, created, local.ID)
+	}
+	// 关联后即使改为reject也能直接登录
+	if user, _, err := s.LoginWithExternalIdentity(context.Background(), verified, EmailConflictReject); err != nil || user.ID != local.ID {
+		t.Errorf("login after link = %v, %v; want the existing user", user, err)
+	}
+}
+
+func TestLinkAndUnlinkExternalIdentity(t *testing.T) {
+	db := newTestDB(t)
+	s := NewUserService(db, testPasswordConfig)
+	alice := createTestUser(t, db, "alice", models.RoleUser)
+	bob := createTestUser(t, db, "bob", models.RoleUser)
+	claims := &ExternalIdentityClaims{Provider: "github", Subject: "1001", Email: "octo@example.com"}
+
+	identity, err := s.LinkExternalIdentity(context.Background(), alice.ID, claims)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	found, err := s.FindByExternalIdentity(context.Background(), "github", "1001")
+	if err != nil || found.ID != alice.ID {
+		t.Fatalf("FindByExternalIdentity() = %v, %v; want alice", found, err)
+	}
+
+	// 重复关联到同一用户直接返回已有关联，关联到其他用户被拒绝
+	again, err := s.LinkExternalIdentity(context.Background(), alice.ID, claims)
+	if err != nil || again.ID != identity.ID {
+		t.Errorf("relink to the same user = %v, %v; want the existing identity %d", again, err, identity.ID)
+	}
+	if _, err := s.LinkExternalIdentity(context.Background(), bob.ID, claims); !errors.Is(err, ErrExternalIdentityLinked) {
+		t.Errorf("link to another user error = %v, want ErrExternalIdentityLinked", err)
+	}
+	identities, err := s.ListExternalIdentities(context.Background(), alice.ID)
+	if err != nil || len(identities) != 1 {
+		t.Fatalf("ListExternalIdentities() = %v, %v; want one identity", identities, err)
+	}
+
+	// 只能解除自己的关联
+	if _, err := s.UnlinkExternalIdentity(context.Background(), bob.ID, identity.ID); !errors.Is(err, ErrExternalIdentityNotFound) {
+		t.Errorf("unlink by another user error = %v, want ErrExternalIdentityNotFound", err)
+	}
+	unlinked, err := s.UnlinkExternalIdentity(context.Background(), alice.ID, identity.ID)
+	if err != nil {
+		t.Fatalf("unlink: %v", err)
+	}
+	if unlinked.Provider != "github" || unlinked.Subject != "1001" {
+		t.Errorf("unlinked identity = %+v, want github/1001", unlinked)
+	}
+	if _, err := s.UnlinkExternalIdentity(context.Background(), alice.ID, identity.ID); !errors.Is(err, ErrExternalIdentityNotFound) {
+		t.Errorf("second unlink error = %v, want ErrExternalIdentityNotFound", err)
+	}
+	if _, err := s.FindByExternalIdentity(context.Background(), "github", "1001"); err == nil {
+		t.Error("FindByExternalIdentity() found an unlinked identity")
+	}
+
+	// 解除后可以关联到其他用户
+	if _, err := s.LinkExternalIdentity(context.Background(), bob.ID, claims); err != nil {
+		t.Errorf("link to bob after unlink: %v", err)
+	}
+}
+
+func TestConcurrentLinkExternalIdentity(t *testing.T) {
+	db := newTestDB(t)
+	s := NewUserService(db, testPasswordConfig)
+	users := make([]*models.User, 8)
+	for i := range users {
+		users[i] = createTestUser(t, db, fmt.Sprintf("user-%d", i), models.RoleUser)
+	}
+	claims := &ExternalIdentityClaims{Provider: "github", Subject: "1001", Email: "octo@example.com"}
+
+	errs := runConcurrently(len(users), func(i int) error {
+		_, err := s.LinkExternalIdentity(context.Background(), users[i].ID, claims)
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrExternalIdentityLinked)
+
+	var count int64
+	if err := db.Model(&models.ExternalIdentity{}).Where("provider = ? AND subject = ?", "github", "1001").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("identity rows = %d, want 1", count)
+	}
+}
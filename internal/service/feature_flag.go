@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"webservice/internal/feature"
+	"webservice/internal/logger"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// featureFlagName 功能开关名称：小写字母开头，由小写字母、数字、下划线、点和短横线组成
+var featureFlagName = regexp.MustCompile(`^[a-z][a-z0-9_.-]*$`)
+
+// 功能开关错误
+var (
+	ErrFeatureFlagNotFound    = errors.New("feature flag not found")
+	ErrFeatureFlagExists      = errors.New("feature flag already exists")
+	ErrInvalidFeatureFlagName = errors.New("feature flag name must start with a lowercase letter and contain only lowercase letters, digits, '_', '.' and '-'")
+)
+
+// FeatureFlagService 功能开关管理，修改后立即重新加载内存中的开关
+type FeatureFlagService struct {
+	db    *gorm.DB
+	flags *feature.Flags
+}
+
+// NewFeatureFlagService 创建功能开关服务，flags为nil时修改只在下次定期刷新后生效
+func NewFeatureFlagService(db *gorm.DB, flags *feature.Flags) *FeatureFlagService {
+	return &FeatureFlagService{db: db, flags: flags}
+}
+
+// ListFlags 按名称列出全部功能开关
+func (s *FeatureFlagService) ListFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	if err := s.db.WithContext(ctx).Order("name").Find(&flags).Error; err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// CreateFlag 创建功能开关
+func (s *FeatureFlagService) CreateFlag(ctx context.Context, req *models.CreateFeatureFlagRequest) (*models.FeatureFlag, error) {
+	if !featureFlagName.MatchString(req.Name) {
+		return nil, ErrInvalidFeatureFlagName
+	}
+
+	flag := models.FeatureFlag{
+		Name:              req.Name,
+		Description:       req.Description,
+		EnabledGlobally:   req.EnabledGlobally,
+		EnabledForRoles:   req.EnabledForRoles,
+		EnabledForUserIDs: req.EnabledForUserIDs,
+		RolloutPercentage: req.RolloutPercentage,
+	}
+	if err := s.db.WithContext(ctx).Create(&flag).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrFeatureFlagExists
+		}
+		return nil, fmt.Errorf("failed to create feature flag: %w", err)
+	}
+	s.reload(ctx)
+	return &flag, nil
+}
+
+// UpdateFlag 修改功能开关，返回修改后的开关
+func (s *FeatureFlagService) UpdateFlag(ctx context.Context, name string, req *models.UpdateFeatureFlagRequest) (*models.FeatureFlag, error) {
+	flag, err := s.findFlag(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		flag.Description = *req.Description
+	}
+	if req.EnabledGlobally != nil {
+		flag.EnabledGlobally = *req.EnabledGlobally
+	}
+	if req.EnabledForRoles != nil {
+		flag.EnabledForRoles = req.EnabledForRoles
+	}
+	if req.EnabledForUserIDs != nil {
+		flag.EnabledForUserIDs = req.EnabledForUserIDs
+	}
+	if req.RolloutPercentage != nil {
+		flag.RolloutPercentage = *req.RolloutPercentage
+	}
+	if err := s.db.WithContext(ctx).Save(flag).Error; err != nil {
+		return nil, fmt.Errorf("failed to update feature flag: %w", err)
+	}
+	s.reload(ctx)
+	return flag, nil
+}
+
+// DeleteFlag 删除功能开关，删除后该功能对所有用户未启用
+func (s *FeatureFlagService) DeleteFlag(ctx context.Context, name string) error {
+	result := s.db.WithContext(ctx).Where("name = ?", name).Delete(&models.FeatureFlag{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrFeatureFlagNotFound
+	}
+	s.reload(ctx)
+	return nil
+}
+
+// findFlag 按名称查找功能开关
+func (s *FeatureFlagService) findFlag(ctx context.Context, name string) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	if err := s.db.WithContext(ctx).Where("name = ?", name).First(&flag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFeatureFlagNotFound
+		}
+		return nil, fmt.Errorf("failed to find feature flag: %w", err)
+	}
+	return &flag, nil
+}
+
+// reload 修改后立即重新加载内存中的开关，失败时等待下次定期刷新
+func (s *FeatureFlagService) reload(ctx context.Context) {
+	if s.flags == nil {
+		return
+	}
+	if err := s.flags.Load(ctx); err != nil {
+		logger.Warnf("Failed to reload feature flags: %v", err)
+	}
+}
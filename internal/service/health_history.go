@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/database"
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 未配置时的可用性历史参数
+const (
+	defaultHealthSampleInterval = 30 * time.Second
+	defaultHealthHistorySize    = 2880
+	defaultHealthRetention      = 7 * 24 * time.Hour
+	healthCheckTimeout          = 5 * time.Second // 单个依赖检查的超时时间
+	healthPurgeInterval         = time.Hour       // 清理health_checks表过期记录的最小间隔
+	// MaxHealthHistoryWindow 可查询的最长时间窗口
+	MaxHealthHistoryWindow = 31 * 24 * time.Hour
+)
+
+// ErrInvalidHealthWindow 查询窗口不是正数或超过MaxHealthHistoryWindow
+var ErrInvalidHealthWindow = errors.New("window must be a positive duration no longer than 744h")
+
+// HealthHistoryService 依赖可用性历史：定期检查数据库和存储，结果保存在固定容量的环形缓冲区中，可选写入health_checks表
+type HealthHistoryService struct {
+	db          *gorm.DB
+	minioClient *minio.Client // 可能为nil（存储不可用），此时不记录存储状态
+	interval    time.Duration
+	persist     bool
+	retention   time.Duration
+	ring        *healthRing
+
+	purgeMu   sync.Mutex
+	lastPurge time.Time
+}
+
+// NewHealthHistoryService 创建可用性历史服务实例
+func NewHealthHistoryService(db *gorm.DB, minioClient *minio.Client, cfg config.HealthConfig) *HealthHistoryService {
+	if cfg.HistorySize <= 0 {
+		cfg.HistorySize = defaultHealthHistorySize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultHealthRetention
+	}
+	return &HealthHistoryService{
+		db:          db,
+		minioClient: minioClient,
+		interval:    cfg.SampleInterval,
+		persist:     cfg.Persist,
+		retention:   cfg.Retention,
+		ring:        newHealthRing(cfg.HistorySize),
+	}
+}
+
+// Interval 检查间隔，0表示不记录
+func (s *HealthHistoryService) Interval() time.Duration {
+	return s.interval
+}
+
+// sampleInterval 合并停机区间时使用的检查间隔
+func (s *HealthHistoryService) sampleInterval() time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+	return defaultHealthSampleInterval
+}
+
+// Sample 检查一次数据库和存储并记录结果；依赖不可用不是错误，只有写入health_checks表失败时返回错误
+func (s *HealthHistoryService) Sample(ctx context.Context) (*models.HealthCheck, error) {
+	check := models.HealthCheck{CheckedAt: time.Now().UTC()}
+
+	var err error
+	check.DatabaseLatencyMs, err = timedCheck(ctx, func(ctx context.Context) error { return database.Probe(ctx, s.db) })
+	check.DatabaseUp = err == nil
+	check.Healthy = check.DatabaseUp
+
+	if s.minioClient != nil {
+		check.StorageLatencyMs, err = timedCheck(ctx, s.minioClient.Ping)
+		up := err == nil
+		check.StorageUp = &up
+		check.Healthy = check.Healthy && up
+	}
+
+	s.ring.add(check)
+	if !s.persist {
+		return &check, nil
+	}
+
+	if err := s.db.WithContext(ctx).Create(&check).Error; err != nil {
+		return &check, fmt.Errorf("failed to save health check: %w", err)
+	}
+	s.purgeExpired(ctx)
+	return &check, nil
+}
+
+// timedCheck 在超时控制下执行单项检查，返回耗时（毫秒）
+func timedCheck(ctx context.Context, check func(ctx context.Context) error) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	return time.Since(start).Milliseconds(), err
+}
+
+// purgeExpired 删除health_checks表中超过保留期的记录，每小时最多执行一次
+func (s *HealthHistoryService) purgeExpired(ctx context.Context) {
+	s.purgeMu.Lock()
+	if time.Since(s.lastPurge) < healthPurgeInterval {
+		s.purgeMu.Unlock()
+		return
+	}
+	s.lastPurge = time.Now()
+	s.purgeMu.Unlock()
+
+	cutoff := time.Now().UTC().Add(-s.retention)
+	if err := s.db.WithContext(ctx).Where("checked_at < ?", cutoff).Delete(&models.HealthCheck{}).Error; err != nil {
+		logger.Warnf("Failed to purge expired health checks: %v", err)
+	}
+}
+
+// History 统计最近window内各依赖的可用性和停机区间
+// 开启persist时从health_checks表读取（包含重启前及其他实例的记录），否则读取内存中的最近记录
+func (s *HealthHistoryService) History(ctx context.Context, window time.Duration) (*models.HealthHistory, error) {
+	if window <= 0 || window > MaxHealthHistoryWindow {
+		return nil, ErrInvalidHealthWindow
+	}
+
+	since := time.Now().UTC().Add(-window)
+	history := &models.HealthHistory{Window: window.String(), Source: "memory"}
+
+	var checks []models.HealthCheck
+	if s.persist {
+		history.Source = "database"
+		if err := s.db.WithContext(ctx).Where("checked_at >= ?", since).Order("checked_at").Find(&checks).Error; err != nil {
+			return nil, fmt.Errorf("failed to load health checks: %w", err)
+		}
+	} else {
+		checks = s.ring.since(since)
+	}
+
+	history.Samples = len(checks)
+	if len(checks) > 0 {
+		from, to := checks[0].CheckedAt, checks[len(checks)-1].CheckedAt
+		history.From, history.To = &from, &to
+	}
+
+	interval := s.sampleInterval()
+	history.Dependencies = []models.DependencyAvailability{
+		summarizeAvailability("database", checks, interval, true, func(c models.HealthCheck) (bool, int64, bool) {
+			return c.DatabaseUp, c.DatabaseLatencyMs, true
+		}),
+	}
+	if s.minioClient != nil {
+		history.Dependencies = append(history.Dependencies, summarizeAvailability("storage", checks, interval, true, func(c models.HealthCheck) (bool, int64, bool) {
+			if c.StorageUp == nil {
+				return false, 0, false
+			}
+			return *c.StorageUp, c.StorageLatencyMs, true
+		}))
+	}
+	history.Dependencies = append(history.Dependencies, summarizeAvailability("overall", checks, interval, false, func(c models.HealthCheck) (bool, int64, bool) {
+		return c.Healthy, 0, true
+	}))
+	return history, nil
+}
+
+// summarizeAvailability 按时间顺序的采样计算单个依赖的可用率和停机区间
+// pick返回该依赖是否可用、检查耗时以及该采样是否包含该依赖，withLatency为false时不统计平均耗时
+// 连续失败的采样合并为一个区间，区间在下一次成功的采样时结束；相邻采样间隔超过两倍检查间隔（服务未运行）时，
+// 区间在最后一次失败后一个检查间隔处结束，空档期不计入停机时间
+func summarizeAvailability(name string, checks []models.HealthCheck, interval time.Duration, withLatency bool, pick func(models.HealthCheck) (up bool, latencyMs int64, ok bool)) models.DependencyAvailability {
+	result := models.DependencyAvailability{Name: name, Downtime: []models.DowntimeInterval{}}
+
+	var (
+		down         *models.DowntimeInterval
+		last         time.Time
+		latencyTotal int64
+	)
+	closeDown := func(end time.Time, ongoing bool) {
+		down.End = end
+		down.DurationSeconds = int64(end.Sub(down.Start).Seconds())
+		down.Ongoing = ongoing
+		result.Downtime = append(result.Downtime, *down)
+		down = nil
+	}
+
+	for _, c := range checks {
+		up, latency, ok := pick(c)
+		if !ok {
+			continue
+		}
+		if down != nil && c.CheckedAt.Sub(last) > 2*interval {
+			closeDown(last.Add(interval), false)
+		}
+
+		result.Samples++
+		latencyTotal += latency
+		if up {
+			if down != nil {
+				closeDown(c.CheckedAt, false)
+			}
+		} else {
+			result.Failures++
+			if down == nil {
+				down = &models.DowntimeInterval{Start: c.CheckedAt}
+			}
+		}
+		last = c.CheckedAt
+	}
+	if down != nil {
+		closeDown(last, true)
+	}
+
+	if result.Samples > 0 {
+		percent := math.Round(float64(result.Samples-result.Failures)/float64(result.Samples)*10000) / 100
+		result.AvailabilityPercent = &percent
+		if withLatency {
+			avg := math.Round(float64(latencyTotal)/float64(result.Samples)*100) / 100
+			result.AvgLatencyMs = &avg
+		}
+	}
+	return result
+}
+
+// healthRing 固定容量的检查结果环形缓冲区，写满后覆盖最早的记录
+type healthRing struct {
+	mu   sync.Mutex
+	buf  []models.HealthCheck
+	next int
+	full bool
+}
+
+// newHealthRing 创建容量为size的环形缓冲区
+func newHealthRing(size int) *healthRing {
+	return &healthRing{buf: make([]models.HealthCheck, size)}
+}
+
+// add 追加一条记录
+func (r *healthRing) add(check models.HealthCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.next] = check
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// since 按时间顺序返回since之后的记录
+func (r *healthRing) since(since time.Time) []models.HealthCheck {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ordered := r.buf[:r.next]
+	if r.full {
+		ordered = append(append([]models.HealthCheck(nil), r.buf[r.next:]...), r.buf[:r.next]...)
+	}
+	result := make([]models.HealthCheck, 0, len(ordered))
+	for _, check := range ordered {
+		if !check.CheckedAt.Before(since) {
+			result = append(result, check)
+		}
+	}
+	return result
+}
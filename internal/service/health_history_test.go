@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+// healthSample 相对t0偏移at秒的一次检查，up为false表示失败
+type healthSample struct {
+	at      int
+	up      bool
+	latency int64
+}
+
+// summarize 以30s检查间隔统计一组采样
+func summarize(t0 time.Time, samples []healthSample, withLatency bool) models.DependencyAvailability {
+	checks := make([]models.HealthCheck, len(samples))
+	for i, s := range samples {
+		checks[i] = models.HealthCheck{CheckedAt: t0.Add(time.Duration(s.at) * time.Second), DatabaseUp: s.up, DatabaseLatencyMs: s.latency}
+	}
+	return summarizeAvailability("database", checks, 30*time.Second, withLatency, func(c models.HealthCheck) (bool, int64, bool) {
+		return c.DatabaseUp, c.DatabaseLatencyMs, true
+	})
+}
+
+// downtime 相对t0的停机区间，便于比较
+func downtime(t0 time.Time, intervals []models.DowntimeInterval) string {
+	var out []string
+	for _, d := range intervals {
+		out = append(out, fmt.Sprintf("%v-%v/%ds/ongoing=%v", d.Start.Sub(t0).Seconds(), d.End.Sub(t0).Seconds(), d.DurationSeconds, d.Ongoing))
+	}
+	return fmt.Sprint(out)
+}
+
+func TestSummarizeAvailability(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		samples  []healthSample
+		failures int
+		percent  float64
+		downtime string
+	}{
+		{"all up", []healthSample{{0, true, 0}, {30, true, 0}, {60, true, 0}}, 0, 100, "[]"},
+		{"all down", []healthSample{{0, false, 0}, {30, false, 0}}, 2, 0, "[0-30/30s/ongoing=true]"},
+		{"recovered", []healthSample{{0, true, 0}, {30, false, 0}, {60, false, 0}, {90, true, 0}}, 2, 50, "[30-90/60s/ongoing=false]"},
+		{"ongoing single failure", []healthSample{{0, true, 0}, {30, true, 0}, {60, false, 0}}, 1, 66.67, "[60-60/0s/ongoing=true]"},
+		{"rounded to two decimals", []healthSample{{0, true, 0}, {30, true, 0}, {60, true, 0}, {90, false, 0}, {120, true, 0}, {150, true, 0}, {180, true, 0}}, 1, 85.71, "[90-120/30s/ongoing=false]"},
+		{"two intervals", []healthSample{{0, false, 0}, {30, true, 0}, {60, false, 0}, {90, true, 0}}, 2, 50, "[0-30/30s/ongoing=false 60-90/30s/ongoing=false]"},
+		// 相邻采样间隔正好两倍检查间隔时不视为空档
+		{"gap of two intervals", []healthSample{{0, false, 0}, {60, false, 0}, {90, true, 0}}, 2, 33.33, "[0-90/90s/ongoing=false]"},
+		// 服务未运行的空档：区间在最后一次失败后一个检查间隔处结束
+		{"gap before recovery", []healthSample{{0, false, 0}, {30, false, 0}, {300, true, 0}}, 2, 33.33, "[0-60/60s/ongoing=false]"},
+		{"gap while still down", []healthSample{{0, false, 0}, {300, false, 0}}, 2, 0, "[0-30/30s/ongoing=false 300-300/0s/ongoing=true]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(t0, tt.samples, false)
+			if got.Samples != len(tt.samples) || got.Failures != tt.failures {
+				t.Errorf("samples/failures = %d/%d, want %d/%d", got.Samples, got.Failures, len(tt.samples), tt.failures)
+			}
+			if got.AvailabilityPercent == nil || *got.AvailabilityPercent != tt.percent {
+				t.Errorf("availability = %v, want %v", got.AvailabilityPercent, tt.percent)
+			}
+			if d := downtime(t0, got.Downtime); d != tt.downtime {
+				t.Errorf("downtime = %s, want %s", d, tt.downtime)
+			}
+			if got.AvgLatencyMs != nil {
+				t.Errorf("avg latency = %v, want nil without latency", *got.AvgLatencyMs)
+			}
+		})
+	}
+}
+
+func TestSummarizeAvailabilityLatencyAndEmpty(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := summarize(t0, []healthSample{{0, true, 10}, {30, true, 20}, {60, false, 5000}, {90, true, 11}}, true)
+	// 失败的检查同样计入平均耗时：(10+20+5000+11)/4 = 1260.25
+	if got.AvgLatencyMs == nil || *got.AvgLatencyMs != 1260.25 {
+		t.Errorf("avg latency = %v, want 1260.25", got.AvgLatencyMs)
+	}
+
+	empty := summarize(t0, nil, true)
+	if empty.Samples != 0 || empty.AvailabilityPercent != nil || empty.AvgLatencyMs != nil {
+		t.Errorf("empty = %+v, want no availability and no latency", empty)
+	}
+	if empty.Downtime == nil {
+		t.Error("empty downtime = nil, want an empty list")
+	}
+
+	// 不包含该依赖的采样（未配置存储）不计入
+	up := true
+	checks := []models.HealthCheck{{CheckedAt: t0}, {CheckedAt: t0.Add(30 * time.Second), StorageUp: &up}}
+	storage := summarizeAvailability("storage", checks, 30*time.Second, true, func(c models.HealthCheck) (bool, int64, bool) {
+		if c.StorageUp == nil {
+			return false, 0, false
+		}
+		return *c.StorageUp, c.StorageLatencyMs, true
+	})
+	if storage.Samples != 1 || storage.Failures != 0 || *storage.AvailabilityPercent != 100 {
+		t.Errorf("storage = %+v, want one successful sample", storage)
+	}
+}
+
+func TestHealthRingOverwritesOldest(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ring := newHealthRing(3)
+	at := func(i int) time.Time { return t0.Add(time.Duration(i) * time.Minute) }
+	offsets := func(checks []models.HealthCheck) string {
+		var out []float64
+		for _, c := range checks {
+			out = append(out, c.CheckedAt.Sub(t0).Minutes())
+		}
+		return fmt.Sprint(out)
+	}
+
+	for i := 0; i < 2; i++ {
+		ring.add(models.HealthCheck{CheckedAt: at(i)})
+	}
+	if got := offsets(ring.since(time.Time{})); got != "[0 1]" {
+		t.Errorf("before wrapping = %s, want [0 1]", got)
+	}
+	for i := 2; i < 5; i++ {
+		ring.add(models.HealthCheck{CheckedAt: at(i)})
+	}
+	if got := offsets(ring.since(time.Time{})); got != "[2 3 4]" {
+		t.Errorf("after wrapping = %s, want [2 3 4]", got)
+	}
+	if got := offsets(ring.since(at(3))); got != "[3 4]" {
+		t.Errorf("since minute 3 = %s, want [3 4]", got)
+	}
+}
+
+func TestHealthHistoryWindow(t *testing.T) {
+	s := NewHealthHistoryService(newTestDB(t), nil, config.HealthConfig{})
+	for _, window := range []time.Duration{0, -time.Hour, MaxHealthHistoryWindow + time.Second} {
+		if _, err := s.History(context.Background(), window); !errors.Is(err, ErrInvalidHealthWindow) {
+			t.Errorf("History(%v) err = %v, want ErrInvalidHealthWindow", window, err)
+		}
+	}
+	if _, err := s.History(context.Background(), MaxHealthHistoryWindow); err != nil {
+		t.Errorf("History(max) err = %v", err)
+	}
+}
+
+func TestHealthHistoryFromDatabase(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC()
+	for _, c := range []models.HealthCheck{
+		{CheckedAt: now.Add(-2 * time.Hour), DatabaseUp: false}, // 窗口外
+		{CheckedAt: now.Add(-90 * time.Second), DatabaseUp: true, Healthy: true},
+		{CheckedAt: now.Add(-60 * time.Second), DatabaseUp: false},
+		{CheckedAt: now.Add(-30 * time.Second), DatabaseUp: true, Healthy: true},
+		{CheckedAt: now, DatabaseUp: true, Healthy: true},
+	} {
+		if err := db.Create(&c).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewHealthHistoryService(db, nil, config.HealthConfig{SampleInterval: 30 * time.Second, Persist: true})
+	history, err := s.History(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if history.Source != "database" || history.Samples != 4 || history.Window != "1h0m0s" {
+		t.Errorf("history = source %s samples %d window %s, want database/4/1h0m0s", history.Source, history.Samples, history.Window)
+	}
+	if len(history.Dependencies) != 2 || history.Dependencies[0].Name != "database" || history.Dependencies[1].Name != "overall" {
+		t.Fatalf("dependencies = %+v, want database and overall", history.Dependencies)
+	}
+	database := history.Dependencies[0]
+	if *database.AvailabilityPercent != 75 || len(database.Downtime) != 1 || database.Downtime[0].DurationSeconds != 30 {
+		t.Errorf("database = %+v, want 75%% with one 30s interval", database)
+	}
+	if history.Dependencies[1].AvgLatencyMs != nil {
+		t.Error("overall availability reports a latency")
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/migration"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// testPasswordConfig 测试使用最低成本的bcrypt，避免argon2占用大量内存
+var testPasswordConfig = config.PasswordConfig{Algorithm: "bcrypt", BcryptCost: 4}
+
+// newTestDB 创建包含全部业务表的测试数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return testutil.NewDB(t, migration.Models()...)
+}
+
+// createTestUser 创建指定角色的用户
+func createTestUser(t *testing.T, db *gorm.DB, username, role string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: username + "@example.com", Password: "x", Role: role, Status: models.UserStatusActive}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// newTestPackageService 创建不连接存储的包服务
+func newTestPackageService(t *testing.T, db *gorm.DB) *PackageService {
+	t.Helper()
+	return NewPackageService(db, nil, nil, config.PackagesConfig{})
+}
+
+// createTestPackage 创建属于owner的包
+func createTestPackage(t *testing.T, db *gorm.DB, name string, owner *models.User, private bool) *models.Package {
+	t.Helper()
+	pkg := &models.Package{Name: name, OwnerID: owner.ID}
+	if err := db.Create(pkg).Error; err != nil {
+		t.Fatalf("failed to create package: %v", err)
+	}
+	// default:false的字段为零值时会被GORM忽略，单独更新
+	if private {
+		if err := db.Model(pkg).Update("is_private", true).Error; err != nil {
+			t.Fatalf("failed to mark package private: %v", err)
+		}
+		pkg.IsPrivate = true
+	}
+	return pkg
+}
+
+// createTestVersion 创建包版本，可通过modify在写入前修改字段
+func createTestVersion(t *testing.T, db *gorm.DB, pkg *models.Package, version string, modify func(v *models.PackageVersion)) *models.PackageVersion {
+	t.Helper()
+	v := &models.PackageVersion{
+		PackageID:  pkg.ID,
+		Version:    version,
+		FileSize:   100,
+		MinIOPath:  "packages/" + pkg.Name + "/" + version,
+		UploaderID: pkg.OwnerID,
+		ScanStatus: models.ScanStatusActive,
+	}
+	if modify != nil {
+		modify(v)
+	}
+	if err := db.Create(v).Error; err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"webservice/internal/models"
+)
+
+// maxHighlightTerms 最多高亮的搜索词数量
+const maxHighlightTerms = 3
+
+// highlightTerms 按空白拆分搜索词，去重后最多保留maxHighlightTerms个
+func highlightTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(query) {
+		key := strings.ToLower(term)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, term)
+		if len(terms) == maxHighlightTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// HighlightText 用<em>标签包裹text中不区分大小写匹配的terms
+// 同一位置匹配多个词时取最长的一个；文本中的其他内容会做HTML转义，结果可直接嵌入页面
+func HighlightText(text string, terms []string) string {
+	var b strings.Builder
+	plainStart := 0
+	for i := 0; i < len(text); {
+		matchLen := 0
+		for _, term := range terms {
+			if n := matchFoldPrefix(text[i:], term); n > matchLen {
+				matchLen = n
+			}
+		}
+		if matchLen == 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[plainStart:i]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[i : i+matchLen]))
+		b.WriteString("</em>")
+		i += matchLen
+		plainStart = i
+	}
+	b.WriteString(html.EscapeString(text[plainStart:]))
+	return b.String()
+}
+
+// matchFoldPrefix 判断s是否以term开头（不区分大小写），返回匹配部分在s中的字节长度，不匹配返回0
+func matchFoldPrefix(s, term string) int {
+	if term == "" {
+		return 0
+	}
+	n := 0
+	for _, tr := range term {
+		if n >= len(s) {
+			return 0
+		}
+		sr, size := utf8.DecodeRuneInString(s[n:])
+		if !strings.EqualFold(string(sr), string(tr)) {
+			return 0
+		}
+		n += size
+	}
+	return n
+}
+
+// highlightPackages 为搜索结果填充name_highlighted和description_highlighted
+func highlightPackages(packages []models.Package, query string) {
+	terms := highlightTerms(query)
+	if len(terms) == 0 {
+		return
+	}
+	for i := range packages {
+		packages[i].NameHighlighted = HighlightText(packages[i].Name, terms)
+		packages[i].DescriptionHighlighted = HighlightText(packages[i].Description, terms)
+	}
+}
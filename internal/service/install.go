@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"webservice/internal/metrics"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// flaggedDownloads 疑似爬虫流量的下载数，这些下载不计入下载数和安装数
+var flaggedDownloads = metrics.NewCounterVec(
+	"downloads_flagged_total",
+	"Total number of downloads flagged as automated traffic and excluded from download and install counters.",
+)
+
+// defaultInstallDedupWindow 未配置时同一用户/IP重复下载同一版本不重复计入安装数的时间窗口
+const defaultInstallDedupWindow = time.Hour
+
+// installDeduper 安装计数去重缓存（进程内），记录窗口内已计数的版本+访问者
+type installDeduper struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // key -> 计数时间
+	lastSweep time.Time
+}
+
+// newInstallDeduper 创建去重缓存，window<=0时使用默认窗口
+func newInstallDeduper(window time.Duration) *installDeduper {
+	if window <= 0 {
+		window = defaultInstallDedupWindow
+	}
+	return &installDeduper{window: window, seen: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+// installKey 去重键：登录用户按用户ID，匿名访问按IP
+func installKey(versionID uint, userID *uint, ipAddress string) string {
+	key := strconv.FormatUint(uint64(versionID), 10)
+	if userID != nil {
+		return key + "|u:" + strconv.FormatUint(uint64(*userID), 10)
+	}
+	return key + "|ip:" + ipAddress
+}
+
+// allow 窗口内首次出现的key返回true并记录
+func (d *installDeduper) allow(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 每个窗口清理一次过期记录，避免缓存无限增长
+	if now.Sub(d.lastSweep) >= d.window {
+		for k, t := range d.seen {
+			if now.Sub(t) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	if t, ok := d.seen[key]; ok && now.Sub(t) < d.window {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// completionReader 读取到末尾（io.EOF）时调用一次onComplete
+// 客户端中途断开或校验失败时不会读到io.EOF，因此只有完整成功的下载才会触发
+type completionReader struct {
+	io.ReadCloser
+	onComplete func()
+	once       sync.Once
+}
+
+// Read 读取数据，遇到io.EOF时触发完成回调
+func (r *completionReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err == io.EOF {
+		r.once.Do(r.onComplete)
+	}
+	return n, err
+}
+
+// recordInstall 完整下载后按去重窗口累加安装数
+func (s *PackageService) recordInstall(pkgVersion *models.PackageVersion, userID *uint, ipAddress string) {
+	if !s.installs.allow(installKey(pkgVersion.ID, userID, ipAddress), time.Now()) {
+		return
+	}
+	go func() {
+		if err := s.db.Model(&models.PackageVersion{}).Where("id = ?", pkgVersion.ID).
+			UpdateColumn("install_count", gorm.Expr("install_count + ?", 1)).Error; err != nil {
+			fmt.Printf("Warning: failed to update install count: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+)
+
+func TestInstallDeduperWindow(t *testing.T) {
+	d := newInstallDeduper(time.Minute)
+	now := time.Now()
+	alice := uint(1)
+
+	if !d.allow(installKey(1, &alice, "192.0.2.1"), now) {
+		t.Fatal("first install was not counted")
+	}
+	if d.allow(installKey(1, &alice, "198.51.100.1"), now.Add(30*time.Second)) {
+		t.Error("same user counted twice within the window (IP is ignored for logged-in users)")
+	}
+	if !d.allow(installKey(2, &alice, "192.0.2.1"), now) {
+		t.Error("another version of the same user was not counted")
+	}
+	if !d.allow(installKey(1, nil, "192.0.2.1"), now) || d.allow(installKey(1, nil, "192.0.2.1"), now) {
+		t.Error("anonymous installs are not deduplicated by IP")
+	}
+	if !d.allow(installKey(1, &alice, "192.0.2.1"), now.Add(time.Minute)) {
+		t.Error("install after the window was not counted")
+	}
+
+	// 过了一个窗口后访问时清理过期记录
+	d.allow("other", now.Add(3*time.Minute))
+	if len(d.seen) != 1 {
+		t.Errorf("%d entries left after the sweep, want only the new one", len(d.seen))
+	}
+}
+
+func TestCompletionReaderFiresOnceAtEOF(t *testing.T) {
+	calls := 0
+	r := &completionReader{ReadCloser: io.NopCloser(strings.NewReader("0123456789")), onComplete: func() { calls++ }}
+
+	buf := make([]byte, 4)
+	if _, err := r.Read(buf); err != nil || calls != 0 {
+		t.Fatalf("partial read: err = %v, calls = %d; want no completion yet", err, calls)
+	}
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(buf); err != io.EOF {
+		t.Fatalf("read after EOF = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("onComplete called %d times, want 1", calls)
+	}
+}
+
+func TestInstallCountedOncePerCompleteDownload(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "demo", owner, false)
+	version, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	download := func(userID *uint, ip string, full, flagged bool) {
+		t.Helper()
+		reader, _, err := s.DownloadPackageVersion(context.Background(), "demo", "1.0.0", userID, ip, "curl/8.4.0", flagged)
+		if err != nil {
+			t.Fatalf("DownloadPackageVersion: %v", err)
+		}
+		defer reader.Close()
+		if full {
+			if _, err := io.ReadAll(reader); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+		} else if _, err := reader.Read(make([]byte, 3)); err != nil {
+			t.Fatalf("partial read: %v", err)
+		}
+	}
+
+	download(&owner.ID, "192.0.2.1", true, false)
+	download(&owner.ID, "192.0.2.2", true, false) // 同一用户在窗口内重复下载
+	download(nil, "192.0.2.3", false, false)      // 中途断开
+	download(nil, "192.0.2.4", true, true)        // 疑似爬虫
+	download(nil, "192.0.2.5", true, false)
+	download(nil, "192.0.2.5", true, false)
+
+	waitForInstallCount(t, s, version.ID, 2)
+}
+
+// waitForInstallCount 等待异步累加的安装数达到want，并确认之后不再变化
+func waitForInstallCount(t *testing.T, s *PackageService, versionID uint, want int64) {
+	t.Helper()
+	count := func() int64 {
+		var v models.PackageVersion
+		if err := s.db.First(&v, versionID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return v.InstallCount
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for count() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := count(); got != want {
+		t.Fatalf("install_count = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+)
+
+// 未配置时的完整性校验参数
+const (
+	defaultIntegrityBatchSize = 20
+	corruptedReportLimit      = 100
+)
+
+// 完整性校验结果
+const (
+	IntegrityResultOK        = "ok"
+	IntegrityResultCorrupted = "corrupted"
+	IntegrityResultFailed    = "failed" // 读取失败（如网络错误），下次重试
+)
+
+// integrityChecks 完整性校验次数，按结果区分；corrupted非零时应告警
+var integrityChecks = metrics.NewCounterVec(
+	"storage_integrity_checks_total",
+	"Total number of stored object integrity checks.",
+	"result",
+)
+
+// IntegrityService 存储完整性校验服务，抽样读取对象重新计算SHA256并与上传时记录的FileHash比对
+type IntegrityService struct {
+	db          *gorm.DB
+	minioClient *minio.Client
+	paused      bool
+	batchSize   int
+	maxBytes    int64
+	limiter     *rate.Limiter
+}
+
+// NewIntegrityService 创建存储完整性校验服务实例
+func NewIntegrityService(db *gorm.DB, minioClient *minio.Client, cfg config.IntegrityConfig) *IntegrityService {
+	s := &IntegrityService{
+		db:          db,
+		minioClient: minioClient,
+		paused:      cfg.Paused,
+		batchSize:   cfg.BatchSize,
+		maxBytes:    cfg.MaxBytesPerRun,
+		limiter:     minio.NewBandwidthLimiter(cfg.BandwidthBytesPerSec),
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultIntegrityBatchSize
+	}
+	return s
+}
+
+// IntegrityResult 一次校验的结果
+type IntegrityResult struct {
+	Checked   int   `json:"checked"`
+	Corrupted int   `json:"corrupted"`
+	Failed    int   `json:"failed"`
+	Bytes     int64 `json:"bytes"`
+	Skipped   bool  `json:"skipped"` // 已暂停
+}
+
+// integrityCandidate 待校验的版本
+type integrityCandidate struct {
+	ID          uint
+	PackageName string
+	Version     string
+	MinIOPath   string
+	FileHash    string
+	FileSize    int64
+	StorageTier string
+}
+
+// VerifySample 按最久未校验优先选取最多batchSize个版本校验，读取字节数达到maxBytes后停止
+// 内容一致时更新last_verified_at并清除损坏标记；不一致或对象丢失时标记为损坏并记录错误日志；读取失败的版本保持不变，下次重试
+func (s *IntegrityService) VerifySample(ctx context.Context) (*IntegrityResult, error) {
+	result := &IntegrityResult{}
+	if s.paused {
+		result.Skipped = true
+		return result, nil
+	}
+	if s.minioClient == nil {
+		return nil, errors.New("file storage is not available")
+	}
+
+	var candidates []integrityCandidate
+	err := s.db.WithContext(ctx).Table("package_versions AS pv").
+		Select("pv.id, p.name AS package_name, pv.version, pv.min_io_path, pv.file_hash, pv.file_size, pv.storage_tier").
+		Joins("JOIN packages p ON p.id = pv.package_id").
+		Where("pv.deleted_at IS NULL AND pv.min_io_path <> '' AND pv.file_hash <> ''").
+		Order("pv.last_verified_at IS NOT NULL, pv.last_verified_at, pv.id").
+		Limit(s.batchSize).
+		Scan(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to select versions to verify: %w", err)
+	}
+
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		// 至少校验一个版本，避免单个大文件超过预算后永远无法校验
+		if s.maxBytes > 0 && result.Checked > 0 && result.Bytes+candidate.FileSize > s.maxBytes {
+			break
+		}
+
+		status, n, err := s.verify(ctx, candidate)
+		result.Bytes += n
+		integrityChecks.Inc(status)
+		switch status {
+		case IntegrityResultFailed:
+			result.Failed++
+			logger.Warnf("Integrity check of %s@%s failed: %v", candidate.PackageName, candidate.Version, err)
+			continue
+		case IntegrityResultCorrupted:
+			result.Corrupted++
+			logger.Errorf("Stored object corrupted: %s@%s (%s): %v", candidate.PackageName, candidate.Version, candidate.MinIOPath, err)
+		}
+		result.Checked++
+
+		update := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("id = ?", candidate.ID).
+			Updates(map[string]interface{}{"last_verified_at": time.Now().UTC(), "corrupted": status == IntegrityResultCorrupted})
+		if update.Error != nil {
+			return result, fmt.Errorf("failed to record integrity check: %w", update.Error)
+		}
+	}
+	return result, nil
+}
+
+// verify 读取对象并计算SHA256，返回校验结果和读取的字节数
+func (s *IntegrityService) verify(ctx context.Context, candidate integrityCandidate) (string, int64, error) {
+	store := objectStore(s.minioClient, &models.PackageVersion{StorageTier: candidate.StorageTier})
+	reader, _, err := store.DownloadObject(ctx, candidate.MinIOPath)
+	if err != nil {
+		exists, existsErr := store.ObjectExists(ctx, candidate.MinIOPath)
+		if existsErr == nil && !exists {
+			return IntegrityResultCorrupted, 0, errors.New("object is missing")
+		}
+		return IntegrityResultFailed, 0, err
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	n, err := io.Copy(hasher, minio.NewThrottledReader(ctx, reader, s.limiter, minio.ThrottleScopeIntegrity))
+	if err != nil {
+		return IntegrityResultFailed, n, err
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != candidate.FileHash {
+		return IntegrityResultCorrupted, n, fmt.Errorf("sha256 mismatch: expected %s, got %s", candidate.FileHash, actual)
+	}
+	return IntegrityResultOK, n, nil
+}
+
+// GetReport 获取完整性校验概况及损坏的版本列表
+func (s *IntegrityService) GetReport(ctx context.Context) (*models.IntegrityReport, error) {
+	report := &models.IntegrityReport{Paused: s.paused, CorruptedVersions: []models.CorruptedVersion{}}
+
+	base := func() *gorm.DB {
+		return s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("min_io_path <> '' AND file_hash <> ''")
+	}
+	if err := base().Where("last_verified_at IS NOT NULL").Count(&report.Verified).Error; err != nil {
+		return nil, fmt.Errorf("failed to count verified versions: %w", err)
+	}
+	if err := base().Where("last_verified_at IS NULL").Count(&report.NeverVerified).Error; err != nil {
+		return nil, fmt.Errorf("failed to count unverified versions: %w", err)
+	}
+	if err := base().Where("corrupted = ?", true).Count(&report.Corrupted).Error; err != nil {
+		return nil, fmt.Errorf("failed to count corrupted versions: %w", err)
+	}
+
+	var oldest models.PackageVersion
+	err := base().Where("last_verified_at IS NOT NULL").Order("last_verified_at").Select("last_verified_at").First(&oldest).Error
+	if err == nil {
+		report.OldestVerifiedAt = oldest.LastVerifiedAt
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find oldest verification: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Table("package_versions AS pv").
+		Select("p.name AS package_name, pv.version, pv.min_io_path, pv.last_verified_at").
+		Joins("JOIN packages p ON p.id = pv.package_id").
+		Where("pv.deleted_at IS NULL AND pv.corrupted = ?", true).
+		Order("pv.last_verified_at DESC").
+		Limit(corruptedReportLimit).
+		Scan(&report.CorruptedVersions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list corrupted versions: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestIntegrityVerifySampleFindsMissingObject(t *testing.T) {
+	s := newUploadTestService(t)
+	ctx := context.Background()
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+	if _, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	broken, err := uploadTestVersion(s, pkg, "2.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.minioClient.DeleteObject(ctx, broken.MinIOPath); err != nil {
+		t.Fatal(err)
+	}
+
+	integrity := NewIntegrityService(s.db, s.minioClient, config.IntegrityConfig{})
+	result, err := integrity.VerifySample(ctx)
+	if err != nil {
+		t.Fatalf("VerifySample: %v", err)
+	}
+	if result.Checked != 2 || result.Corrupted != 1 || result.Failed != 0 {
+		t.Errorf("result = %+v, want 2 checked and 1 corrupted", result)
+	}
+
+	report, err := integrity.GetReport(ctx)
+	if err != nil {
+		t.Fatalf("GetReport: %v", err)
+	}
+	if report.Verified != 2 || report.NeverVerified != 0 || report.Corrupted != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.CorruptedVersions) != 1 || report.CorruptedVersions[0].Version != "2.0.0" || report.CorruptedVersions[0].MinIOPath != broken.MinIOPath {
+		t.Errorf("corrupted versions = %+v", report.CorruptedVersions)
+	}
+}
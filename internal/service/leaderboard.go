@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxLeaderboardEntries 每个周期保存的排行榜条目数上限
+	maxLeaderboardEntries = 1000
+	// leaderboardRetention 历史周期条目的保留时间，超过后由计算任务删除
+	leaderboardRetention = 2 * 365 * 24 * time.Hour
+	// leaderboardKeyPrefix 排行榜在Redis中的键前缀，每个周期三个键：
+	// {prefix}{period}:meta 周期开始和计算时间，{prefix}{period}:items 按名次排列的条目列表，{prefix}{period}:ranks 包ID -> 排名
+	leaderboardKeyPrefix = "leaderboard:"
+
+	// DefaultLeaderboardLimit 默认返回的排行榜条目数
+	DefaultLeaderboardLimit = 50
+	// MaxLeaderboardLimit 单次最多返回的排行榜条目数
+	MaxLeaderboardLimit = 100
+	// DefaultLeaderboardHistoryWeeks 排名走势默认覆盖的周数
+	DefaultLeaderboardHistoryWeeks = 12
+	// MaxLeaderboardHistoryWeeks 排名走势最多覆盖的周数
+	MaxLeaderboardHistoryWeeks = 104
+)
+
+// LeaderboardPeriods 所有排行榜周期
+var LeaderboardPeriods = []string{models.LeaderboardWeekly, models.LeaderboardMonthly, models.LeaderboardAllTime}
+
+// packageScore 包在一个周期内的得分
+type packageScore struct {
+	PackageID uint
+	Downloads int64
+}
+
+// leaderboardSnapshot 一个周期的当前排行榜
+type leaderboardSnapshot struct {
+	periodStart time.Time
+	computedAt  time.Time
+	items       []models.LeaderboardItem
+}
+
+// leaderboardMeta 排行榜快照在Redis中保存的周期信息
+type leaderboardMeta struct {
+	PeriodStart time.Time `json:"period_start"`
+	ComputedAt  time.Time `json:"computed_at"`
+}
+
+// LeaderboardService 包排行榜服务
+// 排行榜由LeaderboardJob定期计算，写入leaderboard_entries后发布到Redis；排行榜和当前排名只从Redis读取，不在请求中计算或查询条目表
+// 排名走势按周期读取leaderboard_entries中的历史条目
+type LeaderboardService struct {
+	db  *gorm.DB
+	rdb *redis.Client // 可能为nil（未配置Redis），此时排行榜和当前排名返回ErrLeaderboardUnavailable
+}
+
+// NewLeaderboardService 创建排行榜服务
+func NewLeaderboardService(db *gorm.DB, rdb *redis.Client) *LeaderboardService {
+	return &LeaderboardService{db: db, rdb: rdb}
+}
+
+// IsValidLeaderboardPeriod 检查排行榜周期
+func IsValidLeaderboardPeriod(period string) bool {
+	for _, p := range LeaderboardPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// leaderboardPeriodStart 返回t所在周期的开始时间（UTC）；累计榜按周保存快照，使用所在周的开始时间
+func leaderboardPeriodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == models.LeaderboardMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	// 周一为一周的开始
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// nextLeaderboardPeriod 返回下一个周期的开始时间
+func nextLeaderboardPeriod(period string, start time.Time) time.Time {
+	if period == models.LeaderboardMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// ComputeLeaderboards 重新计算所有周期的排行榜，替换当前周期的条目并删除超过保留时间的历史条目，返回写入的条目数
+// 得分为周期内公开包的有效下载次数（不含疑似爬虫流量），得分相同的包排名相同
+func (s *LeaderboardService) ComputeLeaderboards(ctx context.Context) (int, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "LeaderboardService.ComputeLeaderboards")
+	defer span.Finish()
+
+	now := time.Now().UTC()
+	total := 0
+	for _, period := range LeaderboardPeriods {
+		periodStart := leaderboardPeriodStart(period, now)
+
+		query := s.db.WithContext(ctx).Table("package_downloads d").
+			Select("pv.package_id AS package_id, COUNT(*) AS downloads").
+			Joins("JOIN package_versions pv ON pv.id = d.package_version_id").
+			Joins("JOIN packages p ON p.id = pv.package_id").
+			Where("d.flagged = ? AND p.is_private = ? AND p.deleted_at IS NULL", false, false)
+		if period != models.LeaderboardAllTime {
+			query = query.Where("d.download_time >= ?", periodStart)
+		}
+		var scores []packageScore
+		err := query.Group("pv.package_id").
+			Order("downloads DESC, pv.package_id ASC").
+			Limit(maxLeaderboardEntries).
+			Scan(&scores).Error
+		if err != nil {
+			return total, fmt.Errorf("failed to compute %s leaderboard: %w", period, err)
+		}
+
+		entries := make([]models.LeaderboardEntry, 0, len(scores))
+		for i, sc := range scores {
+			rank := i + 1
+			if i > 0 && sc.Downloads == scores[i-1].Downloads {
+				rank = entries[i-1].Rank
+			}
+			entries = append(entries, models.LeaderboardEntry{
+				PeriodType:  period,
+				PeriodStart: periodStart,
+				Rank:        rank,
+				PackageID:   sc.PackageID,
+				Score:       float64(sc.Downloads),
+				ComputedAt:  now,
+			})
+		}
+
+		// 只替换当前周期，读取方在事务提交前仍看到上一次的结果
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("period_type = ? AND period_start = ?", period, periodStart).Delete(&models.LeaderboardEntry{}).Error; err != nil {
+				return fmt.Errorf("failed to clear %s leaderboard: %w", period, err)
+			}
+			if len(entries) == 0 {
+				return nil
+			}
+			if err := tx.CreateInBatches(entries, 500).Error; err != nil {
+				return fmt.Errorf("failed to save %s leaderboard: %w", period, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(entries)
+	}
+
+	if err := s.db.WithContext(ctx).Where("period_start < ?", now.Add(-leaderboardRetention)).Delete(&models.LeaderboardEntry{}).Error; err != nil {
+		return total, fmt.Errorf("failed to delete expired leaderboard entries: %w", err)
+	}
+
+	if err := s.PublishLeaderboards(ctx); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// PublishLeaderboards 把leaderboard_entries中各周期最近一次计算的排行榜写入Redis，替换之前发布的内容
+// 每个周期在一个MULTI事务中替换，读取方看到的总是完整的一次结果；未配置Redis时跳过
+func (s *LeaderboardService) PublishLeaderboards(ctx context.Context) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "LeaderboardService.PublishLeaderboards")
+	defer span.Finish()
+
+	if s.rdb == nil {
+		logger.Warn("Redis is not configured, leaderboards are not published")
+		return nil
+	}
+
+	for _, period := range LeaderboardPeriods {
+		snapshot, err := s.loadSnapshot(ctx, period)
+		if err != nil {
+			return err
+		}
+		if err := s.publishSnapshot(ctx, period, snapshot); err != nil {
+			return fmt.Errorf("failed to publish %s leaderboard: %w", period, err)
+		}
+	}
+	return nil
+}
+
+// publishSnapshot 在一个事务中替换一个周期的Redis键，snapshot为nil时删除
+func (s *LeaderboardService) publishSnapshot(ctx context.Context, period string, snapshot *leaderboardSnapshot) error {
+	metaKey, itemsKey, ranksKey := leaderboardKeys(period)
+
+	var meta []byte
+	var items []interface{}
+	ranks := map[string]interface{}{}
+	if snapshot != nil {
+		var err error
+		meta, err = json.Marshal(leaderboardMeta{PeriodStart: snapshot.periodStart, ComputedAt: snapshot.computedAt})
+		if err != nil {
+			return err
+		}
+		for _, item := range snapshot.items {
+			encoded, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			items = append(items, encoded)
+
+			rank, err := json.Marshal(models.PackageRank{
+				Rank:         item.Rank,
+				Score:        item.Score,
+				PreviousRank: item.PreviousRank,
+				RankDelta:    item.RankDelta,
+				PeriodStart:  snapshot.periodStart,
+			})
+			if err != nil {
+				return err
+			}
+			ranks[strconv.FormatUint(uint64(item.Package.ID), 10)] = rank
+		}
+	}
+
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, metaKey, itemsKey, ranksKey)
+		if snapshot == nil {
+			return nil
+		}
+		pipe.Set(ctx, metaKey, meta, 0)
+		if len(items) > 0 {
+			pipe.RPush(ctx, itemsKey, items...)
+			pipe.HSet(ctx, ranksKey, ranks)
+		}
+		return nil
+	})
+	return err
+}
+
+// leaderboardKeys 返回一个周期的Redis键
+func leaderboardKeys(period string) (meta, items, ranks string) {
+	prefix := leaderboardKeyPrefix + period
+	return prefix + ":meta", prefix + ":items", prefix + ":ranks"
+}
+
+// GetLeaderboard 获取排行榜的前limit名，尚未计算过时返回空列表
+func (s *LeaderboardService) GetLeaderboard(ctx context.Context, period string, limit int) (*models.LeaderboardResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "LeaderboardService.GetLeaderboard")
+	defer span.Finish()
+
+	if !IsValidLeaderboardPeriod(period) {
+		return nil, ErrInvalidLeaderboardPeriod
+	}
+	if limit <= 0 {
+		limit = DefaultLeaderboardLimit
+	}
+	if limit > MaxLeaderboardLimit {
+		limit = MaxLeaderboardLimit
+	}
+
+	if s.rdb == nil {
+		return nil, ErrLeaderboardUnavailable
+	}
+
+	metaKey, itemsKey, _ := leaderboardKeys(period)
+	var metaCmd *redis.StringCmd
+	var itemsCmd *redis.StringSliceCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		metaCmd = pipe.Get(ctx, metaKey)
+		itemsCmd = pipe.LRange(ctx, itemsKey, 0, int64(limit-1))
+		return nil
+	})
+	resp := &models.LeaderboardResponse{Period: period, Items: []models.LeaderboardItem{}}
+	if errors.Is(err, redis.Nil) {
+		// 尚未发布过该周期的排行榜
+		return resp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s leaderboard: %w", period, err)
+	}
+
+	var meta leaderboardMeta
+	if err := json.Unmarshal([]byte(metaCmd.Val()), &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode %s leaderboard: %w", period, err)
+	}
+	resp.PeriodStart = &meta.PeriodStart
+	resp.ComputedAt = &meta.ComputedAt
+	for _, encoded := range itemsCmd.Val() {
+		var item models.LeaderboardItem
+		if err := json.Unmarshal([]byte(encoded), &item); err != nil {
+			return nil, fmt.Errorf("failed to decode %s leaderboard: %w", period, err)
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp, nil
+}
+
+// GetPackageRanks 获取公开包在各周期排行榜中的当前排名
+func (s *LeaderboardService) GetPackageRanks(ctx context.Context, packageName string) (*models.PackageRankResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "LeaderboardService.GetPackageRanks")
+	defer span.Finish()
+
+	pkg, err := s.findPublicPackage(ctx, packageName)
+	if err != nil {
+		return nil, err
+	}
+	if s.rdb == nil {
+		return nil, ErrLeaderboardUnavailable
+	}
+
+	field := strconv.FormatUint(uint64(pkg.ID), 10)
+	cmds := make(map[string]*redis.StringCmd, len(LeaderboardPeriods))
+	_, err = s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, period := range LeaderboardPeriods {
+			_, _, ranksKey := leaderboardKeys(period)
+			cmds[period] = pipe.HGet(ctx, ranksKey, field)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read leaderboard ranks: %w", err)
+	}
+
+	resp := &models.PackageRankResponse{Package: pkg.Name, Ranks: make(map[string]*models.PackageRank, len(LeaderboardPeriods))}
+	for _, period := range LeaderboardPeriods {
+		resp.Ranks[period] = nil
+		encoded, err := cmds[period].Result()
+		if errors.Is(err, redis.Nil) {
+			// 未上榜或尚未发布
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s leaderboard rank: %w", period, err)
+		}
+		var rank models.PackageRank
+		if err := json.Unmarshal([]byte(encoded), &rank); err != nil {
+			return nil, fmt.Errorf("failed to decode %s leaderboard rank: %w", period, err)
+		}
+		resp.Ranks[period] = &rank
+	}
+	return resp, nil
+}
+
+// GetRankHistory 获取公开包最近若干周的排名走势，每个周期一个点，未上榜的周期rank为空
+func (s *LeaderboardService) GetRankHistory(ctx context.Context, packageName, period string, weeks int) (*models.LeaderboardHistoryResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "LeaderboardService.GetRankHistory")
+	defer span.Finish()
+
+	if !IsValidLeaderboardPeriod(period) {
+		return nil, ErrInvalidLeaderboardPeriod
+	}
+	if weeks <= 0 {
+		weeks = DefaultLeaderboardHistoryWeeks
+	}
+	if weeks > MaxLeaderboardHistoryWeeks {
+		weeks = MaxLeaderboardHistoryWeeks
+	}
+
+	pkg, err := s.findPublicPackage(ctx, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 月榜取覆盖这些周的自然月
+	now := time.Now().UTC()
+	current := leaderboardPeriodStart(period, now)
+	since := leaderboardPeriodStart(period, leaderboardPeriodStart(models.LeaderboardWeekly, now).AddDate(0, 0, -7*(weeks-1)))
+
+	var entries []models.LeaderboardEntry
+	err = s.db.WithContext(ctx).
+		Where("period_type = ? AND package_id = ? AND period_start >= ?", period, pkg.ID, since).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank history: %w", err)
+	}
+	byStart := make(map[time.Time]models.LeaderboardEntry, len(entries))
+	for _, e := range entries {
+		byStart[e.PeriodStart.UTC()] = e
+	}
+
+	resp := &models.LeaderboardHistoryResponse{Package: pkg.Name, Period: period, Points: []models.LeaderboardHistoryPoint{}}
+	for start := since; !start.After(current); start = nextLeaderboardPeriod(period, start) {
+		point := models.LeaderboardHistoryPoint{PeriodStart: start}
+		if e, ok := byStart[start]; ok {
+			rank := e.Rank
+			point.Rank = &rank
+			point.Score = e.Score
+		}
+		resp.Points = append(resp.Points, point)
+	}
+	return resp, nil
+}
+
+// findPublicPackage 按名称查找公开包，私有包按不存在处理
+func (s *LeaderboardService) findPublicPackage(ctx context.Context, packageName string) (*models.Package, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ? AND is_private = ?", packageName, false).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	return &pkg, nil
+}
+
+// loadSnapshot 读取一个周期最近一次计算的排行榜和上一周期的排名，没有条目时返回nil
+func (s *LeaderboardService) loadSnapshot(ctx context.Context, period string) (*leaderboardSnapshot, error) {
+	var latest []models.LeaderboardEntry
+	err := s.db.WithContext(ctx).Where("period_type = ?", period).
+		Order("period_start DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find latest %s leaderboard: %w", period, err)
+	}
+	if len(latest) == 0 {
+		return nil, nil
+	}
+	periodStart := latest[0].PeriodStart.UTC()
+
+	var entries []models.LeaderboardEntry
+	err = s.db.WithContext(ctx).Where("period_type = ? AND period_start = ?", period, periodStart).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "rank"}}).Order("package_id ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s leaderboard: %w", period, err)
+	}
+
+	// 上一周期的排名，用于计算排名变化
+	var previous []models.LeaderboardEntry
+	err = s.db.WithContext(ctx).
+		Where("period_type = ? AND period_start = (?)", period,
+			s.db.Model(&models.LeaderboardEntry{}).Select("MAX(period_start)").
+				Where("period_type = ? AND period_start < ?", period, periodStart)).
+		Find(&previous).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load previous %s leaderboard: %w", period, err)
+	}
+	previousRanks := make(map[uint]int, len(previous))
+	for _, e := range previous {
+		previousRanks[e.PackageID] = e.Rank
+	}
+
+	ids := make([]uint, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.PackageID)
+	}
+	var packages []*models.Package
+	if len(ids) > 0 {
+		// 计算后被设为私有或删除的包不再展示
+		err = s.db.WithContext(ctx).Preload("Owner").
+			Where("id IN ? AND is_private = ?", ids, false).
+			Find(&packages).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load leaderboard packages: %w", err)
+		}
+	}
+	packagesByID := make(map[uint]*models.Package, len(packages))
+	for _, p := range packages {
+		packagesByID[p.ID] = p
+	}
+
+	snapshot := &leaderboardSnapshot{
+		periodStart: periodStart,
+		computedAt:  latest[0].ComputedAt,
+		items:       make([]models.LeaderboardItem, 0, len(entries)),
+	}
+	for _, e := range entries {
+		pkg, ok := packagesByID[e.PackageID]
+		if !ok {
+			continue
+		}
+		item := models.LeaderboardItem{Rank: e.Rank, Score: e.Score, Package: pkg}
+		if prev, ok := previousRanks[e.PackageID]; ok {
+			delta := prev - e.Rank
+			item.PreviousRank = &prev
+			item.RankDelta = &delta
+		}
+		snapshot.items = append(snapshot.items, item)
+	}
+	return snapshot, nil
+}
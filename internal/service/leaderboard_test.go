@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"webservice/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// newTestLeaderboardService 创建连接miniredis的排行榜服务
+func newTestLeaderboardService(t *testing.T, db *gorm.DB) (*LeaderboardService, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewLeaderboardService(db, rdb), mr
+}
+
+// recordTestDownloads 为版本写入n条有效下载记录
+func recordTestDownloads(t *testing.T, db *gorm.DB, version *models.PackageVersion, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := db.Create(&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "127.0.0.1"}).Error; err != nil {
+			t.Fatalf("failed to record download: %v", err)
+		}
+	}
+}
+
+func TestLeaderboardServedFromRedis(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	popular := createTestPackage(t, db, "popular", owner, false)
+	quiet := createTestPackage(t, db, "quiet", owner, false)
+	hidden := createTestPackage(t, db, "hidden", owner, true)
+	recordTestDownloads(t, db, createTestVersion(t, db, popular, "1.0.0", nil), 3)
+	recordTestDownloads(t, db, createTestVersion(t, db, quiet, "1.0.0", nil), 1)
+	recordTestDownloads(t, db, createTestVersion(t, db, hidden, "1.0.0", nil), 5)
+
+	s, _ := newTestLeaderboardService(t, db)
+	if _, err := s.ComputeLeaderboards(ctx); err != nil {
+		t.Fatalf("ComputeLeaderboards: %v", err)
+	}
+
+	// 读取只访问Redis：清空条目表后结果不变
+	if err := db.Where("1 = 1").Delete(&models.LeaderboardEntry{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := s.GetLeaderboard(ctx, models.LeaderboardWeekly, 10)
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(resp.Items) != 2 || resp.PeriodStart == nil || resp.ComputedAt == nil {
+		t.Fatalf("leaderboard = %+v, want 2 items with period info", resp)
+	}
+	if resp.Items[0].Package.Name != "popular" || resp.Items[0].Rank != 1 || resp.Items[0].Score != 3 {
+		t.Errorf("first item = %+v, want popular ranked 1 with score 3", resp.Items[0])
+	}
+	if resp.Items[1].Package.Name != "quiet" || resp.Items[1].Rank != 2 {
+		t.Errorf("second item = %+v, want quiet ranked 2", resp.Items[1])
+	}
+
+	limited, err := s.GetLeaderboard(ctx, models.LeaderboardWeekly, 1)
+	if err != nil {
+		t.Fatalf("GetLeaderboard with limit: %v", err)
+	}
+	if len(limited.Items) != 1 {
+		t.Errorf("limit 1 returned %d items", len(limited.Items))
+	}
+
+	ranks, err := s.GetPackageRanks(ctx, "quiet")
+	if err != nil {
+		t.Fatalf("GetPackageRanks: %v", err)
+	}
+	for _, period := range LeaderboardPeriods {
+		if rank := ranks.Ranks[period]; rank == nil || rank.Rank != 2 || rank.Score != 1 {
+			t.Errorf("%s rank = %+v, want rank 2 with score 1", period, rank)
+		}
+	}
+	if _, err := s.GetPackageRanks(ctx, "hidden"); err == nil {
+		t.Error("private package rank returned without error")
+	}
+}
+
+func TestLeaderboardEmptyBeforePublish(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "unranked", owner, false)
+	s, _ := newTestLeaderboardService(t, db)
+
+	resp, err := s.GetLeaderboard(ctx, models.LeaderboardMonthly, 10)
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(resp.Items) != 0 || resp.PeriodStart != nil {
+		t.Errorf("leaderboard = %+v, want empty", resp)
+	}
+
+	ranks, err := s.GetPackageRanks(ctx, "unranked")
+	if err != nil {
+		t.Fatalf("GetPackageRanks: %v", err)
+	}
+	for _, period := range LeaderboardPeriods {
+		if rank, ok := ranks.Ranks[period]; !ok || rank != nil {
+			t.Errorf("%s rank = %+v, want null", period, rank)
+		}
+	}
+}
+
+func TestLeaderboardPublishReplacesPreviousResult(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "once", owner, false)
+	recordTestDownloads(t, db, createTestVersion(t, db, pkg, "1.0.0", nil), 1)
+	s, mr := newTestLeaderboardService(t, db)
+
+	if _, err := s.ComputeLeaderboards(ctx); err != nil {
+		t.Fatalf("ComputeLeaderboards: %v", err)
+	}
+	if err := db.Where("1 = 1").Delete(&models.PackageDownload{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ComputeLeaderboards(ctx); err != nil {
+		t.Fatalf("second ComputeLeaderboards: %v", err)
+	}
+
+	// 当前周期没有下载时所有周期都没有条目，Redis中的旧结果被删除
+	resp, err := s.GetLeaderboard(ctx, models.LeaderboardWeekly, 10)
+	if err != nil {
+		t.Fatalf("GetLeaderboard: %v", err)
+	}
+	if len(resp.Items) != 0 {
+		t.Errorf("leaderboard = %+v, want previous result replaced", resp.Items)
+	}
+	if mr.Exists(leaderboardKeyPrefix + models.LeaderboardWeekly + ":items") {
+		t.Error("stale items key left in redis")
+	}
+}
+
+func TestLeaderboardUnavailableWithoutRedis(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "pkg", owner, false)
+	s := NewLeaderboardService(db, nil)
+
+	if _, err := s.ComputeLeaderboards(ctx); err != nil {
+		t.Fatalf("ComputeLeaderboards without redis: %v", err)
+	}
+	if _, err := s.GetLeaderboard(ctx, models.LeaderboardWeekly, 10); !errors.Is(err, ErrLeaderboardUnavailable) {
+		t.Errorf("GetLeaderboard error = %v, want ErrLeaderboardUnavailable", err)
+	}
+	if _, err := s.GetPackageRanks(ctx, "pkg"); !errors.Is(err, ErrLeaderboardUnavailable) {
+		t.Errorf("GetPackageRanks error = %v, want ErrLeaderboardUnavailable", err)
+	}
+}
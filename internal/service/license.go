@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+)
+
+// GetUnrecognizedLicenses 列出无法识别为合法标识符的许可证值（启动迁移未能自动规范化的部分），按使用的包数量降序
+func (s *PackageService) GetUnrecognizedLicenses(ctx context.Context) ([]models.UnrecognizedLicense, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetUnrecognizedLicenses")
+	defer span.Finish()
+
+	var rows []struct {
+		License string
+		Count   int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Package{}).
+		Select("license, COUNT(*) AS count").
+		Where("license <> ''").
+		Group("license").
+		Order("count DESC, license").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count package licenses: %w", err)
+	}
+
+	result := make([]models.UnrecognizedLicense, 0)
+	for _, row := range rows {
+		if _, ok := s.licenses.Canonical(row.License); ok {
+			continue
+		}
+		result = append(result, models.UnrecognizedLicense{
+			License:      row.License,
+			PackageCount: row.Count,
+			Suggestions:  s.licenses.Suggest(row.License),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// objectMigrationBatchSize 每批读取的版本数
+const objectMigrationBatchSize = 200
+
+// ObjectMigrationProgress 对象布局迁移进度
+type ObjectMigrationProgress struct {
+	Total    int64 `json:"total"`
+	Migrated int   `json:"migrated"`
+	Skipped  int   `json:"skipped"` // 已符合目标方案
+	Failed   int   `json:"failed"`
+}
+
+// ObjectMigrationService 对象布局迁移服务，将已有包文件迁移到新的命名方案
+type ObjectMigrationService struct {
+	db          *gorm.DB
+	minioClient *minio.Client
+}
+
+// NewObjectMigrationService 创建对象布局迁移服务实例
+func NewObjectMigrationService(db *gorm.DB, minioClient *minio.Client) *ObjectMigrationService {
+	return &ObjectMigrationService{db: db, minioClient: minioClient}
+}
+
+// objectMigrationRow 待迁移的版本
+type objectMigrationRow struct {
+	ID          uint
+	PackageName string
+	Version     string
+	MinIOPath   string
+	FileHash    string
+	FileSize    int64
+	StorageTier string
+}
+
+// Migrate 将所有版本的对象迁移到scheme方案：复制到新键、校验内容、更新MinIOPath，最后删除旧对象
+// 单个版本失败时清理已复制的对象并继续；dryRun为true时只统计需要迁移的数量
+// progress在每个版本处理后调用
+func (s *ObjectMigrationService) Migrate(ctx context.Context, scheme string, dryRun bool, progress func(ObjectMigrationProgress)) (*ObjectMigrationProgress, error) {
+	if s.minioClient == nil {
+		return nil, errors.New("file storage is not available")
+	}
+	namer, err := minio.NewObjectNamer(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ObjectMigrationProgress{}
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Count(&result.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count package versions: %w", err)
+	}
+
+	var lastID uint
+	for {
+		var rows []objectMigrationRow
+		err := s.db.WithContext(ctx).Table("package_versions AS pv").
+			Select("pv.id, p.name AS package_name, pv.version, pv.min_io_path, pv.file_hash, pv.file_size, pv.storage_tier").
+			Joins("JOIN packages p ON p.id = pv.package_id").
+			Where("pv.deleted_at IS NULL AND pv.id > ?", lastID).
+			Order("pv.id").Limit(objectMigrationBatchSize).
+			Scan(&rows).Error
+		if err != nil {
+			return result, fmt.Errorf("failed to load package versions: %w", err)
+		}
+		if len(rows) == 0 {
+			return result, nil
+		}
+
+		for _, row := range rows {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			lastID = row.ID
+
+			target := namer.ObjectKey(row.PackageName, row.Version)
+			switch {
+			case target == row.MinIOPath:
+				result.Skipped++
+			case dryRun:
+				result.Migrated++
+			default:
+				if err := s.migrateObject(ctx, row, target); err != nil {
+					logger.Warnf("Failed to migrate %s@%s from %s to %s: %v", row.PackageName, row.Version, row.MinIOPath, target, err)
+					result.Failed++
+				} else {
+					result.Migrated++
+				}
+			}
+
+			if progress != nil {
+				progress(*result)
+			}
+		}
+	}
+}
+
+// migrateObject 迁移单个对象：复制、校验、更新记录、删除旧对象
+func (s *ObjectMigrationService) migrateObject(ctx context.Context, row objectMigrationRow, target string) error {
+	// 冷存储中的版本在冷存储bucket内迁移
+	store := objectStore(s.minioClient, &models.PackageVersion{StorageTier: row.StorageTier})
+	if err := store.CopyObject(ctx, row.MinIOPath, target); err != nil {
+		return err
+	}
+
+	if err := s.verifyObject(ctx, store, target, row); err != nil {
+		store.DeleteObject(ctx, target)
+		return err
+	}
+
+	// 迁移期间版本可能被移入或移出冷存储，只在记录仍位于同一bucket时更新
+	update := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("id = ? AND storage_tier = ?", row.ID, row.StorageTier).
+		Update("min_io_path", target)
+	if update.Error == nil && update.RowsAffected == 0 {
+		update.Error = errors.New("package version storage tier was changed concurrently")
+	}
+	if err := update.Error; err != nil {
+		store.DeleteObject(ctx, target)
+		return fmt.Errorf("failed to update object key: %w", err)
+	}
+
+	// 记录已指向新对象，旧对象删除失败只会残留文件
+	if err := store.DeleteObject(ctx, row.MinIOPath); err != nil {
+		logger.Warnf("Failed to delete migrated object %s: %v", row.MinIOPath, err)
+	}
+	return nil
+}
+
+// verifyObject 读取复制后的对象，校验内容的SHA256（未记录哈希时校验大小）
+func (s *ObjectMigrationService) verifyObject(ctx context.Context, store *minio.Client, objectName string, row objectMigrationRow) error {
+	reader, _, err := store.DownloadObject(ctx, objectName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, reader)
+	if err != nil {
+		return fmt.Errorf("failed to read copied object: %w", err)
+	}
+
+	if row.FileHash != "" {
+		if hex.EncodeToString(hasher.Sum(nil)) != row.FileHash {
+			return ErrChecksumMismatch
+		}
+		return nil
+	}
+	if size != row.FileSize {
+		return fmt.Errorf("copied object size mismatch: expected %d, got %d", row.FileSize, size)
+	}
+	return nil
+}
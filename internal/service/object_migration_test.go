@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"webservice/internal/minio"
+	"webservice/internal/models"
+)
+
+func TestObjectMigrationMovesObjectsToNewScheme(t *testing.T) {
+	s := newUploadTestService(t)
+	ctx := context.Background()
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+	uploaded, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	oldKey := uploaded.MinIOPath
+
+	migrator := NewObjectMigrationService(s.db, s.minioClient)
+	dryRun, err := migrator.Migrate(ctx, minio.NamingFlat, true, nil)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if dryRun.Migrated != 1 {
+		t.Errorf("dry run = %+v, want 1 to migrate", dryRun)
+	}
+
+	result, err := migrator.Migrate(ctx, minio.NamingFlat, false, nil)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if result.Migrated != 1 || result.Failed != 0 {
+		t.Fatalf("result = %+v, want 1 migrated", result)
+	}
+
+	var stored models.PackageVersion
+	if err := s.db.First(&stored, uploaded.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := "packages/app/app-1.0.0.pkg"; stored.MinIOPath != want {
+		t.Fatalf("object key = %q, want %q", stored.MinIOPath, want)
+	}
+	if exists, err := s.minioClient.ObjectExists(ctx, oldKey); err != nil || exists {
+		t.Errorf("old object exists = %v, %v, want deleted", exists, err)
+	}
+
+	reader, _, err := s.DownloadPackageVersion(ctx, "app", "1.0.0", &owner.ID, "127.0.0.1", "test", false)
+	if err != nil {
+		t.Fatalf("download after migration: %v", err)
+	}
+	defer reader.Close()
+	if content, _ := io.ReadAll(reader); string(content) != "content of app@1.0.0" {
+		t.Errorf("content = %q", content)
+	}
+
+	// 再次执行时已符合目标方案
+	again, err := migrator.Migrate(ctx, minio.NamingFlat, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Skipped != 1 || again.Migrated != 0 {
+		t.Errorf("second run = %+v, want 1 skipped", again)
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// RenameVersionObject 修正版本文件的对象键（如早期命名逻辑错误时），无需重新上传
+// destination为空时使用当前命名方案生成的键；dryRun时只校验源对象存在、目标不存在并返回计划
+// 执行顺序为复制到新键、更新MinIOPath、删除旧对象，任一时刻记录都指向可访问的对象；更新记录失败时删除已复制的对象
+// 调用方需要对包有admin_override权限
+func (s *PackageService) RenameVersionObject(ctx context.Context, packageName, version, destination string, dryRun bool, caller PackageCaller) (*models.RenameObjectResult, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.RenameVersionObject")
+	defer span.Finish()
+
+	if s.minioClient == nil {
+		return nil, errors.New("file storage is not available")
+	}
+
+	var pkgVersion models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package version not found")
+		}
+		return nil, fmt.Errorf("failed to find package version: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, caller, PackageRightAdminOverride); err != nil {
+		return nil, err
+	}
+
+	destination = strings.TrimPrefix(strings.TrimSpace(destination), "/")
+	if destination == "" {
+		destination = s.minioClient.ObjectKey(pkgVersion.Package.Name, pkgVersion.Version)
+	}
+	result := &models.RenameObjectResult{
+		Package:     pkgVersion.Package.Name,
+		Version:     pkgVersion.Version,
+		Source:      pkgVersion.MinIOPath,
+		Destination: destination,
+		DryRun:      dryRun,
+	}
+	if destination == pkgVersion.MinIOPath {
+		return nil, ErrSameObjectKey
+	}
+
+	// 冷存储中的版本在冷存储bucket内重命名
+	store := objectStore(s.minioClient, &pkgVersion)
+	if err := s.checkObjectRename(ctx, store, pkgVersion.MinIOPath, destination); err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err := store.CopyObject(ctx, pkgVersion.MinIOPath, destination); err != nil {
+		return nil, err
+	}
+
+	// 只在记录仍指向源对象时更新，避免覆盖并发的修改
+	update := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Where("id = ? AND min_io_path = ? AND storage_tier = ?", pkgVersion.ID, pkgVersion.MinIOPath, pkgVersion.StorageTier).
+		Update("min_io_path", destination)
+	if update.Error == nil && update.RowsAffected == 0 {
+		update.Error = errors.New("package version object key was changed concurrently")
+	}
+	if update.Error != nil {
+		// 回滚：记录仍指向源对象，删除已复制的目标对象
+		if err := store.DeleteObject(ctx, destination); err != nil {
+			logger.Warnf("Failed to remove copied object %s after rollback: %v", destination, err)
+		}
+		return nil, fmt.Errorf("failed to update object key: %w", update.Error)
+	}
+
+	// 记录已指向新对象，旧对象删除失败只会残留文件
+	if err := store.DeleteObject(ctx, pkgVersion.MinIOPath); err != nil {
+		logger.Warnf("Failed to delete renamed object %s: %v", pkgVersion.MinIOPath, err)
+	}
+
+	result.Renamed = true
+	return result, nil
+}
+
+// checkObjectRename 校验源对象存在，目标对象不存在且未被其他版本记录使用
+func (s *PackageService) checkObjectRename(ctx context.Context, store *minio.Client, source, destination string) error {
+	exists, err := store.ObjectExists(ctx, source)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrObjectNotFound
+	}
+
+	exists, err = store.ObjectExists(ctx, destination)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrObjectExists
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("min_io_path = ?", destination).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check object key usage: %w", err)
+	}
+	if count > 0 {
+		return ErrObjectExists
+	}
+	return nil
+}
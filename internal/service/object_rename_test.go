@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestRenameVersionObject(t *testing.T) {
+	s := newUploadTestService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, s.db, "root", models.RoleAdmin)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+	uploaded, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if _, err := uploadTestVersion(s, pkg, "2.0.0", owner.ID); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	caller := PackageCaller{UserID: &admin.ID, AdminOverride: true}
+	const destination = "packages/app/fixed-1.0.0.pkg"
+
+	if _, err := s.RenameVersionObject(ctx, "app", "1.0.0", "", false, PackageCaller{UserID: &owner.ID}); err == nil {
+		t.Error("rename without admin override succeeded")
+	}
+
+	plan, err := s.RenameVersionObject(ctx, "app", "1.0.0", destination, true, caller)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if plan.Renamed || plan.Source != uploaded.MinIOPath || plan.Destination != destination {
+		t.Errorf("dry run = %+v", plan)
+	}
+
+	// 目标已被其他版本使用
+	var other models.PackageVersion
+	if err := s.db.Where("package_id = ? AND version = ?", pkg.ID, "2.0.0").First(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RenameVersionObject(ctx, "app", "1.0.0", other.MinIOPath, false, caller); !errors.Is(err, ErrObjectExists) {
+		t.Errorf("rename onto another version's object = %v, want ErrObjectExists", err)
+	}
+
+	result, err := s.RenameVersionObject(ctx, "app", "1.0.0", destination, false, caller)
+	if err != nil {
+		t.Fatalf("RenameVersionObject: %v", err)
+	}
+	if !result.Renamed {
+		t.Errorf("result = %+v, want renamed", result)
+	}
+	var stored models.PackageVersion
+	if err := s.db.First(&stored, uploaded.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.MinIOPath != destination {
+		t.Errorf("object key = %q, want %q", stored.MinIOPath, destination)
+	}
+	if exists, _ := s.minioClient.ObjectExists(ctx, uploaded.MinIOPath); exists {
+		t.Error("source object still exists")
+	}
+	if exists, _ := s.minioClient.ObjectExists(ctx, destination); !exists {
+		t.Error("destination object missing")
+	}
+
+	if _, err := s.RenameVersionObject(ctx, "app", "1.0.0", destination, false, caller); !errors.Is(err, ErrSameObjectKey) {
+		t.Errorf("rename to the current key = %v, want ErrSameObjectKey", err)
+	}
+}
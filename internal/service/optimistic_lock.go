@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrStaleUpdate 记录在客户端读取后已被其他请求修改
+	ErrStaleUpdate = errors.New("conflict_stale_update")
+	// ErrIfVersionRequired 配置了require_if_version但更新请求未携带if_version
+	ErrIfVersionRequired = errors.New("if_version is required")
+)
+
+// StaleUpdateError 乐观锁冲突，附带记录的当前状态，客户端据此合并修改后重试
+type StaleUpdateError struct {
+	Current interface{} // *models.Package 或 *models.PackageVersion
+}
+
+// Error 实现error接口
+func (e *StaleUpdateError) Error() string {
+	return ErrStaleUpdate.Error()
+}
+
+// Unwrap 返回ErrStaleUpdate
+func (e *StaleUpdateError) Unwrap() error {
+	return ErrStaleUpdate
+}
+
+// checkIfVersion 配置要求时拒绝未携带if_version的更新请求
+func (s *PackageService) checkIfVersion(ifVersion *int) error {
+	if s.requireIfVersion && ifVersion == nil {
+		return ErrIfVersionRequired
+	}
+	return nil
+}
+
+// lockedUpdate 执行带乐观锁的更新并将lock_version加1
+// ifVersion为nil时不比较版本号（最后写入生效）；返回false表示lock_version已变化，记录未更新
+func lockedUpdate(db *gorm.DB, model interface{}, id uint, currentVersion int, ifVersion *int, updates map[string]interface{}) (bool, error) {
+	if len(updates) == 0 {
+		// 没有要修改的字段时只比较客户端读取的版本号
+		return ifVersion == nil || *ifVersion == currentVersion, nil
+	}
+
+	updates["lock_version"] = gorm.Expr("lock_version + 1")
+	query := db.Model(model).Where("id = ?", id)
+	if ifVersion != nil {
+		query = query.Where("lock_version = ?", *ifVersion)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+)
+
+func TestUpdatePackageStaleIfVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "locked-pkg", owner, false)
+	s := newTestPackageService(t, db)
+
+	// 两个客户端都读取到lock_version=1，先提交的成功，后提交的得到冲突和当前状态
+	ifVersion := 1
+	first, err := s.UpdatePackage(context.Background(), "locked-pkg", &models.UpdatePackageRequest{Description: "first", IfVersion: &ifVersion}, owner.ID)
+	if err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if first.LockVersion != 2 {
+		t.Errorf("lock_version after first update = %d, want 2", first.LockVersion)
+	}
+
+	_, err = s.UpdatePackage(context.Background(), "locked-pkg", &models.UpdatePackageRequest{Description: "second", IfVersion: &ifVersion}, owner.ID)
+	var staleErr *StaleUpdateError
+	if !errors.As(err, &staleErr) || !errors.Is(err, ErrStaleUpdate) {
+		t.Fatalf("second update error = %v, want StaleUpdateError", err)
+	}
+	current, ok := staleErr.Current.(*models.Package)
+	if !ok {
+		t.Fatalf("conflict current = %T, want *models.Package", staleErr.Current)
+	}
+	if current.Description != "first" || current.LockVersion != 2 {
+		t.Errorf("conflict current = description %q lock_version %d, want first/2", current.Description, current.LockVersion)
+	}
+
+	// 使用冲突返回的lock_version重试成功
+	retry := current.LockVersion
+	updated, err := s.UpdatePackage(context.Background(), "locked-pkg", &models.UpdatePackageRequest{Description: "second", IfVersion: &retry}, owner.ID)
+	if err != nil {
+		t.Fatalf("retry update: %v", err)
+	}
+	if updated.Description != "second" || updated.LockVersion != 3 {
+		t.Errorf("after retry = description %q lock_version %d, want second/3", updated.Description, updated.LockVersion)
+	}
+}
+
+func TestConcurrentUpdatePackageSameIfVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "race-pkg", owner, false)
+	s := newTestPackageService(t, db)
+
+	ifVersion := 1
+	errs := runConcurrently(8, func(i int) error {
+		req := &models.UpdatePackageRequest{Description: fmt.Sprintf("writer-%d", i), IfVersion: &ifVersion}
+		_, err := s.UpdatePackage(context.Background(), "race-pkg", req, owner.ID)
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrStaleUpdate)
+
+	var pkg models.Package
+	if err := db.Where("name = ?", "race-pkg").First(&pkg).Error; err != nil {
+		t.Fatal(err)
+	}
+	if pkg.LockVersion != 2 {
+		t.Errorf("lock_version = %d, want 2 after a single successful update", pkg.LockVersion)
+	}
+	for i, err := range errs {
+		if err == nil && pkg.Description != fmt.Sprintf("writer-%d", i) {
+			t.Errorf("description = %q, want the successful writer-%d", pkg.Description, i)
+		}
+	}
+}
+
+func TestConcurrentUpdatePackageVersionSameIfVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "race-pkg", owner, false)
+	createTestVersion(t, db, pkg, "1.0.0", nil)
+	s := newTestPackageService(t, db)
+
+	ifVersion := 1
+	errs := runConcurrently(8, func(i int) error {
+		description := fmt.Sprintf("writer-%d", i)
+		req := &models.UpdatePackageVersionRequest{Description: &description, IfVersion: &ifVersion}
+		_, err := s.UpdatePackageVersion(context.Background(), "race-pkg", "1.0.0", req, owner.ID)
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrStaleUpdate)
+
+	// 冲突返回的当前状态是版本记录
+	stale := "stale"
+	_, err := s.UpdatePackageVersion(context.Background(), "race-pkg", "1.0.0", &models.UpdatePackageVersionRequest{Description: &stale, IfVersion: &ifVersion}, owner.ID)
+	var staleErr *StaleUpdateError
+	if !errors.As(err, &staleErr) {
+		t.Fatalf("stale version update error = %v, want StaleUpdateError", err)
+	}
+	if current, ok := staleErr.Current.(*models.PackageVersion); !ok || current.LockVersion != 2 {
+		t.Errorf("conflict current = %#v, want the version at lock_version 2", staleErr.Current)
+	}
+}
+
+func TestUpdatePackageWithoutIfVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "lww-pkg", owner, false)
+	createTestVersion(t, db, pkg, "1.0.0", nil)
+
+	// 默认未携带if_version时最后写入生效
+	s := newTestPackageService(t, db)
+	for _, description := range []string{"one", "two"} {
+		updated, err := s.UpdatePackage(context.Background(), "lww-pkg", &models.UpdatePackageRequest{Description: description}, owner.ID)
+		if err != nil {
+			t.Fatalf("update %q: %v", description, err)
+		}
+		if updated.Description != description {
+			t.Errorf("description = %q, want %q", updated.Description, description)
+		}
+	}
+
+	// 配置要求if_version时拒绝
+	strict := NewPackageService(db, nil, nil, config.PackagesConfig{RequireIfVersion: true})
+	_, err := strict.UpdatePackage(context.Background(), "lww-pkg", &models.UpdatePackageRequest{Description: "three"}, owner.ID)
+	if !errors.Is(err, ErrIfVersionRequired) {
+		t.Errorf("update without if_version error = %v, want ErrIfVersionRequired", err)
+	}
+	description := "three"
+	_, err = strict.UpdatePackageVersion(context.Background(), "lww-pkg", "1.0.0", &models.UpdatePackageVersionRequest{Description: &description}, owner.ID)
+	if !errors.Is(err, ErrIfVersionRequired) {
+		t.Errorf("version update without if_version error = %v, want ErrIfVersionRequired", err)
+	}
+}
@@ -7,37 +7,248 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"webservice/internal/config"
+	"webservice/internal/events"
+	"webservice/internal/license"
+	"webservice/internal/logger"
 	"webservice/internal/minio"
 	"webservice/internal/models"
+	"webservice/internal/outbox"
+	"webservice/internal/scan"
+	"webservice/internal/tracer"
+	"webservice/internal/validation"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PackageService 包管理服务
 type PackageService struct {
 	db          *gorm.DB
 	minioClient *minio.Client
+	eventBus    events.Publisher
+	installs    *installDeduper
+
+	reservedNames   map[string]struct{} // 保留包名（小写）
+	deletedNameHold time.Duration       // 删除后包名的保留期
+
+	downloadURLMode string          // 下载链接模式：presign或app-signed
+	downloadSigner  *downloadSigner // app-signed模式的下载令牌签名器
+	publicBaseURL   string          // 下载链接的外部访问地址，为空时返回相对路径
+
+	licenses *license.Registry // 合法的许可证标识符
+
+	uploadLock        uploadLocker  // 同一包版本的上传互斥锁
+	uploadLockTimeout time.Duration // 等待上传锁的最长时间
+	useAdvisoryLocks  bool          // 写入版本记录时使用PostgreSQL事务级咨询锁，其他数据库锁定包记录
+
+	deleteLimiter *deleteLimiter // 限制同时执行的删除事务数，nil表示不限
+
+	userUploadLimit int64    // 每个用户的上传带宽上限（字节/秒），0表示不限速
+	userLimiters    sync.Map // userID -> *rate.Limiter
+
+	storageQuota          int64 // 每个用户的存储配额（字节），0表示不限
+	quotaSoftLimitPercent int   // 软限制占配额的百分比
+
+	requireIfVersion bool // 修改包和版本元数据时必须携带if_version
+
+	maxLongDescription int // 包详细介绍的最大字符数
+
+	requireHTTPSLinks bool // 包的主页、仓库地址等链接只允许https
+
+	enforceChangelogFormat bool   // 检查版本更新日志的Markdown格式
+	maxChangelogBytes      int    // 更新日志的最大字节数
+	changelogOverflow      string // 更新日志超长时的处理方式：reject、truncate
+
+	dependencyCheck         string        // 发布时依赖检查模式：off、warn、enforce
+	dependencyCheckMaxDepth int           // 依赖解析的最大深度
+	dependencyCheckTimeout  time.Duration // 依赖解析的最长时间
+
+	ecosystem ecosystemCache // 生态统计缓存
+	stats     statsCache     // 包统计缓存
+
+	archiveTotals sync.Map // 版本ID -> 版本文件中的条目总数
+
+	downloadLimiter *packageDownloadLimiter // 按包名的下载限流，nil表示不限流
+
+	presignedUpload config.PresignedUploadConfig // 预签名上传配置
+
+	icon config.PackageIconConfig // 包图标限制
+
+	scanner         scan.Scanner  // 病毒扫描引擎，未配置时为scan.Noop
+	scanMode        string        // 扫描模式：async、sync
+	scanSyncTimeout time.Duration // sync模式下上传请求等待扫描结果的最长时间
+
+	// 合并相同的并发元数据读取，热门包的大量并发请求只执行一次查询
+	packageReads flightGroup[*models.Package]
+	versionReads flightGroup[*packageVersionsPage]
 }
 
 // NewPackageService 创建包管理服务实例
-func NewPackageService(db *gorm.DB, minioClient *minio.Client) *PackageService {
+func NewPackageService(db *gorm.DB, minioClient *minio.Client, eventBus events.Publisher, cfg config.PackagesConfig) *PackageService {
+	if eventBus == nil {
+		eventBus = events.NullEventBus{}
+	}
+	if cfg.DeletedNameHold <= 0 {
+		cfg.DeletedNameHold = defaultDeletedNameHold
+	}
+	if cfg.MaxLongDescriptionLength <= 0 {
+		cfg.MaxLongDescriptionLength = defaultMaxLongDescriptionLength
+	}
+	if cfg.QuotaSoftLimitPercent <= 0 || cfg.QuotaSoftLimitPercent > 100 {
+		cfg.QuotaSoftLimitPercent = defaultQuotaSoftLimitPercent
+	}
+	if cfg.DependencyCheckMaxDepth <= 0 {
+		cfg.DependencyCheckMaxDepth = defaultDependencyCheckMaxDepth
+	}
+	if cfg.DependencyCheckTimeout <= 0 {
+		cfg.DependencyCheckTimeout = defaultDependencyCheckTimeout
+	}
+	if cfg.PresignedUpload.URLTTL <= 0 {
+		cfg.PresignedUpload.URLTTL = defaultUploadURLTTL
+	}
+	if cfg.PresignedUpload.SyncHashMaxBytes <= 0 {
+		cfg.PresignedUpload.SyncHashMaxBytes = defaultSyncHashMaxBytes
+	}
+	if cfg.MaxChangelogBytes <= 0 {
+		cfg.MaxChangelogBytes = defaultMaxChangelogBytes
+	}
+	if cfg.Icon.MaxBytes <= 0 {
+		cfg.Icon.MaxBytes = defaultIconMaxBytes
+	}
+	if cfg.Icon.MaxDimension <= 0 {
+		cfg.Icon.MaxDimension = defaultIconMaxDimension
+	}
+	if cfg.Scan.SyncTimeout <= 0 {
+		cfg.Scan.SyncTimeout = defaultScanSyncTimeout
+	}
+	// 配置在启动时已校验（见main.go），这里出错只会发生在未经校验的调用方，按不扫描处理
+	scanner, err := scan.New(cfg.Scan)
+	if err != nil {
+		logger.Warnf("Invalid scan configuration, malware scanning disabled: %v", err)
+		scanner = scan.Noop{}
+	}
 	return &PackageService{
 		db:          db,
 		minioClient: minioClient,
+		eventBus:    eventBus,
+		installs:    newInstallDeduper(cfg.InstallDedupWindow),
+
+		reservedNames:   newReservedNames(cfg.ReservedNames),
+		deletedNameHold: cfg.DeletedNameHold,
+
+		downloadURLMode: cfg.DownloadURLMode,
+		downloadSigner:  newDownloadSigner(cfg.DownloadURLSecret, cfg.DownloadURLTTL),
+		publicBaseURL:   strings.TrimSuffix(cfg.PublicBaseURL, "/"),
+
+		licenses: license.NewRegistry(cfg.CustomLicenses, cfg.AllowedLicenses),
+
+		uploadLock:        newUploadLocker(db, cfg.UploadLock),
+		uploadLockTimeout: cfg.UploadLockTimeout,
+		useAdvisoryLocks:  db.Dialector.Name() == "postgres",
+
+		deleteLimiter: newDeleteLimiter(cfg.MaxConcurrentDeletes, cfg.DeleteQueueTimeout),
+
+		userUploadLimit: cfg.UserUploadBandwidthLimitBytesPerSec,
+
+		storageQuota:          cfg.StorageQuotaBytes,
+		quotaSoftLimitPercent: cfg.QuotaSoftLimitPercent,
+
+		requireIfVersion: cfg.RequireIfVersion,
+
+		maxLongDescription: cfg.MaxLongDescriptionLength,
+
+		requireHTTPSLinks: cfg.RequireHTTPSLinks,
+
+		enforceChangelogFormat: cfg.EnforceChangelogFormat,
+		maxChangelogBytes:      cfg.MaxChangelogBytes,
+		changelogOverflow:      cfg.ChangelogOverflow,
+
+		dependencyCheck:         cfg.DependencyCheck,
+		dependencyCheckMaxDepth: cfg.DependencyCheckMaxDepth,
+		dependencyCheckTimeout:  cfg.DependencyCheckTimeout,
+
+		downloadLimiter: newPackageDownloadLimiter(cfg.DownloadRateLimit),
+
+		presignedUpload: cfg.PresignedUpload,
+
+		icon: cfg.Icon,
+
+		scanner:         scanner,
+		scanMode:        cfg.Scan.Mode,
+		scanSyncTimeout: cfg.Scan.SyncTimeout,
+
+		stats: statsCache{ttl: cfg.StatsCacheTTL},
+	}
+}
+
+// defaultMaxLongDescriptionLength 未配置时包详细介绍的最大字符数
+const defaultMaxLongDescriptionLength = 20000
+
+// checkLongDescription 检查包详细介绍的字符数（按Unicode字符计算）
+func (s *PackageService) checkLongDescription(text string) error {
+	if n := utf8.RuneCountInString(text); n > s.maxLongDescription {
+		return fmt.Errorf("%w: %d characters, at most %d", ErrLongDescriptionTooLong, n, s.maxLongDescription)
+	}
+	return nil
+}
+
+// maxLinkLength 链接字段的最大长度，与数据库列长度一致
+const maxLinkLength = 255
+
+// checkLinks 检查包的链接字段，字段名和值成对传入，为空的字段不检查
+func (s *PackageService) checkLinks(fieldValues ...string) error {
+	for i := 0; i+1 < len(fieldValues); i += 2 {
+		if fieldValues[i+1] == "" {
+			continue
+		}
+		if n := len(fieldValues[i+1]); n > maxLinkLength {
+			return fmt.Errorf("%w: %s is %d bytes, at most %d", ErrInvalidLinkURL, fieldValues[i], n, maxLinkLength)
+		}
+		if err := validation.CheckLinkURL(fieldValues[i+1], s.requireHTTPSLinks); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrInvalidLinkURL, fieldValues[i], err)
+		}
 	}
+	return nil
 }
 
 // CreatePackage 创建包
 func (s *PackageService) CreatePackage(ctx context.Context, req *models.CreatePackageRequest, ownerID uint) (*models.Package, error) {
-	// 检查包名是否已存在
-	var existingPackage models.Package
-	if err := s.db.Where("name = ?", req.Name).First(&existingPackage).Error; err == nil {
-		return nil, errors.New("package name already exists")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("failed to check package existence: %w", err)
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.CreatePackage")
+	defer span.Finish()
+
+	// 检查包名是否已存在或被重命名的包保留为别名
+	if err := s.checkPackageNameAvailable(ctx, req.Name, 0); err != nil {
+		return nil, err
+	}
+
+	licenseID, err := s.licenses.Normalize(req.License)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLongDescription(req.LongDescription); err != nil {
+		return nil, err
+	}
+	err = s.checkLinks(
+		"homepage", req.Homepage,
+		"repository", req.Repository,
+		"funding_url", req.FundingURL,
+		"bug_tracker_url", req.BugTrackerURL,
+		"documentation", req.Documentation,
+	)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.resolveCategories(ctx, req.CategoryIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	// 处理关键词
@@ -54,42 +265,91 @@ func (s *PackageService) CreatePackage(ctx context.Context, req *models.CreatePa
 		Author:      req.Author,
 		Homepage:    req.Homepage,
 		Repository:  req.Repository,
-		License:     req.License,
+		License:     licenseID,
 		Keywords:    keywordsJSON,
 		IsPrivate:   req.IsPrivate,
 		OwnerID:     ownerID,
+
+		LongDescription: req.LongDescription,
+		FundingURL:      req.FundingURL,
+		BugTrackerURL:   req.BugTrackerURL,
+		Documentation:   req.Documentation,
+
+		RequireMonotonicVersions: req.RequireMonotonicVersions,
+		AutoPrereleaseDetection:  req.AutoPrereleaseDetection,
+		DisallowPrereleaseLatest: req.DisallowPrereleaseLatest,
+		UploadLocked:             req.UploadLocked,
 	}
 
-	if err := s.db.Create(pkg).Error; err != nil {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(pkg).Error; err != nil {
+			return err
+		}
+		if err := replacePackageCategories(tx, pkg.ID, categories); err != nil {
+			return err
+		}
+		return outbox.Append(tx, events.Event{
+			Type:        events.PackageCreated,
+			PackageID:   pkg.ID,
+			PackageName: pkg.Name,
+			UserID:      ownerID,
+		})
+	})
+	if err != nil {
+		// 并发创建同名包时预检查可能同时通过，由唯一索引兜底
+		if isDuplicateKeyError(err) {
+			return nil, ErrPackageExists
+		}
 		return nil, fmt.Errorf("failed to create package: %w", err)
 	}
+	s.stats.invalidate()
 
 	// 预加载关联数据
-	if err := s.db.Preload("Owner").First(pkg, pkg.ID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Owner").Preload("Categories").First(pkg, pkg.ID).Error; err != nil {
 		return nil, fmt.Errorf("failed to load package with associations: %w", err)
 	}
 
 	return pkg, nil
 }
 
-// GetPackage 获取包信息
-func (s *PackageService) GetPackage(ctx context.Context, packageName string) (*models.Package, error) {
-	var pkg models.Package
-	err := s.db.Preload("Owner").Preload("Versions").Where("name = ?", packageName).First(&pkg).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("package not found")
+// GetPackage 获取包信息，viewerID为nil表示匿名，私有包只有有读取权限的调用方可以获取
+func (s *PackageService) GetPackage(ctx context.Context, packageName string, viewerID *uint) (*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackage")
+	defer span.Finish()
+
+	// 相同包名的并发请求共享一次查询，每个调用方得到独立的副本
+	// 共享的查询不区分调用方，读取权限在得到结果后按各调用方分别检查
+	shared, err := s.packageReads.do(ctx, "get_package", packageName, func(ctx context.Context) (*models.Package, error) {
+		var pkg models.Package
+		err := s.db.WithContext(ctx).Preload("Owner").Preload("Versions").Preload("Categories").Where("name = ?", packageName).First(&pkg).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("package not found")
+			}
+			return nil, fmt.Errorf("failed to get package: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get package: %w", err)
+		return &pkg, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), shared, PackageCaller{UserID: viewerID}, PackageRightRead); err != nil {
+		return nil, err
 	}
 
+	pkg := *shared
+	pkg.Versions = slices.Clone(shared.Versions)
+	pkg.Categories = slices.Clone(shared.Categories)
 	return &pkg, nil
 }
 
 // UpdatePackage 更新包信息
 func (s *PackageService) UpdatePackage(ctx context.Context, packageName string, req *models.UpdatePackageRequest, userID uint) (*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.UpdatePackage")
+	defer span.Finish()
+
 	var pkg models.Package
-	if err := s.db.Where("name = ?", packageName).First(&pkg).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("package not found")
 		}
@@ -97,8 +357,21 @@ func (s *PackageService) UpdatePackage(ctx context.Context, packageName string,
 	}
 
 	// 检查权限
-	if pkg.OwnerID != userID {
-		return nil, errors.New("permission denied")
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightEditMetadata); err != nil {
+		return nil, err
+	}
+	if err := s.checkIfVersion(req.IfVersion); err != nil {
+		return nil, err
+	}
+	err := s.checkLinks(
+		"homepage", req.Homepage,
+		"repository", req.Repository,
+		"funding_url", req.FundingURL,
+		"bug_tracker_url", req.BugTrackerURL,
+		"documentation", req.Documentation,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	// 更新字段
@@ -116,34 +389,96 @@ func (s *PackageService) UpdatePackage(ctx context.Context, packageName string,
 		updates["repository"] = req.Repository
 	}
 	if req.License != "" {
-		updates["license"] = req.License
+		licenseID, err := s.licenses.Normalize(req.License)
+		if err != nil {
+			return nil, err
+		}
+		updates["license"] = licenseID
+	}
+	if req.LongDescription != "" {
+		if err := s.checkLongDescription(req.LongDescription); err != nil {
+			return nil, err
+		}
+		updates["long_description"] = req.LongDescription
+	}
+	if req.FundingURL != "" {
+		updates["funding_url"] = req.FundingURL
+	}
+	if req.BugTrackerURL != "" {
+		updates["bug_tracker_url"] = req.BugTrackerURL
+	}
+	if req.Documentation != "" {
+		updates["documentation"] = req.Documentation
 	}
 	if req.IsPrivate != nil {
 		updates["is_private"] = *req.IsPrivate
 	}
+	if req.KeepRecentVersions != nil {
+		updates["keep_recent_versions"] = *req.KeepRecentVersions
+	}
+	if req.RequireMonotonicVersions != nil {
+		updates["require_monotonic_versions"] = *req.RequireMonotonicVersions
+	}
+	if req.AutoPrereleaseDetection != nil {
+		updates["auto_prerelease_detection"] = *req.AutoPrereleaseDetection
+	}
+	if req.DisallowPrereleaseLatest != nil {
+		updates["disallow_prerelease_latest"] = *req.DisallowPrereleaseLatest
+	}
+	if req.UploadLocked != nil {
+		if pkg.UploadLocked && !*req.UploadLocked {
+			return nil, ErrUploadLockPermanent
+		}
+		updates["upload_locked"] = *req.UploadLocked
+	}
 	if len(req.Keywords) > 0 {
 		keywordsBytes, _ := json.Marshal(req.Keywords)
 		updates["keywords"] = string(keywordsBytes)
 	}
+	// category_ids为nil时不修改分类，空数组清除所有分类
+	var categories []models.Category
+	if req.CategoryIDs != nil {
+		var err error
+		if categories, err = s.resolveCategories(ctx, req.CategoryIDs); err != nil {
+			return nil, err
+		}
+		// 只修改分类时同样递增lock_version
+		updates["updated_at"] = time.Now().UTC()
+	}
 
-	if len(updates) > 0 {
-		if err := s.db.Model(&pkg).Updates(updates).Error; err != nil {
-			return nil, fmt.Errorf("failed to update package: %w", err)
+	var updated bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if updated, err = lockedUpdate(tx, &models.Package{}, pkg.ID, pkg.LockVersion, req.IfVersion, updates); err != nil || !updated {
+			return err
 		}
+		if req.CategoryIDs != nil {
+			return replacePackageCategories(tx, pkg.ID, categories)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update package: %w", err)
 	}
 
 	// 重新加载数据
-	if err := s.db.Preload("Owner").Preload("Versions").First(&pkg, pkg.ID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Owner").Preload("Versions").Preload("Categories").First(&pkg, pkg.ID).Error; err != nil {
 		return nil, fmt.Errorf("failed to reload package: %w", err)
 	}
+	if !updated {
+		return nil, &StaleUpdateError{Current: &pkg}
+	}
 
 	return &pkg, nil
 }
 
 // DeletePackage 删除包
 func (s *PackageService) DeletePackage(ctx context.Context, packageName string, userID uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.DeletePackage")
+	defer span.Finish()
+
 	var pkg models.Package
-	if err := s.db.Where("name = ?", packageName).First(&pkg).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("package not found")
 		}
@@ -151,12 +486,18 @@ func (s *PackageService) DeletePackage(ctx context.Context, packageName string,
 	}
 
 	// 检查权限
-	if pkg.OwnerID != userID {
-		return errors.New("permission denied")
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightDelete); err != nil {
+		return err
 	}
 
+	release, err := s.deleteLimiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	// 开始事务
-	tx := s.db.Begin()
+	tx := s.db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -172,22 +513,32 @@ func (s *PackageService) DeletePackage(ctx context.Context, packageName string,
 
 	// 删除MinIO中的文件
 	for _, version := range versions {
-		if err := s.minioClient.DeletePackage(ctx, packageName, version.Version); err != nil {
+		if err := objectStore(s.minioClient, &version).DeleteObject(ctx, version.MinIOPath); err != nil {
 			// 记录错误但不中断删除流程
 			fmt.Printf("Warning: failed to delete package file from MinIO: %v\n", err)
 		}
 	}
-
-	// 删除下载记录
-	if err := tx.Where("package_version_id IN (SELECT id FROM package_versions WHERE package_id = ?)", pkg.ID).Delete(&models.PackageDownload{}).Error; err != nil {
+	if pkg.IconObjectKey != "" {
+		if err := s.minioClient.DeleteObject(ctx, pkg.IconObjectKey); err != nil {
+			fmt.Printf("Warning: failed to delete package icon from MinIO: %v\n", err)
+		}
+	}
+	// 删除未完成的预签名上传留下的暂存对象，会话记录删除后清理任务不会再处理它们
+	var stagedKeys []string
+	if err := tx.Model(&models.UploadSession{}).Where("package_id = ? AND status = ?", pkg.ID, models.UploadSessionPending).Pluck("object_key", &stagedKeys).Error; err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to delete download records: %w", err)
+		return fmt.Errorf("failed to find upload sessions: %w", err)
+	}
+	for _, key := range stagedKeys {
+		if err := s.minioClient.DeleteObject(ctx, key); err != nil {
+			fmt.Printf("Warning: failed to delete staging object from MinIO: %v\n", err)
+		}
 	}
 
-	// 删除版本
-	if err := tx.Where("package_id = ?", pkg.ID).Delete(&models.PackageVersion{}).Error; err != nil {
+	// 删除与包关联的数据
+	if err := deletePackageRows(tx, pkg.ID); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("failed to delete package versions: %w", err)
+		return err
 	}
 
 	// 删除包
@@ -196,37 +547,52 @@ func (s *PackageService) DeletePackage(ctx context.Context, packageName string,
 		return fmt.Errorf("failed to delete package: %w", err)
 	}
 
-	return tx.Commit().Error
+	if err := outbox.Append(tx, events.Event{
+		Type:        events.PackageDeleted,
+		PackageID:   pkg.ID,
+		PackageName: pkg.Name,
+		UserID:      userID,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	s.stats.invalidate()
+	return nil
 }
 
 // UploadPackageVersion 上传包版本
 func (s *PackageService) UploadPackageVersion(ctx context.Context, packageName string, req *models.CreatePackageVersionRequest, fileReader io.Reader, fileSize int64, uploaderID uint) (*models.PackageVersion, error) {
-	// 查找包
-	var pkg models.Package
-	if err := s.db.Where("name = ?", packageName).First(&pkg).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("package not found")
-		}
-		return nil, fmt.Errorf("failed to find package: %w", err)
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.UploadPackageVersion")
+	defer span.Finish()
+
+	pkg, err := s.findUploadTarget(ctx, packageName, uploaderID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 检查权限
-	if pkg.OwnerID != uploaderID {
-		return nil, errors.New("permission denied")
+	// 同一版本的并发上传串行执行：后到的请求在先到的请求完成后才检查版本是否存在，已存在时不再上传文件
+	release, err := s.acquireUploadLock(ctx, pkg.ID, req.Version)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 
-	// 检查版本是否已存在
-	var existingVersion models.PackageVersion
-	if err := s.db.Where("package_id = ? AND version = ?", pkg.ID, req.Version).First(&existingVersion).Error; err == nil {
-		return nil, errors.New("version already exists")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("failed to check version existence: %w", err)
+	dependencyWarnings, err := s.checkPublishable(ctx, pkg, req, fileSize, uploaderID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 计算文件哈希
 	hasher := sha256.New()
 	fileReader = io.TeeReader(fileReader, hasher)
 
+	// 按用户限速，同一用户的并发上传共享带宽
+	fileReader = minio.NewThrottledReader(ctx, fileReader, s.userUploadLimiter(uploaderID), minio.ThrottleScopeUser)
+
 	// 上传到MinIO
 	packageInfo, err := s.minioClient.UploadPackage(ctx, packageName, req.Version, fileReader, fileSize, &minio.UploadOptions{
 		ContentType: "application/octet-stream",
@@ -234,11 +600,73 @@ func (s *PackageService) UploadPackageVersion(ctx context.Context, packageName s
 			"uploader-id": fmt.Sprintf("%d", uploaderID),
 			"description": req.Description,
 		},
+		FilenameOverride: minio.DownloadFilename(pkg.Name, req.Version),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload package to storage: %w", err)
 	}
 
+	version := newUploadedVersion(pkg, req, uploaderID, packageInfo, fmt.Sprintf("%x", hasher.Sum(nil)), dependencyWarnings)
+	return s.publishVersion(ctx, pkg, req, version)
+}
+
+// findUploadTarget 查找上传者要发布版本的包，只有包所有者和maintainer协作者可以发布，已归档的包不能发布
+func (s *PackageService) findUploadTarget(ctx context.Context, packageName string, uploaderID uint) (*models.Package, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	// 检查权限
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &uploaderID}, PackageRightPublish); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// checkPublishable 写入文件前的发布检查：版本不存在、发布策略、更新日志格式、存储配额和依赖解析
+// 发布策略可能修改req（如自动识别预发布版本），返回依赖检查产生的警告
+func (s *PackageService) checkPublishable(ctx context.Context, pkg *models.Package, req *models.CreatePackageVersionRequest, fileSize int64, uploaderID uint) ([]models.DependencyConflict, error) {
+	// 检查版本是否已存在
+	var existingVersion models.PackageVersion
+	if err := s.db.WithContext(ctx).Where("package_id = ? AND version = ?", pkg.ID, req.Version).First(&existingVersion).Error; err == nil {
+		return nil, ErrVersionExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check version existence: %w", err)
+	}
+	if err := s.checkVersionNumberReusable(ctx, pkg, req.Version); err != nil {
+		return nil, err
+	}
+
+	// 执行包的发布策略
+	if err := s.enforcePublishPolicy(ctx, pkg, req); err != nil {
+		return nil, err
+	}
+
+	// 检查更新日志大小和格式，truncate模式下超长的更新日志在这里截断
+	changelog, err := s.limitChangelog(req.Changelog)
+	if err != nil {
+		return nil, err
+	}
+	req.Changelog = changelog
+	if err := s.checkChangelog(req.Changelog); err != nil {
+		return nil, err
+	}
+
+	// 检查包所有者的存储配额
+	if err := s.checkStorageQuota(ctx, pkg.OwnerID, fileSize); err != nil {
+		return nil, err
+	}
+
+	// 检查声明的依赖能否解析，enforce模式下有冲突时拒绝上传
+	return s.checkDependencies(ctx, pkg, req.Version, req.Dependencies, uploaderID)
+}
+
+// newUploadedVersion 根据上传请求和已写入存储的文件创建版本记录（未保存）
+func newUploadedVersion(pkg *models.Package, req *models.CreatePackageVersionRequest, uploaderID uint, packageInfo *minio.PackageInfo, fileHash string, dependencyWarnings []models.DependencyConflict) *models.PackageVersion {
 	// 处理依赖关系
 	dependenciesJSON := ""
 	if len(req.Dependencies) > 0 {
@@ -246,39 +674,177 @@ func (s *PackageService) UploadPackageVersion(ctx context.Context, packageName s
 		dependenciesJSON = string(dependenciesBytes)
 	}
 
-	// 创建版本记录
 	version := &models.PackageVersion{
-		PackageID:    pkg.ID,
-		Version:      req.Version,
-		Description:  req.Description,
-		Changelog:    req.Changelog,
-		Dependencies: dependenciesJSON,
-		FileSize:     packageInfo.Size,
-		FileHash:     fmt.Sprintf("%x", hasher.Sum(nil)),
-		MinIOPath:    fmt.Sprintf("packages/%s/%s", packageName, req.Version),
-		IsPrerelease: req.IsPrerelease,
-		UploaderID:   uploaderID,
-	}
-
-	if err := s.db.Create(version).Error; err != nil {
-		// 如果数据库操作失败，尝试删除已上传的文件
-		s.minioClient.DeletePackage(ctx, packageName, req.Version)
+		PackageID:        pkg.ID,
+		Version:          req.Version,
+		Description:      req.Description,
+		Changelog:        validation.SanitizeChangelog(req.Changelog),
+		Dependencies:     dependenciesJSON,
+		FileSize:         packageInfo.Size,
+		StoredSize:       packageInfo.StoredSize,
+		CompressedStored: packageInfo.Compressed,
+		FileHash:         fileHash,
+		MinIOPath:        packageInfo.ObjectKey,
+		IsPrerelease:     req.IsPrerelease,
+		SourceRepository: req.SourceRepository,
+		SourceCommit:     req.SourceCommit,
+		BuildURL:         req.BuildURL,
+		UploaderID:       uploaderID,
+
+		DependencyWarnings: dependencyWarnings,
+		CreatedAt:          time.Now().UTC(),
+	}
+	version.PublishedManifest = models.NewPublishedManifest(pkg.Name, version)
+	return version
+}
+
+// publishVersion 保存版本记录并在同一事务中写入VersionUploaded事件，失败时删除已写入存储的文件
+// 版本已存在（并发发布同一版本）时返回ErrVersionExists，此时文件属于先写入记录的请求，不会删除
+func (s *PackageService) publishVersion(ctx context.Context, pkg *models.Package, req *models.CreatePackageVersionRequest, version *models.PackageVersion) (*models.PackageVersion, error) {
+	uploaderID := version.UploaderID
+	version.ScanStatus = s.initialScanStatus()
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 上传锁可能未启用（none）或只在进程内有效，写入前在事务内加锁并重新检查版本是否存在，
+		// 并发写入同一版本时后到的请求返回ErrVersionExists而不是唯一索引错误
+		if err := s.lockPackageVersion(tx, pkg, req.Version); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.PackageVersion{}).Where("package_id = ? AND version = ?", pkg.ID, req.Version).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check version existence: %w", err)
+		}
+		if count > 0 {
+			return ErrVersionExists
+		}
+		// 唯一索引同样包含软删除的行，重新上传已删除的版本前先清除旧记录（不可变包已在checkVersionNumberReusable中拒绝）
+		// 旧版本的下载记录、置顶、扫描结果等一并删除，不会被新版本继承，也不会因外键约束使清除失败
+		var deletedIDs []uint
+		if err := tx.Unscoped().Model(&models.PackageVersion{}).
+			Where("package_id = ? AND version = ? AND deleted_at IS NOT NULL", pkg.ID, req.Version).
+			Pluck("id", &deletedIDs).Error; err != nil {
+			return fmt.Errorf("failed to check deleted version: %w", err)
+		}
+		if len(deletedIDs) > 0 {
+			if err := deleteVersionRows(tx, deletedIDs); err != nil {
+				return err
+			}
+			if err := tx.Model(&models.UploadSession{}).Where("version_id IN ?", deletedIDs).Update("version_id", nil).Error; err != nil {
+				return fmt.Errorf("failed to detach upload sessions: %w", err)
+			}
+			if err := tx.Unscoped().Delete(&models.PackageVersion{}, deletedIDs).Error; err != nil {
+				return fmt.Errorf("failed to purge deleted version: %w", err)
+			}
+		}
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return outbox.Append(tx, events.Event{
+			Type:        events.VersionUploaded,
+			PackageID:   pkg.ID,
+			PackageName: pkg.Name,
+			Version:     version.Version,
+			UserID:      uploaderID,
+		})
+	})
+	if err != nil {
+		// 并发上传同一版本：对象属于先写入记录的请求，不能删除
+		if errors.Is(err, ErrVersionExists) || (isDuplicateKeyError(err) && s.versionExists(ctx, pkg.ID, req.Version)) {
+			return nil, ErrVersionExists
+		}
+		// 如果数据库操作失败（包括与该版本无关的唯一约束冲突），尝试删除已上传的文件
+		s.minioClient.DeleteObject(ctx, version.MinIOPath)
 		return nil, fmt.Errorf("failed to create version record: %w", err)
 	}
+	s.stats.invalidate()
+
+	// 追加版本元数据中的关键字，失败不影响已发布的版本
+	if len(req.AddKeywords) > 0 {
+		if err := s.addPackageKeywords(ctx, pkg, req.AddKeywords); err != nil {
+			fmt.Printf("Warning: failed to add package keywords: %v\n", err)
+		}
+	}
 
 	// 预加载关联数据
-	if err := s.db.Preload("Package").Preload("Uploader").First(version, version.ID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Package").Preload("Uploader").First(version, version.ID).Error; err != nil {
 		return nil, fmt.Errorf("failed to load version with associations: %w", err)
 	}
 
+	// 启用病毒扫描时，扫描完成（未发现病毒）前版本不能下载
+	s.startVersionScan(ctx, version)
+
 	return version, nil
 }
 
+// versionExists 检查包中是否已有该版本（未删除），用于判断唯一约束冲突是否由同一版本的并发发布引起
+func (s *PackageService) versionExists(ctx context.Context, packageID uint, version string) bool {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("package_id = ? AND version = ?", packageID, version).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// acquireUploadLock 获取包版本的上传锁，最多等待uploadLockTimeout
+func (s *PackageService) acquireUploadLock(ctx context.Context, packageID uint, version string) (func(), error) {
+	timeout := s.uploadLockTimeout
+	if timeout <= 0 {
+		timeout = defaultUploadLockTimeout
+	}
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return s.uploadLock.acquire(lockCtx, fmt.Sprintf("%d@%s", packageID, version))
+}
+
+// lockPackageVersion 在事务内串行化同一包版本的写入，锁在事务结束时释放
+// PostgreSQL使用按包名和版本的事务级咨询锁，不阻塞同一包其他版本的写入；其他数据库使用SELECT ... FOR UPDATE锁定包记录
+func (s *PackageService) lockPackageVersion(tx *gorm.DB, pkg *models.Package, version string) error {
+	if s.useAdvisoryLocks {
+		// 与上传锁（会话级，键为upload:前缀的哈希）使用不同的键，避免同一请求在两个连接上互相等待
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "version:"+pkg.Name+"@"+version).Error; err != nil {
+			return fmt.Errorf("failed to lock package version: %w", err)
+		}
+		return nil
+	}
+	var locked models.Package
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, pkg.ID).Error; err != nil {
+		return fmt.Errorf("failed to lock package: %w", err)
+	}
+	return nil
+}
+
+// addPackageKeywords 将关键字合并到包已有的关键字中，保持原有顺序并去重
+func (s *PackageService) addPackageKeywords(ctx context.Context, pkg *models.Package, keywords []string) error {
+	var merged []string
+	if pkg.Keywords != "" {
+		if err := json.Unmarshal([]byte(pkg.Keywords), &merged); err != nil {
+			return fmt.Errorf("failed to parse existing keywords: %w", err)
+		}
+	}
+
+	seen := make(map[string]bool, len(merged))
+	for _, keyword := range merged {
+		seen[keyword] = true
+	}
+	for _, keyword := range keywords {
+		if !seen[keyword] {
+			seen[keyword] = true
+			merged = append(merged, keyword)
+		}
+	}
+
+	keywordsBytes, _ := json.Marshal(merged)
+	return s.db.WithContext(ctx).Model(pkg).Update("keywords", string(keywordsBytes)).Error
+}
+
 // DownloadPackageVersion 下载包版本
-func (s *PackageService) DownloadPackageVersion(ctx context.Context, packageName, version string, userID *uint, ipAddress, userAgent string) (io.ReadCloser, *models.PackageVersion, error) {
+// flagged为true（疑似爬虫流量）时仍记录下载，但不计入下载数和安装数，也就不影响热门包排名
+func (s *PackageService) DownloadPackageVersion(ctx context.Context, packageName, version string, userID *uint, ipAddress, userAgent string, flagged bool) (io.ReadCloser, *models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.DownloadPackageVersion")
+	defer span.Finish()
+
 	// 查找包版本
 	var pkgVersion models.PackageVersion
-	err := s.db.Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil, errors.New("package version not found")
@@ -287,15 +853,47 @@ func (s *PackageService) DownloadPackageVersion(ctx context.Context, packageName
 	}
 
 	// 检查私有包权限
-	if pkgVersion.Package.IsPrivate && (userID == nil || pkgVersion.Package.OwnerID != *userID) {
-		return nil, nil, errors.New("access denied to private package")
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, PackageCaller{UserID: userID}, PackageRightRead); err != nil {
+		return nil, nil, err
+	}
+
+	// 扫描中和已隔离的版本不能下载
+	if err := checkScanStatus(&pkgVersion); err != nil {
+		return nil, nil, err
+	}
+
+	// 管理员设置了每月流量上限且本月已用完时拒绝下载
+	if err := checkBandwidthLimit(ctx, s.db, &pkgVersion.Package); err != nil {
+		return nil, nil, err
+	}
+
+	// 按包名限流，避免热门包占满带宽
+	if err := s.downloadLimiter.Allow(pkgVersion.Package.Name, pkgVersion.FileSize); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.checkColdDownload(ctx, &pkgVersion, userID); err != nil {
+		return nil, nil, err
 	}
 
-	// 从MinIO下载文件
-	reader, _, err := s.minioClient.DownloadPackage(ctx, packageName, version)
+	// 按记录的对象键从MinIO下载文件，配置了镜像时取最先响应的节点；冷存储中的版本从冷存储bucket读取
+	// 开启download_coalescing时同一版本的并发下载共享一次存储读取
+	reader, _, err := objectStore(s.minioClient, &pkgVersion).CoalescedDownload(ctx,
+		pkgVersion.Package.Name+"@"+pkgVersion.Version, pkgVersion.MinIOPath, pkgVersion.FileSize)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to download package from storage: %w", err)
 	}
+	if pkgVersion.FileHash != "" {
+		reader = newChecksumReader(reader, pkgVersion.FileHash)
+	}
+	// 完整读取（且校验通过）后才计入安装数，下载数仍按每次请求累加
+	if !flagged {
+		reader = &completionReader{ReadCloser: reader, onComplete: func() {
+			s.recordInstall(&pkgVersion, userID, ipAddress)
+		}}
+	} else {
+		flaggedDownloads.Inc()
+	}
 
 	// 记录下载
 	go func() {
@@ -304,24 +902,68 @@ func (s *PackageService) DownloadPackageVersion(ctx context.Context, packageName
 			UserID:           userID,
 			IPAddress:        ipAddress,
 			UserAgent:        userAgent,
+			Flagged:          flagged,
 		}
 		if err := s.db.Create(downloadRecord).Error; err != nil {
 			fmt.Printf("Warning: failed to record download: %v\n", err)
+		} else {
+			// 交由事件总线异步解析User-Agent与IP归属地
+			s.eventBus.Publish(events.Event{
+				Type:        events.DownloadRecorded,
+				PackageID:   pkgVersion.PackageID,
+				PackageName: pkgVersion.Package.Name,
+				Version:     pkgVersion.Version,
+				Payload:     map[string]interface{}{"download_id": downloadRecord.ID},
+			})
 		}
 
 		// 更新下载计数
-		if err := s.db.Model(&pkgVersion).UpdateColumn("download_count", gorm.Expr("download_count + ?", 1)).Error; err != nil {
-			fmt.Printf("Warning: failed to update download count: %v\n", err)
+		if !flagged {
+			if err := s.db.Model(&pkgVersion).UpdateColumn("download_count", gorm.Expr("download_count + ?", 1)).Error; err != nil {
+				fmt.Printf("Warning: failed to update download count: %v\n", err)
+			}
+		}
+		// 计入包当月的下载流量
+		if err := recordBandwidth(s.db, pkgVersion.PackageID, pkgVersion.FileSize, time.Now()); err != nil {
+			fmt.Printf("Warning: failed to record bandwidth usage: %v\n", err)
 		}
 	}()
 
 	return reader, &pkgVersion, nil
 }
 
-// GetPackageVersions 获取包的所有版本
-func (s *PackageService) GetPackageVersions(ctx context.Context, packageName string, page, pageSize int) (*models.PackageVersionListResponse, error) {
+// packageVersionsPage 共享的版本列表查询结果，包含用于按调用方检查读取权限的包
+type packageVersionsPage struct {
+	pkg      models.Package
+	response *models.PackageVersionListResponse
+}
+
+// GetPackageVersions 获取包的所有版本，viewerID为nil表示匿名，私有包只有有读取权限的调用方可以获取
+func (s *PackageService) GetPackageVersions(ctx context.Context, packageName string, page, pageSize int, viewerID *uint) (*models.PackageVersionListResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackageVersions")
+	defer span.Finish()
+
+	// 相同包名和分页参数的并发请求共享一次查询，每个调用方得到独立的副本，读取权限按调用方分别检查
+	key := fmt.Sprintf("%s|%d|%d", packageName, page, pageSize)
+	shared, err := s.versionReads.do(ctx, "get_package_versions", key, func(ctx context.Context) (*packageVersionsPage, error) {
+		return s.queryPackageVersions(ctx, packageName, page, pageSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &shared.pkg, PackageCaller{UserID: viewerID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+
+	response := *shared.response
+	response.Versions = slices.Clone(shared.response.Versions)
+	return &response, nil
+}
+
+// queryPackageVersions 查询包的一页版本并标记置顶版本
+func (s *PackageService) queryPackageVersions(ctx context.Context, packageName string, page, pageSize int) (*packageVersionsPage, error) {
 	var pkg models.Package
-	if err := s.db.Where("name = ?", packageName).First(&pkg).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("package not found")
 		}
@@ -329,36 +971,93 @@ func (s *PackageService) GetPackageVersions(ctx context.Context, packageName str
 	}
 
 	var total int64
-	if err := s.db.Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Count(&total).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Count(&total).Error; err != nil {
 		return nil, fmt.Errorf("failed to count versions: %w", err)
 	}
 
-	offset := (page - 1) * pageSize
+	pagination := models.NewPagination(page, pageSize, total)
 	var versions []models.PackageVersion
-	err := s.db.Preload("Uploader").Where("package_id = ?", pkg.ID).
+	err := s.db.WithContext(ctx).Preload("Uploader").Where("package_id = ?", pkg.ID).
 		Order("created_at DESC").
-		Limit(pageSize).Offset(offset).
+		Limit(pagination.PageSize).Offset(pagination.Offset()).
 		Find(&versions).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get versions: %w", err)
 	}
 
-	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	// 标记置顶版本
+	if err := s.markPinned(ctx, versions); err != nil {
+		return nil, err
+	}
 
-	return &models.PackageVersionListResponse{
-		Versions:   versions,
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+	return &packageVersionsPage{
+		pkg: pkg,
+		response: &models.PackageVersionListResponse{
+			Versions:   versions,
+			Pagination: pagination,
+		},
 	}, nil
 }
 
+// GetPackageVersion 获取包的指定版本，viewerID为nil表示匿名，私有包只有有读取权限的调用方可以获取
+func (s *PackageService) GetPackageVersion(ctx context.Context, packageName, version string, viewerID *uint) (*models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackageVersion")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: viewerID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+
+	var pkgVersion models.PackageVersion
+	if err := s.db.WithContext(ctx).Where("package_id = ? AND version = ?", pkg.ID, version).First(&pkgVersion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("version not found")
+		}
+		return nil, fmt.Errorf("failed to find version: %w", err)
+	}
+	pkgVersion.Package = pkg
+	return &pkgVersion, nil
+}
+
+// GetPackagesByName 批量获取包信息（含版本列表），不存在或调用方无权读取的包不返回
+func (s *PackageService) GetPackagesByName(ctx context.Context, names []string, viewerID *uint) ([]*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackagesByName")
+	defer span.Finish()
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var found []*models.Package
+	err := s.db.WithContext(ctx).Preload("Owner").Preload("Versions").Where("name IN ?", names).Find(&found).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get packages: %w", err)
+	}
+
+	packages := make([]*models.Package, 0, len(found))
+	for _, pkg := range found {
+		if authorizePackage(s.db.WithContext(ctx), pkg, PackageCaller{UserID: viewerID}, PackageRightRead) == nil {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, nil
+}
+
 // DeletePackageVersion 删除包版本
 func (s *PackageService) DeletePackageVersion(ctx context.Context, packageName, version string, userID uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.DeletePackageVersion")
+	defer span.Finish()
+
 	// 查找包版本
 	var pkgVersion models.PackageVersion
-	err := s.db.Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("package version not found")
@@ -367,12 +1066,77 @@ func (s *PackageService) DeletePackageVersion(ctx context.Context, packageName,
 	}
 
 	// 检查权限
-	if pkgVersion.Package.OwnerID != userID {
-		return errors.New("permission denied")
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, PackageCaller{UserID: &userID}, PackageRightPublish); err != nil {
+		return err
+	}
+	if pkgVersion.Locked {
+		return ErrVersionLocked
+	}
+
+	release, err := s.deleteLimiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return s.removeVersion(ctx, &pkgVersion, packageName, userID)
+}
+
+// UpdatePackageVersion 修改包版本的描述和更新日志（包所有者和maintainer协作者），携带if_version时使用乐观锁
+func (s *PackageService) UpdatePackageVersion(ctx context.Context, packageName, version string, req *models.UpdatePackageVersionRequest, userID uint) (*models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.UpdatePackageVersion")
+	defer span.Finish()
+
+	var pkgVersion models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package version not found")
+		}
+		return nil, fmt.Errorf("failed to find package version: %w", err)
+	}
+
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, PackageCaller{UserID: &userID}, PackageRightEditMetadata); err != nil {
+		return nil, err
+	}
+	if err := s.checkIfVersion(req.IfVersion); err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Changelog != nil {
+		changelog, err := s.limitChangelog(*req.Changelog)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkChangelog(changelog); err != nil {
+			return nil, err
+		}
+		updates["changelog"] = validation.SanitizeChangelog(changelog)
+	}
+
+	updated, err := lockedUpdate(s.db.WithContext(ctx), &models.PackageVersion{}, pkgVersion.ID, pkgVersion.LockVersion, req.IfVersion, updates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update package version: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Preload("Package").Preload("Uploader").First(&pkgVersion, pkgVersion.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload package version: %w", err)
+	}
+	if !updated {
+		return nil, &StaleUpdateError{Current: &pkgVersion}
 	}
 
+	return &pkgVersion, nil
+}
+
+// removeVersion 删除版本记录、下载记录、置顶记录及存储文件，并发布版本删除事件
+func (s *PackageService) removeVersion(ctx context.Context, pkgVersion *models.PackageVersion, packageName string, userID uint) error {
 	// 开始事务
-	tx := s.db.Begin()
+	tx := s.db.WithContext(ctx).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -385,19 +1149,37 @@ func (s *PackageService) DeletePackageVersion(ctx context.Context, packageName,
 		return fmt.Errorf("failed to delete download records: %w", err)
 	}
 
+	// 删除置顶记录
+	if err := tx.Where("package_version_id = ?", pkgVersion.ID).Delete(&models.PackageVersionPin{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete version pin: %w", err)
+	}
+
 	// 删除版本记录
-	if err := tx.Delete(&pkgVersion).Error; err != nil {
+	if err := tx.Delete(pkgVersion).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete version: %w", err)
 	}
 
+	if err := outbox.Append(tx, events.Event{
+		Type:        events.VersionYanked,
+		PackageID:   pkgVersion.PackageID,
+		PackageName: packageName,
+		Version:     pkgVersion.Version,
+		UserID:      userID,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	s.stats.invalidate()
 
 	// 删除MinIO中的文件
-	if err := s.minioClient.DeletePackage(ctx, packageName, version); err != nil {
+	if err := objectStore(s.minioClient, pkgVersion).DeleteObject(ctx, pkgVersion.MinIOPath); err != nil {
 		// 记录错误但不返回失败
 		fmt.Printf("Warning: failed to delete package file from MinIO: %v\n", err)
 	}
@@ -407,12 +1189,17 @@ func (s *PackageService) DeletePackageVersion(ctx context.Context, packageName,
 
 // SearchPackages 搜索包
 func (s *PackageService) SearchPackages(ctx context.Context, req *models.SearchPackagesRequest) (*models.PackageListResponse, error) {
-	query := s.db.Model(&models.Package{}).Preload("Owner")
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.SearchPackages")
+	defer span.Finish()
+
+	query := s.db.WithContext(ctx).Model(&models.Package{}).Preload("Owner").Preload("Categories")
 
 	// 构建搜索条件
 	if req.Query != "" {
 		searchTerm := "%" + strings.ToLower(req.Query) + "%"
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", searchTerm, searchTerm)
+		// 同时匹配重命名前的旧名称
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR id IN (SELECT package_id FROM package_aliases WHERE LOWER(name) LIKE ?)",
+			searchTerm, searchTerm, searchTerm)
 	}
 
 	if req.Author != "" {
@@ -424,13 +1211,26 @@ func (s *PackageService) SearchPackages(ctx context.Context, req *models.SearchP
 	}
 
 	if req.License != "" {
-		query = query.Where("LOWER(license) = ?", strings.ToLower(req.License))
+		// 许可证已按规范写法存储，过滤条件同样规范化后精确匹配（可使用索引）
+		licenseID := req.License
+		if id, ok := s.licenses.Canonical(req.License); ok {
+			licenseID = id
+		}
+		query = query.Where("license = ?", licenseID)
 	}
 
 	if req.IsPrivate != nil {
 		query = query.Where("is_private = ?", *req.IsPrivate)
 	}
 
+	if req.TrustLevel != "" {
+		query = applyTrustLevelFilter(query, req.TrustLevel)
+	}
+
+	if req.Category != "" {
+		query = query.Where("id IN (SELECT package_categories.package_id FROM package_categories JOIN categories ON categories.id = package_categories.category_id WHERE categories.slug = ?)", req.Category)
+	}
+
 	// 计算总数
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -438,95 +1238,254 @@ func (s *PackageService) SearchPackages(ctx context.Context, req *models.SearchP
 	}
 
 	// 分页查询
-	offset := (req.Page - 1) * req.PageSize
+	pagination := models.NewPagination(req.Page, req.PageSize, total)
 	var packages []models.Package
-	err := query.Order("created_at DESC").
-		Limit(req.PageSize).Offset(offset).
+	err := query.Omit("long_description").Order("created_at DESC").
+		Limit(pagination.PageSize).Offset(pagination.Offset()).
 		Find(&packages).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to search packages: %w", err)
 	}
 
-	totalPages := int((total + int64(req.PageSize) - 1) / int64(req.PageSize))
+	if req.Highlight {
+		highlightPackages(packages, req.Query)
+	}
+
+	return &models.PackageListResponse{
+		Packages:   packages,
+		Pagination: pagination,
+	}, nil
+}
+
+// ListByOwner 获取指定用户发布的包列表
+// owner 可以是用户名或用户ID；访问者为该用户本人时包含私有包，否则只返回公开包
+func (s *PackageService) ListByOwner(ctx context.Context, owner string, viewerID *uint, page, pageSize int) (*models.PackageListResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ListByOwner")
+	defer span.Finish()
+
+	var user models.User
+	err := s.db.WithContext(ctx).Where("username = ?", owner).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 用户名未命中时按用户ID查找
+		if id, convErr := strconv.ParseUint(owner, 10, 32); convErr == nil {
+			err = s.db.WithContext(ctx).First(&user, uint(id)).Error
+		}
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Package{}).Where("owner_id = ?", user.ID)
+
+	// 非本人只能看到公开包
+	if viewerID == nil || *viewerID != user.ID {
+		query = query.Where("is_private = ?", false)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count packages: %w", err)
+	}
+
+	pagination := models.NewPagination(page, pageSize, total)
+	var packages []models.Package
+	err = query.Preload("Owner").Omit("long_description").Order("created_at DESC").
+		Limit(pagination.PageSize).Offset(pagination.Offset()).
+		Find(&packages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
 
 	return &models.PackageListResponse{
 		Packages:   packages,
-		Total:      total,
-		Page:       req.Page,
-		PageSize:   req.PageSize,
-		TotalPages: totalPages,
+		Pagination: pagination,
 	}, nil
 }
 
-// GetPackageStats 获取包统计信息
-func (s *PackageService) GetPackageStats(ctx context.Context) (*models.PackageStatsResponse, error) {
-	stats := &models.PackageStatsResponse{}
+// GetDownloadRecords 获取包的下载记录（仅包所有者，包含IP等访问信息）
+func (s *PackageService) GetDownloadRecords(ctx context.Context, packageName string, userID uint, page, pageSize int) (*models.PackageDownloadListResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetDownloadRecords")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if pkg.OwnerID != userID {
+		return nil, errors.New("permission denied")
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.PackageDownload{}).
+		Where("package_version_id IN (SELECT id FROM package_versions WHERE package_id = ?)", pkg.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count downloads: %w", err)
+	}
+
+	pagination := models.NewPagination(page, pageSize, total)
+	var downloads []models.PackageDownload
+	err := query.Preload("PackageVersion").
+		Order("download_time DESC").
+		Limit(pagination.PageSize).Offset(pagination.Offset()).
+		Find(&downloads).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get downloads: %w", err)
+	}
+
+	return &models.PackageDownloadListResponse{
+		Downloads:  downloads,
+		Pagination: pagination,
+	}, nil
+}
+
+// GetPackageStats 获取包统计信息，rankBy为热门包的排序依据：installs（默认）或downloads
+// 结果按packages.stats_cache_ttl缓存，过期后先返回旧结果并在后台重新计算，computed_at为结果的计算时间
+// 返回的结果由所有调用方共享，不能修改
+func (s *PackageService) GetPackageStats(ctx context.Context, rankBy string) (*models.PackageStatsResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackageStats")
+	defer span.Finish()
+
+	return s.stats.get(ctx, rankBy, func(ctx context.Context) (*models.PackageStatsResponse, error) {
+		return s.computePackageStats(ctx, rankBy)
+	})
+}
+
+// computePackageStats 执行聚合查询计算包统计
+func (s *PackageService) computePackageStats(ctx context.Context, rankBy string) (*models.PackageStatsResponse, error) {
+	stats := &models.PackageStatsResponse{ComputedAt: time.Now().UTC()}
 
 	// 总包数
-	if err := s.db.Model(&models.Package{}).Count(&stats.TotalPackages).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&models.Package{}).Count(&stats.TotalPackages).Error; err != nil {
 		return nil, fmt.Errorf("failed to count packages: %w", err)
 	}
 
 	// 总版本数
-	if err := s.db.Model(&models.PackageVersion{}).Count(&stats.TotalVersions).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Count(&stats.TotalVersions).Error; err != nil {
 		return nil, fmt.Errorf("failed to count versions: %w", err)
 	}
 
 	// 总下载数
-	if err := s.db.Model(&models.PackageVersion{}).Select("SUM(download_count)").Scan(&stats.TotalDownloads).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Select("COALESCE(SUM(download_count), 0)").Scan(&stats.TotalDownloads).Error; err != nil {
 		return nil, fmt.Errorf("failed to count downloads: %w", err)
 	}
 
+	// 总安装数
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Select("COALESCE(SUM(install_count), 0)").Scan(&stats.TotalInstalls).Error; err != nil {
+		return nil, fmt.Errorf("failed to count installs: %w", err)
+	}
+
 	// 最近30天下载数
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
-	if err := s.db.Model(&models.PackageDownload{}).Where("download_time >= ?", thirtyDaysAgo).Count(&stats.RecentDownloads).Error; err != nil {
+	thirtyDaysAgo := time.Now().UTC().AddDate(0, 0, -30)
+	if err := s.db.WithContext(ctx).Model(&models.PackageDownload{}).Where("download_time >= ? AND flagged = ?", thirtyDaysAgo, false).Count(&stats.RecentDownloads).Error; err != nil {
 		return nil, fmt.Errorf("failed to count recent downloads: %w", err)
 	}
 
-	// 热门包（按下载量排序）
-	err := s.db.Preload("Owner").
-		Joins("JOIN (SELECT package_id, SUM(download_count) as total_downloads FROM package_versions GROUP BY package_id ORDER BY total_downloads DESC LIMIT 10) pv ON packages.id = pv.package_id").
-		Order("pv.total_downloads DESC").
+	// 热门包（默认按安装数排序，原始下载数包含重试和探测请求）
+	rankColumn := "install_count"
+	stats.RankedBy = models.RankByInstalls
+	if rankBy == models.RankByDownloads {
+		rankColumn = "download_count"
+		stats.RankedBy = models.RankByDownloads
+	}
+	err := s.db.WithContext(ctx).Preload("Owner").
+		Joins(fmt.Sprintf("JOIN (SELECT package_id, SUM(%s) as total FROM package_versions GROUP BY package_id ORDER BY total DESC LIMIT 10) pv ON packages.id = pv.package_id", rankColumn)).
+		Where("packages.is_archived = ?", false).
+		Order("pv.total DESC").
 		Find(&stats.PopularPackages).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get popular packages: %w", err)
 	}
 
 	// 最新包
-	if err := s.db.Preload("Owner").Order("created_at DESC").Limit(10).Find(&stats.RecentPackages).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Owner").Order("created_at DESC").Limit(10).Find(&stats.RecentPackages).Error; err != nil {
 		return nil, fmt.Errorf("failed to get recent packages: %w", err)
 	}
 
 	// 最新版本
-	if err := s.db.Preload("Package").Preload("Uploader").Order("created_at DESC").Limit(10).Find(&stats.RecentVersions).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Package").Preload("Uploader").Order("created_at DESC").Limit(10).Find(&stats.RecentVersions).Error; err != nil {
 		return nil, fmt.Errorf("failed to get recent versions: %w", err)
 	}
 
 	return stats, nil
 }
 
-// GetDownloadURL 获取下载URL
-func (s *PackageService) GetDownloadURL(ctx context.Context, packageName, version string, userID *uint) (string, error) {
+// GetDownloadURL 获取下载链接、浏览器保存时使用的文件名（{name}-{version}.pkg）和链接的过期时间，有效期见DownloadURLTTL
+// 每次调用都签发新的链接，客户端可在过期前重新获取
+// presign模式返回MinIO预签名URL，通过response-content-disposition参数指定文件名；
+// app-signed模式返回 /download/:token 链接，未配置public_base_url时为相对路径
+// countryCode为客户端所在国家，presign模式下用于选择同地域的下载镜像，为空时由主节点签发
+func (s *PackageService) GetDownloadURL(ctx context.Context, packageName, version string, userID *uint, countryCode string) (downloadURL, filename string, expiresAt time.Time, err error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetDownloadURL")
+	defer span.Finish()
+
 	// 查找包版本
 	var pkgVersion models.PackageVersion
-	err := s.db.Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	err = s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("package version not found")
+			return "", "", time.Time{}, errors.New("package version not found")
 		}
-		return "", fmt.Errorf("failed to find package version: %w", err)
+		return "", "", time.Time{}, fmt.Errorf("failed to find package version: %w", err)
 	}
 
 	// 检查私有包权限
-	if pkgVersion.Package.IsPrivate && (userID == nil || pkgVersion.Package.OwnerID != *userID) {
-		return "", errors.New("access denied to private package")
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, PackageCaller{UserID: userID}, PackageRightRead); err != nil {
+		return "", "", time.Time{}, err
+	}
+	if err := checkScanStatus(&pkgVersion); err != nil {
+		return "", "", time.Time{}, err
 	}
 
-	// 生成下载URL（1小时有效期）
-	url, err := s.minioClient.GetDownloadURL(ctx, packageName, version, time.Hour)
+	if err := checkBandwidthLimit(ctx, s.db, &pkgVersion.Package); err != nil {
+		return "", "", time.Time{}, err
+	}
+	if err := s.checkColdDownload(ctx, &pkgVersion, userID); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	filename = minio.DownloadFilename(pkgVersion.Package.Name, pkgVersion.Version)
+	now := time.Now().UTC()
+	expiresAt = time.Unix(now.Add(s.downloadSigner.ttl).Unix(), 0).UTC()
+	// 压缩存储的对象直接从存储下载得到的是gzip内容，只能经由服务端解压转发
+	if s.downloadURLMode == DownloadURLModeAppSigned || pkgVersion.CompressedStored {
+		token, err := s.downloadSigner.sign(pkgVersion.Package.Name, pkgVersion.Version, userID, now)
+		if err != nil {
+			return "", "", time.Time{}, fmt.Errorf("failed to sign download token: %w", err)
+		}
+		return s.publicBaseURL + "/download/" + token, filename, expiresAt, nil
+	}
+
+	urlOpts := &minio.DownloadURLOptions{
+		Filename:    filename,
+		ContentType: "application/octet-stream",
+	}
+	// 下载镜像只同步主bucket，冷存储中的版本始终由主节点签发
+	if pkgVersion.InColdStorage() {
+		downloadURL, err = s.minioClient.Cold().GetObjectURL(ctx, pkgVersion.MinIOPath, s.downloadSigner.ttl, urlOpts)
+	} else {
+		downloadURL, err = s.minioClient.GetMirroredObjectURL(ctx, countryCode, pkgVersion.MinIOPath, s.downloadSigner.ttl, urlOpts)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to generate download URL: %w", err)
+		return "", "", time.Time{}, fmt.Errorf("failed to generate download URL: %w", err)
 	}
 
-	return url, nil
+	return downloadURL, filename, expiresAt, nil
+}
+
+// DownloadURLTTL 下载链接有效期
+func (s *PackageService) DownloadURLTTL() time.Duration {
+	return s.downloadSigner.ttl
+}
+
+// VerifyDownloadToken 校验app-signed下载令牌，返回令牌中的包名、版本和签发用户
+func (s *PackageService) VerifyDownloadToken(token string) (*DownloadToken, error) {
+	return s.downloadSigner.verify(token, time.Now().UTC())
 }
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// 包权限
+const (
+	PackageRightRead                = "read"                 // 查看包及其私有内容（文档、图标、版本文件）
+	PackageRightPublish             = "publish"              // 发布、删除、清理和弃用版本
+	PackageRightEditMetadata        = "edit_metadata"        // 修改包和版本的元数据、重命名、文档和图标
+	PackageRightDelete              = "delete"               // 删除包
+	PackageRightManageCollaborators = "manage_collaborators" // 添加、修改和移除协作者
+	PackageRightAdminOverride       = "admin_override"       // 管理员对他人包的管理操作（修正对象键、重建数据、锁定和重新扫描版本等）
+)
+
+// packageRights 权限查询接口返回的全部权限
+var packageRights = []string{
+	PackageRightRead,
+	PackageRightPublish,
+	PackageRightEditMetadata,
+	PackageRightDelete,
+	PackageRightManageCollaborators,
+	PackageRightAdminOverride,
+}
+
+// 权限的授予或拒绝原因
+const (
+	AccessReasonPublic     = "public"      // 公开包，任何人可读
+	AccessReasonOwner      = "owner"       // 包所有者
+	AccessReasonMaintainer = "maintainer"  // 协作者角色为maintainer
+	AccessReasonReader     = "reader"      // 协作者角色为reader
+	AccessReasonAdmin      = "admin"       // 角色策略允许package.moderate
+	AccessReasonArchived   = "archived"    // 包已归档，不能修改
+	AccessReasonTokenScope = "token_scope" // token限定了包范围且不包含该包
+)
+
+// PackageCaller 对包执行操作的调用方
+type PackageCaller struct {
+	UserID        *uint // 未登录时为nil
+	OutOfScope    bool  // token限定了包范围且不包含该包，认证中间件会拒绝写操作
+	AdminOverride bool  // 角色策略允许package.moderate，可以对他人的包行使全部权限
+}
+
+// evaluatePackageRight 计算调用方对包的一项权限，collaboratorRole为调用方在该包的协作者角色（不是协作者时为空）
+// 服务层的所有权检查（authorizePackage）和权限查询接口都使用这里的规则，两者不会不一致
+// 授予顺序：公开、所有者、协作者角色、管理员；已归档的包拒绝发布和修改元数据，token范围外的包只保留read和admin_override
+func evaluatePackageRight(pkg *models.Package, caller PackageCaller, collaboratorRole, right string) models.PackagePermission {
+	owner := caller.UserID != nil && pkg.OwnerID == *caller.UserID
+	var permission models.PackagePermission
+	switch right {
+	case PackageRightRead:
+		switch {
+		case !pkg.IsPrivate:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonPublic}
+		case owner:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonOwner}
+		case collaboratorRole == models.CollaboratorRoleMaintainer:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonMaintainer}
+		case collaboratorRole == models.CollaboratorRoleReader:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonReader}
+		case caller.AdminOverride:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonAdmin}
+		}
+	case PackageRightPublish, PackageRightEditMetadata:
+		switch {
+		case owner:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonOwner}
+		case collaboratorRole == models.CollaboratorRoleMaintainer:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonMaintainer}
+		case caller.AdminOverride:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonAdmin}
+		}
+		if permission.Granted && pkg.IsArchived {
+			permission = models.PackagePermission{Reason: AccessReasonArchived}
+		}
+	case PackageRightDelete, PackageRightManageCollaborators:
+		switch {
+		case owner:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonOwner}
+		case caller.AdminOverride:
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonAdmin}
+		}
+		// 归档的包仍可删除，但不能修改协作者
+		if right == PackageRightManageCollaborators && permission.Granted && pkg.IsArchived {
+			permission = models.PackagePermission{Reason: AccessReasonArchived}
+		}
+	case PackageRightAdminOverride:
+		if caller.AdminOverride {
+			permission = models.PackagePermission{Granted: true, Reason: AccessReasonAdmin}
+		}
+	}
+	// 写操作的路由要求token包含该包，读取和管理员操作不受限制
+	if caller.OutOfScope && right != PackageRightRead && right != PackageRightAdminOverride {
+		permission = models.PackagePermission{Reason: AccessReasonTokenScope}
+	}
+	return permission
+}
+
+// packageCollaboratorRole 查询调用方在包上的协作者角色，匿名用户和所有者返回空
+func packageCollaboratorRole(db *gorm.DB, pkg *models.Package, userID *uint) (string, error) {
+	if userID == nil || pkg.OwnerID == *userID {
+		return "", nil
+	}
+	var roles []string
+	err := db.Model(&models.PackageCollaborator{}).
+		Where("package_id = ? AND user_id = ?", pkg.ID, *userID).
+		Limit(1).
+		Pluck("role", &roles).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to load package collaborator: %w", err)
+	}
+	if len(roles) == 0 {
+		return "", nil
+	}
+	return roles[0], nil
+}
+
+// authorizePackage 检查调用方能否对包行使某项权限，db用于查询调用方的协作者角色
+// 不能读取私有包时返回access denied，包已归档时返回ErrPackageArchived，其他情况返回permission denied
+func authorizePackage(db *gorm.DB, pkg *models.Package, caller PackageCaller, right string) error {
+	role := ""
+	// 公开包的读取不依赖协作者角色，省去一次查询
+	if right != PackageRightRead || pkg.IsPrivate {
+		var err error
+		if role, err = packageCollaboratorRole(db, pkg, caller.UserID); err != nil {
+			return err
+		}
+	}
+	permission := evaluatePackageRight(pkg, caller, role, right)
+	switch {
+	case permission.Granted:
+		return nil
+	case permission.Reason == AccessReasonArchived:
+		return ErrPackageArchived
+	case right == PackageRightRead:
+		return errors.New("access denied to private package")
+	default:
+		return errors.New("permission denied")
+	}
+}
+
+// GetPackagePermissions 返回调用方对包的有效权限及原因，供界面决定显示哪些操作
+func (s *PackageService) GetPackagePermissions(ctx context.Context, packageName string, caller PackageCaller) (*models.PackagePermissionsResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackagePermissions")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	resp := &models.PackagePermissionsResponse{
+		Package:       pkg.Name,
+		Authenticated: caller.UserID != nil,
+		Permissions:   make(map[string]models.PackagePermission, len(packageRights)),
+	}
+	role, err := packageCollaboratorRole(s.db.WithContext(ctx), &pkg, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, right := range packageRights {
+		resp.Permissions[right] = evaluatePackageRight(&pkg, caller, role, right)
+	}
+	return resp, nil
+}
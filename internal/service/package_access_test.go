@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+// accessFixture 权限测试的用户和包：owner拥有两个包，maintainer和reader是两个包的协作者
+type accessFixture struct {
+	s                                   *PackageService
+	owner, maintainer, reader, stranger *models.User
+	admin                               *models.User
+	publicPkg, privatePkg, archivedPkg  *models.Package
+}
+
+func newAccessFixture(t *testing.T) *accessFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := &accessFixture{s: newTestPackageService(t, db)}
+	f.owner = createTestUser(t, db, "owner", models.RoleUser)
+	f.maintainer = createTestUser(t, db, "maint", models.RoleUser)
+	f.reader = createTestUser(t, db, "reader", models.RoleUser)
+	f.stranger = createTestUser(t, db, "stranger", models.RoleUser)
+	f.admin = createTestUser(t, db, "admin", models.RoleAdmin)
+	f.publicPkg = createTestPackage(t, db, "public-pkg", f.owner, false)
+	f.privatePkg = createTestPackage(t, db, "private-pkg", f.owner, true)
+	f.archivedPkg = createTestPackage(t, db, "archived-pkg", f.owner, false)
+
+	ownerCaller := PackageCaller{UserID: &f.owner.ID}
+	for _, name := range []string{"public-pkg", "private-pkg", "archived-pkg"} {
+		if _, err := f.s.SetCollaborator(context.Background(), name, "maint", models.CollaboratorRoleMaintainer, ownerCaller); err != nil {
+			t.Fatalf("SetCollaborator maintainer: %v", err)
+		}
+		if _, err := f.s.SetCollaborator(context.Background(), name, "reader", models.CollaboratorRoleReader, ownerCaller); err != nil {
+			t.Fatalf("SetCollaborator reader: %v", err)
+		}
+	}
+	// 归档后不能再修改协作者，先添加协作者再归档
+	if err := db.Model(f.archivedPkg).Update("is_archived", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// caller 返回persona对应的调用方，admin由角色策略授予admin_override
+func (f *accessFixture) caller(persona string) PackageCaller {
+	switch persona {
+	case "owner":
+		return PackageCaller{UserID: &f.owner.ID}
+	case "maintainer":
+		return PackageCaller{UserID: &f.maintainer.ID}
+	case "reader":
+		return PackageCaller{UserID: &f.reader.ID}
+	case "stranger":
+		return PackageCaller{UserID: &f.stranger.ID}
+	case "admin":
+		return PackageCaller{UserID: &f.admin.ID, AdminOverride: true}
+	default:
+		return PackageCaller{}
+	}
+}
+
+// grant 和 deny 构造期望的权限
+func grant(reason string) models.PackagePermission {
+	return models.PackagePermission{Granted: true, Reason: reason}
+}
+func deny(reason string) models.PackagePermission { return models.PackagePermission{Reason: reason} }
+
+func TestPackagePermissionsByRoleAndRight(t *testing.T) {
+	f := newAccessFixture(t)
+	none := deny("")
+	tests := []struct {
+		persona string
+		pkg     string
+		want    map[string]models.PackagePermission
+	}{
+		{"owner", "public-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: grant(AccessReasonOwner), PackageRightEditMetadata: grant(AccessReasonOwner),
+			PackageRightDelete: grant(AccessReasonOwner), PackageRightManageCollaborators: grant(AccessReasonOwner), PackageRightAdminOverride: none}},
+		{"owner", "private-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonOwner), PackageRightPublish: grant(AccessReasonOwner), PackageRightEditMetadata: grant(AccessReasonOwner),
+			PackageRightDelete: grant(AccessReasonOwner), PackageRightManageCollaborators: grant(AccessReasonOwner), PackageRightAdminOverride: none}},
+		{"owner", "archived-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: deny(AccessReasonArchived), PackageRightEditMetadata: deny(AccessReasonArchived),
+			PackageRightDelete: grant(AccessReasonOwner), PackageRightManageCollaborators: deny(AccessReasonArchived), PackageRightAdminOverride: none}},
+		{"maintainer", "public-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: grant(AccessReasonMaintainer), PackageRightEditMetadata: grant(AccessReasonMaintainer),
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"maintainer", "private-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonMaintainer), PackageRightPublish: grant(AccessReasonMaintainer), PackageRightEditMetadata: grant(AccessReasonMaintainer),
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"maintainer", "archived-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: deny(AccessReasonArchived), PackageRightEditMetadata: deny(AccessReasonArchived),
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"reader", "public-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: none, PackageRightEditMetadata: none,
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"reader", "private-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonReader), PackageRightPublish: none, PackageRightEditMetadata: none,
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"admin", "public-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: grant(AccessReasonAdmin), PackageRightEditMetadata: grant(AccessReasonAdmin),
+			PackageRightDelete: grant(AccessReasonAdmin), PackageRightManageCollaborators: grant(AccessReasonAdmin), PackageRightAdminOverride: grant(AccessReasonAdmin)}},
+		{"admin", "private-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonAdmin), PackageRightPublish: grant(AccessReasonAdmin), PackageRightEditMetadata: grant(AccessReasonAdmin),
+			PackageRightDelete: grant(AccessReasonAdmin), PackageRightManageCollaborators: grant(AccessReasonAdmin), PackageRightAdminOverride: grant(AccessReasonAdmin)}},
+		{"admin", "archived-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: deny(AccessReasonArchived), PackageRightEditMetadata: deny(AccessReasonArchived),
+			PackageRightDelete: grant(AccessReasonAdmin), PackageRightManageCollaborators: deny(AccessReasonArchived), PackageRightAdminOverride: grant(AccessReasonAdmin)}},
+		{"stranger", "public-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: none, PackageRightEditMetadata: none,
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"stranger", "private-pkg", map[string]models.PackagePermission{
+			PackageRightRead: none, PackageRightPublish: none, PackageRightEditMetadata: none,
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"anonymous", "public-pkg", map[string]models.PackagePermission{
+			PackageRightRead: grant(AccessReasonPublic), PackageRightPublish: none, PackageRightEditMetadata: none,
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+		{"anonymous", "private-pkg", map[string]models.PackagePermission{
+			PackageRightRead: none, PackageRightPublish: none, PackageRightEditMetadata: none,
+			PackageRightDelete: none, PackageRightManageCollaborators: none, PackageRightAdminOverride: none}},
+	}
+
+	for _, tt := range tests {
+		caller := f.caller(tt.persona)
+		resp, err := f.s.GetPackagePermissions(context.Background(), tt.pkg, caller)
+		if err != nil {
+			t.Fatalf("%s on %s: GetPackagePermissions: %v", tt.persona, tt.pkg, err)
+		}
+		var pkg models.Package
+		if err := f.s.db.Where("name = ?", tt.pkg).First(&pkg).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, right := range packageRights {
+			t.Run(fmt.Sprintf("%s/%s/%s", tt.persona, tt.pkg, right), func(t *testing.T) {
+				want := tt.want[right]
+				if got := resp.Permissions[right]; got != want {
+					t.Errorf("reported %+v, want %+v", got, want)
+				}
+				// 执行时的检查必须与权限查询接口的结果一致
+				err := authorizePackage(f.s.db, &pkg, caller, right)
+				if want.Granted != (err == nil) {
+					t.Errorf("authorizePackage returned %v, reported granted=%v", err, want.Granted)
+				}
+				if want.Reason == AccessReasonArchived && !errors.Is(err, ErrPackageArchived) {
+					t.Errorf("authorizePackage returned %v, want ErrPackageArchived", err)
+				}
+			})
+		}
+	}
+}
+
+func TestPackagePermissionsOutsideTokenScope(t *testing.T) {
+	f := newAccessFixture(t)
+	caller := f.caller("owner")
+	caller.OutOfScope = true
+
+	resp, err := f.s.GetPackagePermissions(context.Background(), "private-pkg", caller)
+	if err != nil {
+		t.Fatalf("GetPackagePermissions: %v", err)
+	}
+	if got := resp.Permissions[PackageRightRead]; got != grant(AccessReasonOwner) {
+		t.Errorf("read = %+v, want granted to the owner", got)
+	}
+	for _, right := range []string{PackageRightPublish, PackageRightEditMetadata, PackageRightDelete, PackageRightManageCollaborators} {
+		if got := resp.Permissions[right]; got != deny(AccessReasonTokenScope) {
+			t.Errorf("%s = %+v, want denied by token scope", right, got)
+		}
+	}
+}
+
+func TestCollaboratorRoleGatesServiceOperations(t *testing.T) {
+	f := newAccessFixture(t)
+	ctx := context.Background()
+
+	// reader可以读取私有包的版本列表，但不能修改
+	if _, err := f.s.GetPackageVersions(ctx, "private-pkg", 1, 10, &f.reader.ID); err != nil {
+		t.Fatalf("reader GetPackageVersions: %v", err)
+	}
+	if _, err := f.s.UpdatePackage(ctx, "private-pkg", &models.UpdatePackageRequest{Description: "changed"}, f.reader.ID); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("reader UpdatePackage err = %v, want permission denied", err)
+	}
+	if _, err := f.s.UpdatePackage(ctx, "private-pkg", &models.UpdatePackageRequest{Description: "changed"}, f.maintainer.ID); err != nil {
+		t.Fatalf("maintainer UpdatePackage: %v", err)
+	}
+	if err := f.s.DeletePackage(ctx, "private-pkg", f.maintainer.ID); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("maintainer DeletePackage err = %v, want permission denied", err)
+	}
+
+	// maintainer不能管理协作者；移除后失去访问权限
+	if _, err := f.s.SetCollaborator(ctx, "private-pkg", "stranger", models.CollaboratorRoleReader, f.caller("maintainer")); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("maintainer SetCollaborator err = %v, want permission denied", err)
+	}
+	if err := f.s.RemoveCollaborator(ctx, "private-pkg", "reader", f.caller("owner")); err != nil {
+		t.Fatalf("RemoveCollaborator: %v", err)
+	}
+	if _, err := f.s.GetPackageVersions(ctx, "private-pkg", 1, 10, &f.reader.ID); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Fatalf("removed reader GetPackageVersions err = %v, want access denied", err)
+	}
+}
+
+func TestSetCollaboratorUpdatesRoleAndRejectsOwner(t *testing.T) {
+	f := newAccessFixture(t)
+	ctx := context.Background()
+
+	info, err := f.s.SetCollaborator(ctx, "public-pkg", "reader", models.CollaboratorRoleMaintainer, f.caller("admin"))
+	if err != nil {
+		t.Fatalf("SetCollaborator: %v", err)
+	}
+	if info.Role != models.CollaboratorRoleMaintainer {
+		t.Errorf("role = %q, want maintainer", info.Role)
+	}
+	list, err := f.s.ListCollaborators(ctx, "public-pkg", f.caller("anonymous"))
+	if err != nil {
+		t.Fatalf("ListCollaborators: %v", err)
+	}
+	if len(list) != 2 || list[1].Username != "reader" || list[1].Role != models.CollaboratorRoleMaintainer {
+		t.Errorf("collaborators = %+v, want maint and reader, both maintainers", list)
+	}
+
+	if _, err := f.s.SetCollaborator(ctx, "public-pkg", "owner", models.CollaboratorRoleReader, f.caller("owner")); !errors.Is(err, ErrCollaboratorIsOwner) {
+		t.Errorf("adding the owner err = %v, want ErrCollaboratorIsOwner", err)
+	}
+	if _, err := f.s.SetCollaborator(ctx, "public-pkg", "nobody", models.CollaboratorRoleReader, f.caller("owner")); err == nil || !strings.Contains(err.Error(), "user not found") {
+		t.Errorf("adding a missing user err = %v, want user not found", err)
+	}
+}
+
+func TestAdminPackageOperationsRequireAdminOverride(t *testing.T) {
+	f := newAccessFixture(t)
+	ctx := context.Background()
+	createTestVersion(t, f.s.db, f.privatePkg, "1.0.0", nil)
+
+	// 路由上的RequirePermission之外，服务层也按admin_override检查，所有者和协作者不能使用管理操作
+	for _, persona := range []string{"owner", "maintainer", "stranger"} {
+		if _, err := f.s.RebuildPackage(ctx, "private-pkg", f.caller(persona)); err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Errorf("%s RebuildPackage err = %v, want permission denied", persona, err)
+		}
+		if _, _, err := f.s.SetVersionLocked(ctx, "private-pkg", "1.0.0", true, f.caller(persona)); err == nil || !strings.Contains(err.Error(), "permission denied") {
+			t.Errorf("%s SetVersionLocked err = %v, want permission denied", persona, err)
+		}
+	}
+
+	if _, err := f.s.RebuildPackage(ctx, "private-pkg", f.caller("admin")); err != nil {
+		t.Fatalf("admin RebuildPackage: %v", err)
+	}
+	version, changed, err := f.s.SetVersionLocked(ctx, "private-pkg", "1.0.0", true, f.caller("admin"))
+	if err != nil || !changed || !version.Locked {
+		t.Fatalf("admin SetVersionLocked = %+v, %v, %v; want locked", version, changed, err)
+	}
+}
+
+func TestVersionScanReportVisibility(t *testing.T) {
+	f := newAccessFixture(t)
+	createTestVersion(t, f.s.db, f.privatePkg, "1.0.0", nil)
+
+	for persona, allowed := range map[string]bool{"owner": true, "maintainer": true, "admin": true, "reader": false, "stranger": false} {
+		_, err := f.s.GetVersionScanReport(context.Background(), "private-pkg", "1.0.0", f.caller(persona))
+		if allowed != (err == nil) {
+			t.Errorf("%s GetVersionScanReport err = %v, want allowed=%v", persona, err, allowed)
+		}
+	}
+}
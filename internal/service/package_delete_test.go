@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/migration"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// packageRefColumns 引用包或版本的列（字段名 -> 引用的是否为版本）
+var packageRefColumns = map[string]bool{
+	"PackageID":            false,
+	"RecommendedPackageID": false,
+	"PackageVersionID":     true,
+}
+
+// packageRowExemptions 删除包后有意保留的记录：包和版本是软删除（名称保留期和历史），发件箱事件和审计日志是事件记录
+var packageRowExemptions = map[string]bool{
+	"packages":         true,
+	"package_versions": true,
+	"outbox_events":    true,
+	"audit_logs":       true,
+}
+
+// seedPackageRows 为包及其版本在每个关联表中写入一条记录，推荐记录指向other
+func seedPackageRows(t *testing.T, db *gorm.DB, pkg *models.Package, version *models.PackageVersion, user *models.User, other *models.Package) {
+	t.Helper()
+	category := &models.Category{Slug: "cat-" + pkg.Name, Name: "Category " + pkg.Name}
+	page := &models.WikiPage{PackageID: pkg.ID, Slug: "intro", Title: "Intro", AuthorUserID: user.ID}
+	rows := []interface{}{
+		&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1"},
+		&models.PackageVersionPin{PackageVersionID: version.ID, PinnedBy: user.ID},
+		&models.StorageTierChange{PackageVersionID: version.ID, FromTier: "hot", ToTier: "cold", Direction: "demote"},
+		&models.VersionScanResult{PackageVersionID: version.ID, Engine: "fake", Verdict: models.ScanVerdictClean, ScannedAt: time.Now()},
+		&models.PackageAlias{Name: pkg.Name + "-old", PackageID: pkg.ID, CreatedBy: user.ID},
+		category,
+		page,
+		&models.PackageWatcher{PackageID: pkg.ID, UserID: user.ID},
+		&models.PackageCollaborator{PackageID: pkg.ID, UserID: user.ID, Role: models.CollaboratorRoleReader},
+		&models.PackageRecommendation{PackageID: pkg.ID, RecommendedPackageID: other.ID, Score: 0.5},
+		&models.PackageBandwidthUsage{PackageID: pkg.ID, Month: "2026-10", Downloads: 1},
+		&models.LeaderboardEntry{PeriodType: models.LeaderboardWeekly, PeriodStart: time.Now(), Rank: 1, PackageID: pkg.ID},
+		&models.UploadSession{ID: "up-" + pkg.Name, PackageID: pkg.ID, PackageName: pkg.Name, Version: "2.0.0", UploaderID: user.ID,
+			ObjectKey: "staging/up-" + pkg.Name, Status: models.UploadSessionCompleted, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", row, err)
+		}
+	}
+	extra := []interface{}{
+		&models.PackageCategory{PackageID: pkg.ID, CategoryID: category.ID},
+		&models.WikiPageRevision{PageID: page.ID, Revision: 1, Title: "Intro", AuthorUserID: user.ID},
+	}
+	for _, row := range extra {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", row, err)
+		}
+	}
+}
+
+// countPackageRows 按表统计引用包或其版本的记录数，不含有意保留的表；软删除的记录不计入
+func countPackageRows(t *testing.T, db *gorm.DB, pkg *models.Package) map[string]int64 {
+	t.Helper()
+	var versionIDs []uint
+	if err := db.Unscoped().Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Pluck("id", &versionIDs).Error; err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int64)
+	for _, model := range migration.Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("failed to parse %T: %v", model, err)
+		}
+		if packageRowExemptions[stmt.Schema.Table] {
+			continue
+		}
+		for name, isVersion := range packageRefColumns {
+			field := stmt.Schema.LookUpField(name)
+			if field == nil {
+				continue
+			}
+			ids := []uint{pkg.ID}
+			if isVersion {
+				ids = versionIDs
+			}
+			var n int64
+			if err := db.Model(model).Where(field.DBName+" IN ?", ids).Count(&n).Error; err != nil {
+				t.Fatalf("failed to count %s: %v", stmt.Schema.Table, err)
+			}
+			counts[stmt.Schema.Table] += n
+		}
+	}
+	var revisions int64
+	if err := db.Model(&models.WikiPageRevision{}).Where("page_id IN (SELECT id FROM package_wiki_pages WHERE package_id = ?)", pkg.ID).Count(&revisions).Error; err != nil {
+		t.Fatal(err)
+	}
+	counts["package_wiki_page_revisions"] = revisions
+	return counts
+}
+
+func TestDeletePackageRemovesEveryRelatedRow(t *testing.T) {
+	db := newTestDB(t)
+	s := NewPackageService(db, testutil.NewStorage(t, nil), nil, config.PackagesConfig{})
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	fan := createTestUser(t, db, "bob", models.RoleUser)
+	doomed := createTestPackage(t, db, "doomed", owner, false)
+	kept := createTestPackage(t, db, "kept", owner, false)
+	doomedVersion := createTestVersion(t, db, doomed, "1.0.0", nil)
+	keptVersion := createTestVersion(t, db, kept, "1.0.0", nil)
+	seedPackageRows(t, db, doomed, doomedVersion, fan, kept)
+	seedPackageRows(t, db, kept, keptVersion, fan, doomed)
+
+	// 每个引用包或版本的表都要有记录，新增的表没有加入seedPackageRows时在这里失败
+	before := countPackageRows(t, db, doomed)
+	for table, n := range before {
+		if n == 0 {
+			t.Errorf("table %s has no rows for the package; seed it so the delete test covers it", table)
+		}
+	}
+	keptBefore := countPackageRows(t, db, kept)
+
+	if err := s.DeletePackage(context.Background(), "doomed", owner.ID); err != nil {
+		t.Fatalf("DeletePackage: %v", err)
+	}
+
+	for table, n := range countPackageRows(t, db, doomed) {
+		if n != 0 {
+			t.Errorf("%d rows in %s still reference the deleted package", n, table)
+		}
+	}
+	// 另一个包只失去指向被删除包的推荐
+	for table, n := range countPackageRows(t, db, kept) {
+		want := keptBefore[table]
+		if table == "package_recommendations" {
+			want -= 2
+		}
+		if n != want {
+			t.Errorf("%s has %d rows for the other package, want %d", table, n, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"webservice/internal/minio"
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// packageExportBatchSize 迁移导出每批读取的包数
+const packageExportBatchSize = 200
+
+// ExportFilter 包迁移导出的筛选条件，nil表示导出全部包
+type ExportFilter struct {
+	Owner               string     // 所有者用户名，为空时不限
+	UpdatedSince        *time.Time // 只导出此后更新过的包
+	IncludeDownloadURLs bool       // 为每个版本生成预签名下载URL，有效期与下载链接相同
+}
+
+// PackageExportRecord 迁移导出的一行：完整的包信息和全部版本（不含文件内容，包含对象键minio_path和file_hash）
+type PackageExportRecord struct {
+	models.Package
+	DownloadURLs map[string]string `json:"download_urls,omitempty"` // 版本号 -> 预签名下载URL
+}
+
+// exportFlusher 支持刷新的写入器（如HTTP响应），每批写完后刷新
+type exportFlusher interface {
+	Flush()
+}
+
+// exportPackagesQuery 按筛选条件查询包，包括私有包和已归档的包
+func (s *PackageService) exportPackagesQuery(ctx context.Context, filter *ExportFilter) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&models.Package{})
+	if filter == nil {
+		return query
+	}
+	if filter.Owner != "" {
+		query = query.Where("packages.owner_id = (SELECT id FROM users WHERE username = ?)", filter.Owner)
+	}
+	if filter.UpdatedSince != nil {
+		query = query.Where("packages.updated_at >= ?", *filter.UpdatedSince)
+	}
+	return query
+}
+
+// CountExportPackages 统计符合迁移导出筛选条件的包数，导出期间新建的包也可能被导出，因此只是估计值
+func (s *PackageService) CountExportPackages(ctx context.Context, filter *ExportFilter) (int64, error) {
+	var count int64
+	if err := s.exportPackagesQuery(ctx, filter).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count packages: %w", err)
+	}
+	return count, nil
+}
+
+// BulkExportPackages 以NDJSON格式把包元数据写入writer，每行一个PackageExportRecord，用于迁移到其他仓库
+// 按包ID顺序每批读取200个包及其版本，不会一次加载全部数据；writer支持Flush时每批写完后刷新
+func (s *PackageService) BulkExportPackages(ctx context.Context, writer io.Writer, filter *ExportFilter) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.BulkExportPackages")
+	defer span.Finish()
+
+	includeURLs := filter != nil && filter.IncludeDownloadURLs
+	if includeURLs && s.minioClient == nil {
+		return errors.New("storage is not available, cannot generate download URLs")
+	}
+
+	encoder := json.NewEncoder(writer)
+	var lastID uint
+	for {
+		var batch []models.Package
+		err := s.exportPackagesQuery(ctx, filter).
+			Preload("Owner").
+			Preload("Categories").
+			Preload("Versions", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Where("packages.id > ?", lastID).
+			Order("packages.id ASC").
+			Limit(packageExportBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return fmt.Errorf("failed to load packages: %w", err)
+		}
+
+		for i := range batch {
+			record := PackageExportRecord{Package: batch[i]}
+			if includeURLs {
+				if record.DownloadURLs, err = s.exportDownloadURLs(ctx, record.Name, record.Versions); err != nil {
+					return err
+				}
+			}
+			if err := encoder.Encode(record); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+		if f, ok := writer.(exportFlusher); ok {
+			f.Flush()
+		}
+
+		if len(batch) < packageExportBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// exportDownloadURLs 为包的每个版本生成预签名下载URL，冷存储中的版本由冷存储bucket签发
+func (s *PackageService) exportDownloadURLs(ctx context.Context, packageName string, versions []models.PackageVersion) (map[string]string, error) {
+	urls := make(map[string]string, len(versions))
+	for i := range versions {
+		v := &versions[i]
+		url, err := objectStore(s.minioClient, v).GetObjectURL(ctx, v.MinIOPath, s.downloadSigner.ttl, &minio.DownloadURLOptions{
+			Filename:    minio.DownloadFilename(packageName, v.Version),
+			ContentType: "application/octet-stream",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate download URL for version %s: %w", v.Version, err)
+		}
+		urls[v.Version] = url
+	}
+	return urls, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+	"webservice/internal/validation"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultIconMaxBytes 未配置时包图标的最大字节数
+	defaultIconMaxBytes = 256 << 10
+	// defaultIconMaxDimension 未配置时包图标宽高的最大像素数
+	defaultIconMaxDimension = 1024
+)
+
+// PackageIcon 读取到的包图标
+type PackageIcon struct {
+	Data        []byte
+	ContentType string
+	ETag        string // 内容的SHA256
+	Private     bool   // 所属包是否为私有包，私有包的图标不能被共享缓存
+}
+
+// IconMaxBytes 包图标的最大字节数，供处理器限制请求体读取
+func (s *PackageService) IconMaxBytes() int64 {
+	return s.icon.MaxBytes
+}
+
+// UploadPackageIcon 上传或替换包图标，只有包所有者和maintainer协作者可以操作
+// 按内容识别格式并校验尺寸，svg清理后保存；新图标写入新的对象键，数据库更新成功后再删除旧图标
+func (s *PackageService) UploadPackageIcon(ctx context.Context, packageName string, userID uint, data []byte) (*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.UploadPackageIcon")
+	defer span.Finish()
+
+	if int64(len(data)) > s.icon.MaxBytes {
+		return nil, fmt.Errorf("%w: maximum is %d bytes", ErrIconTooLarge, s.icon.MaxBytes)
+	}
+	if s.minioClient == nil {
+		return nil, errors.New("storage is not available")
+	}
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightEditMetadata); err != nil {
+		return nil, err
+	}
+
+	icon, err := validation.CheckIcon(data, s.icon.MaxDimension)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIcon, err)
+	}
+
+	sum := sha256.Sum256(icon.Data)
+	etag := hex.EncodeToString(sum[:])
+	if etag == pkg.IconHash {
+		return &pkg, nil
+	}
+	objectKey := minio.IconObjectKey(pkg.ID, etag)
+	if err := s.minioClient.PutIcon(ctx, objectKey, icon.Data, icon.ContentType); err != nil {
+		return nil, err
+	}
+
+	oldKey := pkg.IconObjectKey
+	err = s.db.WithContext(ctx).Model(&pkg).Updates(map[string]interface{}{
+		"icon_object_key":   objectKey,
+		"icon_hash":         etag,
+		"icon_content_type": icon.ContentType,
+	}).Error
+	if err != nil {
+		if delErr := s.minioClient.DeleteObject(ctx, objectKey); delErr != nil {
+			logger.Warnf("Failed to delete orphaned icon %s: %v", objectKey, delErr)
+		}
+		return nil, fmt.Errorf("failed to update package icon: %w", err)
+	}
+	if oldKey != "" && oldKey != objectKey {
+		if err := s.minioClient.DeleteObject(ctx, oldKey); err != nil {
+			logger.Warnf("Failed to delete previous icon %s: %v", oldKey, err)
+		}
+	}
+
+	pkg.IconObjectKey, pkg.IconHash, pkg.IconContentType = objectKey, etag, icon.ContentType
+	pkg.IconURL = models.PackageIconURL(pkg.Name, etag)
+	return &pkg, nil
+}
+
+// GetPackageIcon 读取包图标，私有包只有能读取该包的用户可以查看
+func (s *PackageService) GetPackageIcon(ctx context.Context, packageName string, userID *uint) (*PackageIcon, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetPackageIcon")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: userID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+	if pkg.IconObjectKey == "" {
+		return nil, ErrIconNotFound
+	}
+	if s.minioClient == nil {
+		return nil, errors.New("storage is not available")
+	}
+
+	data, err := s.minioClient.GetIcon(ctx, pkg.IconObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	return &PackageIcon{Data: data, ContentType: pkg.IconContentType, ETag: pkg.IconHash, Private: pkg.IsPrivate}, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+	"webservice/internal/outbox"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// PackageRebuildReport 重建包派生状态的结果，健康的包各修正列表为空
+type PackageRebuildReport struct {
+	Package            string              `json:"package"`
+	DownloadCounts     []CounterCorrection `json:"download_counts"`      // 与下载记录不一致、已修正的版本下载数
+	MissingObjects     []string            `json:"missing_objects"`      // 存储中对象不存在的版本（已标记为损坏）
+	StorageChecked     bool                `json:"storage_checked"`      // 存储不可用时为false，未检查对象
+	PurgedCaches       []string            `json:"purged_caches"`        // 清除的进程内缓存
+	ReindexEventQueued bool                `json:"reindex_event_queued"` // 已写入package.reindexed事件
+}
+
+// CounterCorrection 计数修正
+type CounterCorrection struct {
+	Version string `json:"version"`
+	Before  int64  `json:"before"`
+	After   int64  `json:"after"`
+}
+
+// Corrected 是否修正了数据（清除缓存和重新索引不计入）
+func (r *PackageRebuildReport) Corrected() bool {
+	return len(r.DownloadCounts) > 0 || len(r.MissingObjects) > 0
+}
+
+// RebuildPackage 按源数据重建包的派生状态（管理员）：按下载记录重算各版本下载数、检查每个版本的存储对象、
+// 清除与该包相关的缓存并写入package.reindexed事件；数据一致时只清除缓存和重新索引，可安全重复执行
+// 调用方需要对包有admin_override权限
+func (s *PackageService) RebuildPackage(ctx context.Context, packageName string, caller PackageCaller) (*PackageRebuildReport, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.RebuildPackage")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, caller, PackageRightAdminOverride); err != nil {
+		return nil, err
+	}
+
+	var versions []models.PackageVersion
+	if err := s.db.WithContext(ctx).Where("package_id = ?", pkg.ID).Order("id").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	report := &PackageRebuildReport{Package: pkg.Name, DownloadCounts: []CounterCorrection{}, MissingObjects: []string{}}
+	var err error
+	if report.DownloadCounts, err = s.RecountDownloads(ctx, versions); err != nil {
+		return nil, err
+	}
+	if s.minioClient != nil {
+		if report.MissingObjects, err = s.CheckVersionObjects(ctx, versions); err != nil {
+			return nil, err
+		}
+		report.StorageChecked = true
+	}
+	report.PurgedCaches = s.PurgePackageCaches(versions)
+
+	event := events.Event{Type: events.PackageReindexed, PackageID: pkg.ID, PackageName: pkg.Name}
+	if caller.UserID != nil {
+		event.UserID = *caller.UserID
+	}
+	if err := outbox.Append(s.db.WithContext(ctx), event); err != nil {
+		return nil, fmt.Errorf("failed to queue reindex event: %w", err)
+	}
+	report.ReindexEventQueued = true
+	return report, nil
+}
+
+// RecountDownloads 按下载记录重算版本的下载数，只更新不一致的版本并返回修正列表
+func (s *PackageService) RecountDownloads(ctx context.Context, versions []models.PackageVersion) ([]CounterCorrection, error) {
+	corrections := []CounterCorrection{}
+	if len(versions) == 0 {
+		return corrections, nil
+	}
+
+	ids := make([]uint, len(versions))
+	for i, v := range versions {
+		ids[i] = v.ID
+	}
+	var rows []struct {
+		PackageVersionID uint
+		Count            int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.PackageDownload{}).
+		Select("package_version_id, COUNT(*) AS count").
+		Where("package_version_id IN ? AND flagged = ?", ids, false).
+		Group("package_version_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count downloads: %w", err)
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.PackageVersionID] = row.Count
+	}
+
+	for _, v := range versions {
+		actual := counts[v.ID]
+		if v.DownloadCount == actual {
+			continue
+		}
+		if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("id = ?", v.ID).UpdateColumn("download_count", actual).Error; err != nil {
+			return nil, fmt.Errorf("failed to update download count of %s: %w", v.Version, err)
+		}
+		corrections = append(corrections, CounterCorrection{Version: v.Version, Before: v.DownloadCount, After: actual})
+	}
+	return corrections, nil
+}
+
+// CheckVersionObjects 检查每个版本的存储对象是否存在，不存在的版本标记为损坏并返回其版本号
+// 对象存在时不清除损坏标记（标记也可能来自内容校验不一致），由完整性校验任务重新校验
+func (s *PackageService) CheckVersionObjects(ctx context.Context, versions []models.PackageVersion) ([]string, error) {
+	missing := []string{}
+	for _, v := range versions {
+		if v.MinIOPath == "" {
+			continue
+		}
+		exists, err := objectStore(s.minioClient, &v).ObjectExists(ctx, v.MinIOPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check object of %s: %w", v.Version, err)
+		}
+		if exists {
+			continue
+		}
+		missing = append(missing, v.Version)
+		if !v.Corrupted {
+			if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("id = ?", v.ID).Update("corrupted", true).Error; err != nil {
+				return nil, fmt.Errorf("failed to mark %s as corrupted: %w", v.Version, err)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// PurgePackageCaches 清除与包相关的进程内缓存：各版本的归档条目总数和生态统计
+func (s *PackageService) PurgePackageCaches(versions []models.PackageVersion) []string {
+	for _, v := range versions {
+		s.archiveTotals.Delete(v.ID)
+	}
+
+	s.ecosystem.mu.Lock()
+	s.ecosystem.value = nil
+	s.ecosystem.mu.Unlock()
+
+	return []string{"archive_totals", "ecosystem"}
+}
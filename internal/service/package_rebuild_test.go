@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// adminCaller 返回拥有admin_override权限的调用方
+func adminCaller(t *testing.T, db *gorm.DB) PackageCaller {
+	t.Helper()
+	admin := createTestUser(t, db, "root", models.RoleAdmin)
+	return PackageCaller{UserID: &admin.ID, AdminOverride: true}
+}
+
+// createTestDownloads 为版本写入下载记录，flagged为true时标记为可疑下载
+func createTestDownloads(t *testing.T, db *gorm.DB, version *models.PackageVersion, count int, flagged bool) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		download := &models.PackageDownload{PackageVersionID: version.ID, IPAddress: fmt.Sprintf("192.0.2.%d", i+1), Flagged: flagged}
+		if err := db.Create(download).Error; err != nil {
+			t.Fatalf("failed to create download: %v", err)
+		}
+	}
+}
+
+// downloadCountOf 读取版本当前保存的下载数
+func downloadCountOf(t *testing.T, db *gorm.DB, version *models.PackageVersion) int64 {
+	t.Helper()
+	var v models.PackageVersion
+	if err := db.First(&v, version.ID).Error; err != nil {
+		t.Fatalf("failed to load version: %v", err)
+	}
+	return v.DownloadCount
+}
+
+// reindexEventCount 统计包的package.reindexed事件数
+func reindexEventCount(t *testing.T, db *gorm.DB, packageName string) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Model(&models.OutboxEvent{}).Where("event_type = ? AND package_name = ?", string(events.PackageReindexed), packageName).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	return count
+}
+
+func TestRebuildPackageRecountsDownloads(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "app", owner, false)
+	// 1.0.0计数偏高，2.0.0计数偏低且有可疑下载，3.0.0计数正确
+	inflated := createTestVersion(t, db, pkg, "1.0.0", func(v *models.PackageVersion) { v.DownloadCount = 10 })
+	createTestDownloads(t, db, inflated, 2, false)
+	deflated := createTestVersion(t, db, pkg, "2.0.0", nil)
+	createTestDownloads(t, db, deflated, 3, false)
+	createTestDownloads(t, db, deflated, 4, true)
+	healthy := createTestVersion(t, db, pkg, "3.0.0", func(v *models.PackageVersion) { v.DownloadCount = 1 })
+	createTestDownloads(t, db, healthy, 1, false)
+	s := newTestPackageService(t, db)
+	caller := adminCaller(t, db)
+
+	before := map[*models.PackageVersion]int64{inflated: 10, deflated: 0, healthy: 1}
+	for v, want := range before {
+		if got := downloadCountOf(t, db, v); got != want {
+			t.Fatalf("%s download_count before rebuild = %d, want %d", v.Version, got, want)
+		}
+	}
+	if got := reindexEventCount(t, db, "app"); got != 0 {
+		t.Fatalf("reindex events before rebuild = %d, want 0", got)
+	}
+
+	report, err := s.RebuildPackage(context.Background(), "app", caller)
+	if err != nil {
+		t.Fatalf("RebuildPackage: %v", err)
+	}
+	want := []CounterCorrection{{Version: "1.0.0", Before: 10, After: 2}, {Version: "2.0.0", Before: 0, After: 3}}
+	if fmt.Sprint(report.DownloadCounts) != fmt.Sprint(want) {
+		t.Errorf("download corrections = %+v, want %+v", report.DownloadCounts, want)
+	}
+	if !report.Corrected() || report.StorageChecked || len(report.MissingObjects) != 0 {
+		t.Errorf("report = %+v, want corrected counts and no storage check", report)
+	}
+
+	// 修正后数据库中的计数等于未标记的下载记录数
+	after := map[*models.PackageVersion]int64{inflated: 2, deflated: 3, healthy: 1}
+	for v, want := range after {
+		if got := downloadCountOf(t, db, v); got != want {
+			t.Errorf("%s download_count after rebuild = %d, want %d", v.Version, got, want)
+		}
+	}
+	if !report.ReindexEventQueued {
+		t.Error("ReindexEventQueued = false, want true")
+	}
+	if got := reindexEventCount(t, db, "app"); got != 1 {
+		t.Errorf("reindex events after rebuild = %d, want 1", got)
+	}
+
+	// 再次重建不修正任何数据，只再次写入重新索引事件
+	again, err := s.RebuildPackage(context.Background(), "app", caller)
+	if err != nil {
+		t.Fatalf("second RebuildPackage: %v", err)
+	}
+	if again.Corrected() || len(again.DownloadCounts) != 0 {
+		t.Errorf("second report = %+v, want no corrections", again)
+	}
+	for v, want := range after {
+		if got := downloadCountOf(t, db, v); got != want {
+			t.Errorf("%s download_count after second rebuild = %d, want %d", v.Version, got, want)
+		}
+	}
+	if got := reindexEventCount(t, db, "app"); got != 2 {
+		t.Errorf("reindex events after second rebuild = %d, want 2", got)
+	}
+}
+
+func TestRebuildPackageMarksMissingObjects(t *testing.T) {
+	s := newUploadTestService(t)
+	ctx := context.Background()
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+	intact, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken, err := uploadTestVersion(s, pkg, "2.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.minioClient.DeleteObject(ctx, broken.MinIOPath); err != nil {
+		t.Fatal(err)
+	}
+	// 缓存中保留版本的条目总数，重建后应被清除
+	s.archiveTotals.Store(intact.ID, 1)
+	caller := adminCaller(t, s.db)
+
+	corrupted := func(v *models.PackageVersion) bool {
+		t.Helper()
+		var current models.PackageVersion
+		if err := s.db.First(&current, v.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return current.Corrupted
+	}
+	if corrupted(intact) || corrupted(broken) {
+		t.Fatal("versions are corrupted before rebuild")
+	}
+
+	report, err := s.RebuildPackage(ctx, "app", caller)
+	if err != nil {
+		t.Fatalf("RebuildPackage: %v", err)
+	}
+	if !report.StorageChecked || fmt.Sprint(report.MissingObjects) != "[2.0.0]" {
+		t.Errorf("report = %+v, want storage checked and 2.0.0 missing", report)
+	}
+	if corrupted(intact) || !corrupted(broken) {
+		t.Errorf("corrupted after rebuild = 1.0.0 %v, 2.0.0 %v; want only 2.0.0", corrupted(intact), corrupted(broken))
+	}
+	if _, cached := s.archiveTotals.Load(intact.ID); cached {
+		t.Error("archive total is still cached after rebuild")
+	}
+
+	// 对象仍然缺失，再次重建继续报告但不重复修改
+	again, err := s.RebuildPackage(ctx, "app", caller)
+	if err != nil {
+		t.Fatalf("second RebuildPackage: %v", err)
+	}
+	if fmt.Sprint(again.MissingObjects) != "[2.0.0]" || !corrupted(broken) {
+		t.Errorf("second report = %+v, want 2.0.0 still missing and corrupted", again)
+	}
+}
+
+func TestRebuildPackageNotFound(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestPackageService(t, db)
+	if _, err := s.RebuildPackage(context.Background(), "missing", adminCaller(t, db)); err == nil || err.Error() != "package not found" {
+		t.Errorf("RebuildPackage err = %v, want package not found", err)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// versionRows 引用版本的数据，参数为版本ID列表或子查询；删除包和重新发布已删除的版本号时先删除这些记录
+// 新增引用版本的表时需要加入这里，否则删除后会留下孤立的记录，或者因外键约束无法删除版本
+var versionRows = []struct {
+	model interface{}
+	where string
+	name  string
+}{
+	{&models.PackageDownload{}, "package_version_id IN (?)", "download records"},
+	{&models.PackageVersionPin{}, "package_version_id IN (?)", "version pins"},
+	{&models.StorageTierChange{}, "package_version_id IN (?)", "storage tier changes"},
+	{&models.VersionScanResult{}, "package_version_id IN (?)", "version scan results"},
+}
+
+// packageRows 删除包时一并删除的关联数据，在versionRows之后按顺序执行：先删除版本，再删除其他引用包的记录
+// 新增引用包的表时需要加入这里，否则删除包后会留下孤立的记录
+var packageRows = []struct {
+	model interface{}
+	where string
+	name  string
+}{
+	{&models.PackageVersion{}, "package_id = @id", "package versions"},
+	{&models.PackageAlias{}, "package_id = @id", "package aliases"}, // 释放旧名称
+	{&models.PackageCategory{}, "package_id = @id", "package categories"},
+	{&models.WikiPageRevision{}, "page_id IN (SELECT id FROM package_wiki_pages WHERE package_id = @id)", "wiki page revisions"},
+	{&models.WikiPage{}, "package_id = @id", "wiki pages"},
+	{&models.PackageWatcher{}, "package_id = @id", "package watchers"},
+	{&models.PackageCollaborator{}, "package_id = @id", "package collaborators"},
+	{&models.PackageRecommendation{}, "package_id = @id OR recommended_package_id = @id", "package recommendations"},
+	{&models.PackageBandwidthUsage{}, "package_id = @id", "bandwidth usage"},
+	{&models.LeaderboardEntry{}, "package_id = @id", "leaderboard entries"},
+	{&models.UploadSession{}, "package_id = @id", "upload sessions"},
+}
+
+// deleteVersionRows 在事务tx中删除引用指定版本的数据，ids为版本ID列表或返回版本ID的子查询
+func deleteVersionRows(tx *gorm.DB, ids interface{}) error {
+	for _, rows := range versionRows {
+		if err := tx.Where(rows.where, ids).Delete(rows.model).Error; err != nil {
+			return fmt.Errorf("failed to delete %s: %w", rows.name, err)
+		}
+	}
+	return nil
+}
+
+// deletePackageRows 在事务tx中删除包的全部关联数据，包本身由调用方删除
+func deletePackageRows(tx *gorm.DB, packageID uint) error {
+	// 包的全部版本ID，包括已软删除的版本
+	versionIDs := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.PackageVersion{}).Select("id").Where("package_id = ?", packageID)
+	if err := deleteVersionRows(tx, versionIDs); err != nil {
+		return err
+	}
+	for _, rows := range packageRows {
+		if err := tx.Where(rows.where, sql.Named("id", packageID)).Delete(rows.model).Error; err != nil {
+			return fmt.Errorf("failed to delete %s: %w", rows.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultUploadURLTTL 未配置时预签名上传URL和上传会话的有效期
+	defaultUploadURLTTL = time.Hour
+	// defaultSyncHashMaxBytes 未配置时在回调请求中同步处理的最大文件大小
+	defaultSyncHashMaxBytes = 64 << 20
+	// staleProcessingAfter 处理中的会话超过该时间未更新时视为已中断（如进程重启），由清理任务标记为失败
+	staleProcessingAfter = 6 * time.Hour
+	// finishedUploadRetention 已完成或失败的会话保留时长，供客户端查询结果
+	finishedUploadRetention = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrUploadSessionNotFound 上传会话不存在、不属于当前用户或与路径中的包版本不一致
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	// ErrUploadSessionExpired 上传会话已过期
+	ErrUploadSessionExpired = errors.New("upload session expired")
+	// ErrUploadObjectMissing 存储中没有找到上传的文件
+	ErrUploadObjectMissing = errors.New("uploaded object not found, upload the file to upload_url first")
+	// ErrUploadSizeMismatch 上传的文件大小与声明的不一致
+	ErrUploadSizeMismatch = errors.New("uploaded object size does not match")
+	// ErrUploadETagMismatch 上传的文件ETag与回调中的不一致
+	ErrUploadETagMismatch = errors.New("uploaded object etag does not match")
+)
+
+// CreateUploadSession 创建预签名上传会话（包所有者），返回客户端直接上传文件使用的URL
+// 创建时执行与直接上传相同的发布检查，回调时再检查一次
+func (s *PackageService) CreateUploadSession(ctx context.Context, packageName string, req *models.CreateUploadSessionRequest, uploaderID uint) (*models.CreateUploadSessionResponse, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.CreateUploadSession")
+	defer span.Finish()
+
+	pkg, err := s.findUploadTarget(ctx, packageName, uploaderID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.checkPublishable(ctx, pkg, &req.CreatePackageVersionRequest, req.Size, uploaderID); err != nil {
+		return nil, err
+	}
+	if req.Dependencies == nil {
+		req.Dependencies = make(map[string]string)
+	}
+	metadata, err := json.Marshal(req.CreatePackageVersionRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode version metadata: %w", err)
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+	uploadID := hex.EncodeToString(raw)
+
+	session := &models.UploadSession{
+		ID:          uploadID,
+		PackageID:   pkg.ID,
+		PackageName: pkg.Name,
+		Version:     req.Version,
+		UploaderID:  uploaderID,
+		ObjectKey:   minio.StagingObjectKey(uploadID),
+		Size:        req.Size,
+		Metadata:    string(metadata),
+		Status:      models.UploadSessionPending,
+		ExpiresAt:   time.Now().UTC().Add(s.presignedUpload.URLTTL),
+	}
+
+	uploadURL, err := s.minioClient.GetUploadURL(ctx, session.ObjectKey, s.presignedUpload.URLTTL)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return &models.CreateUploadSessionResponse{
+		UploadID:  session.ID,
+		UploadURL: uploadURL,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// GetUploadSession 查询上传会话（上传者本人），用于轮询后台处理的结果
+func (s *PackageService) GetUploadSession(ctx context.Context, packageName, version, uploadID string, uploaderID uint) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := s.db.WithContext(ctx).Where("id = ?", uploadID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to find upload session: %w", err)
+	}
+	if session.UploaderID != uploaderID || session.PackageName != packageName || session.Version != version {
+		return nil, ErrUploadSessionNotFound
+	}
+	return &session, nil
+}
+
+// CompleteUpload 处理预签名上传的完成回调（上传者本人）
+// 确认暂存对象存在且大小（和ETag）一致后计算SHA256、创建版本并标记会话完成；
+// 不超过sync_hash_max_bytes的文件同步处理，返回创建的版本，更大的文件在后台处理，返回的会话状态为processing，version为nil
+// 会话已不是pending（重复回调）时直接返回会话的当前状态
+func (s *PackageService) CompleteUpload(ctx context.Context, packageName, version string, req *models.CompleteUploadRequest, uploaderID uint) (*models.UploadSession, *models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.CompleteUpload")
+	defer span.Finish()
+
+	session, err := s.GetUploadSession(ctx, packageName, version, req.UploadID, uploaderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.Status != models.UploadSessionPending {
+		return session, nil, nil
+	}
+	if time.Now().UTC().After(session.ExpiresAt) {
+		return nil, nil, ErrUploadSessionExpired
+	}
+	if req.Size != session.Size {
+		return nil, nil, fmt.Errorf("%w: declared %d bytes, got %d", ErrUploadSizeMismatch, session.Size, req.Size)
+	}
+
+	info, err := s.minioClient.StatObject(ctx, session.ObjectKey)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, nil, ErrUploadObjectMissing
+		}
+		return nil, nil, err
+	}
+	if info.Size != session.Size {
+		return nil, nil, fmt.Errorf("%w: declared %d bytes, stored %d", ErrUploadSizeMismatch, session.Size, info.Size)
+	}
+	if req.ETag != "" && strings.Trim(req.ETag, `"`) != strings.Trim(info.ETag, `"`) {
+		return nil, nil, ErrUploadETagMismatch
+	}
+
+	// 按状态条件更新，并发的重复回调只有一个会开始处理
+	result := s.db.WithContext(ctx).Model(&models.UploadSession{}).
+		Where("id = ? AND status = ?", session.ID, models.UploadSessionPending).
+		Update("status", models.UploadSessionProcessing)
+	if result.Error != nil {
+		return nil, nil, fmt.Errorf("failed to update upload session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		current, err := s.GetUploadSession(ctx, packageName, version, req.UploadID, uploaderID)
+		return current, nil, err
+	}
+	session.Status = models.UploadSessionProcessing
+
+	// 处理不随回调请求取消，客户端断开后仍会完成
+	processCtx := context.WithoutCancel(ctx)
+	if session.Size > s.presignedUpload.SyncHashMaxBytes {
+		go func() {
+			if _, err := s.processUpload(processCtx, session); err != nil {
+				logger.Warnf("Failed to process upload %s for %s@%s: %v", session.ID, session.PackageName, session.Version, err)
+			}
+		}()
+		return session, nil, nil
+	}
+
+	pkgVersion, err := s.processUpload(processCtx, session)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, pkgVersion, nil
+}
+
+// processUpload 计算暂存对象的SHA256，复制到正式对象键并创建版本，完成后更新会话状态
+// 失败时删除暂存对象并将会话标记为failed
+func (s *PackageService) processUpload(ctx context.Context, session *models.UploadSession) (*models.PackageVersion, error) {
+	pkgVersion, err := s.publishUpload(ctx, session)
+	if err != nil {
+		if delErr := s.minioClient.DeleteObject(ctx, session.ObjectKey); delErr != nil {
+			logger.Warnf("Failed to delete staging object %s: %v", session.ObjectKey, delErr)
+		}
+		s.finishUploadSession(ctx, session, nil, err)
+		return nil, err
+	}
+	s.finishUploadSession(ctx, session, pkgVersion, nil)
+	return pkgVersion, nil
+}
+
+// publishUpload 在上传锁内重新执行发布检查，计算哈希并创建版本
+func (s *PackageService) publishUpload(ctx context.Context, session *models.UploadSession) (*models.PackageVersion, error) {
+	var req models.CreatePackageVersionRequest
+	if err := json.Unmarshal([]byte(session.Metadata), &req); err != nil {
+		return nil, fmt.Errorf("invalid upload session metadata: %w", err)
+	}
+
+	pkg, err := s.findUploadTarget(ctx, session.PackageName, session.UploaderID)
+	if err != nil {
+		return nil, err
+	}
+	release, err := s.acquireUploadLock(ctx, pkg.ID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	dependencyWarnings, err := s.checkPublishable(ctx, pkg, &req, session.Size, session.UploaderID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, _, err := s.minioClient.DownloadObject(ctx, session.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded object: %w", err)
+	}
+
+	packageInfo, err := s.minioClient.PromoteUpload(ctx, session.ObjectKey, pkg.Name, req.Version, &minio.UploadOptions{
+		ContentType: "application/octet-stream",
+		Metadata: map[string]string{
+			"uploader-id": fmt.Sprintf("%d", session.UploaderID),
+			"description": req.Description,
+		},
+		FilenameOverride: minio.DownloadFilename(pkg.Name, req.Version),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	version := newUploadedVersion(pkg, &req, session.UploaderID, packageInfo, fmt.Sprintf("%x", hasher.Sum(nil)), dependencyWarnings)
+	return s.publishVersion(ctx, pkg, &req, version)
+}
+
+// finishUploadSession 记录上传会话的处理结果，更新失败只记录警告
+func (s *PackageService) finishUploadSession(ctx context.Context, session *models.UploadSession, pkgVersion *models.PackageVersion, processErr error) {
+	now := time.Now().UTC()
+	updates := map[string]interface{}{"completed_at": now}
+	if processErr != nil {
+		message := processErr.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+		updates["status"] = models.UploadSessionFailed
+		updates["error"] = message
+		session.Status, session.Error = models.UploadSessionFailed, message
+	} else {
+		updates["status"] = models.UploadSessionCompleted
+		updates["version_id"] = pkgVersion.ID
+		session.Status, session.VersionID = models.UploadSessionCompleted, &pkgVersion.ID
+	}
+	session.CompletedAt = &now
+
+	if err := s.db.WithContext(ctx).Model(&models.UploadSession{}).Where("id = ?", session.ID).Updates(updates).Error; err != nil {
+		logger.Warnf("Failed to update upload session %s: %v", session.ID, err)
+	}
+}
+
+// CleanupUploadSessions 清理上传会话：过期未完成和处理中断的会话标记为失败并删除暂存对象，
+// 删除完成超过7天的会话，返回标记为失败的会话数
+func (s *PackageService) CleanupUploadSessions(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+
+	var stale []models.UploadSession
+	err := s.db.WithContext(ctx).
+		Where("(status = ? AND expires_at < ?) OR (status = ? AND updated_at < ?)",
+			models.UploadSessionPending, now, models.UploadSessionProcessing, now.Add(-staleProcessingAfter)).
+		Find(&stale).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find stale upload sessions: %w", err)
+	}
+
+	failed := 0
+	for i := range stale {
+		session := &stale[i]
+		exists, err := s.minioClient.ObjectExists(ctx, session.ObjectKey)
+		if err != nil {
+			logger.Warnf("Failed to check staging object %s: %v", session.ObjectKey, err)
+			continue
+		}
+		if exists {
+			if err := s.minioClient.DeleteObject(ctx, session.ObjectKey); err != nil {
+				logger.Warnf("Failed to delete staging object %s: %v", session.ObjectKey, err)
+				continue
+			}
+		}
+		reason := ErrUploadSessionExpired
+		if session.Status == models.UploadSessionProcessing {
+			reason = errors.New("upload processing was interrupted")
+		}
+		s.finishUploadSession(ctx, session, nil, reason)
+		failed++
+	}
+
+	err = s.db.WithContext(ctx).
+		Where("status IN ? AND completed_at < ?", []string{models.UploadSessionCompleted, models.UploadSessionFailed}, now.Add(-finishedUploadRetention)).
+		Delete(&models.UploadSession{}).Error
+	if err != nil {
+		return failed, fmt.Errorf("failed to delete finished upload sessions: %w", err)
+	}
+	return failed, nil
+}
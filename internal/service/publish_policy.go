@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"webservice/internal/models"
+)
+
+// enforcePublishPolicy 按包的发布策略校验待上传版本，必要时修正预发布标记
+func (s *PackageService) enforcePublishPolicy(ctx context.Context, pkg *models.Package, req *models.CreatePackageVersionRequest) error {
+	// 自动识别预发布版本，忽略表单中的is_prerelease
+	if pkg.AutoPrereleaseDetection && looksLikePrerelease(req.Version) {
+		req.IsPrerelease = true
+	}
+
+	if !pkg.RequireMonotonicVersions && !pkg.DisallowPrereleaseLatest {
+		return nil
+	}
+
+	newVersion, ok := parseSemver(req.Version)
+	if !ok {
+		if pkg.RequireMonotonicVersions {
+			return ErrInvalidSemver
+		}
+		// 无法比较的版本号不做最新版本判断
+		return nil
+	}
+
+	// 已删除（撤回）的版本不参与比较
+	var existing []models.PackageVersion
+	if err := s.db.WithContext(ctx).Select("version", "is_prerelease").
+		Where("package_id = ?", pkg.ID).Find(&existing).Error; err != nil {
+		return fmt.Errorf("failed to get existing versions: %w", err)
+	}
+
+	var highest, highestStable *semVersion
+	for _, v := range existing {
+		sv, ok := parseSemver(v.Version)
+		if !ok {
+			continue
+		}
+		if highest == nil || compareSemver(sv, *highest) > 0 {
+			highest = &sv
+		}
+		if !v.IsPrerelease && (highestStable == nil || compareSemver(sv, *highestStable) > 0) {
+			svStable := sv
+			highestStable = &svStable
+		}
+	}
+
+	if pkg.RequireMonotonicVersions && !req.ForceBackfill &&
+		highest != nil && compareSemver(newVersion, *highest) < 0 {
+		return ErrVersionNotMonotonic
+	}
+
+	if pkg.DisallowPrereleaseLatest && req.IsPrerelease &&
+		(highestStable == nil || compareSemver(newVersion, *highestStable) > 0) {
+		return ErrPrereleaseLatest
+	}
+
+	return nil
+}
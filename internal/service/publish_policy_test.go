@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestEnforcePublishPolicy(t *testing.T) {
+	type policy struct{ monotonic, autoPrerelease, noPrereleaseLatest bool }
+	tests := []struct {
+		name           string
+		policy         policy
+		existing       map[string]bool // 已有版本 -> 是否预发布
+		version        string
+		prerelease     bool
+		forceBackfill  bool
+		wantErr        error
+		wantPrerelease bool
+	}{
+		{name: "no policy allows lower version", existing: map[string]bool{"2.0.0": false}, version: "1.2.0"},
+		{name: "monotonic rejects lower version", policy: policy{monotonic: true}, existing: map[string]bool{"2.0.0": false}, version: "1.2.0", wantErr: ErrVersionNotMonotonic},
+		{name: "monotonic allows higher version", policy: policy{monotonic: true}, existing: map[string]bool{"2.0.0": false}, version: "2.0.1"},
+		{name: "monotonic compares against prereleases", policy: policy{monotonic: true}, existing: map[string]bool{"3.0.0-rc.1": true}, version: "2.5.0", wantErr: ErrVersionNotMonotonic},
+		{name: "monotonic with force backfill", policy: policy{monotonic: true}, existing: map[string]bool{"2.0.0": false}, version: "1.2.0", forceBackfill: true},
+		{name: "monotonic rejects non-semver", policy: policy{monotonic: true}, version: "latest", wantErr: ErrInvalidSemver},
+		{name: "monotonic first version", policy: policy{monotonic: true}, version: "0.1.0"},
+		{name: "auto detection marks prerelease", policy: policy{autoPrerelease: true}, version: "1.0.0-beta.2", wantPrerelease: true},
+		{name: "auto detection overrides form for -RC", policy: policy{autoPrerelease: true}, version: "1.0.0-RC1", wantPrerelease: true},
+		{name: "auto detection keeps stable version", policy: policy{autoPrerelease: true}, version: "1.0.0"},
+		{name: "without detection the form field wins", version: "1.0.0-beta", prerelease: false},
+		{name: "prerelease cannot become latest", policy: policy{noPrereleaseLatest: true}, existing: map[string]bool{"1.0.0": false}, version: "2.0.0-alpha", prerelease: true, wantErr: ErrPrereleaseLatest},
+		{name: "prerelease below latest stable", policy: policy{noPrereleaseLatest: true}, existing: map[string]bool{"2.0.0": false}, version: "1.5.0-alpha", prerelease: true, wantPrerelease: true},
+		{name: "first version cannot be prerelease", policy: policy{noPrereleaseLatest: true}, version: "0.1.0-alpha", prerelease: true, wantErr: ErrPrereleaseLatest},
+		{name: "detected prerelease cannot become latest", policy: policy{autoPrerelease: true, noPrereleaseLatest: true}, existing: map[string]bool{"1.0.0": false}, version: "1.1.0-rc.1", wantErr: ErrPrereleaseLatest},
+		{name: "stable release allowed with all policies", policy: policy{true, true, true}, existing: map[string]bool{"1.0.0": false, "1.1.0-rc.1": true}, version: "1.1.0"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createTestUser(t, db, "alice", models.RoleUser)
+			pkg := createTestPackage(t, db, "policy", owner, false)
+			pkg.RequireMonotonicVersions = tt.policy.monotonic
+			pkg.AutoPrereleaseDetection = tt.policy.autoPrerelease
+			pkg.DisallowPrereleaseLatest = tt.policy.noPrereleaseLatest
+			for version, prerelease := range tt.existing {
+				prerelease := prerelease
+				createTestVersion(t, db, pkg, version, func(v *models.PackageVersion) { v.IsPrerelease = prerelease })
+			}
+
+			s := newTestPackageService(t, db)
+			req := &models.CreatePackageVersionRequest{Version: tt.version, IsPrerelease: tt.prerelease, ForceBackfill: tt.forceBackfill}
+			err := s.enforcePublishPolicy(context.Background(), pkg, req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("case %d: err = %v, want %v", i, err, tt.wantErr)
+			}
+			if err == nil && req.IsPrerelease != tt.wantPrerelease {
+				t.Errorf("IsPrerelease = %v, want %v", req.IsPrerelease, tt.wantPrerelease)
+			}
+		})
+	}
+}
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.0.0+build.5", "1.0.0", 0},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta", 1},
+	}
+	for _, tt := range tests {
+		a, okA := parseSemver(tt.a)
+		b, okB := parseSemver(tt.b)
+		if !okA || !okB {
+			t.Fatalf("failed to parse %q or %q", tt.a, tt.b)
+		}
+		if got := compareSemver(a, b); got != tt.want {
+			t.Errorf("compareSemver(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "1.0", "1.0.0.0", "1.x.0", "1.0.0-", "1.0.0-a..b", "-1.0.0"} {
+		if _, ok := parseSemver(invalid); ok {
+			t.Errorf("parseSemver(%q) succeeded, want failure", invalid)
+		}
+	}
+}
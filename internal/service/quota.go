@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// defaultQuotaSoftLimitPercent 未配置时软限制占存储配额的百分比
+const defaultQuotaSoftLimitPercent = 80
+
+// ErrQuotaExceeded 上传后将超过用户的存储配额
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// QuotaUsage 用户的存储用量
+type QuotaUsage struct {
+	Used    int64 // 用户所有包的版本文件大小合计（字节）
+	Limit   int64 // 存储配额（字节），0表示不限
+	Warning bool  // 用量达到软限制
+}
+
+// StorageQuotaEnabled 是否配置了存储配额
+func (s *PackageService) StorageQuotaEnabled() bool {
+	return s.storageQuota > 0
+}
+
+// GetQuotaUsage 获取用户的存储用量，用量达到配额的软限制百分比时Warning为true
+func (s *PackageService) GetQuotaUsage(ctx context.Context, userID uint) (*QuotaUsage, error) {
+	usage := &QuotaUsage{Limit: s.storageQuota}
+	err := s.db.WithContext(ctx).Table("package_versions AS pv").
+		Select("COALESCE(SUM(pv.file_size), 0)").
+		Joins("JOIN packages p ON p.id = pv.package_id").
+		Where("pv.deleted_at IS NULL AND p.deleted_at IS NULL AND p.owner_id = ?", userID).
+		Scan(&usage.Used).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	if usage.Limit > 0 {
+		usage.Warning = usage.Used*100 >= usage.Limit*int64(s.quotaSoftLimitPercent)
+	}
+	return usage, nil
+}
+
+// checkStorageQuota 检查上传size字节后是否超过用户的存储配额
+// 同一用户的并发上传可能同时通过检查，配额用于限制长期用量，允许这种少量超出
+func (s *PackageService) checkStorageQuota(ctx context.Context, userID uint, size int64) error {
+	if !s.StorageQuotaEnabled() {
+		return nil
+	}
+	usage, err := s.GetQuotaUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.Used+size > usage.Limit {
+		return fmt.Errorf("%w: %d of %d bytes used, upload is %d bytes", ErrQuotaExceeded, usage.Used, usage.Limit, size)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// recommendationWindow 计算共同下载时统计的下载记录时间范围
+	recommendationWindow = 90 * 24 * time.Hour
+	// maxStoredRecommendations 每个包保存的推荐数量上限
+	maxStoredRecommendations = 20
+	// DefaultRecommendationLimit 默认返回的推荐数量
+	DefaultRecommendationLimit = 5
+	// MaxRecommendationLimit 单次最多返回的推荐数量
+	MaxRecommendationLimit = maxStoredRecommendations
+)
+
+// coDownload 两个包在同一会话（同一用户同一天）中被共同下载的次数
+type coDownload struct {
+	PackageID            uint
+	RecommendedPackageID uint
+	Sessions             int64
+}
+
+// packageSessions 下载过某个包的会话数
+type packageSessions struct {
+	PackageID uint
+	Sessions  int64
+}
+
+// downloadSessionsSQL 最近的下载按（包、用户、日期）去重，每行表示一个会话下载过一个包，匿名下载不参与计算
+const downloadSessionsSQL = `SELECT DISTINCT pv.package_id AS package_id, d.user_id AS user_id, DATE(d.download_time) AS day
+FROM package_downloads d
+JOIN package_versions pv ON pv.id = d.package_version_id
+WHERE d.user_id IS NOT NULL AND d.download_time >= ?`
+
+// ComputeRecommendations 根据共同下载重新计算所有包的推荐，返回写入的推荐数量
+// 推荐分数为下载过该包的会话中同时下载推荐包的比例，每个包只保留分数最高的若干个推荐
+func (s *PackageService) ComputeRecommendations(ctx context.Context) (int, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ComputeRecommendations")
+	defer span.Finish()
+
+	since := time.Now().UTC().Add(-recommendationWindow)
+
+	var totals []packageSessions
+	err := s.db.WithContext(ctx).Raw(
+		"SELECT s.package_id AS package_id, COUNT(*) AS sessions FROM ("+downloadSessionsSQL+") s GROUP BY s.package_id",
+		since,
+	).Scan(&totals).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count download sessions: %w", err)
+	}
+	sessionsByPackage := make(map[uint]int64, len(totals))
+	for _, t := range totals {
+		sessionsByPackage[t.PackageID] = t.Sessions
+	}
+
+	var pairs []coDownload
+	err = s.db.WithContext(ctx).Raw(
+		"SELECT a.package_id AS package_id, b.package_id AS recommended_package_id, COUNT(*) AS sessions"+
+			" FROM ("+downloadSessionsSQL+") a JOIN ("+downloadSessionsSQL+") b"+
+			" ON a.user_id = b.user_id AND a.day = b.day AND a.package_id <> b.package_id"+
+			" GROUP BY a.package_id, b.package_id",
+		since, since,
+	).Scan(&pairs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate co-downloads: %w", err)
+	}
+
+	// 按包分组，取共同下载次数最多的推荐
+	byPackage := make(map[uint][]coDownload)
+	for _, p := range pairs {
+		byPackage[p.PackageID] = append(byPackage[p.PackageID], p)
+	}
+
+	now := time.Now().UTC()
+	var recommendations []models.PackageRecommendation
+	for packageID, candidates := range byPackage {
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].Sessions != candidates[j].Sessions {
+				return candidates[i].Sessions > candidates[j].Sessions
+			}
+			return candidates[i].RecommendedPackageID < candidates[j].RecommendedPackageID
+		})
+		if len(candidates) > maxStoredRecommendations {
+			candidates = candidates[:maxStoredRecommendations]
+		}
+		total := sessionsByPackage[packageID]
+		for _, c := range candidates {
+			score := 0.0
+			if total > 0 {
+				score = float64(c.Sessions) / float64(total)
+			}
+			recommendations = append(recommendations, models.PackageRecommendation{
+				PackageID:            packageID,
+				RecommendedPackageID: c.RecommendedPackageID,
+				Score:                score,
+				ComputedAt:           now,
+			})
+		}
+	}
+
+	// 整表替换，读取方在事务提交前仍看到上一次的结果
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.PackageRecommendation{}).Error; err != nil {
+			return fmt.Errorf("failed to clear recommendations: %w", err)
+		}
+		if len(recommendations) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(recommendations, 500).Error; err != nil {
+			return fmt.Errorf("failed to save recommendations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(recommendations), nil
+}
+
+// GetRecommendations 获取与指定包经常被一起下载的公开包，没有共同下载数据时回退为同一作者的其他包
+func (s *PackageService) GetRecommendations(ctx context.Context, packageName string, limit int) ([]*models.Package, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetRecommendations")
+	defer span.Finish()
+
+	if limit <= 0 {
+		limit = DefaultRecommendationLimit
+	}
+	if limit > MaxRecommendationLimit {
+		limit = MaxRecommendationLimit
+	}
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	var packages []*models.Package
+	err := s.db.WithContext(ctx).Preload("Owner").
+		Joins("JOIN package_recommendations r ON r.recommended_package_id = packages.id").
+		Where("r.package_id = ? AND packages.is_private = ?", pkg.ID, false).
+		Order("r.score DESC").
+		Limit(limit).
+		Find(&packages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendations: %w", err)
+	}
+	if len(packages) > 0 {
+		return packages, nil
+	}
+
+	// 回退：同一所有者或同一作者的其他公开包
+	query := s.db.WithContext(ctx).Preload("Owner").
+		Where("id <> ? AND is_private = ?", pkg.ID, false)
+	if pkg.Author != "" {
+		query = query.Where("(owner_id = ? OR author = ?)", pkg.OwnerID, pkg.Author)
+	} else {
+		query = query.Where("owner_id = ?", pkg.OwnerID)
+	}
+	if err := query.Order("updated_at DESC").Limit(limit).Find(&packages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get packages by the same author: %w", err)
+	}
+	return packages, nil
+}
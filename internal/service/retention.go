@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// PinVersion 置顶包版本，置顶版本不参与清理和预发布版本过期（包所有者和maintainer协作者）
+func (s *PackageService) PinVersion(ctx context.Context, packageName, version string, userID uint) (*models.PackageVersionPin, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.PinVersion")
+	defer span.Finish()
+
+	pkgVersion, err := s.findOwnedVersion(ctx, packageName, version, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重复置顶时返回已有记录
+	var pin models.PackageVersionPin
+	err = s.db.WithContext(ctx).Where("package_version_id = ?", pkgVersion.ID).First(&pin).Error
+	if err == nil {
+		return &pin, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check version pin: %w", err)
+	}
+
+	pin = models.PackageVersionPin{
+		PackageVersionID: pkgVersion.ID,
+		PinnedBy:         userID,
+	}
+	if err := s.db.WithContext(ctx).Create(&pin).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return &pin, nil
+		}
+		return nil, fmt.Errorf("failed to pin version: %w", err)
+	}
+	return &pin, nil
+}
+
+// UnpinVersion 取消包版本置顶（包所有者和maintainer协作者）
+func (s *PackageService) UnpinVersion(ctx context.Context, packageName, version string, userID uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.UnpinVersion")
+	defer span.Finish()
+
+	pkgVersion, err := s.findOwnedVersion(ctx, packageName, version, userID)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).Where("package_version_id = ?", pkgVersion.ID).Delete(&models.PackageVersionPin{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unpin version: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("version is not pinned")
+	}
+	return nil
+}
+
+// PruneOldVersions 清理旧版本，保留最近keep个版本（不少于包设置的保留数）及所有置顶版本，跳过锁定的版本（包所有者和maintainer协作者）
+func (s *PackageService) PruneOldVersions(ctx context.Context, packageName string, keep int, userID uint) (*models.PruneResult, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.PruneOldVersions")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightPublish); err != nil {
+		return nil, err
+	}
+
+	if pkg.KeepRecentVersions > keep {
+		keep = pkg.KeepRecentVersions
+	}
+
+	candidates, pinned, err := s.retentionCandidates(ctx, pkg.ID, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.PruneResult{
+		Deleted:       []string{},
+		SkippedPinned: []string{},
+		SkippedLocked: []string{},
+		Kept:          keep,
+	}
+	for _, v := range pinned {
+		result.SkippedPinned = append(result.SkippedPinned, v.Version)
+	}
+	for i := range candidates {
+		// 锁定的版本不能由所有者删除，清理时跳过
+		if candidates[i].Locked {
+			result.SkippedLocked = append(result.SkippedLocked, candidates[i].Version)
+			continue
+		}
+		if err := s.removeVersion(ctx, &candidates[i], pkg.Name, userID); err != nil {
+			return result, err
+		}
+		result.Deleted = append(result.Deleted, candidates[i].Version)
+	}
+
+	return result, nil
+}
+
+// ExpirePrereleases 删除超过maxAge的预发布版本，置顶版本和包设置的最近保留版本不受影响，返回删除数量；锁定版本同样会被过期删除（系统保留策略不受锁定限制）
+func (s *PackageService) ExpirePrereleases(ctx context.Context, maxAge time.Duration) (int, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ExpirePrereleases")
+	defer span.Finish()
+
+	cutoff := time.Now().UTC().Add(-maxAge)
+
+	// 只处理存在过期预发布版本的包
+	var packageIDs []uint
+	err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Where("is_prerelease = ? AND created_at < ?", true, cutoff).
+		Distinct("package_id").Pluck("package_id", &packageIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired prereleases: %w", err)
+	}
+
+	deleted := 0
+	for _, packageID := range packageIDs {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+
+		var pkg models.Package
+		if err := s.db.WithContext(ctx).First(&pkg, packageID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return deleted, fmt.Errorf("failed to find package: %w", err)
+		}
+
+		candidates, _, err := s.retentionCandidates(ctx, pkg.ID, pkg.KeepRecentVersions)
+		if err != nil {
+			return deleted, err
+		}
+
+		for i := range candidates {
+			if !candidates[i].IsPrerelease || !candidates[i].CreatedAt.Before(cutoff) {
+				continue
+			}
+			if err := s.removeVersion(ctx, &candidates[i], pkg.Name, 0); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// retentionCandidates 返回可被清理的版本（按创建时间倒序跳过最近keep个版本，排除置顶版本）及被置顶保护的版本
+func (s *PackageService) retentionCandidates(ctx context.Context, packageID uint, keep int) ([]models.PackageVersion, []models.PackageVersion, error) {
+	var versions []models.PackageVersion
+	err := s.db.WithContext(ctx).Where("package_id = ?", packageID).
+		Order("created_at DESC, id DESC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get versions: %w", err)
+	}
+
+	if err := s.markPinned(ctx, versions); err != nil {
+		return nil, nil, err
+	}
+
+	var candidates, pinned []models.PackageVersion
+	for i, v := range versions {
+		if i < keep {
+			continue
+		}
+		if v.Pinned {
+			pinned = append(pinned, v)
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	return candidates, pinned, nil
+}
+
+// markPinned 设置版本列表中各版本的置顶标记
+func (s *PackageService) markPinned(ctx context.Context, versions []models.PackageVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(versions))
+	for i, v := range versions {
+		ids[i] = v.ID
+	}
+
+	var pinnedIDs []uint
+	err := s.db.WithContext(ctx).Model(&models.PackageVersionPin{}).
+		Where("package_version_id IN ?", ids).
+		Pluck("package_version_id", &pinnedIDs).Error
+	if err != nil {
+		return fmt.Errorf("failed to get version pins: %w", err)
+	}
+
+	pinned := make(map[uint]bool, len(pinnedIDs))
+	for _, id := range pinnedIDs {
+		pinned[id] = true
+	}
+	for i := range versions {
+		versions[i].Pinned = pinned[versions[i].ID]
+	}
+	return nil
+}
+
+// findOwnedVersion 查找包版本并校验当前用户有发布权限（所有者或maintainer协作者）
+func (s *PackageService) findOwnedVersion(ctx context.Context, packageName, version string, userID uint) (*models.PackageVersion, error) {
+	var pkgVersion models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").
+		Where("package_id = (SELECT id FROM packages WHERE name = ? AND deleted_at IS NULL) AND version = ?", packageName, version).
+		First(&pkgVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package version not found")
+		}
+		return nil, fmt.Errorf("failed to find package version: %w", err)
+	}
+
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, PackageCaller{UserID: &userID}, PackageRightPublish); err != nil {
+		return nil, err
+	}
+	return &pkgVersion, nil
+}
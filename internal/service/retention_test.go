@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+// createAgedVersions 创建按顺序发布的版本，越靠后越新
+func createAgedVersions(t *testing.T, s *PackageService, pkg *models.Package, versions ...string) {
+	t.Helper()
+	base := time.Now().Add(-time.Duration(len(versions)+1) * 24 * time.Hour)
+	for i, version := range versions {
+		prerelease := strings.Contains(version, "-")
+		createTestVersion(t, s.db, pkg, version, func(v *models.PackageVersion) {
+			v.CreatedAt = base.Add(time.Duration(i) * 24 * time.Hour)
+			v.IsPrerelease = prerelease
+		})
+	}
+}
+
+// remainingVersions 返回包中未删除的版本号
+func remainingVersions(t *testing.T, s *PackageService, pkg *models.Package) string {
+	t.Helper()
+	var versions []string
+	if err := s.db.Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Order("created_at").Pluck("version", &versions).Error; err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(versions, ",")
+}
+
+func newRetentionTestService(t *testing.T) *PackageService {
+	t.Helper()
+	db := newTestDB(t)
+	return NewPackageService(db, testutil.NewStorage(t, nil), nil, config.PackagesConfig{})
+}
+
+func TestPruneKeepsPinnedVersion(t *testing.T) {
+	s := newRetentionTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "pruned", owner, false)
+	createAgedVersions(t, s, pkg, "1.0.0", "1.1.0", "1.2.0", "2.0.0", "2.1.0")
+
+	if _, err := s.PinVersion(context.Background(), "pruned", "1.0.0", owner.ID); err != nil {
+		t.Fatalf("PinVersion: %v", err)
+	}
+
+	result, err := s.PruneOldVersions(context.Background(), "pruned", 2, owner.ID)
+	if err != nil {
+		t.Fatalf("PruneOldVersions: %v", err)
+	}
+	if got := strings.Join(result.Deleted, ","); got != "1.2.0,1.1.0" {
+		t.Errorf("deleted = %s, want 1.2.0,1.1.0", got)
+	}
+	if got := strings.Join(result.SkippedPinned, ","); got != "1.0.0" {
+		t.Errorf("skipped pinned = %s, want 1.0.0", got)
+	}
+	if got := remainingVersions(t, s, pkg); got != "1.0.0,2.0.0,2.1.0" {
+		t.Errorf("remaining = %s, want 1.0.0,2.0.0,2.1.0", got)
+	}
+}
+
+func TestPruneRespectsPackageKeepCount(t *testing.T) {
+	s := newRetentionTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "kept", owner, false)
+	if err := s.db.Model(pkg).Update("keep_recent_versions", 3).Error; err != nil {
+		t.Fatal(err)
+	}
+	createAgedVersions(t, s, pkg, "1.0.0", "1.1.0", "1.2.0", "1.3.0")
+
+	// 请求保留1个版本，但包设置的保留数更大
+	result, err := s.PruneOldVersions(context.Background(), "kept", 1, owner.ID)
+	if err != nil {
+		t.Fatalf("PruneOldVersions: %v", err)
+	}
+	if result.Kept != 3 {
+		t.Errorf("kept = %d, want 3", result.Kept)
+	}
+	if got := remainingVersions(t, s, pkg); got != "1.1.0,1.2.0,1.3.0" {
+		t.Errorf("remaining = %s, want 1.1.0,1.2.0,1.3.0", got)
+	}
+}
+
+func TestPruneRequiresPublishRight(t *testing.T) {
+	s := newRetentionTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	stranger := createTestUser(t, s.db, "bob", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "guarded", owner, false)
+	createAgedVersions(t, s, pkg, "1.0.0", "2.0.0")
+
+	if _, err := s.PruneOldVersions(context.Background(), "guarded", 1, stranger.ID); err == nil {
+		t.Fatal("stranger pruned another user's package")
+	}
+	if _, err := s.PinVersion(context.Background(), "guarded", "1.0.0", stranger.ID); err == nil {
+		t.Error("stranger pinned another user's version")
+	}
+	if got := remainingVersions(t, s, pkg); got != "1.0.0,2.0.0" {
+		t.Errorf("remaining = %s, want both versions", got)
+	}
+}
+
+func TestExpirePrereleasesKeepsPinned(t *testing.T) {
+	s := newRetentionTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "pre", owner, false)
+	createAgedVersions(t, s, pkg, "1.0.0-alpha", "1.0.0-beta", "1.0.0", "2.0.0-rc1")
+
+	if _, err := s.PinVersion(context.Background(), "pre", "1.0.0-alpha", owner.ID); err != nil {
+		t.Fatalf("PinVersion: %v", err)
+	}
+
+	// 所有预发布版本都已超过一小时
+	deleted, err := s.ExpirePrereleases(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("ExpirePrereleases: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d versions, want 2", deleted)
+	}
+	if got := remainingVersions(t, s, pkg); got != "1.0.0-alpha,1.0.0" {
+		t.Errorf("remaining = %s, want 1.0.0-alpha,1.0.0", got)
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semVersion 语义化版本
+type semVersion struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease []string
+}
+
+// parseSemver 解析语义化版本，允许v前缀，忽略构建元数据
+func parseSemver(version string) (semVersion, bool) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+
+	var sv semVersion
+	core := v
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		core = v[:i]
+		pre := v[i+1:]
+		if pre == "" {
+			return semVersion{}, false
+		}
+		sv.Prerelease = strings.Split(pre, ".")
+		for _, id := range sv.Prerelease {
+			if id == "" {
+				return semVersion{}, false
+			}
+		}
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return semVersion{}, false
+	}
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semVersion{}, false
+		}
+		nums[i] = n
+	}
+	sv.Major, sv.Minor, sv.Patch = nums[0], nums[1], nums[2]
+	return sv, true
+}
+
+// compareSemver 按语义化版本优先级比较，返回-1、0或1
+func compareSemver(a, b semVersion) int {
+	if c := compareInt(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+
+	// 没有预发布标识的版本优先级更高
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := comparePrereleaseID(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a.Prerelease), len(b.Prerelease))
+}
+
+// comparePrereleaseID 比较预发布标识：数字按数值比较且低于字母标识
+func comparePrereleaseID(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareInt 比较两个整数
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// looksLikePrerelease 根据版本号内容判断是否为预发布版本（包含-alpha、-beta、-rc）
+func looksLikePrerelease(version string) bool {
+	v := strings.ToLower(version)
+	for _, marker := range []string{"-alpha", "-beta", "-rc"} {
+		if strings.Contains(v, marker) {
+			return true
+		}
+	}
+	return false
+}
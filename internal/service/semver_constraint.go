@@ -0,0 +1,219 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versionConstraint 版本约束，多个比较组之间为"或"关系（||），组内比较条件为"且"关系
+// 支持的写法：*、1.2.3、=1.2.3、>1.2.3、>=1.2.3、<2.0.0、<=1.2.3、^1.2.3、~1.2.3、1.x、1.2.x、1、1.2
+// 及其组合，如 ">=1.2.0 <2.0.0 || ^3.0.0"
+type versionConstraint struct {
+	raw    string
+	groups [][]versionComparator
+}
+
+// versionComparator 单个比较条件
+type versionComparator struct {
+	op      string // =, >, >=, <, <=
+	version semVersion
+}
+
+// parseVersionConstraint 解析版本约束，空字符串和*匹配所有正式版本
+func parseVersionConstraint(raw string) (versionConstraint, error) {
+	c := versionConstraint{raw: raw}
+	for _, part := range strings.Split(raw, "||") {
+		group, err := parseComparatorGroup(strings.TrimSpace(part))
+		if err != nil {
+			return versionConstraint{}, fmt.Errorf("invalid version constraint %q: %w", raw, err)
+		}
+		c.groups = append(c.groups, group)
+	}
+	return c, nil
+}
+
+// parseComparatorGroup 解析空格分隔的一组比较条件
+func parseComparatorGroup(group string) ([]versionComparator, error) {
+	var comparators []versionComparator
+	for _, term := range strings.Fields(group) {
+		parsed, err := parseComparatorTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		comparators = append(comparators, parsed...)
+	}
+	return comparators, nil
+}
+
+// parseComparatorTerm 将单个条件展开为比较条件，^、~和x范围展开为上下界
+func parseComparatorTerm(term string) ([]versionComparator, error) {
+	if term == "*" || term == "x" || term == "X" || term == "latest" {
+		return nil, nil
+	}
+
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, prefix) {
+			op = prefix
+			term = strings.TrimSpace(term[len(prefix):])
+			break
+		}
+	}
+
+	base, parts, err := parsePartialVersion(term)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "^":
+		// 不改变最左侧非零位：^1.2.3 := >=1.2.3 <2.0.0，^0.2.3 := >=0.2.3 <0.3.0，^0.0.3 := >=0.0.3 <0.0.4
+		upper := semVersion{Major: base.Major + 1}
+		switch {
+		case base.Major == 0 && parts >= 2 && base.Minor == 0 && parts == 3:
+			upper = semVersion{Patch: base.Patch + 1}
+		case base.Major == 0 && parts >= 2:
+			upper = semVersion{Minor: base.Minor + 1}
+		}
+		return []versionComparator{{op: ">=", version: base}, {op: "<", version: withLowestPrerelease(upper)}}, nil
+	case "~":
+		// 允许补丁版本变化：~1.2.3 := >=1.2.3 <1.3.0，~1 := >=1.0.0 <2.0.0
+		upper := semVersion{Major: base.Major, Minor: base.Minor + 1}
+		if parts == 1 {
+			upper = semVersion{Major: base.Major + 1}
+		}
+		return []versionComparator{{op: ">=", version: base}, {op: "<", version: withLowestPrerelease(upper)}}, nil
+	case "", "=":
+		if parts == 3 {
+			return []versionComparator{{op: "=", version: base}}, nil
+		}
+		// 部分版本号表示范围：1 := >=1.0.0 <2.0.0，1.2 := >=1.2.0 <1.3.0
+		upper := semVersion{Major: base.Major + 1}
+		if parts == 2 {
+			upper = semVersion{Major: base.Major, Minor: base.Minor + 1}
+		}
+		return []versionComparator{{op: ">=", version: base}, {op: "<", version: withLowestPrerelease(upper)}}, nil
+	}
+	return []versionComparator{{op: op, version: base}}, nil
+}
+
+// parsePartialVersion 解析可能省略次版本号、补丁号或使用x通配的版本号，返回补全为0的版本及给出的位数
+func parsePartialVersion(v string) (semVersion, int, error) {
+	v = strings.TrimPrefix(v, "v")
+	if v == "" {
+		return semVersion{}, 0, fmt.Errorf("missing version")
+	}
+	if sv, ok := parseSemver(v); ok {
+		return sv, 3, nil
+	}
+
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return semVersion{}, 0, fmt.Errorf("invalid version %q", v)
+	}
+	nums := make([]int, 0, 3)
+	for _, field := range fields {
+		if field == "x" || field == "X" || field == "*" {
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return semVersion{}, 0, fmt.Errorf("invalid version %q", v)
+		}
+		nums = append(nums, n)
+	}
+	if len(nums) == 0 {
+		return semVersion{}, 0, fmt.Errorf("invalid version %q", v)
+	}
+
+	var sv semVersion
+	sv.Major = nums[0]
+	if len(nums) > 1 {
+		sv.Minor = nums[1]
+	}
+	if len(nums) > 2 {
+		sv.Patch = nums[2]
+	}
+	return sv, len(nums), nil
+}
+
+// withLowestPrerelease 返回版本的最低预发布版本，作为上界时排除该版本的预发布（如<2.0.0-0）
+func withLowestPrerelease(v semVersion) semVersion {
+	v.Prerelease = []string{"0"}
+	return v
+}
+
+// matches 版本是否满足约束
+// 预发布版本只在约束的某个比较条件使用了相同主次补丁版本号的预发布版本时才匹配（与npm一致）
+func (c versionConstraint) matches(v semVersion) bool {
+	for _, group := range c.groups {
+		if groupMatches(group, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIncludingPrerelease 版本是否满足约束，预发布版本与正式版本一样只按版本号比较
+func (c versionConstraint) matchesIncludingPrerelease(v semVersion) bool {
+	for _, group := range c.groups {
+		matched := true
+		for _, cmp := range group {
+			if !cmp.matches(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMatches 版本是否满足组内所有比较条件
+func groupMatches(group []versionComparator, v semVersion) bool {
+	for _, cmp := range group {
+		if !cmp.matches(v) {
+			return false
+		}
+	}
+	if len(v.Prerelease) == 0 {
+		return true
+	}
+	for _, cmp := range group {
+		cv := cmp.version
+		if len(cv.Prerelease) > 0 && !isLowestPrerelease(cv) &&
+			cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+// isLowestPrerelease 是否为withLowestPrerelease生成的上界
+func isLowestPrerelease(v semVersion) bool {
+	return len(v.Prerelease) == 1 && v.Prerelease[0] == "0"
+}
+
+// matches 版本是否满足比较条件
+func (cmp versionComparator) matches(v semVersion) bool {
+	c := compareSemver(v, cmp.version)
+	switch cmp.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return c == 0
+}
+
+// String 返回原始约束
+func (c versionConstraint) String() string {
+	return c.raw
+}
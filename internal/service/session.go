@@ -0,0 +1,176 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// sessionRetention 会话在JWT过期后保留的时长，之后由清理任务删除
+const sessionRetention = 24 * time.Hour
+
+// SessionService 用户会话服务
+type SessionService struct {
+	db *gorm.DB
+}
+
+// NewSessionService 创建用户会话服务实例
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{db: db}
+}
+
+// CreateSession 为新签发的token创建会话记录
+func (s *SessionService) CreateSession(userID uint, jti string, expiresAt time.Time, userAgent, ipAddress string) (*models.UserSession, error) {
+	return s.CreateScopedSession(userID, jti, expiresAt, userAgent, ipAddress, nil)
+}
+
+// CreateScopedSession 为限定包范围的token创建会话记录，packages为空时与CreateSession相同
+func (s *SessionService) CreateScopedSession(userID uint, jti string, expiresAt time.Time, userAgent, ipAddress string, packages []string) (*models.UserSession, error) {
+	session := &models.UserSession{
+		UserID:            userID,
+		DeviceName:        deviceNameFromUserAgent(userAgent),
+		DeviceFingerprint: deviceFingerprint(userAgent),
+		IPAddress:         ipAddress,
+		JWTJTI:            jti,
+		ExpiresAt:         expiresAt.UTC(),
+		Packages:          packages,
+	}
+	if err := s.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RotateSession token刷新后将会话切换到新token
+func (s *SessionService) RotateSession(oldJTI, newJTI string, expiresAt time.Time) error {
+	result := s.db.Model(&models.UserSession{}).
+		Where("jwt_jti = ? AND revoked_at IS NULL", oldJTI).
+		Updates(map[string]interface{}{
+			"jwt_jti":    newJTI,
+			"expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found or revoked")
+	}
+	return nil
+}
+
+// IsSessionActive 检查token对应的会话是否有效（实现middleware.SessionChecker）
+func (s *SessionService) IsSessionActive(jti string) (uint, bool, error) {
+	var session models.UserSession
+	if err := s.db.Where("jwt_jti = ?", jti).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return session.ID, session.RevokedAt == nil, nil
+}
+
+// ListActiveSessions 获取用户的有效会话列表
+func (s *SessionService) ListActiveSessions(userID uint) ([]*models.UserSession, error) {
+	var sessions []*models.UserSession
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now().UTC()).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession 吊销用户的指定会话
+func (s *SessionService) RevokeSession(userID, sessionID uint) error {
+	result := s.db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeAllSessions 吊销用户的所有会话（退出所有设备），返回吊销数量
+func (s *SessionService) RevokeAllSessions(userID uint) (int64, error) {
+	result := s.db.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now().UTC())
+	return result.RowsAffected, result.Error
+}
+
+// CleanupExpiredSessions 删除JWT过期超过保留时长的会话记录
+func (s *SessionService) CleanupExpiredSessions() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now().UTC().Add(-sessionRetention)).Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
+
+// deviceFingerprint 根据User-Agent计算设备指纹
+func deviceFingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// deviceNameFromUserAgent 从User-Agent中提取可读的设备名称
+func deviceNameFromUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	client := ""
+	switch {
+	case strings.Contains(ua, "edg/"):
+		client = "Edge"
+	case strings.Contains(ua, "chrome/"):
+		client = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		client = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		client = "Safari"
+	case strings.Contains(ua, "curl/"):
+		client = "curl"
+	case strings.Contains(ua, "go-http-client"):
+		client = "Go client"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case client != "" && os != "":
+		return client + " on " + os
+	case client != "":
+		return client
+	case os != "":
+		return os
+	}
+
+	if len(userAgent) > 100 {
+		return userAgent[:100]
+	}
+	return userAgent
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+	"webservice/internal/models"
+)
+
+// statsRefreshTimeout 后台重新计算包统计的超时时间
+const statsRefreshTimeout = 30 * time.Second
+
+// statsComputations 包统计的完整计算次数，mode为sync（无缓存时同步计算）或async（过期后后台重新计算）
+var statsComputations = metrics.NewCounterVec(
+	"package_stats_computations_total",
+	"Total number of package stats aggregate computations.",
+	"mode",
+)
+
+// statsCacheEntry 一种排序依据的包统计快照
+type statsCacheEntry struct {
+	value      *models.PackageStatsResponse
+	staleAt    time.Time // 过期时间，失效后为零值
+	refreshing bool      // 后台重新计算中
+}
+
+// statsCache 包统计的stale-while-revalidate缓存：未过期时直接返回快照；过期后仍返回旧快照，同时在后台重新计算
+// 包和版本的创建、删除会使快照失效，下一次请求触发后台重新计算
+type statsCache struct {
+	ttl time.Duration // 为0时不缓存
+
+	mu         sync.Mutex
+	entries    map[string]*statsCacheEntry // 排序依据 -> 快照
+	generation uint64                      // 每次失效加一
+
+	initial flightGroup[*models.PackageStatsResponse] // 合并还没有快照时的并发计算
+}
+
+// get 返回排序依据对应的包统计，compute执行一次完整计算
+func (c *statsCache) get(ctx context.Context, rankBy string, compute func(ctx context.Context) (*models.PackageStatsResponse, error)) (*models.PackageStatsResponse, error) {
+	if c.ttl <= 0 {
+		statsComputations.Inc("sync")
+		return compute(ctx)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[rankBy]
+	if ok {
+		if !entry.refreshing && !time.Now().Before(entry.staleAt) {
+			entry.refreshing = true
+			go c.refresh(context.WithoutCancel(ctx), rankBy, c.generation, compute)
+		}
+		value := entry.value
+		c.mu.Unlock()
+		return value, nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	return c.initial.do(ctx, "package_stats", rankBy, func(ctx context.Context) (*models.PackageStatsResponse, error) {
+		statsComputations.Inc("sync")
+		value, err := compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(rankBy, value, generation)
+		return value, nil
+	})
+}
+
+// refresh 在后台重新计算快照，失败时保留旧快照，下一次请求再重试
+func (c *statsCache) refresh(ctx context.Context, rankBy string, generation uint64, compute func(ctx context.Context) (*models.PackageStatsResponse, error)) {
+	ctx, cancel := context.WithTimeout(ctx, statsRefreshTimeout)
+	defer cancel()
+
+	statsComputations.Inc("async")
+	value, err := compute(ctx)
+	if err != nil {
+		logger.Warnf("Failed to refresh package stats (rank_by=%s): %v", rankBy, err)
+		c.mu.Lock()
+		if entry, ok := c.entries[rankBy]; ok {
+			entry.refreshing = false
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.store(rankBy, value, generation)
+}
+
+// store 保存快照，计算开始后发生过失效时快照立即视为过期
+func (c *statsCache) store(rankBy string, value *models.PackageStatsResponse, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*statsCacheEntry)
+	}
+	staleAt := value.ComputedAt.Add(c.ttl)
+	if generation != c.generation {
+		staleAt = time.Time{}
+	}
+	c.entries[rankBy] = &statsCacheEntry{value: value, staleAt: staleAt}
+}
+
+// invalidate 使所有快照过期，下一次请求仍返回旧快照并触发后台重新计算
+func (c *statsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, entry := range c.entries {
+		entry.staleAt = time.Time{}
+	}
+}
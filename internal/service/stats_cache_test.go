@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// countQueries 统计所有查询（包括Count和Raw扫描）的次数
+func countQueries(t *testing.T, db *gorm.DB) *int64 {
+	t.Helper()
+	var count int64
+	inc := func(*gorm.DB) { atomic.AddInt64(&count, 1) }
+	if err := db.Callback().Query().Before("gorm:query").Register("test:count_queries", inc); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("test:count_rows", inc); err != nil {
+		t.Fatal(err)
+	}
+	return &count
+}
+
+// waitForQueries 等待后台计算发出查询并结束，返回期间的查询次数
+func waitForQueries(t *testing.T, queries *int64, s *PackageService, rankBy string) int64 {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.stats.mu.Lock()
+		entry := s.stats.entries[rankBy]
+		done := entry != nil && !entry.refreshing && !entry.staleAt.IsZero()
+		s.stats.mu.Unlock()
+		if done {
+			return atomic.LoadInt64(queries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("background stats refresh did not finish")
+	return 0
+}
+
+func TestPackageStatsCachedHitIssuesNoQueries(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "app", owner, false)
+	s := NewPackageService(db, nil, nil, config.PackagesConfig{StatsCacheTTL: time.Minute})
+	queries := countQueries(t, db)
+
+	first, err := s.GetPackageStats(context.Background(), "downloads")
+	if err != nil {
+		t.Fatalf("GetPackageStats: %v", err)
+	}
+	if atomic.LoadInt64(queries) == 0 {
+		t.Fatal("first request issued no queries, want a full computation")
+	}
+
+	atomic.StoreInt64(queries, 0)
+	for i := 0; i < 10; i++ {
+		stats, err := s.GetPackageStats(context.Background(), "downloads")
+		if err != nil {
+			t.Fatalf("GetPackageStats: %v", err)
+		}
+		if stats != first {
+			t.Fatal("cached hit returned a different snapshot")
+		}
+	}
+	if got := atomic.LoadInt64(queries); got != 0 {
+		t.Errorf("cached hits issued %d queries, want 0", got)
+	}
+
+	// 每种排序依据有各自的快照
+	if _, err := s.GetPackageStats(context.Background(), "installs"); err != nil {
+		t.Fatalf("GetPackageStats: %v", err)
+	}
+	if atomic.LoadInt64(queries) == 0 {
+		t.Error("first installs request issued no queries, want a separate computation")
+	}
+}
+
+func TestPackageStatsServesStaleWhileRevalidating(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	createTestPackage(t, db, "app", owner, false)
+	s := NewPackageService(db, nil, nil, config.PackagesConfig{StatsCacheTTL: time.Minute})
+	queries := countQueries(t, db)
+
+	first, err := s.GetPackageStats(context.Background(), "downloads")
+	if err != nil {
+		t.Fatalf("GetPackageStats: %v", err)
+	}
+	if first.TotalPackages != 1 {
+		t.Fatalf("total_packages = %d, want 1", first.TotalPackages)
+	}
+
+	// 创建包使快照失效：下一次请求立即返回旧快照，并在后台重新计算
+	if _, err := s.CreatePackage(context.Background(), &models.CreatePackageRequest{Name: "tool"}, owner.ID); err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+	atomic.StoreInt64(queries, 0)
+	stale, err := s.GetPackageStats(context.Background(), "downloads")
+	if err != nil {
+		t.Fatalf("GetPackageStats: %v", err)
+	}
+	if stale != first {
+		t.Errorf("stale request returned total_packages = %d, want the old snapshot", stale.TotalPackages)
+	}
+	if got := waitForQueries(t, queries, s, "downloads"); got == 0 {
+		t.Error("stale request did not trigger a background computation")
+	}
+
+	atomic.StoreInt64(queries, 0)
+	fresh, err := s.GetPackageStats(context.Background(), "downloads")
+	if err != nil {
+		t.Fatalf("GetPackageStats: %v", err)
+	}
+	if fresh.TotalPackages != 2 || fresh.ComputedAt.Before(first.ComputedAt) {
+		t.Errorf("refreshed stats = %d packages computed at %v, want 2 after %v", fresh.TotalPackages, fresh.ComputedAt, first.ComputedAt)
+	}
+	if got := atomic.LoadInt64(queries); got != 0 {
+		t.Errorf("request after refresh issued %d queries, want 0", got)
+	}
+}
+
+func TestPackageStatsCacheDisabled(t *testing.T) {
+	db := newTestDB(t)
+	s := newTestPackageService(t, db)
+	queries := countQueries(t, db)
+
+	for i := 0; i < 2; i++ {
+		atomic.StoreInt64(queries, 0)
+		if _, err := s.GetPackageStats(context.Background(), "downloads"); err != nil {
+			t.Fatalf("GetPackageStats: %v", err)
+		}
+		if atomic.LoadInt64(queries) == 0 {
+			t.Errorf("request %d issued no queries with stats_cache_ttl=0", i+1)
+		}
+	}
+}
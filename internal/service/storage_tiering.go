@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/metrics"
+	"webservice/internal/minio"
+	"webservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// 存储分层阈值
+const (
+	tierVelocityWindow  = 7 * 24 * time.Hour  // 计算下载速度的窗口
+	tierColdWindow      = 30 * 24 * time.Hour // 判定冷数据的观察期
+	tierPromoteVelocity = 50.0                // 日均下载超过该值时提升到STANDARD
+	tierDemoteVelocity  = 1.0                 // 观察期内日均下载低于该值时降级到STANDARD_IA
+)
+
+// 层级变更方向
+const (
+	TierDirectionPromote = "promote"
+	TierDirectionDemote  = "demote"
+)
+
+// storageTierTransitions 存储层级变更次数，按方向区分
+var storageTierTransitions = metrics.NewCounterVec(
+	"storage_tier_transitions_total",
+	"Total number of package version storage tier transitions.",
+	"direction",
+)
+
+// StorageTieringService 存储分层服务，根据下载频率在存储层级之间迁移包文件
+type StorageTieringService struct {
+	db          *gorm.DB
+	minioClient *minio.Client
+}
+
+// NewStorageTieringService 创建存储分层服务实例
+func NewStorageTieringService(db *gorm.DB, minioClient *minio.Client) *StorageTieringService {
+	return &StorageTieringService{
+		db:          db,
+		minioClient: minioClient,
+	}
+}
+
+// versionVelocity 包版本的下载速度统计
+type versionVelocity struct {
+	ID           uint
+	PackageName  string
+	Version      string
+	MinIOPath    string
+	StorageClass string
+	CreatedAt    time.Time
+	Downloads7d  int64
+	Downloads30d int64
+}
+
+// TieringResult 一次分层执行的结果
+type TieringResult struct {
+	Promoted int `json:"promoted"`
+	Demoted  int `json:"demoted"`
+	Failed   int `json:"failed"`
+}
+
+// ApplyTiering 计算主bucket中所有版本的下载速度并执行提升/降级，冷存储中的版本由ApplyColdTiering处理
+func (s *StorageTieringService) ApplyTiering(ctx context.Context) (*TieringResult, error) {
+	if s.minioClient == nil {
+		return nil, errors.New("file storage is not available")
+	}
+
+	now := time.Now().UTC()
+	var velocities []versionVelocity
+	err := s.db.WithContext(ctx).Table("package_versions AS pv").
+		Select(`pv.id, p.name AS package_name, pv.version, pv.min_io_path, pv.storage_class, pv.created_at,
+			COUNT(CASE WHEN pd.download_time >= ? THEN 1 END) AS downloads7d,
+			COUNT(pd.id) AS downloads30d`, now.Add(-tierVelocityWindow)).
+		Joins("JOIN packages p ON p.id = pv.package_id AND p.deleted_at IS NULL").
+		Joins("LEFT JOIN package_downloads pd ON pd.package_version_id = pv.id AND pd.download_time >= ?", now.Add(-tierColdWindow)).
+		Where("pv.deleted_at IS NULL AND pv.storage_tier = ?", models.StorageTierHot).
+		Group("pv.id, p.name, pv.version, pv.min_io_path, pv.storage_class, pv.created_at").
+		Scan(&velocities).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute download velocity: %w", err)
+	}
+
+	result := &TieringResult{}
+	for _, v := range velocities {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		currentTier := v.StorageClass
+		if currentTier == "" {
+			currentTier = minio.StorageClassStandard
+		}
+
+		velocity7d := float64(v.Downloads7d) / 7
+		velocity30d := float64(v.Downloads30d) / 30
+
+		var targetTier, direction string
+		var velocity float64
+		switch {
+		case currentTier == minio.StorageClassStandardIA && velocity7d > tierPromoteVelocity:
+			targetTier, direction, velocity = minio.StorageClassStandard, TierDirectionPromote, velocity7d
+		case currentTier == minio.StorageClassStandard && now.Sub(v.CreatedAt) >= tierColdWindow && velocity30d < tierDemoteVelocity:
+			targetTier, direction, velocity = minio.StorageClassStandardIA, TierDirectionDemote, velocity30d
+		default:
+			continue
+		}
+
+		if err := s.transition(ctx, v, currentTier, targetTier, direction, velocity); err != nil {
+			logger.Warnf("Failed to %s %s@%s to %s: %v", direction, v.PackageName, v.Version, targetTier, err)
+			result.Failed++
+			continue
+		}
+
+		if direction == TierDirectionPromote {
+			result.Promoted++
+		} else {
+			result.Demoted++
+		}
+	}
+
+	return result, nil
+}
+
+// transition 迁移单个版本的存储层级并记录变更
+func (s *StorageTieringService) transition(ctx context.Context, v versionVelocity, fromTier, toTier, direction string, velocity float64) error {
+	if err := s.minioClient.SetObjectStorageClass(ctx, v.MinIOPath, toTier); err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PackageVersion{}).Where("id = ?", v.ID).
+			Update("storage_class", toTier).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.StorageTierChange{
+			PackageVersionID: v.ID,
+			FromTier:         fromTier,
+			ToTier:           toTier,
+			Direction:        direction,
+			DownloadsPerDay:  velocity,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record tier change: %w", err)
+	}
+
+	storageTierTransitions.Inc(direction)
+	logger.Infof("Storage tier %s: %s@%s %s -> %s (%.2f downloads/day)",
+		direction, v.PackageName, v.Version, fromTier, toTier, velocity)
+	return nil
+}
+
+// GetTierSummary 获取各存储层级的版本数量和容量分布
+func (s *StorageTieringService) GetTierSummary(ctx context.Context) ([]models.StorageTierSummary, error) {
+	var rows []models.StorageTierSummary
+	err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Select("storage_class, COUNT(*) AS versions, COALESCE(SUM(stored_size), 0) AS total_size").
+		Group("storage_class").
+		Order("storage_class").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tier summary: %w", err)
+	}
+
+	// 未设置存储类型的旧数据归入STANDARD
+	summary := make([]models.StorageTierSummary, 0, len(rows))
+	index := make(map[string]int)
+	for _, row := range rows {
+		if row.StorageClass == "" {
+			row.StorageClass = minio.StorageClassStandard
+		}
+		if i, ok := index[row.StorageClass]; ok {
+			summary[i].Versions += row.Versions
+			summary[i].TotalSize += row.TotalSize
+			continue
+		}
+		index[row.StorageClass] = len(summary)
+		summary = append(summary, row)
+	}
+	return summary, nil
+}
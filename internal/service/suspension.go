@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUserSuspended 用户已被暂停，登录时返回*UserSuspendedError
+	ErrUserSuspended = errors.New("user account is suspended")
+	// ErrUserNotSuspended 解除暂停时用户不处于暂停状态
+	ErrUserNotSuspended = errors.New("user is not suspended")
+	// ErrCannotSuspendSelf 不能暂停自己的账户
+	ErrCannotSuspendSelf = errors.New("cannot suspend your own account")
+)
+
+// UserSuspendedError 用户处于暂停状态，附带原因和到期时间
+type UserSuspendedError struct {
+	Reason    string
+	ExpiresAt *time.Time // 为空表示无限期
+}
+
+// Error 实现error接口
+func (e *UserSuspendedError) Error() string {
+	if e.Reason == "" {
+		return ErrUserSuspended.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrUserSuspended.Error(), e.Reason)
+}
+
+// Unwrap 返回ErrUserSuspended
+func (e *UserSuspendedError) Unwrap() error {
+	return ErrUserSuspended
+}
+
+// SuspendUser 暂停用户（管理员）：将用户状态设为暂停、记录原因并吊销其所有会话
+// duration不为nil时到期后由后台任务自动解除；用户已处于暂停状态时返回ErrUserSuspended
+func (s *UserService) SuspendUser(ctx context.Context, targetID, actorID uint, reason string, duration *time.Duration) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.SuspendUser")
+	defer span.Finish()
+
+	if targetID == actorID {
+		return ErrCannotSuspendSelf
+	}
+
+	now := time.Now().UTC()
+	suspension := &models.UserSuspension{UserID: targetID, SuspendedByUserID: actorID, Reason: reason}
+	if duration != nil {
+		expiresAt := now.Add(*duration)
+		suspension.ExpiresAt = &expiresAt
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user not found")
+			}
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		if user.Status == models.UserStatusSuspended {
+			return ErrUserSuspended
+		}
+
+		if err := tx.Create(suspension).Error; err != nil {
+			return fmt.Errorf("failed to create suspension: %w", err)
+		}
+		if err := tx.Model(&user).Update("status", models.UserStatusSuspended).Error; err != nil {
+			return fmt.Errorf("failed to update user status: %w", err)
+		}
+		// 已签发的token立即失效
+		if err := tx.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", targetID).Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+}
+
+// LiftSuspension 手动解除用户的暂停（管理员），用户状态恢复为正常
+func (s *UserService) LiftSuspension(ctx context.Context, targetID, actorID uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.LiftSuspension")
+	defer span.Finish()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user not found")
+			}
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+		if user.Status != models.UserStatusSuspended {
+			return ErrUserNotSuspended
+		}
+		return liftSuspension(tx, targetID, &actorID)
+	})
+}
+
+// LiftExpiredSuspensions 解除所有已到期的暂停，返回解除的用户数
+func (s *UserService) LiftExpiredSuspensions(ctx context.Context) (int, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.LiftExpiredSuspensions")
+	defer span.Finish()
+
+	var userIDs []uint
+	err := s.db.WithContext(ctx).Model(&models.UserSuspension{}).
+		Where("lifted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", time.Now().UTC()).
+		Distinct().Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired suspensions: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return liftSuspension(tx, userID, nil)
+		}); err != nil {
+			return 0, fmt.Errorf("failed to lift suspension of user %d: %w", userID, err)
+		}
+	}
+	return len(userIDs), nil
+}
+
+// GetSuspensions 返回用户的暂停记录，最近的在前
+func (s *UserService) GetSuspensions(ctx context.Context, userID uint) ([]models.UserSuspension, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.GetSuspensions")
+	defer span.Finish()
+
+	suspensions := []models.UserSuspension{}
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&suspensions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list suspensions: %w", err)
+	}
+	return suspensions, nil
+}
+
+// checkSuspension 检查暂停状态的用户能否登录：暂停已到期但后台任务尚未解除时立即解除，否则返回*UserSuspendedError
+func (s *UserService) checkSuspension(ctx context.Context, user *models.User) error {
+	suspension, err := s.activeSuspension(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check suspension: %w", err)
+	}
+	// 直接修改状态暂停的用户没有暂停记录，只能手动解除
+	if suspension == nil {
+		return &UserSuspendedError{}
+	}
+	if suspension.IsActive(time.Now().UTC()) {
+		return &UserSuspendedError{Reason: suspension.Reason, ExpiresAt: suspension.ExpiresAt}
+	}
+
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return liftSuspension(tx, user.ID, nil)
+	}); err != nil {
+		return err
+	}
+	user.Status = models.UserStatusActive
+	return nil
+}
+
+// activeSuspension 返回用户最近一条未解除的暂停记录（可能已到期），没有时返回nil
+func (s *UserService) activeSuspension(ctx context.Context, userID uint) (*models.UserSuspension, error) {
+	var suspension models.UserSuspension
+	err := s.db.WithContext(ctx).Where("user_id = ? AND lifted_at IS NULL", userID).Order("id DESC").First(&suspension).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &suspension, nil
+}
+
+// liftSuspension 在事务内解除用户所有未解除的暂停记录，仍处于暂停状态的用户恢复为正常
+// liftedBy为nil表示到期自动解除
+func liftSuspension(tx *gorm.DB, userID uint, liftedBy *uint) error {
+	now := time.Now().UTC()
+	if err := tx.Model(&models.UserSuspension{}).
+		Where("user_id = ? AND lifted_at IS NULL", userID).
+		Updates(map[string]interface{}{"lifted_at": now, "lifted_by_user_id": liftedBy}).Error; err != nil {
+		return fmt.Errorf("failed to lift suspension: %w", err)
+	}
+	if err := tx.Model(&models.User{}).
+		Where("id = ? AND status = ?", userID, models.UserStatusSuspended).
+		Update("status", models.UserStatusActive).Error; err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+	return nil
+}
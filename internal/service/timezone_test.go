@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+// nonUTCZones 部署时区远离UTC的两个方向
+var nonUTCZones = []*time.Location{
+	time.FixedZone("UTC+8", 8*60*60),
+	time.FixedZone("UTC-7", -7*60*60),
+	time.FixedZone("UTC+14", 14*60*60),
+}
+
+func TestPackageStatsWindowIgnoresLocalTimezone(t *testing.T) {
+	for _, loc := range nonUTCZones {
+		t.Run(loc.String(), func(t *testing.T) {
+			testutil.SetLocalTimezone(t, loc)
+			db := newTestDB(t)
+			owner := createTestUser(t, db, "alice", models.RoleUser)
+			pkg := createTestPackage(t, db, "timed", owner, false)
+			version := createTestVersion(t, db, pkg, "1.0.0", nil)
+
+			// 30天窗口边界两侧各一条记录，另一条由GORM自动填充下载时间
+			now := time.Now().UTC()
+			for _, at := range []time.Time{now.AddDate(0, 0, -30).Add(2 * time.Hour), now.AddDate(0, 0, -30).Add(-2 * time.Hour)} {
+				if err := db.Create(&models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1", DownloadTime: at}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			recent := &models.PackageDownload{PackageVersionID: version.ID, IPAddress: "192.0.2.1"}
+			if err := db.Create(recent).Error; err != nil {
+				t.Fatal(err)
+			}
+			if recent.DownloadTime.Location() != time.UTC {
+				t.Errorf("auto download_time location = %v, want UTC", recent.DownloadTime.Location())
+			}
+
+			stats, err := newTestPackageService(t, db).computePackageStats(context.Background(), "")
+			if err != nil {
+				t.Fatalf("computePackageStats() error = %v", err)
+			}
+			if stats.RecentDownloads != 2 {
+				t.Errorf("recent downloads = %d, want 2 regardless of the local timezone", stats.RecentDownloads)
+			}
+			if stats.ComputedAt.Location() != time.UTC {
+				t.Errorf("computed_at location = %v, want UTC", stats.ComputedAt.Location())
+			}
+
+			// 读回的时间戳与写入的是同一时刻
+			var stored models.PackageDownload
+			if err := db.First(&stored, recent.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !stored.DownloadTime.Equal(recent.DownloadTime) {
+				t.Errorf("stored download_time = %v, want %v", stored.DownloadTime, recent.DownloadTime)
+			}
+			if _, offset := stored.DownloadTime.Zone(); offset != 0 {
+				t.Errorf("stored download_time offset = %d, want UTC", offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+)
+
+// ErrNoPublishRights 用户不能向包发布版本（不是包所有者或maintainer协作者）
+var ErrNoPublishRights = errors.New("no publish rights on package")
+
+// CheckPublishRights 检查用户能否向每个包发布版本，用于创建限定包范围的token
+// 包不存在时返回"package not found"错误，没有publish权限时返回包装了ErrNoPublishRights的错误
+// 已归档的包仍可加入token范围，上传时再拒绝
+func (s *PackageService) CheckPublishRights(ctx context.Context, userID uint, packageNames []string) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.CheckPublishRights")
+	defer span.Finish()
+
+	var pkgs []models.Package
+	if err := s.db.WithContext(ctx).Select("id", "name", "owner_id", "is_private", "is_archived").Where("name IN ?", packageNames).Find(&pkgs).Error; err != nil {
+		return fmt.Errorf("failed to find packages: %w", err)
+	}
+	byName := make(map[string]*models.Package, len(pkgs))
+	for i := range pkgs {
+		byName[pkgs[i].Name] = &pkgs[i]
+	}
+
+	for _, name := range packageNames {
+		pkg, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("package not found: %s", name)
+		}
+		err := authorizePackage(s.db.WithContext(ctx), pkg, PackageCaller{UserID: &userID}, PackageRightPublish)
+		if err != nil && !errors.Is(err, ErrPackageArchived) {
+			if strings.Contains(err.Error(), "permission denied") {
+				return fmt.Errorf("%w: %s", ErrNoPublishRights, name)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// SetPackageTrustLevel 设置包自身的信任等级（超级管理员）
+// 返回的变更记录为包自身等级的变化，响应中的有效等级仍会继承所有者的等级
+func (s *PackageService) SetPackageTrustLevel(ctx context.Context, packageName, level string) (*models.Package, *models.TrustLevelChange, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.SetPackageTrustLevel")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("package not found")
+		}
+		return nil, nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	change := &models.TrustLevelChange{From: pkg.OwnTrustLevel, To: level}
+	if err := s.db.WithContext(ctx).Model(&pkg).Update("trust_level", level).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update trust level: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Preload("Owner").First(&pkg, pkg.ID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to reload package: %w", err)
+	}
+
+	return &pkg, change, nil
+}
+
+// SetUserTrustLevel 设置用户的信任等级（超级管理员），该用户的所有包至少继承此等级
+func (s *UserService) SetUserTrustLevel(ctx context.Context, id uint, level string) (*models.User, *models.TrustLevelChange, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.SetUserTrustLevel")
+	defer span.Finish()
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("user not found")
+		}
+		return nil, nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	change := &models.TrustLevelChange{From: models.MaxTrustLevel(user.TrustLevel, ""), To: level}
+	if err := s.db.WithContext(ctx).Model(&user).Update("trust_level", level).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update trust level: %w", err)
+	}
+
+	return &user, change, nil
+}
+
+// applyTrustLevelFilter 按有效信任等级过滤包
+// 有效等级为包自身与所有者等级中较高者，因此两者都不能高于目标等级，且至少一个等于目标等级
+func applyTrustLevelFilter(query *gorm.DB, level string) *gorm.DB {
+	allowed := models.TrustLevelsUpTo(level)
+	return query.
+		Where("trust_level IN ?", allowed).
+		Where("owner_id IN (SELECT id FROM users WHERE trust_level IN ?)", allowed).
+		Where("trust_level = ? OR owner_id IN (SELECT id FROM users WHERE trust_level = ?)", level, level)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"webservice/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// 上传锁模式
+const (
+	// UploadLockMemory 进程内互斥锁，只对单实例部署有效（默认）
+	UploadLockMemory = "memory"
+	// UploadLockDatabase 数据库咨询锁（MySQL GET_LOCK / PostgreSQL pg_try_advisory_lock），多实例部署共享
+	UploadLockDatabase = "database"
+	// UploadLockNone 不加锁，并发上传同一版本时由唯一索引兜底
+	UploadLockNone = "none"
+)
+
+// defaultUploadLockTimeout 未配置时等待上传锁的最长时间
+const defaultUploadLockTimeout = 5 * time.Minute
+
+// advisoryLockPollInterval PostgreSQL轮询咨询锁的间隔
+const advisoryLockPollInterval = 200 * time.Millisecond
+
+// ErrUploadInProgress 同一版本的另一个上传在等待时间内未完成
+var ErrUploadInProgress = errors.New("another upload of this version is in progress")
+
+// uploadLocker 按包版本串行化上传，后到的请求等待先到的请求完成后再检查版本是否已存在，
+// 避免两个请求都通过存在性检查后各自上传文件，其中一个在写入记录时才因唯一索引失败
+type uploadLocker interface {
+	// acquire 获取key对应的锁，ctx结束前未获取到时返回ErrUploadInProgress
+	acquire(ctx context.Context, key string) (release func(), err error)
+}
+
+// newUploadLocker 按配置创建上传锁，数据库方言不支持咨询锁时退回进程内锁
+func newUploadLocker(db *gorm.DB, mode string) uploadLocker {
+	switch mode {
+	case UploadLockNone:
+		return noopUploadLocker{}
+	case UploadLockDatabase:
+		if dialect := db.Dialector.Name(); dialect == "mysql" || dialect == "postgres" {
+			return &dbUploadLocker{db: db, dialect: dialect}
+		}
+		logger.Warnf("Database upload lock is not supported by %s, falling back to in-memory lock", db.Dialector.Name())
+	}
+	return newMemoryUploadLocker()
+}
+
+// noopUploadLocker 不加锁
+type noopUploadLocker struct{}
+
+// acquire 直接返回
+func (noopUploadLocker) acquire(context.Context, string) (func(), error) {
+	return func() {}, nil
+}
+
+// memoryUploadLocker 进程内的按key互斥锁，没有请求持有或等待的key会被移除
+type memoryUploadLocker struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock 单个key的锁，容量为1的channel便于在等待时响应ctx取消
+type keyLock struct {
+	ch   chan struct{}
+	refs int // 持有和等待该锁的请求数
+}
+
+// newMemoryUploadLocker 创建进程内上传锁
+func newMemoryUploadLocker() *memoryUploadLocker {
+	return &memoryUploadLocker{locks: make(map[string]*keyLock)}
+}
+
+// acquire 获取key对应的互斥锁
+func (l *memoryUploadLocker) acquire(ctx context.Context, key string) (func(), error) {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &keyLock{ch: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	select {
+	case lock.ch <- struct{}{}:
+		return func() {
+			<-lock.ch
+			l.unref(key, lock)
+		}, nil
+	case <-ctx.Done():
+		l.unref(key, lock)
+		return nil, ErrUploadInProgress
+	}
+}
+
+// unref 释放对key的引用，最后一个引用释放时移除该key
+func (l *memoryUploadLocker) unref(key string, lock *keyLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
+
+// dbUploadLocker 数据库咨询锁，锁属于数据库会话，因此持有期间独占一个连接
+type dbUploadLocker struct {
+	db      *gorm.DB
+	dialect string
+}
+
+// acquire 在独占的连接上获取咨询锁，释放时归还连接
+func (l *dbUploadLocker) acquire(ctx context.Context, key string) (func(), error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrUploadInProgress
+		}
+		return nil, fmt.Errorf("failed to get connection for upload lock: %w", err)
+	}
+
+	// MySQL锁名最长64个字符，统一使用key的哈希
+	name := fmt.Sprintf("upload:%x", sha1.Sum([]byte(key)))
+
+	var locked bool
+	switch l.dialect {
+	case "mysql":
+		locked, err = l.getLockMySQL(ctx, conn, name)
+	default:
+		locked, err = l.getLockPostgres(ctx, conn, name)
+	}
+	if err != nil || !locked {
+		conn.Close()
+		if err != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("failed to acquire upload lock: %w", err)
+		}
+		return nil, ErrUploadInProgress
+	}
+
+	return func() {
+		// 请求的ctx可能已结束，释放锁使用独立的ctx
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		query := "SELECT pg_advisory_unlock(hashtext($1))"
+		if l.dialect == "mysql" {
+			query = "SELECT RELEASE_LOCK(?)"
+		}
+		if _, err := conn.ExecContext(releaseCtx, query, name); err != nil {
+			logger.Warnf("Failed to release upload lock %s: %v", name, err)
+		}
+		// 关闭连接时数据库也会释放会话持有的锁
+		conn.Close()
+	}, nil
+}
+
+// getLockMySQL 使用GET_LOCK等待到ctx的截止时间
+func (l *dbUploadLocker) getLockMySQL(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	timeout := defaultUploadLockTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	seconds := int(timeout.Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, seconds).Scan(&got); err != nil {
+		return false, err
+	}
+	return got.Valid && got.Int64 == 1, nil
+}
+
+// getLockPostgres pg_advisory_lock无法设置等待时间，轮询pg_try_advisory_lock直到ctx结束
+func (l *dbUploadLocker) getLockPostgres(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	ticker := time.NewTicker(advisoryLockPollInterval)
+	defer ticker.Stop()
+	for {
+		var got bool
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", name).Scan(&got); err != nil {
+			return false, err
+		}
+		if got {
+			return true, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false, nil
+		}
+	}
+}
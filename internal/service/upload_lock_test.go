@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+// countingReader 记录上传内容是否被读取，读取时稍作停顿让并发请求在锁上相遇
+type countingReader struct {
+	r     *strings.Reader
+	reads *int32
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	if atomic.AddInt32(c.reads, 1) == 1 {
+		time.Sleep(50 * time.Millisecond)
+	}
+	return c.r.Read(p)
+}
+
+func TestConcurrentUploadOfSameVersion(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "race-pkg", owner, false)
+
+	const uploads = 2
+	var readsPerUpload [uploads]int32
+	errs := runConcurrently(uploads, func(i int) error {
+		content := "content of race-pkg@1.0.0"
+		reader := countingReader{r: strings.NewReader(content), reads: &readsPerUpload[i]}
+		_, err := s.UploadPackageVersion(context.Background(), pkg.Name, &models.CreatePackageVersionRequest{Version: "1.0.0"},
+			reader, int64(len(content)), owner.ID)
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrVersionExists)
+
+	// 失败的请求在上传文件前就发现版本已存在
+	for i, err := range errs {
+		if err != nil && readsPerUpload[i] != 0 {
+			t.Errorf("rejected upload %d read its file %d times before failing", i, readsPerUpload[i])
+		}
+	}
+
+	var count int64
+	if err := s.db.Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d versions stored, want 1", count)
+	}
+}
+
+func TestMemoryUploadLockerTimesOut(t *testing.T) {
+	l := newMemoryUploadLocker()
+	release, err := l.acquire(context.Background(), "1@1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "1@1.0.0"); !errors.Is(err, ErrUploadInProgress) {
+		t.Fatalf("second acquire = %v, want ErrUploadInProgress", err)
+	}
+
+	// 其他版本不受影响
+	other, err := l.acquire(context.Background(), "1@2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other()
+
+	release()
+	again, err := l.acquire(context.Background(), "1@1.0.0")
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	again()
+
+	if len(l.locks) != 0 {
+		t.Errorf("%d keys left in the lock map after release", len(l.locks))
+	}
+}
+
+func TestConcurrentPublishWithoutUploadLock(t *testing.T) {
+	// 关闭上传锁后只靠写入事务内的锁和重新检查防止重复版本
+	s := NewPackageService(newTestDB(t), testutil.NewStorage(t, nil), nil, config.PackagesConfig{UploadLock: "none"})
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "race-pkg", owner, false)
+
+	errs := runConcurrently(50, func(int) error {
+		_, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+		return err
+	})
+	assertOneSucceeded(t, errs, ErrVersionExists)
+
+	var count int64
+	if err := s.db.Model(&models.PackageVersion{}).Where("package_id = ?", pkg.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("%d versions stored, want 1", count)
+	}
+}
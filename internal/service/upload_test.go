@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+)
+
+// newUploadTestService 创建连接内存存储的包服务
+func newUploadTestService(t *testing.T) *PackageService {
+	t.Helper()
+	return NewPackageService(newTestDB(t), testutil.NewStorage(t, nil), nil, config.PackagesConfig{})
+}
+
+// uploadTestVersion 通过上传接口发布版本
+func uploadTestVersion(s *PackageService, pkg *models.Package, version string, uploaderID uint) (*models.PackageVersion, error) {
+	content := "content of " + pkg.Name + "@" + version
+	return s.UploadPackageVersion(context.Background(), pkg.Name, &models.CreatePackageVersionRequest{Version: version},
+		strings.NewReader(content), int64(len(content)), uploaderID)
+}
+
+func TestUploadSameVersionUnderTwoPackages(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	a := createTestPackage(t, s.db, "a", owner, false)
+	b := createTestPackage(t, s.db, "b", owner, false)
+
+	if _, err := uploadTestVersion(s, a, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("upload a@1.0.0: %v", err)
+	}
+	if _, err := uploadTestVersion(s, b, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("upload b@1.0.0: %v", err)
+	}
+	if _, err := uploadTestVersion(s, a, "1.0.0", owner.ID); err != ErrVersionExists {
+		t.Fatalf("second upload a@1.0.0 = %v, want ErrVersionExists", err)
+	}
+}
+
+func TestReuploadDeletedVersion(t *testing.T) {
+	s := newUploadTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "reused", owner, false)
+
+	if _, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if err := s.DeletePackageVersion(context.Background(), pkg.Name, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("DeletePackageVersion: %v", err)
+	}
+	if _, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("re-upload of a deleted version: %v", err)
+	}
+}
+
+func TestReuploadDeletedVersionPurgesVersionRows(t *testing.T) {
+	s := newUploadTestService(t)
+	// 只用一个连接，使外键检查对事务内的语句同样生效
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := s.db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		t.Fatal(err)
+	}
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "reused", owner, false)
+
+	old, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if err := s.DeletePackageVersion(context.Background(), pkg.Name, "1.0.0", owner.ID); err != nil {
+		t.Fatalf("DeletePackageVersion: %v", err)
+	}
+	rows := []interface{}{
+		&models.PackageDownload{PackageVersionID: old.ID, IPAddress: "192.0.2.1"},
+		&models.PackageVersionPin{PackageVersionID: old.ID, PinnedBy: owner.ID},
+		&models.StorageTierChange{PackageVersionID: old.ID, FromTier: "hot", ToTier: "cold", Direction: "demote"},
+		&models.VersionScanResult{PackageVersionID: old.ID, Engine: "fake", Verdict: models.ScanVerdictClean, ScannedAt: time.Now()},
+		&models.UploadSession{ID: "up-reused", PackageID: pkg.ID, PackageName: pkg.Name, Version: "1.0.0", UploaderID: owner.ID,
+			ObjectKey: "staging/up-reused", Status: models.UploadSessionCompleted, VersionID: &old.ID, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	for _, row := range rows {
+		if err := s.db.Create(row).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", row, err)
+		}
+	}
+
+	republished, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatalf("re-upload of a deleted version with dependent rows: %v", err)
+	}
+	if republished.ID == old.ID {
+		t.Fatal("re-upload reused the deleted version row")
+	}
+
+	// 旧版本的记录全部清除，新版本不继承下载数、置顶和扫描结果
+	for _, model := range []interface{}{&models.PackageDownload{}, &models.PackageVersionPin{}, &models.StorageTierChange{}, &models.VersionScanResult{}} {
+		var n int64
+		if err := s.db.Model(model).Where("package_version_id IN ?", []uint{old.ID, republished.ID}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%T: %d rows still reference the purged version or moved to the new one", model, n)
+		}
+	}
+	var session models.UploadSession
+	if err := s.db.First(&session, "id = ?", "up-reused").Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.VersionID != nil {
+		t.Errorf("upload session still points at version %d", *session.VersionID)
+	}
+	var purged int64
+	if err := s.db.Unscoped().Model(&models.PackageVersion{}).Where("id = ?", old.ID).Count(&purged).Error; err != nil {
+		t.Fatal(err)
+	}
+	if purged != 0 {
+		t.Error("deleted version row was not purged")
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"webservice/internal/minio"
+	"webservice/internal/models"
+
+	"golang.org/x/time/rate"
+)
+
+// userUploadLimiter 获取用户的上传限速器，首次上传时创建，未配置限速时返回nil
+func (s *PackageService) userUploadLimiter(userID uint) *rate.Limiter {
+	if s.userUploadLimit <= 0 {
+		return nil
+	}
+	if limiter, ok := s.userLimiters.Load(userID); ok {
+		return limiter.(*rate.Limiter)
+	}
+	limiter, _ := s.userLimiters.LoadOrStore(userID, minio.NewBandwidthLimiter(s.userUploadLimit))
+	return limiter.(*rate.Limiter)
+}
+
+// UploadThrottleSettings 返回当前的上传限速配置
+func (s *PackageService) UploadThrottleSettings() *models.UploadThrottleSettings {
+	settings := &models.UploadThrottleSettings{
+		UserBandwidthLimitBytesPerSec: s.userUploadLimit,
+	}
+	if s.minioClient != nil {
+		settings.UploadBandwidthLimitBytesPerSec = s.minioClient.UploadBandwidthLimit()
+	}
+	s.userLimiters.Range(func(_, _ interface{}) bool {
+		settings.TrackedUsers++
+		return true
+	})
+	return settings
+}
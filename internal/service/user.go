@@ -1,35 +1,44 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"time"
 
+	"webservice/internal/config"
+	"webservice/internal/logger"
 	"webservice/internal/models"
+	"webservice/internal/password"
+	"webservice/internal/tracer"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // UserService 用户服务
 type UserService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	hasher *password.Hasher
 }
 
 // NewUserService 创建用户服务实例
-func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(db *gorm.DB, passwordCfg config.PasswordConfig) *UserService {
+	return &UserService{db: db, hasher: password.NewHasher(passwordCfg)}
 }
 
 // CreateUser 创建用户
-func (s *UserService) CreateUser(req *models.RegisterRequest) (*models.User, error) {
+func (s *UserService) CreateUser(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.CreateUser")
+	defer span.Finish()
+
 	// 检查用户名是否已存在
 	var existingUser models.User
-	if err := s.db.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
+	if err := s.db.WithContext(ctx).Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
 		if existingUser.Username == req.Username {
-			return nil, errors.New("username already exists")
+			return nil, ErrUsernameExists
 		}
 		if existingUser.Email == req.Email {
-			return nil, errors.New("email already exists")
+			return nil, ErrEmailExists
 		}
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -51,81 +60,128 @@ func (s *UserService) CreateUser(req *models.RegisterRequest) (*models.User, err
 		Status:   models.UserStatusActive,
 	}
 
-	if err := s.db.Create(user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Create(user).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, s.duplicateUserError(req.Username)
+		}
 		return nil, err
 	}
 
 	return user, nil
 }
 
+// duplicateUserError 唯一约束冲突时判断是用户名还是邮箱冲突
+func (s *UserService) duplicateUserError(username string) error {
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err == nil && count > 0 {
+		return ErrUsernameExists
+	}
+	return ErrEmailExists
+}
+
 // GetUserByID 根据ID获取用户
-func (s *UserService) GetUserByID(id uint) (*models.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.GetUserByID")
+	defer span.Finish()
+
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetUserByUsername 根据用户名获取用户
-func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.GetUserByUsername")
+	defer span.Finish()
+
 	var user models.User
-	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // GetUserByEmail 根据邮箱获取用户
-func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.GetUserByEmail")
+	defer span.Finish()
+
 	var user models.User
-	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
 // AuthenticateUser 验证用户登录
-func (s *UserService) AuthenticateUser(username, password string) (*models.User, error) {
+// 已启用两步验证的用户须同时提供有效的验证码，与totp功能开关是否启用无关
+func (s *UserService) AuthenticateUser(ctx context.Context, username, password, totpCode string) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.AuthenticateUser")
+	defer span.Finish()
+
 	// 根据用户名或邮箱查找用户
 	var user models.User
-	if err := s.db.Where("username = ? OR email = ?", username, username).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("username = ? OR email = ?", username, username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid username or password")
 		}
 		return nil, err
 	}
 
-	// 检查用户状态
-	if !user.IsActive() {
+	// 检查用户状态，暂停的用户在密码验证通过后返回暂停原因
+	if !user.IsActive() && user.Status != models.UserStatusSuspended {
 		return nil, errors.New("user account is not active")
 	}
 
 	// 验证密码
-	if err := s.verifyPassword(password, user.Password); err != nil {
+	if err := s.hasher.Verify(password, user.Password); err != nil {
 		return nil, errors.New("invalid username or password")
 	}
 
+	if user.Status == models.UserStatusSuspended {
+		if err := s.checkSuspension(ctx, &user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkTOTP(&user, totpCode); err != nil {
+		return nil, err
+	}
+
+	// 旧算法或旧参数的哈希在登录成功时重新计算，失败不影响登录
+	if s.hasher.NeedsRehash(user.Password) {
+		if hashed, err := s.hasher.Hash(password); err != nil {
+			logger.Warnf("Failed to rehash password for user %d: %v", user.ID, err)
+		} else if err := s.db.WithContext(ctx).Model(&user).Update("password", hashed).Error; err != nil {
+			logger.Warnf("Failed to store rehashed password for user %d: %v", user.ID, err)
+		}
+	}
+
 	// 更新最后登录时间
-	now := time.Now()
+	now := time.Now().UTC()
 	user.LastLogin = &now
-	s.db.Model(&user).Update("last_login", now)
+	s.db.WithContext(ctx).Model(&user).Update("last_login", now)
 
 	return &user, nil
 }
 
 // UpdateUser 更新用户信息
-func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) (*models.User, error) {
+func (s *UserService) UpdateUser(ctx context.Context, id uint, req *models.UpdateUserRequest) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.UpdateUser")
+	defer span.Finish()
+
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 
 	// 检查邮箱是否已被其他用户使用
 	if req.Email != "" && req.Email != user.Email {
 		var existingUser models.User
-		if err := s.db.Where("email = ? AND id != ?", req.Email, id).First(&existingUser).Error; err == nil {
-			return nil, errors.New("email already exists")
+		if err := s.db.WithContext(ctx).Where("email = ? AND id != ?", req.Email, id).First(&existingUser).Error; err == nil {
+			return nil, ErrEmailExists
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
 		}
@@ -149,7 +205,10 @@ func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 		updates["status"] = req.Status
 	}
 
-	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrEmailExists
+		}
 		return nil, err
 	}
 
@@ -157,17 +216,20 @@ func (s *UserService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 }
 
 // UpdateProfile 更新用户个人资料
-func (s *UserService) UpdateProfile(id uint, req *models.UpdateProfileRequest) (*models.User, error) {
+func (s *UserService) UpdateProfile(ctx context.Context, id uint, req *models.UpdateProfileRequest) (*models.User, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.UpdateProfile")
+	defer span.Finish()
+
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 
 	// 检查邮箱是否已被其他用户使用
 	if req.Email != "" && req.Email != user.Email {
 		var existingUser models.User
-		if err := s.db.Where("email = ? AND id != ?", req.Email, id).First(&existingUser).Error; err == nil {
-			return nil, errors.New("email already exists")
+		if err := s.db.WithContext(ctx).Where("email = ? AND id != ?", req.Email, id).First(&existingUser).Error; err == nil {
+			return nil, ErrEmailExists
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, err
 		}
@@ -185,7 +247,10 @@ func (s *UserService) UpdateProfile(id uint, req *models.UpdateProfileRequest) (
 		updates["email"] = req.Email
 	}
 
-	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrEmailExists
+		}
 		return nil, err
 	}
 
@@ -193,23 +258,60 @@ func (s *UserService) UpdateProfile(id uint, req *models.UpdateProfileRequest) (
 }
 
 // DeleteUser 删除用户（软删除）
-func (s *UserService) DeleteUser(id uint) error {
-	return s.db.Delete(&models.User{}, id).Error
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.DeleteUser")
+	defer span.Finish()
+
+	return s.db.WithContext(ctx).Delete(&models.User{}, id).Error
+}
+
+// 管理员用户列表的排序字段
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortLastLogin = "last_login"
+	UserSortUsername  = "username"
+)
+
+// UserListFilter 管理员用户列表的筛选、排序和分页条件
+type UserListFilter struct {
+	Page          int
+	PageSize      int
+	Role          string
+	Status        *models.UserStatus // nil表示不按状态筛选，与筛选inactive(0)区分
+	Query         string             // 按用户名、邮箱、昵称模糊搜索（不区分大小写）
+	CreatedAfter  *time.Time         // 注册时间不早于该时间
+	CreatedBefore *time.Time         // 注册时间早于该时间
+	Sort          string             // created_at（默认）、last_login或username
+	Ascending     bool               // 默认降序
 }
 
 // GetUsers 获取用户列表
-func (s *UserService) GetUsers(page, pageSize int, role string, status models.UserStatus) ([]*models.User, int64, error) {
+func (s *UserService) GetUsers(ctx context.Context, filter *UserListFilter) ([]*models.User, int64, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.GetUsers")
+	defer span.Finish()
+
 	var users []*models.User
 	var total int64
 
-	query := s.db.Model(&models.User{})
+	query := s.db.WithContext(ctx).Model(&models.User{})
 
 	// 添加过滤条件
-	if role != "" {
-		query = query.Where("role = ?", role)
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Query)) + "%"
+		query = query.Where("LOWER(username) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!' OR LOWER(nickname) LIKE ? ESCAPE '!'",
+			pattern, pattern, pattern)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
 	}
-	if status != 0 {
-		query = query.Where("status = ?", status)
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
 	}
 
 	// 获取总数
@@ -217,21 +319,39 @@ func (s *UserService) GetUsers(page, pageSize int, role string, status models.Us
 		return nil, 0, err
 	}
 
-	// 分页查询
-	offset := (page - 1) * pageSize
-	if err := query.Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error; err != nil {
+	// 分页查询，排序字段相同时按ID排序，保证分页稳定
+	sortColumn := UserSortCreatedAt
+	if filter.Sort != "" {
+		sortColumn = filter.Sort
+	}
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+	pagination := models.NewPagination(filter.Page, filter.PageSize, total)
+	if err := query.Offset(pagination.Offset()).Limit(pagination.PageSize).
+		Order(sortColumn + " " + direction).Order("id " + direction).
+		Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return users, total, nil
 }
 
+// escapeLike 转义LIKE模式中的通配符，配合ESCAPE '!'使用，使搜索词中的%和_按字面匹配
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
 // GetPublicUsers 获取公开用户列表
-func (s *UserService) GetPublicUsers(page, pageSize int) ([]*models.PublicUser, int64, error) {
+func (s *UserService) GetPublicUsers(ctx context.Context, page, pageSize int) ([]*models.PublicUser, int64, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "UserService.GetPublicUsers")
+	defer span.Finish()
+
 	var users []*models.User
 	var total int64
 
-	query := s.db.Model(&models.User{}).Where("status = ?", models.UserStatusActive)
+	query := s.db.WithContext(ctx).Model(&models.User{}).Where("status = ?", models.UserStatusActive)
 
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
@@ -239,8 +359,8 @@ func (s *UserService) GetPublicUsers(page, pageSize int) ([]*models.PublicUser,
 	}
 
 	// 分页查询
-	offset := (page - 1) * pageSize
-	if err := query.Select("id, username, nickname, avatar, status, created_at").Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error; err != nil {
+	pagination := models.NewPagination(page, pageSize, total)
+	if err := query.Select("id, username, nickname, avatar, status, created_at").Offset(pagination.Offset()).Limit(pagination.PageSize).Order("created_at DESC").Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -255,14 +375,5 @@ func (s *UserService) GetPublicUsers(page, pageSize int) ([]*models.PublicUser,
 
 // hashPassword 加密密码
 func (s *UserService) hashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashed), nil
-}
-
-// verifyPassword 验证密码
-func (s *UserService) verifyPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return s.hasher.Hash(password)
 }
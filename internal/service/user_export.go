@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"webservice/internal/models"
+)
+
+// userExportBatchSize 导出用户时每批读取的行数
+const userExportBatchSize = 500
+
+// userExportColumns 用户导出列，不包含密码哈希
+var userExportColumns = []string{"id", "username", "email", "role", "status", "created_at", "last_login"}
+
+// UserExportFilters 用户导出筛选条件
+type UserExportFilters struct {
+	CreatedAfter  *time.Time         `json:"created_after,omitempty"`
+	CreatedBefore *time.Time         `json:"created_before,omitempty"`
+	Role          string             `json:"role,omitempty"`
+	Status        *models.UserStatus `json:"status,omitempty"`
+}
+
+// userExportRow 导出时查询的列，显式列出以避免读取密码
+type userExportRow struct {
+	ID        uint
+	Username  string
+	Email     string
+	Role      string
+	Status    models.UserStatus
+	CreatedAt time.Time
+	LastLogin *time.Time
+}
+
+// ExportUsersCSV 导出用户列表为CSV，数据由后台goroutine分批写入管道，调用方读取返回的Reader
+func (s *UserService) ExportUsersCSV(ctx context.Context, filters *UserExportFilters) (io.Reader, error) {
+	if filters == nil {
+		filters = &UserExportFilters{}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writeUsersCSV(ctx, filters, pw))
+	}()
+	return pr, nil
+}
+
+// writeUsersCSV 按ID分批查询用户并写入CSV
+func (s *UserService) writeUsersCSV(ctx context.Context, filters *UserExportFilters, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(userExportColumns); err != nil {
+		return err
+	}
+
+	var lastID uint
+	for {
+		query := s.db.WithContext(ctx).Model(&models.User{}).
+			Select("id, username, email, role, status, created_at, last_login").
+			Where("id > ?", lastID)
+		if filters.CreatedAfter != nil {
+			query = query.Where("created_at >= ?", *filters.CreatedAfter)
+		}
+		if filters.CreatedBefore != nil {
+			query = query.Where("created_at < ?", *filters.CreatedBefore)
+		}
+		if filters.Role != "" {
+			query = query.Where("role = ?", filters.Role)
+		}
+		if filters.Status != nil {
+			query = query.Where("status = ?", *filters.Status)
+		}
+
+		var rows []userExportRow
+		if err := query.Order("id").Limit(userExportBatchSize).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to query users: %w", err)
+		}
+
+		for _, row := range rows {
+			lastLogin := ""
+			if row.LastLogin != nil {
+				lastLogin = row.LastLogin.Format(time.RFC3339)
+			}
+			record := []string{
+				strconv.FormatUint(uint64(row.ID), 10),
+				row.Username,
+				row.Email,
+				row.Role,
+				row.Status.String(),
+				row.CreatedAt.Format(time.RFC3339),
+				lastLogin,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+
+		// 每批写完后刷新，使数据尽快到达客户端
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		if len(rows) < userExportBatchSize {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
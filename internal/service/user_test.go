@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/password"
+)
+
+func TestLoginMigratesBcryptHashToArgon2id(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	user := createTestUser(t, db, "alice", models.RoleUser)
+	bcryptHash, err := password.NewHasher(testPasswordConfig).Hash("correct-horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(user).Update("password", bcryptHash).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewUserService(db, config.PasswordConfig{
+		Algorithm: password.AlgorithmArgon2id,
+		Argon2:    config.Argon2Config{Time: 1, MemoryKB: 1024, Threads: 1},
+	})
+
+	if _, err := s.AuthenticateUser(ctx, "alice", "wrong", ""); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+	stored := func() string {
+		t.Helper()
+		var u models.User
+		if err := db.First(&u, user.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return u.Password
+	}
+	if stored() != bcryptHash {
+		t.Fatal("failed login changed the stored hash")
+	}
+
+	if _, err := s.AuthenticateUser(ctx, "alice", "correct-horse", ""); err != nil {
+		t.Fatalf("login with bcrypt hash: %v", err)
+	}
+	migrated := stored()
+	if !strings.HasPrefix(migrated, "$argon2id$") {
+		t.Fatalf("stored hash after login = %q, want argon2id", migrated)
+	}
+
+	// 升级后的哈希可以继续登录，且不再重新计算
+	if _, err := s.AuthenticateUser(ctx, "alice", "correct-horse", ""); err != nil {
+		t.Fatalf("login with migrated hash: %v", err)
+	}
+	if stored() != migrated {
+		t.Error("argon2id hash with current parameters was rehashed")
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxCleanupVersions 单次批量清理最多删除的版本数，超出部分在结果的remaining中返回
+	MaxCleanupVersions = 500
+	// cleanupBatchSize 批量清理时每批删除的版本数，批次之间检查请求是否已取消
+	cleanupBatchSize = 50
+)
+
+// CleanupVersions 按条件批量清理包版本（包所有者和maintainer协作者），confirm为false时只返回预览
+// 置顶、锁定、最新（含最新正式版本）和包设置的最近保留版本始终不会被清理；逐个版本删除，失败的版本记录在failed中并继续处理
+func (s *PackageService) CleanupVersions(ctx context.Context, packageName string, req *models.VersionCleanupRequest, userID uint) (*models.VersionCleanupResult, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.CleanupVersions")
+	defer span.Finish()
+
+	if err := validateCleanupCriteria(req); err != nil {
+		return nil, err
+	}
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightPublish); err != nil {
+		return nil, err
+	}
+
+	var versions []models.PackageVersion
+	err := s.db.WithContext(ctx).Where("package_id = ?", pkg.ID).
+		Order("created_at DESC, id DESC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions: %w", err)
+	}
+	if err := s.markPinned(ctx, versions); err != nil {
+		return nil, err
+	}
+
+	result := &models.VersionCleanupResult{
+		DryRun:  !req.Confirm,
+		Matched: []models.VersionCleanupItem{},
+		Deleted: []string{},
+		Failed:  []models.VersionCleanupFailure{},
+	}
+	candidates, skipped := selectCleanupVersions(versions, req, pkg.KeepRecentVersions)
+	result.Skipped = skipped
+	if len(candidates) > MaxCleanupVersions {
+		result.Remaining = len(candidates) - MaxCleanupVersions
+		candidates = candidates[:MaxCleanupVersions]
+	}
+	for _, v := range candidates {
+		result.Matched = append(result.Matched, models.VersionCleanupItem{
+			Version:      v.Version,
+			IsPrerelease: v.IsPrerelease,
+			CreatedAt:    v.CreatedAt,
+		})
+	}
+	if !req.Confirm {
+		return result, nil
+	}
+
+	for start := 0; start < len(candidates); start += cleanupBatchSize {
+		if ctx.Err() != nil {
+			result.Remaining += len(candidates) - start
+			return result, nil
+		}
+		end := min(start+cleanupBatchSize, len(candidates))
+		for i := start; i < end; i++ {
+			if err := s.removeVersion(ctx, &candidates[i], pkg.Name, userID); err != nil {
+				result.Failed = append(result.Failed, models.VersionCleanupFailure{Version: candidates[i].Version, Error: err.Error()})
+				continue
+			}
+			result.Deleted = append(result.Deleted, candidates[i].Version)
+		}
+	}
+	return result, nil
+}
+
+// selectCleanupVersions 从按发布时间倒序排列的版本中选出待删除的版本（从最早的开始）和受保护的版本
+// 符合条件的前keep_last个版本直接保留，不计入skipped
+func selectCleanupVersions(versions []models.PackageVersion, req *models.VersionCleanupRequest, keepRecent int) ([]models.PackageVersion, []models.VersionCleanupSkip) {
+	var candidates []models.PackageVersion
+	skipped := []models.VersionCleanupSkip{}
+	matched := 0
+	stableSeen := false
+	for i, v := range versions {
+		isDefault := i == 0 || (!v.IsPrerelease && !stableSeen)
+		if !v.IsPrerelease {
+			stableSeen = true
+		}
+		if !matchesCleanupCriteria(&v, req) {
+			continue
+		}
+		matched++
+		if matched <= req.KeepLast {
+			continue
+		}
+		if reason := cleanupProtection(&v, isDefault, i < keepRecent); reason != "" {
+			skipped = append(skipped, models.VersionCleanupSkip{Version: v.Version, Reason: reason})
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	}
+	return candidates, skipped
+}
+
+// validateCleanupCriteria 至少需要一个筛选条件，版本匹配模式必须有效
+func validateCleanupCriteria(req *models.VersionCleanupRequest) error {
+	req.VersionGlob = strings.TrimSpace(req.VersionGlob)
+	if !req.PrereleaseOnly && req.OlderThan == nil && req.VersionGlob == "" {
+		return fmt.Errorf("%w: at least one of prerelease_only, older_than or version_glob is required", ErrInvalidCleanupCriteria)
+	}
+	if req.VersionGlob != "" {
+		if _, err := path.Match(req.VersionGlob, ""); err != nil {
+			return fmt.Errorf("%w: invalid version_glob: %v", ErrInvalidCleanupCriteria, err)
+		}
+	}
+	return nil
+}
+
+// matchesCleanupCriteria 版本是否满足所有筛选条件
+func matchesCleanupCriteria(v *models.PackageVersion, req *models.VersionCleanupRequest) bool {
+	if req.PrereleaseOnly && !v.IsPrerelease {
+		return false
+	}
+	if req.OlderThan != nil && !v.CreatedAt.Before(*req.OlderThan) {
+		return false
+	}
+	if req.VersionGlob != "" {
+		if ok, _ := path.Match(req.VersionGlob, v.Version); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// cleanupProtection 返回版本不能被清理的原因，可以清理时返回空字符串
+func cleanupProtection(v *models.PackageVersion, isDefault, retained bool) string {
+	switch {
+	case v.Pinned:
+		return models.CleanupSkipPinned
+	case v.Locked:
+		return models.CleanupSkipLocked
+	case isDefault:
+		return models.CleanupSkipDefault
+	case retained:
+		return models.CleanupSkipRetained
+	default:
+		return ""
+	}
+}
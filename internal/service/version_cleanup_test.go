@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/models"
+	"webservice/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// cleanupVersions 按发布时间倒序构造版本，带"-"的为预发布版本；后缀!pinned、!locked标记置顶和锁定
+func cleanupVersions(specs ...string) []models.PackageVersion {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	versions := make([]models.PackageVersion, len(specs))
+	for i, spec := range specs {
+		parts := strings.Split(spec, "!")
+		v := models.PackageVersion{
+			Version:      parts[0],
+			IsPrerelease: strings.Contains(parts[0], "-"),
+			CreatedAt:    now.AddDate(0, 0, -i),
+		}
+		for _, flag := range parts[1:] {
+			switch flag {
+			case "pinned":
+				v.Pinned = true
+			case "locked":
+				v.Locked = true
+			}
+		}
+		versions[i] = v
+	}
+	return versions
+}
+
+func TestSelectCleanupVersions(t *testing.T) {
+	olderThan := time.Date(2025, 12, 30, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		versions   []models.PackageVersion
+		req        models.VersionCleanupRequest
+		keepRecent int
+		candidates string // 从最早的开始
+		skipped    string
+	}{
+		{
+			name:       "latest is always kept",
+			versions:   cleanupVersions("1.2.0", "1.1.0", "1.0.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*"},
+			candidates: "[1.0.0 1.1.0]",
+			skipped:    "[1.2.0:default]",
+		},
+		{
+			name:       "keep_last keeps the newest matches without reporting them",
+			versions:   cleanupVersions("1.3.0", "1.2.0", "1.1.0", "1.0.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*", KeepLast: 2},
+			candidates: "[1.0.0 1.1.0]",
+			skipped:    "[]",
+		},
+		{
+			name:       "keep_last counts only matching versions",
+			versions:   cleanupVersions("1.1.0", "1.1.0-beta.2", "1.0.0", "1.0.0-beta.2", "1.0.0-beta.1"),
+			req:        models.VersionCleanupRequest{PrereleaseOnly: true, KeepLast: 1},
+			candidates: "[1.0.0-beta.1 1.0.0-beta.2]",
+			skipped:    "[]",
+		},
+		{
+			name:       "keep_last larger than the matches",
+			versions:   cleanupVersions("1.1.0", "1.0.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*", KeepLast: 5},
+			candidates: "[]",
+			skipped:    "[]",
+		},
+		{
+			name:       "pinned and locked are skipped",
+			versions:   cleanupVersions("1.3.0", "1.2.0!pinned", "1.1.0!locked", "1.0.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "1.*"},
+			candidates: "[1.0.0]",
+			skipped:    "[1.3.0:default 1.2.0:pinned 1.1.0:locked]",
+		},
+		{
+			name:       "pinned takes precedence over locked and default",
+			versions:   cleanupVersions("1.1.0!pinned!locked", "1.0.0!locked"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*"},
+			candidates: "[]",
+			skipped:    "[1.1.0:pinned 1.0.0:locked]",
+		},
+		{
+			name:       "latest stable is default when prereleases come first",
+			versions:   cleanupVersions("2.0.0-rc.2", "2.0.0-rc.1", "1.1.0", "1.0.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*"},
+			candidates: "[1.0.0 2.0.0-rc.1]",
+			skipped:    "[2.0.0-rc.2:default 1.1.0:default]",
+		},
+		{
+			name:       "prerelease_only leaves the latest stable untouched",
+			versions:   cleanupVersions("2.0.0-rc.2", "2.0.0-rc.1", "1.1.0", "1.1.0-beta.1"),
+			req:        models.VersionCleanupRequest{PrereleaseOnly: true},
+			candidates: "[1.1.0-beta.1 2.0.0-rc.1]",
+			skipped:    "[2.0.0-rc.2:default]",
+		},
+		{
+			name:       "only prereleases",
+			versions:   cleanupVersions("1.0.0-rc.2", "1.0.0-rc.1"),
+			req:        models.VersionCleanupRequest{PrereleaseOnly: true},
+			candidates: "[1.0.0-rc.1]",
+			skipped:    "[1.0.0-rc.2:default]",
+		},
+		{
+			name:       "package retention keeps the most recent versions",
+			versions:   cleanupVersions("1.3.0", "1.2.0", "1.1.0", "1.0.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*"},
+			keepRecent: 3,
+			candidates: "[1.0.0]",
+			skipped:    "[1.3.0:default 1.2.0:retained 1.1.0:retained]",
+		},
+		{
+			name:       "retention counts all versions, not only matches",
+			versions:   cleanupVersions("1.2.0", "1.2.0-beta.1", "1.1.0-beta.1", "1.0.0-beta.1"),
+			req:        models.VersionCleanupRequest{PrereleaseOnly: true},
+			keepRecent: 2,
+			candidates: "[1.0.0-beta.1 1.1.0-beta.1]",
+			skipped:    "[1.2.0-beta.1:retained]",
+		},
+		{
+			name:       "older_than and version_glob combined",
+			versions:   cleanupVersions("0.0.0-ci.4", "0.0.0-ci.3", "1.0.0", "0.0.0-ci.2", "0.0.0-ci.1"),
+			req:        models.VersionCleanupRequest{VersionGlob: "0.0.0-ci.*", OlderThan: &olderThan},
+			candidates: "[0.0.0-ci.1 0.0.0-ci.2]",
+			skipped:    "[]",
+		},
+		{
+			name:       "keep_last applied before protections",
+			versions:   cleanupVersions("1.2.0", "1.1.0!pinned", "1.0.0!pinned", "0.9.0"),
+			req:        models.VersionCleanupRequest{VersionGlob: "*", KeepLast: 2},
+			candidates: "[0.9.0]",
+			skipped:    "[1.0.0:pinned]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, skipped := selectCleanupVersions(tt.versions, &tt.req, tt.keepRecent)
+			names := []string{}
+			for _, v := range candidates {
+				names = append(names, v.Version)
+			}
+			if got := fmt.Sprint(names); got != tt.candidates {
+				t.Errorf("candidates = %s, want %s", got, tt.candidates)
+			}
+			skips := []string{}
+			for _, s := range skipped {
+				skips = append(skips, s.Version+":"+s.Reason)
+			}
+			if got := fmt.Sprint(skips); got != tt.skipped {
+				t.Errorf("skipped = %s, want %s", got, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestValidateCleanupCriteria(t *testing.T) {
+	for _, req := range []models.VersionCleanupRequest{
+		{},
+		{KeepLast: 3, Confirm: true},
+		{VersionGlob: "   "},
+		{VersionGlob: "1.[0"},
+	} {
+		if err := validateCleanupCriteria(&req); !errors.Is(err, ErrInvalidCleanupCriteria) {
+			t.Errorf("validateCleanupCriteria(%+v) = %v, want ErrInvalidCleanupCriteria", req, err)
+		}
+	}
+}
+
+// newCleanupTestService 创建包app及count个按发布时间递增的预发布版本和一个最新正式版本
+func newCleanupTestService(t *testing.T, count int) (*PackageService, *models.User) {
+	t.Helper()
+	s := NewPackageService(newTestDB(t), testutil.NewStorage(t, nil), nil, config.PackagesConfig{})
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+
+	start := time.Now().UTC().Add(-time.Duration(count+1) * time.Minute)
+	versions := make([]models.PackageVersion, 0, count+1)
+	for i := 0; i < count; i++ {
+		versions = append(versions, models.PackageVersion{
+			PackageID:    pkg.ID,
+			Version:      fmt.Sprintf("0.0.0-ci.%d", i+1),
+			IsPrerelease: true,
+			MinIOPath:    fmt.Sprintf("packages/app/0.0.0-ci.%d", i+1),
+			FileHash:     "x",
+			CreatedAt:    start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	versions = append(versions, models.PackageVersion{PackageID: pkg.ID, Version: "1.0.0", MinIOPath: "packages/app/1.0.0", FileHash: "x", CreatedAt: time.Now().UTC()})
+	if err := s.db.CreateInBatches(versions, 100).Error; err != nil {
+		t.Fatalf("failed to create versions: %v", err)
+	}
+	return s, owner
+}
+
+func TestCleanupVersionsCap(t *testing.T) {
+	s, owner := newCleanupTestService(t, MaxCleanupVersions+5)
+	req := &models.VersionCleanupRequest{PrereleaseOnly: true}
+
+	result, err := s.CleanupVersions(context.Background(), "app", req, owner.ID)
+	if err != nil {
+		t.Fatalf("CleanupVersions: %v", err)
+	}
+	if !result.DryRun || len(result.Deleted) != 0 {
+		t.Fatalf("preview deleted %d versions, want a dry run", len(result.Deleted))
+	}
+	if len(result.Matched) != MaxCleanupVersions || result.Remaining != 5 {
+		t.Fatalf("matched %d, remaining %d, want %d and 5", len(result.Matched), result.Remaining, MaxCleanupVersions)
+	}
+	if first, last := result.Matched[0].Version, result.Matched[MaxCleanupVersions-1].Version; first != "0.0.0-ci.1" || last != "0.0.0-ci.500" {
+		t.Errorf("matched %s..%s, want the oldest versions 0.0.0-ci.1..0.0.0-ci.500", first, last)
+	}
+
+	// 确认后删除上限内的版本，再次调用继续清理剩余的版本
+	req.Confirm = true
+	result, err = s.CleanupVersions(context.Background(), "app", req, owner.ID)
+	if err != nil {
+		t.Fatalf("CleanupVersions: %v", err)
+	}
+	if len(result.Deleted) != MaxCleanupVersions || len(result.Failed) != 0 || result.Remaining != 5 {
+		t.Fatalf("deleted %d, failed %v, remaining %d, want %d, none and 5", len(result.Deleted), result.Failed, result.Remaining, MaxCleanupVersions)
+	}
+	result, err = s.CleanupVersions(context.Background(), "app", req, owner.ID)
+	if err != nil {
+		t.Fatalf("CleanupVersions: %v", err)
+	}
+	if fmt.Sprint(result.Deleted) != "[0.0.0-ci.501 0.0.0-ci.502 0.0.0-ci.503 0.0.0-ci.504 0.0.0-ci.505]" || result.Remaining != 0 {
+		t.Errorf("second run deleted %v, remaining %d, want the last 5 versions", result.Deleted, result.Remaining)
+	}
+	var left []string
+	s.db.Model(&models.PackageVersion{}).Pluck("version", &left)
+	if fmt.Sprint(left) != "[1.0.0]" {
+		t.Errorf("versions left = %v, want [1.0.0]", left)
+	}
+}
+
+func TestCleanupVersionsStopsBetweenBatchesWhenCancelled(t *testing.T) {
+	s, owner := newCleanupTestService(t, 2*cleanupBatchSize+20)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 在第一批最后一个版本删除完成后取消请求
+	deletes := 0
+	err := s.db.Callback().Delete().After("gorm:delete").Register("test:cancel_after_first_batch", func(tx *gorm.DB) {
+		if tx.Statement.Table == "package_versions" {
+			deletes++
+			if deletes == cleanupBatchSize {
+				cancel()
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.CleanupVersions(ctx, "app", &models.VersionCleanupRequest{PrereleaseOnly: true, Confirm: true}, owner.ID)
+	if err != nil {
+		t.Fatalf("CleanupVersions: %v", err)
+	}
+	// 取消时正在删除的版本事务回滚并记为失败，之后的批次不再开始
+	if len(result.Deleted) != cleanupBatchSize-1 || result.Deleted[0] != "0.0.0-ci.1" {
+		t.Fatalf("deleted %v, want the first %d versions of the batch", result.Deleted, cleanupBatchSize-1)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Version != "0.0.0-ci.50" || !strings.Contains(result.Failed[0].Error, "context canceled") {
+		t.Fatalf("failed = %v, want 0.0.0-ci.50 rolled back by the cancellation", result.Failed)
+	}
+	if result.Remaining != cleanupBatchSize+20 {
+		t.Errorf("remaining = %d, want %d", result.Remaining, cleanupBatchSize+20)
+	}
+	if len(result.Matched) != 2*cleanupBatchSize+20 {
+		t.Errorf("matched = %d, want all %d candidates", len(result.Matched), 2*cleanupBatchSize+20)
+	}
+	var count int64
+	s.db.Model(&models.PackageVersion{}).Count(&count)
+	if want := int64(2*cleanupBatchSize + 20 + 1 - (cleanupBatchSize - 1)); count != want {
+		t.Errorf("versions left = %d, want %d", count, want)
+	}
+
+	// 请求开始前已取消时不删除任何版本
+	if _, err := s.CleanupVersions(ctx, "app", &models.VersionCleanupRequest{PrereleaseOnly: true, Confirm: true}, owner.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled cleanup err = %v, want context.Canceled", err)
+	}
+	var after int64
+	s.db.Model(&models.PackageVersion{}).Count(&after)
+	if after != count {
+		t.Errorf("versions after cancelled cleanup = %d, want %d", after, count)
+	}
+}
+
+func TestCleanupVersionsPermissions(t *testing.T) {
+	s, owner := newCleanupTestService(t, 3)
+	stranger := createTestUser(t, s.db, "mallory", models.RoleUser)
+	req := &models.VersionCleanupRequest{PrereleaseOnly: true, Confirm: true}
+
+	if _, err := s.CleanupVersions(context.Background(), "app", req, stranger.ID); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("stranger cleanup err = %v, want permission denied", err)
+	}
+	if _, err := s.CleanupVersions(context.Background(), "missing", req, owner.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing package cleanup err = %v, want not found", err)
+	}
+	if _, err := s.CleanupVersions(context.Background(), "app", &models.VersionCleanupRequest{Confirm: true}, owner.ID); !errors.Is(err, ErrInvalidCleanupCriteria) {
+		t.Errorf("cleanup without criteria err = %v, want ErrInvalidCleanupCriteria", err)
+	}
+	var count int64
+	s.db.Model(&models.PackageVersion{}).Count(&count)
+	if count != 4 {
+		t.Errorf("versions left = %d, want 4", count)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/events"
+	"webservice/internal/models"
+	"webservice/internal/outbox"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// MaxBulkDeprecateIDs 单次批量弃用/取消弃用的最大版本数
+const MaxBulkDeprecateIDs = 500
+
+// BulkDeprecateVersions 批量弃用包版本（包所有者和maintainer协作者），已弃用的版本保留原弃用说明
+// 不属于该包或不存在的版本ID在结果的not_found_ids中返回
+func (s *PackageService) BulkDeprecateVersions(ctx context.Context, packageName string, versionIDs []uint, message string, userID uint) (*models.BulkDeprecateResult, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.BulkDeprecateVersions")
+	defer span.Finish()
+
+	pkg, versions, notFound, err := s.findBulkVersions(ctx, packageName, versionIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BulkDeprecateResult{NotFoundIDs: notFound}
+	var pending []models.PackageVersion
+	for _, v := range versions {
+		if v.Deprecated {
+			result.AlreadyDeprecatedCount++
+			continue
+		}
+		pending = append(pending, v)
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uint, len(pending))
+	for i, v := range pending {
+		ids[i] = v.ID
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		update := tx.Model(&models.PackageVersion{}).
+			Where("id IN ? AND package_id = ?", ids, pkg.ID).
+			Updates(map[string]interface{}{"deprecated": true, "deprecation_message": message})
+		if update.Error != nil {
+			return update.Error
+		}
+		result.DeprecatedCount = int(update.RowsAffected)
+
+		for _, v := range pending {
+			err := outbox.Append(tx, events.Event{
+				Type:        events.VersionYanked,
+				PackageID:   pkg.ID,
+				PackageName: pkg.Name,
+				Version:     v.Version,
+				UserID:      userID,
+				Payload:     map[string]interface{}{"deprecated": true, "message": message},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to deprecate versions: %w", err)
+	}
+
+	return result, nil
+}
+
+// BulkUndeprecateVersions 批量取消包版本的弃用状态（包所有者和maintainer协作者）
+func (s *PackageService) BulkUndeprecateVersions(ctx context.Context, packageName string, versionIDs []uint, userID uint) (*models.BulkUndeprecateResult, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.BulkUndeprecateVersions")
+	defer span.Finish()
+
+	pkg, versions, notFound, err := s.findBulkVersions(ctx, packageName, versionIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.BulkUndeprecateResult{NotFoundIDs: notFound}
+	var ids []uint
+	for _, v := range versions {
+		if !v.Deprecated {
+			result.NotDeprecatedCount++
+			continue
+		}
+		ids = append(ids, v.ID)
+	}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	update := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Where("id IN ? AND package_id = ?", ids, pkg.ID).
+		Updates(map[string]interface{}{"deprecated": false, "deprecation_message": ""})
+	if update.Error != nil {
+		return nil, fmt.Errorf("failed to undeprecate versions: %w", update.Error)
+	}
+	result.UndeprecatedCount = int(update.RowsAffected)
+
+	return result, nil
+}
+
+// findBulkVersions 校验包所有权并查找属于该包的版本，返回不存在或不属于该包的ID
+func (s *PackageService) findBulkVersions(ctx context.Context, packageName string, versionIDs []uint, userID uint) (*models.Package, []models.PackageVersion, []uint, error) {
+	if len(versionIDs) > MaxBulkDeprecateIDs {
+		return nil, nil, nil, fmt.Errorf("too many version ids: at most %d per request", MaxBulkDeprecateIDs)
+	}
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, errors.New("package not found")
+		}
+		return nil, nil, nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightPublish); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var versions []models.PackageVersion
+	err := s.db.WithContext(ctx).Select("id", "version", "deprecated").
+		Where("id IN ? AND package_id = ?", versionIDs, pkg.ID).
+		Find(&versions).Error
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to find versions: %w", err)
+	}
+
+	found := make(map[uint]bool, len(versions))
+	for _, v := range versions {
+		found[v.ID] = true
+	}
+	notFound := []uint{}
+	seen := make(map[uint]bool, len(versionIDs))
+	for _, id := range versionIDs {
+		if !found[id] && !seen[id] {
+			notFound = append(notFound, id)
+		}
+		seen[id] = true
+	}
+
+	return &pkg, versions, notFound, nil
+}
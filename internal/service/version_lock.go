@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// checkVersionNumberReusable 不可变包（upload_locked）中发布过的版本号即使已被删除也不能再次上传
+func (s *PackageService) checkVersionNumberReusable(ctx context.Context, pkg *models.Package, version string) error {
+	if !pkg.UploadLocked {
+		return nil
+	}
+	var count int64
+	err := s.db.WithContext(ctx).Unscoped().Model(&models.PackageVersion{}).
+		Where("package_id = ? AND version = ?", pkg.ID, version).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check published versions: %w", err)
+	}
+	if count > 0 {
+		return ErrVersionNumberLocked
+	}
+	return nil
+}
+
+// LockPublishedVersions 锁定发布超过age的所有未锁定版本，返回锁定的数量
+func (s *PackageService) LockPublishedVersions(ctx context.Context, age time.Duration) (int64, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.LockPublishedVersions")
+	defer span.Finish()
+
+	now := time.Now().UTC()
+	result := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Where("locked = ? AND created_at <= ?", false, now.Add(-age)).
+		UpdateColumns(map[string]interface{}{"locked": true, "locked_at": now})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to lock versions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// SetVersionLocked 立即锁定或解锁版本（管理员操作），返回版本和状态是否发生变化
+// 锁定需要对包有admin_override权限；解锁由路由上的version.unlock权限单独控制（默认仅super角色）
+func (s *PackageService) SetVersionLocked(ctx context.Context, packageName, version string, locked bool, caller PackageCaller) (*models.PackageVersion, bool, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.SetVersionLocked")
+	defer span.Finish()
+
+	var pkgVersion models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, errors.New("package version not found")
+		}
+		return nil, false, fmt.Errorf("failed to find package version: %w", err)
+	}
+	if locked {
+		if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, caller, PackageRightAdminOverride); err != nil {
+			return nil, false, err
+		}
+	}
+	if pkgVersion.Locked == locked {
+		return &pkgVersion, false, nil
+	}
+
+	var lockedAt *time.Time
+	if locked {
+		now := time.Now().UTC()
+		lockedAt = &now
+	}
+	err = s.db.WithContext(ctx).Model(&pkgVersion).
+		UpdateColumns(map[string]interface{}{"locked": locked, "locked_at": lockedAt}).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update version lock: %w", err)
+	}
+	pkgVersion.Locked, pkgVersion.LockedAt = locked, lockedAt
+	return &pkgVersion, true, nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// MatchVersion 返回满足版本约束的最高版本，已删除和已弃用的版本不参与匹配，无法解析为语义化版本的版本号被忽略
+// includePrerelease为false时预发布版本只在约束指定了相同主次补丁版本号的预发布版本时匹配（与npm一致）
+func (s *PackageService) MatchVersion(ctx context.Context, packageName, constraint string, includePrerelease bool, userID *uint) (*models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.MatchVersion")
+	defer span.Finish()
+
+	parsed, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConstraint, err)
+	}
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: userID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+
+	var versions []models.PackageVersion
+	err = s.db.WithContext(ctx).Where("package_id = ? AND deprecated = ?", pkg.ID, false).Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions: %w", err)
+	}
+
+	best := highestMatchingVersion(versions, parsed, includePrerelease)
+	if best == nil {
+		return nil, ErrNoMatchingVersion
+	}
+	return best, nil
+}
+
+// highestMatchingVersion 返回满足约束的最高版本，没有时返回nil
+func highestMatchingVersion(versions []models.PackageVersion, constraint versionConstraint, includePrerelease bool) *models.PackageVersion {
+	var best *models.PackageVersion
+	var bestVersion semVersion
+	for i := range versions {
+		sv, ok := parseSemver(versions[i].Version)
+		if !ok {
+			continue
+		}
+		matched := constraint.matches(sv)
+		if includePrerelease {
+			matched = constraint.matchesIncludingPrerelease(sv)
+		}
+		if !matched {
+			continue
+		}
+		if best == nil || compareSemver(sv, bestVersion) > 0 {
+			best, bestVersion = &versions[i], sv
+		}
+	}
+	return best
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"webservice/internal/models"
+)
+
+func TestMatchVersion(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, db, "app", owner, false)
+	for _, v := range []string{"1.2.0", "1.2.5", "1.4.0", "2.0.0", "2.1.0-beta.1", "not-semver"} {
+		createTestVersion(t, db, pkg, v, nil)
+	}
+	createTestVersion(t, db, pkg, "1.9.0", func(v *models.PackageVersion) { v.Deprecated = true })
+	s := newTestPackageService(t, db)
+
+	tests := []struct {
+		constraint        string
+		includePrerelease bool
+		want              string
+		wantErr           error
+	}{
+		{constraint: "^1.2.0", want: "1.4.0"},
+		{constraint: "~1.2.0", want: "1.2.5"},
+		{constraint: "1.2.0", want: "1.2.0"},
+		{constraint: "=2.0.0", want: "2.0.0"},
+		{constraint: ">=1.0.0 <2.0.0", want: "1.4.0"},
+		{constraint: "^1.5.0 || ^2.0.0", want: "2.0.0"},
+		{constraint: "^2.0.0", includePrerelease: true, want: "2.1.0-beta.1"},
+		{constraint: "^3.0.0", wantErr: ErrNoMatchingVersion},
+		{constraint: "1.9.0", wantErr: ErrNoMatchingVersion},
+		{constraint: "^^1", wantErr: ErrInvalidConstraint},
+	}
+
+	for _, tt := range tests {
+		got, err := s.MatchVersion(context.Background(), "app", tt.constraint, tt.includePrerelease, nil)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("MatchVersion(%q) error = %v, want %v", tt.constraint, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("MatchVersion(%q): %v", tt.constraint, err)
+			continue
+		}
+		if got.Version != tt.want {
+			t.Errorf("MatchVersion(%q) = %s, want %s", tt.constraint, got.Version, tt.want)
+		}
+	}
+}
+
+func TestMatchVersionPrivatePackage(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "alice", models.RoleUser)
+	other := createTestUser(t, db, "bob", models.RoleUser)
+	pkg := createTestPackage(t, db, "secret", owner, true)
+	createTestVersion(t, db, pkg, "1.0.0", nil)
+	s := newTestPackageService(t, db)
+
+	if _, err := s.MatchVersion(context.Background(), "secret", "^1.0.0", false, &other.ID); err == nil {
+		t.Error("non-member matched a version of a private package")
+	}
+	if got, err := s.MatchVersion(context.Background(), "secret", "^1.0.0", false, &owner.ID); err != nil || got.Version != "1.0.0" {
+		t.Errorf("owner match = %v, %v, want 1.0.0", got, err)
+	}
+}
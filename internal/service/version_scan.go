@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"webservice/internal/events"
+	"webservice/internal/logger"
+	"webservice/internal/models"
+	"webservice/internal/notify"
+	"webservice/internal/outbox"
+	"webservice/internal/scan"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// defaultScanSyncTimeout sync模式下上传请求等待扫描结果的默认最长时间
+const defaultScanSyncTimeout = 30 * time.Second
+
+// staleScanAge 扫描状态停留超过该时间的版本由后台任务重新扫描（进程在扫描期间退出、扫描引擎暂时不可用等）
+const staleScanAge = 10 * time.Minute
+
+// rescanBatchSize 后台任务每次重新扫描的最大版本数
+const rescanBatchSize = 50
+
+// SetScanner 替换病毒扫描引擎，默认按packages.scan配置创建
+func (s *PackageService) SetScanner(scanner scan.Scanner) {
+	if scanner == nil {
+		scanner = scan.Noop{}
+	}
+	s.scanner = scanner
+}
+
+// initialScanStatus 新版本的扫描状态，未启用扫描时直接可下载
+func (s *PackageService) initialScanStatus() string {
+	if scan.Enabled(s.scanner) {
+		return models.ScanStatusScanning
+	}
+	return models.ScanStatusActive
+}
+
+// checkScanStatus 扫描中和已隔离的版本不能下载
+func checkScanStatus(v *models.PackageVersion) error {
+	switch v.ScanStatus {
+	case models.ScanStatusQuarantined:
+		return ErrVersionQuarantined
+	case models.ScanStatusScanning:
+		return ErrVersionScanning
+	}
+	return nil
+}
+
+// startVersionScan 发布后扫描新版本，扫描在后台执行，不受请求取消的影响
+// sync模式下等待扫描完成（最多scanSyncTimeout）并把结果写回version.ScanStatus，超时后照常返回
+func (s *PackageService) startVersionScan(ctx context.Context, version *models.PackageVersion) {
+	if version.ScanStatus != models.ScanStatusScanning {
+		return
+	}
+
+	target := *version
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := s.scanVersion(context.WithoutCancel(ctx), &target, nil); err != nil {
+			logger.Warnf("Failed to scan %s@%s, will retry later: %v", target.Package.Name, target.Version, err)
+		}
+	}()
+	if s.scanMode != scan.ModeSync {
+		return
+	}
+
+	timer := time.NewTimer(s.scanSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		logger.Warnf("Scan of %s@%s did not finish within %s, continuing in the background", version.Package.Name, version.Version, s.scanSyncTimeout)
+		return
+	case <-ctx.Done():
+		return
+	}
+	var status string
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("id = ?", version.ID).Pluck("scan_status", &status).Error; err == nil && status != "" {
+		version.ScanStatus = status
+	}
+}
+
+// scanVersion 扫描版本文件并记录结果，version需预加载Package
+// 发现病毒时隔离版本并在同一事务中写入VersionQuarantined事件；未发现病毒时只把scanning改为active，
+// 已隔离的版本需由管理员解除隔离；扫描未完成时记录error结果，版本状态不变
+func (s *PackageService) scanVersion(ctx context.Context, version *models.PackageVersion, triggeredBy *uint) (*models.VersionScanResult, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.scanVersion")
+	defer span.Finish()
+
+	result := &models.VersionScanResult{
+		PackageVersionID: version.ID,
+		Engine:           s.scanner.Name(),
+		TriggeredBy:      triggeredBy,
+	}
+	found, scanErr := s.scanObject(ctx, version)
+	result.ScannedAt = time.Now().UTC()
+	switch {
+	case scanErr != nil:
+		result.Verdict = models.ScanVerdictError
+		result.Error = truncateScanText(scanErr.Error(), 500)
+	case found.Infected:
+		result.Verdict = models.ScanVerdictInfected
+		result.Signature = truncateScanText(found.Signature, 255)
+	default:
+		result.Verdict = models.ScanVerdictClean
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(result).Error; err != nil {
+			return err
+		}
+		switch result.Verdict {
+		case models.ScanVerdictClean:
+			return tx.Model(&models.PackageVersion{}).
+				Where("id = ? AND scan_status = ?", version.ID, models.ScanStatusScanning).
+				UpdateColumn("scan_status", models.ScanStatusActive).Error
+		case models.ScanVerdictInfected:
+			update := tx.Model(&models.PackageVersion{}).
+				Where("id = ? AND scan_status <> ?", version.ID, models.ScanStatusQuarantined).
+				UpdateColumn("scan_status", models.ScanStatusQuarantined)
+			if update.Error != nil {
+				return update.Error
+			}
+			if update.RowsAffected == 0 {
+				return nil
+			}
+			return outbox.Append(tx, events.Event{
+				Type:        events.VersionQuarantined,
+				PackageID:   version.PackageID,
+				PackageName: version.Package.Name,
+				Version:     version.Version,
+				UserID:      version.UploaderID,
+				Payload:     map[string]interface{}{"signature": result.Signature, "engine": result.Engine},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save scan result: %w", err)
+	}
+	if result.Verdict == models.ScanVerdictInfected {
+		logger.Warnf("Quarantined %s@%s: %s detected %s", version.Package.Name, version.Version, result.Engine, result.Signature)
+	}
+	if scanErr != nil {
+		return result, fmt.Errorf("%w: %v", ErrScanFailed, scanErr)
+	}
+	return result, nil
+}
+
+// scanObject 读取版本文件（压缩存储的对象解压后）交给扫描引擎
+func (s *PackageService) scanObject(ctx context.Context, version *models.PackageVersion) (scan.Result, error) {
+	if s.minioClient == nil {
+		return scan.Result{}, errors.New("file storage is not available")
+	}
+	reader, _, err := objectStore(s.minioClient, version).DownloadObject(ctx, version.MinIOPath)
+	if err != nil {
+		return scan.Result{}, fmt.Errorf("failed to read package from storage: %w", err)
+	}
+	defer reader.Close()
+	return s.scanner.Scan(ctx, reader)
+}
+
+// RescanStaleVersions 重新扫描停留在scanning状态超过staleScanAge的版本，返回完成扫描的数量
+func (s *PackageService) RescanStaleVersions(ctx context.Context) (int, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.RescanStaleVersions")
+	defer span.Finish()
+
+	if !scan.Enabled(s.scanner) {
+		return 0, nil
+	}
+	var versions []models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").
+		Where("scan_status = ? AND created_at <= ?", models.ScanStatusScanning, time.Now().UTC().Add(-staleScanAge)).
+		Order("id").Limit(rescanBatchSize).
+		Find(&versions).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find versions pending scan: %w", err)
+	}
+
+	scanned := 0
+	for i := range versions {
+		if err := ctx.Err(); err != nil {
+			return scanned, err
+		}
+		if _, err := s.scanVersion(ctx, &versions[i], nil); err != nil {
+			logger.Warnf("Failed to rescan %s@%s: %v", versions[i].Package.Name, versions[i].Version, err)
+			continue
+		}
+		scanned++
+	}
+	return scanned, nil
+}
+
+// RescanVersion 管理员重新扫描版本，返回本次扫描记录和扫描后的版本，调用方需要对包有admin_override权限
+// 未启用扫描引擎时返回ErrScanFailed
+func (s *PackageService) RescanVersion(ctx context.Context, packageName, version string, caller PackageCaller) (*models.VersionScanResult, *models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.RescanVersion")
+	defer span.Finish()
+
+	if !scan.Enabled(s.scanner) {
+		return nil, nil, fmt.Errorf("%w: no scan engine is configured", ErrScanFailed)
+	}
+	pkgVersion, err := s.findScanVersion(ctx, packageName, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, caller, PackageRightAdminOverride); err != nil {
+		return nil, nil, err
+	}
+	result, err := s.scanVersion(ctx, pkgVersion, caller.UserID)
+	if err != nil {
+		return result, nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&models.PackageVersion{}).Where("id = ?", pkgVersion.ID).Pluck("scan_status", &pkgVersion.ScanStatus).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to reload scan status: %w", err)
+	}
+	return result, pkgVersion, nil
+}
+
+// ReleaseQuarantinedVersion 管理员解除版本隔离（如确认为误报），版本恢复为可下载
+func (s *PackageService) ReleaseQuarantinedVersion(ctx context.Context, packageName, version string) (*models.PackageVersion, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.ReleaseQuarantinedVersion")
+	defer span.Finish()
+
+	pkgVersion, err := s.findScanVersion(ctx, packageName, version)
+	if err != nil {
+		return nil, err
+	}
+	result := s.db.WithContext(ctx).Model(&models.PackageVersion{}).
+		Where("id = ? AND scan_status = ?", pkgVersion.ID, models.ScanStatusQuarantined).
+		UpdateColumn("scan_status", models.ScanStatusActive)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to release version: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrVersionNotQuarantined
+	}
+	pkgVersion.ScanStatus = models.ScanStatusActive
+	return pkgVersion, nil
+}
+
+// GetVersionScanReport 返回版本的扫描状态和扫描记录，有publish权限的调用方（所有者、maintainer协作者和管理员）可以查看
+// 已归档的包仍可查看
+func (s *PackageService) GetVersionScanReport(ctx context.Context, packageName, version string, caller PackageCaller) (*models.VersionScanReport, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "PackageService.GetVersionScanReport")
+	defer span.Finish()
+
+	pkgVersion, err := s.findScanVersion(ctx, packageName, version)
+	if err != nil {
+		return nil, err
+	}
+	err = authorizePackage(s.db.WithContext(ctx), &pkgVersion.Package, caller, PackageRightPublish)
+	if err != nil && !errors.Is(err, ErrPackageArchived) {
+		return nil, err
+	}
+
+	report := &models.VersionScanReport{
+		Package:    pkgVersion.Package.Name,
+		Version:    pkgVersion.Version,
+		ScanStatus: pkgVersion.ScanStatus,
+		Results:    []models.VersionScanResult{},
+	}
+	if err := s.db.WithContext(ctx).Where("package_version_id = ?", pkgVersion.ID).Order("scanned_at DESC, id DESC").Find(&report.Results).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scan results: %w", err)
+	}
+	return report, nil
+}
+
+// findScanVersion 按包名和版本号查找版本并预加载包
+func (s *PackageService) findScanVersion(ctx context.Context, packageName, version string) (*models.PackageVersion, error) {
+	var pkgVersion models.PackageVersion
+	err := s.db.WithContext(ctx).Preload("Package").Where("package_id = (SELECT id FROM packages WHERE name = ?) AND version = ?", packageName, version).First(&pkgVersion).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package version not found")
+		}
+		return nil, fmt.Errorf("failed to find package version: %w", err)
+	}
+	return &pkgVersion, nil
+}
+
+// truncateScanText 截断超过列长度的扫描结果文本，不截断在UTF-8字符中间
+func truncateScanText(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
+
+// QuarantineNotifier 发件箱消费者：版本因发现病毒被隔离后通知包所有者
+type QuarantineNotifier struct {
+	db       *gorm.DB
+	notifier notify.Notifier
+}
+
+// NewQuarantineNotifier 创建隔离通知消费者
+func NewQuarantineNotifier(db *gorm.DB, notifier notify.Notifier) *QuarantineNotifier {
+	return &QuarantineNotifier{db: db, notifier: notifier}
+}
+
+// Name 消费者名称
+func (q *QuarantineNotifier) Name() string {
+	return "quarantine_notifications"
+}
+
+// Handle 处理version.quarantined事件，发送失败时返回错误由发件箱重试
+func (q *QuarantineNotifier) Handle(ctx context.Context, event events.Event) error {
+	if event.Type != events.VersionQuarantined {
+		return nil
+	}
+
+	var owner models.User
+	err := q.db.WithContext(ctx).Joins("JOIN packages p ON p.owner_id = users.id").
+		Where("p.id = ?", event.PackageID).
+		First(&owner).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 包或所有者已被删除，无需通知
+			return nil
+		}
+		return fmt.Errorf("failed to load package owner: %w", err)
+	}
+
+	signature, _ := event.Payload["signature"].(string)
+	err = q.notifier.Notify(ctx, notify.Notification{
+		Type:     "version_quarantined",
+		UserID:   owner.ID,
+		Username: owner.Username,
+		Email:    owner.Email,
+		Package:  event.PackageName,
+		Version:  event.Version,
+		Message:  fmt.Sprintf("%s %s has been quarantined: malware detected (%s); downloads are blocked until an administrator releases it", event.PackageName, event.Version, signature),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to notify owner of quarantined version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/events"
+	"webservice/internal/models"
+	"webservice/internal/scan"
+	"webservice/internal/testutil"
+)
+
+// fakeScanner 内容包含signature时报告病毒，err不为nil时扫描失败
+type fakeScanner struct {
+	mu        sync.Mutex
+	signature string
+	err       error
+}
+
+func (f *fakeScanner) Name() string { return "fake" }
+
+func (f *fakeScanner) Scan(_ context.Context, r io.Reader) (scan.Result, error) {
+	f.mu.Lock()
+	signature, err := f.signature, f.err
+	f.mu.Unlock()
+	if err != nil {
+		return scan.Result{}, err
+	}
+	content, readErr := io.ReadAll(r)
+	if readErr != nil {
+		return scan.Result{}, readErr
+	}
+	if signature != "" && strings.Contains(string(content), signature) {
+		return scan.Result{Infected: true, Signature: "Test.Signature"}, nil
+	}
+	return scan.Result{}, nil
+}
+
+// set 修改扫描行为
+func (f *fakeScanner) set(signature string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signature, f.err = signature, err
+}
+
+// newScanTestService 创建使用fakeScanner、同步扫描的包服务
+func newScanTestService(t *testing.T) (*PackageService, *fakeScanner) {
+	t.Helper()
+	s := NewPackageService(newTestDB(t), testutil.NewStorage(t, nil), nil,
+		config.PackagesConfig{Scan: config.ScanConfig{Mode: scan.ModeSync, SyncTimeout: 10 * time.Second}})
+	scanner := &fakeScanner{}
+	s.SetScanner(scanner)
+	return s, scanner
+}
+
+// downloadScanTestVersion 下载版本并关闭读取器，返回错误
+func downloadScanTestVersion(s *PackageService, pkg *models.Package, version string) error {
+	reader, _, err := s.DownloadPackageVersion(context.Background(), pkg.Name, version, nil, "127.0.0.1", "test", false)
+	if err != nil {
+		return err
+	}
+	return reader.Close()
+}
+
+func TestScanCleanVersionBecomesActive(t *testing.T) {
+	s, _ := newScanTestService(t)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+
+	v, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ScanStatus != models.ScanStatusActive {
+		t.Errorf("scan status = %q, want active", v.ScanStatus)
+	}
+	if err := downloadScanTestVersion(s, pkg, "1.0.0"); err != nil {
+		t.Errorf("download clean version: %v", err)
+	}
+
+	report, err := s.GetVersionScanReport(context.Background(), pkg.Name, "1.0.0", PackageCaller{UserID: &owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 1 || report.Results[0].Verdict != models.ScanVerdictClean || report.Results[0].Engine != "fake" {
+		t.Errorf("scan results = %+v, want one clean result", report.Results)
+	}
+}
+
+func TestScanInfectedVersionIsQuarantined(t *testing.T) {
+	s, scanner := newScanTestService(t)
+	scanner.set("bad@", nil)
+	ctx := context.Background()
+	admin := createTestUser(t, s.db, "root", models.RoleAdmin)
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "bad", owner, false)
+
+	v, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ScanStatus != models.ScanStatusQuarantined {
+		t.Fatalf("scan status = %q, want quarantined", v.ScanStatus)
+	}
+	if err := downloadScanTestVersion(s, pkg, "1.0.0"); !errors.Is(err, ErrVersionQuarantined) {
+		t.Errorf("download quarantined version = %v, want ErrVersionQuarantined", err)
+	}
+
+	var quarantined []models.OutboxEvent
+	if err := s.db.Where("event_type = ?", string(events.VersionQuarantined)).Find(&quarantined).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(quarantined) != 1 || quarantined[0].PackageName != "bad" || quarantined[0].UserID != owner.ID {
+		t.Errorf("quarantine events = %+v, want one for bad@1.0.0 addressed to the owner", quarantined)
+	}
+
+	// 重新扫描为无病毒时不会自动解除隔离
+	scanner.set("", nil)
+	if _, rescanned, err := s.RescanVersion(ctx, pkg.Name, "1.0.0", PackageCaller{UserID: &admin.ID, AdminOverride: true}); err != nil || rescanned.ScanStatus != models.ScanStatusQuarantined {
+		t.Fatalf("clean rescan = %+v, %v, want still quarantined", rescanned, err)
+	}
+
+	released, err := s.ReleaseQuarantinedVersion(ctx, pkg.Name, "1.0.0")
+	if err != nil || released.ScanStatus != models.ScanStatusActive {
+		t.Fatalf("release = %+v, %v", released, err)
+	}
+	if err := downloadScanTestVersion(s, pkg, "1.0.0"); err != nil {
+		t.Errorf("download after release: %v", err)
+	}
+	if _, err := s.ReleaseQuarantinedVersion(ctx, pkg.Name, "1.0.0"); !errors.Is(err, ErrVersionNotQuarantined) {
+		t.Errorf("second release = %v, want ErrVersionNotQuarantined", err)
+	}
+}
+
+func TestScanFailureLeavesVersionScanning(t *testing.T) {
+	s, scanner := newScanTestService(t)
+	scanner.set("", errors.New("engine unavailable"))
+	owner := createTestUser(t, s.db, "alice", models.RoleUser)
+	pkg := createTestPackage(t, s.db, "app", owner, false)
+
+	v, err := uploadTestVersion(s, pkg, "1.0.0", owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ScanStatus != models.ScanStatusScanning {
+		t.Fatalf("scan status = %q, want scanning", v.ScanStatus)
+	}
+	if err := downloadScanTestVersion(s, pkg, "1.0.0"); !errors.Is(err, ErrVersionScanning) {
+		t.Errorf("download while scanning = %v, want ErrVersionScanning", err)
+	}
+
+	// 引擎恢复后由后台任务重新扫描停留过久的版本
+	scanner.set("", nil)
+	if err := s.db.Model(v).UpdateColumn("created_at", time.Now().Add(-2*staleScanAge)).Error; err != nil {
+		t.Fatal(err)
+	}
+	scanned, err := s.RescanStaleVersions(context.Background())
+	if err != nil || scanned != 1 {
+		t.Fatalf("RescanStaleVersions = %d, %v, want 1", scanned, err)
+	}
+	if err := downloadScanTestVersion(s, pkg, "1.0.0"); err != nil {
+		t.Errorf("download after rescan: %v", err)
+	}
+
+	var verdicts []string
+	if err := s.db.Model(&models.VersionScanResult{}).Where("package_version_id = ?", v.ID).Order("id").Pluck("verdict", &verdicts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(verdicts) != 2 || verdicts[0] != models.ScanVerdictError || verdicts[1] != models.ScanVerdictClean {
+		t.Errorf("verdicts = %v, want [error clean]", verdicts)
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"webservice/internal/config"
+	"webservice/internal/events"
+	"webservice/internal/logger"
+	"webservice/internal/models"
+	"webservice/internal/notify"
+	"webservice/internal/tracer"
+
+	"gorm.io/gorm"
+)
+
+// 未配置时新版本通知的分批大小和并发数
+const (
+	defaultWatchNotifyBatchSize   = 500
+	defaultWatchNotifyConcurrency = 8
+)
+
+// WatchService 包关注服务
+type WatchService struct {
+	db *gorm.DB
+}
+
+// NewWatchService 创建包关注服务实例
+func NewWatchService(db *gorm.DB) *WatchService {
+	return &WatchService{db: db}
+}
+
+// findWatchablePackage 查找用户可以关注的包，私有包只有能读取该包的用户可以关注
+func (s *WatchService) findWatchablePackage(ctx context.Context, packageName string, userID uint) (*models.Package, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// Watch 关注包，已关注时直接返回当前状态
+func (s *WatchService) Watch(ctx context.Context, packageName string, userID uint) (*models.WatchStatus, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WatchService.Watch")
+	defer span.Finish()
+
+	pkg, err := s.findWatchablePackage(ctx, packageName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PackageWatcher{PackageID: pkg.ID, UserID: userID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Package{}).Where("id = ?", pkg.ID).UpdateColumn("watch_count", gorm.Expr("watch_count + 1")).Error
+	})
+	if err != nil && !isDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to watch package: %w", err)
+	}
+	return s.status(ctx, pkg, true)
+}
+
+// Unwatch 取消关注包，未关注时不报错；正在发送的通知读取关注者时不会再包含该用户
+func (s *WatchService) Unwatch(ctx context.Context, packageName string, userID uint) (*models.WatchStatus, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WatchService.Unwatch")
+	defer span.Finish()
+
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("package_id = ? AND user_id = ?", pkg.ID, userID).Delete(&models.PackageWatcher{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Package{}).Where("id = ? AND watch_count > 0", pkg.ID).UpdateColumn("watch_count", gorm.Expr("watch_count - 1")).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwatch package: %w", err)
+	}
+	return s.status(ctx, &pkg, false)
+}
+
+// status 读取包的最新关注数
+func (s *WatchService) status(ctx context.Context, pkg *models.Package, watching bool) (*models.WatchStatus, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Package{}).Where("id = ?", pkg.ID).Pluck("watch_count", &count).Error; err != nil {
+		return nil, fmt.Errorf("failed to load watch count: %w", err)
+	}
+	return &models.WatchStatus{Package: pkg.Name, Watching: watching, WatchCount: count}, nil
+}
+
+// ListWatching 分页列出用户关注的包（按关注时间倒序）及各包的最新版本
+func (s *WatchService) ListWatching(ctx context.Context, userID uint, page, pageSize int) (*models.WatchedPackageList, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WatchService.ListWatching")
+	defer span.Finish()
+
+	page, pageSize = models.NormalizePage(page, pageSize, models.DefaultPageSize, models.MaxPageSize)
+	query := s.db.WithContext(ctx).Table("package_watchers AS pw").
+		Joins("JOIN packages p ON p.id = pw.package_id AND p.deleted_at IS NULL").
+		Where("pw.user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count watched packages: %w", err)
+	}
+
+	var rows []struct {
+		PackageID   uint
+		Name        string
+		Description string
+		WatchCount  int64
+		WatchedAt   time.Time
+	}
+	err := query.Select("p.id AS package_id, p.name, p.description, p.watch_count, pw.created_at AS watched_at").
+		Order("pw.created_at DESC, pw.id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watched packages: %w", err)
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.PackageID
+	}
+	latest, err := s.latestVersions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.WatchedPackageList{
+		Packages:   make([]models.WatchedPackage, 0, len(rows)),
+		Pagination: models.NewPagination(page, pageSize, total),
+	}
+	for _, row := range rows {
+		item := models.WatchedPackage{
+			Package:     row.Name,
+			Description: row.Description,
+			WatchCount:  row.WatchCount,
+			WatchedAt:   row.WatchedAt,
+		}
+		if v, ok := latest[row.PackageID]; ok {
+			published := v.CreatedAt
+			item.LatestVersion = v.Version
+			item.PublishedAt = &published
+		}
+		list.Packages = append(list.Packages, item)
+	}
+	return list, nil
+}
+
+// latestVersions 查询每个包最近发布的版本
+func (s *WatchService) latestVersions(ctx context.Context, packageIDs []uint) (map[uint]models.PackageVersion, error) {
+	latest := make(map[uint]models.PackageVersion, len(packageIDs))
+	if len(packageIDs) == 0 {
+		return latest, nil
+	}
+
+	var versions []models.PackageVersion
+	newest := s.db.Model(&models.PackageVersion{}).Select("MAX(id)").Where("package_id IN ?", packageIDs).Group("package_id")
+	if err := s.db.WithContext(ctx).Select("package_id, version, created_at").Where("id IN (?)", newest).Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load latest versions: %w", err)
+	}
+	for _, v := range versions {
+		latest[v.PackageID] = v
+	}
+	return latest, nil
+}
+
+// WatchNotifier 发件箱消费者：新版本发布后通知包的关注者（不包括发布者本人）
+// 由发件箱分发任务在后台执行，不影响发布请求的响应时间；关注者分批读取，每批并发发送
+// 单个通知发送失败只记录日志，不重试，避免重试时重复通知同一批中已发送成功的用户
+type WatchNotifier struct {
+	db          *gorm.DB
+	notifier    notify.Notifier
+	batchSize   int
+	concurrency int
+}
+
+// NewWatchNotifier 创建关注者通知消费者
+func NewWatchNotifier(db *gorm.DB, notifier notify.Notifier, cfg config.NotifyConfig) *WatchNotifier {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWatchNotifyBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultWatchNotifyConcurrency
+	}
+	return &WatchNotifier{db: db, notifier: notifier, batchSize: cfg.BatchSize, concurrency: cfg.Concurrency}
+}
+
+// Name 消费者名称
+func (w *WatchNotifier) Name() string {
+	return "watch_notifications"
+}
+
+// watchRecipient 通知接收者
+type watchRecipient struct {
+	WatcherID uint
+	UserID    uint
+	Username  string
+	Email     string
+}
+
+// Handle 处理version.uploaded事件，按关注记录ID分批通知关注者
+func (w *WatchNotifier) Handle(ctx context.Context, event events.Event) error {
+	if event.Type != events.VersionUploaded {
+		return nil
+	}
+
+	var lastID uint
+	sent, failed := 0, 0
+	for {
+		var recipients []watchRecipient
+		err := w.db.WithContext(ctx).Table("package_watchers AS pw").
+			Select("pw.id AS watcher_id, u.id AS user_id, u.username, u.email").
+			Joins("JOIN users u ON u.id = pw.user_id AND u.deleted_at IS NULL").
+			Where("pw.package_id = ? AND pw.user_id <> ? AND pw.id > ?", event.PackageID, event.UserID, lastID).
+			Order("pw.id").
+			Limit(w.batchSize).
+			Scan(&recipients).Error
+		if err != nil {
+			return fmt.Errorf("failed to load watchers: %w", err)
+		}
+		if len(recipients) == 0 {
+			break
+		}
+
+		n, f := w.sendBatch(ctx, event, recipients)
+		sent += n
+		failed += f
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lastID = recipients[len(recipients)-1].WatcherID
+		if len(recipients) < w.batchSize {
+			break
+		}
+	}
+
+	if sent > 0 || failed > 0 {
+		logger.Infof("Notified %d watchers of %s@%s (%d failed)", sent, event.PackageName, event.Version, failed)
+	}
+	return nil
+}
+
+// sendBatch 并发发送一批通知，返回成功和失败的数量
+func (w *WatchNotifier) sendBatch(ctx context.Context, event events.Event, recipients []watchRecipient) (int, int) {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		sent   int
+		failed int
+	)
+	sem := make(chan struct{}, w.concurrency)
+	for _, r := range recipients {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(r watchRecipient) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := w.notifier.Notify(ctx, notify.Notification{
+				Type:     "version_published",
+				UserID:   r.UserID,
+				Username: r.Username,
+				Email:    r.Email,
+				Package:  event.PackageName,
+				Version:  event.Version,
+				Message:  fmt.Sprintf("%s %s has been published", event.PackageName, event.Version),
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				logger.Warnf("Failed to notify user %d of %s@%s: %v", r.UserID, event.PackageName, event.Version, err)
+				return
+			}
+			sent++
+		}(r)
+	}
+	wg.Wait()
+	return sent, failed
+}
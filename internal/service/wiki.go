@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"webservice/internal/models"
+	"webservice/internal/tracer"
+	"webservice/internal/validation"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidWikiSlug 文档页面slug只能包含小写字母、数字和-
+	ErrInvalidWikiSlug = errors.New("wiki page slug must start with a lowercase letter or digit and contain only lowercase letters, digits or '-'")
+	// ErrWikiPageExists 包中已存在相同slug的文档页面
+	ErrWikiPageExists = errors.New("wiki page already exists")
+	// ErrWikiPageNotFound 文档页面不存在
+	ErrWikiPageNotFound = errors.New("wiki page not found")
+)
+
+// wikiSlugPattern 文档页面slug格式
+var wikiSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// WikiService 包文档页面服务，页面内容保存前按更新日志的规则清理
+type WikiService struct {
+	db *gorm.DB
+}
+
+// NewWikiService 创建包文档页面服务实例
+func NewWikiService(db *gorm.DB) *WikiService {
+	return &WikiService{db: db}
+}
+
+// findReadablePackage 查找用户可以查看的包，私有包只有所有者和协作者可以查看
+func (s *WikiService) findReadablePackage(ctx context.Context, packageName string, userID *uint) (*models.Package, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: userID}, PackageRightRead); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// findWritablePackage 查找用户可以编辑文档的包，只有包所有者和maintainer协作者可以编辑
+func (s *WikiService) findWritablePackage(ctx context.Context, packageName string, userID uint) (*models.Package, error) {
+	var pkg models.Package
+	if err := s.db.WithContext(ctx).Where("name = ?", packageName).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("package not found")
+		}
+		return nil, fmt.Errorf("failed to find package: %w", err)
+	}
+	if err := authorizePackage(s.db.WithContext(ctx), &pkg, PackageCaller{UserID: &userID}, PackageRightEditMetadata); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// findPage 查找包中的文档页面
+func (s *WikiService) findPage(ctx context.Context, packageID uint, slug string) (*models.WikiPage, error) {
+	var page models.WikiPage
+	if err := s.db.WithContext(ctx).Where("package_id = ? AND slug = ?", packageID, slug).First(&page).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWikiPageNotFound
+		}
+		return nil, fmt.Errorf("failed to find wiki page: %w", err)
+	}
+	return &page, nil
+}
+
+// ListPages 列出包的文档页面（不含内容），按slug排序
+func (s *WikiService) ListPages(ctx context.Context, packageName string, userID *uint) ([]models.WikiPage, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WikiService.ListPages")
+	defer span.Finish()
+
+	pkg, err := s.findReadablePackage(ctx, packageName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := []models.WikiPage{}
+	err = s.db.WithContext(ctx).
+		Select("id, package_id, slug, title, author_user_id, revision, created_at, updated_at").
+		Where("package_id = ?", pkg.ID).
+		Order("slug").
+		Find(&pages).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wiki pages: %w", err)
+	}
+	return pages, nil
+}
+
+// GetPage 获取文档页面
+func (s *WikiService) GetPage(ctx context.Context, packageName, slug string, userID *uint) (*models.WikiPage, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WikiService.GetPage")
+	defer span.Finish()
+
+	pkg, err := s.findReadablePackage(ctx, packageName, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.findPage(ctx, pkg.ID, slug)
+}
+
+// GetRevisions 获取文档页面的修改历史，最新的在前
+func (s *WikiService) GetRevisions(ctx context.Context, packageName, slug string, userID *uint) ([]models.WikiPageRevision, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WikiService.GetRevisions")
+	defer span.Finish()
+
+	pkg, err := s.findReadablePackage(ctx, packageName, userID)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.findPage(ctx, pkg.ID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []models.WikiPageRevision{}
+	if err := s.db.WithContext(ctx).Where("page_id = ?", page.ID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wiki page revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// CreatePage 创建文档页面（包所有者和maintainer协作者），同时记录第1个历史版本
+func (s *WikiService) CreatePage(ctx context.Context, packageName string, userID uint, req *models.CreateWikiPageRequest) (*models.WikiPage, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WikiService.CreatePage")
+	defer span.Finish()
+
+	if !wikiSlugPattern.MatchString(req.Slug) {
+		return nil, ErrInvalidWikiSlug
+	}
+	pkg, err := s.findWritablePackage(ctx, packageName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.WikiPage{
+		PackageID:       pkg.ID,
+		Slug:            req.Slug,
+		Title:           req.Title,
+		ContentMarkdown: validation.SanitizeChangelog(req.ContentMarkdown),
+		AuthorUserID:    userID,
+		Revision:        1,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(page).Error; err != nil {
+			return err
+		}
+		return tx.Create(newWikiRevision(page)).Error
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return nil, ErrWikiPageExists
+		}
+		return nil, fmt.Errorf("failed to create wiki page: %w", err)
+	}
+	return page, nil
+}
+
+// UpdatePage 修改文档页面（包所有者和maintainer协作者），修订号加1并记录历史版本
+// 携带if_revision且页面已被修改时返回*StaleUpdateError，附带页面的当前内容
+func (s *WikiService) UpdatePage(ctx context.Context, packageName, slug string, userID uint, req *models.UpdateWikiPageRequest) (*models.WikiPage, error) {
+	ctx, span := tracer.StartServiceSpan(ctx, "WikiService.UpdatePage")
+	defer span.Finish()
+
+	pkg, err := s.findWritablePackage(ctx, packageName, userID)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.findPage(ctx, pkg.ID, slug)
+	if err != nil {
+		return nil, err
+	}
+	if req.IfRevision != nil && *req.IfRevision != page.Revision {
+		return nil, &StaleUpdateError{Current: page}
+	}
+
+	updated := *page
+	if req.Title != nil {
+		updated.Title = *req.Title
+	}
+	if req.ContentMarkdown != nil {
+		updated.ContentMarkdown = validation.SanitizeChangelog(*req.ContentMarkdown)
+	}
+	if updated.Title == page.Title && updated.ContentMarkdown == page.ContentMarkdown {
+		return page, nil
+	}
+	updated.Revision = page.Revision + 1
+	updated.AuthorUserID = userID
+
+	stale := false
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 按读取时的修订号条件更新，避免并发修改使用相同的修订号
+		result := tx.Model(&models.WikiPage{}).
+			Where("id = ? AND revision = ?", page.ID, page.Revision).
+			Updates(map[string]interface{}{
+				"title":            updated.Title,
+				"content_markdown": updated.ContentMarkdown,
+				"author_user_id":   userID,
+				"revision":         updated.Revision,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			stale = true
+			return nil
+		}
+		return tx.Create(newWikiRevision(&updated)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update wiki page: %w", err)
+	}
+	if stale {
+		current, err := s.findPage(ctx, pkg.ID, slug)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &StaleUpdateError{Current: current}
+	}
+	return s.findPage(ctx, pkg.ID, slug)
+}
+
+// DeletePage 删除文档页面及其历史版本（包所有者和maintainer协作者）
+func (s *WikiService) DeletePage(ctx context.Context, packageName, slug string, userID uint) error {
+	ctx, span := tracer.StartServiceSpan(ctx, "WikiService.DeletePage")
+	defer span.Finish()
+
+	pkg, err := s.findWritablePackage(ctx, packageName, userID)
+	if err != nil {
+		return err
+	}
+	page, err := s.findPage(ctx, pkg.ID, slug)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("page_id = ?", page.ID).Delete(&models.WikiPageRevision{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(page).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete wiki page: %w", err)
+	}
+	return nil
+}
+
+// newWikiRevision 根据页面的当前内容创建历史版本
+func newWikiRevision(page *models.WikiPage) *models.WikiPageRevision {
+	return &models.WikiPageRevision{
+		PageID:          page.ID,
+		Revision:        page.Revision,
+		Title:           page.Title,
+		ContentMarkdown: page.ContentMarkdown,
+		AuthorUserID:    page.AuthorUserID,
+	}
+}
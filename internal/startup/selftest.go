@@ -0,0 +1,97 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"webservice/internal/database"
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+
+	"gorm.io/gorm"
+)
+
+// selfTestTimeout 单项自检的超时时间
+const selfTestTimeout = 10 * time.Second
+
+// CheckResult 单项自检结果
+type CheckResult struct {
+	Name     string
+	Err      error
+	Skipped  bool // 依赖未初始化，未执行自检
+	Duration time.Duration
+}
+
+// Report 启动自检报告
+type Report struct {
+	Results []CheckResult
+}
+
+// Failed 返回未通过的检查项（strict模式下跳过的检查项也视为失败）
+func (r *Report) Failed(strict bool) []CheckResult {
+	var failed []CheckResult
+	for _, result := range r.Results {
+		if result.Err != nil || (strict && result.Skipped) {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Log 输出自检报告
+func (r *Report) Log() {
+	for _, result := range r.Results {
+		switch {
+		case result.Skipped:
+			logger.Warnf("Self-test [%s]: SKIPPED (not initialized)", result.Name)
+		case result.Err != nil:
+			logger.Errorf("Self-test [%s]: FAILED in %s: %v", result.Name, result.Duration, result.Err)
+		default:
+			logger.Infof("Self-test [%s]: PASSED in %s", result.Name, result.Duration)
+		}
+	}
+}
+
+// SelfTest 依次执行数据库查询和MinIO探针对象读写，minioClient为nil时跳过存储检查
+func SelfTest(ctx context.Context, db *gorm.DB, minioClient *minio.Client) *Report {
+	report := &Report{}
+
+	report.Results = append(report.Results, runCheck(ctx, "database", func(ctx context.Context) error {
+		return database.Probe(ctx, db)
+	}))
+
+	if minioClient == nil {
+		report.Results = append(report.Results, CheckResult{Name: "minio", Skipped: true})
+	} else {
+		report.Results = append(report.Results, runCheck(ctx, "minio", minioClient.Probe))
+	}
+
+	return report
+}
+
+// runCheck 在超时控制下执行单项检查
+func runCheck(ctx context.Context, name string, check func(ctx context.Context) error) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	return CheckResult{Name: name, Err: err, Duration: time.Since(start)}
+}
+
+// Summary 汇总失败项，用于strict模式下的退出信息
+func Summary(failed []CheckResult) string {
+	summary := ""
+	for i, result := range failed {
+		if i > 0 {
+			summary += "; "
+		}
+		if result.Skipped {
+			summary += fmt.Sprintf("%s: not initialized", result.Name)
+		} else {
+			summary += fmt.Sprintf("%s: %v", result.Name, result.Err)
+		}
+	}
+	return summary
+}
@@ -0,0 +1,86 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"webservice/internal/testutil"
+)
+
+func failedNames(failed []CheckResult) string {
+	var names []string
+	for _, result := range failed {
+		names = append(names, result.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSelfTestPasses(t *testing.T) {
+	db := testutil.NewDB(t)
+	storage := testutil.NewStorage(t, nil)
+
+	report := SelfTest(context.Background(), db, storage)
+	if len(report.Results) != 2 {
+		t.Fatalf("results = %+v, want database and minio", report.Results)
+	}
+	for _, strict := range []bool{false, true} {
+		if failed := report.Failed(strict); len(failed) > 0 {
+			t.Errorf("Failed(%v) = %s", strict, Summary(failed))
+		}
+	}
+}
+
+func TestSelfTestFailingProbe(t *testing.T) {
+	db := testutil.NewDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	report := SelfTest(context.Background(), db, testutil.NewStorage(t, nil))
+
+	// 探针失败时无论是否strict都视为失败，由调用方决定终止还是继续
+	for _, strict := range []bool{false, true} {
+		failed := report.Failed(strict)
+		if got := failedNames(failed); got != "database" {
+			t.Errorf("Failed(%v) = %q, want database", strict, got)
+		}
+	}
+	if summary := Summary(report.Failed(true)); !strings.HasPrefix(summary, "database: failed to query database") {
+		t.Errorf("Summary = %q", summary)
+	}
+}
+
+func TestSelfTestSkippedStorage(t *testing.T) {
+	report := SelfTest(context.Background(), testutil.NewDB(t), nil)
+
+	// 宽松模式下未初始化的存储不阻止启动，strict模式下视为失败
+	if failed := report.Failed(false); len(failed) > 0 {
+		t.Errorf("lenient Failed = %s, want none", Summary(failed))
+	}
+	failed := report.Failed(true)
+	if got := failedNames(failed); got != "minio" {
+		t.Fatalf("strict Failed = %q, want minio", got)
+	}
+	if summary := Summary(failed); summary != "minio: not initialized" {
+		t.Errorf("Summary = %q", summary)
+	}
+}
+
+func TestSelfTestHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := SelfTest(ctx, testutil.NewDB(t), testutil.NewStorage(t, nil))
+	for _, result := range report.Failed(false) {
+		if result.Name == "minio" && !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("minio err = %v, want context.Canceled", result.Err)
+		}
+	}
+	if got := failedNames(report.Failed(false)); !strings.Contains(got, "minio") {
+		t.Errorf("Failed = %q, want the storage probe to fail on a cancelled context", got)
+	}
+}
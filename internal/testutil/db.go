@@ -0,0 +1,36 @@
+// Package testutil 测试辅助函数，只在测试中使用
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// NewDB 创建测试用的SQLite数据库（临时目录中的文件，测试结束后删除）并迁移给定的模型
+// 使用WAL和busy_timeout，可以在并发测试中从多个连接同时写入
+func NewDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	// 与database.Init相同，自动时间戳使用UTC
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard, NowFunc: func() time.Time { return time.Now().UTC() }})
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get test database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if len(models) > 0 {
+		if err := db.AutoMigrate(models...); err != nil {
+			t.Fatalf("failed to migrate test database: %v", err)
+		}
+	}
+	return db
+}
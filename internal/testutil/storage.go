@@ -0,0 +1,36 @@
+package testutil
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"webservice/internal/config"
+	"webservice/internal/minio"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// NewStorage 启动内存中的S3兼容服务并返回连接它的MinIO客户端，测试结束后关闭
+// modify可在创建客户端前修改配置（如开启压缩、冷存储bucket）
+func NewStorage(t testing.TB, modify func(cfg *config.MinIOConfig)) *minio.Client {
+	t.Helper()
+	server := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+	t.Cleanup(server.Close)
+
+	cfg := config.MinIOConfig{
+		Endpoint:   strings.TrimPrefix(server.URL, "http://"),
+		AccessKey:  "test",
+		SecretKey:  "test-secret",
+		BucketName: "packages",
+	}
+	if modify != nil {
+		modify(&cfg)
+	}
+	client, err := minio.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("failed to create storage client: %v", err)
+	}
+	return client
+}
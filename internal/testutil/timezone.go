@@ -0,0 +1,15 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+)
+
+// SetLocalTimezone 将进程的本地时区（time.Local）替换为loc，测试结束后恢复
+// 相当于以TZ环境变量启动进程，用于验证时间处理不依赖部署时区；使用此函数的测试不能并行执行
+func SetLocalTimezone(t testing.TB, loc *time.Location) {
+	t.Helper()
+	previous := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = previous })
+}
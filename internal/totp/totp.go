@@ -0,0 +1,115 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RFC 6238参数，与常见的身份验证器应用（Google Authenticator等）默认值一致
+const (
+	// Period 验证码有效的时间步长
+	Period = 30 * time.Second
+	// Digits 验证码位数
+	Digits = 6
+	// Skew 校验时前后各容许的时间步数，用于容忍客户端时钟偏差
+	Skew = 1
+	// secretLength 密钥字节数（160位，RFC 4226推荐长度）
+	secretLength = 20
+)
+
+// encoding 不带填充的Base32编码，身份验证器应用以此格式录入密钥
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 生成Base32编码的随机密钥
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// Code 计算指定时刻的验证码
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, counterAt(t)), nil
+}
+
+// Validate 校验验证码，容许前后Skew个时间步的偏差
+func Validate(secret, passcode string, t time.Time) bool {
+	passcode = strings.TrimSpace(passcode)
+	if len(passcode) != Digits {
+		return false
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return false
+	}
+
+	counter := counterAt(t)
+	for offset := -Skew; offset <= Skew; offset++ {
+		expected := code(key, uint64(int64(counter)+int64(offset)))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(passcode)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// ProvisioningURI 返回otpauth://格式的密钥URI，可生成二维码供身份验证器应用扫描
+func ProvisioningURI(secret, issuer, account string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// decodeSecret 解码Base32密钥，兼容小写、空格和填充
+func decodeSecret(secret string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := encoding.DecodeString(strings.TrimRight(normalized, "="))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid totp secret")
+	}
+	return key, nil
+}
+
+// counterAt 时刻对应的时间步计数
+func counterAt(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period/time.Second))
+}
+
+// code 按RFC 4226计算HOTP值并截断为Digits位
+func code(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
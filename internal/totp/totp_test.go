@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238附录B的SHA1测试密钥
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	// RFC 6238附录B给出8位验证码，6位验证码取其后6位
+	vectors := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, v := range vectors {
+		got, err := Code(rfcSecret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", v.unix, err)
+		}
+		if got != v.want {
+			t.Errorf("Code(%d) = %s, want %s", v.unix, got, v.want)
+		}
+	}
+}
+
+func TestValidateAllowsOneStepOfSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	for _, offset := range []time.Duration{-Period, 0, Period} {
+		passcode, err := Code(secret, now.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Validate(secret, passcode, now) {
+			t.Errorf("code from offset %v rejected", offset)
+		}
+	}
+
+	stale, _ := Code(secret, now.Add(-2*Period))
+	if Validate(secret, stale, now) {
+		t.Error("code two steps old accepted")
+	}
+	if Validate(secret, "", now) || Validate(secret, "12345", now) {
+		t.Error("malformed code accepted")
+	}
+	if Validate("not base32!", "123456", now) {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("ABCDEF", "webservice", "alice")
+	if !strings.HasPrefix(uri, "otpauth://totp/webservice:alice?") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+	for _, part := range []string{"secret=ABCDEF", "issuer=webservice", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("uri %s missing %s", uri, part)
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package tracer
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	"webservice/internal/config"
+	"webservice/internal/version"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	jaegercfg "github.com/uber/jaeger-client-go/config"
 	jaegerlog "github.com/uber/jaeger-client-go/log"
 	"github.com/uber/jaeger-lib/metrics"
@@ -14,6 +17,8 @@ import (
 
 // Init 初始化Jaeger链路追踪
 func Init(cfg config.JaegerConfig) (io.Closer, error) {
+	build := version.Get()
+
 	// 配置Jaeger
 	jaegerCfg := jaegercfg.Configuration{
 		ServiceName: cfg.ServiceName,
@@ -25,6 +30,11 @@ func Init(cfg config.JaegerConfig) (io.Closer, error) {
 			LogSpans:           false, // 禁用日志输出避免干扰
 			LocalAgentHostPort: fmt.Sprintf("%s:%d", cfg.AgentHost, cfg.AgentPort),
 		},
+		// 构建信息作为tracer级别的标签，附加到所有span所在的进程信息中
+		Tags: []opentracing.Tag{
+			{Key: "service.version", Value: build.Version},
+			{Key: "service.commit", Value: build.Commit},
+		},
 	}
 
 	// 创建tracer
@@ -52,6 +62,19 @@ func StartSpanFromContext(ctx opentracing.SpanContext, operationName string) ope
 	return opentracing.StartSpan(operationName, opentracing.ChildOf(ctx))
 }
 
+// StartServiceSpan 在ctx中当前span下开始一个服务层子span，返回携带新span的context
+// 后续使用该context的数据库操作会作为此span的子span记录
+func StartServiceSpan(ctx context.Context, operationName string) (context.Context, opentracing.Span) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, operationName)
+	ext.Component.Set(span, "service")
+	return ctx, span
+}
+
+// Enabled 判断是否已注册真实的全局tracer，未初始化或初始化失败时全局tracer为NoopTracer
+func Enabled() bool {
+	return opentracing.IsGlobalTracerRegistered()
+}
+
 // GetGlobalTracer 获取全局tracer
 func GetGlobalTracer() opentracing.Tracer {
 	return opentracing.GlobalTracer()
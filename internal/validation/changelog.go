@@ -0,0 +1,168 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 更新日志校验错误码
+const (
+	ChangelogMissingHeading  = "changelog_missing_heading"
+	ChangelogMissingListItem = "changelog_missing_list_item"
+	ChangelogRawHTML         = "changelog_raw_html"
+)
+
+// ValidationError 单项校验错误
+type ValidationError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"` // 出错的行号（从1开始），与具体行无关时为0
+}
+
+var (
+	// atxHeading # 标题（最多3个空格缩进）
+	atxHeading = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+	// setextUnderline 标题下方的 === 或 --- 下划线
+	setextUnderline = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	// listItem 无序（-、*、+）或有序（1. 1)）列表项
+	listItem = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s+\S`)
+	// fence 围栏代码块的开始或结束
+	fence = regexp.MustCompile("^ {0,3}(```|~~~)")
+	// inlineCode 行内代码
+	inlineCode = regexp.MustCompile("`+[^`]*`+")
+	// htmlTag 原始HTML标签、注释或声明；<https://...>、<user@example.com> 等自动链接不匹配
+	// 标签名后可以是空白或/（浏览器把<svg/onload=...>中的/视为属性分隔符）
+	htmlTag = regexp.MustCompile(`(?i)<(/?[a-z][a-z0-9-]*([\s/][^>]*)?|!--[\s\S]*?--|![a-z][^>]*|\?[^>]*\?)>`)
+)
+
+// ValidateChangelog 检查更新日志的Markdown格式：至少一个标题、至少一个列表项、不包含原始HTML
+// 代码块和行内代码中的内容不参与检查
+func ValidateChangelog(markdown string) []ValidationError {
+	var errs []ValidationError
+	hasHeading, hasListItem := false, false
+
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	inFence := false
+	prevText := false // 上一行是普通文本段落，用于识别setext标题
+	for i, line := range lines {
+		if fence.MatchString(line) {
+			inFence = !inFence
+			prevText = false
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case atxHeading.MatchString(line):
+			hasHeading = true
+		case prevText && setextUnderline.MatchString(line):
+			hasHeading = true
+		case listItem.MatchString(line):
+			hasListItem = true
+		}
+
+		for _, tag := range findHTML(line) {
+			errs = append(errs, ValidationError{
+				Code:    ChangelogRawHTML,
+				Message: fmt.Sprintf("raw HTML is not allowed: %s", tag),
+				Line:    i + 1,
+			})
+		}
+		prevText = trimmed != "" && !atxHeading.MatchString(line) && !listItem.MatchString(line)
+	}
+
+	if !hasHeading {
+		errs = append(errs, ValidationError{Code: ChangelogMissingHeading, Message: "changelog must contain at least one heading"})
+	}
+	if !hasListItem {
+		errs = append(errs, ValidationError{Code: ChangelogMissingListItem, Message: "changelog must contain at least one list item"})
+	}
+	return errs
+}
+
+// findHTML 返回一行中（行内代码之外）的原始HTML标签
+func findHTML(line string) []string {
+	line = inlineCode.ReplaceAllString(line, "")
+	return htmlTag.FindAllString(line, -1)
+}
+
+var (
+	// dangerousElements 连同内容一起删除的元素
+	dangerousElements = regexp.MustCompile(`(?is)<(script|style|iframe|object|embed|noscript|template)\b[^>]*>.*?</(script|style|iframe|object|embed|noscript|template)\s*>`)
+	// unsafeAutolink 使用脚本协议的自动链接
+	unsafeAutolink = regexp.MustCompile(`(?i)<\s*(javascript|vbscript|data)\s*:[^>]*>`)
+	// unsafeLink Markdown链接或图片中的脚本协议
+	unsafeLink = regexp.MustCompile(`(?i)(\]\(\s*<?)\s*(javascript|vbscript|data)\s*:([^()\s>]|\([^()]*\))*`)
+	// unsafeReference 引用式链接定义中的脚本协议
+	unsafeReference = regexp.MustCompile(`(?im)^( {0,3}\[[^\]]+\]:\s*<?)\s*(javascript|vbscript|data)\s*:\S*`)
+)
+
+// SanitizeChangelog 删除更新日志中可能导致XSS的内容，供网页渲染：
+// script、style、iframe等元素连同内容删除，其他原始HTML标签删除（保留标签之间的文本），
+// javascript:、vbscript:、data: 链接替换为#；代码块和行内代码保持不变
+func SanitizeChangelog(markdown string) string {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+
+	var (
+		out     []string
+		segment []string // 代码块之外的连续行，整体处理以删除跨行的元素
+		inFence bool
+	)
+	flush := func() {
+		if len(segment) > 0 {
+			out = append(out, sanitizeText(strings.Join(segment, "\n")))
+			segment = segment[:0]
+		}
+	}
+	for _, line := range lines {
+		if fence.MatchString(line) {
+			if !inFence {
+				flush()
+			}
+			inFence = !inFence
+			out = append(out, line)
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+		segment = append(segment, line)
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// sanitizeText 处理代码块之外的文本，行内代码保持不变
+func sanitizeText(text string) string {
+	text = dangerousElements.ReplaceAllString(text, "")
+
+	// 先取出行内代码，处理完成后放回
+	var codes []string
+	text = inlineCode.ReplaceAllStringFunc(text, func(code string) string {
+		codes = append(codes, code)
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+
+	// 删除标签后剩余的文本可能重新组成标签（如<<b>script>），重复处理直到不再变化
+	for {
+		stripped := dangerousElements.ReplaceAllString(text, "")
+		stripped = htmlTag.ReplaceAllString(stripped, "")
+		stripped = unsafeAutolink.ReplaceAllString(stripped, "")
+		if stripped == text {
+			break
+		}
+		text = stripped
+	}
+	text = unsafeLink.ReplaceAllString(text, "${1}#")
+	text = unsafeReference.ReplaceAllString(text, "${1}#")
+
+	for i, code := range codes {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), code, 1)
+	}
+	return text
+}
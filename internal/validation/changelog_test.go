@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateChangelog(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string // 错误码及行号
+	}{
+		{"valid", "# 1.0.0\n\n- fixed a bug\n", nil},
+		{"setext heading and ordered list", "1.0.0\n=====\n\n1. fixed a bug\n", nil},
+		{"missing heading", "- fixed a bug\n", []string{ChangelogMissingHeading}},
+		{"missing list item", "# 1.0.0\n\nfixed a bug\n", []string{ChangelogMissingListItem}},
+		{"empty", "", []string{ChangelogMissingHeading, ChangelogMissingListItem}},
+		{"heading needs a space", "#1.0.0\n- fixed\n", []string{ChangelogMissingHeading}},
+		{"thematic break is not a setext heading", "- fixed\n\n---\n", []string{ChangelogMissingHeading}},
+		{"script", "# 1.0.0\n- fixed <script>alert(1)</script>\n", []string{ChangelogRawHTML + "@2", ChangelogRawHTML + "@2"}},
+		{"event handler", "# 1.0.0\n- <img src=x onerror=alert(1)>\n", []string{ChangelogRawHTML + "@2"}},
+		{"slash before attributes", "# 1.0.0\n- <svg/onload=alert(1)>\n", []string{ChangelogRawHTML + "@2"}},
+		{"comment", "# 1.0.0\n- fixed <!-- hidden -->\n", []string{ChangelogRawHTML + "@2"}},
+		{"autolinks are not HTML", "# 1.0.0\n- see <https://example.com> or <dev@example.com>\n", nil},
+		{"inline code", "# 1.0.0\n- escape `<script>` in output\n", nil},
+		{"fenced code", "# 1.0.0\n- fixed\n\n```html\n<script>alert(1)</script>\n```\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateChangelog(tt.markdown) {
+				code := err.Code
+				if err.Line > 0 {
+					code = fmt.Sprintf("%s@%d", code, err.Line)
+				}
+				got = append(got, code)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ValidateChangelog() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeChangelog(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"plain", "# 1.0.0\n- fixed a bug", "# 1.0.0\n- fixed a bug"},
+		{"script removed with content", "- fixed <script>alert(1)</script> bug", "- fixed  bug"},
+		{"multi-line script", "- a <script>\nalert(1)\n</script> b", "- a  b"},
+		{"uppercase script", "- <SCRIPT type=text/javascript>alert(1)</SCRIPT>", "- "},
+		{"unclosed script keeps only text", "- <script>alert(1)", "- alert(1)"},
+		{"nested tags do not reassemble", "- <scr<script>ipt>alert(1)</script>", "- <scr"},
+		{"split tag reassembly", "- <<b>script>alert(1)<</b>/script>", "- "},
+		{"iframe", `- <iframe src="https://evil.example"></iframe>`, "- "},
+		{"event handler", "- <img src=x onerror=alert(1)> text", "-  text"},
+		{"event handler on link", `- <a href="https://example.com" onclick="steal()">docs</a>`, "- docs"},
+		{"slash before attributes", "- <svg/onload=alert(1)>", "- "},
+		{"javascript link", "- [docs](javascript:alert(1))", "- [docs](#)"},
+		{"javascript link with spaces and case", "- [docs]( JaVaScRiPt:alert(document.cookie))", "- [docs]( #)"},
+		{"javascript image", "- ![x](javascript:alert(1))", "- ![x](#)"},
+		{"angle bracket destination", "- [docs](<javascript:alert(1)>)", "- [docs]()"},
+		{"vbscript and data links", "- [a](vbscript:msgbox) [b](data:text/html;base64,PHNjcmlwdD4=)", "- [a](#) [b](#)"},
+		{"javascript reference", "[docs]: javascript:alert(1)\n- see [docs]", "[docs]: #\n- see [docs]"},
+		{"javascript autolink", "- <javascript:alert(1)>", "- "},
+		{"safe links kept", "- [docs](https://example.com/a_(b)) <https://example.com>", "- [docs](https://example.com/a_(b)) <https://example.com>"},
+		{"inline code kept", "- use `<script>` and `[x](javascript:y)`", "- use `<script>` and `[x](javascript:y)`"},
+		{"fenced code kept", "```\n<script>alert(1)</script>\n```\n- <b>bold</b>", "```\n<script>alert(1)</script>\n```\n- bold"},
+		{"crlf normalized", "# 1.0.0\r\n- <i>x</i>\r\n", "# 1.0.0\n- x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeChangelog(tt.markdown)
+			if got != tt.want {
+				t.Errorf("SanitizeChangelog(%q) = %q, want %q", tt.markdown, got, tt.want)
+			}
+			// 代码块之外不应残留标签或脚本协议
+			if outside := stripCode(got); htmlTag.MatchString(outside) || strings.Contains(strings.ToLower(outside), "javascript:") {
+				t.Errorf("sanitized output still contains HTML or a script link: %q", got)
+			}
+			// 已处理的内容再次处理不再变化
+			if again := SanitizeChangelog(got); again != got {
+				t.Errorf("sanitize is not idempotent: %q -> %q", got, again)
+			}
+		})
+	}
+}
+
+// stripCode 去掉代码块和行内代码，只保留会被渲染的文本
+func stripCode(markdown string) string {
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if fence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			out = append(out, inlineCode.ReplaceAllString(line, ""))
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/png"
+)
+
+// 图标文件的魔数
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	riffHeader   = []byte("RIFF")
+	webpFormat   = []byte("WEBP")
+)
+
+// errUnsupportedIcon 不是支持的图标格式
+var errUnsupportedIcon = errors.New("unsupported icon format, only png, webp and svg are accepted")
+
+// Icon 校验通过的图标
+type Icon struct {
+	ContentType string // image/png、image/webp或image/svg+xml
+	Data        []byte // 保存的内容，svg为清理后的内容
+	Width       int    // 像素宽度，svg未声明像素宽度时为0
+	Height      int
+}
+
+// CheckIcon 按文件内容（而不是客户端声明的类型）识别图标格式，检查宽高不超过maxDimension
+// png和webp原样保存；svg经过SanitizeSVG清理后保存
+func CheckIcon(data []byte, maxDimension int) (*Icon, error) {
+	var icon *Icon
+	var err error
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		icon, err = checkPNG(data)
+	case len(data) >= 12 && bytes.Equal(data[:4], riffHeader) && bytes.Equal(data[8:12], webpFormat):
+		icon, err = checkWebP(data)
+	case looksLikeSVG(data):
+		icon, err = checkSVG(data)
+	default:
+		return nil, errUnsupportedIcon
+	}
+	if err != nil {
+		return nil, err
+	}
+	if maxDimension > 0 && (icon.Width > maxDimension || icon.Height > maxDimension) {
+		return nil, fmt.Errorf("icon is %dx%d, the maximum is %dx%d", icon.Width, icon.Height, maxDimension, maxDimension)
+	}
+	return icon, nil
+}
+
+// checkPNG 读取png的宽高
+func checkPNG(data []byte) (*Icon, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid png: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("invalid png: empty image")
+	}
+	return &Icon{ContentType: "image/png", Data: data, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// checkWebP 从第一个数据块读取webp的宽高，支持有损（VP8）、无损（VP8L）和扩展（VP8X）格式
+func checkWebP(data []byte) (*Icon, error) {
+	if len(data) < 20 {
+		return nil, errors.New("invalid webp: truncated header")
+	}
+	chunk, size := string(data[12:16]), binary.LittleEndian.Uint32(data[16:20])
+	payload := data[20:]
+	if uint64(size) > uint64(len(payload)) {
+		return nil, errors.New("invalid webp: truncated chunk")
+	}
+
+	var width, height int
+	switch chunk {
+	case "VP8 ":
+		// 3字节帧标记、3字节起始码，之后是14位宽和14位高
+		if len(payload) < 10 || !bytes.Equal(payload[3:6], []byte{0x9d, 0x01, 0x2a}) {
+			return nil, errors.New("invalid webp: bad VP8 frame header")
+		}
+		width = int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3fff)
+	case "VP8L":
+		// 1字节签名0x2f，之后依次是14位的宽减1和高减1
+		if len(payload) < 5 || payload[0] != 0x2f {
+			return nil, errors.New("invalid webp: bad VP8L header")
+		}
+		bits := binary.LittleEndian.Uint32(payload[1:5])
+		width = int(bits&0x3fff) + 1
+		height = int((bits>>14)&0x3fff) + 1
+	case "VP8X":
+		// 4字节标志位，之后依次是24位的画布宽减1和高减1
+		if len(payload) < 10 {
+			return nil, errors.New("invalid webp: bad VP8X header")
+		}
+		width = int(uint32(payload[4])|uint32(payload[5])<<8|uint32(payload[6])<<16) + 1
+		height = int(uint32(payload[7])|uint32(payload[8])<<8|uint32(payload[9])<<16) + 1
+	default:
+		return nil, fmt.Errorf("invalid webp: unknown chunk %q", chunk)
+	}
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("invalid webp: empty image")
+	}
+	return &Icon{ContentType: "image/webp", Data: data, Width: width, Height: height}, nil
+}
+
+// checkSVG 清理svg并读取根元素声明的像素宽高
+func checkSVG(data []byte) (*Icon, error) {
+	sanitized, width, height, err := sanitizeSVG(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Icon{ContentType: "image/svg+xml", Data: sanitized, Width: width, Height: height}, nil
+}
@@ -0,0 +1,36 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CheckLinkURL 检查在页面中作为链接展示的URL（主页、仓库地址等）
+// 只允许http和https（httpsOnly为true时只允许https），拒绝javascript:、data:等其他协议以及包含用户名或密码的URL
+func CheckLinkURL(raw string, httpsOnly bool) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return errors.New("invalid URL")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+	case "http":
+		if httpsOnly {
+			return errors.New("must use https")
+		}
+	default:
+		if httpsOnly {
+			return fmt.Errorf("scheme %q is not allowed, only https is accepted", u.Scheme)
+		}
+		return fmt.Errorf("scheme %q is not allowed, only http and https are accepted", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("must contain a host")
+	}
+	if u.User != nil {
+		return errors.New("must not contain credentials")
+	}
+	return nil
+}
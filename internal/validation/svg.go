@@ -0,0 +1,213 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SVG相关的命名空间
+const (
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+	xmlNamespace   = "http://www.w3.org/XML/1998/namespace"
+)
+
+// svgAllowedElements 清理后保留的元素，其他元素（script、foreignObject、image、a、style、animate等）连同子元素一起移除
+var svgAllowedElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "title": true, "desc": true, "symbol": true, "use": true,
+	"path": true, "rect": true, "circle": true, "ellipse": true, "line": true, "polyline": true, "polygon": true,
+	"text": true, "tspan": true, "textPath": true,
+	"linearGradient": true, "radialGradient": true, "stop": true, "pattern": true,
+	"clipPath": true, "mask": true, "marker": true,
+	"filter": true, "feGaussianBlur": true, "feOffset": true, "feBlend": true, "feColorMatrix": true,
+	"feComposite": true, "feFlood": true, "feMerge": true, "feMergeNode": true, "feDropShadow": true,
+	"feMorphology": true,
+}
+
+var (
+	// svgURLReference 属性值或样式中的url(...)引用
+	svgURLReference = regexp.MustCompile(`(?i)url\(\s*['"]?\s*([^)'"]*)`)
+	// svgUnsafeValue 去掉空白和控制字符后出现这些内容的属性值一律移除
+	svgUnsafeValue = regexp.MustCompile(`(?i)(javascript:|vbscript:|data:|expression\(|@import|behavior:|-moz-binding)`)
+	// svgIgnoredChars 浏览器解析URL时会忽略的空白和控制字符，检查前先去掉，防止java&#9;script:之类的绕过
+	svgIgnoredChars = regexp.MustCompile(`[\x00-\x20]`)
+	// svgPixelLength 像素长度（无单位或px）
+	svgPixelLength = regexp.MustCompile(`^\s*([0-9]*\.?[0-9]+)\s*(px)?\s*$`)
+)
+
+// SanitizeSVG 清理用户上传的svg，使其在页面内联展示时不能执行脚本或加载外部资源
+// 移除不在白名单中的元素（含子元素）、on*事件属性、指向文档外部的href和url()引用、注释、处理指令和DOCTYPE
+func SanitizeSVG(data []byte) ([]byte, error) {
+	sanitized, _, _, err := sanitizeSVG(data)
+	return sanitized, err
+}
+
+// looksLikeSVG 内容是否以XML声明、注释、DOCTYPE或<svg开头
+func looksLikeSVG(data []byte) bool {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<!")) ||
+		bytes.HasPrefix(trimmed, []byte("<svg"))
+}
+
+// svgWriter 清理过程中的输出
+type svgWriter struct {
+	buf         bytes.Buffer
+	depth       int  // 已输出的未闭合元素数
+	rootChecked bool // 已检查根元素
+	rootDone    bool // 根元素已闭合
+	width       int  // 根元素声明的像素宽度
+	height      int
+}
+
+// sanitizeSVG 清理svg，同时返回根元素width/height声明的像素宽高（未声明或不是像素单位时为0）
+func sanitizeSVG(data []byte) ([]byte, int, int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	w := &svgWriter{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("invalid svg: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if w.rootDone {
+				return nil, 0, 0, errors.New("invalid svg: content after the root element")
+			}
+			if !w.rootChecked {
+				if !isSVGElement(t.Name, "svg") {
+					return nil, 0, 0, errors.New("invalid svg: root element must be <svg>")
+				}
+				w.rootChecked = true
+				w.width, w.height = svgPixelSize(t.Attr)
+			}
+			if !isSVGElement(t.Name, t.Name.Local) || !svgAllowedElements[t.Name.Local] {
+				if err := decoder.Skip(); err != nil {
+					return nil, 0, 0, fmt.Errorf("invalid svg: %w", err)
+				}
+				continue
+			}
+			w.writeStart(t)
+		case xml.EndElement:
+			w.buf.WriteString("</" + t.Name.Local + ">")
+			w.depth--
+			if w.depth == 0 {
+				w.rootDone = true
+			}
+		case xml.CharData:
+			if w.depth > 0 {
+				xml.EscapeText(&w.buf, t)
+			}
+			// 注释、处理指令（含XML声明）和DOCTYPE等声明一律丢弃
+		}
+	}
+	if !w.rootDone {
+		return nil, 0, 0, errors.New("invalid svg: missing <svg> root element")
+	}
+	return w.buf.Bytes(), w.width, w.height, nil
+}
+
+// isSVGElement 元素是否属于SVG命名空间（未声明命名空间时按SVG处理）
+func isSVGElement(name xml.Name, local string) bool {
+	return (name.Space == "" || name.Space == svgNamespace) && name.Local == local
+}
+
+// writeStart 输出开始标签和安全的属性，根元素重新声明所需的命名空间
+func (w *svgWriter) writeStart(t xml.StartElement) {
+	w.buf.WriteString("<" + t.Name.Local)
+	if w.depth == 0 {
+		w.buf.WriteString(` xmlns="` + svgNamespace + `" xmlns:xlink="` + xlinkNamespace + `"`)
+	}
+	for _, attr := range t.Attr {
+		name, ok := svgAttributeName(attr.Name)
+		if !ok || !svgAttributeAllowed(name, attr.Value) {
+			continue
+		}
+		w.buf.WriteString(" " + name + `="`)
+		xml.EscapeText(&w.buf, []byte(attr.Value))
+		w.buf.WriteString(`"`)
+	}
+	w.buf.WriteString(">")
+	w.depth++
+}
+
+// svgAttributeName 输出使用的属性名；命名空间声明和未知命名空间的属性返回false
+func svgAttributeName(name xml.Name) (string, bool) {
+	switch name.Space {
+	case "", svgNamespace:
+		if name.Local == "xmlns" {
+			return "", false
+		}
+		return name.Local, true
+	case xlinkNamespace:
+		return "xlink:" + name.Local, true
+	case xmlNamespace:
+		return "xml:" + name.Local, true
+	default:
+		return "", false
+	}
+}
+
+// svgAttributeAllowed 检查属性是否可以保留：拒绝事件属性，href只能引用文档内的片段（#id），url()同理
+func svgAttributeAllowed(name, value string) bool {
+	local := strings.ToLower(name)
+	if i := strings.IndexByte(local, ':'); i >= 0 {
+		local = local[i+1:]
+	}
+	if strings.HasPrefix(local, "on") {
+		return false
+	}
+	// CSS转义（如\6a avascript:）可以绕过关键字检查，含反斜杠的属性值直接移除
+	if strings.ContainsRune(value, '\\') {
+		return false
+	}
+	compact := svgIgnoredChars.ReplaceAllString(value, "")
+	if local == "href" {
+		return strings.HasPrefix(compact, "#")
+	}
+	if svgUnsafeValue.MatchString(compact) {
+		return false
+	}
+	for _, match := range svgURLReference.FindAllStringSubmatch(value, -1) {
+		if !strings.HasPrefix(strings.TrimSpace(match[1]), "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// svgPixelSize 读取根元素width/height声明的像素宽高
+func svgPixelSize(attrs []xml.Attr) (int, int) {
+	var width, height int
+	for _, attr := range attrs {
+		if attr.Name.Space != "" && attr.Name.Space != svgNamespace {
+			continue
+		}
+		match := svgPixelLength.FindStringSubmatch(attr.Value)
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+		switch attr.Name.Local {
+		case "width":
+			width = int(value + 0.5)
+		case "height":
+			height = int(value + 0.5)
+		}
+	}
+	return width, height
+}
@@ -0,0 +1,107 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSVGRemovesActiveContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		forbidden []string
+		kept      []string
+	}{
+		{
+			name:      "script element",
+			input:     `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><circle r="5"/></svg>`,
+			forbidden: []string{"script", "alert"},
+			kept:      []string{`<circle r="5">`},
+		},
+		{
+			name:      "event handler attribute",
+			input:     `<svg onload="alert(1)"><rect width="1" height="1" onClick="alert(2)"/></svg>`,
+			forbidden: []string{"onload", "onClick", "alert"},
+			kept:      []string{`<rect width="1" height="1">`},
+		},
+		{
+			name:      "foreignObject with html",
+			input:     `<svg><foreignObject><body xmlns="http://www.w3.org/1999/xhtml"><iframe src="https://evil.example"/></body></foreignObject></svg>`,
+			forbidden: []string{"foreignObject", "iframe", "evil"},
+		},
+		{
+			name:      "external and javascript hrefs",
+			input:     `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="https://evil.example/x.svg#a"/><use href="java&#9;script:alert(1)"/><use href="#local"/></svg>`,
+			forbidden: []string{"evil", "script"},
+			kept:      []string{`<use href="#local">`},
+		},
+		{
+			name:      "external url reference in style",
+			input:     `<svg><rect style="fill:url(https://evil.example/p)"/><rect fill="url(#grad)"/></svg>`,
+			forbidden: []string{"evil"},
+			kept:      []string{`fill="url(#grad)"`},
+		},
+		{
+			name:      "css escape",
+			input:     `<svg><rect style="background:\6a avascript:alert(1)"/></svg>`,
+			forbidden: []string{"avascript", "style"},
+		},
+		{
+			name:      "comments and doctype",
+			input:     `<?xml version="1.0"?><!DOCTYPE svg><!-- secret --><svg><title>icon</title></svg>`,
+			forbidden: []string{"DOCTYPE", "secret", "<?xml"},
+			kept:      []string{"<title>icon</title>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := SanitizeSVG([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("SanitizeSVG: %v", err)
+			}
+			got := string(out)
+			for _, s := range tt.forbidden {
+				if strings.Contains(got, s) {
+					t.Errorf("output contains %q: %s", s, got)
+				}
+			}
+			for _, s := range tt.kept {
+				if !strings.Contains(got, s) {
+					t.Errorf("output lost %q: %s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeSVGRejectsInvalidDocuments(t *testing.T) {
+	for _, input := range []string{
+		`<html><svg></svg></html>`,
+		`<svg><circle r="1"></svg>`,
+		`<svg></svg><svg></svg>`,
+		`not xml`,
+	} {
+		if _, err := SanitizeSVG([]byte(input)); err == nil {
+			t.Errorf("SanitizeSVG(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestSanitizeSVGPixelSize(t *testing.T) {
+	_, width, height, err := sanitizeSVG([]byte(`<svg width="64px" height="48"></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 64 || height != 48 {
+		t.Errorf("size = %dx%d, want 64x48", width, height)
+	}
+
+	_, width, height, err = sanitizeSVG([]byte(`<svg width="100%" height="2em"></svg>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if width != 0 || height != 0 {
+		t.Errorf("relative size = %dx%d, want 0x0", width, height)
+	}
+}
@@ -0,0 +1,77 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 构建信息，通过 -ldflags "-X webservice/internal/version.Version=..." 注入
+// 注入空值时（如构建脚本取不到git信息）按未注入处理，读取时使用Get
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// 未注入构建信息时的默认值
+const (
+	defaultVersion = "dev"
+	unknown        = "unknown"
+)
+
+// startTime 进程启动时间
+var startTime = time.Now()
+
+// Info 构建信息
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get 返回构建信息，未注入的字段使用默认值
+func Get() Info {
+	return Info{
+		Version:   orDefault(Version, defaultVersion),
+		Commit:    orDefault(Commit, unknown),
+		BuildDate: orDefault(BuildDate, unknown),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String 返回可读的版本描述，用于启动日志
+func String() string {
+	info := Get()
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", info.Version, info.Commit, info.BuildDate, info.GoVersion)
+}
+
+// orDefault value为空（或只有空白）时返回def
+func orDefault(value, def string) string {
+	if strings.TrimSpace(value) == "" {
+		return def
+	}
+	return value
+}
+
+// Fields 返回构建信息的日志字段，用于结构化启动日志
+func (i Info) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"version":    i.Version,
+		"commit":     i.Commit,
+		"build_date": i.BuildDate,
+		"go_version": i.GoVersion,
+	}
+}
+
+// Uptime 返回进程已运行的时长
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// StartTime 返回进程启动时间
+func StartTime() time.Time {
+	return startTime
+}
@@ -0,0 +1,41 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setBuildInfo 临时替换注入的构建信息，测试结束后恢复
+func setBuildInfo(t *testing.T, v, commit, date string) {
+	t.Helper()
+	oldVersion, oldCommit, oldDate := Version, Commit, BuildDate
+	Version, Commit, BuildDate = v, commit, date
+	t.Cleanup(func() { Version, Commit, BuildDate = oldVersion, oldCommit, oldDate })
+}
+
+func TestGetInjected(t *testing.T) {
+	setBuildInfo(t, "v1.4.2", "abc1234", "2024-05-01T10:00:00Z")
+
+	want := Info{Version: "v1.4.2", Commit: "abc1234", BuildDate: "2024-05-01T10:00:00Z", GoVersion: runtime.Version()}
+	if got := Get(); got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+	if got := String(); !strings.HasPrefix(got, "v1.4.2 (commit abc1234, built 2024-05-01T10:00:00Z") {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestGetFallsBackWhenUnset(t *testing.T) {
+	for _, empty := range []string{"", "  "} {
+		setBuildInfo(t, empty, empty, empty)
+
+		got := Get()
+		if got.Version != "dev" || got.Commit != "unknown" || got.BuildDate != "unknown" {
+			t.Errorf("Get() with %q = %+v, want dev/unknown/unknown", empty, got)
+		}
+		if got.GoVersion != runtime.Version() {
+			t.Errorf("GoVersion = %q, want %q", got.GoVersion, runtime.Version())
+		}
+	}
+}
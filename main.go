@@ -2,21 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"webservice/internal/cache"
 	"webservice/internal/config"
 	"webservice/internal/database"
+	"webservice/internal/feature"
+	"webservice/internal/grpcserver"
+	"webservice/internal/httpclient"
+	"webservice/internal/jobs"
 	"webservice/internal/logger"
 	"webservice/internal/migration"
 	"webservice/internal/minio"
+	"webservice/internal/notify"
+	"webservice/internal/outbox"
 	"webservice/internal/router"
+	"webservice/internal/scan"
+	"webservice/internal/service"
+	"webservice/internal/startup"
 	"webservice/internal/tracer"
+	"webservice/internal/version"
+
+	"google.golang.org/grpc"
 )
 
 // main 程序入口点
@@ -29,11 +44,19 @@ func main() {
 
 	// 初始化日志
 	logger.Init(cfg.Log)
-	logger.Info("Starting webservice...")
+	if cfg.Server.StartupBanner == "structured" {
+		logger.WithFields(version.Get().Fields()).Info("Starting webservice")
+	} else {
+		logger.Infof("Starting webservice %s...", version.String())
+	}
 
 	// 初始化链路追踪
-	closer, err := tracer.Init(cfg.Jaeger)
-	if err != nil {
+	if !cfg.Jaeger.Enabled {
+		logger.Info("Tracing disabled by configuration")
+	} else if closer, err := tracer.Init(cfg.Jaeger); err != nil {
+		if cfg.Server.StrictStartup {
+			logger.Fatalf("Failed to initialize tracer (strict startup): %v", err)
+		}
 		logger.Warnf("Failed to initialize tracer (continuing without tracing): %v", err)
 	} else {
 		defer closer.Close()
@@ -48,22 +71,136 @@ func main() {
 	logger.Info("Database connected successfully")
 
 	// 运行数据库迁移
-	if err := migration.RunMigrations(db); err != nil {
+	if err := migration.RunMigrations(db, cfg); err != nil {
 		logger.Fatalf("Failed to run database migrations: %v", err)
 	}
 	logger.Info("Database migrations completed successfully")
 
+	// 首次启动引导：没有管理员时输出一次性安装令牌
+	setupToken, expiresAt, err := service.NewBootstrapService(db, cfg.Bootstrap, cfg.Password).IssueSetupToken()
+	if err != nil {
+		logger.Fatalf("Failed to issue setup token: %v", err)
+	}
+	if setupToken != "" {
+		logger.Warnf("No admin account exists. Use setup token %s with POST /api/v1/public/setup before %s",
+			setupToken, expiresAt.Format(time.RFC3339))
+	}
+
 	// 初始化MinIO客户端
 	minioClient, err := minio.NewClient(cfg.MinIO)
 	if err != nil {
-		logger.Warnf("Failed to initialize MinIO client (continuing without file storage): %v", err)
+		if cfg.Server.StrictStartup {
+			logger.Fatalf("Failed to initialize MinIO client (strict startup): %v", err)
+		}
+		var retryErr *minio.StartupRetryError
+		if errors.As(err, &retryErr) {
+			logger.Warnf("MinIO unavailable after %d retries over %d seconds (continuing without file storage): %v",
+				retryErr.Retries, int(retryErr.Elapsed.Seconds()), retryErr.Err)
+		} else {
+			logger.Warnf("Failed to initialize MinIO client (continuing without file storage): %v", err)
+		}
 		minioClient = nil // 设置为nil，让应用程序知道MinIO不可用
 	} else {
 		logger.Info("MinIO client initialized successfully")
 	}
 
+	// 对象布局迁移子命令：执行后直接退出，不启动HTTP服务
+	if isObjectMigrationCommand() {
+		os.Exit(runObjectMigration(db, minioClient, os.Args[2:]))
+	}
+
+	// 启动自检：数据库查询和MinIO探针对象读写，strict模式下未通过则终止启动
+	report := startup.SelfTest(context.Background(), db, minioClient)
+	report.Log()
+	if failed := report.Failed(cfg.Server.StrictStartup); len(failed) > 0 {
+		if cfg.Server.StrictStartup {
+			logger.Fatalf("Startup self-test failed: %s", startup.Summary(failed))
+		}
+		logger.Warnf("Startup self-test failed (continuing): %s", startup.Summary(failed))
+	} else {
+		logger.Info("Startup self-test passed")
+	}
+
+	// 出站HTTP客户端：统一代理、超时、CA证书和SSRF防护
+	httpClients, err := httpclient.NewFactory(cfg.Outbound, nil)
+	if err != nil {
+		logger.Fatalf("Invalid outbound HTTP configuration: %v", err)
+	}
+	defer httpClients.CloseIdleConnections()
+
+	// 用户通知渠道：关注的包发布新版本时通知关注者
+	notifier, err := notify.New(cfg.Notify, httpClients)
+	if err != nil {
+		logger.Fatalf("Invalid notify configuration: %v", err)
+	}
+
+	// 病毒扫描引擎：配置错误时终止启动，避免在未扫描的情况下对外提供上传的版本
+	if _, err := scan.New(cfg.Packages.Scan); err != nil {
+		logger.Fatalf("Invalid scan configuration: %v", err)
+	}
+
+	// Redis：排行榜计算后发布到Redis，请求只从Redis读取；不可用时排行榜接口返回503
+	redisClient, err := cache.NewRedisClient(cfg.Redis)
+	if err != nil {
+		logger.Warnf("Redis unavailable, leaderboards disabled: %v", err)
+		redisClient = nil
+	}
+	if redisClient != nil {
+		defer redisClient.Close()
+	}
+	leaderboards := service.NewLeaderboardService(db, redisClient)
+	// 计算任务只按周期执行，启动时把表中已有的排行榜发布到Redis，避免Redis为空时等待下一次计算
+	go func() {
+		if err := leaderboards.PublishLeaderboards(context.Background()); err != nil {
+			logger.Warnf("Failed to publish leaderboards: %v", err)
+		}
+	}()
+
+	// 启动后台任务：定期清理过期会话并解除到期的用户暂停、投递发件箱事件，每天计算包推荐、每小时计算包排行榜并发布到Redis，存储可用时每天执行存储分层和预发布版本过期、定期抽样校验存储完整性、清理预签名上传会话和重新扫描扫描未完成的版本
+	scheduler := jobs.NewScheduler()
+	scheduler.Register(jobs.NewSessionCleanupJob(service.NewSessionService(db), service.NewUserService(db, cfg.Password)), time.Hour)
+	scheduler.Register(jobs.NewRecommendationJob(service.NewPackageService(db, minioClient, nil, cfg.Packages)), 24*time.Hour)
+	scheduler.Register(jobs.NewLeaderboardJob(leaderboards), time.Hour)
+	if cfg.Packages.LockAfterMinutes > 0 {
+		scheduler.Register(jobs.NewVersionLockJob(service.NewPackageService(db, minioClient, nil, cfg.Packages),
+			time.Duration(cfg.Packages.LockAfterMinutes)*time.Minute), 5*time.Minute)
+	}
+
+	// 发件箱分发：未投递的事件（包括重启前遗留的）按各消费者保存的进度继续投递
+	dispatcher := outbox.NewDispatcher(db, cfg.Outbox)
+	outbox.RegisterDefaultConsumers(dispatcher)
+	dispatcher.Register(service.NewWatchNotifier(db, notifier, cfg.Notify))
+	dispatcher.Register(service.NewQuarantineNotifier(db, notifier))
+	scheduler.Register(dispatcher, dispatcher.Interval())
+
+	// 依赖可用性历史：定期检查数据库和存储，供 /api/v1/admin/health/history 查询
+	healthHistory := service.NewHealthHistoryService(db, minioClient, cfg.Health)
+	if healthHistory.Interval() > 0 {
+		scheduler.Register(jobs.NewHealthSampleJob(healthHistory), healthHistory.Interval())
+	}
+
+	// 功能开关：启动时加载，之后定期刷新，使其他实例上的修改生效
+	featureFlags := feature.New(db)
+	if err := featureFlags.Load(context.Background()); err != nil {
+		logger.Warnf("Failed to load feature flags, all flags disabled until the next refresh: %v", err)
+	}
+	scheduler.Register(jobs.NewFeatureFlagRefreshJob(featureFlags), feature.RefreshInterval)
+	if minioClient != nil {
+		scheduler.Register(jobs.NewStorageTieringJob(service.NewStorageTieringService(db, minioClient)), 24*time.Hour)
+		scheduler.Register(jobs.NewIntegrityJob(service.NewIntegrityService(db, minioClient, cfg.Integrity)), cfg.Integrity.Interval)
+		scheduler.Register(jobs.NewUploadSessionCleanupJob(service.NewPackageService(db, minioClient, nil, cfg.Packages)), time.Hour)
+		scheduler.Register(jobs.NewVersionScanJob(service.NewPackageService(db, minioClient, nil, cfg.Packages)), 10*time.Minute)
+		if cfg.Retention.PrereleaseMaxAge > 0 {
+			scheduler.Register(jobs.NewPrereleaseExpiryJob(service.NewPackageService(db, minioClient, nil, cfg.Packages), cfg.Retention.PrereleaseMaxAge), 24*time.Hour)
+		}
+		if mirrors := minioClient.Mirrors(); mirrors != nil && cfg.MinIO.MirrorHealth.CheckInterval > 0 {
+			scheduler.Register(jobs.NewMirrorHealthJob(mirrors), cfg.MinIO.MirrorHealth.CheckInterval)
+		}
+	}
+	scheduler.Start()
+
 	// 初始化路由
-	r := router.Setup(cfg, db, minioClient)
+	r := router.Setup(cfg, db, minioClient, httpClients, healthHistory, leaderboards, featureFlags)
 
 	// 创建HTTP服务器
 	srv := &http.Server{
@@ -81,6 +218,22 @@ func main() {
 		}
 	}()
 
+	// 供内部服务使用的只读gRPC接口，使用单独的端口
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Enabled {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+		if err != nil {
+			logger.Fatalf("Failed to listen on gRPC port %d: %v", cfg.GRPC.Port, err)
+		}
+		grpcSrv = grpcserver.New(cfg, db, minioClient)
+		go func() {
+			logger.Infof("gRPC server starting on port %d", cfg.GRPC.Port)
+			if err := grpcSrv.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				logger.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
 	// 等待中断信号以优雅地关闭服务器
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -91,9 +244,28 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if grpcSrv != nil {
+		stopGRPC(ctx, grpcSrv)
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
+	scheduler.Stop()
 
 	logger.Info("Server exited")
 }
+
+// stopGRPC 优雅关闭gRPC服务：不再接受新请求并等待进行中的请求完成，超时后强制关闭
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("gRPC server did not stop in time, forcing shutdown")
+		srv.Stop()
+	}
+}
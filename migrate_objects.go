@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"webservice/internal/logger"
+	"webservice/internal/minio"
+	"webservice/internal/service"
+
+	"gorm.io/gorm"
+)
+
+// objectMigrationReportInterval 迁移进度日志的输出间隔
+const objectMigrationReportInterval = 5 * time.Second
+
+// runObjectMigration 执行 migrate-objects 子命令：将已有包文件迁移到指定的命名方案
+// 用法: webservice migrate-objects -scheme hash-sharded [-dry-run]
+func runObjectMigration(db *gorm.DB, minioClient *minio.Client, args []string) int {
+	flags := flag.NewFlagSet("migrate-objects", flag.ContinueOnError)
+	scheme := flags.String("scheme", "", "target object naming scheme: legacy, flat, hash-sharded")
+	dryRun := flags.Bool("dry-run", false, "only report how many objects would be migrated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *scheme == "" {
+		logger.Error("migrate-objects: -scheme is required")
+		return 2
+	}
+	if minioClient == nil {
+		logger.Error("migrate-objects: MinIO is not available")
+		return 1
+	}
+
+	// 允许Ctrl+C中断，已迁移的版本保持迁移后的状态
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Infof("Migrating package objects to %s naming scheme (dry run: %t)", *scheme, *dryRun)
+	lastReport := time.Now()
+	result, err := service.NewObjectMigrationService(db, minioClient).Migrate(ctx, *scheme, *dryRun,
+		func(p service.ObjectMigrationProgress) {
+			if time.Since(lastReport) >= objectMigrationReportInterval {
+				lastReport = time.Now()
+				logger.Infof("Progress: %d/%d processed (migrated %d, skipped %d, failed %d)",
+					p.Migrated+p.Skipped+p.Failed, p.Total, p.Migrated, p.Skipped, p.Failed)
+			}
+		})
+	if err != nil {
+		logger.Errorf("Object migration aborted: %v", err)
+		return 1
+	}
+
+	logger.Infof("Object migration finished: total %d, migrated %d, skipped %d, failed %d",
+		result.Total, result.Migrated, result.Skipped, result.Failed)
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// isObjectMigrationCommand 判断是否以 migrate-objects 子命令启动
+func isObjectMigrationCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == "migrate-objects"
+}